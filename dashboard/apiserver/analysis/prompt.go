@@ -0,0 +1,155 @@
+package analysis
+
+import (
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+// Message is a message of the conversation to analyze.
+type Message struct {
+    UserID string
+    Text   string
+}
+
+// OpenQuestion is a question of the thread still waiting for an answer.
+type OpenQuestion struct {
+    Question    string `json:"question"`
+    AskedPerson string `json:"asked_person"`
+}
+
+// Result is the classification of a thread, in the format the collector
+// stores in ai_analysis_json.
+type Result struct {
+    ThreadState       string         `json:"thread_state"`
+    Priority          string         `json:"priority"`
+    ConfidenceScore   float64        `json:"confidence_score"`
+    Reasoning         string         `json:"reasoning"`
+    ActionItems       []string       `json:"action_items"`
+    Stakeholders      []string       `json:"stakeholders"`
+    OpenQuestionsLeft []OpenQuestion `json:"open_questions_left"`
+    QualityScore      *float64       `json:"quality_score"`
+    MissingInfo       []string       `json:"missing_info"`
+    // AnalysisTimestamp lets the collector skip threads without activity
+    // since they were analyzed
+    AnalysisTimestamp string `json:"analysis_timestamp,omitempty"`
+}
+
+// userIDPattern matches Slack user IDs.
+var userIDPattern = regexp.MustCompile(`\bU[A-Z0-9]{8,}\b`)
+
+// Prompt returns the prompt classifying a conversation. It is the prompt of
+// the collector, so threads read the same whichever analyzed them.
+func Prompt(conversation []Message) string {
+    var text strings.Builder
+    for _, message := range conversation {
+        fmt.Fprintf(&text, "[User: %s] %s\n", message.UserID, message.Text)
+    }
+    return `You are an expert conversation analyzer. Analyze the following conversation and classify it precisely.
+
+ONLY return a valid JSON object. DO NOT include explanations, notes, or formatting (like triple backticks). Output must be raw JSON.
+
+Classification Categories:
+- thread_state: one of ["open", "closed", "resolved", "deferred", "chit_chat", "unknown"]
+- priority: one of ["high", "medium", "low", "none"]
+- missing_info: any of ["logs", "version", "clear_question", "steps_to_reproduce"]
+
+Conversation Data:
+` + text.String() + `
+Your task:
+1. Analyze the conversation.
+2. Identify the slack thread_state and priority.
+3. Extract ALL user IDs in the format U123ABC456 (e.g., [User: U123ABC456] or just U123ABC456).
+4. Identify clear action items (as a list of strings).
+5. Identify unresolved questions with the user being asked.
+6. Score how complete the first message is for triage, from 0.0 (nothing to go on) to 1.0 (ready to act on),
+   and list what it lacks: logs or error output, the version in use, a clear question or ask, steps to reproduce.
+   Only list details that matter for this kind of thread; chit chat and announcements lack nothing.
+7. Return ONLY a JSON object matching the exact structure below — no extra text, markdown, or code blocks.
+
+Required JSON format:
+{
+  "thread_state": "one of: open, closed, resolved, deferred, chit_chat, unknown",
+  "priority": "one of: high, medium, low, none",
+  "confidence_score": 0.85,
+  "reasoning": "Brief explanation of classification decision",
+  "action_items": ["specific", "actionable", "items"],
+  "stakeholders": ["U123ABC456", "U789DEF012"],
+  "open_questions_left": [
+    { "question": "Why is the API not working?", "asked_person": "U123ABC456" },
+    { "question": "When will the database migration be completed?", "asked_person": "U789DEF012" }
+  ],
+  "quality_score": 0.4,
+  "missing_info": ["logs", "version"]
+}
+
+IMPORTANT:
+- DO NOT return anything other than this JSON object.
+- DO NOT include introductory text, explanations, or code formatting like ` + "```json" + `.
+- If unsure, return "unknown" for thread_state or empty arrays where applicable.
+`
+}
+
+// ParseResult decodes a completion, tolerating the code fences models add
+// despite being told not to.
+func ParseResult(completion string) (*Result, error) {
+    cleaned := strings.ReplaceAll(completion, "```json", "")
+    cleaned = strings.TrimSpace(strings.ReplaceAll(cleaned, "```", ""))
+    if start, end := strings.Index(cleaned, "{"), strings.LastIndex(cleaned, "}"); start >= 0 && end > start {
+        cleaned = cleaned[start : end+1]
+    }
+    var result Result
+    if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
+        return nil, fmt.Errorf("analysis: completion is not the expected JSON: %w", err)
+    }
+    switch result.Priority {
+    case "high", "medium", "low", "none":
+    default:
+        result.Priority = "none"
+    }
+    if result.ConfidenceScore < 0 || result.ConfidenceScore > 1 {
+        result.ConfidenceScore = 0
+    }
+    return &result, nil
+}
+
+// StakeholderIDs returns the user IDs the model identified, keeping only
+// well formed ones.
+func (r *Result) StakeholderIDs() []string {
+    ids := []string{}
+    for _, id := range r.Stakeholders {
+        if match := userIDPattern.FindString(id); match != "" {
+            ids = append(ids, match)
+        }
+    }
+    return ids
+}
+
+// ThreadName returns a concise name for the thread, named like the
+// collector names them.
+func (r *Result) ThreadName() string {
+    if r.Reasoning != "" {
+        firstSentence := strings.TrimSpace(strings.SplitN(r.Reasoning, ".", 2)[0])
+        if len([]rune(firstSentence)) <= 50 {
+            return firstSentence
+        }
+        return strings.TrimSpace(string([]rune(r.Reasoning)[:47])) + "..."
+    }
+    if len(r.ActionItems) > 0 {
+        item := []rune(r.ActionItems[0])
+        if len(item) > 40 {
+            item = item[:40]
+        }
+        return "Discussion: " + string(item) + "..."
+    }
+    return "Thread Discussion"
+}
+
+// Description returns the description of the thread.
+func (r *Result) Description() string {
+    if r.Reasoning == "" {
+        return "No description available"
+    }
+    return r.Reasoning
+}
@@ -0,0 +1,209 @@
+// Package analysis classifies Slack threads with a large language model.
+package analysis
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Providers supported by NewProvider.
+const (
+    ProviderOpenAI    = "openai"
+    ProviderAnthropic = "anthropic"
+    // ProviderLocal is any server offering the OpenAI chat completions API,
+    // e.g. Ollama, vLLM or llama.cpp
+    ProviderLocal = "local"
+)
+
+// Provider completes a prompt with a language model.
+type Provider interface {
+    Name() string
+    Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// APIError is a response with an unexpected status. Retryable errors are
+// worth trying again, after RetryAfter when the provider told.
+type APIError struct {
+    Provider   string
+    Status     int
+    Message    string
+    RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+    return fmt.Sprintf("%s: status %d: %s", e.Provider, e.Status, e.Message)
+}
+
+// Retryable reports whether the request may succeed when sent again.
+func (e *APIError) Retryable() bool {
+    return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+// Config selects and configures a provider.
+type Config struct {
+    Provider string
+    APIKey   string
+    Model    string
+    // BaseURL overrides the API root, required for local providers
+    BaseURL string
+}
+
+// NewProvider returns the provider of config.
+func NewProvider(config Config) (Provider, error) {
+    httpClient := &http.Client{Timeout: 2 * time.Minute}
+    switch config.Provider {
+    case ProviderOpenAI, ProviderLocal:
+        baseURL := config.BaseURL
+        if baseURL == "" {
+            if config.Provider == ProviderLocal {
+                return nil, fmt.Errorf("analysis: the local provider needs a base URL")
+            }
+            baseURL = "https://api.openai.com/v1"
+        }
+        if config.Provider == ProviderOpenAI && config.APIKey == "" {
+            return nil, fmt.Errorf("analysis: the openai provider needs an API key")
+        }
+        model := config.Model
+        if model == "" {
+            model = "gpt-4o-mini"
+        }
+        return &chatCompletions{name: config.Provider, baseURL: strings.TrimSuffix(baseURL, "/"),
+            apiKey: config.APIKey, model: model, httpClient: httpClient}, nil
+    case ProviderAnthropic:
+        if config.APIKey == "" {
+            return nil, fmt.Errorf("analysis: the anthropic provider needs an API key")
+        }
+        baseURL := config.BaseURL
+        if baseURL == "" {
+            baseURL = "https://api.anthropic.com/v1"
+        }
+        model := config.Model
+        if model == "" {
+            model = "claude-3-5-haiku-latest"
+        }
+        return &messages{baseURL: strings.TrimSuffix(baseURL, "/"), apiKey: config.APIKey,
+            model: model, httpClient: httpClient}, nil
+    }
+    return nil, fmt.Errorf("analysis: unknown provider %q", config.Provider)
+}
+
+// chatCompletions calls the OpenAI chat completions API.
+type chatCompletions struct {
+    name       string
+    baseURL    string
+    apiKey     string
+    model      string
+    httpClient *http.Client
+}
+
+func (p *chatCompletions) Name() string {
+    return p.name
+}
+
+func (p *chatCompletions) Complete(ctx context.Context, prompt string) (string, error) {
+    body := map[string]interface{}{
+        "model":       p.model,
+        "temperature": 0,
+        "messages":    []map[string]string{{"role": "user", "content": prompt}},
+    }
+    headers := map[string]string{}
+    if p.apiKey != "" {
+        headers["Authorization"] = "Bearer " + p.apiKey
+    }
+    var resp struct {
+        Choices []struct {
+            Message struct {
+                Content string `json:"content"`
+            } `json:"message"`
+        } `json:"choices"`
+    }
+    if err := post(ctx, p.httpClient, p.name, p.baseURL+"/chat/completions", headers, body, &resp); err != nil {
+        return "", err
+    }
+    if len(resp.Choices) == 0 {
+        return "", fmt.Errorf("%s: empty completion", p.name)
+    }
+    return resp.Choices[0].Message.Content, nil
+}
+
+// messages calls the Anthropic messages API.
+type messages struct {
+    baseURL    string
+    apiKey     string
+    model      string
+    httpClient *http.Client
+}
+
+func (p *messages) Name() string {
+    return ProviderAnthropic
+}
+
+func (p *messages) Complete(ctx context.Context, prompt string) (string, error) {
+    body := map[string]interface{}{
+        "model":       p.model,
+        "max_tokens":  2048,
+        "temperature": 0,
+        "messages":    []map[string]string{{"role": "user", "content": prompt}},
+    }
+    headers := map[string]string{
+        "x-api-key":         p.apiKey,
+        "anthropic-version": "2023-06-01",
+    }
+    var resp struct {
+        Content []struct {
+            Type string `json:"type"`
+            Text string `json:"text"`
+        } `json:"content"`
+    }
+    if err := post(ctx, p.httpClient, ProviderAnthropic, p.baseURL+"/messages", headers, body, &resp); err != nil {
+        return "", err
+    }
+    var text strings.Builder
+    for _, block := range resp.Content {
+        if block.Type == "text" {
+            text.WriteString(block.Text)
+        }
+    }
+    if text.Len() == 0 {
+        return "", fmt.Errorf("%s: empty completion", ProviderAnthropic)
+    }
+    return text.String(), nil
+}
+
+func post(ctx context.Context, httpClient *http.Client, provider string, url string, headers map[string]string, in interface{}, out interface{}) error {
+    encoded, err := json.Marshal(in)
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(encoded))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    for key, value := range headers {
+        req.Header.Set(key, value)
+    }
+
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+        apiErr := &APIError{Provider: provider, Status: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+        if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+            apiErr.RetryAfter = time.Duration(seconds) * time.Second
+        }
+        return apiErr
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,167 @@
+// Package apierror is how the API answers requests that failed: handlers
+// return an *Error and the HTTPErrorHandler of the server writes it as the
+// error envelope
+//
+//	{"error": {"code": "not_found", "message": "Thread not found", "request_id": "...", "details": {...}}}
+//
+// Clients branch on the code, the message is meant for people.
+package apierror
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+
+    "dashboard/apiserver/logger"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Codes of errors, one per status unless a more precise one applies.
+const (
+    CodeInvalidRequest      = "invalid_request"
+    CodeUnauthenticated     = "unauthenticated"
+    CodeForbidden           = "forbidden"
+    CodeNotFound            = "not_found"
+    CodeMethodNotAllowed    = "method_not_allowed"
+    CodeConflict            = "conflict"
+    CodePayloadTooLarge     = "payload_too_large"
+    CodeRateLimited         = "rate_limited"
+    CodeInternal            = "internal_error"
+    CodeUpstream            = "upstream_error"
+    CodeUnavailable         = "unavailable"
+    CodeDatabaseUnavailable = "database_unavailable"
+)
+
+// statusCodes are the codes of errors created from their status alone.
+var statusCodes = map[int]string{
+    http.StatusBadRequest:            CodeInvalidRequest,
+    http.StatusUnauthorized:          CodeUnauthenticated,
+    http.StatusForbidden:             CodeForbidden,
+    http.StatusNotFound:              CodeNotFound,
+    http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+    http.StatusConflict:              CodeConflict,
+    http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+    http.StatusTooManyRequests:       CodeRateLimited,
+    http.StatusInternalServerError:   CodeInternal,
+    http.StatusBadGateway:            CodeUpstream,
+    http.StatusServiceUnavailable:    CodeUnavailable,
+}
+
+// codeOf returns the code of errors with status.
+func codeOf(status int) string {
+    if code, ok := statusCodes[status]; ok {
+        return code
+    }
+    if status >= 500 {
+        return CodeInternal
+    }
+    return CodeInvalidRequest
+}
+
+// Error is a failed request, answered with Status. Details are any data
+// helping clients act on the error, e.g. the items of a request that were
+// not found.
+type Error struct {
+    Status  int
+    Code    string
+    Message string
+    Details map[string]interface{}
+}
+
+// New returns an error answered with status, its code following from it.
+func New(status int, message string) *Error {
+    return &Error{Status: status, Code: codeOf(status), Message: message}
+}
+
+// Newf is New with a formatted message.
+func Newf(status int, format string, args ...interface{}) *Error {
+    return New(status, fmt.Sprintf(format, args...))
+}
+
+// ErrDatabaseUnavailable answers requests whose database cannot be reached,
+// which clients may retry.
+var ErrDatabaseUnavailable = &Error{
+    Status:  http.StatusServiceUnavailable,
+    Code:    CodeDatabaseUnavailable,
+    Message: "Database connection failed",
+}
+
+func (e *Error) Error() string {
+    return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// WithCode returns a copy of the error with a more precise code.
+func (e *Error) WithCode(code string) *Error {
+    copied := *e
+    copied.Code = code
+    return &copied
+}
+
+// WithDetails returns a copy of the error with details.
+func (e *Error) WithDetails(details map[string]interface{}) *Error {
+    copied := *e
+    copied.Details = details
+    return &copied
+}
+
+// envelope is the body of error responses.
+type envelope struct {
+    Error body `json:"error"`
+}
+
+type body struct {
+    Code      string                 `json:"code"`
+    Message   string                 `json:"message"`
+    RequestID string                 `json:"request_id,omitempty"`
+    Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// from converts the error of a handler to an *Error. Errors of echo, e.g.
+// unknown routes, keep their status, any other error is internal.
+func from(err error) (*Error, bool) {
+    var apiErr *Error
+    if errors.As(err, &apiErr) {
+        return apiErr, true
+    }
+    var httpErr *echo.HTTPError
+    if errors.As(err, &httpErr) {
+        message, ok := httpErr.Message.(string)
+        if !ok {
+            message = http.StatusText(httpErr.Code)
+        }
+        return New(httpErr.Code, message), true
+    }
+    return New(http.StatusInternalServerError, "Internal server error"), false
+}
+
+// Handler returns the HTTPErrorHandler writing the errors returned by
+// handlers and middleware as the error envelope, with the ID of the request
+// set by the RequestID middleware. Unexpected errors are logged and their
+// cause is not disclosed.
+func Handler(log logger.Logger) echo.HTTPErrorHandler {
+    return func(err error, ctx echo.Context) {
+        if ctx.Response().Committed {
+            return
+        }
+        apiErr, expected := from(err)
+        requestID := ctx.Response().Header().Get(echo.HeaderXRequestID)
+        if !expected {
+            log.Errorf("request %s %s (%s) failed: %v", ctx.Request().Method, ctx.Request().URL.Path, requestID, err)
+        }
+
+        if ctx.Request().Method == http.MethodHead {
+            err = ctx.NoContent(apiErr.Status)
+        } else {
+            err = ctx.JSON(apiErr.Status, envelope{Error: body{
+                Code:      apiErr.Code,
+                Message:   apiErr.Message,
+                RequestID: requestID,
+                Details:   apiErr.Details,
+            }})
+        }
+        if err != nil {
+            log.Warnf("failed to write error response: %v", err)
+        }
+    }
+}
@@ -0,0 +1,178 @@
+package apierror
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "dashboard/apiserver/logger"
+
+    "github.com/labstack/echo/v4"
+    "github.com/labstack/echo/v4/middleware"
+)
+
+// errorLog records the errors logged by the handler.
+type errorLog struct {
+    errors []string
+}
+
+func (l *errorLog) Debugf(string, ...interface{}) {}
+
+func (l *errorLog) Infof(string, ...interface{}) {}
+
+func (l *errorLog) Warnf(string, ...interface{}) {}
+
+func (l *errorLog) Errorf(format string, args ...interface{}) {
+    l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func (l *errorLog) With(...interface{}) logger.Logger { return l }
+
+func (l *errorLog) Cleanup() {}
+
+// serveError answers a request with method with the error returned by its
+// handler, behind the RequestID middleware like the server.
+func serveError(t *testing.T, log logger.Logger, method string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+    t.Helper()
+    e := echo.New()
+    e.HTTPErrorHandler = Handler(log)
+    e.Use(middleware.RequestID())
+    e.Add(method, "/fail", handler)
+    rec := httptest.NewRecorder()
+    e.ServeHTTP(rec, httptest.NewRequest(method, "/fail", nil))
+    return rec
+}
+
+func TestHandler(t *testing.T) {
+    tests := []struct {
+        name     string
+        err      error
+        status   int
+        code     string
+        message  string
+        details  map[string]interface{}
+        internal bool
+    }{
+        {
+            name:    "error",
+            err:     New(http.StatusNotFound, "Thread not found"),
+            status:  http.StatusNotFound,
+            code:    CodeNotFound,
+            message: "Thread not found",
+        },
+        {
+            name:    "wrapped error with details",
+            err:     fmt.Errorf("lookup: %w", New(http.StatusConflict, "Thread already has a Jira ticket").WithDetails(map[string]interface{}{"jira_ticket": "OPS-1"})),
+            status:  http.StatusConflict,
+            code:    CodeConflict,
+            message: "Thread already has a Jira ticket",
+            details: map[string]interface{}{"jira_ticket": "OPS-1"},
+        },
+        {
+            name:    "precise code",
+            err:     ErrDatabaseUnavailable,
+            status:  http.StatusServiceUnavailable,
+            code:    CodeDatabaseUnavailable,
+            message: "Database connection failed",
+        },
+        {
+            name:    "status without a code",
+            err:     New(http.StatusTeapot, "No coffee"),
+            status:  http.StatusTeapot,
+            code:    CodeInvalidRequest,
+            message: "No coffee",
+        },
+        {
+            name:    "echo error",
+            err:     echo.NewHTTPError(http.StatusMethodNotAllowed),
+            status:  http.StatusMethodNotAllowed,
+            code:    CodeMethodNotAllowed,
+            message: http.StatusText(http.StatusMethodNotAllowed),
+        },
+        {
+            name:    "echo error without a message",
+            err:     echo.NewHTTPError(http.StatusBadRequest, map[string]string{"field": "name"}),
+            status:  http.StatusBadRequest,
+            code:    CodeInvalidRequest,
+            message: http.StatusText(http.StatusBadRequest),
+        },
+        {
+            name:     "unexpected error",
+            err:      errors.New("pq: password authentication failed"),
+            status:   http.StatusInternalServerError,
+            code:     CodeInternal,
+            message:  "Internal server error",
+            internal: true,
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            log := &errorLog{}
+            rec := serveError(t, log, http.MethodGet, func(echo.Context) error { return tt.err })
+            if rec.Code != tt.status {
+                t.Fatalf("got status %d, want %d", rec.Code, tt.status)
+            }
+
+            var got envelope
+            if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+                t.Fatalf("invalid envelope %q: %v", rec.Body.String(), err)
+            }
+            if got.Error.Code != tt.code || got.Error.Message != tt.message {
+                t.Errorf("got code %q and message %q, want %q and %q", got.Error.Code, got.Error.Message, tt.code, tt.message)
+            }
+            if requestID := rec.Header().Get(echo.HeaderXRequestID); requestID == "" || got.Error.RequestID != requestID {
+                t.Errorf("got request ID %q, want the one of the response %q", got.Error.RequestID, requestID)
+            }
+            if fmt.Sprint(got.Error.Details) != fmt.Sprint(tt.details) {
+                t.Errorf("got details %v, want %v", got.Error.Details, tt.details)
+            }
+
+            // Only unexpected errors are logged, and their cause is not
+            // disclosed to the caller
+            if tt.internal != (len(log.errors) == 1) {
+                t.Errorf("got logged errors %q", log.errors)
+            }
+            if tt.internal && strings.Contains(rec.Body.String(), "pq:") {
+                t.Errorf("response %q discloses the cause of the error", rec.Body.String())
+            }
+        })
+    }
+}
+
+func TestHandlerHead(t *testing.T) {
+    rec := serveError(t, &errorLog{}, http.MethodHead, func(echo.Context) error {
+        return New(http.StatusNotFound, "Thread not found")
+    })
+    if rec.Code != http.StatusNotFound || rec.Body.Len() != 0 {
+        t.Fatalf("got status %d and body %q, want 404 without a body", rec.Code, rec.Body.String())
+    }
+}
+
+func TestHandlerCommitted(t *testing.T) {
+    rec := serveError(t, &errorLog{}, http.MethodGet, func(ctx echo.Context) error {
+        if err := ctx.String(http.StatusOK, "partial"); err != nil {
+            return err
+        }
+        return New(http.StatusInternalServerError, "Failed to stream")
+    })
+    // Responses already sent are left alone
+    if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+        t.Fatalf("got status %d and body %q, want the response of the handler", rec.Code, rec.Body.String())
+    }
+}
+
+func TestErrorCopies(t *testing.T) {
+    err := New(http.StatusServiceUnavailable, "Jira is not configured")
+    coded := err.WithCode(CodeUpstream)
+    detailed := err.WithDetails(map[string]interface{}{"site": "example"})
+    if err.Code != CodeUnavailable || err.Details != nil {
+        t.Fatalf("got %+v, want the original error unchanged", err)
+    }
+    if coded.Code != CodeUpstream || detailed.Details["site"] != "example" {
+        t.Fatalf("got copies %+v and %+v", coded, detailed)
+    }
+}
@@ -1,16 +1,25 @@
 package apiserver
 
 import (
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/demo"
     "dashboard/apiserver/handlers"
+    "dashboard/apiserver/inbound"
     "dashboard/apiserver/logger"
+    "dashboard/apiserver/openapi"
     "dashboard/apiserver/templates"
 
+    "context"
     "embed"
+    "fmt"
     "io/fs"
     "net"
     "net/http"
     "os"
+    "os/signal"
     "strconv"
+    "syscall"
     "time"
 
     "html/template"
@@ -21,6 +30,21 @@ import (
 
 const logLevelEnv string = "YB_OPEN_THREADS_REMINDER_DASHBOARD_UI_LOG_LEVEL"
 
+// autoMigrateEnv turns off applying schema migrations at startup, e.g. when
+// a deployment job runs the binary with -migrate instead.
+const autoMigrateEnv = "YB_OPEN_THREADS_REMINDER_AUTO_MIGRATE"
+
+// shutdownTimeoutEnv bounds how long in-flight requests and background jobs
+// are waited for on SIGINT or SIGTERM.
+const shutdownTimeoutEnv = "YB_OPEN_THREADS_REMINDER_SHUTDOWN_TIMEOUT"
+
+const (
+    authUserHeaderEnv   = "YB_OPEN_THREADS_REMINDER_AUTH_USER_HEADER"
+    authRequiredEnv     = "YB_OPEN_THREADS_REMINDER_AUTH_REQUIRED"
+    captchaVerifyURLEnv = "YB_OPEN_THREADS_REMINDER_CAPTCHA_VERIFY_URL"
+    captchaSecretEnv    = "YB_OPEN_THREADS_REMINDER_CAPTCHA_SECRET"
+)
+
 const (
     uiDir     = "dist"
     extension = "/*.html"
@@ -75,7 +99,296 @@ func getStaticFiles() http.FileSystem {
     return http.FS(fsys)
 }
 
-func Start(bindAddr string, port string) {
+// registerAPI sets up the handlers with their background jobs and mounts
+// the API routes on e. With quickstart the channels of the bot are tracked
+// without the collector. With faultInjection admins can inject faults. The
+// background jobs run with background until shutdown. Options replace the
+// dependencies of the handlers.
+func registerAPI(e *echo.Echo, log logger.Logger, background *jobs, quickstart bool, faultInjection bool, options ...handlers.Option) (*handlers.Container, error) {
+    if quickstart {
+        handlers.ApplyQuickstartDefaults()
+    }
+    if faultInjection {
+        handlers.EnableFaultInjection()
+    }
+    c, err := handlers.NewContainer(log, options...)
+    if err != nil {
+        return nil, err
+    }
+    // Quickstart needs the tables of the collector the migrations create
+    autoMigrate, _ := strconv.ParseBool(getEnv(autoMigrateEnv, "true"))
+    if autoMigrate || quickstart {
+        if err := c.EnsureSchema(); err != nil {
+            log.Warnf("failed to migrate the database schema: %v", err)
+        }
+    } else if pending, err := c.PendingMigrations(); err != nil {
+        log.Warnf("failed to look for pending schema migrations: %v", err)
+    } else if pending > 0 {
+        log.Warnf("%d schema migrations are pending, apply them with -migrate", pending)
+    }
+    if quickstart {
+        ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+        err := c.Quickstart(ctx)
+        cancel()
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    // Signing in is pointless while anonymous callers keep full access
+    authRequired, _ := strconv.ParseBool(getEnv(authRequiredEnv, strconv.FormatBool(c.SignInConfigured())))
+    authConfig := auth.Config{
+        UserHeader:  getEnv(authUserHeaderEnv, ""),
+        Required:    authRequired,
+        LookupToken: c.LookupToken,
+        LookupKey:   c.LookupAPIKey,
+        LookupRole:  c.LookupRole,
+        RecordLogin: c.RecordLoginEvent,
+    }
+    if c.SignInConfigured() {
+        authConfig.LookupSession = c.LookupSession
+    }
+    if captchaURL := getEnv(captchaVerifyURLEnv, ""); captchaURL != "" {
+        authConfig.Captcha = auth.NewSiteVerifyCaptcha(captchaURL, getEnv(captchaSecretEnv, ""))
+    }
+    authenticator := auth.NewAuthenticator(log, authConfig)
+    background.run(c.RunDBHealthCheck)
+    background.run(c.RunIngestion)
+    background.run(c.RunWebhookDelivery)
+    background.run(c.ResumeBackfills)
+    background.run(c.RunExports)
+    background.run(c.RunUsageFlush)
+    background.run(c.RunAnalysis)
+    background.run(c.RunGridDiscovery)
+    background.run(c.RunSlackScopeAudit)
+    // Jobs working on threads run once per database
+    for _, ctx := range c.WorkspaceContexts(background.context()) {
+        background.runWith(ctx, c.RunSuggestions)
+        background.runWith(ctx, c.RunReminders)
+        background.runWith(ctx, c.RunSnoozeWakeups)
+        background.runWith(ctx, c.RunWatcherSync)
+        background.runWith(ctx, c.RunCalibration)
+        background.runWith(ctx, c.RunStatsSnapshots)
+        background.runWith(ctx, c.RunReleaseWatch)
+        background.runWith(ctx, c.RunReporterNudges)
+        background.runWith(ctx, c.RunQualityPrompts)
+        background.runWith(ctx, c.RunSlackConnectSync)
+        background.runWith(ctx, c.RunUsergroupSync)
+        background.runWith(ctx, c.RunUserProfileSync)
+        background.runWith(ctx, c.RunChannelSummaries)
+        background.runWith(ctx, c.RunChannelPolling)
+        background.runWith(ctx, c.RunLiveUpdates)
+        background.runWith(ctx, c.RunStorageMonitor)
+        background.runWith(ctx, c.RunDigest)
+    }
+    background.run(func(ctx context.Context) {
+        ticker := time.NewTicker(10 * time.Minute)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+            authenticator.Prune()
+            c.PruneSlackEventDedup()
+            c.PruneSessions()
+            c.PruneRateLimits()
+            for _, ctx := range c.WorkspaceContexts(ctx) {
+                c.PruneThreadWebhooks(ctx)
+            }
+        }
+    })
+
+    // Probes are answered without authentication
+    e.GET("/readyz", c.GetReadiness)
+
+    // API endpoints
+    api := e.Group("/api", c.DebugRecorder().Middleware(), authenticator.Middleware(), c.RateLimit, c.RouteWorkspace, c.TrackUsage, c.EnforceReadOnly, c.ServeStale)
+    api.GET("/openapi.json", openapi.GetSpec)
+    api.GET("/docs", openapi.GetDocs)
+    api.GET("/sample_get", c.GetSample)
+    api.POST("/sample_post", c.PostSample)
+    
+    // Thread Dashboard API endpoints
+    api.GET("/stats", c.GetDashboardStats)
+    api.GET("/stats/diff", c.GetStatsDiff)
+    api.GET("/stats/timeseries", c.GetStatsTimeseries)
+    api.GET("/stats/resolutions", c.GetResolutionStats)
+    api.GET("/stats/usergroups", c.GetUsergroupStats)
+    api.GET("/threads", c.GetThreads)
+    api.GET("/schema/threads", c.GetThreadSchema)
+    api.GET("/threads/search", c.SearchThreads)
+    api.GET("/threads/export", c.ExportThreads)
+    api.POST("/threads/batch-get", c.BatchGetThreads)
+    api.POST("/threads/bulk", c.BulkUpdateThreads, authenticator.RequireScope(auth.ScopeTriage))
+    api.GET("/ws", c.StreamLiveUpdates)
+    api.GET("/threads/answered", c.GetAnsweredThreads)
+    api.DELETE("/threads/:channel_id/:thread_ts/answered", c.DismissAnsweredThread, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/reply", c.ReplyToThread, authenticator.RequireScope(auth.ScopeTriage))
+    api.GET("/threads/:channel_id/:thread_ts/webhooks", c.GetThreadWebhooks)
+    api.POST("/threads/:channel_id/:thread_ts/webhooks", c.CreateThreadWebhook, authenticator.RequireScope(auth.ScopeTriage))
+    api.DELETE("/threads/:channel_id/:thread_ts/webhooks/:id", c.DeleteThreadWebhook, authenticator.RequireScope(auth.ScopeTriage))
+    api.GET("/reply-templates", c.GetReplyTemplates)
+    api.POST("/reply-templates", c.CreateReplyTemplate, authenticator.RequireScope(auth.ScopeTriage))
+    api.PUT("/reply-templates/:id", c.UpdateReplyTemplate, authenticator.RequireScope(auth.ScopeTriage))
+    api.DELETE("/reply-templates/:id", c.DeleteReplyTemplate, authenticator.RequireScope(auth.ScopeTriage))
+    api.GET("/channels", c.GetChannels)
+    api.GET("/channels/:channel_id", c.GetChannel)
+    api.GET("/channels/:channel_id/config", c.GetChannelSettings)
+    api.GET("/user-profiles", c.GetUserProfiles)
+    api.GET("/users/:user_id/threads", c.GetUserThreads)
+    api.GET("/usergroups", c.GetUsergroups)
+    api.GET("/threads/:channel_id/:thread_ts/watchers", c.GetThreadWatchers)
+    api.GET("/threads/:channel_id/:thread_ts", c.GetThread)
+    api.GET("/threads/:channel_id/:thread_ts/qr.png", c.GetThreadQRCode)
+    api.GET("/threads/:channel_id/:thread_ts/report.pdf", c.GetThreadReport)
+    api.PATCH("/threads/:channel_id/:thread_ts", c.UpdateThreadStatus, authenticator.RequireScope(auth.ScopeTriage))
+    api.PATCH("/threads/:channel_id/:thread_ts/sla", c.SetThreadSLA, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/resolve", c.ResolveThread, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/snooze", c.SnoozeThread, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/resolve/approve", c.ApproveResolution, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/resolve/reject", c.RejectResolution, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/github", c.CreateThreadGitHubIssue, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/jira", c.CreateThreadJiraTicket, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/analyze", c.AnalyzeThread, authenticator.RequireScope(auth.ScopeTriage))
+    api.PUT("/threads/:channel_id/:thread_ts/release", c.SetThreadWaitingRelease, authenticator.RequireScope(auth.ScopeTriage))
+    api.DELETE("/threads/:channel_id/:thread_ts/release", c.ClearThreadWaitingRelease, authenticator.RequireScope(auth.ScopeTriage))
+    api.PUT("/threads/:channel_id/:thread_ts/waiting-reporter", c.SetThreadWaitingReporter, authenticator.RequireScope(auth.ScopeTriage))
+    api.DELETE("/threads/:channel_id/:thread_ts/waiting-reporter", c.ClearThreadWaitingReporter, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/ack", c.AcknowledgeThread, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/assign", c.AssignThread, authenticator.RequireScope(auth.ScopeTriage))
+    api.GET("/threads/:channel_id/:thread_ts/notes", c.GetThreadNotes)
+    api.POST("/threads/:channel_id/:thread_ts/notes", c.AddThreadNote, authenticator.RequireScope(auth.ScopeTriage))
+    api.PUT("/threads/:channel_id/:thread_ts/priority", c.SetThreadPriority, authenticator.RequireScope(auth.ScopeTriage))
+    api.GET("/threads/:channel_id/:thread_ts/effort", c.GetThreadEffort)
+    api.PUT("/threads/:channel_id/:thread_ts/effort", c.SetThreadEffort, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/time/start", c.StartThreadTimer, authenticator.RequireScope(auth.ScopeTriage))
+    api.POST("/threads/:channel_id/:thread_ts/time/stop", c.StopThreadTimer, authenticator.RequireScope(auth.ScopeTriage))
+    api.GET("/resolution-requests", c.GetResolutionRequests)
+    api.GET("/acks/latency", c.GetAckLatency)
+    api.GET("/effort", c.GetEffortAnalytics)
+    api.GET("/channel-groups", c.GetChannelGroups)
+    api.POST("/channel-groups", c.CreateChannelGroup, authenticator.RequireScope(auth.ScopeAdmin))
+    api.GET("/channel-groups/:id", c.GetChannelGroup)
+    api.PUT("/channel-groups/:id", c.UpdateChannelGroup, authenticator.RequireScope(auth.ScopeAdmin))
+    api.DELETE("/channel-groups/:id", c.DeleteChannelGroup, authenticator.RequireScope(auth.ScopeAdmin))
+    api.GET("/goals", c.GetGoals)
+    api.POST("/goals", c.CreateGoal, authenticator.RequireScope(auth.ScopeTriage))
+    api.GET("/goals/:id", c.GetGoal)
+    api.PUT("/goals/:id", c.UpdateGoal, authenticator.RequireScope(auth.ScopeTriage))
+    api.DELETE("/goals/:id", c.DeleteGoal, authenticator.RequireScope(auth.ScopeTriage))
+    api.GET("/goals/:id/progress", c.GetGoalProgress)
+    api.POST("/exports", c.CreateExport, authenticator.RequireScope(auth.ScopeTriage))
+    api.GET("/exports/:id", c.GetExport, authenticator.RequireScope(auth.ScopeRead))
+    api.POST("/digest/preview", c.PreviewDigest, authenticator.RequireScope(auth.ScopeRead))
+    api.PUT("/channels/:channel_id/config", c.SetChannelSettings, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/text-indexing", c.SetChannelTextIndexing, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/view", c.SetChannelView, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/heat", c.SetChannelHeatThresholds, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/resolve-approval", c.SetChannelResolveApproval, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/github-routing", c.SetChannelGitHubRouting, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/jira-mapping", c.SetChannelJiraMapping, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/note-sync", c.SetChannelNoteSync, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/responder-rotation", c.SetChannelResponderRotation, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/reminder-schedule", c.SetChannelReminderSchedule, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/quality-prompts", c.SetChannelQualityPrompts, authenticator.RequireScope(auth.ScopeAdmin))
+    api.PUT("/channels/:channel_id/slack-connect", c.SetChannelSlackConnect, authenticator.RequireScope(auth.ScopeAdmin))
+    api.GET("/channels/:channel_id/summary", c.GetChannelSummary)
+    api.PUT("/channels/:channel_id/summary", c.SetChannelSummary, authenticator.RequireScope(auth.ScopeAdmin))
+
+    // Personal API tokens
+    api.GET("/me", c.GetMe)
+    api.GET("/ui-config", c.GetUIConfig)
+    me := api.Group("/me", authenticator.RequireUser())
+    me.GET("/tokens", c.GetMyTokens)
+    me.POST("/tokens", c.CreateMyToken)
+    me.DELETE("/tokens/:id", c.RevokeMyToken)
+    me.GET("/threads", c.GetMyThreads)
+    me.GET("/preferences", c.GetMyPreferences)
+    me.PUT("/preferences", c.PutMyPreferences)
+
+    // Admin endpoints
+    admin := api.Group("/admin", authenticator.RequireUser(), authenticator.RequireScope(auth.ScopeAdmin))
+    admin.GET("/roles", c.GetUserRoles)
+    admin.PUT("/roles/:user_id", c.SetUserRole)
+    admin.DELETE("/roles/:user_id", c.DeleteUserRole)
+    admin.GET("/login-audit", c.GetLoginAudit)
+    admin.GET("/api-keys", c.GetAPIKeys)
+    admin.POST("/api-keys", c.CreateAPIKey)
+    admin.DELETE("/api-keys/:id", c.RevokeAPIKey)
+    admin.GET("/database", c.GetDatabaseStats)
+    admin.GET("/storage", c.GetStorage)
+    admin.GET("/stats-cache", c.GetStatsCache)
+    admin.GET("/workspaces", c.GetWorkspaces)
+    admin.GET("/slack-grid", c.GetEnterpriseGrid)
+    admin.GET("/slack-scopes", c.GetSlackScopes)
+    admin.GET("/adoption", c.GetAdoption)
+    admin.GET("/webhooks/inbound", c.GetInboundWebhookStats)
+    admin.GET("/webhooks/quarantine", c.GetInboundWebhookQuarantine)
+    admin.GET("/webhooks/outgoing", c.GetOutgoingWebhooks)
+    admin.POST("/webhooks/outgoing", c.CreateOutgoingWebhook)
+    admin.PUT("/webhooks/outgoing/:id", c.UpdateOutgoingWebhook)
+    admin.DELETE("/webhooks/outgoing/:id", c.DeleteOutgoingWebhook)
+    admin.POST("/webhooks/outgoing/:id/test", c.TestOutgoingWebhook)
+    admin.GET("/ingest", c.GetIngestStats)
+    admin.GET("/backfills", c.GetBackfills)
+    admin.POST("/channels/discover", c.DiscoverChannels)
+    admin.POST("/backfills", c.StartBackfills)
+    admin.GET("/legal-holds", c.GetLegalHolds)
+    admin.POST("/legal-holds", c.PlaceLegalHold)
+    admin.DELETE("/legal-holds/:id", c.ReleaseLegalHold)
+    admin.GET("/settings/display-name", c.GetDisplayNameSettings)
+    admin.PUT("/settings/display-name", c.PutDisplayNameSettings)
+    admin.GET("/user-directory", c.GetUserDirectory)
+    admin.POST("/user-directory", c.ImportUserDirectory)
+    admin.GET("/calibration", c.GetPriorityCalibration)
+    admin.GET("/debug-recording", c.GetDebugRecording)
+    admin.POST("/debug-recording", c.StartDebugRecording)
+    admin.DELETE("/debug-recording", c.StopDebugRecording)
+    admin.GET("/debug-bundle", c.GetDebugBundle)
+    admin.GET("/read-only", c.GetReadOnly)
+    admin.PUT("/read-only", c.SetReadOnly)
+    if c.FaultInjectionEnabled() {
+        log.Warnf("fault injection enabled, admins can slow down and break the dashboard")
+        admin.GET("/chaos", c.GetChaos)
+        admin.POST("/chaos", c.StartChaos)
+        admin.DELETE("/chaos", c.StopChaos)
+    }
+
+    // Slack events and interactivity, verified with the signing secret
+    // instead of the dashboard authentication
+    e.POST("/slack/events", c.HandleSlackEvent, c.InboundGuard().Middleware(inbound.SourceSlack))
+    e.POST("/slack/interactions", c.HandleSlackInteraction, c.InboundGuard().Middleware(inbound.SourceSlackInteractions))
+
+    // GitHub release, Jira issue and email gateway events, verified with
+    // their webhook secrets
+    e.POST("/github/webhook", c.HandleGitHubWebhook, c.InboundGuard().Middleware(inbound.SourceGitHub))
+    e.POST("/jira/webhook", c.HandleJiraWebhook, c.InboundGuard().Middleware(inbound.SourceJira))
+    e.POST("/email/webhook", c.HandleEmailWebhook, c.InboundGuard().Middleware(inbound.SourceEmail))
+
+    // Export downloads are authorized by the signature of the link
+    e.GET("/exports/:id/download", c.DownloadExport)
+
+    // Sign-in, outside of /api since it is what authenticates callers
+    e.GET("/auth/login", c.Login)
+    e.GET("/auth/callback", c.LoginCallback)
+    e.POST("/auth/logout", c.Logout)
+
+    // Email preference links are authorized by their signature. Mail
+    // clients unsubscribe with a POST (RFC 8058), so that link scanners
+    // following the link do not.
+    e.POST("/email/unsubscribe", c.UnsubscribeEmail, c.EnforceReadOnly)
+    e.GET("/email/preferences", c.GetEmailPreferences)
+    e.PUT("/email/preferences", c.UpdateEmailPreferences, c.EnforceReadOnly)
+    return c, nil
+}
+
+// Start serves the dashboard. With demoMode the API answers from generated
+// data in memory, without a database or Slack. With quickstart the channels
+// the bot is in are tracked with default settings. With faultInjection admins
+// can inject faults to validate retries and degradation.
+func Start(bindAddr string, port string, demoMode bool, quickstart bool, faultInjection bool) {
 
     // Initialize logger
     logLevel := getEnv(logLevelEnv, "info")
@@ -101,10 +414,17 @@ func Start(bindAddr string, port string) {
     LoadTemplates()
 
     e := echo.New()
-
-    c, _ := handlers.NewContainer(log)
+    extractor, err := ipExtractor(getEnv(trustedProxiesEnv, ""))
+    if err != nil {
+        log.Errorf("failed to read %s: %v", trustedProxiesEnv, err)
+        return
+    }
+    e.IPExtractor = extractor
+    // Failed requests are answered with the error envelope
+    e.HTTPErrorHandler = apierror.Handler(log)
 
     // Middleware
+    e.Use(middleware.RequestID())
     e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
         LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
             log.Errorf("[PANIC RECOVER] %v %s\n", err, stack)
@@ -112,6 +432,10 @@ func Start(bindAddr string, port string) {
         },
     }))
     e.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+        // Probes would drown the other requests
+        Skipper: func(c echo.Context) bool {
+            return c.Path() == "/healthz" || c.Path() == "/readyz"
+        },
         LogURI:           true,
         LogStatus:        true,
         LogLatency:       true,
@@ -123,6 +447,8 @@ func Start(bindAddr string, port string) {
         LogRemoteIP:      true,
         LogRequestID:     true,
         LogError:         true,
+        // Errors are written before logging to log the status they got
+        HandleError: true,
         LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
             bytes_in, err := strconv.ParseInt(v.ContentLength, 10, 64)
             if err != nil {
@@ -164,19 +490,39 @@ func Start(bindAddr string, port string) {
         AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
         AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-HTTP-Method-Override"},
         AllowCredentials: false,
-        ExposeHeaders:    []string{"Content-Length", "Content-Type"},
+        ExposeHeaders:    []string{"Content-Length", "Content-Type", echo.HeaderXRequestID, "X-Stale", "X-Stale-Since"},
         MaxAge:           86400, // 24 hours
     }))
 
-    // API endpoints
-    e.GET("/api/sample_get", c.GetSample)
-    e.POST("/api/sample_post", c.PostSample)
-    
-    // Thread Dashboard API endpoints
-    e.GET("/api/stats", c.GetDashboardStats)
-    e.GET("/api/threads", c.GetThreads)
-    e.GET("/api/channels", c.GetChannels)
-    e.GET("/api/user-profiles", c.GetUserProfiles)
+    e.GET("/healthz", handlers.GetLiveness)
+    background := newJobs()
+    var c *handlers.Container
+    if demoMode {
+        log.Infof("demo mode, serving generated data from memory")
+        server := demo.NewServer(log)
+        background.run(server.Run)
+        server.Register(e)
+        e.GET("/readyz", handlers.GetLiveness)
+    } else {
+        var options []handlers.Option
+        if quickstart {
+            dsn, stopDB, err := startEmbeddedDB(log)
+            if err != nil {
+                log.Errorf("%v", err)
+                return
+            }
+            if stopDB != nil {
+                defer stopDB()
+                options = append(options, handlers.WithDatabaseDSN(dsn))
+            }
+        }
+        var err error
+        c, err = registerAPI(e, log, background, quickstart, faultInjection, options...)
+        if err != nil {
+            log.Errorf("failed to initialize handlers: %v", err)
+            return
+        }
+    }
 
     render_htmls := templates.NewTemplate()
 
@@ -187,5 +533,45 @@ func Start(bindAddr string, port string) {
     e.GET("/", handlers.IndexHandler)
 
     uiBindAddress := net.JoinHostPort(bindAddr, port)
-    e.Logger.Fatal(e.Start(uiBindAddress))
+    if quickstart {
+        host := bindAddr
+        if host == "" || host == "0.0.0.0" || host == "::" {
+            host = "localhost"
+        }
+        fmt.Printf("Open Threads Reminder is running at http://%s/\n", net.JoinHostPort(host, port))
+    }
+
+    // Serve until SIGINT or SIGTERM, then stop accepting connections and
+    // let in-flight requests and background jobs finish
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    server := &http.Server{Addr: uiBindAddress}
+    go func() {
+        if err := e.StartServer(server); err != nil && err != http.ErrServerClosed {
+            log.Errorf("failed to serve: %v", err)
+            stop()
+        }
+    }()
+    <-ctx.Done()
+    stop()
+
+    shutdownTimeout, err := time.ParseDuration(getEnv(shutdownTimeoutEnv, "25s"))
+    if err != nil {
+        log.Warnf("invalid %s, waiting 25s: %v", shutdownTimeoutEnv, err)
+        shutdownTimeout = 25 * time.Second
+    }
+    log.Infof("shutting down, waiting up to %s for requests and background jobs", shutdownTimeout)
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+    if err := e.Shutdown(shutdownCtx); err != nil {
+        log.Warnf("failed to drain requests: %v", err)
+    }
+    if err := background.stop(shutdownCtx); err != nil {
+        log.Warnf("background jobs did not finish: %v", err)
+        return
+    }
+    if c != nil {
+        c.Close()
+    }
+    log.Infof("shut down")
 }
@@ -0,0 +1,116 @@
+package auth
+
+import (
+    "sync"
+    "time"
+)
+
+const (
+    // captchaAfterFailures is the number of consecutive failures after which
+    // a CAPTCHA response is demanded, when a verifier is configured.
+    captchaAfterFailures = 3
+    // lockoutAfterFailures is the number of consecutive failures that trigger
+    // a temporary lockout.
+    lockoutAfterFailures = 5
+    baseLockout          = time.Minute
+    maxLockout           = time.Hour
+    // failureMemory is how long a quiet client keeps its failure count.
+    failureMemory = 24 * time.Hour
+)
+
+type failureRecord struct {
+    failures    int
+    lockouts    int
+    lockedUntil time.Time
+    lastFailure time.Time
+}
+
+// LoginLimiter tracks failed authentication attempts per client and applies
+// an exponentially growing lockout once a client keeps failing.
+type LoginLimiter struct {
+    mu      sync.Mutex
+    records map[string]*failureRecord
+    now     func() time.Time
+}
+
+// NewLoginLimiter returns an empty limiter.
+func NewLoginLimiter() *LoginLimiter {
+    return &LoginLimiter{
+        records: make(map[string]*failureRecord),
+        now:     time.Now,
+    }
+}
+
+// Locked reports whether key is currently locked out and for how long.
+func (l *LoginLimiter) Locked(key string) (time.Duration, bool) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    record, ok := l.records[key]
+    if !ok {
+        return 0, false
+    }
+    if remaining := record.lockedUntil.Sub(l.now()); remaining > 0 {
+        return remaining, true
+    }
+    return 0, false
+}
+
+// NeedsCaptcha reports whether key has failed often enough to be challenged.
+func (l *LoginLimiter) NeedsCaptcha(key string) bool {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    record, ok := l.records[key]
+    return ok && record.failures >= captchaAfterFailures
+}
+
+// Failure records a failed attempt and returns the lockout it triggered, if
+// any. Every lockout doubles the previous one up to maxLockout.
+func (l *LoginLimiter) Failure(key string) time.Duration {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := l.now()
+    record, ok := l.records[key]
+    if !ok || now.Sub(record.lastFailure) > failureMemory {
+        record = &failureRecord{}
+        l.records[key] = record
+    }
+    record.failures++
+    record.lastFailure = now
+
+    if record.failures < lockoutAfterFailures {
+        return 0
+    }
+
+    lockout := baseLockout << record.lockouts
+    if lockout > maxLockout || lockout <= 0 {
+        lockout = maxLockout
+    }
+    record.lockouts++
+    record.failures = 0
+    record.lockedUntil = now.Add(lockout)
+    return lockout
+}
+
+// Success forgets the failure history of key.
+func (l *LoginLimiter) Success(key string) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    delete(l.records, key)
+}
+
+// Prune drops records that have been quiet for longer than failureMemory.
+func (l *LoginLimiter) Prune() {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := l.now()
+    for key, record := range l.records {
+        if now.Sub(record.lastFailure) > failureMemory && now.After(record.lockedUntil) {
+            delete(l.records, key)
+        }
+    }
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+    "testing"
+    "time"
+)
+
+func TestLoginLimiterDoublesLockouts(t *testing.T) {
+    now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+    limiter := NewLoginLimiter()
+    limiter.now = func() time.Time { return now }
+
+    for _, want := range []time.Duration{baseLockout, 2 * baseLockout, 4 * baseLockout} {
+        var lockout time.Duration
+        for i := 0; i < lockoutAfterFailures; i++ {
+            lockout = limiter.Failure("client")
+        }
+        if lockout != want {
+            t.Fatalf("got lockout %s, want %s", lockout, want)
+        }
+        if _, locked := limiter.Locked("client"); !locked {
+            t.Fatal("client is not locked out")
+        }
+        now = now.Add(lockout)
+    }
+}
+
+func TestLoginLimiterCaptchaAndSuccess(t *testing.T) {
+    limiter := NewLoginLimiter()
+    for i := 0; i < captchaAfterFailures; i++ {
+        if limiter.NeedsCaptcha("client") {
+            t.Fatalf("CAPTCHA demanded after %d failures", i)
+        }
+        limiter.Failure("client")
+    }
+    if !limiter.NeedsCaptcha("client") {
+        t.Fatalf("no CAPTCHA demanded after %d failures", captchaAfterFailures)
+    }
+    limiter.Success("client")
+    if limiter.NeedsCaptcha("client") {
+        t.Fatal("CAPTCHA still demanded after a success")
+    }
+}
+
+func TestLoginLimiterCapsLockout(t *testing.T) {
+    now := time.Now()
+    limiter := NewLoginLimiter()
+    limiter.now = func() time.Time { return now }
+
+    var lockout time.Duration
+    for i := 0; i < 20*lockoutAfterFailures; i++ {
+        if l := limiter.Failure("client"); l > 0 {
+            lockout = l
+            now = now.Add(l)
+        }
+    }
+    if lockout != maxLockout {
+        t.Fatalf("got lockout %s, want %s", lockout, maxLockout)
+    }
+}
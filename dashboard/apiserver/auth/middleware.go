@@ -0,0 +1,307 @@
+package auth
+
+import (
+    "math"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/logger"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Login methods recorded in the login audit trail.
+const (
+    MethodAPIToken    = "api_token"
+    MethodAPIKey      = "api_key"
+    MethodProxyHeader = "proxy_header"
+    MethodSession     = "session"
+)
+
+// SessionCookie holds the session of users signed in to the dashboard.
+const SessionCookie = "otr_session"
+
+// tokenAuditInterval limits how often a successful use of the same API token
+// is written to the login audit trail.
+const tokenAuditInterval = time.Hour
+
+// TokenLookup resolves the hash of a bearer token to the principal it was
+// issued to. It returns a nil principal for unknown, expired or revoked tokens.
+type TokenLookup func(hash string) (*Principal, error)
+
+// SessionLookup resolves the hash of a session cookie to the signed in user.
+// It returns a nil principal for unknown or expired sessions.
+type SessionLookup func(hash string) (*Principal, error)
+
+// LoginEvent is a single authentication attempt.
+type LoginEvent struct {
+    UserID    string
+    RemoteIP  string
+    UserAgent string
+    Method    string
+    Success   bool
+    Reason    string
+}
+
+// LoginRecorder persists login events for the audit trail.
+type LoginRecorder func(event LoginEvent)
+
+// Config holds the settings of an Authenticator.
+type Config struct {
+    // UserHeader is the header an authenticating reverse proxy uses to pass
+    // the signed in user. Users identified this way hold every scope unless
+    // roles are enforced.
+    UserHeader string
+
+    // Required rejects anonymous API requests when set. Otherwise anonymous
+    // callers may read, and keep the unrestricted access the dashboard
+    // always had only while roles are not enforced.
+    Required bool
+
+    // LookupRole is optional. When set, users get the scope of their role
+    // instead of every scope, and tokens are limited to it.
+    LookupRole RoleLookup
+
+    LookupToken TokenLookup
+
+    // LookupKey is optional. When set, API keys are accepted as bearer
+    // tokens too.
+    LookupKey TokenLookup
+
+    // LookupSession is optional, set when users can sign in to the
+    // dashboard.
+    LookupSession SessionLookup
+
+    // RecordLogin is optional.
+    RecordLogin LoginRecorder
+
+    // Captcha is optional. When set, clients that failed several times in a
+    // row must also send a valid CaptchaHeader.
+    Captcha CaptchaVerifier
+}
+
+// Authenticator identifies API callers and enforces scopes on routes.
+type Authenticator struct {
+    logger  logger.Logger
+    config  Config
+    limiter *LoginLimiter
+
+    mu           sync.Mutex
+    tokenAudited map[string]time.Time
+}
+
+// NewAuthenticator returns an authenticator for the given configuration.
+func NewAuthenticator(log logger.Logger, config Config) *Authenticator {
+    return &Authenticator{
+        logger:       log,
+        config:       config,
+        limiter:      NewLoginLimiter(),
+        tokenAudited: make(map[string]time.Time),
+    }
+}
+
+// Middleware authenticates the request from a bearer token, a session
+// cookie or the trusted proxy header and stores the resulting principal on
+// the context.
+func (a *Authenticator) Middleware() echo.MiddlewareFunc {
+    return func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(ctx echo.Context) error {
+            if header := ctx.Request().Header.Get(echo.HeaderAuthorization); header != "" {
+                return a.authenticateToken(ctx, header, next)
+            }
+
+            if cookie, err := ctx.Cookie(SessionCookie); err == nil && a.config.LookupSession != nil {
+                principal, err := a.config.LookupSession(HashToken(cookie.Value))
+                if err != nil {
+                    a.logger.Errorf("failed to look up session: %v", err)
+                    return apierror.New(http.StatusInternalServerError, "Failed to verify session")
+                }
+                if principal != nil {
+                    if !sameOrigin(ctx.Request()) {
+                        return apierror.New(http.StatusForbidden, "Cross-origin request refused")
+                    }
+                    return a.authenticated(ctx, principal, next)
+                }
+            }
+
+            if a.config.UserHeader != "" {
+                if userID := strings.TrimSpace(ctx.Request().Header.Get(a.config.UserHeader)); userID != "" {
+                    return a.authenticated(ctx, &Principal{
+                        UserID: userID,
+                        Scopes: []Scope{ScopeAdmin},
+                        Method: MethodProxyHeader,
+                    }, next)
+                }
+            }
+
+            if a.config.Required {
+                return apierror.New(http.StatusUnauthorized, "Authentication required")
+            }
+            return next(ctx)
+        }
+    }
+}
+
+// sameOrigin reports whether a request carrying a session cookie may act on
+// it. Browsers send the Origin of cross-site pages with changing requests,
+// those are refused so other sites cannot act with the user's session.
+func sameOrigin(req *http.Request) bool {
+    switch req.Method {
+    case http.MethodGet, http.MethodHead, http.MethodOptions:
+        return true
+    }
+    origin := req.Header.Get("Origin")
+    if origin == "" {
+        return true
+    }
+    parsed, err := url.Parse(origin)
+    return err == nil && parsed.Host == req.Host
+}
+
+func (a *Authenticator) authenticateToken(ctx echo.Context, header string, next echo.HandlerFunc) error {
+    remoteIP := ctx.RealIP()
+
+    if retryAfter, locked := a.limiter.Locked(remoteIP); locked {
+        ctx.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+        return apierror.New(http.StatusTooManyRequests, "Too many failed authentication attempts, try again later")
+    }
+
+    if a.config.Captcha != nil && a.limiter.NeedsCaptcha(remoteIP) {
+        ok, err := a.config.Captcha.Verify(ctx.Request().Context(), ctx.Request().Header.Get(CaptchaHeader), remoteIP)
+        if err != nil {
+            a.logger.Errorf("failed to verify CAPTCHA response: %v", err)
+            return apierror.New(http.StatusServiceUnavailable, "Failed to verify CAPTCHA response")
+        }
+        if !ok {
+            a.recordFailure(ctx, "", "captcha required")
+            return apierror.New(http.StatusUnauthorized, "CAPTCHA verification required").
+                WithDetails(map[string]interface{}{"captcha_required": true})
+        }
+    }
+
+    token, ok := BearerToken(header)
+    // API keys are resolved apart from personal tokens
+    lookup, method := a.config.LookupToken, MethodAPIToken
+    if ok && a.config.LookupKey != nil && strings.HasPrefix(token, KeyPrefix) {
+        lookup, method = a.config.LookupKey, MethodAPIKey
+    } else if !ok || !strings.HasPrefix(token, TokenPrefix) {
+        a.recordFailure(ctx, "", "malformed authorization header")
+        return apierror.New(http.StatusUnauthorized, "Malformed Authorization header")
+    }
+
+    principal, err := lookup(HashToken(token))
+    if err != nil {
+        a.logger.Errorf("failed to look up API token: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to verify API token")
+    }
+    if principal == nil {
+        a.recordFailure(ctx, "", "invalid or expired token")
+        return apierror.New(http.StatusUnauthorized, "Invalid or expired API token")
+    }
+
+    a.limiter.Success(remoteIP)
+    if a.shouldAuditToken(principal.Credential()) {
+        a.record(ctx, LoginEvent{UserID: principal.UserID, Method: method, Success: true})
+    }
+
+    return a.authenticated(ctx, principal, next)
+}
+
+// authenticated applies the role of an identified caller and hands the
+// request on.
+func (a *Authenticator) authenticated(ctx echo.Context, principal *Principal, next echo.HandlerFunc) error {
+    // API keys hold the scopes they were created with, having no user
+    if a.config.LookupRole != nil && principal.KeyID == 0 {
+        role, err := a.config.LookupRole(principal.UserID)
+        if err != nil {
+            a.logger.Errorf("failed to look up role of %s: %v", principal.UserID, err)
+            return apierror.New(http.StatusInternalServerError, "Failed to look up role")
+        }
+        principal.restrictTo(role)
+    }
+    SetPrincipal(ctx, principal)
+    return next(ctx)
+}
+
+func (a *Authenticator) recordFailure(ctx echo.Context, userID string, reason string) {
+    remoteIP := ctx.RealIP()
+    if lockout := a.limiter.Failure(remoteIP); lockout > 0 {
+        a.logger.Warnf("locking out %s for %s after repeated authentication failures", remoteIP, lockout)
+        reason += ", locked out for " + lockout.String()
+    }
+    a.record(ctx, LoginEvent{UserID: userID, Method: MethodAPIToken, Success: false, Reason: reason})
+}
+
+func (a *Authenticator) record(ctx echo.Context, event LoginEvent) {
+    if a.config.RecordLogin == nil {
+        return
+    }
+    event.RemoteIP = ctx.RealIP()
+    event.UserAgent = ctx.Request().UserAgent()
+    a.config.RecordLogin(event)
+}
+
+func (a *Authenticator) shouldAuditToken(credential string) bool {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    now := time.Now()
+    if last, ok := a.tokenAudited[credential]; ok && now.Sub(last) < tokenAuditInterval {
+        return false
+    }
+    a.tokenAudited[credential] = now
+    return true
+}
+
+// Prune releases memory held for stale failure and audit records.
+func (a *Authenticator) Prune() {
+    a.limiter.Prune()
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    for id, last := range a.tokenAudited {
+        if time.Since(last) >= tokenAuditInterval {
+            delete(a.tokenAudited, id)
+        }
+    }
+}
+
+// RequireScope rejects callers that do not hold the required scope.
+// Anonymous callers are only let through while authentication is optional,
+// and only to read once roles are enforced, as they would otherwise hold
+// more than a signed in viewer.
+func (a *Authenticator) RequireScope(required Scope) echo.MiddlewareFunc {
+    return func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(ctx echo.Context) error {
+            principal, ok := PrincipalFrom(ctx)
+            if !ok {
+                if a.config.Required || (a.config.LookupRole != nil && !ScopeRead.Implies(required)) {
+                    return apierror.New(http.StatusUnauthorized, "Authentication required")
+                }
+                return next(ctx)
+            }
+            if !principal.Has(required) {
+                return apierror.New(http.StatusForbidden, "This action requires the "+string(required)+" scope")
+            }
+            return next(ctx)
+        }
+    }
+}
+
+// RequireUser rejects anonymous callers regardless of configuration, for
+// endpoints that act on behalf of a specific user.
+func (a *Authenticator) RequireUser() echo.MiddlewareFunc {
+    return func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(ctx echo.Context) error {
+            if _, ok := PrincipalFrom(ctx); !ok {
+                return apierror.New(http.StatusUnauthorized, "Authentication required")
+            }
+            return next(ctx)
+        }
+    }
+}
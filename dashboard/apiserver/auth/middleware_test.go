@@ -0,0 +1,158 @@
+package auth
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "testing"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/logger"
+
+    "github.com/labstack/echo/v4"
+)
+
+func newTestEcho(t *testing.T, lookup TokenLookup) *echo.Echo {
+    t.Helper()
+    log, err := logger.NewLogger(logger.Error)
+    if err != nil {
+        t.Fatal(err)
+    }
+    e := echo.New()
+    e.HTTPErrorHandler = apierror.Handler(log)
+    e.IPExtractor = echo.ExtractIPDirect()
+    authenticator := NewAuthenticator(log, Config{Required: true, LookupToken: lookup})
+    e.Use(authenticator.Middleware())
+    e.GET("/api/threads", func(ctx echo.Context) error {
+        return ctx.NoContent(http.StatusOK)
+    })
+    return e
+}
+
+func serve(e *echo.Echo, remoteAddr, forwardedFor, token string) int {
+    req := httptest.NewRequest(http.MethodGet, "/api/threads", nil)
+    req.RemoteAddr = remoteAddr
+    if forwardedFor != "" {
+        req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+    }
+    req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+    rec := httptest.NewRecorder()
+    e.ServeHTTP(rec, req)
+    return rec.Code
+}
+
+func TestLockoutAfterRepeatedFailures(t *testing.T) {
+    good := TokenPrefix + "good"
+    e := newTestEcho(t, func(hash string) (*Principal, error) {
+        if hash == HashToken(good) {
+            return &Principal{UserID: "U1", Scopes: []Scope{ScopeAdmin}}, nil
+        }
+        return nil, nil
+    })
+
+    for i := 0; i < lockoutAfterFailures; i++ {
+        if code := serve(e, "203.0.113.7:5000", "", TokenPrefix+"bad"); code != http.StatusUnauthorized {
+            t.Fatalf("attempt %d: got status %d, want %d", i+1, code, http.StatusUnauthorized)
+        }
+    }
+    if code := serve(e, "203.0.113.7:5000", "", good); code != http.StatusTooManyRequests {
+        t.Fatalf("locked out client got status %d, want %d", code, http.StatusTooManyRequests)
+    }
+    if code := serve(e, "203.0.113.8:5000", "", good); code != http.StatusOK {
+        t.Fatalf("other client got status %d, want %d", code, http.StatusOK)
+    }
+}
+
+func TestLockoutIgnoresForwardedFor(t *testing.T) {
+    e := newTestEcho(t, func(hash string) (*Principal, error) { return nil, nil })
+
+    // Every attempt claims a different client, all come from one peer
+    for i := 0; i < lockoutAfterFailures; i++ {
+        forwardedFor := "198.51.100." + strconv.Itoa(i+1)
+        serve(e, "203.0.113.7:5000", forwardedFor, TokenPrefix+"bad")
+    }
+    if code := serve(e, "203.0.113.7:5000", "198.51.100.99", TokenPrefix+"bad"); code != http.StatusTooManyRequests {
+        t.Fatalf("spoofed X-Forwarded-For got status %d, want %d", code, http.StatusTooManyRequests)
+    }
+}
+
+func TestRequireScopeAnonymous(t *testing.T) {
+    log, err := logger.NewLogger(logger.Error)
+    if err != nil {
+        t.Fatal(err)
+    }
+    lookupRole := func(string) (Role, error) { return RoleAdmin, nil }
+
+    tests := []struct {
+        name     string
+        config   Config
+        required Scope
+        want     int
+    }{
+        {"optional auth without roles, read", Config{}, ScopeRead, http.StatusOK},
+        {"optional auth without roles, triage", Config{}, ScopeTriage, http.StatusOK},
+        {"optional auth with roles, read", Config{LookupRole: lookupRole}, ScopeRead, http.StatusOK},
+        {"optional auth with roles, triage", Config{LookupRole: lookupRole}, ScopeTriage, http.StatusUnauthorized},
+        {"optional auth with roles, admin", Config{LookupRole: lookupRole}, ScopeAdmin, http.StatusUnauthorized},
+        {"required auth, read", Config{Required: true, LookupRole: lookupRole}, ScopeRead, http.StatusUnauthorized},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            e := echo.New()
+            e.HTTPErrorHandler = apierror.Handler(log)
+            authenticator := NewAuthenticator(log, tt.config)
+            e.POST("/api/threads", func(ctx echo.Context) error {
+                return ctx.NoContent(http.StatusOK)
+            }, authenticator.RequireScope(tt.required))
+            rec := httptest.NewRecorder()
+            e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/threads", nil))
+            if rec.Code != tt.want {
+                t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+            }
+        })
+    }
+}
+
+func TestTokenScopes(t *testing.T) {
+    log, err := logger.NewLogger(logger.Error)
+    if err != nil {
+        t.Fatal(err)
+    }
+    reader := TokenPrefix + "reader"
+    authenticator := NewAuthenticator(log, Config{Required: true, LookupToken: func(hash string) (*Principal, error) {
+        if hash == HashToken(reader) {
+            return &Principal{UserID: "U1", Scopes: []Scope{ScopeRead}, TokenID: 1, Method: MethodAPIToken}, nil
+        }
+        return nil, nil
+    }})
+    e := echo.New()
+    e.HTTPErrorHandler = apierror.Handler(log)
+    e.Use(authenticator.Middleware())
+    ok := func(ctx echo.Context) error { return ctx.NoContent(http.StatusOK) }
+    e.GET("/api/threads", ok, authenticator.RequireScope(ScopeRead))
+    e.PATCH("/api/threads", ok, authenticator.RequireScope(ScopeTriage))
+
+    tests := []struct {
+        name          string
+        method        string
+        authorization string
+        want          int
+    }{
+        {"read with a read-only token", http.MethodGet, "Bearer " + reader, http.StatusOK},
+        {"triage with a read-only token", http.MethodPatch, "Bearer " + reader, http.StatusForbidden},
+        {"unknown token", http.MethodGet, "Bearer " + TokenPrefix + "unknown", http.StatusUnauthorized},
+        {"token without prefix", http.MethodGet, "Bearer reader", http.StatusUnauthorized},
+        {"other scheme", http.MethodGet, "Basic " + reader, http.StatusUnauthorized},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest(tt.method, "/api/threads", nil)
+            req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+            rec := httptest.NewRecorder()
+            e.ServeHTTP(rec, req)
+            if rec.Code != tt.want {
+                t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+// SlackIssuer is the OpenID Connect issuer of Sign in with Slack.
+const SlackIssuer = "https://slack.com"
+
+// Claims Slack adds to the user info of Sign in with Slack.
+const (
+    SlackUserClaim = "https://slack.com/user_id"
+    SlackTeamClaim = "https://slack.com/team_id"
+)
+
+// OIDCConfig holds the settings of an OpenID Connect sign-in.
+type OIDCConfig struct {
+    // Issuer is the provider, SlackIssuer for Sign in with Slack
+    Issuer       string
+    ClientID     string
+    ClientSecret string
+    // UserClaim names the user info claim used as the user ID, falling back
+    // to the subject when the provider does not send it
+    UserClaim string
+}
+
+// Identity is the user a provider signed in.
+type Identity struct {
+    UserID string
+    Name   string
+    Email  string
+    // Claims are all claims of the user info, e.g. to check the workspace
+    Claims map[string]interface{}
+}
+
+// Claim returns a string claim of the user info, empty when missing.
+func (i *Identity) Claim(name string) string {
+    value, _ := i.Claims[name].(string)
+    return value
+}
+
+type oidcEndpoints struct {
+    Authorization string `json:"authorization_endpoint"`
+    Token         string `json:"token_endpoint"`
+    UserInfo      string `json:"userinfo_endpoint"`
+}
+
+// OIDCProvider signs users in with the authorization code flow. The user is
+// taken from the user info endpoint, called with the access token received
+// straight from the provider, so no ID token has to be verified.
+type OIDCProvider struct {
+    config OIDCConfig
+    client *http.Client
+
+    mu        sync.Mutex
+    endpoints *oidcEndpoints
+}
+
+// NewOIDCProvider returns a provider for config. Endpoints are discovered on
+// first use.
+func NewOIDCProvider(config OIDCConfig) *OIDCProvider {
+    config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+    return &OIDCProvider{
+        config: config,
+        client: &http.Client{Timeout: 10 * time.Second},
+    }
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcEndpoints, error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if p.endpoints != nil {
+        return p.endpoints, nil
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Issuer+"/.well-known/openid-configuration", nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := p.client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("oidc discovery: status %d", resp.StatusCode)
+    }
+
+    var endpoints oidcEndpoints
+    if err := json.NewDecoder(resp.Body).Decode(&endpoints); err != nil {
+        return nil, err
+    }
+    if endpoints.Authorization == "" || endpoints.Token == "" || endpoints.UserInfo == "" {
+        return nil, errors.New("oidc discovery: provider lacks an authorization, token or userinfo endpoint")
+    }
+    p.endpoints = &endpoints
+    return p.endpoints, nil
+}
+
+// AuthCodeURL returns the address of the provider's sign-in page, coming
+// back to redirectURL with state.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, redirectURL string, state string) (string, error) {
+    endpoints, err := p.discover(ctx)
+    if err != nil {
+        return "", err
+    }
+    query := url.Values{}
+    query.Set("response_type", "code")
+    query.Set("client_id", p.config.ClientID)
+    query.Set("redirect_uri", redirectURL)
+    query.Set("scope", "openid profile email")
+    query.Set("state", state)
+
+    separator := "?"
+    if strings.Contains(endpoints.Authorization, "?") {
+        separator = "&"
+    }
+    return endpoints.Authorization + separator + query.Encode(), nil
+}
+
+// Exchange redeems the code the provider sent to redirectURL and returns
+// the signed in user.
+func (p *OIDCProvider) Exchange(ctx context.Context, redirectURL string, code string) (*Identity, error) {
+    endpoints, err := p.discover(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    form := url.Values{}
+    form.Set("grant_type", "authorization_code")
+    form.Set("code", code)
+    form.Set("redirect_uri", redirectURL)
+    form.Set("client_id", p.config.ClientID)
+    form.Set("client_secret", p.config.ClientSecret)
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoints.Token, strings.NewReader(form.Encode()))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.Header.Set("Accept", "application/json")
+
+    var token struct {
+        AccessToken string `json:"access_token"`
+        // Slack answers with status 200 and ok false on errors
+        OK    *bool  `json:"ok"`
+        Error string `json:"error"`
+    }
+    if err := p.do(req, &token); err != nil {
+        return nil, fmt.Errorf("oidc token: %w", err)
+    }
+    if token.Error != "" || (token.OK != nil && !*token.OK) || token.AccessToken == "" {
+        return nil, fmt.Errorf("oidc token: %s", token.Error)
+    }
+
+    req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoints.UserInfo, nil)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+    req.Header.Set("Accept", "application/json")
+
+    claims := map[string]interface{}{}
+    if err := p.do(req, &claims); err != nil {
+        return nil, fmt.Errorf("oidc userinfo: %w", err)
+    }
+    identity := &Identity{Claims: claims}
+    identity.Name = identity.Claim("name")
+    identity.Email = identity.Claim("email")
+    identity.UserID = identity.Claim(p.config.UserClaim)
+    if identity.UserID == "" {
+        identity.UserID = identity.Claim("sub")
+    }
+    if identity.UserID == "" {
+        return nil, errors.New("oidc userinfo: no user ID")
+    }
+    return identity, nil
+}
+
+func (p *OIDCProvider) do(req *http.Request, out interface{}) error {
+    resp, err := p.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("status %d", resp.StatusCode)
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Role is what a user may do on the dashboard. Roles are stored per user
+// and grant the scope of the same level, so routes only check scopes.
+type Role string
+
+const (
+    RoleViewer  Role = "viewer"
+    RoleTriager Role = "triager"
+    RoleAdmin   Role = "admin"
+)
+
+var roleScopes = map[Role]Scope{
+    RoleViewer:  ScopeRead,
+    RoleTriager: ScopeTriage,
+    RoleAdmin:   ScopeAdmin,
+}
+
+// RoleLookup returns the role of a user, the default role for users
+// without one.
+type RoleLookup func(userID string) (Role, error)
+
+// ParseRole validates a role name coming from a request, the database or
+// the configuration.
+func ParseRole(s string) (Role, error) {
+    role := Role(strings.TrimSpace(strings.ToLower(s)))
+    if _, ok := roleScopes[role]; !ok {
+        return "", fmt.Errorf("unknown role %q, expected one of viewer, triager, admin", s)
+    }
+    return role, nil
+}
+
+// Scope returns the scope granted by the role.
+func (r Role) Scope() Scope {
+    return roleScopes[r]
+}
+
+// restrictTo limits the principal to what role allows. Users signed in
+// through the proxy header or a session get the scope of their role,
+// tokens keep the scopes they were issued with up to it, so a demoted user's
+// tokens lose what the user lost.
+func (p *Principal) restrictTo(role Role) {
+    p.Role = role
+    if p.TokenID == 0 {
+        p.Scopes = []Scope{role.Scope()}
+        return
+    }
+    allowed := []Scope{}
+    for _, scope := range p.Scopes {
+        if role.Scope().Implies(scope) {
+            allowed = append(allowed, scope)
+        }
+    }
+    p.Scopes = allowed
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "strings"
+)
+
+// TokenPrefix marks personal API tokens so they are easy to spot in logs and
+// secret scanners.
+const TokenPrefix = "otr_"
+
+// KeyPrefix marks API keys owned by the dashboard, used by CI jobs and bots.
+const KeyPrefix = "otrk_"
+
+// SessionPrefix marks the session cookies of signed in dashboard users.
+const SessionPrefix = "otrs_"
+
+// GenerateToken returns a new random API token together with the hash that
+// is stored in the database. The plaintext token is only ever shown once.
+func GenerateToken() (token string, hash string, err error) {
+    return generateSecret(TokenPrefix)
+}
+
+// GenerateKey returns a new random API key together with the hash that is
+// stored in the database. The plaintext key is only ever shown once.
+func GenerateKey() (key string, hash string, err error) {
+    return generateSecret(KeyPrefix)
+}
+
+// GenerateSessionToken returns a new session cookie value and the hash that
+// is stored in the database.
+func GenerateSessionToken() (token string, hash string, err error) {
+    return generateSecret(SessionPrefix)
+}
+
+func generateSecret(prefix string) (string, string, error) {
+    buf := make([]byte, 32)
+    if _, err := rand.Read(buf); err != nil {
+        return "", "", err
+    }
+    token := prefix + base64.RawURLEncoding.EncodeToString(buf)
+    return token, HashToken(token), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a token.
+func HashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+// DisplayPrefix returns the non-secret leading part of a token used to let
+// users tell their tokens apart.
+func DisplayPrefix(token string) string {
+    if len(token) <= len(TokenPrefix)+6 {
+        return token
+    }
+    return token[:len(TokenPrefix)+6]
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer ..." header.
+func BearerToken(header string) (string, bool) {
+    scheme, token, found := strings.Cut(header, " ")
+    if !found || !strings.EqualFold(scheme, "Bearer") {
+        return "", false
+    }
+    token = strings.TrimSpace(token)
+    return token, token != ""
+}
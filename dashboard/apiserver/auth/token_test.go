@@ -0,0 +1,59 @@
+package auth
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestGenerateToken(t *testing.T) {
+    token, hash, err := GenerateToken()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !strings.HasPrefix(token, TokenPrefix) || hash != HashToken(token) {
+        t.Fatalf("got token %q with hash %q", token, hash)
+    }
+    if DisplayPrefix(token) != token[:len(TokenPrefix)+6] {
+        t.Fatalf("got display prefix %q", DisplayPrefix(token))
+    }
+    other, _, err := GenerateToken()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if other == token {
+        t.Fatal("generated the same token twice")
+    }
+}
+
+func TestBearerToken(t *testing.T) {
+    tests := []struct {
+        header string
+        token  string
+        ok     bool
+    }{
+        {"Bearer otr_abc", "otr_abc", true},
+        {"bearer  otr_abc ", "otr_abc", true},
+        {"Basic otr_abc", "", false},
+        {"Bearer ", "", false},
+        {"otr_abc", "", false},
+    }
+    for _, tt := range tests {
+        if token, ok := BearerToken(tt.header); token != tt.token || ok != tt.ok {
+            t.Errorf("BearerToken(%q) = %q, %v, want %q, %v", tt.header, token, ok, tt.token, tt.ok)
+        }
+    }
+}
+
+func TestScopesImply(t *testing.T) {
+    scopes, err := ParseScopes("read-only, Triage")
+    if err != nil {
+        t.Fatal(err)
+    }
+    principal := &Principal{Scopes: scopes}
+    if !principal.Has(ScopeRead) || !principal.Has(ScopeTriage) || principal.Has(ScopeAdmin) || principal.Highest() != ScopeTriage {
+        t.Fatalf("got scopes %v", scopes)
+    }
+    if _, err := ParseScopes("read-only,owner"); err == nil {
+        t.Fatal("parsed an unknown scope")
+    }
+}
@@ -0,0 +1,95 @@
+package chaos
+
+import (
+    "context"
+    "database/sql/driver"
+)
+
+// Connector wraps a database connector so that statements run through it
+// are delayed by the injected latency. A nil injector returns inner as is.
+func (i *Injector) Connector(inner driver.Connector) driver.Connector {
+    if i == nil {
+        return inner
+    }
+    return &connector{Connector: inner, injector: i}
+}
+
+type connector struct {
+    driver.Connector
+    injector *Injector
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+    conn, err := c.Connector.Connect(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return &slowConn{Conn: conn, injector: c.injector}, nil
+}
+
+// slowConn delays the statements executed directly on a connection and
+// pings.
+type slowConn struct {
+    driver.Conn
+    injector *Injector
+}
+
+func (c *slowConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+    queryer, ok := c.Conn.(driver.QueryerContext)
+    if !ok {
+        return nil, driver.ErrSkip
+    }
+    if err := c.injector.delay(ctx); err != nil {
+        return nil, err
+    }
+    return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *slowConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+    execer, ok := c.Conn.(driver.ExecerContext)
+    if !ok {
+        return nil, driver.ErrSkip
+    }
+    if err := c.injector.delay(ctx); err != nil {
+        return nil, err
+    }
+    return execer.ExecContext(ctx, query, args)
+}
+
+func (c *slowConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+    if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+        return preparer.PrepareContext(ctx, query)
+    }
+    return c.Conn.Prepare(query)
+}
+
+func (c *slowConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+    if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+        return beginner.BeginTx(ctx, opts)
+    }
+    return c.Conn.Begin()
+}
+
+func (c *slowConn) Ping(ctx context.Context) error {
+    if err := c.injector.delay(ctx); err != nil {
+        return err
+    }
+    if pinger, ok := c.Conn.(driver.Pinger); ok {
+        return pinger.Ping(ctx)
+    }
+    return nil
+}
+
+func (c *slowConn) ResetSession(ctx context.Context) error {
+    if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+        return resetter.ResetSession(ctx)
+    }
+    return nil
+}
+
+func (c *slowConn) IsValid() bool {
+    if validator, ok := c.Conn.(driver.Validator); ok {
+        return validator.IsValid()
+    }
+    return true
+}
@@ -0,0 +1,166 @@
+// Package chaos injects faults into the dashboard, latency into database
+// statements, failures into Slack calls and dropped Slack events, so that
+// operators can check the retries, circuit breakers and degraded modes
+// before relying on them. Faults are only injected for a limited time.
+package chaos
+
+import (
+    "context"
+    "errors"
+    "math/rand"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// Limits of the faults that may be injected.
+const (
+    MaxDuration  = time.Hour
+    MaxDBLatency = 30 * time.Second
+)
+
+// ErrInvalidFaults is returned for faults outside of the limits.
+var ErrInvalidFaults = errors.New("duration must be at most 1h, db_latency at most 30s and percentages from 0 to 100")
+
+// Faults are what is injected. Percentages are of Slack calls failed and of
+// Slack events dropped.
+type Faults struct {
+    DBLatency           time.Duration
+    SlackFailurePercent float64
+    EventDropPercent    float64
+}
+
+// Status is the state of the current or last injection.
+type Status struct {
+    Active              bool       `json:"active"`
+    StartedBy           string     `json:"started_by,omitempty"`
+    StartedAt           *time.Time `json:"started_at,omitempty"`
+    ExpiresAt           *time.Time `json:"expires_at,omitempty"`
+    DBLatencyMS         int64      `json:"db_latency_ms"`
+    SlackFailurePercent float64    `json:"slack_failure_percent"`
+    EventDropPercent    float64    `json:"event_drop_percent"`
+    // Counts of the faults injected since started
+    DelayedStatements  int64 `json:"delayed_statements"`
+    FailedSlackCalls   int64 `json:"failed_slack_calls"`
+    DroppedSlackEvents int64 `json:"dropped_slack_events"`
+}
+
+// Injector holds the faults to inject. A nil injector injects nothing.
+type Injector struct {
+    mu        sync.Mutex
+    faults    Faults
+    startedBy string
+    startedAt time.Time
+    expiresAt time.Time
+
+    delayed atomic.Int64
+    failed  atomic.Int64
+    dropped atomic.Int64
+}
+
+// NewInjector returns an injector with no faults.
+func NewInjector() *Injector {
+    return &Injector{}
+}
+
+// Start injects faults for d, replacing the faults being injected.
+func (i *Injector) Start(actor string, faults Faults, d time.Duration) (Status, error) {
+    if d <= 0 || d > MaxDuration || faults.DBLatency < 0 || faults.DBLatency > MaxDBLatency ||
+        !validPercent(faults.SlackFailurePercent) || !validPercent(faults.EventDropPercent) {
+        return Status{}, ErrInvalidFaults
+    }
+    i.mu.Lock()
+    defer i.mu.Unlock()
+    now := time.Now().UTC()
+    i.faults = faults
+    i.startedBy = actor
+    i.startedAt = now
+    i.expiresAt = now.Add(d)
+    i.delayed.Store(0)
+    i.failed.Store(0)
+    i.dropped.Store(0)
+    return i.status(now), nil
+}
+
+// Stop ends the injection early.
+func (i *Injector) Stop() Status {
+    i.mu.Lock()
+    defer i.mu.Unlock()
+    now := time.Now().UTC()
+    if now.Before(i.expiresAt) {
+        i.expiresAt = now
+    }
+    return i.status(now)
+}
+
+// Status returns the state of the current or last injection.
+func (i *Injector) Status() Status {
+    i.mu.Lock()
+    defer i.mu.Unlock()
+    return i.status(time.Now().UTC())
+}
+
+func (i *Injector) status(now time.Time) Status {
+    status := Status{
+        Active:              now.Before(i.expiresAt),
+        StartedBy:           i.startedBy,
+        DBLatencyMS:         i.faults.DBLatency.Milliseconds(),
+        SlackFailurePercent: i.faults.SlackFailurePercent,
+        EventDropPercent:    i.faults.EventDropPercent,
+        DelayedStatements:   i.delayed.Load(),
+        FailedSlackCalls:    i.failed.Load(),
+        DroppedSlackEvents:  i.dropped.Load(),
+    }
+    if !i.startedAt.IsZero() {
+        startedAt, expiresAt := i.startedAt, i.expiresAt
+        status.StartedAt, status.ExpiresAt = &startedAt, &expiresAt
+    }
+    return status
+}
+
+// current returns the faults to inject now, none once expired.
+func (i *Injector) current() Faults {
+    if i == nil {
+        return Faults{}
+    }
+    i.mu.Lock()
+    defer i.mu.Unlock()
+    if !time.Now().Before(i.expiresAt) {
+        return Faults{}
+    }
+    return i.faults
+}
+
+func validPercent(p float64) bool {
+    return p >= 0 && p <= 100
+}
+
+func hit(percent float64) bool {
+    return percent > 0 && rand.Float64()*100 < percent
+}
+
+// delay waits for the injected database latency, or until ctx is done.
+func (i *Injector) delay(ctx context.Context) error {
+    latency := i.current().DBLatency
+    if latency <= 0 {
+        return nil
+    }
+    i.delayed.Add(1)
+    timer := time.NewTimer(latency)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
+
+// DropEvent reports whether a Slack event should be dropped.
+func (i *Injector) DropEvent() bool {
+    if !hit(i.current().EventDropPercent) {
+        return false
+    }
+    i.dropped.Add(1)
+    return true
+}
@@ -0,0 +1,34 @@
+package chaos
+
+import (
+    "errors"
+    "net/http"
+)
+
+// ErrInjected is the error of the calls failed on purpose.
+var ErrInjected = errors.New("failure injected by chaos testing")
+
+// Transport wraps an HTTP transport so that the injected percentage of
+// requests fails without being sent. A nil injector returns inner as is.
+func (i *Injector) Transport(inner http.RoundTripper) http.RoundTripper {
+    if i == nil {
+        return inner
+    }
+    return &failingTransport{inner: inner, injector: i}
+}
+
+type failingTransport struct {
+    inner    http.RoundTripper
+    injector *Injector
+}
+
+func (t *failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    if hit(t.injector.current().SlackFailurePercent) {
+        t.injector.failed.Add(1)
+        if req.Body != nil {
+            req.Body.Close()
+        }
+        return nil, ErrInjected
+    }
+    return t.inner.RoundTrip(req)
+}
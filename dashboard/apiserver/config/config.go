@@ -0,0 +1,266 @@
+// Package config loads the database settings of the dashboard from an
+// optional JSON or YAML file, overridden by environment variables.
+//
+// Multi-tenant deployments can keep the threads of a workspace in another
+// database, configured under workspaces.<team_id> with the keys of database,
+// or with YB_OPEN_THREADS_REMINDER_WORKSPACE_<team_id>_DB_DSN. Settings a
+// workspace leaves out are those of database.
+package config
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable holding the config file path.
+const FileEnv = "YB_OPEN_THREADS_REMINDER_CONFIG_FILE"
+
+// Database holds the connection and pool settings of the database. DSN,
+// a libpq URL or connection string, replaces the connection settings.
+type Database struct {
+    DSN             string
+    Host            string
+    Port            int
+    User            string
+    Password        string
+    DBName          string
+    SSLMode         string
+    ConnectTimeout  time.Duration
+    MaxOpenConns    int
+    MaxIdleConns    int
+    ConnMaxLifetime time.Duration
+}
+
+// Config is the file and environment configuration.
+type Config struct {
+    Database Database
+    // Workspaces holds the databases of workspaces whose threads are not
+    // stored in Database, keyed by Slack team ID
+    Workspaces map[string]Database
+}
+
+// Workspace database settings in the file and the environment.
+const (
+    workspacesKey   = "workspaces."
+    workspaceEnv    = "YB_OPEN_THREADS_REMINDER_WORKSPACE_"
+    workspaceDSNEnv = "_DB_DSN"
+)
+
+// Default returns the settings used when neither the file nor the
+// environment sets them.
+func Default() Config {
+    return Config{Database: Database{
+        Host:            "127.0.0.1",
+        Port:            5433,
+        User:            "yugabyte",
+        DBName:          "open_thread_db",
+        SSLMode:         "disable",
+        ConnectTimeout:  10 * time.Second,
+        MaxOpenConns:    20,
+        MaxIdleConns:    5,
+        ConnMaxLifetime: 30 * time.Minute,
+    }}
+}
+
+// field is a setting addressable as a file key and an environment variable.
+type field struct {
+    key string
+    env string
+    set func(c *Config, value string) error
+}
+
+func stringField(key, env string, target func(c *Config) *string) field {
+    return field{key, env, func(c *Config, value string) error {
+        *target(c) = value
+        return nil
+    }}
+}
+
+func intField(key, env string, target func(c *Config) *int) field {
+    return field{key, env, func(c *Config, value string) error {
+        n, err := strconv.Atoi(value)
+        if err != nil || n < 0 {
+            return fmt.Errorf("%s must be a non-negative integer", key)
+        }
+        *target(c) = n
+        return nil
+    }}
+}
+
+func durationField(key, env string, target func(c *Config) *time.Duration) field {
+    return field{key, env, func(c *Config, value string) error {
+        d, err := time.ParseDuration(value)
+        if err != nil || d < 0 {
+            return fmt.Errorf("%s must be a duration such as 30m", key)
+        }
+        *target(c) = d
+        return nil
+    }}
+}
+
+var fields = []field{
+    stringField("database.dsn", "YB_OPEN_THREADS_REMINDER_DB_DSN", func(c *Config) *string { return &c.Database.DSN }),
+    stringField("database.host", "YB_OPEN_THREADS_REMINDER_DB_HOST", func(c *Config) *string { return &c.Database.Host }),
+    intField("database.port", "YB_OPEN_THREADS_REMINDER_DB_PORT", func(c *Config) *int { return &c.Database.Port }),
+    stringField("database.user", "YB_OPEN_THREADS_REMINDER_DB_USER", func(c *Config) *string { return &c.Database.User }),
+    stringField("database.password", "YB_OPEN_THREADS_REMINDER_DB_PASSWORD", func(c *Config) *string { return &c.Database.Password }),
+    stringField("database.dbname", "YB_OPEN_THREADS_REMINDER_DB_NAME", func(c *Config) *string { return &c.Database.DBName }),
+    stringField("database.sslmode", "YB_OPEN_THREADS_REMINDER_DB_SSLMODE", func(c *Config) *string { return &c.Database.SSLMode }),
+    durationField("database.connect_timeout", "YB_OPEN_THREADS_REMINDER_DB_CONNECT_TIMEOUT", func(c *Config) *time.Duration { return &c.Database.ConnectTimeout }),
+    intField("database.max_open_conns", "YB_OPEN_THREADS_REMINDER_DB_MAX_OPEN_CONNS", func(c *Config) *int { return &c.Database.MaxOpenConns }),
+    intField("database.max_idle_conns", "YB_OPEN_THREADS_REMINDER_DB_MAX_IDLE_CONNS", func(c *Config) *int { return &c.Database.MaxIdleConns }),
+    durationField("database.conn_max_lifetime", "YB_OPEN_THREADS_REMINDER_DB_CONN_MAX_LIFETIME", func(c *Config) *time.Duration { return &c.Database.ConnMaxLifetime }),
+}
+
+// Load returns the defaults, overridden by the file named by FileEnv if
+// set, overridden by the environment.
+func Load() (Config, error) {
+    c := Default()
+    workspaces := make(map[string]map[string]string)
+
+    if path := os.Getenv(FileEnv); path != "" {
+        values, err := readFile(path)
+        if err != nil {
+            return c, err
+        }
+        known := make(map[string]bool, len(fields))
+        for _, f := range fields {
+            known[f.key] = true
+            if value, ok := values[f.key]; ok {
+                if err := f.set(&c, value); err != nil {
+                    return c, fmt.Errorf("%s: %w", path, err)
+                }
+            }
+        }
+        for key, value := range values {
+            if strings.HasPrefix(key, workspacesKey) {
+                team, setting, ok := strings.Cut(strings.TrimPrefix(key, workspacesKey), ".")
+                if !ok || team == "" || !known["database."+setting] {
+                    return c, fmt.Errorf("%s: unknown setting %s", path, key)
+                }
+                if workspaces[team] == nil {
+                    workspaces[team] = make(map[string]string)
+                }
+                workspaces[team]["database."+setting] = value
+                continue
+            }
+            if !known[key] {
+                return c, fmt.Errorf("%s: unknown setting %s", path, key)
+            }
+        }
+    }
+
+    for _, f := range fields {
+        if value, ok := os.LookupEnv(f.env); ok {
+            if err := f.set(&c, value); err != nil {
+                return c, fmt.Errorf("%s: %w", f.env, err)
+            }
+        }
+    }
+
+    for _, env := range os.Environ() {
+        name, value, _ := strings.Cut(env, "=")
+        if !strings.HasPrefix(name, workspaceEnv) || !strings.HasSuffix(name, workspaceDSNEnv) {
+            continue
+        }
+        team := strings.TrimSuffix(strings.TrimPrefix(name, workspaceEnv), workspaceDSNEnv)
+        if team == "" {
+            continue
+        }
+        if workspaces[team] == nil {
+            workspaces[team] = make(map[string]string)
+        }
+        workspaces[team]["database.dsn"] = value
+    }
+
+    // Workspaces start from the database settings, so they only need to
+    // set what differs
+    c.Workspaces = make(map[string]Database, len(workspaces))
+    for team, values := range workspaces {
+        workspace := Config{Database: c.Database}
+        for _, f := range fields {
+            if value, ok := values[f.key]; ok {
+                if err := f.set(&workspace, value); err != nil {
+                    return c, fmt.Errorf("workspace %s: %w", team, err)
+                }
+            }
+        }
+        c.Workspaces[team] = workspace.Database
+    }
+    return c, nil
+}
+
+// readFile returns the settings of a JSON or YAML file keyed by their
+// dotted path, e.g. "database.host".
+func readFile(path string) (map[string]string, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    var tree map[string]interface{}
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".json":
+        decoder := json.NewDecoder(bytes.NewReader(data))
+        decoder.UseNumber()
+        err = decoder.Decode(&tree)
+    case ".yaml", ".yml":
+        err = yaml.Unmarshal(data, &tree)
+    default:
+        return nil, fmt.Errorf("%s: config file must end in .json, .yaml or .yml", path)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    values := make(map[string]string)
+    flatten("", tree, values)
+    return values, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, values map[string]string) {
+    for key, value := range tree {
+        if prefix != "" {
+            key = prefix + "." + key
+        }
+        switch v := value.(type) {
+        case map[string]interface{}:
+            flatten(key, v, values)
+        case nil:
+        default:
+            values[key] = fmt.Sprint(v)
+        }
+    }
+}
+
+// ConnString returns the libpq connection string of the database.
+func (d Database) ConnString() string {
+    if d.DSN != "" {
+        return d.DSN
+    }
+    params := []struct{ key, value string }{
+        {"host", d.Host},
+        {"port", strconv.Itoa(d.Port)},
+        {"user", d.User},
+        {"password", d.Password},
+        {"dbname", d.DBName},
+        {"sslmode", d.SSLMode},
+        {"connect_timeout", strconv.Itoa(int(d.ConnectTimeout.Seconds()))},
+    }
+    parts := []string{}
+    for _, p := range params {
+        if p.value == "" {
+            continue
+        }
+        quoted := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(p.value)
+        parts = append(parts, fmt.Sprintf("%s='%s'", p.key, quoted))
+    }
+    return strings.Join(parts, " ")
+}
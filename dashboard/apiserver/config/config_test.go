@@ -0,0 +1,150 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// clearEnv unsets the settings of the environment for the duration of the
+// test.
+func clearEnv(t *testing.T) {
+    for _, env := range os.Environ() {
+        name, _, _ := strings.Cut(env, "=")
+        if strings.HasPrefix(name, "YB_OPEN_THREADS_REMINDER_") {
+            t.Setenv(name, "")
+            os.Unsetenv(name)
+        }
+    }
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), name)
+    if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+func TestLoadFile(t *testing.T) {
+    tests := []struct {
+        name    string
+        file    string
+        content string
+        want    func(c Config) bool
+        err     string
+    }{
+        {
+            name: "yaml block mapping",
+            file: "config.yaml",
+            content: `
+# Production database
+database:
+  host: db.internal   # primary
+  port: 5434
+  password: "p@ss # not a comment"
+  connect_timeout: 5s
+`,
+            want: func(c Config) bool {
+                return c.Database.Host == "db.internal" && c.Database.Port == 5434 &&
+                    c.Database.Password == "p@ss # not a comment" && c.Database.ConnectTimeout == 5*time.Second
+            },
+        },
+        {
+            name:    "yaml flow mapping",
+            file:    "config.yml",
+            content: `database: {host: db.internal, port: 5434, sslmode: 'require'}`,
+            want: func(c Config) bool {
+                return c.Database.Host == "db.internal" && c.Database.Port == 5434 && c.Database.SSLMode == "require"
+            },
+        },
+        {
+            name:    "yaml keeps defaults",
+            file:    "config.yaml",
+            content: "database:\n  dbname: threads\n",
+            want: func(c Config) bool {
+                return c.Database.DBName == "threads" && c.Database.Host == Default().Database.Host
+            },
+        },
+        {
+            name:    "json",
+            file:    "config.json",
+            content: `{"database": {"host": "db.internal", "max_open_conns": 40}}`,
+            want: func(c Config) bool {
+                return c.Database.Host == "db.internal" && c.Database.MaxOpenConns == 40
+            },
+        },
+        {
+            name: "workspace inherits the database",
+            file: "config.yaml",
+            content: `
+database:
+  host: db.internal
+workspaces:
+  T123:
+    dbname: tenant
+`,
+            want: func(c Config) bool {
+                w := c.Workspaces["T123"]
+                return w.Host == "db.internal" && w.DBName == "tenant"
+            },
+        },
+        {name: "unknown setting", file: "config.yaml", content: "database:\n  hostname: db\n", err: "unknown setting database.hostname"},
+        {name: "unknown workspace setting", file: "config.yaml", content: "workspaces:\n  T1:\n    pool: 3\n", err: "unknown setting workspaces.T1.pool"},
+        {name: "invalid port", file: "config.yaml", content: "database:\n  port: many\n", err: "database.port"},
+        {name: "invalid yaml", file: "config.yaml", content: "database:\n  host: [db\n", err: "config.yaml"},
+        {name: "invalid json", file: "config.json", content: `{"database": `, err: "config.json"},
+        {name: "unknown extension", file: "config.toml", content: "", err: "must end in .json, .yaml or .yml"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            clearEnv(t)
+            t.Setenv(FileEnv, writeFile(t, tt.file, tt.content))
+            c, err := Load()
+            if tt.err != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.err) {
+                    t.Fatalf("got error %v, want %q", err, tt.err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !tt.want(c) {
+                t.Fatalf("got %+v", c)
+            }
+        })
+    }
+}
+
+func TestLoadEnvironmentOverridesFile(t *testing.T) {
+    clearEnv(t)
+    t.Setenv(FileEnv, writeFile(t, "config.yaml", "database:\n  host: file.internal\n  port: 5434\n"))
+    t.Setenv("YB_OPEN_THREADS_REMINDER_DB_HOST", "env.internal")
+    t.Setenv("YB_OPEN_THREADS_REMINDER_WORKSPACE_T9_DB_DSN", "host=tenant.internal")
+
+    c, err := Load()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if c.Database.Host != "env.internal" || c.Database.Port != 5434 {
+        t.Fatalf("got %s:%d, want the host of the environment and the port of the file", c.Database.Host, c.Database.Port)
+    }
+    if got := c.Workspaces["T9"].ConnString(); got != "host=tenant.internal" {
+        t.Fatalf("got workspace connection %q", got)
+    }
+}
+
+func TestConnString(t *testing.T) {
+    d := Default().Database
+    d.Password = `it's\secret`
+    got := d.ConnString()
+    for _, part := range []string{"host='127.0.0.1'", "port='5433'", `password='it\'s\\secret'`, "connect_timeout='10'"} {
+        if !strings.Contains(got, part) {
+            t.Errorf("%q does not contain %s", got, part)
+        }
+    }
+}
@@ -0,0 +1,50 @@
+package debugbundle
+
+import (
+    "archive/zip"
+    "encoding/json"
+    "io"
+    "runtime"
+    "time"
+)
+
+// Manifest describes a bundle.
+type Manifest struct {
+    GeneratedAt time.Time `json:"generated_at"`
+    GoVersion   string    `json:"go_version"`
+    Recording   Status    `json:"recording"`
+}
+
+// WriteBundle writes the current or last recording to w as a zip archive
+// holding manifest.json, exchanges.json and slow_queries.json.
+func (r *Recorder) WriteBundle(w io.Writer) error {
+    status, exchanges, queries := r.snapshot()
+    if exchanges == nil {
+        exchanges = []Exchange{}
+    }
+    if queries == nil {
+        queries = []QueryTrace{}
+    }
+
+    archive := zip.NewWriter(w)
+    files := []struct {
+        name    string
+        content interface{}
+    }{
+        {"manifest.json", Manifest{GeneratedAt: time.Now().UTC(), GoVersion: runtime.Version(), Recording: status}},
+        {"exchanges.json", exchanges},
+        {"slow_queries.json", queries},
+    }
+    for _, file := range files {
+        fw, err := archive.Create(file.name)
+        if err != nil {
+            return err
+        }
+        encoder := json.NewEncoder(fw)
+        encoder.SetIndent("", "  ")
+        if err := encoder.Encode(file.content); err != nil {
+            return err
+        }
+    }
+    return archive.Close()
+}
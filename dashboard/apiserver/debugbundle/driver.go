@@ -0,0 +1,94 @@
+package debugbundle
+
+import (
+    "context"
+    "database/sql/driver"
+    "time"
+)
+
+// Connector wraps a database connector so that statements run through it
+// are traced while a recording is running.
+func (r *Recorder) Connector(inner driver.Connector) driver.Connector {
+    return &connector{Connector: inner, recorder: r}
+}
+
+type connector struct {
+    driver.Connector
+    recorder *Recorder
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+    conn, err := c.Connector.Connect(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return &tracedConn{Conn: conn, recorder: c.recorder}, nil
+}
+
+// tracedConn times the statements executed directly on a connection.
+// Query durations cover the time to the first row, not reading the result.
+type tracedConn struct {
+    driver.Conn
+    recorder *Recorder
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+    queryer, ok := c.Conn.(driver.QueryerContext)
+    if !ok {
+        return nil, driver.ErrSkip
+    }
+    started := time.Now()
+    rows, err := queryer.QueryContext(ctx, query, args)
+    if err != driver.ErrSkip {
+        c.recorder.traceQuery(query, len(args), started, err)
+    }
+    return rows, err
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+    execer, ok := c.Conn.(driver.ExecerContext)
+    if !ok {
+        return nil, driver.ErrSkip
+    }
+    started := time.Now()
+    result, err := execer.ExecContext(ctx, query, args)
+    if err != driver.ErrSkip {
+        c.recorder.traceQuery(query, len(args), started, err)
+    }
+    return result, err
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+    if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+        return preparer.PrepareContext(ctx, query)
+    }
+    return c.Conn.Prepare(query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+    if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+        return beginner.BeginTx(ctx, opts)
+    }
+    return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+    if pinger, ok := c.Conn.(driver.Pinger); ok {
+        return pinger.Ping(ctx)
+    }
+    return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+    if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+        return resetter.ResetSession(ctx)
+    }
+    return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+    if validator, ok := c.Conn.(driver.Validator); ok {
+        return validator.IsValid()
+    }
+    return true
+}
@@ -0,0 +1,100 @@
+package debugbundle
+
+import (
+    "bufio"
+    "bytes"
+    "io"
+    "net"
+    "net/http"
+    "time"
+
+    "github.com/labstack/echo/v4"
+)
+
+// captureWriter keeps the first maxBodyBytes of a response while passing
+// it through.
+type captureWriter struct {
+    http.ResponseWriter
+    body      bytes.Buffer
+    truncated bool
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+    if room := maxBodyBytes - w.body.Len(); room > 0 {
+        if len(b) > room {
+            w.body.Write(b[:room])
+            w.truncated = true
+        } else {
+            w.body.Write(b)
+        }
+    } else if len(b) > 0 {
+        w.truncated = true
+    }
+    return w.ResponseWriter.Write(b)
+}
+
+// Hijack hands over the connection for WebSockets, whose traffic is not
+// recorded.
+func (w *captureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *captureWriter) Flush() {
+    if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+        flusher.Flush()
+    }
+}
+
+// Middleware records every exchange while a recording is running. It costs
+// nothing otherwise.
+func (r *Recorder) Middleware() echo.MiddlewareFunc {
+    return func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(ctx echo.Context) error {
+            if !r.Active() {
+                return next(ctx)
+            }
+
+            req := ctx.Request()
+            var requestBody []byte
+            requestTruncated := false
+            if req.Body != nil {
+                requestBody, _ = io.ReadAll(io.LimitReader(req.Body, maxBodyBytes+1))
+                requestTruncated = len(requestBody) > maxBodyBytes
+                // Hand the handler the complete body again
+                req.Body = struct {
+                    io.Reader
+                    io.Closer
+                }{io.MultiReader(bytes.NewReader(requestBody), req.Body), req.Body}
+                if requestTruncated {
+                    requestBody = requestBody[:maxBodyBytes]
+                }
+            }
+
+            resp := ctx.Response()
+            capture := &captureWriter{ResponseWriter: resp.Writer}
+            resp.Writer = capture
+            defer func() { resp.Writer = capture.ResponseWriter }()
+
+            started := time.Now()
+            err := next(ctx)
+            if err != nil {
+                // Let echo write the error now so its response is recorded
+                // too, handling it again later is a no-op
+                ctx.Error(err)
+            }
+
+            r.addExchange(Exchange{
+                Time:            started.UTC(),
+                Method:          req.Method,
+                URI:             sanitizeURI(req.RequestURI),
+                RequestHeaders:  sanitizeHeaders(req.Header),
+                RequestBody:     sanitizeBody(req.Header.Get(echo.HeaderContentType), requestBody, requestTruncated),
+                Status:          resp.Status,
+                ResponseHeaders: sanitizeHeaders(resp.Header()),
+                ResponseBody:    sanitizeBody(resp.Header().Get(echo.HeaderContentType), capture.body.Bytes(), capture.truncated),
+                LatencyMS:       float64(time.Since(started).Microseconds()) / 1000,
+            })
+            return err
+        }
+    }
+}
@@ -0,0 +1,197 @@
+// Package debugbundle records sanitized API exchanges and slow database
+// queries for a limited time, so they can be downloaded as a bundle and
+// attached to bug reports.
+package debugbundle
+
+import (
+    "errors"
+    "sync"
+    "time"
+)
+
+// MaxDuration caps how long a recording may run.
+const MaxDuration = time.Hour
+
+const (
+    maxExchanges = 1000
+    maxQueries   = 1000
+)
+
+// ErrInvalidDuration is returned for recording windows outside
+// (0, MaxDuration].
+var ErrInvalidDuration = errors.New("recording duration must be positive and at most one hour")
+
+// Exchange is a recorded request and its response.
+type Exchange struct {
+    Time            time.Time           `json:"time"`
+    Method          string              `json:"method"`
+    URI             string              `json:"uri"`
+    RequestHeaders  map[string][]string `json:"request_headers"`
+    RequestBody     string              `json:"request_body"`
+    Status          int                 `json:"status"`
+    ResponseHeaders map[string][]string `json:"response_headers"`
+    ResponseBody    string              `json:"response_body"`
+    LatencyMS       float64             `json:"latency_ms"`
+}
+
+// QueryTrace is a database statement slower than the recording threshold.
+// Arguments are never kept, only their count.
+type QueryTrace struct {
+    Time       time.Time `json:"time"`
+    Query      string    `json:"query"`
+    Args       int       `json:"args"`
+    DurationMS float64   `json:"duration_ms"`
+    Error      string    `json:"error,omitempty"`
+}
+
+// Status describes the current or last recording.
+type Status struct {
+    Active             bool       `json:"active"`
+    StartedBy          string     `json:"started_by,omitempty"`
+    StartedAt          *time.Time `json:"started_at,omitempty"`
+    EndsAt             *time.Time `json:"ends_at,omitempty"`
+    SlowQueryThreshold string     `json:"slow_query_threshold,omitempty"`
+    Exchanges          int        `json:"exchanges"`
+    Queries            int        `json:"queries"`
+    Dropped            int        `json:"dropped"`
+}
+
+// Recorder keeps the exchanges and slow queries of one recording window in
+// memory. Starting a new recording discards the previous one.
+type Recorder struct {
+    mu        sync.Mutex
+    startedBy string
+    startedAt time.Time
+    endsAt    time.Time
+    slowQuery time.Duration
+    exchanges []Exchange
+    queries   []QueryTrace
+    dropped   int
+}
+
+// NewRecorder returns an idle recorder.
+func NewRecorder() *Recorder {
+    return &Recorder{}
+}
+
+// Start begins a recording of duration d, tracing queries slower than
+// slowQuery.
+func (r *Recorder) Start(actor string, d time.Duration, slowQuery time.Duration) (Status, error) {
+    if d <= 0 || d > MaxDuration {
+        return Status{}, ErrInvalidDuration
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    now := time.Now().UTC()
+    r.startedBy = actor
+    r.startedAt = now
+    r.endsAt = now.Add(d)
+    r.slowQuery = slowQuery
+    r.exchanges = nil
+    r.queries = nil
+    r.dropped = 0
+    return r.status(now), nil
+}
+
+// Stop ends the running recording early. What was captured is kept for the
+// bundle.
+func (r *Recorder) Stop() Status {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    now := time.Now().UTC()
+    if now.Before(r.endsAt) {
+        r.endsAt = now
+    }
+    return r.status(now)
+}
+
+// Status returns the state of the current or last recording.
+func (r *Recorder) Status() Status {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return r.status(time.Now())
+}
+
+func (r *Recorder) status(now time.Time) Status {
+    status := Status{
+        Active:    r.active(now),
+        StartedBy: r.startedBy,
+        Exchanges: len(r.exchanges),
+        Queries:   len(r.queries),
+        Dropped:   r.dropped,
+    }
+    if !r.startedAt.IsZero() {
+        startedAt, endsAt := r.startedAt, r.endsAt
+        status.StartedAt = &startedAt
+        status.EndsAt = &endsAt
+        status.SlowQueryThreshold = r.slowQuery.String()
+    }
+    return status
+}
+
+func (r *Recorder) active(now time.Time) bool {
+    return now.Before(r.endsAt)
+}
+
+// Active reports whether a recording is running.
+func (r *Recorder) Active() bool {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return r.active(time.Now())
+}
+
+func (r *Recorder) addExchange(exchange Exchange) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if !r.active(time.Now()) {
+        return
+    }
+    if len(r.exchanges) >= maxExchanges {
+        r.dropped++
+        return
+    }
+    r.exchanges = append(r.exchanges, exchange)
+}
+
+// traceQuery records a statement if a recording is running and it took at
+// least the slow query threshold.
+func (r *Recorder) traceQuery(query string, args int, started time.Time, err error) {
+    elapsed := time.Since(started)
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if !r.active(time.Now()) || elapsed < r.slowQuery {
+        return
+    }
+    if len(r.queries) >= maxQueries {
+        r.dropped++
+        return
+    }
+    trace := QueryTrace{
+        Time:       started.UTC(),
+        Query:      query,
+        Args:       args,
+        DurationMS: float64(elapsed.Microseconds()) / 1000,
+    }
+    if err != nil {
+        trace.Error = err.Error()
+    }
+    r.queries = append(r.queries, trace)
+}
+
+// snapshot returns copies of what was recorded.
+func (r *Recorder) snapshot() (Status, []Exchange, []QueryTrace) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    exchanges := append([]Exchange(nil), r.exchanges...)
+    queries := append([]QueryTrace(nil), r.queries...)
+    return r.status(time.Now()), exchanges, queries
+}
@@ -0,0 +1,97 @@
+package debugbundle
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+)
+
+const (
+    maxBodyBytes = 16 << 10
+    redacted     = "[redacted]"
+)
+
+// sensitiveWords mark header, query parameter and JSON field names whose
+// values are never recorded.
+var sensitiveWords = []string{"authorization", "cookie", "token", "secret", "password", "signature", "sig", "key", "captcha", "email"}
+
+func sensitive(name string) bool {
+    name = strings.ToLower(name)
+    for _, word := range sensitiveWords {
+        if strings.Contains(name, word) {
+            return true
+        }
+    }
+    return false
+}
+
+func sanitizeHeaders(header http.Header) map[string][]string {
+    sanitized := make(map[string][]string, len(header))
+    for name, values := range header {
+        if sensitive(name) {
+            sanitized[name] = []string{redacted}
+            continue
+        }
+        sanitized[name] = values
+    }
+    return sanitized
+}
+
+func sanitizeURI(uri string) string {
+    parsed, err := url.ParseRequestURI(uri)
+    if err != nil {
+        return uri
+    }
+    query := parsed.Query()
+    for name := range query {
+        if sensitive(name) {
+            query.Set(name, redacted)
+        }
+    }
+    parsed.RawQuery = query.Encode()
+    return parsed.RequestURI()
+}
+
+// sanitizeBody keeps JSON bodies with sensitive fields redacted. Other
+// content, such as imported CSV files or Slack payloads, is only described.
+func sanitizeBody(contentType string, body []byte, truncated bool) string {
+    if len(body) == 0 {
+        return ""
+    }
+    if !strings.HasPrefix(contentType, "application/json") {
+        return fmt.Sprintf("[%d bytes of %q omitted]", len(body), contentType)
+    }
+    if truncated {
+        return fmt.Sprintf("[JSON body over %d bytes omitted]", maxBodyBytes)
+    }
+
+    var value interface{}
+    if err := json.Unmarshal(body, &value); err != nil {
+        return "[invalid JSON omitted]"
+    }
+    encoded, err := json.Marshal(redactJSON(value))
+    if err != nil {
+        return "[invalid JSON omitted]"
+    }
+    return string(encoded)
+}
+
+func redactJSON(value interface{}) interface{} {
+    switch v := value.(type) {
+    case map[string]interface{}:
+        for name, field := range v {
+            if sensitive(name) {
+                v[name] = redacted
+                continue
+            }
+            v[name] = redactJSON(field)
+        }
+    case []interface{}:
+        for i := range v {
+            v[i] = redactJSON(v[i])
+        }
+    }
+    return value
+}
@@ -0,0 +1,173 @@
+// Package demo serves generated data from memory so the UI can be developed
+// and shown without a database or Slack workspace.
+package demo
+
+import (
+    "encoding/json"
+    "fmt"
+    "math/rand"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/handlers"
+)
+
+var channelNames = []string{"support", "eng-infra", "release-coordination", "docs-feedback", "customer-escalations"}
+
+var users = []struct {
+    ID, Name, RealName, Team, Title string
+}{
+    {"U01DEMO0001", "asha", "Asha Raman", "Support", "Support Engineer"},
+    {"U01DEMO0002", "mateo", "Mateo Alvarez", "Infrastructure", "SRE"},
+    {"U01DEMO0003", "li.wei", "Li Wei", "Database", "Staff Engineer"},
+    {"U01DEMO0004", "noor", "Noor Haddad", "Release", "Release Manager"},
+    {"U01DEMO0005", "sam", "Sam Okafor", "Docs", "Technical Writer"},
+    {"U01DEMO0006", "jonas", "Jonas Berg", "Support", "Support Lead"},
+    {"U01DEMO0007", "priya", "Priya Nair", "Database", "Engineer"},
+    {"U01DEMO0008", "kenji", "Kenji Sato", "Infrastructure", "Engineering Manager"},
+}
+
+var topics = []struct {
+    Title, Description string
+}{
+    {"Cluster upgrade stuck at 60%", "Rolling upgrade of a 3 node cluster stopped progressing after the second tserver restarted."},
+    {"Backup fails with permission denied", "Scheduled backups to the object store fail since the bucket policy was rotated."},
+    {"High read latency after index creation", "p99 reads went from 5ms to 80ms after adding a secondary index on the orders table."},
+    {"Release notes missing for 2.20.1", "The docs site does not list the fixes shipped in the latest patch release."},
+    {"TLS certificate rotation procedure", "Customer asks for the supported way to rotate node certificates without downtime."},
+    {"Tablet splitting not triggering", "Automatic tablet splitting is enabled but a 40GB table still has a single tablet."},
+    {"Follower reads returning stale data", "Reads with follower reads enabled are older than the configured staleness."},
+    {"Connection pool exhaustion in staging", "The app reports too many clients when the nightly batch job runs."},
+    {"xCluster replication lag alerts", "Replication lag alert fires every night around 2am and resolves on its own."},
+    {"Docs example for JSONB indexes is wrong", "The GIN index example uses an operator class that does not exist."},
+    {"Point in time restore to a new universe", "Is it possible to restore a snapshot into a universe with a different node count?"},
+    {"Helm chart values for resource limits", "Which values control memory limits of the master pods?"},
+}
+
+var priorities = []domain.Priority{domain.PriorityHigh, domain.PriorityMedium, domain.PriorityLow, domain.PriorityNone}
+
+// store holds the generated dataset.
+type store struct {
+    channels     []channel
+    threads      map[string][]*handlers.Thread
+    profiles     map[string]handlers.UserProfile
+    textIndexing map[string]bool
+    views        map[string]json.RawMessage
+    heat         map[string]json.RawMessage
+}
+
+type channel struct {
+    ID        string
+    Name      string
+    CreatedAt time.Time
+}
+
+func strPtr(s string) *string {
+    return &s
+}
+
+// generate builds a deterministic dataset relative to now.
+func generate(now time.Time) *store {
+    rng := rand.New(rand.NewSource(42))
+    s := &store{
+        threads:      make(map[string][]*handlers.Thread),
+        profiles:     make(map[string]handlers.UserProfile),
+        textIndexing: make(map[string]bool),
+        views:        make(map[string]json.RawMessage),
+        heat:         make(map[string]json.RawMessage),
+    }
+
+    for _, u := range users {
+        // Images are left empty, the UI falls back to generated avatars
+        s.profiles[u.ID] = handlers.UserProfile{UserProfile: domain.UserProfile{
+            UserID:       u.ID,
+            Name:         u.Name,
+            DisplayName:  strings.Split(u.RealName, " ")[0],
+            RealName:     u.RealName,
+            ResolvedName: strings.Split(u.RealName, " ")[0],
+            Title:        u.Title,
+            Team:         u.Team,
+        }}
+    }
+
+    for i, name := range channelNames {
+        ch := channel{
+            ID:        fmt.Sprintf("C0DEMO%04d", i+1),
+            Name:      name,
+            CreatedAt: now.AddDate(0, -6, -i),
+        }
+        s.channels = append(s.channels, ch)
+        s.textIndexing[ch.ID] = true
+
+        count := 8 + rng.Intn(10)
+        for j := 0; j < count; j++ {
+            s.threads[ch.ID] = append(s.threads[ch.ID], newThread(rng, ch, now.Add(-time.Duration(rng.Intn(21*24))*time.Hour)))
+        }
+    }
+    return s
+}
+
+func newThread(rng *rand.Rand, ch channel, createdAt time.Time) *handlers.Thread {
+    topic := topics[rng.Intn(len(topics))]
+    author := users[rng.Intn(len(users))]
+    stakeholders := []string{author.ID}
+    for k := 0; k < 1+rng.Intn(3); k++ {
+        if id := users[rng.Intn(len(users))].ID; id != author.ID {
+            stakeholders = append(stakeholders, id)
+        }
+    }
+    encoded, _ := json.Marshal(stakeholders)
+
+    replies := rng.Intn(15)
+    latest := createdAt
+    if replies > 0 {
+        latest = createdAt.Add(time.Duration(rng.Intn(72)) * time.Hour)
+        if latest.After(time.Now()) {
+            latest = time.Now().Add(-time.Duration(rng.Intn(60)) * time.Minute)
+        }
+    }
+    status := domain.StatusOpen
+    if rng.Intn(3) == 0 {
+        status = domain.StatusClosed
+    }
+    priority := priorities[rng.Intn(len(priorities))]
+    confidence := 0.5 + rng.Float64()/2
+
+    thread := &handlers.Thread{
+        Thread: domain.Thread{
+            ThreadTS:    fmt.Sprintf("%d.%06d", createdAt.Unix(), rng.Intn(1000000)),
+            ChannelID:   ch.ID,
+            UserID:      author.ID,
+            ReplyCount:  replies,
+            LatestReply: latest,
+            Status:      status,
+            CreatedAt:   createdAt,
+        },
+        ChannelName:    ch.Name,
+        AIThreadName:   strPtr(topic.Title),
+        AIDescription:  strPtr(topic.Description),
+        AIStakeholders: string(encoded),
+        AIConfidence:   &confidence,
+        Priority:       priority,
+    }
+    if priority != domain.PriorityNone {
+        thread.AIPriority = strPtr(string(priority))
+    }
+    if rng.Intn(4) == 0 {
+        thread.GithubIssue = strPtr(fmt.Sprintf("https://github.com/example/db/issues/%d", 1000+rng.Intn(9000)))
+    }
+    if rng.Intn(5) == 0 {
+        thread.JiraTicket = strPtr(fmt.Sprintf("DB-%d", 100+rng.Intn(900)))
+    }
+
+    thread.MissingInfo = []string{}
+    for _, kind := range []string{"clear_question", "version", "logs", "steps_to_reproduce"} {
+        if rng.Intn(3) == 0 {
+            thread.MissingInfo = append(thread.MissingInfo, kind)
+        }
+    }
+    quality := 1 - float64(len(thread.MissingInfo))/4
+    thread.QualityScore = &quality
+    return thread
+}
@@ -0,0 +1,627 @@
+package demo
+
+import (
+    "context"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "math/rand"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/handlers"
+    "dashboard/apiserver/logger"
+    "dashboard/apiserver/openapi"
+
+    "github.com/labstack/echo/v4"
+    "golang.org/x/net/websocket"
+)
+
+const eventInterval = 5 * time.Second
+
+// Server answers the dashboard API from generated data. Changes made
+// through the API only live in memory.
+type Server struct {
+    logger logger.Logger
+    rng    *rand.Rand
+
+    mu          sync.Mutex
+    data        *store
+    subscribers map[chan event]bool
+
+    // fallback answers the routes without fixtures, nil when the API
+    // specification cannot be read
+    fallback *fallback
+}
+
+// event has the shape of the live updates of the real server, so the
+// dashboard handles both the same way
+type event = handlers.LiveEvent
+
+// NewServer returns a demo server with a freshly generated dataset.
+func NewServer(log logger.Logger) *Server {
+    s := &Server{
+        logger:      log,
+        rng:         rand.New(rand.NewSource(time.Now().UnixNano())),
+        data:        generate(time.Now()),
+        subscribers: make(map[chan event]bool),
+    }
+    var err error
+    if s.fallback, err = newFallback(); err != nil {
+        log.Warnf("demo: failed to read the API specification, routes without fixtures answer 404: %v", err)
+    }
+    return s
+}
+
+// Register mounts the API routes on e.
+func (s *Server) Register(e *echo.Echo) {
+    api := e.Group("/api")
+    api.GET("/openapi.json", openapi.GetSpec)
+    api.GET("/docs", openapi.GetDocs)
+    api.GET("/ui-config", s.getUIConfig)
+    api.GET("/stats", s.getStats)
+    api.GET("/threads", s.getThreads)
+    api.GET("/schema/threads", func(ctx echo.Context) error { return ctx.JSON(http.StatusOK, handlers.DescribeThreads()) })
+    api.GET("/threads/search", s.searchThreads)
+    api.GET("/channels", s.getChannels)
+    api.GET("/channels/:channel_id", s.getChannel)
+    api.PUT("/channels/:channel_id/text-indexing", s.setChannelSetting("text_indexing"))
+    api.PUT("/channels/:channel_id/view", s.setChannelSetting("view"))
+    api.PUT("/channels/:channel_id/heat", s.setChannelSetting("heat_thresholds"))
+    api.GET("/user-profiles", s.getUserProfiles)
+    api.GET("/users/:user_id/threads", s.getUserThreads)
+    api.GET("/threads/:channel_id/:thread_ts/watchers", s.getWatchers)
+    api.GET("/threads/:channel_id/:thread_ts", s.getThread)
+    api.POST("/exports", s.createExport)
+    api.GET("/events", s.streamEvents)
+    api.GET("/ws", s.streamWebSocket)
+
+    // Personal and admin endpoints have nothing to show without a backend
+    empty := func(ctx echo.Context) error { return ctx.JSON(http.StatusOK, []interface{}{}) }
+    api.GET("/me", func(ctx echo.Context) error { return ctx.JSON(http.StatusOK, handlers.Me{Scopes: []string{}}) })
+    api.GET("/me/tokens", empty)
+    for _, path := range []string{"/login-audit", "/webhooks/inbound", "/webhooks/quarantine", "/backfills",
+        "/legal-holds", "/user-directory"} {
+        api.GET("/admin"+path, empty)
+    }
+    api.GET("/admin/ingest", func(ctx echo.Context) error { return ctx.JSON(http.StatusOK, map[string]interface{}{}) })
+    api.GET("/admin/settings/display-name", func(ctx echo.Context) error {
+        return ctx.JSON(http.StatusOK, handlers.DisplayNameSettings{Precedence: []string{"display_name", "real_name", "name"}})
+    })
+
+    // The other routes answer empty values of the shape they document
+    if s.fallback != nil {
+        api.Any("/*", s.fallback.serve)
+    }
+}
+
+// Run simulates Slack activity, notifying event stream subscribers, until
+// ctx is cancelled.
+func (s *Server) Run(ctx context.Context) {
+    ticker := time.NewTicker(eventInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            s.publish(s.simulate())
+        }
+    }
+}
+
+// simulate adds a reply to a random open thread, or now and then starts a
+// new thread.
+func (s *Server) simulate() event {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    ch := s.data.channels[s.rng.Intn(len(s.data.channels))]
+    threads := s.data.threads[ch.ID]
+    if s.rng.Intn(4) == 0 || len(threads) == 0 {
+        thread := newThread(s.rng, ch, time.Now())
+        thread.Status = "open"
+        thread.ReplyCount = 0
+        thread.LatestReply = thread.CreatedAt
+        s.data.threads[ch.ID] = append(threads, thread)
+        copied := *thread
+        return event{Type: handlers.LiveThreadCreated, Thread: &copied}
+    }
+
+    thread := threads[s.rng.Intn(len(threads))]
+    thread.ReplyCount++
+    thread.LatestReply = time.Now()
+    thread.Status = "open"
+    copied := *thread
+    return event{Type: handlers.LiveThreadUpdated, Thread: &copied}
+}
+
+func (s *Server) publish(ev event) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for sub := range s.subscribers {
+        select {
+        case sub <- ev:
+        default:
+            // Slow subscribers miss events rather than block the others
+        }
+    }
+}
+
+func (s *Server) subscribe() chan event {
+    sub := make(chan event, 16)
+    s.mu.Lock()
+    s.subscribers[sub] = true
+    s.mu.Unlock()
+    return sub
+}
+
+func (s *Server) unsubscribe(sub chan event) {
+    s.mu.Lock()
+    delete(s.subscribers, sub)
+    s.mu.Unlock()
+}
+
+func (s *Server) streamEvents(ctx echo.Context) error {
+    sub := s.subscribe()
+    defer s.unsubscribe(sub)
+
+    resp := ctx.Response()
+    resp.Header().Set(echo.HeaderContentType, "text/event-stream")
+    resp.Header().Set("Cache-Control", "no-cache")
+    resp.WriteHeader(http.StatusOK)
+    resp.Flush()
+
+    for {
+        select {
+        case <-ctx.Request().Context().Done():
+            return nil
+        case ev := <-sub:
+            encoded, _ := json.Marshal(ev)
+            if _, err := fmt.Fprintf(resp, "event: %s\ndata: %s\n\n", ev.Type, encoded); err != nil {
+                return nil
+            }
+            resp.Flush()
+        }
+    }
+}
+
+func (s *Server) streamWebSocket(ctx echo.Context) error {
+    websocket.Handler(func(ws *websocket.Conn) {
+        handlers.ServeLiveUpdates(ws, s.subscribe, s.unsubscribe)
+    }).ServeHTTP(ctx.Response(), ctx.Request())
+    return nil
+}
+
+// listed reports whether a comma separated filter is empty or lists one of
+// values.
+func listed(filter string, values ...string) bool {
+    if filter == "" {
+        return true
+    }
+    for _, item := range strings.Split(filter, ",") {
+        for _, value := range values {
+            if strings.TrimSpace(item) == value {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// snapshot returns copies of the threads of the selected channels, most
+// recently active first. Both filters may list several values.
+func (s *Server) snapshot(channelName string, priority string) []handlers.Thread {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    threads := []handlers.Thread{}
+    for _, ch := range s.data.channels {
+        if !listed(channelName, ch.Name, ch.ID) {
+            continue
+        }
+        thresholds := s.thresholds(ch.ID)
+        for _, thread := range s.data.threads[ch.ID] {
+            if !listed(priority, string(thread.Priority)) {
+                continue
+            }
+            copied := *thread
+            if !s.data.textIndexing[ch.ID] {
+                copied.AIThreadName = nil
+                copied.AIDescription = nil
+            }
+            copied.ApplySLA(thresholds, nil, now)
+            threads = append(threads, copied)
+        }
+    }
+    sort.Slice(threads, func(i, j int) bool { return threads[i].LatestReply.After(threads[j].LatestReply) })
+    return threads
+}
+
+// thresholds returns the heat thresholds stored for a channel, or the
+// defaults of the real server.
+func (s *Server) thresholds(channelID string) handlers.HeatThresholds {
+    thresholds := handlers.HeatThresholds{YellowHours: 24, OrangeHours: 72, RedHours: 168}
+    if stored, ok := s.data.heat[channelID]; ok {
+        json.Unmarshal(stored, &thresholds)
+    }
+    return thresholds
+}
+
+func (s *Server) getStats(ctx echo.Context) error {
+    stats := handlers.DashboardStats{Channels: len(s.data.channels)}
+    for _, thread := range s.snapshot("", "") {
+        stats.TotalThreads++
+        if thread.Status == "open" {
+            stats.ActiveThreads++
+        }
+        if thread.AIThreadName != nil {
+            stats.AIAnalyzed++
+        }
+    }
+    return ctx.JSON(http.StatusOK, stats)
+}
+
+func (s *Server) getUIConfig(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, handlers.DefaultUIConfig(map[string]bool{
+        "ai_analysis":     true,
+        "dashboard_links": false,
+        "jira":            false,
+        "live_updates":    true,
+        "qr_codes":        false,
+        "sign_in":         false,
+        "slack_replies":   false,
+    }))
+}
+
+func (s *Server) getThreads(ctx echo.Context) error {
+    limit := 10
+    if parsed, err := strconv.Atoi(ctx.QueryParam("limit")); err == nil && parsed > 0 {
+        limit = parsed
+    }
+    // Demo cursors are plain offsets, clients treat them as opaque
+    offset, _ := strconv.Atoi(ctx.QueryParam("offset"))
+    if cursor, err := strconv.Atoi(ctx.QueryParam("cursor")); err == nil {
+        offset = cursor
+    }
+    threads := s.snapshot(ctx.QueryParam("channel"), ctx.QueryParam("priority"))
+    page := handlers.ThreadPage{Items: []handlers.Thread{}, Total: len(threads)}
+
+    switch ctx.QueryParam("group_by") {
+    case "":
+    case "channel":
+        if offset > 0 || ctx.QueryParam("cursor") != "" {
+            return apierror.New(http.StatusBadRequest, "group_by limits the threads of each channel, it cannot be combined with offset or cursor")
+        }
+        page.Groups = groupByChannel(threads, limit)
+        return ctx.JSON(http.StatusOK, page)
+    default:
+        return apierror.New(http.StatusBadRequest, "group_by must be channel")
+    }
+
+    if offset < 0 || offset >= len(threads) {
+        return ctx.JSON(http.StatusOK, page)
+    }
+    threads = threads[offset:]
+    if len(threads) > limit {
+        threads = threads[:limit]
+        next := strconv.Itoa(offset + limit)
+        page.NextCursor = &next
+    }
+    page.Items = threads
+    return ctx.JSON(http.StatusOK, page)
+}
+
+// groupByChannel nests up to limit threads under each of their channels,
+// keeping the order of threads. Channels come in the order of their first
+// thread.
+func groupByChannel(threads []handlers.Thread, limit int) []handlers.ChannelThreads {
+    groups := []handlers.ChannelThreads{}
+    index := map[string]int{}
+    for _, thread := range threads {
+        i, ok := index[thread.ChannelID]
+        if !ok {
+            i = len(groups)
+            index[thread.ChannelID] = i
+            groups = append(groups, handlers.ChannelThreads{
+                ChannelID:   thread.ChannelID,
+                ChannelName: thread.ChannelName,
+                Items:       []handlers.Thread{},
+            })
+        }
+        groups[i].Total++
+        if len(groups[i].Items) < limit {
+            groups[i].Items = append(groups[i].Items, thread)
+        }
+    }
+    return groups
+}
+
+// getUserThreads serves the open threads a user authored or is a
+// stakeholder of. Without sign in there is nobody to stand for me.
+func (s *Server) getUserThreads(ctx echo.Context) error {
+    userID := ctx.Param("user_id")
+    if userID == "me" {
+        return apierror.New(http.StatusUnauthorized, "user_id=me requires a signed in user")
+    }
+    limit := 10
+    if parsed, err := strconv.Atoi(ctx.QueryParam("limit")); err == nil && parsed > 0 {
+        limit = parsed
+    }
+    offset, _ := strconv.Atoi(ctx.QueryParam("offset"))
+
+    backlog := handlers.UserThreads{
+        UserID:     userID,
+        Items:      []handlers.Thread{},
+        ByPriority: map[string]int{"high": 0, "medium": 0, "low": 0, "none": 0},
+    }
+    for _, thread := range s.snapshot(ctx.QueryParam("channel"), ctx.QueryParam("priority")) {
+        if thread.Status != "open" {
+            continue
+        }
+        involved := thread.UserID == userID
+        stakeholders := []string{}
+        json.Unmarshal([]byte(thread.AIStakeholders), &stakeholders)
+        for _, stakeholder := range stakeholders {
+            involved = involved || stakeholder == userID
+        }
+        if !involved {
+            continue
+        }
+        backlog.ByPriority[string(thread.Priority)]++
+        if backlog.Total >= offset && len(backlog.Items) < limit {
+            backlog.Items = append(backlog.Items, thread)
+        }
+        backlog.Total++
+    }
+    return ctx.JSON(http.StatusOK, backlog)
+}
+
+// searchThreads ranks threads by how many query words their name, issue
+// and description contain, names counting most.
+func (s *Server) searchThreads(ctx echo.Context) error {
+    q := strings.TrimSpace(ctx.QueryParam("q"))
+    if q == "" {
+        return apierror.New(http.StatusBadRequest, "q is required")
+    }
+    limit := 20
+    if parsed, err := strconv.Atoi(ctx.QueryParam("limit")); err == nil && parsed > 0 {
+        limit = parsed
+    }
+    status := ctx.QueryParam("status")
+
+    words := strings.Fields(strings.ToLower(q))
+    field := func(value *string) string {
+        if value == nil {
+            return ""
+        }
+        return strings.ToLower(*value)
+    }
+    results := handlers.ThreadSearchResults{Query: q, Items: []handlers.ThreadMatch{}}
+    for _, thread := range s.snapshot(ctx.QueryParam("channel"), "") {
+        if status != "" && string(thread.Status) != status {
+            continue
+        }
+        rank := 0.0
+        for _, word := range words {
+            if strings.Contains(field(thread.AIThreadName), word) {
+                rank += 1
+            }
+            if strings.Contains(field(thread.ThreadIssue), word) {
+                rank += 0.4
+            }
+            if strings.Contains(field(thread.AIDescription), word) {
+                rank += 0.2
+            }
+        }
+        if rank > 0 {
+            results.Items = append(results.Items, handlers.ThreadMatch{Thread: thread, Rank: rank})
+        }
+    }
+    sort.SliceStable(results.Items, func(i, j int) bool { return results.Items[i].Rank > results.Items[j].Rank })
+    if len(results.Items) > limit {
+        results.Items = results.Items[:limit]
+    }
+    return ctx.JSON(http.StatusOK, results)
+}
+
+func (s *Server) channelJSON(ch channel) map[string]interface{} {
+    total, active := 0, 0
+    lastActivity := ch.CreatedAt
+    for _, thread := range s.data.threads[ch.ID] {
+        total++
+        if thread.Status == "open" {
+            active++
+        }
+        if thread.LatestReply.After(lastActivity) {
+            lastActivity = thread.LatestReply
+        }
+    }
+    return map[string]interface{}{
+        "channel_id":          ch.ID,
+        "channel_name":        ch.Name,
+        "thread_count":        total,
+        "active_thread_count": active,
+        "last_activity":       lastActivity,
+        "created_at":          ch.CreatedAt,
+        "text_indexing":       s.data.textIndexing[ch.ID],
+    }
+}
+
+func (s *Server) getChannels(ctx echo.Context) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    channels := []map[string]interface{}{}
+    for _, ch := range s.data.channels {
+        channels = append(channels, s.channelJSON(ch))
+    }
+    sort.Slice(channels, func(i, j int) bool {
+        return channels[i]["channel_name"].(string) < channels[j]["channel_name"].(string)
+    })
+    return ctx.JSON(http.StatusOK, channels)
+}
+
+func (s *Server) findChannel(channelID string) (channel, bool) {
+    for _, ch := range s.data.channels {
+        if ch.ID == channelID {
+            return ch, true
+        }
+    }
+    return channel{}, false
+}
+
+func (s *Server) getChannel(ctx echo.Context) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    ch, ok := s.findChannel(ctx.Param("channel_id"))
+    if !ok {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    result := s.channelJSON(ch)
+    result["view"] = json.RawMessage(`{"sort":"latest_reply","order":"desc","filters":{},"columns":["thread","author","stakeholders","priority","status","replies","latest_reply"]}`)
+    if view, ok := s.data.views[ch.ID]; ok {
+        result["view"] = view
+    }
+    result["heat_thresholds"] = json.RawMessage(`{"yellow_hours":24,"orange_hours":72,"red_hours":168}`)
+    if thresholds, ok := s.data.heat[ch.ID]; ok {
+        result["heat_thresholds"] = thresholds
+    }
+    return ctx.JSON(http.StatusOK, result)
+}
+
+// setChannelSetting stores a channel setting as given, without the
+// validation of the real server.
+func (s *Server) setChannelSetting(setting string) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+        var body json.RawMessage
+        if err := json.NewDecoder(ctx.Request().Body).Decode(&body); err != nil {
+            return apierror.New(http.StatusBadRequest, "Invalid request body")
+        }
+
+        s.mu.Lock()
+        defer s.mu.Unlock()
+
+        channelID := ctx.Param("channel_id")
+        if _, ok := s.findChannel(channelID); !ok {
+            return apierror.New(http.StatusNotFound, "Channel not found")
+        }
+        switch setting {
+        case "text_indexing":
+            var req handlers.TextIndexingRequest
+            if err := json.Unmarshal(body, &req); err != nil || req.Enabled == nil {
+                return apierror.New(http.StatusBadRequest, "expected a boolean enabled field")
+            }
+            s.data.textIndexing[channelID] = *req.Enabled
+            return ctx.JSON(http.StatusOK, map[string]interface{}{
+                "channel_id":    channelID,
+                "text_indexing": *req.Enabled,
+            })
+        case "view":
+            s.data.views[channelID] = body
+        case "heat_thresholds":
+            s.data.heat[channelID] = body
+        }
+        return ctx.JSON(http.StatusOK, body)
+    }
+}
+
+func (s *Server) getUserProfiles(ctx echo.Context) error {
+    userIDs := ctx.QueryParam("user_ids")
+    if userIDs == "" {
+        return apierror.New(http.StatusBadRequest, "user_ids parameter is required")
+    }
+
+    profiles := []handlers.UserProfile{}
+    for _, userID := range strings.Split(userIDs, ",") {
+        if profile, ok := s.data.profiles[strings.TrimSpace(userID)]; ok {
+            profiles = append(profiles, profile)
+        }
+    }
+    return ctx.JSON(http.StatusOK, profiles)
+}
+
+func (s *Server) getWatchers(ctx echo.Context) error {
+    for _, thread := range s.snapshot("", "") {
+        if thread.ChannelID != ctx.Param("channel_id") || thread.ThreadTS != ctx.Param("thread_ts") {
+            continue
+        }
+        stakeholders := []string{}
+        json.Unmarshal([]byte(thread.AIStakeholders), &stakeholders)
+        watchers := []handlers.Watcher{}
+        for _, userID := range stakeholders[1:] {
+            watchers = append(watchers, handlers.Watcher{UserID: userID, Source: "reaction", CreatedAt: thread.CreatedAt})
+        }
+        return ctx.JSON(http.StatusOK, watchers)
+    }
+    return ctx.JSON(http.StatusOK, []handlers.Watcher{})
+}
+
+// getThread serves the root of a thread as its only message, demo threads
+// have no replies to show.
+func (s *Server) getThread(ctx echo.Context) error {
+    for _, thread := range s.snapshot("", "") {
+        if thread.ChannelID != ctx.Param("channel_id") || thread.ThreadTS != ctx.Param("thread_ts") {
+            continue
+        }
+        detail := handlers.ThreadDetail{
+            Thread: thread,
+            Messages: []handlers.ThreadMessage{
+                {TS: thread.ThreadTS, UserID: thread.UserID, Text: thread.AIDescription, PostedAt: thread.CreatedAt},
+            },
+            MessagesSource: "stored",
+            Stakeholders:   []handlers.UserProfile{},
+        }
+        stakeholders := []string{}
+        json.Unmarshal([]byte(thread.AIStakeholders), &stakeholders)
+        for _, userID := range stakeholders {
+            if profile, ok := s.data.profiles[userID]; ok {
+                detail.Stakeholders = append(detail.Stakeholders, profile)
+            }
+        }
+        if thread.GithubIssue != nil {
+            detail.GitHub = &handlers.LinkedGitHubIssue{URL: *thread.GithubIssue, State: "open"}
+        }
+        if thread.JiraTicket != nil {
+            detail.Jira = &handlers.LinkedJiraTicket{Key: *thread.JiraTicket}
+        }
+        return ctx.JSON(http.StatusOK, detail)
+    }
+    return apierror.New(http.StatusNotFound, "Thread not found")
+}
+
+// createExport always answers synchronously, the dataset is small.
+func (s *Server) createExport(ctx echo.Context) error {
+    var req handlers.ExportRequest
+    json.NewDecoder(ctx.Request().Body).Decode(&req)
+
+    threads := s.snapshot(req.Channel, req.Priority)
+    if req.Format == "json" {
+        return ctx.JSON(http.StatusOK, threads)
+    }
+
+    resp := ctx.Response()
+    resp.Header().Set(echo.HeaderContentType, "text/csv")
+    resp.Header().Set(echo.HeaderContentDisposition, `attachment; filename="threads.csv"`)
+    resp.WriteHeader(http.StatusOK)
+    w := csv.NewWriter(resp)
+    w.Write([]string{"channel_name", "thread_ts", "user_id", "status", "priority", "reply_count", "latest_reply", "ai_thread_name"})
+    for _, thread := range threads {
+        name := ""
+        if thread.AIThreadName != nil {
+            name = *thread.AIThreadName
+        }
+        w.Write([]string{thread.ChannelName, thread.ThreadTS, thread.UserID, string(thread.Status), string(thread.Priority),
+            strconv.Itoa(thread.ReplyCount), thread.LatestReply.UTC().Format(time.RFC3339), name})
+    }
+    w.Flush()
+    return w.Error()
+}
@@ -0,0 +1,152 @@
+package demo
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/handlers"
+    "dashboard/apiserver/logger"
+
+    "github.com/labstack/echo/v4"
+)
+
+func newTestServer(t *testing.T) (*Server, *echo.Echo) {
+    t.Helper()
+    log, err := logger.NewLogger(logger.Error)
+    if err != nil {
+        t.Fatal(err)
+    }
+    s := NewServer(log)
+    if s.fallback == nil {
+        t.Fatal("the API specification was not read")
+    }
+    e := echo.New()
+    e.HTTPErrorHandler = apierror.Handler(log)
+    s.Register(e)
+    return s, e
+}
+
+func serve(e *echo.Echo, method string, target string) *httptest.ResponseRecorder {
+    rec := httptest.NewRecorder()
+    e.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+    return rec
+}
+
+// TestEveryRouteAnswers requests every read of the API specification, with
+// made up path parameters, and expects none to be missing or to fail.
+func TestEveryRouteAnswers(t *testing.T) {
+    s, e := newTestServer(t)
+    for _, op := range s.fallback.operations[http.MethodGet] {
+        segments := make([]string, len(op.segments))
+        for i, segment := range op.segments {
+            if strings.HasPrefix(segment, "{") {
+                segment = "X1"
+            }
+            segments[i] = segment
+        }
+        target := strings.Join(segments, "/")
+        if target == "/api/events" || target == "/api/ws" {
+            continue
+        }
+        rec := serve(e, http.MethodGet, target)
+        if rec.Code >= 500 {
+            t.Errorf("GET %s answered %d: %s", target, rec.Code, rec.Body.String())
+        }
+        // Fixtures may not find made up IDs, but every route exists
+        if rec.Code == http.StatusNotFound && strings.Contains(rec.Body.String(), `"Not Found"`) {
+            t.Errorf("GET %s is not routed", target)
+        }
+    }
+}
+
+func TestFallback(t *testing.T) {
+    _, e := newTestServer(t)
+
+    rec := serve(e, http.MethodGet, "/api/admin/adoption")
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var report map[string]interface{}
+    if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+        t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+    }
+
+    rec = serve(e, http.MethodGet, "/api/goals")
+    if rec.Code != http.StatusOK || rec.Body.String() != "[]\n" {
+        t.Fatalf("got %d %q, want an empty list", rec.Code, rec.Body.String())
+    }
+
+    rec = serve(e, http.MethodPost, "/api/goals")
+    if rec.Code != http.StatusForbidden {
+        t.Fatalf("got status %d for a change, want 403", rec.Code)
+    }
+
+    rec = serve(e, http.MethodGet, "/api/no-such-route")
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("got status %d for an unknown route, want 404", rec.Code)
+    }
+}
+
+func TestGroupByChannel(t *testing.T) {
+    s, e := newTestServer(t)
+
+    rec := serve(e, http.MethodGet, "/api/threads?group_by=channel&limit=2")
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var page handlers.ThreadPage
+    if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+        t.Fatal(err)
+    }
+    if len(page.Groups) != len(s.data.channels) {
+        t.Fatalf("got %d groups, want one per channel", len(page.Groups))
+    }
+    total := 0
+    for _, group := range page.Groups {
+        if len(group.Items) > 2 || len(group.Items) == 0 {
+            t.Fatalf("channel %s has %d threads, want 1 or 2", group.ChannelName, len(group.Items))
+        }
+        total += group.Total
+    }
+    if total != page.Total {
+        t.Fatalf("groups count %d threads, the page %d", total, page.Total)
+    }
+
+    if rec := serve(e, http.MethodGet, "/api/threads?group_by=user"); rec.Code != http.StatusBadRequest {
+        t.Fatalf("got status %d for an unknown grouping, want 400", rec.Code)
+    }
+}
+
+func TestUserThreads(t *testing.T) {
+    s, e := newTestServer(t)
+    var userID string
+    for _, thread := range s.snapshot("", "") {
+        if thread.Status == "open" {
+            userID = thread.UserID
+            break
+        }
+    }
+
+    rec := serve(e, http.MethodGet, "/api/users/"+userID+"/threads?limit=1")
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var backlog handlers.UserThreads
+    if err := json.Unmarshal(rec.Body.Bytes(), &backlog); err != nil {
+        t.Fatal(err)
+    }
+    if backlog.Total == 0 || len(backlog.Items) != 1 {
+        t.Fatalf("got %d of %d threads, want the first of the backlog", len(backlog.Items), backlog.Total)
+    }
+    counted := 0
+    for _, count := range backlog.ByPriority {
+        counted += count
+    }
+    if counted != backlog.Total {
+        t.Fatalf("priorities count %d threads, the backlog %d", counted, backlog.Total)
+    }
+}
@@ -0,0 +1,147 @@
+package demo
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/openapi"
+
+    "github.com/labstack/echo/v4"
+)
+
+// maxSchemaDepth bounds how deep empty responses are built, so that
+// recursive schemas end.
+const maxSchemaDepth = 8
+
+// successStatuses are the statuses of the responses the fallback answers
+// with, the first an operation documents.
+var successStatuses = []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent}
+
+// schema is the part of an OpenAPI schema empty responses are built from.
+type schema struct {
+    Ref        string             `json:"$ref"`
+    Type       string             `json:"type"`
+    Properties map[string]*schema `json:"properties"`
+    Required   []string           `json:"required"`
+}
+
+// operation is an operation of the specification with the schema of its
+// JSON response, nil when it answers without a JSON body.
+type operation struct {
+    segments []string
+    status   int
+    response *schema
+}
+
+// fallback answers the /api routes the demo has no fixtures for with an
+// empty value of the response they document: reads get empty lists and
+// objects, so that every page of the dashboard loads, while changes and
+// downloads are refused like in read-only mode.
+type fallback struct {
+    // operations are keyed by method
+    operations map[string][]operation
+    schemas    map[string]*schema
+}
+
+// newFallback reads the operations of the OpenAPI specification of the API.
+func newFallback() (*fallback, error) {
+    var doc struct {
+        Paths map[string]map[string]struct {
+            Responses map[string]struct {
+                Content map[string]struct {
+                    Schema *schema `json:"schema"`
+                } `json:"content"`
+            } `json:"responses"`
+        } `json:"paths"`
+        Components struct {
+            Schemas map[string]*schema `json:"schemas"`
+        } `json:"components"`
+    }
+    if err := json.Unmarshal(openapi.Spec(), &doc); err != nil {
+        return nil, err
+    }
+
+    f := &fallback{operations: map[string][]operation{}, schemas: doc.Components.Schemas}
+    for path, methods := range doc.Paths {
+        for method, op := range methods {
+            for _, status := range successStatuses {
+                response, ok := op.Responses[strconv.Itoa(status)]
+                if !ok {
+                    continue
+                }
+                found := operation{segments: strings.Split(path, "/"), status: status}
+                if content, ok := response.Content[echo.MIMEApplicationJSON]; ok {
+                    found.response = content.Schema
+                }
+                method = strings.ToUpper(method)
+                f.operations[method] = append(f.operations[method], found)
+                break
+            }
+        }
+    }
+    return f, nil
+}
+
+// find returns the operation of a request path, whose parameters match any
+// segment.
+func (f *fallback) find(method string, path string) (operation, bool) {
+    segments := strings.Split(path, "/")
+    for _, op := range f.operations[method] {
+        if len(op.segments) != len(segments) {
+            continue
+        }
+        matched := true
+        for i, segment := range op.segments {
+            if segment != segments[i] && !strings.HasPrefix(segment, "{") {
+                matched = false
+                break
+            }
+        }
+        if matched {
+            return op, true
+        }
+    }
+    return operation{}, false
+}
+
+// empty returns the empty value of a schema: objects with their required
+// properties empty, no items for lists, zero for numbers and booleans.
+func (f *fallback) empty(s *schema, depth int) interface{} {
+    if s == nil || depth > maxSchemaDepth {
+        return nil
+    }
+    if s.Ref != "" {
+        return f.empty(f.schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")], depth+1)
+    }
+    switch s.Type {
+    case "array":
+        return []interface{}{}
+    case "string":
+        return ""
+    case "integer", "number":
+        return 0
+    case "boolean":
+        return false
+    }
+    value := map[string]interface{}{}
+    for _, name := range s.Required {
+        value[name] = f.empty(s.Properties[name], depth+1)
+    }
+    return value
+}
+
+// serve answers a request with the empty response of its operation.
+func (f *fallback) serve(ctx echo.Context) error {
+    req := ctx.Request()
+    op, ok := f.find(req.Method, req.URL.Path)
+    if !ok {
+        return echo.ErrNotFound
+    }
+    if req.Method != http.MethodGet || op.response == nil {
+        return apierror.New(http.StatusForbidden, "Not available in demo mode")
+    }
+    return ctx.JSON(op.status, f.empty(op.response, 0))
+}
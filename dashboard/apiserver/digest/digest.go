@@ -0,0 +1,119 @@
+package digest
+
+import (
+    "fmt"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/slack"
+)
+
+// MaxTopThreads is how many of the highest priority open threads a digest
+// lists.
+const MaxTopThreads = 10
+
+// ChannelSummary counts the threads of a channel in a digest.
+type ChannelSummary struct {
+    ChannelID   string `json:"channel_id"`
+    ChannelName string `json:"channel_name"`
+    NewThreads  int    `json:"new_threads"`
+    OpenThreads int    `json:"open_threads"`
+    Overdue     int    `json:"overdue"`
+}
+
+// Thread is an open thread listed by a digest.
+type Thread struct {
+    ChannelID   string     `json:"channel_id"`
+    ChannelName string     `json:"channel_name"`
+    ThreadTS    string     `json:"thread_ts"`
+    Title       string     `json:"title"`
+    Priority    domain.Priority `json:"priority"`
+    CreatedAt   time.Time  `json:"created_at"`
+    DueAt       *time.Time `json:"due_at"`
+    Overdue     bool       `json:"overdue"`
+}
+
+// Digest summarizes the threads of a workspace since a point in time.
+type Digest struct {
+    Since        time.Time        `json:"since"`
+    Until        time.Time        `json:"until"`
+    NewThreads   int              `json:"new_threads"`
+    OpenThreads  int              `json:"open_threads"`
+    Overdue      int              `json:"overdue"`
+    Channels     []ChannelSummary `json:"channels"`
+    TopPriority  []Thread         `json:"top_priority"`
+    DashboardURL string           `json:"dashboard_url,omitempty"`
+}
+
+// Message returns the fallback text and blocks of a digest posted in Slack.
+func Message(d *Digest) (string, []slack.Block) {
+    text := fmt.Sprintf("Open threads digest: %d new, %d open, %d overdue", d.NewThreads, d.OpenThreads, d.Overdue)
+
+    blocks := []slack.Block{slack.Section("*" + text + "*")}
+    if channels := channelLines(d); channels != "" {
+        blocks = append(blocks, slack.Divider(), slack.Section("*By channel*\n"+channels))
+    }
+    if len(d.TopPriority) > 0 {
+        lines := []string{}
+        for _, t := range d.TopPriority {
+            lines = append(lines, describe(t))
+        }
+        blocks = append(blocks, slack.Divider(), slack.Section("*Top priorities*\n"+strings.Join(lines, "\n")))
+    }
+    if d.DashboardURL != "" {
+        blocks = append(blocks, slack.Section(fmt.Sprintf("<%s|Open the dashboard>", d.DashboardURL)))
+    }
+    return text, blocks
+}
+
+// Summary returns the mrkdwn text of a digest, for recipients that cannot
+// show blocks such as email.
+func Summary(d *Digest) string {
+    text, _ := Message(d)
+    text += fmt.Sprintf("\n\nSince %s.", d.Since.UTC().Format("Mon Jan 2 15:04 MST"))
+    if channels := channelLines(d); channels != "" {
+        text += "\n\nBy channel\n" + channels
+    }
+    if len(d.TopPriority) > 0 {
+        text += "\n\nTop priorities"
+        for _, t := range d.TopPriority {
+            text += "\n" + describe(t)
+        }
+    }
+    if d.DashboardURL != "" {
+        text += "\n\nDashboard: " + d.DashboardURL
+    }
+    return text
+}
+
+// channelLines lists the channels with new or overdue threads, one per
+// line.
+func channelLines(d *Digest) string {
+    lines := []string{}
+    for _, ch := range d.Channels {
+        if ch.NewThreads == 0 && ch.Overdue == 0 {
+            continue
+        }
+        line := fmt.Sprintf("<#%s>: %d new, %d open", ch.ChannelID, ch.NewThreads, ch.OpenThreads)
+        if ch.Overdue > 0 {
+            line += fmt.Sprintf(", *%d overdue*", ch.Overdue)
+        }
+        lines = append(lines, line)
+    }
+    return strings.Join(lines, "\n")
+}
+
+// describe returns the linked title of a thread and where it is.
+func describe(t Thread) string {
+    emoji := t.Priority.Emoji()
+    title := t.Title
+    if title == "" {
+        title = "Untitled thread"
+    }
+    line := fmt.Sprintf("%s <%s|%s> in <#%s>", emoji, slack.Permalink(t.ChannelID, t.ThreadTS), title, t.ChannelID)
+    if t.Overdue {
+        line += ", overdue"
+    }
+    return line
+}
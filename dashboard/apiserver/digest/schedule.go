@@ -0,0 +1,131 @@
+// Package digest composes the daily summary of open threads sent to Slack
+// channels and email recipients, and parses the cron schedule it is sent on.
+package digest
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Schedule is a standard five field cron expression: minute, hour, day of
+// month, month and day of week (0 or 7 for Sunday). Fields take *, values,
+// ranges, lists and steps such as */15 or 1-5.
+type Schedule struct {
+    minutes, hours, days, months, weekdays map[int]bool
+    // Like cron, a day matches either of a restricted day of month and day
+    // of week
+    anyDay, anyWeekday bool
+}
+
+// ParseSchedule parses a cron expression such as "0 9 * * 1-5".
+func ParseSchedule(spec string) (*Schedule, error) {
+    fields := strings.Fields(spec)
+    if len(fields) != 5 {
+        return nil, fmt.Errorf("schedule %q must have 5 fields: minute hour day month weekday", spec)
+    }
+    s := &Schedule{anyDay: fields[2] == "*", anyWeekday: fields[4] == "*"}
+    var err error
+    if s.minutes, err = parseField(fields[0], 0, 59); err != nil {
+        return nil, fmt.Errorf("minute of schedule %q: %w", spec, err)
+    }
+    if s.hours, err = parseField(fields[1], 0, 23); err != nil {
+        return nil, fmt.Errorf("hour of schedule %q: %w", spec, err)
+    }
+    if s.days, err = parseField(fields[2], 1, 31); err != nil {
+        return nil, fmt.Errorf("day of schedule %q: %w", spec, err)
+    }
+    if s.months, err = parseField(fields[3], 1, 12); err != nil {
+        return nil, fmt.Errorf("month of schedule %q: %w", spec, err)
+    }
+    if s.weekdays, err = parseField(fields[4], 0, 7); err != nil {
+        return nil, fmt.Errorf("weekday of schedule %q: %w", spec, err)
+    }
+    if s.weekdays[7] {
+        s.weekdays[0] = true
+    }
+    return s, nil
+}
+
+// parseField returns the values of a field between min and max.
+func parseField(field string, min int, max int) (map[int]bool, error) {
+    values := map[int]bool{}
+    for _, part := range strings.Split(field, ",") {
+        rangePart, stepPart, hasStep := strings.Cut(part, "/")
+        step := 1
+        if hasStep {
+            n, err := strconv.Atoi(stepPart)
+            if err != nil || n <= 0 {
+                return nil, fmt.Errorf("invalid step %q", stepPart)
+            }
+            step = n
+        }
+
+        from, to := min, max
+        if rangePart != "*" {
+            low, high, isRange := strings.Cut(rangePart, "-")
+            var err error
+            if from, err = strconv.Atoi(low); err != nil {
+                return nil, fmt.Errorf("invalid value %q", low)
+            }
+            to = from
+            if isRange {
+                if to, err = strconv.Atoi(high); err != nil {
+                    return nil, fmt.Errorf("invalid value %q", high)
+                }
+            } else if hasStep {
+                to = max
+            }
+        }
+        if from < min || to > max || from > to {
+            return nil, fmt.Errorf("%q is outside of %d-%d", part, min, max)
+        }
+        for v := from; v <= to; v += step {
+            values[v] = true
+        }
+    }
+    return values, nil
+}
+
+// matchesDay reports whether the schedule runs on the day of t.
+func (s *Schedule) matchesDay(t time.Time) bool {
+    if !s.months[int(t.Month())] {
+        return false
+    }
+    day, weekday := s.days[t.Day()], s.weekdays[int(t.Weekday())]
+    switch {
+    case s.anyDay && s.anyWeekday:
+        return true
+    case s.anyDay:
+        return weekday
+    case s.anyWeekday:
+        return day
+    }
+    return day || weekday
+}
+
+// Next returns the first time after after the schedule runs at, in the
+// location of after. It is zero when the schedule never runs, e.g. on
+// February 30.
+func (s *Schedule) Next(after time.Time) time.Time {
+    t := after.Truncate(time.Minute).Add(time.Minute)
+    // Four years cover every leap day
+    limit := t.AddDate(4, 0, 0)
+    for t.Before(limit) {
+        if !s.matchesDay(t) {
+            t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+            continue
+        }
+        if !s.hours[t.Hour()] {
+            t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+            continue
+        }
+        if !s.minutes[t.Minute()] {
+            t = t.Add(time.Minute)
+            continue
+        }
+        return t
+    }
+    return time.Time{}
+}
@@ -0,0 +1,10 @@
+package domain
+
+// Channel is a Slack channel whose threads are tracked.
+type Channel struct {
+    ChannelID   string `json:"channel_id"`
+    ChannelName string `json:"channel_name"`
+    // TextIndexing is unset when the text of the channel's messages is not
+    // searchable
+    TextIndexing bool `json:"text_indexing"`
+}
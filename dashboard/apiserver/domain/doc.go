@@ -0,0 +1,9 @@
+// Package domain holds the threads, channels and user profiles shared by the
+// handlers, the stores, the ingestion pipeline and the reminders, and the
+// statuses and priorities of threads, so that each only declares what it
+// adds to them.
+//
+// The Python collector writing the threads table cannot use this package.
+// Its priorities are declared again in vertex/enums.py and have to be kept
+// in step with Priority by hand.
+package domain
@@ -0,0 +1,61 @@
+package domain
+
+import "fmt"
+
+// Priority is how urgent a thread is.
+type Priority string
+
+// Thread priorities. PriorityNone is set by hand or by the AI on threads
+// needing no attention, threads never prioritized have an empty priority.
+const (
+    PriorityHigh   Priority = "high"
+    PriorityMedium Priority = "medium"
+    PriorityLow    Priority = "low"
+    PriorityNone   Priority = "none"
+)
+
+// Priorities are the priorities threads are ranked by, the most urgent
+// first.
+var Priorities = []Priority{PriorityHigh, PriorityMedium, PriorityLow}
+
+// ParsePriority returns the priority named s, PriorityNone included.
+func ParsePriority(s string) (Priority, error) {
+    priority := Priority(s)
+    if !priority.Valid() && priority != PriorityNone {
+        return "", fmt.Errorf("unknown priority %q", s)
+    }
+    return priority, nil
+}
+
+// Valid reports whether p is one of Priorities.
+func (p Priority) Valid() bool {
+    return p.Rank() > 0
+}
+
+// Rank orders priorities by urgency, from 3 for PriorityHigh to 0 for
+// PriorityNone and unknown priorities.
+func (p Priority) Rank() int {
+    switch p {
+    case PriorityHigh:
+        return 3
+    case PriorityMedium:
+        return 2
+    case PriorityLow:
+        return 1
+    }
+    return 0
+}
+
+// Emoji returns the emoji of p in Slack messages and canvases, a white
+// circle for PriorityNone and unknown priorities.
+func (p Priority) Emoji() string {
+    switch p {
+    case PriorityHigh:
+        return "🔴"
+    case PriorityMedium:
+        return "🟡"
+    case PriorityLow:
+        return "🟢"
+    }
+    return "⚪"
+}
@@ -0,0 +1,49 @@
+package domain
+
+import "fmt"
+
+// Status is the state of a thread.
+type Status string
+
+// Thread statuses. The collector creates threads as open, the dashboard
+// sets the others: releases park threads as StatusWaitingRelease and
+// questions to their author as StatusWaitingReporter.
+const (
+    StatusOpen            Status = "open"
+    StatusResolved        Status = "resolved"
+    StatusSnoozed         Status = "snoozed"
+    StatusClosed          Status = "closed"
+    StatusWaitingRelease  Status = "waiting_release"
+    StatusWaitingReporter Status = "waiting_reporter"
+)
+
+// Statuses are every status of threads.
+var Statuses = []Status{StatusOpen, StatusResolved, StatusSnoozed, StatusClosed, StatusWaitingRelease, StatusWaitingReporter}
+
+// ParseStatus returns the status named s.
+func ParseStatus(s string) (Status, error) {
+    status := Status(s)
+    if !status.Valid() {
+        return "", fmt.Errorf("unknown thread status %q", s)
+    }
+    return status, nil
+}
+
+// Valid reports whether s is a known status.
+func (s Status) Valid() bool {
+    switch s {
+    case StatusOpen, StatusResolved, StatusSnoozed, StatusClosed, StatusWaitingRelease, StatusWaitingReporter:
+        return true
+    }
+    return false
+}
+
+// Settable reports whether people may set s by hand. The waiting statuses
+// are only set by the dashboard itself.
+func (s Status) Settable() bool {
+    switch s {
+    case StatusOpen, StatusResolved, StatusSnoozed, StatusClosed:
+        return true
+    }
+    return false
+}
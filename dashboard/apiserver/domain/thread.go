@@ -0,0 +1,54 @@
+package domain
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+    "time"
+)
+
+// tsPattern matches Slack message timestamps.
+var tsPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+
+// Thread is a thread as the collector stores it in the table of its
+// channel.
+type Thread struct {
+    ThreadTS    string    `json:"thread_ts"`
+    ChannelID   string    `json:"channel_id"`
+    UserID      string    `json:"user_id"`
+    ReplyCount  int       `json:"reply_count"`
+    LatestReply time.Time `json:"latest_reply"`
+    Status      Status    `json:"status"`
+    CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewThread returns the open thread of channelID started by userID with
+// the message threadTS, without replies yet.
+func NewThread(channelID, threadTS, userID string, createdAt time.Time) (Thread, error) {
+    thread := Thread{
+        ThreadTS:    threadTS,
+        ChannelID:   channelID,
+        UserID:      userID,
+        LatestReply: createdAt,
+        Status:      StatusOpen,
+        CreatedAt:   createdAt,
+    }
+    return thread, thread.Validate()
+}
+
+// Validate returns an error when t cannot be stored.
+func (t Thread) Validate() error {
+    if t.ChannelID == "" {
+        return errors.New("thread has no channel")
+    }
+    if !tsPattern.MatchString(t.ThreadTS) {
+        return fmt.Errorf("thread_ts %q is not a Slack timestamp", t.ThreadTS)
+    }
+    if !t.Status.Valid() {
+        return fmt.Errorf("unknown thread status %q", t.Status)
+    }
+    if t.ReplyCount < 0 {
+        return errors.New("reply_count must not be negative")
+    }
+    return nil
+}
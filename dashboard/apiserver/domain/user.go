@@ -0,0 +1,21 @@
+package domain
+
+// UserProfile is the Slack profile of a user, synced into user_profiles.
+type UserProfile struct {
+    UserID          string `json:"user_id"`
+    Name            string `json:"name"`
+    DisplayName     string `json:"display_name"`
+    RealName        string `json:"real_name"`
+    ProfileImageURL string `json:"profile_image_url"`
+    ProfileImage24  string `json:"profile_image_24"`
+    ProfileImage32  string `json:"profile_image_32"`
+    ProfileImage48  string `json:"profile_image_48"`
+    ProfileImage72  string `json:"profile_image_72"`
+    // ResolvedName is the name shown for the user, picked by the display
+    // name precedence of the workspace
+    ResolvedName string `json:"resolved_name"`
+    Title        string `json:"title"`
+    Team         string `json:"team"`
+    Email        string `json:"email"`
+    Deactivated  bool   `json:"deactivated"`
+}
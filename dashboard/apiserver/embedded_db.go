@@ -0,0 +1,119 @@
+package apiserver
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/config"
+    "dashboard/apiserver/logger"
+
+    embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// Settings of the database the quickstart mode embeds when none is set up.
+// The data is kept in the directory across restarts.
+const (
+    embeddedDBEnv     = "YB_OPEN_THREADS_REMINDER_EMBEDDED_DB"
+    embeddedDBDirEnv  = "YB_OPEN_THREADS_REMINDER_EMBEDDED_DB_DIR"
+    embeddedDBPortEnv = "YB_OPEN_THREADS_REMINDER_EMBEDDED_DB_PORT"
+)
+
+const (
+    embeddedDBPort = 15432
+    embeddedDBName = "open_thread_db"
+    // embeddedDBProbeTimeout bounds looking for a database on the default
+    // settings before embedding one
+    embeddedDBProbeTimeout = 3 * time.Second
+)
+
+// databaseConfigured reports whether the database is set up by the config
+// file or any database setting of the environment.
+func databaseConfigured() bool {
+    if os.Getenv(config.FileEnv) != "" {
+        return true
+    }
+    for _, env := range os.Environ() {
+        if strings.HasPrefix(env, "YB_OPEN_THREADS_REMINDER_DB_") {
+            return true
+        }
+    }
+    return false
+}
+
+// defaultDatabaseReachable reports whether a database answers on the
+// default settings, such as a local YugabyteDB.
+var defaultDatabaseReachable = func() bool {
+    db, err := sql.Open("postgres", config.Default().Database.ConnString())
+    if err != nil {
+        return false
+    }
+    defer db.Close()
+    ctx, cancel := context.WithTimeout(context.Background(), embeddedDBProbeTimeout)
+    defer cancel()
+    return db.PingContext(ctx) == nil
+}
+
+// embeddedServer is the PostgreSQL server embedded by quickstart.
+type embeddedServer interface {
+    Start() error
+    Stop() error
+}
+
+// newEmbeddedServer returns the server listening on port with its files in
+// dir: the data in data, the binaries in runtime and the archive they are
+// extracted from, downloaded from Maven Central, in cache.
+var newEmbeddedServer = func(port uint32, dir string) embeddedServer {
+    return embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+        Port(port).
+        Database(embeddedDBName).
+        DataPath(filepath.Join(dir, "data")).
+        RuntimePath(filepath.Join(dir, "runtime")).
+        CachePath(filepath.Join(dir, "cache")).
+        Logger(io.Discard))
+}
+
+// startEmbeddedDB starts a PostgreSQL server for the quickstart mode when no
+// database is set up and none answers on the default settings. The binaries
+// are downloaded on the first run. It returns the DSN of the server and the
+// function stopping it, an empty DSN and nil when none was started.
+func startEmbeddedDB(log logger.Logger) (string, func(), error) {
+    enabled, err := strconv.ParseBool(getEnv(embeddedDBEnv, "true"))
+    if err != nil {
+        return "", nil, fmt.Errorf("%s must be true or false", embeddedDBEnv)
+    }
+    if !enabled || databaseConfigured() || defaultDatabaseReachable() {
+        return "", nil, nil
+    }
+
+    port, err := strconv.ParseUint(getEnv(embeddedDBPortEnv, strconv.Itoa(embeddedDBPort)), 10, 16)
+    if err != nil || port == 0 {
+        return "", nil, fmt.Errorf("%s must be a port number", embeddedDBPortEnv)
+    }
+    dir := getEnv(embeddedDBDirEnv, "")
+    if dir == "" {
+        home, err := os.UserHomeDir()
+        if err != nil {
+            return "", nil, fmt.Errorf("set %s, the home directory is unknown: %w", embeddedDBDirEnv, err)
+        }
+        dir = filepath.Join(home, ".open-threads-reminder", "postgres")
+    }
+
+    log.Infof("quickstart: no database is set up, starting an embedded PostgreSQL on port %d with its data in %s", port, dir)
+    server := newEmbeddedServer(uint32(port), dir)
+    if err := server.Start(); err != nil {
+        return "", nil, fmt.Errorf("failed to start the embedded database: %w", err)
+    }
+    dsn := fmt.Sprintf("host=127.0.0.1 port=%d user=postgres password=postgres dbname=%s sslmode=disable", port, embeddedDBName)
+    return dsn, func() {
+        if err := server.Stop(); err != nil {
+            log.Warnf("failed to stop the embedded database: %v", err)
+        }
+    }, nil
+}
@@ -0,0 +1,139 @@
+package apiserver
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "dashboard/apiserver/config"
+    "dashboard/apiserver/logger"
+)
+
+// clearDatabaseEnv unsets the database settings of the environment for the
+// duration of the test.
+func clearDatabaseEnv(t *testing.T) {
+    for _, env := range os.Environ() {
+        name, _, _ := strings.Cut(env, "=")
+        if strings.HasPrefix(name, "YB_OPEN_THREADS_REMINDER_DB_") || name == config.FileEnv {
+            t.Setenv(name, "")
+            os.Unsetenv(name)
+        }
+    }
+}
+
+func TestDatabaseConfigured(t *testing.T) {
+    clearDatabaseEnv(t)
+    if databaseConfigured() {
+        t.Fatal("configured without any setting")
+    }
+    t.Setenv("YB_OPEN_THREADS_REMINDER_DB_HOST", "db.internal")
+    if !databaseConfigured() {
+        t.Fatal("not configured with the host set")
+    }
+}
+
+func TestStartEmbeddedDBSkipped(t *testing.T) {
+    log, _ := logger.NewLogger(logger.Error)
+    clearDatabaseEnv(t)
+    t.Setenv("YB_OPEN_THREADS_REMINDER_DB_DSN", "host=db.internal")
+    dsn, stop, err := startEmbeddedDB(log)
+    if err != nil || stop != nil || dsn != "" {
+        t.Fatalf("started with a database set up: %v", err)
+    }
+
+    clearDatabaseEnv(t)
+    t.Setenv(embeddedDBEnv, "false")
+    dsn, stop, err = startEmbeddedDB(log)
+    if err != nil || stop != nil || dsn != "" {
+        t.Fatalf("started while turned off: %v", err)
+    }
+    if _, ok := os.LookupEnv("YB_OPEN_THREADS_REMINDER_DB_DSN"); ok {
+        t.Fatal("database settings changed")
+    }
+
+    t.Setenv(embeddedDBEnv, "maybe")
+    if _, _, err := startEmbeddedDB(log); err == nil {
+        t.Fatal("invalid setting accepted")
+    }
+}
+
+// fakeServer records being started and stopped instead of running
+// PostgreSQL.
+type fakeServer struct {
+    port     uint32
+    dir      string
+    startErr error
+    started  bool
+    stopped  bool
+}
+
+func (s *fakeServer) Start() error {
+    s.started = s.startErr == nil
+    return s.startErr
+}
+
+func (s *fakeServer) Stop() error {
+    s.stopped = true
+    return nil
+}
+
+// embedFake makes startEmbeddedDB start server, as if no database answered
+// on the default settings.
+func embedFake(t *testing.T, server *fakeServer) {
+    reachable, newServer := defaultDatabaseReachable, newEmbeddedServer
+    t.Cleanup(func() { defaultDatabaseReachable, newEmbeddedServer = reachable, newServer })
+    defaultDatabaseReachable = func() bool { return false }
+    newEmbeddedServer = func(port uint32, dir string) embeddedServer {
+        server.port, server.dir = port, dir
+        return server
+    }
+}
+
+func TestStartEmbeddedDB(t *testing.T) {
+    log, _ := logger.NewLogger(logger.Error)
+    clearDatabaseEnv(t)
+    home := t.TempDir()
+    t.Setenv("HOME", home)
+    t.Setenv(embeddedDBPortEnv, "25432")
+    server := &fakeServer{}
+    embedFake(t, server)
+
+    dsn, stop, err := startEmbeddedDB(log)
+    if err != nil || stop == nil {
+        t.Fatalf("got %v, want the embedded database started", err)
+    }
+    if !server.started || server.port != 25432 || server.dir != filepath.Join(home, ".open-threads-reminder", "postgres") {
+        t.Fatalf("started %+v, want port 25432 with its files in the home directory", server)
+    }
+    if want := "host=127.0.0.1 port=25432 user=postgres password=postgres dbname=open_thread_db sslmode=disable"; dsn != want {
+        t.Fatalf("got DSN %q, want %q", dsn, want)
+    }
+    // The DSN is handed to the container, the environment is left alone
+    if databaseConfigured() {
+        t.Fatal("database settings of the environment changed")
+    }
+    stop()
+    if !server.stopped {
+        t.Fatal("the embedded database was not stopped")
+    }
+}
+
+func TestStartEmbeddedDBFailure(t *testing.T) {
+    log, _ := logger.NewLogger(logger.Error)
+    clearDatabaseEnv(t)
+    t.Setenv(embeddedDBDirEnv, t.TempDir())
+    server := &fakeServer{startErr: errors.New("download failed")}
+    embedFake(t, server)
+
+    dsn, stop, err := startEmbeddedDB(log)
+    if err == nil || !strings.Contains(err.Error(), "download failed") || stop != nil || dsn != "" {
+        t.Fatalf("got %q, %v, want the start failure", dsn, err)
+    }
+
+    t.Setenv(embeddedDBPortEnv, "70000")
+    if _, _, err := startEmbeddedDB(log); err == nil {
+        t.Fatal("invalid port accepted")
+    }
+}
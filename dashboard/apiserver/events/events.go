@@ -0,0 +1,108 @@
+// Package events is the bus the subsystems of the dashboard publish what
+// happened on, so ingestion, analysis, reminders, webhooks and live updates
+// react to each other without calling each other.
+package events
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "os"
+    "regexp"
+    "strings"
+    "time"
+)
+
+// Topics events are published on.
+const (
+    // TopicAction carries the actions written to the audit log
+    TopicAction = "action"
+    // TopicThreadMessage carries the messages ingested from Slack, thread
+    // roots and replies
+    TopicThreadMessage = "thread.message"
+    // TopicThreadAnalyzed carries the threads whose AI analysis was stored
+    TopicThreadAnalyzed = "thread.analyzed"
+)
+
+// ErrClosed is returned when publishing on or subscribing to a closed bus.
+var ErrClosed = errors.New("event bus is closed")
+
+// Event is something that happened to a thread, or to the dashboard when
+// ChannelID is empty. Workspace is the workspace whose database it happened
+// in, empty for the shared one.
+type Event struct {
+    Topic      string                 `json:"topic"`
+    Workspace  string                 `json:"workspace,omitempty"`
+    Action     string                 `json:"action,omitempty"`
+    Actor      string                 `json:"actor,omitempty"`
+    ChannelID  string                 `json:"channel_id,omitempty"`
+    ThreadTS   string                 `json:"thread_ts,omitempty"`
+    Details    map[string]interface{} `json:"details,omitempty"`
+    OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// Handler reacts to the events of a subscription, one at a time.
+type Handler func(ctx context.Context, event Event)
+
+// subscribeOptions are the options a subscription was made with.
+type subscribeOptions struct {
+    perInstance bool
+}
+
+// SubscribeOption changes how a subscription receives events.
+type SubscribeOption func(o *subscribeOptions)
+
+// PerInstance has every instance of the dashboard receive the events of a
+// subscription, for subscribers keeping state in memory such as live
+// updates. Otherwise the instances subscribing under the same name share
+// the events, each handled by one of them.
+func PerInstance() SubscribeOption {
+    return func(o *subscribeOptions) { o.perInstance = true }
+}
+
+func applySubscribeOptions(options []SubscribeOption) subscribeOptions {
+    var o subscribeOptions
+    for _, option := range options {
+        option(&o)
+    }
+    return o
+}
+
+// Bus delivers the events published on a topic to every subscription to
+// it. Publishing never waits for handlers. A MemoryBus drops events when
+// subscribers fall behind, brokers deliver them at least once, so handlers
+// must tolerate seeing an event twice.
+type Bus interface {
+    Publish(ctx context.Context, event Event) error
+    // Subscribe calls handler with the events of topic until unsubscribe
+    // is called or the bus is closed. The name of the subscription tells
+    // subscribers apart in logs, and names its consumer group on brokers.
+    Subscribe(topic string, name string, handler Handler, options ...SubscribeOption) (unsubscribe func(), err error)
+    // Close stops accepting events and returns once the events already
+    // published were handled.
+    Close() error
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// consumerName returns the name of the consumer group of a subscription on
+// a broker, unique to this process for PerInstance subscriptions.
+func consumerName(prefix string, topic string, name string, perInstance bool) string {
+    parts := []string{prefix, topic, name}
+    if perInstance {
+        parts = append(parts, instanceID)
+    }
+    for i, part := range parts {
+        parts[i] = strings.Trim(unsafeNameChars.ReplaceAllString(part, "-"), "-")
+    }
+    return strings.Join(parts, "_")
+}
+
+// instanceID tells apart the processes subscribing to a broker.
+var instanceID = func() string {
+    host, _ := os.Hostname()
+    buf := make([]byte, 4)
+    _, _ = rand.Read(buf)
+    return host + "-" + hex.EncodeToString(buf)
+}()
@@ -0,0 +1,189 @@
+package events
+
+import (
+    "context"
+    "encoding/json"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/logger"
+
+    "github.com/segmentio/kafka-go"
+)
+
+const (
+    // kafkaRetryDelay is how long a subscription waits after failing to
+    // read from the brokers.
+    kafkaRetryDelay = 5 * time.Second
+    // kafkaCommitTimeout bounds committing a handled event.
+    kafkaCommitTimeout = 10 * time.Second
+)
+
+// KafkaConfig configures a KafkaBus.
+type KafkaConfig struct {
+    // Brokers to bootstrap from
+    Brokers []string
+    // Prefix prefixes the Kafka topics of the topics and the consumer
+    // groups of the subscriptions
+    Prefix string
+}
+
+// KafkaBus is a Bus on Kafka topics, shared by instances of the dashboard
+// deployed apart. Subscriptions are consumer groups committing an event
+// once handled, so events are delivered at least once and the instances
+// subscribing under the same name share them. The events of a thread are
+// kept in order on a single partition.
+type KafkaBus struct {
+    logger logger.Logger
+    config KafkaConfig
+    writer *kafka.Writer
+    // ctx is the context of handlers, consuming the one of subscriptions
+    ctx           context.Context
+    cancel        context.CancelFunc
+    consuming     context.Context
+    stopConsuming context.CancelFunc
+    wg            sync.WaitGroup
+
+    mu     sync.Mutex
+    closed bool
+}
+
+// NewKafkaBus returns a bus on brokers, the topics being created when first
+// published to.
+func NewKafkaBus(log logger.Logger, config KafkaConfig) *KafkaBus {
+    ctx, cancel := context.WithCancel(context.Background())
+    consuming, stopConsuming := context.WithCancel(ctx)
+    return &KafkaBus{
+        logger: log,
+        config: config,
+        writer: &kafka.Writer{
+            Addr:                   kafka.TCP(config.Brokers...),
+            Balancer:               &kafka.Hash{},
+            RequiredAcks:           kafka.RequireAll,
+            AllowAutoTopicCreation: true,
+        },
+        ctx:           ctx,
+        cancel:        cancel,
+        consuming:     consuming,
+        stopConsuming: stopConsuming,
+    }
+}
+
+func (b *KafkaBus) Publish(ctx context.Context, event Event) error {
+    b.mu.Lock()
+    closed := b.closed
+    b.mu.Unlock()
+    if closed {
+        return ErrClosed
+    }
+    data, err := json.Marshal(event)
+    if err != nil {
+        return err
+    }
+    return b.writer.WriteMessages(ctx, kafka.Message{
+        Topic: b.config.Prefix + "." + event.Topic,
+        Key:   []byte(event.ChannelID + "/" + event.ThreadTS),
+        Value: data,
+    })
+}
+
+func (b *KafkaBus) Subscribe(topic string, name string, handler Handler, options ...SubscribeOption) (func(), error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.closed {
+        return nil, ErrClosed
+    }
+
+    // Groups of a single instance only see the events published while
+    // they run
+    o := applySubscribeOptions(options)
+    startOffset := kafka.FirstOffset
+    if o.perInstance {
+        startOffset = kafka.LastOffset
+    }
+    reader := kafka.NewReader(kafka.ReaderConfig{
+        Brokers:     b.config.Brokers,
+        GroupID:     consumerName(b.config.Prefix, topic, name, o.perInstance),
+        Topic:       b.config.Prefix + "." + topic,
+        StartOffset: startOffset,
+    })
+
+    ctx, cancel := context.WithCancel(b.consuming)
+    done := make(chan struct{})
+    b.wg.Add(1)
+    go func() {
+        defer b.wg.Done()
+        defer close(done)
+        defer reader.Close()
+        b.consume(ctx, reader, name, handler)
+    }()
+
+    var once sync.Once
+    return func() {
+        once.Do(func() {
+            cancel()
+            <-done
+        })
+    }, nil
+}
+
+// consume hands the events of reader to handler until ctx is done,
+// committing each once handled.
+func (b *KafkaBus) consume(ctx context.Context, reader *kafka.Reader, name string, handler Handler) {
+    for {
+        msg, err := reader.FetchMessage(ctx)
+        if ctx.Err() != nil {
+            return
+        }
+        if err != nil {
+            b.logger.Warnf("failed to read events for %s: %v", name, err)
+            if !sleep(ctx, kafkaRetryDelay) {
+                return
+            }
+            continue
+        }
+
+        var event Event
+        if err := json.Unmarshal(msg.Value, &event); err != nil {
+            b.logger.Warnf("dropped malformed event for %s: %v", name, err)
+        } else {
+            handler(b.ctx, event)
+        }
+        commitCtx, cancel := context.WithTimeout(b.ctx, kafkaCommitTimeout)
+        err = reader.CommitMessages(commitCtx, msg)
+        cancel()
+        if err != nil {
+            b.logger.Warnf("failed to commit %s event for %s: %v", event.Topic, name, err)
+        }
+    }
+}
+
+// sleep waits for d and reports whether ctx is still running.
+func sleep(ctx context.Context, d time.Duration) bool {
+    select {
+    case <-ctx.Done():
+        return false
+    case <-time.After(d):
+        return true
+    }
+}
+
+// Close stops the subscriptions once their current events are handled,
+// and flushes the events being published.
+func (b *KafkaBus) Close() error {
+    b.mu.Lock()
+    if b.closed {
+        b.mu.Unlock()
+        return nil
+    }
+    b.closed = true
+    b.mu.Unlock()
+
+    b.stopConsuming()
+    b.wg.Wait()
+    b.cancel()
+    return b.writer.Close()
+}
+
+// Ensure that Bus interface is implemented
+var _ Bus = (*KafkaBus)(nil)
@@ -0,0 +1,123 @@
+package events
+
+import (
+    "context"
+    "sync"
+
+    "dashboard/apiserver/logger"
+)
+
+// memoryBacklog is how many events a subscription of a MemoryBus holds
+// before dropping new ones.
+const memoryBacklog = 1024
+
+type memorySubscription struct {
+    topic   string
+    name    string
+    events  chan Event
+    handler Handler
+    done    chan struct{}
+}
+
+// MemoryBus is a Bus within a single apiserver instance, handing events to
+// each subscription through a channel drained by its own goroutine.
+type MemoryBus struct {
+    logger logger.Logger
+    ctx    context.Context
+    cancel context.CancelFunc
+
+    mu            sync.RWMutex
+    closed        bool
+    subscriptions map[*memorySubscription]bool
+}
+
+// NewMemoryBus returns an empty bus.
+func NewMemoryBus(log logger.Logger) *MemoryBus {
+    ctx, cancel := context.WithCancel(context.Background())
+    return &MemoryBus{
+        logger:        log,
+        ctx:           ctx,
+        cancel:        cancel,
+        subscriptions: make(map[*memorySubscription]bool),
+    }
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, event Event) error {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    if b.closed {
+        return ErrClosed
+    }
+    for sub := range b.subscriptions {
+        if sub.topic != event.Topic {
+            continue
+        }
+        select {
+        case sub.events <- event:
+        default:
+            b.logger.Warnf("dropped %s event for %s, its subscriber is falling behind", event.Topic, sub.name)
+        }
+    }
+    return nil
+}
+
+// Subscribe ignores options, the bus having a single instance.
+func (b *MemoryBus) Subscribe(topic string, name string, handler Handler, options ...SubscribeOption) (func(), error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.closed {
+        return nil, ErrClosed
+    }
+    sub := &memorySubscription{
+        topic:   topic,
+        name:    name,
+        events:  make(chan Event, memoryBacklog),
+        handler: handler,
+        done:    make(chan struct{}),
+    }
+    b.subscriptions[sub] = true
+    go func() {
+        defer close(sub.done)
+        for event := range sub.events {
+            sub.handler(b.ctx, event)
+        }
+    }()
+
+    var once sync.Once
+    return func() {
+        once.Do(func() {
+            b.mu.Lock()
+            if b.subscriptions[sub] {
+                delete(b.subscriptions, sub)
+                close(sub.events)
+            }
+            b.mu.Unlock()
+            <-sub.done
+        })
+    }, nil
+}
+
+func (b *MemoryBus) Close() error {
+    b.mu.Lock()
+    if b.closed {
+        b.mu.Unlock()
+        return nil
+    }
+    b.closed = true
+    subscriptions := make([]*memorySubscription, 0, len(b.subscriptions))
+    for sub := range b.subscriptions {
+        close(sub.events)
+        subscriptions = append(subscriptions, sub)
+    }
+    b.subscriptions = map[*memorySubscription]bool{}
+    b.mu.Unlock()
+
+    for _, sub := range subscriptions {
+        <-sub.done
+    }
+    b.cancel()
+    return nil
+}
+
+// Ensure that Bus interface is implemented
+var _ Bus = (*MemoryBus)(nil)
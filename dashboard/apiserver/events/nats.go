@@ -0,0 +1,186 @@
+package events
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/logger"
+
+    "github.com/nats-io/nats.go"
+    "github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+    // natsAckWait is how long a handler may take before its event is
+    // delivered again.
+    natsAckWait = time.Minute
+    // natsMaxDeliver bounds how often an event is delivered when its
+    // handler never gets to ack it.
+    natsMaxDeliver = 5
+    // natsInstanceIdle is how long the consumer of a PerInstance
+    // subscription outlives its process.
+    natsInstanceIdle = 5 * time.Minute
+)
+
+// NATSConfig configures a NATSBus.
+type NATSConfig struct {
+    // URL of the NATS servers, comma separated
+    URL string
+    // Prefix names the stream and prefixes the subjects of the topics
+    Prefix string
+    // MaxAge is how long the stream keeps events, a day when zero
+    MaxAge time.Duration
+}
+
+// NATSBus is a Bus on a NATS JetStream stream, shared by instances of the
+// dashboard deployed apart. Subscriptions are durable consumers acking an
+// event once handled, so events are delivered at least once and the
+// instances subscribing under the same name share them.
+type NATSBus struct {
+    logger logger.Logger
+    config NATSConfig
+    conn   *nats.Conn
+    js     jetstream.JetStream
+    ctx    context.Context
+    cancel context.CancelFunc
+
+    mu       sync.Mutex
+    closed   bool
+    consumes map[jetstream.ConsumeContext]bool
+}
+
+// NewNATSBus connects to NATS and creates or updates the stream of the bus.
+func NewNATSBus(ctx context.Context, log logger.Logger, config NATSConfig) (*NATSBus, error) {
+    if config.MaxAge <= 0 {
+        config.MaxAge = 24 * time.Hour
+    }
+    conn, err := nats.Connect(config.URL, nats.Name(config.Prefix))
+    if err != nil {
+        return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+    }
+    js, err := jetstream.New(conn)
+    if err != nil {
+        conn.Close()
+        return nil, err
+    }
+    _, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+        Name:     config.Prefix,
+        Subjects: []string{config.Prefix + ".>"},
+        MaxAge:   config.MaxAge,
+        Storage:  jetstream.FileStorage,
+    })
+    if err != nil {
+        conn.Close()
+        return nil, fmt.Errorf("failed to create stream %s: %w", config.Prefix, err)
+    }
+
+    busCtx, cancel := context.WithCancel(context.Background())
+    return &NATSBus{
+        logger:   log,
+        config:   config,
+        conn:     conn,
+        js:       js,
+        ctx:      busCtx,
+        cancel:   cancel,
+        consumes: make(map[jetstream.ConsumeContext]bool),
+    }, nil
+}
+
+func (b *NATSBus) Publish(ctx context.Context, event Event) error {
+    b.mu.Lock()
+    closed := b.closed
+    b.mu.Unlock()
+    if closed {
+        return ErrClosed
+    }
+    data, err := json.Marshal(event)
+    if err != nil {
+        return err
+    }
+    _, err = b.js.Publish(ctx, b.config.Prefix+"."+event.Topic, data)
+    return err
+}
+
+func (b *NATSBus) Subscribe(topic string, name string, handler Handler, options ...SubscribeOption) (func(), error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.closed {
+        return nil, ErrClosed
+    }
+
+    // Consumers of a single instance only see the events published while
+    // they run
+    o := applySubscribeOptions(options)
+    config := jetstream.ConsumerConfig{
+        Durable:       consumerName(b.config.Prefix, topic, name, o.perInstance),
+        FilterSubject: b.config.Prefix + "." + topic,
+        AckPolicy:     jetstream.AckExplicitPolicy,
+        AckWait:       natsAckWait,
+        MaxDeliver:    natsMaxDeliver,
+    }
+    if o.perInstance {
+        config.DeliverPolicy = jetstream.DeliverNewPolicy
+        config.InactiveThreshold = natsInstanceIdle
+    }
+    ctx, cancel := context.WithTimeout(b.ctx, 10*time.Second)
+    defer cancel()
+    consumer, err := b.js.CreateOrUpdateConsumer(ctx, b.config.Prefix, config)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create consumer %s: %w", config.Durable, err)
+    }
+
+    consume, err := consumer.Consume(func(msg jetstream.Msg) {
+        var event Event
+        if err := json.Unmarshal(msg.Data(), &event); err != nil {
+            b.logger.Warnf("dropped malformed event for %s: %v", name, err)
+            _ = msg.Term()
+            return
+        }
+        handler(b.ctx, event)
+        if err := msg.Ack(); err != nil {
+            b.logger.Warnf("failed to ack %s event for %s: %v", event.Topic, name, err)
+        }
+    })
+    if err != nil {
+        return nil, err
+    }
+    b.consumes[consume] = true
+
+    var once sync.Once
+    return func() {
+        once.Do(func() {
+            b.mu.Lock()
+            delete(b.consumes, consume)
+            b.mu.Unlock()
+            consume.Stop()
+            <-consume.Closed()
+        })
+    }, nil
+}
+
+// Close stops the subscriptions once their current events are handled.
+// Events they fetched but did not handle yet are delivered again later.
+func (b *NATSBus) Close() error {
+    b.mu.Lock()
+    if b.closed {
+        b.mu.Unlock()
+        return nil
+    }
+    b.closed = true
+    consumes := b.consumes
+    b.consumes = map[jetstream.ConsumeContext]bool{}
+    b.mu.Unlock()
+
+    for consume := range consumes {
+        consume.Stop()
+        <-consume.Closed()
+    }
+    b.cancel()
+    return b.conn.Drain()
+}
+
+// Ensure that Bus interface is implemented
+var _ Bus = (*NATSBus)(nil)
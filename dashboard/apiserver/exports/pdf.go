@@ -0,0 +1,278 @@
+package exports
+
+import (
+    "bytes"
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "unicode"
+)
+
+// Geometry of PDF pages in points: A4 with 2cm margins, keeping room at the
+// bottom for the footer.
+const (
+    pdfPageWidth    = 595.28
+    pdfPageHeight   = 841.89
+    pdfMargin       = 56.69
+    pdfFooterHeight = 24
+    pdfLabelWidth   = 120
+)
+
+// Sizes of PDF text in points.
+const (
+    pdfTitleSize   = 18
+    pdfHeadingSize = 13
+    pdfBodySize    = 10
+    pdfFooterSize  = 8
+)
+
+// pdfLeading is the height of a line relative to its font size.
+const pdfLeading = 1.35
+
+// pdfFont is one of the standard fonts every PDF reader has, so none is
+// embedded.
+type pdfFont struct {
+    resource string
+    widths   [95]int
+}
+
+// Widths of the printable ASCII characters in thousandths of the font size,
+// from the Adobe font metrics. Other characters are taken as wide as digits.
+var (
+    pdfRegular = pdfFont{resource: "F1", widths: [95]int{
+        278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+        556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+        1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+        667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+        333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+        556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+    }}
+    pdfBold = pdfFont{resource: "F2", widths: [95]int{
+        278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+        556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+        975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+        667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+        333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+        611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+    }}
+)
+
+// width returns the width of text in points at size.
+func (f pdfFont) width(text string, size float64) float64 {
+    total := 0
+    for _, r := range text {
+        if r >= ' ' && r <= '~' {
+            total += f.widths[r-' ']
+        } else {
+            total += 556
+        }
+    }
+    return float64(total) * size / 1000
+}
+
+// winAnsi maps the characters of Windows-1252 outside Latin-1 to their code.
+var winAnsi = map[rune]byte{
+    '€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+    'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91,
+    '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+    '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// pdfString returns text as a PDF string literal in WinAnsiEncoding, which
+// the standard fonts cover. Characters it lacks, such as emoji or CJK, are
+// shown as question marks.
+func pdfString(text string) string {
+    var b strings.Builder
+    b.WriteByte('(')
+    for _, r := range text {
+        switch {
+        case r == '(' || r == ')' || r == '\\':
+            b.WriteByte('\\')
+            b.WriteRune(r)
+        case r >= ' ' && r <= '~':
+            b.WriteRune(r)
+        case r >= 0xa0 && r <= 0xff:
+            fmt.Fprintf(&b, "\\%03o", r)
+        case winAnsi[r] != 0:
+            fmt.Fprintf(&b, "\\%03o", winAnsi[r])
+        case unicode.IsSpace(r):
+            b.WriteByte(' ')
+        default:
+            b.WriteByte('?')
+        }
+    }
+    b.WriteByte(')')
+    return b.String()
+}
+
+// PDFDocument lays out a text document on A4 pages: a title, headings,
+// paragraphs and labeled fields, wrapped to the page and continued on new
+// pages as needed. Every page has a footer with the title of the document
+// and the page number.
+type PDFDocument struct {
+    title string
+    pages []*bytes.Buffer
+    y     float64
+}
+
+// NewPDFDocument starts a document titled title.
+func NewPDFDocument(title string) *PDFDocument {
+    d := &PDFDocument{title: title}
+    d.newPage()
+    return d
+}
+
+func (d *PDFDocument) newPage() {
+    d.pages = append(d.pages, &bytes.Buffer{})
+    d.y = pdfPageHeight - pdfMargin
+}
+
+// line writes a line of text at x, going to a new page first when it does
+// not fit on this one.
+func (d *PDFDocument) line(font pdfFont, size float64, x float64, text string, gray float64) {
+    height := size * pdfLeading
+    if d.y-height < pdfMargin+pdfFooterHeight {
+        d.newPage()
+    }
+    d.y -= height
+    d.text(font, size, x, d.y, text, gray)
+}
+
+func (d *PDFDocument) text(font pdfFont, size float64, x float64, y float64, text string, gray float64) {
+    page := d.pages[len(d.pages)-1]
+    fmt.Fprintf(page, "BT %s g /%s %s Tf %s %s Td %s Tj ET\n",
+        pdfNumber(gray), font.resource, pdfNumber(size), pdfNumber(x), pdfNumber(y), pdfString(text))
+}
+
+// Space leaves points of vertical space.
+func (d *PDFDocument) Space(points float64) {
+    d.y -= points
+}
+
+// Title writes the title of the document.
+func (d *PDFDocument) Title(text string) {
+    for _, line := range wrap(pdfBold, pdfTitleSize, text, pdfPageWidth-2*pdfMargin) {
+        d.line(pdfBold, pdfTitleSize, pdfMargin, line, 0)
+    }
+    d.Space(pdfBodySize)
+}
+
+// Heading starts a section, on a new page when it would be the last line of
+// this one.
+func (d *PDFDocument) Heading(text string) {
+    d.Space(pdfBodySize)
+    if d.y-pdfHeadingSize*pdfLeading-2*pdfBodySize*pdfLeading < pdfMargin+pdfFooterHeight {
+        d.newPage()
+    }
+    d.line(pdfBold, pdfHeadingSize, pdfMargin, text, 0)
+    d.Space(pdfBodySize / 2)
+}
+
+// Paragraph writes text wrapped to the page, keeping its line breaks.
+func (d *PDFDocument) Paragraph(text string) {
+    for _, line := range wrap(pdfRegular, pdfBodySize, text, pdfPageWidth-2*pdfMargin) {
+        d.line(pdfRegular, pdfBodySize, pdfMargin, line, 0)
+    }
+    d.Space(pdfBodySize / 2)
+}
+
+// Note writes text wrapped to the page in gray, for remarks on the document.
+func (d *PDFDocument) Note(text string) {
+    for _, line := range wrap(pdfRegular, pdfBodySize, text, pdfPageWidth-2*pdfMargin) {
+        d.line(pdfRegular, pdfBodySize, pdfMargin, line, 0.4)
+    }
+    d.Space(pdfBodySize / 2)
+}
+
+// Field writes a label with its value wrapped beside it.
+func (d *PDFDocument) Field(label string, value string) {
+    lines := wrap(pdfRegular, pdfBodySize, value, pdfPageWidth-2*pdfMargin-pdfLabelWidth)
+    for i, line := range lines {
+        d.line(pdfRegular, pdfBodySize, pdfMargin+pdfLabelWidth, line, 0)
+        if i == 0 {
+            d.text(pdfBold, pdfBodySize, pdfMargin, d.y, label, 0.3)
+        }
+    }
+}
+
+// Bytes returns the document as a PDF file.
+func (d *PDFDocument) Bytes() []byte {
+    var out bytes.Buffer
+    var offsets []int
+    object := func(body string) {
+        offsets = append(offsets, out.Len())
+        fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+    }
+
+    // Objects 1 to 4 are the catalog, the page tree and the fonts, each page
+    // is followed by its content
+    out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+    kids := make([]string, len(d.pages))
+    for i := range d.pages {
+        kids[i] = strconv.Itoa(5+2*i) + " 0 R"
+    }
+    object("<< /Type /Catalog /Pages 2 0 R >>")
+    object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+    object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+    object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+    for i, page := range d.pages {
+        number := fmt.Sprintf("Page %d of %d", i+1, len(d.pages))
+        content := page.String() +
+            fmt.Sprintf("BT 0.4 g /F1 %d Tf %s %s Td %s Tj ET\n", pdfFooterSize,
+                pdfNumber(pdfMargin), pdfNumber(pdfMargin), pdfString(d.title)) +
+            fmt.Sprintf("BT 0.4 g /F1 %d Tf %s %s Td %s Tj ET\n", pdfFooterSize,
+                pdfNumber(pdfPageWidth-pdfMargin-pdfRegular.width(number, pdfFooterSize)), pdfNumber(pdfMargin), pdfString(number))
+
+        object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+            pdfNumber(pdfPageWidth), pdfNumber(pdfPageHeight), 6+2*i))
+        object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+    }
+    object(fmt.Sprintf("<< /Title %s /Producer (open-threads-reminder) >>", pdfString(d.title)))
+
+    xref := out.Len()
+    fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+    for _, offset := range offsets {
+        fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+    }
+    fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, len(offsets), xref)
+    return out.Bytes()
+}
+
+// pdfNumber formats a coordinate with at most two decimals.
+func pdfNumber(n float64) string {
+    return strconv.FormatFloat(math.Round(n*100)/100, 'f', -1, 64)
+}
+
+// wrap breaks text into lines no wider than width, at spaces when possible,
+// keeping its line breaks.
+func wrap(font pdfFont, size float64, text string, width float64) []string {
+    var lines []string
+    for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+        line := ""
+        for _, word := range strings.Fields(paragraph) {
+            candidate := word
+            if line != "" {
+                candidate = line + " " + word
+            }
+            if font.width(candidate, size) <= width {
+                line = candidate
+                continue
+            }
+            if line != "" {
+                lines = append(lines, line)
+            }
+            // Words wider than a line, such as links, are broken anywhere
+            line = ""
+            for _, r := range word {
+                if line != "" && font.width(line+string(r), size) > width {
+                    lines = append(lines, line)
+                    line = ""
+                }
+                line += string(r)
+            }
+        }
+        lines = append(lines, line)
+    }
+    return lines
+}
@@ -0,0 +1,249 @@
+package exports
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "sort"
+    "strings"
+    "time"
+)
+
+// S3Config locates a bucket of an S3 compatible object storage, such as
+// Amazon S3, Google Cloud Storage with HMAC keys, or MinIO.
+type S3Config struct {
+    // Endpoint is the URL of the service, e.g.
+    // https://s3.us-east-1.amazonaws.com or https://storage.googleapis.com
+    Endpoint string
+    Bucket   string
+    // Prefix is prepended to the keys of objects, e.g. "exports/"
+    Prefix          string
+    Region          string
+    AccessKeyID     string
+    SecretAccessKey string
+}
+
+// S3Store keeps objects in a bucket of an S3 compatible object storage,
+// addressed by path and authenticated with AWS Signature Version 4.
+type S3Store struct {
+    endpoint   *url.URL
+    bucket     string
+    prefix     string
+    region     string
+    accessKey  string
+    secretKey  string
+    httpClient *http.Client
+    now        func() time.Time
+}
+
+// NewS3Store returns a store writing to the bucket of cfg. The region
+// defaults to us-east-1, which services without regions accept.
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+    endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+    if err != nil || endpoint.Host == "" || (endpoint.Scheme != "https" && endpoint.Scheme != "http") {
+        return nil, fmt.Errorf("invalid export storage endpoint %q", cfg.Endpoint)
+    }
+    if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+        return nil, errors.New("export storage needs a bucket, an access key ID and a secret access key")
+    }
+    region := cfg.Region
+    if region == "" {
+        region = "us-east-1"
+    }
+    // Downloads are streamed to clients for as long as they take, only
+    // waiting for the service to answer is bounded
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    transport.ResponseHeaderTimeout = time.Minute
+    return &S3Store{
+        endpoint:   endpoint,
+        bucket:     cfg.Bucket,
+        prefix:     cfg.Prefix,
+        region:     region,
+        accessKey:  cfg.AccessKeyID,
+        secretKey:  cfg.SecretAccessKey,
+        httpClient: &http.Client{Transport: transport},
+        now:        time.Now,
+    }, nil
+}
+
+func (s *S3Store) url(key string) (string, error) {
+    if !keyPattern.MatchString(key) {
+        return "", errors.New("invalid export object key")
+    }
+    u := *s.endpoint
+    u.Path += "/" + s.bucket + "/" + s.prefix + key
+    return u.String(), nil
+}
+
+// Put writes an object, replacing any previous one with the same key. The
+// content is spooled to a temporary file first, as uploads need their
+// length and hash up front. Objects only become visible once fully
+// written.
+func (s *S3Store) Put(key string, r io.Reader) (int64, error) {
+    target, err := s.url(key)
+    if err != nil {
+        return 0, err
+    }
+    tmp, err := os.CreateTemp("", "export-upload-*")
+    if err != nil {
+        return 0, err
+    }
+    defer os.Remove(tmp.Name())
+    defer tmp.Close()
+
+    hash := sha256.New()
+    n, err := io.Copy(io.MultiWriter(tmp, hash), r)
+    if err != nil {
+        return 0, err
+    }
+    if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+        return 0, err
+    }
+
+    req, err := http.NewRequest(http.MethodPut, target, io.NopCloser(tmp))
+    if err != nil {
+        return 0, err
+    }
+    req.ContentLength = n
+    resp, err := s.do(req, hex.EncodeToString(hash.Sum(nil)))
+    if err != nil {
+        return 0, err
+    }
+    resp.Body.Close()
+    return n, nil
+}
+
+// Open returns the content of an object.
+func (s *S3Store) Open(key string) (io.ReadCloser, error) {
+    target, err := s.url(key)
+    if err != nil {
+        return nil, err
+    }
+    req, err := http.NewRequest(http.MethodGet, target, nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := s.do(req, emptyPayloadHash)
+    if err != nil {
+        return nil, err
+    }
+    return resp.Body, nil
+}
+
+// Delete removes an object. Missing objects are not an error.
+func (s *S3Store) Delete(key string) error {
+    target, err := s.url(key)
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequest(http.MethodDelete, target, nil)
+    if err != nil {
+        return err
+    }
+    resp, err := s.do(req, emptyPayloadHash)
+    if err == ErrNotFound {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    return nil
+}
+
+// do signs and sends a request, failing with ErrNotFound for missing
+// objects and with the status and body of other unsuccessful responses.
+func (s *S3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+    signRequest(req, payloadHash, s.accessKey, s.secretKey, s.region, s.now())
+    resp, err := s.httpClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode < 300 {
+        return resp, nil
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode == http.StatusNotFound {
+        return nil, ErrNotFound
+    }
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+    return nil, fmt.Errorf("export storage %s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// emptyPayloadHash is the SHA-256 of an empty body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signRequest adds the AWS Signature Version 4 authorization of an S3
+// request to it, signing its host and every header already set.
+func signRequest(req *http.Request, payloadHash string, accessKey string, secretKey string, region string, now time.Time) {
+    now = now.UTC()
+    amzDate := now.Format("20060102T150405Z")
+    day := now.Format("20060102")
+    req.Header.Set("X-Amz-Date", amzDate)
+    req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+    headers := map[string]string{"host": req.URL.Host}
+    for name, values := range req.Header {
+        headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+    }
+    names := make([]string, 0, len(headers))
+    for name := range headers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    var canonicalHeaders strings.Builder
+    for _, name := range names {
+        canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+    }
+    signedHeaders := strings.Join(names, ";")
+
+    canonicalRequest := strings.Join([]string{
+        req.Method,
+        req.URL.EscapedPath(),
+        canonicalQuery(req.URL.Query()),
+        canonicalHeaders.String(),
+        signedHeaders,
+        payloadHash,
+    }, "\n")
+    scope := day + "/" + region + "/s3/aws4_request"
+    requestHash := sha256.Sum256([]byte(canonicalRequest))
+    stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+    key := hmacSHA256([]byte("AWS4"+secretKey), day)
+    for _, part := range []string{region, "s3", "aws4_request"} {
+        key = hmacSHA256(key, part)
+    }
+    signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+    req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery returns the query parameters sorted by name, encoded the
+// way signatures expect.
+func canonicalQuery(query url.Values) string {
+    var params []string
+    for name, values := range query {
+        for _, value := range values {
+            params = append(params, awsEscape(name)+"="+awsEscape(value))
+        }
+    }
+    sort.Strings(params)
+    return strings.Join(params, "&")
+}
+
+// awsEscape percent-encodes everything but unreserved characters.
+func awsEscape(s string) string {
+    return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(data))
+    return mac.Sum(nil)
+}
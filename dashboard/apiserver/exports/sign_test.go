@@ -0,0 +1,51 @@
+package exports
+
+import (
+    "strconv"
+    "testing"
+    "time"
+)
+
+func TestSigner(t *testing.T) {
+    signer := NewSigner("key")
+    expires := time.Now().Add(time.Hour)
+    unix := strconv.FormatInt(expires.Unix(), 10)
+    signature := signer.Sign("T1:42", expires)
+    tampered := []byte(signature)
+    tampered[0] ^= 1
+
+    tests := []struct {
+        name      string
+        signer    *Signer
+        id        string
+        expires   string
+        signature string
+        want      bool
+    }{
+        {"valid", signer, "T1:42", unix, signature, true},
+        {"same key elsewhere", NewSigner("key"), "T1:42", unix, signature, true},
+        {"other export", signer, "T1:43", unix, signature, false},
+        {"other workspace", signer, "T2:42", unix, signature, false},
+        {"extended expiry", signer, "T1:42", strconv.FormatInt(expires.Add(time.Hour).Unix(), 10), signature, false},
+        {"tampered signature", signer, "T1:42", unix, string(tampered), false},
+        {"missing signature", signer, "T1:42", unix, "", false},
+        {"invalid expiry", signer, "T1:42", "soon", signature, false},
+        {"other key", NewSigner("other"), "T1:42", unix, signature, false},
+        {"random key", NewSigner(""), "T1:42", unix, signature, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := tt.signer.Verify(tt.id, tt.expires, tt.signature); got != tt.want {
+                t.Fatalf("got %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestSignerExpiry(t *testing.T) {
+    signer := NewSigner("key")
+    expired := time.Now().Add(-time.Second)
+    if signer.Verify("42", strconv.FormatInt(expired.Unix(), 10), signer.Sign("42", expired)) {
+        t.Fatal("an expired signature verified")
+    }
+}
@@ -0,0 +1,93 @@
+// Package exports stores generated export files in a directory or an S3
+// compatible bucket, writes Excel workbooks and signs the links used to
+// download exports.
+package exports
+
+import (
+    "errors"
+    "io"
+    "os"
+    "path/filepath"
+    "regexp"
+)
+
+// ErrNotFound is returned for objects that do not exist.
+var ErrNotFound = errors.New("export object not found")
+
+// Store is the object storage export files are written to.
+type Store interface {
+    Put(key string, r io.Reader) (int64, error)
+    Open(key string) (io.ReadCloser, error)
+    Delete(key string) error
+}
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// LocalStore keeps objects as files of a directory, e.g. a mounted bucket.
+type LocalStore struct {
+    dir string
+}
+
+// NewLocalStore returns a store writing to dir, creating it when missing.
+func NewLocalStore(dir string) (*LocalStore, error) {
+    if err := os.MkdirAll(dir, 0o700); err != nil {
+        return nil, err
+    }
+    return &LocalStore{dir: dir}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+    if !keyPattern.MatchString(key) {
+        return "", errors.New("invalid export object key")
+    }
+    return filepath.Join(s.dir, key), nil
+}
+
+// Put writes an object, replacing any previous one with the same key. The
+// object only becomes visible once fully written.
+func (s *LocalStore) Put(key string, r io.Reader) (int64, error) {
+    path, err := s.path(key)
+    if err != nil {
+        return 0, err
+    }
+    tmp, err := os.CreateTemp(s.dir, ".upload-*")
+    if err != nil {
+        return 0, err
+    }
+    defer os.Remove(tmp.Name())
+
+    n, err := io.Copy(tmp, r)
+    if err != nil {
+        tmp.Close()
+        return 0, err
+    }
+    if err := tmp.Close(); err != nil {
+        return 0, err
+    }
+    return n, os.Rename(tmp.Name(), path)
+}
+
+// Open returns the content of an object.
+func (s *LocalStore) Open(key string) (io.ReadCloser, error) {
+    path, err := s.path(key)
+    if err != nil {
+        return nil, err
+    }
+    f, err := os.Open(path)
+    if os.IsNotExist(err) {
+        return nil, ErrNotFound
+    }
+    return f, err
+}
+
+// Delete removes an object. Missing objects are not an error.
+func (s *LocalStore) Delete(key string) error {
+    path, err := s.path(key)
+    if err != nil {
+        return err
+    }
+    if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+        return err
+    }
+    return nil
+}
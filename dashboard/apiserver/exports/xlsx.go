@@ -0,0 +1,111 @@
+package exports
+
+import (
+    "archive/zip"
+    "encoding/xml"
+    "io"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// maxCellLength is the most characters a spreadsheet cell holds.
+const maxCellLength = 32767
+
+// integerPattern matches the values written as numbers rather than text.
+var integerPattern = regexp.MustCompile(`^(0|-?[1-9][0-9]{0,14})$`)
+
+// xlsxParts are the parts of a single sheet workbook besides the sheet.
+var xlsxParts = []struct{ name, content string }{
+    {"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`},
+    {"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+    {"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Threads" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`},
+    {"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`},
+}
+
+// XLSXWriter writes rows to the single sheet of an Excel workbook as they
+// come, so that large exports are never held in memory. Integers are
+// written as numbers and everything else as text.
+type XLSXWriter struct {
+    zip   *zip.Writer
+    sheet io.Writer
+    rows  int
+}
+
+// NewXLSXWriter starts a workbook on w.
+func NewXLSXWriter(w io.Writer) (*XLSXWriter, error) {
+    xw := &XLSXWriter{zip: zip.NewWriter(w)}
+    for _, part := range xlsxParts {
+        f, err := xw.zip.Create(part.name)
+        if err != nil {
+            return nil, err
+        }
+        if _, err := io.WriteString(f, part.content); err != nil {
+            return nil, err
+        }
+    }
+    sheet, err := xw.zip.Create("xl/worksheets/sheet1.xml")
+    if err != nil {
+        return nil, err
+    }
+    xw.sheet = sheet
+    _, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+    return xw, err
+}
+
+// Write adds a row to the sheet.
+func (xw *XLSXWriter) Write(record []string) error {
+    xw.rows++
+    var b strings.Builder
+    b.WriteString(`<row r="` + strconv.Itoa(xw.rows) + `">`)
+    for i, value := range record {
+        ref := columnName(i) + strconv.Itoa(xw.rows)
+        if integerPattern.MatchString(value) {
+            b.WriteString(`<c r="` + ref + `"><v>` + value + `</v></c>`)
+            continue
+        }
+        if runes := []rune(value); len(runes) > maxCellLength {
+            value = string(runes[:maxCellLength])
+        }
+        b.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+        xml.EscapeText(&b, []byte(value))
+        b.WriteString(`</t></is></c>`)
+    }
+    b.WriteString(`</row>`)
+    _, err := io.WriteString(xw.sheet, b.String())
+    return err
+}
+
+// Close ends the sheet and the workbook, without closing the underlying
+// writer.
+func (xw *XLSXWriter) Close() error {
+    if _, err := io.WriteString(xw.sheet, `</sheetData></worksheet>`); err != nil {
+        return err
+    }
+    return xw.zip.Close()
+}
+
+// columnName returns the letters of the zero based column i, e.g. AA for 26.
+func columnName(i int) string {
+    name := ""
+    for i++; i > 0; i = (i - 1) / 26 {
+        name = string(rune('A'+(i-1)%26)) + name
+    }
+    return name
+}
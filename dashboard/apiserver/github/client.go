@@ -0,0 +1,242 @@
+// Package github is a minimal GitHub REST API client.
+package github
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const defaultBaseURL = "https://api.github.com"
+
+// ErrNotFound is returned for resources GitHub does not know, or hides
+// from the token.
+var ErrNotFound = errors.New("github: not found")
+
+// repoPattern matches "owner/name" repository references.
+var repoPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// ValidRepo reports whether repo is an "owner/name" reference.
+func ValidRepo(repo string) bool {
+    return repoPattern.MatchString(repo)
+}
+
+// APIError is a response with an unexpected status.
+type APIError struct {
+    Path    string
+    Status  int
+    Message string
+}
+
+func (e *APIError) Error() string {
+    return fmt.Sprintf("github %s: status %d: %s", e.Path, e.Status, e.Message)
+}
+
+// Client calls the GitHub REST API. Without a token only public
+// repositories are reachable, at a low rate limit.
+type Client struct {
+    token      string
+    baseURL    string
+    httpClient *http.Client
+}
+
+// NewClient returns a client for baseURL, the API root of GitHub
+// Enterprise Server installations, or api.github.com when empty.
+func NewClient(token string, baseURL string) *Client {
+    if baseURL == "" {
+        baseURL = defaultBaseURL
+    }
+    return &Client{
+        token:      token,
+        baseURL:    strings.TrimSuffix(baseURL, "/"),
+        httpClient: &http.Client{Timeout: 30 * time.Second},
+    }
+}
+
+// Authenticated reports whether the client has a token. Creating issues
+// needs one.
+func (c *Client) Authenticated() bool {
+    return c.token != ""
+}
+
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+    return c.do(ctx, http.MethodGet, path, nil, out)
+}
+
+func (c *Client) post(ctx context.Context, path string, in interface{}, out interface{}) error {
+    return c.do(ctx, http.MethodPost, path, in, out)
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+    var body io.Reader
+    if in != nil {
+        encoded, err := json.Marshal(in)
+        if err != nil {
+            return err
+        }
+        body = bytes.NewReader(encoded)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+    if err != nil {
+        return err
+    }
+    if in != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    req.Header.Set("Accept", "application/vnd.github+json")
+    req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+    if c.token != "" {
+        req.Header.Set("Authorization", "Bearer "+c.token)
+    }
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode == http.StatusNotFound {
+        return ErrNotFound
+    }
+    if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+        var body struct {
+            Message string `json:"message"`
+        }
+        json.NewDecoder(resp.Body).Decode(&body)
+        return &APIError{Path: path, Status: resp.StatusCode, Message: body.Message}
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Release is a published GitHub release.
+type Release struct {
+    TagName     string     `json:"tag_name"`
+    Name        string     `json:"name"`
+    HTMLURL     string     `json:"html_url"`
+    Draft       bool       `json:"draft"`
+    Prerelease  bool       `json:"prerelease"`
+    PublishedAt *time.Time `json:"published_at"`
+}
+
+// ReleaseByTag returns the release of repo ("owner/name") for tag. It
+// returns ErrNotFound while the tag has no published release.
+func (c *Client) ReleaseByTag(ctx context.Context, repo string, tag string) (*Release, error) {
+    if !ValidRepo(repo) {
+        return nil, fmt.Errorf("github: invalid repository %q", repo)
+    }
+    var release Release
+    if err := c.get(ctx, "/repos/"+repo+"/releases/tags/"+url.PathEscape(tag), &release); err != nil {
+        return nil, err
+    }
+    if release.Draft || release.PublishedAt == nil {
+        return nil, ErrNotFound
+    }
+    return &release, nil
+}
+
+// issueURLPattern matches issue and pull request links of github.com and
+// GitHub Enterprise Server.
+var issueURLPattern = regexp.MustCompile(`^https?://[^/]+/([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)/(?:issues|pull)/([0-9]+)`)
+
+// issueRefPattern matches "owner/name#123" references.
+var issueRefPattern = regexp.MustCompile(`^([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)#([0-9]+)$`)
+
+// ParseIssueRef returns the repository and number of an issue link or an
+// "owner/name#123" reference. ok is false for anything else.
+func ParseIssueRef(ref string) (repo string, number int, ok bool) {
+    ref = strings.TrimSpace(ref)
+    match := issueURLPattern.FindStringSubmatch(ref)
+    if match == nil {
+        match = issueRefPattern.FindStringSubmatch(ref)
+    }
+    if match == nil {
+        return "", 0, false
+    }
+    number, err := strconv.Atoi(match[2])
+    if err != nil || number <= 0 {
+        return "", 0, false
+    }
+    return match[1], number, true
+}
+
+// Issue is a GitHub issue or pull request.
+type Issue struct {
+    Number    int        `json:"number"`
+    Title     string     `json:"title"`
+    State     string     `json:"state"`
+    HTMLURL   string     `json:"html_url"`
+    Labels    []Label    `json:"labels"`
+    Assignees []User     `json:"assignees"`
+    UpdatedAt time.Time  `json:"updated_at"`
+    ClosedAt  *time.Time `json:"closed_at"`
+}
+
+// Label is a label of an issue.
+type Label struct {
+    Name string `json:"name"`
+}
+
+// User is a GitHub account.
+type User struct {
+    Login string `json:"login"`
+}
+
+// Issue returns issue number of repo ("owner/name").
+func (c *Client) Issue(ctx context.Context, repo string, number int) (*Issue, error) {
+    if !ValidRepo(repo) {
+        return nil, fmt.Errorf("github: invalid repository %q", repo)
+    }
+    var issue Issue
+    if err := c.get(ctx, fmt.Sprintf("/repos/%s/issues/%d", repo, number), &issue); err != nil {
+        return nil, err
+    }
+    return &issue, nil
+}
+
+// NewIssue is the body of CreateIssue.
+type NewIssue struct {
+    Title  string   `json:"title"`
+    Body   string   `json:"body"`
+    Labels []string `json:"labels,omitempty"`
+}
+
+// CreateIssue opens an issue in repo ("owner/name").
+func (c *Client) CreateIssue(ctx context.Context, repo string, issue NewIssue) (*Issue, error) {
+    if !ValidRepo(repo) {
+        return nil, fmt.Errorf("github: invalid repository %q", repo)
+    }
+    var created Issue
+    if err := c.post(ctx, "/repos/"+repo+"/issues", issue, &created); err != nil {
+        return nil, err
+    }
+    return &created, nil
+}
+
+// Comment is a comment on an issue or pull request.
+type Comment struct {
+    ID      int64  `json:"id"`
+    HTMLURL string `json:"html_url"`
+}
+
+// CreateComment comments body, in Markdown, on issue number of repo
+// ("owner/name").
+func (c *Client) CreateComment(ctx context.Context, repo string, number int, body string) (*Comment, error) {
+    if !ValidRepo(repo) {
+        return nil, fmt.Errorf("github: invalid repository %q", repo)
+    }
+    var created Comment
+    path := fmt.Sprintf("/repos/%s/issues/%d/comments", repo, number)
+    if err := c.post(ctx, path, map[string]string{"body": body}, &created); err != nil {
+        return nil, err
+    }
+    return &created, nil
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// How a thread was first acknowledged.
+const (
+    ackSourceReply  = "reply"
+    ackSourceAction = "action"
+    ackSourceClaim  = "claim"
+)
+
+// ThreadAck is the first acknowledgment of a thread
+type ThreadAck struct {
+    ChannelID string    `json:"channel_id"`
+    ThreadTS  string    `json:"thread_ts"`
+    UserID    string    `json:"user_id"`
+    Source    string    `json:"source"`
+    AckedAt   time.Time `json:"acked_at"`
+    // LatencySeconds is the time between the thread being posted and
+    // acknowledged
+    LatencySeconds float64 `json:"latency_seconds"`
+}
+
+// AckLatency summarizes how quickly a person acknowledges threads
+type AckLatency struct {
+    UserID        string  `json:"user_id"`
+    Acknowledged  int     `json:"acknowledged"`
+    Replies       int     `json:"replies"`
+    AvgSeconds    float64 `json:"avg_seconds"`
+    MedianSeconds float64 `json:"median_seconds"`
+    P90Seconds    float64 `json:"p90_seconds"`
+}
+
+// AcknowledgeThread - Acknowledge a thread as its first responder
+func (c *Container) AcknowledgeThread(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    // Acknowledgments feed per-person latency, so they need a person
+    principal, ok := auth.PrincipalFrom(ctx)
+    if !ok || principal.UserID == "" {
+        return apierror.New(http.StatusUnauthorized, "Acknowledging a thread requires a signed in user")
+    }
+
+    ack, first, err := c.acks.Acknowledge(ctx.Request().Context(), principal.UserID, ackSourceAction, channelID, threadTS)
+    if err == errChannelNotTracked || err == errThreadNotFound {
+        return threadLookupError(ctx, err)
+    }
+    if err != nil {
+        c.logger.Errorf("failed to acknowledge thread %s/%s: %v", channelID, threadTS, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to acknowledge thread")
+    }
+
+    if first {
+        c.audit(ctx.Request().Context(), principal.UserID, "thread.ack", channelID, threadTS, nil)
+    }
+
+    return ctx.JSON(http.StatusOK, ack)
+}
+
+// ackThreadAction acknowledges the thread identified by value
+// ("channel_id:thread_ts") on behalf of userID from a Slack button and
+// returns the message to show them.
+func (c *Container) ackThreadAction(ctx context.Context, userID string, value string) (string, error) {
+    channelID, threadTS, ok := strings.Cut(value, ":")
+    if !ok || userID == "" {
+        return "", fmt.Errorf("invalid ack value %q", value)
+    }
+
+    ack, first, err := c.acks.Acknowledge(ctx, userID, ackSourceAction, channelID, threadTS)
+    if err == errChannelNotTracked {
+        return "This channel is no longer tracked.", nil
+    }
+    if err == errThreadNotFound {
+        return "This thread no longer exists.", nil
+    }
+    if err != nil {
+        return "", err
+    }
+    if !first {
+        if ack.UserID == userID {
+            return "You already acknowledged this thread.", nil
+        }
+        return fmt.Sprintf("<@%s> already acknowledged this thread.", ack.UserID), nil
+    }
+
+    c.audit(ctx, userID, "thread.ack", channelID, threadTS, nil)
+    return fmt.Sprintf("You acknowledged <%s|this thread>.", slack.Permalink(channelID, threadTS)), nil
+}
+
+// GetAckLatency - Get how quickly each person first acknowledges threads
+func (c *Container) GetAckLatency(ctx echo.Context) error {
+    var since time.Time
+    if sinceStr := ctx.QueryParam("since"); sinceStr != "" {
+        var err error
+        since, err = time.Parse(time.RFC3339, sinceStr)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, "since must be an RFC3339 timestamp")
+        }
+    }
+
+    latencies, err := c.acks.AckLatency(ctx.Request().Context(), ctx.QueryParam("channel_id"), since)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query acknowledgments")
+    }
+
+    return ctx.JSON(http.StatusOK, latencies)
+}
+
+func (s postgresStore) RecordAck(ctx context.Context, channelID, threadTS, userID, source string, threadCreatedAt, ackedAt time.Time) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+    return recordAck(ctx, db, channelID, threadTS, userID, source, threadCreatedAt, ackedAt)
+}
+
+// recordAck stores the first acknowledgment of a thread and reports whether
+// it was the first. Later acknowledgments are ignored.
+func recordAck(ctx context.Context, db *sql.DB, channelID, threadTS, userID, source string, threadCreatedAt, ackedAt time.Time) (bool, error) {
+    result, err := db.ExecContext(ctx, `
+        INSERT INTO thread_acks (channel_id, thread_ts, user_id, source, thread_created_at, acked_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (channel_id, thread_ts) DO NOTHING
+    `, channelID, threadTS, userID, source, threadCreatedAt.UTC(), ackedAt.UTC())
+    if err != nil {
+        return false, err
+    }
+    inserted, _ := result.RowsAffected()
+    return inserted > 0, nil
+}
+
+func (s postgresStore) Acknowledge(ctx context.Context, userID, source, channelID, threadTS string) (ThreadAck, bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ThreadAck{}, false, err
+    }
+    if err := channelTracked(db, channelID); err != nil {
+        return ThreadAck{}, false, err
+    }
+
+    var createdAt time.Time
+    err = db.QueryRowContext(ctx, "SELECT created_at FROM threads WHERE thread_ts = $1 AND channel_id = $2",
+        threadTS, channelID).Scan(&createdAt)
+    if err == sql.ErrNoRows {
+        return ThreadAck{}, false, errThreadNotFound
+    }
+    if err != nil {
+        return ThreadAck{}, false, err
+    }
+
+    first, err := recordAck(ctx, db, channelID, threadTS, userID, source, createdAt, time.Now())
+    if err != nil {
+        return ThreadAck{}, false, err
+    }
+    ack := ThreadAck{ChannelID: channelID, ThreadTS: threadTS}
+    err = db.QueryRowContext(ctx, `
+        SELECT user_id, source, acked_at, EXTRACT(EPOCH FROM acked_at - thread_created_at)
+        FROM thread_acks WHERE channel_id = $1 AND thread_ts = $2
+    `, channelID, threadTS).Scan(&ack.UserID, &ack.Source, &ack.AckedAt, &ack.LatencySeconds)
+    return ack, first, err
+}
+
+func (s postgresStore) AckLatency(ctx context.Context, channelID string, since time.Time) ([]AckLatency, error) {
+    query := `
+        SELECT user_id, COUNT(*), COUNT(*) FILTER (WHERE source = 'reply'),
+               AVG(EXTRACT(EPOCH FROM acked_at - thread_created_at)),
+               PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM acked_at - thread_created_at)),
+               PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM acked_at - thread_created_at))
+        FROM thread_acks
+        WHERE 1=1`
+    args := []interface{}{}
+
+    if channelID != "" {
+        args = append(args, channelID)
+        query += fmt.Sprintf(" AND channel_id = $%d", len(args))
+    }
+    if !since.IsZero() {
+        args = append(args, since.UTC())
+        query += fmt.Sprintf(" AND acked_at >= $%d", len(args))
+    }
+    query += " GROUP BY user_id ORDER BY 5"
+
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    latencies := []AckLatency{}
+    for rows.Next() {
+        var l AckLatency
+        if err := rows.Scan(&l.UserID, &l.Acknowledged, &l.Replies, &l.AvgSeconds, &l.MedianSeconds, &l.P90Seconds); err != nil {
+            continue
+        }
+        latencies = append(latencies, l)
+    }
+    return latencies, rows.Err()
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestAcknowledgeThreadKeepsTheFirst(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen,
+        CreatedAt: time.Now().Add(-time.Hour)}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    acknowledge := func(userID string, target string) (int, ThreadAck) {
+        principal := &auth.Principal{UserID: userID, Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+        rec := serveAs(c, principal, http.MethodPost, "/api/threads/:channel_id/:thread_ts/ack", target, "", c.AcknowledgeThread)
+        var ack ThreadAck
+        if rec.Code == http.StatusOK {
+            decodeResponse(t, rec, &ack)
+        }
+        return rec.Code, ack
+    }
+
+    if code, _ := acknowledge("U1", "/api/threads/C2/1700000000.000100/ack"); code != http.StatusNotFound {
+        t.Fatalf("untracked channel got status %d", code)
+    }
+    if code, _ := acknowledge("U1", "/api/threads/C1/1700000000.000200/ack"); code != http.StatusNotFound {
+        t.Fatalf("missing thread got status %d", code)
+    }
+    for _, userID := range []string{"U1", "U2"} {
+        code, ack := acknowledge(userID, "/api/threads/C1/1700000000.000100/ack")
+        if code != http.StatusOK || ack.UserID != "U1" || ack.Source != ackSourceAction || ack.LatencySeconds < 3600 {
+            t.Fatalf("%s got status %d and %+v, want the acknowledgment of U1 an hour in", userID, code, ack)
+        }
+    }
+    if audited := store.Audited(); len(audited) != 1 || audited[0].Action != "thread.ack" || audited[0].Actor != "U1" {
+        t.Fatalf("audited %+v, want the first acknowledgment", audited)
+    }
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/stats/ack-latency", "/api/stats/ack-latency?channel_id=C1", c.GetAckLatency)
+    var latencies []AckLatency
+    decodeResponse(t, rec, &latencies)
+    if len(latencies) != 1 || latencies[0].UserID != "U1" || latencies[0].Acknowledged != 1 {
+        t.Fatalf("got latencies %+v, want U1 with one acknowledgment", latencies)
+    }
+}
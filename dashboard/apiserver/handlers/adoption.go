@@ -0,0 +1,410 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+)
+
+// usageFlushInterval is how often API call counts are written to the
+// database.
+const usageFlushInterval = time.Minute
+
+// API clients counted apart from personal tokens, which are counted as
+// "token:<id>".
+const (
+    clientDashboard = "dashboard"
+    clientAnonymous = "anonymous"
+)
+
+type usageKey struct {
+    day    string
+    userID string
+    client string
+    method string
+    route  string
+}
+
+// usageCounter counts API calls in memory between flushes.
+type usageCounter struct {
+    mu     sync.Mutex
+    counts map[usageKey]int64
+}
+
+func newUsageCounter() *usageCounter {
+    return &usageCounter{counts: make(map[usageKey]int64)}
+}
+
+func (u *usageCounter) add(key usageKey, calls int64) {
+    u.mu.Lock()
+    u.counts[key] += calls
+    u.mu.Unlock()
+}
+
+// drain returns the counts since the last drain and resets them.
+func (u *usageCounter) drain() map[usageKey]int64 {
+    u.mu.Lock()
+    defer u.mu.Unlock()
+    counts := u.counts
+    u.counts = make(map[usageKey]int64)
+    return counts
+}
+
+// usageClient returns the user and client of a request.
+func usageClient(ctx echo.Context) (string, string) {
+    principal, ok := auth.PrincipalFrom(ctx)
+    if !ok {
+        return clientAnonymous, clientAnonymous
+    }
+    if credential := principal.Credential(); credential != "" {
+        return principal.UserID, credential
+    }
+    return principal.UserID, clientDashboard
+}
+
+// TrackUsage counts the API calls of every user and client per route for
+// the adoption report. Requests to unknown routes are not counted.
+func (c *Container) TrackUsage(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+        err := next(ctx)
+        if route := ctx.Path(); route != "" && !strings.HasSuffix(route, "*") {
+            userID, client := usageClient(ctx)
+            c.usage.add(usageKey{
+                day:    time.Now().UTC().Format(time.DateOnly),
+                userID: userID,
+                client: client,
+                method: ctx.Request().Method,
+                route:  route,
+            }, 1)
+        }
+        return err
+    }
+}
+
+// RunUsageFlush writes the counted API calls to the database until ctx is
+// done.
+func (c *Container) RunUsageFlush(ctx context.Context) {
+    ticker := time.NewTicker(usageFlushInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            if err := c.flushUsage(ctx); err != nil {
+                c.logger.Warnf("failed to write API usage: %v", err)
+            }
+        }
+    }
+}
+
+// flushUsage adds the counted API calls to the usage of the API. Counts
+// that could not be written are kept for the next flush.
+func (c *Container) flushUsage(ctx context.Context) error {
+    var failed error
+    for key, calls := range c.usage.drain() {
+        if err := c.adoption.RecordUsage(ctx, key, calls); err != nil {
+            c.usage.add(key, calls)
+            failed = err
+        }
+    }
+    return failed
+}
+
+// DailyCount is a count for a UTC day.
+type DailyCount struct {
+    Day   string `json:"day"`
+    Count int64  `json:"count"`
+}
+
+// ClientUsage is the API usage of a client. Personal tokens are listed by
+// their name and owner.
+type ClientUsage struct {
+    Client   string  `json:"client"`
+    Name     string  `json:"name"`
+    UserID   *string `json:"user_id"`
+    Users    int     `json:"users"`
+    Calls    int64   `json:"calls"`
+    LastUsed string  `json:"last_used"`
+}
+
+// ReminderAdoption describes who receives reminders and who opted out of
+// reminder emails.
+type ReminderAdoption struct {
+    Recipients int `json:"recipients"`
+    Threads    int `json:"threads"`
+    // Addresses are the email addresses that saved preferences, OptedOut
+    // those of them not receiving the digest any more
+    Addresses     int     `json:"addresses"`
+    OptedOut      int     `json:"opted_out"`
+    OptedOutSince int     `json:"opted_out_since"`
+    OptOutRate    float64 `json:"opt_out_rate"`
+}
+
+// FeatureUsage is how often a feature was used. API features are routes,
+// action features are audited actions.
+type FeatureUsage struct {
+    Feature string       `json:"feature"`
+    Kind    string       `json:"kind"`
+    Total   int64        `json:"total"`
+    Daily   []DailyCount `json:"daily"`
+}
+
+// AdoptionReport is the response of GetAdoption.
+type AdoptionReport struct {
+    Since            time.Time        `json:"since"`
+    ActiveUsers      int              `json:"active_users"`
+    DailyActiveUsers []DailyCount     `json:"daily_active_users"`
+    Clients          []ClientUsage    `json:"clients"`
+    Reminders        ReminderAdoption `json:"reminders"`
+    Features         []FeatureUsage   `json:"features"`
+    UnusedFeatures   []string         `json:"unused_features"`
+}
+
+// GetAdoption - Report active users, API clients, reminder opt-outs and feature usage (admin only)
+func (c *Container) GetAdoption(ctx echo.Context) error {
+    since := time.Now().UTC().AddDate(0, 0, -30)
+    if sinceStr := ctx.QueryParam("since"); sinceStr != "" {
+        parsed, err := time.Parse(time.RFC3339, sinceStr)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, "since must be an RFC3339 timestamp")
+        }
+        since = parsed.UTC()
+    }
+    since = since.Truncate(24 * time.Hour)
+
+    reqCtx := ctx.Request().Context()
+    if err := c.flushUsage(reqCtx); err != nil {
+        c.logger.Warnf("failed to write API usage: %v", err)
+    }
+
+    report := AdoptionReport{
+        Since:          since,
+        UnusedFeatures: []string{},
+    }
+
+    var err error
+    report.ActiveUsers, report.DailyActiveUsers, err = c.adoption.ActiveUsers(reqCtx, since)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query active users")
+    }
+    if report.Clients, err = c.adoption.APIClients(reqCtx, since); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query API clients")
+    }
+    if report.Reminders, err = c.adoption.ReminderAdoption(reqCtx, since); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query reminder opt-outs")
+    }
+    if report.Features, err = c.adoption.FeatureUsage(reqCtx, since); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query feature usage")
+    }
+
+    used := make(map[string]bool, len(report.Features))
+    for _, feature := range report.Features {
+        if feature.Kind == "api" {
+            used[feature.Feature] = true
+        }
+    }
+    sort.SliceStable(report.Features, func(i, j int) bool {
+        return report.Features[i].Total > report.Features[j].Total
+    })
+    for _, route := range ctx.Echo().Routes() {
+        if !strings.HasPrefix(route.Path, "/api/") || strings.HasSuffix(route.Path, "*") {
+            continue
+        }
+        if feature := route.Method + " " + route.Path; !used[feature] {
+            used[feature] = true
+            report.UnusedFeatures = append(report.UnusedFeatures, feature)
+        }
+    }
+    sort.Strings(report.UnusedFeatures)
+
+    return ctx.JSON(http.StatusOK, report)
+}
+
+// queryDailyCounts returns the (day, count) rows of query.
+func queryDailyCounts(db *sql.DB, query string, args ...interface{}) ([]DailyCount, error) {
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    counts := []DailyCount{}
+    for rows.Next() {
+        var day time.Time
+        var count DailyCount
+        if err := rows.Scan(&day, &count.Count); err != nil {
+            return nil, err
+        }
+        count.Day = day.Format(time.DateOnly)
+        counts = append(counts, count)
+    }
+    return counts, rows.Err()
+}
+
+// queryFeatureUsage returns the features of the (feature, day, count) rows
+// of query, ordered by day.
+func queryFeatureUsage(db *sql.DB, kind string, query string, args ...interface{}) ([]FeatureUsage, error) {
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    features := []FeatureUsage{}
+    index := make(map[string]int)
+    for rows.Next() {
+        var name string
+        var day time.Time
+        var count int64
+        if err := rows.Scan(&name, &day, &count); err != nil {
+            return nil, err
+        }
+        i, ok := index[name]
+        if !ok {
+            i = len(features)
+            index[name] = i
+            features = append(features, FeatureUsage{Feature: name, Kind: kind})
+        }
+        features[i].Total += count
+        features[i].Daily = append(features[i].Daily, DailyCount{Day: day.Format(time.DateOnly), Count: count})
+    }
+    return features, rows.Err()
+}
+
+func (s postgresStore) RecordUsage(ctx context.Context, key usageKey, calls int64) error {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO api_usage (day, user_id, client, method, route, calls)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (day, user_id, client, method, route) DO UPDATE SET
+            calls = api_usage.calls + EXCLUDED.calls
+    `, key.day, key.userID, key.client, key.method, key.route, calls)
+    return err
+}
+
+func (s postgresStore) ActiveUsers(ctx context.Context, since time.Time) (int, []DailyCount, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return 0, nil, err
+    }
+    var active int
+    err = db.QueryRowContext(ctx, `
+        SELECT COUNT(DISTINCT user_id) FROM api_usage WHERE day >= $1 AND user_id <> $2
+    `, since, clientAnonymous).Scan(&active)
+    if err != nil {
+        return 0, nil, err
+    }
+    daily, err := queryDailyCounts(db, `
+        SELECT day, COUNT(DISTINCT user_id) FROM api_usage
+        WHERE day >= $1 AND user_id <> $2
+        GROUP BY day ORDER BY day
+    `, since, clientAnonymous)
+    return active, daily, err
+}
+
+func (s postgresStore) APIClients(ctx context.Context, since time.Time) ([]ClientUsage, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT u.client, COALESCE(t.name, u.client), t.user_id, COUNT(DISTINCT u.user_id), SUM(u.calls), MAX(u.day)
+        FROM api_usage u
+        LEFT JOIN api_tokens t ON u.client = 'token:' || t.id::text
+        WHERE u.day >= $1
+        GROUP BY u.client, t.name, t.user_id
+        ORDER BY SUM(u.calls) DESC
+    `, since)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    clients := []ClientUsage{}
+    for rows.Next() {
+        var client ClientUsage
+        var lastUsed time.Time
+        if err := rows.Scan(&client.Client, &client.Name, &client.UserID, &client.Users, &client.Calls, &lastUsed); err != nil {
+            continue
+        }
+        client.LastUsed = lastUsed.Format(time.DateOnly)
+        clients = append(clients, client)
+    }
+    return clients, rows.Err()
+}
+
+// ReminderAdoption counts reminder recipients in the database of the
+// workspace and the email opt-outs kept in the shared database.
+func (s postgresStore) ReminderAdoption(ctx context.Context, since time.Time) (ReminderAdoption, error) {
+    var adoption ReminderAdoption
+    shared, err := s.c.getDBConnection()
+    if err != nil {
+        return adoption, err
+    }
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return adoption, err
+    }
+    err = db.QueryRowContext(ctx, `
+        SELECT COUNT(DISTINCT user_id), COUNT(DISTINCT (channel_id, thread_ts))
+        FROM reminder_nudges WHERE nudged_at >= $1
+    `, since).Scan(&adoption.Recipients, &adoption.Threads)
+    if err != nil {
+        return adoption, err
+    }
+    err = shared.QueryRowContext(ctx, `
+        SELECT COUNT(*), COUNT(*) FILTER (WHERE NOT digest),
+               COUNT(*) FILTER (WHERE NOT digest AND updated_at >= $1)
+        FROM email_preferences
+    `, since).Scan(&adoption.Addresses, &adoption.OptedOut, &adoption.OptedOutSince)
+    if err != nil {
+        return adoption, err
+    }
+    if adoption.Addresses > 0 {
+        adoption.OptOutRate = float64(adoption.OptedOut) / float64(adoption.Addresses)
+    }
+    return adoption, nil
+}
+
+// FeatureUsage returns the routes called, counted in the shared database,
+// and the actions audited in the database of the workspace.
+func (s postgresStore) FeatureUsage(ctx context.Context, since time.Time) ([]FeatureUsage, error) {
+    shared, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    apiFeatures, err := queryFeatureUsage(shared, "api", `
+        SELECT method || ' ' || route, day, SUM(calls) FROM api_usage
+        WHERE day >= $1
+        GROUP BY 1, 2 ORDER BY 2
+    `, since)
+    if err != nil {
+        return nil, err
+    }
+    actionFeatures, err := queryFeatureUsage(db, "action", `
+        SELECT action, created_at::date, COUNT(*) FROM audit_log
+        WHERE created_at >= $1
+        GROUP BY 1, 2 ORDER BY 2
+    `, since)
+    if err != nil {
+        return nil, err
+    }
+    return append(apiFeatures, actionFeatures...), nil
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "slices"
+    "testing"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+)
+
+func TestAdoptionCountsTrackedUsage(t *testing.T) {
+    store := NewMemoryStore()
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.usage = newUsageCounter()
+    store.Audit(context.Background(), AuditEntry{Actor: "U1", Action: "thread.resolve"})
+
+    e := echo.New()
+    e.HTTPErrorHandler = apierror.Handler(c.logger)
+    e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(ctx echo.Context) error {
+            auth.SetPrincipal(ctx, &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession})
+            return next(ctx)
+        }
+    }, c.TrackUsage)
+    e.GET("/api/threads", func(ctx echo.Context) error { return ctx.NoContent(http.StatusNoContent) })
+    e.GET("/api/adoption", c.GetAdoption)
+    serve := func(target string) *httptest.ResponseRecorder {
+        rec := httptest.NewRecorder()
+        e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+        return rec
+    }
+    serve("/api/threads")
+    serve("/api/threads")
+
+    var report AdoptionReport
+    decodeResponse(t, serve("/api/adoption"), &report)
+    if report.ActiveUsers != 1 || len(report.DailyActiveUsers) != 1 {
+        t.Fatalf("got %d active users, daily %+v", report.ActiveUsers, report.DailyActiveUsers)
+    }
+    if len(report.Clients) != 1 || report.Clients[0].Client != clientDashboard || report.Clients[0].Calls != 2 {
+        t.Fatalf("got clients %+v", report.Clients)
+    }
+    features := map[string]int64{}
+    for _, feature := range report.Features {
+        features[feature.Kind+" "+feature.Feature] = feature.Total
+    }
+    if features["api GET /api/threads"] != 2 || features["action thread.resolve"] != 1 {
+        t.Fatalf("got features %+v", report.Features)
+    }
+    // The report itself is counted once it is served
+    if !slices.Equal(report.UnusedFeatures, []string{"GET /api/adoption"}) {
+        t.Fatalf("got unused features %v", report.UnusedFeatures)
+    }
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/analysis"
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/events"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Retries of a failed analysis, waiting analysisBackoff and twice as long
+// after every further failure unless the provider tells when to retry.
+const (
+    analysisAttempts = 4
+    analysisBackoff  = 5 * time.Second
+    analysisTimeout  = 3 * time.Minute
+)
+
+// analysisQueueSize is how many threads may wait for analysis.
+const analysisQueueSize = 500
+
+var errAnalysisRestricted = errors.New("thread content may not be sent for AI analysis")
+
+var errAnalysisOptedOut = errors.New("channel opted out of storing message text and AI analysis")
+
+// analysisJob is a thread queued for analysis on behalf of requestedBy.
+type analysisJob struct {
+    workspace   string
+    channelID   string
+    threadTS    string
+    requestedBy string
+}
+
+// AnalyzeThread - Queue a thread for AI analysis of its name, description, priority and stakeholders
+func (c *Container) AnalyzeThread(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    if c.analyzer == nil {
+        return apierror.New(http.StatusServiceUnavailable, "AI provider is not configured")
+    }
+
+    reqCtx := ctx.Request().Context()
+    config, err := c.configs.ChannelConfig(reqCtx, channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+    if _, err := c.threads.Thread(reqCtx, channelID, threadTS); err != nil {
+        return threadLookupError(ctx, err)
+    }
+    switch analysisAllowed(config) {
+    case errAnalysisRestricted:
+        return apierror.New(http.StatusConflict, "Threads of this Slack Connect channel are not sent for AI analysis")
+    case errAnalysisOptedOut:
+        return apierror.New(http.StatusConflict, "This channel opted out of storing message text and AI analysis")
+    }
+
+    job := analysisJob{
+        workspace:   workspaceFrom(ctx.Request().Context()),
+        channelID:   channelID,
+        threadTS:    threadTS,
+        requestedBy: actorFrom(ctx),
+    }
+    key := job.workspace + "/" + channelID + "/" + threadTS
+    if _, queued := c.analysisPending.LoadOrStore(key, true); queued {
+        return ctx.JSON(http.StatusAccepted, map[string]string{
+            "status": "queued",
+        })
+    }
+    select {
+    case c.analysisJobs <- job:
+    default:
+        c.analysisPending.Delete(key)
+        return apierror.New(http.StatusServiceUnavailable, "Too many threads are waiting for analysis, try again later")
+    }
+
+    return ctx.JSON(http.StatusAccepted, map[string]string{
+        "status": "queued",
+    })
+}
+
+// RunAnalysis analyzes queued threads with a pool of workers until ctx is
+// done, returning once the analyses in progress have finished.
+func (c *Container) RunAnalysis(ctx context.Context) {
+    if c.analyzer == nil {
+        return
+    }
+    var wg sync.WaitGroup
+    for i := 0; i < c.analysisWorkers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for {
+                select {
+                case <-ctx.Done():
+                    return
+                case job := <-c.analysisJobs:
+                    jobCtx := withWorkspace(ctx, job.workspace)
+                    if err := c.analyzeThread(jobCtx, job); err != nil {
+                        c.logger.Warnf("failed to analyze thread %s/%s: %v", job.channelID, job.threadTS, err)
+                    }
+                    c.analysisPending.Delete(job.workspace + "/" + job.channelID + "/" + job.threadTS)
+                }
+            }
+        }()
+    }
+    wg.Wait()
+}
+
+// analysisAllowed returns why the threads of a channel may not be sent for
+// AI analysis, nil when they may.
+func analysisAllowed(config ChannelConfig) error {
+    if config.SlackConnect.Shared && !config.SlackConnect.AIAnalysis {
+        return errAnalysisRestricted
+    }
+    if !config.TextIndexing {
+        return errAnalysisOptedOut
+    }
+    return nil
+}
+
+// analyzeThread classifies a thread and stores the result in its AI
+// columns, like the collector does.
+func (c *Container) analyzeThread(ctx context.Context, job analysisJob) error {
+    config, err := c.configs.ChannelConfig(ctx, job.channelID)
+    if err != nil {
+        return err
+    }
+    thread, err := c.threads.Thread(ctx, job.channelID, job.threadTS)
+    if err != nil {
+        return err
+    }
+    // The channel may have turned out to be shared, or opted out, since the
+    // thread was queued
+    if err := analysisAllowed(config); err != nil {
+        return err
+    }
+
+    conversation, err := c.threadConversation(ctx, job.channelID, job.threadTS)
+    if err != nil {
+        return err
+    }
+    if len(conversation) == 0 {
+        return fmt.Errorf("thread has no messages to analyze")
+    }
+
+    completion, err := c.completeWithRetry(ctx, analysis.Prompt(conversation))
+    if err != nil {
+        return err
+    }
+    result, err := analysis.ParseResult(completion)
+    if err != nil {
+        return err
+    }
+
+    // Stakeholders are everyone who wrote in the thread, the usergroups
+    // mentioned in it and whoever the model named, falling back to the
+    // author
+    seen := map[string]bool{}
+    stakeholders := []string{}
+    for _, message := range conversation {
+        if message.UserID != "" && !seen[message.UserID] {
+            seen[message.UserID] = true
+            stakeholders = append(stakeholders, message.UserID)
+        }
+    }
+    for _, message := range conversation {
+        for _, id := range slack.MentionedUsergroups(message.Text) {
+            if !seen[id] {
+                seen[id] = true
+                stakeholders = append(stakeholders, id)
+            }
+        }
+    }
+    for _, id := range result.StakeholderIDs() {
+        if !seen[id] {
+            seen[id] = true
+            stakeholders = append(stakeholders, id)
+        }
+    }
+    if len(stakeholders) == 0 && thread.UserID != "" {
+        stakeholders = []string{thread.UserID}
+    }
+    result.AnalysisTimestamp = time.Now().UTC().Format("2006-01-02T15:04:05+00:00")
+    if err := c.analyses.SaveAnalysis(ctx, job.channelID, job.threadTS, result, stakeholders); err != nil {
+        return err
+    }
+    c.publish(ctx, events.Event{
+        Topic:      events.TopicThreadAnalyzed,
+        Workspace:  job.workspace,
+        Actor:      job.requestedBy,
+        ChannelID:  job.channelID,
+        ThreadTS:   job.threadTS,
+        OccurredAt: time.Now().UTC(),
+    })
+
+    c.audit(ctx, job.requestedBy, "thread.analyze", job.channelID, job.threadTS, map[string]interface{}{
+        "provider":   c.analyzer.Name(),
+        "priority":   result.Priority,
+        "confidence": result.ConfidenceScore,
+    })
+    return nil
+}
+
+// threadConversation returns the messages of a thread with their text.
+// Stored messages are used when they have it, threads whose messages were
+// not stored are read from Slack without keeping them.
+func (c *Container) threadConversation(ctx context.Context, channelID string, threadTS string) ([]analysis.Message, error) {
+    stored, err := c.messages.Messages(ctx, channelID, threadTS)
+    if err != nil {
+        return nil, err
+    }
+    conversation := []analysis.Message{}
+    complete := len(stored) > 0
+    for _, message := range stored {
+        if message.Text == nil {
+            complete = false
+            break
+        }
+        conversation = append(conversation, analysis.Message{UserID: message.UserID, Text: *message.Text})
+    }
+    if complete {
+        return conversation, nil
+    }
+    if !c.slackFeatureReady(slackFeatureThreadHistory) {
+        return nil, errSlackFeatureUnavailable
+    }
+
+    conversation = conversation[:0]
+    cursor := ""
+    for len(conversation) < threadFetchLimit {
+        page, err := c.slack.ConversationsReplies(ctx, channelID, threadTS, cursor, 200)
+        if err != nil {
+            return nil, err
+        }
+        for _, msg := range page.Messages {
+            conversation = append(conversation, analysis.Message{UserID: msg.User, Text: msg.Text})
+        }
+        cursor = page.ResponseMetadata.NextCursor
+        if !page.HasMore || cursor == "" {
+            break
+        }
+    }
+    return conversation, nil
+}
+
+// completeWithRetry sends a prompt to the provider at the configured rate,
+// retrying rate limited and failed requests with backoff.
+func (c *Container) completeWithRetry(ctx context.Context, prompt string) (string, error) {
+    backoff := analysisBackoff
+    var lastErr error
+    for attempt := 0; attempt < analysisAttempts; attempt++ {
+        if err := c.analysisLimiter.Wait(ctx); err != nil {
+            return "", err
+        }
+        callCtx, cancel := context.WithTimeout(ctx, analysisTimeout)
+        completion, err := c.analyzer.Complete(callCtx, prompt)
+        cancel()
+        if err == nil {
+            return completion, nil
+        }
+        lastErr = err
+
+        wait := backoff
+        var apiErr *analysis.APIError
+        if errors.As(err, &apiErr) {
+            if !apiErr.Retryable() {
+                return "", err
+            }
+            if apiErr.RetryAfter > 0 {
+                wait = apiErr.RetryAfter
+            }
+        }
+        select {
+        case <-ctx.Done():
+            return "", ctx.Err()
+        case <-time.After(wait):
+        }
+        backoff *= 2
+    }
+    return "", lastErr
+}
+
+func (s postgresStore) SaveAnalysis(ctx context.Context, channelID string, threadTS string, result *analysis.Result, stakeholders []string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    stakeholdersJSON, _ := json.Marshal(stakeholders)
+    analysisJSON, _ := json.Marshal(result)
+    _, err = db.ExecContext(ctx, `
+        UPDATE threads SET ai_thread_name = $1, ai_description = $2, ai_stakeholders = $3, ai_priority = $4,
+                      ai_confidence = $5, ai_analysis_json = $6, updated_at = NOW()
+        WHERE thread_ts = $7 AND channel_id = $8
+    `, result.ThreadName(), result.Description(), string(stakeholdersJSON), result.Priority,
+        result.ConfidenceScore, string(analysisJSON), threadTS, channelID)
+    return err
+}
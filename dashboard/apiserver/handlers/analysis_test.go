@@ -0,0 +1,65 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+
+    "golang.org/x/time/rate"
+)
+
+// promptRecorder completes every prompt with completion, remembering the
+// prompts.
+type promptRecorder struct {
+    completion string
+    prompts    []string
+}
+
+func (p *promptRecorder) Name() string { return "recorder" }
+
+func (p *promptRecorder) Complete(ctx context.Context, prompt string) (string, error) {
+    p.prompts = append(p.prompts, prompt)
+    return p.completion, nil
+}
+
+func TestAnalyzeThreadStoresResult(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "partners", TextIndexing: true}})
+    store.MarkSlackConnect(context.Background(), "C2", true)
+    thread := domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", UserID: "U1", Status: statusOpen, CreatedAt: time.Now(), LatestReply: time.Now()}
+    text := "The deploy fails with a timeout"
+    store.StartThread(context.Background(), thread, ThreadMessage{TS: thread.ThreadTS, UserID: "U1", Text: &text, PostedAt: thread.CreatedAt})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C2", ThreadTS: "1700000000.000200", Status: statusOpen}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    provider := &promptRecorder{completion: `{"thread_state": "open", "priority": "high", "confidence_score": 0.8, "reasoning": "Deploys time out. Needs a fix."}`}
+    c.analyzer, c.analysisLimiter = provider, rate.NewLimiter(rate.Inf, 1)
+    c.analysisJobs = make(chan analysisJob, 1)
+    user := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    route := "/api/threads/:channel_id/:thread_ts/analyze"
+    if rec := serveAs(c, user, http.MethodPost, route, "/api/threads/C2/1700000000.000200/analyze", "", c.AnalyzeThread); rec.Code != http.StatusConflict {
+        t.Fatalf("a thread of a Slack Connect channel got status %d", rec.Code)
+    }
+    if rec := serveAs(c, user, http.MethodPost, route, "/api/threads/C1/1700000000.000999/analyze", "", c.AnalyzeThread); rec.Code != http.StatusNotFound {
+        t.Fatalf("a missing thread got status %d", rec.Code)
+    }
+    if rec := serveAs(c, user, http.MethodPost, route, "/api/threads/C1/1700000000.000100/analyze", "", c.AnalyzeThread); rec.Code != http.StatusAccepted {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+
+    if err := c.analyzeThread(context.Background(), <-c.analysisJobs); err != nil {
+        t.Fatal(err)
+    }
+    if len(provider.prompts) != 1 || !strings.Contains(provider.prompts[0], text) {
+        t.Fatalf("got prompts %q, want the stored conversation", provider.prompts)
+    }
+    if stored, _ := store.Thread(context.Background(), "C1", thread.ThreadTS); stored.Title != "Deploys time out" {
+        t.Fatalf("got title %q", stored.Title)
+    }
+}
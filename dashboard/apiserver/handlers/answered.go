@@ -0,0 +1,273 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "net/http"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/webhooks"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+// Signals that the question of a thread was answered.
+const (
+    // answerSignalThanks is a reply by the author thanking or confirming a
+    // fix
+    answerSignalThanks = "author_thanks"
+    // answerSignalCheckmark is the author reacting with a check mark to the
+    // root message
+    answerSignalCheckmark = "author_checkmark"
+)
+
+// answeredPattern matches replies of a thread author saying the question
+// was answered.
+var answeredPattern = regexp.MustCompile(`(?i)\b(thanks|thank you|thx|ty|that worked|that did it|works now|working now|solved|fixed it|all good|makes sense)\b`)
+
+// notAnsweredPattern matches thanks given before getting an answer.
+var notAnsweredPattern = regexp.MustCompile(`(?i)\b(in advance|but|still|not)\b`)
+
+// answerReactions are the reactions with which authors accept an answer.
+var answerReactions = map[string]bool{
+    "white_check_mark":      true,
+    "heavy_check_mark":      true,
+    "ballot_box_with_check": true,
+}
+
+// answerSignalMuted is the condition on answer_signal_accuracy acc under
+// which a signal is no longer surfaced in a channel: once 20 of its threads
+// were resolved, if fewer than half of them were resolved as answered.
+const answerSignalMuted = `acc.labelled >= 20 AND acc.answered * 2 < acc.labelled`
+
+// answerSignalTrusted is the condition on thread_answer_signals sig that
+// its signal is not muted in its channel.
+const answerSignalTrusted = `NOT EXISTS (SELECT 1 FROM answer_signal_accuracy acc
+     WHERE acc.channel_id = sig.channel_id AND acc.signal = sig.signal AND ` + answerSignalMuted + `)`
+
+// looksAnswered reports whether a reply by the author of a thread reads as
+// the question having been answered. Follow-up questions never do.
+func looksAnswered(text string) bool {
+    text = strings.TrimSpace(text)
+    if text == "" || strings.HasSuffix(text, "?") {
+        return false
+    }
+    return answeredPattern.MatchString(text) && !notAnsweredPattern.MatchString(text)
+}
+
+// recordAnswerSignal notes that a thread looks answered, telling the
+// thread's webhooks about signals not seen before unless the signal is
+// muted in the channel. Signals dismissed before stay dismissed.
+func (c *Container) recordAnswerSignal(ctx context.Context, channelID, threadTS, signal, userID string, detectedAt time.Time) error {
+    surfaced, err := c.answerSignals.RecordAnswerSignal(ctx, channelID, threadTS, signal, userID, detectedAt)
+    if err != nil || !surfaced {
+        return err
+    }
+    c.publishThreadEvent(ctx, webhooks.Event{
+        Action:     threadEventAnswered,
+        Actor:      userID,
+        ChannelID:  channelID,
+        ThreadTS:   threadTS,
+        Details:    map[string]interface{}{"signal": signal},
+        OccurredAt: detectedAt.UTC(),
+    })
+    return nil
+}
+
+// labelAnswerSignals labels the answer signals of a thread with the
+// resolution it got, and recounts how often these signals were right in
+// the channel. Resolutions are how the signals learn: one mostly given to
+// threads resolved for other reasons than being answered gets muted.
+// Failures are ignored, labels only tune the signals.
+func labelAnswerSignals(db *sql.DB, channelID string, threadTS string, resolution string) {
+    if resolution == "" {
+        return
+    }
+    result, err := db.Exec(`
+        UPDATE thread_answer_signals SET resolution = $3
+        WHERE channel_id = $1 AND thread_ts = $2
+    `, channelID, threadTS, resolution)
+    if err != nil {
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return
+    }
+    db.Exec(`
+        INSERT INTO answer_signal_accuracy (channel_id, signal, labelled, answered, updated_at)
+        SELECT channel_id, signal, COUNT(*), COUNT(*) FILTER (WHERE resolution = $3), NOW()
+        FROM thread_answer_signals
+        WHERE channel_id = $1 AND resolution IS NOT NULL
+          AND signal IN (SELECT signal FROM thread_answer_signals WHERE channel_id = $1 AND thread_ts = $2)
+        GROUP BY channel_id, signal
+        ON CONFLICT (channel_id, signal) DO UPDATE SET
+            labelled = EXCLUDED.labelled,
+            answered = EXCLUDED.answered,
+            updated_at = EXCLUDED.updated_at
+    `, channelID, threadTS, resolutionAnswered)
+}
+
+// AnsweredThread is an open thread that looks answered, a candidate for
+// resolving
+type AnsweredThread struct {
+    ChannelID    string    `json:"channel_id"`
+    ChannelName  string    `json:"channel_name"`
+    ThreadTS     string    `json:"thread_ts"`
+    UserID       string    `json:"user_id"`
+    AIThreadName *string   `json:"ai_thread_name"`
+    LatestReply  time.Time `json:"latest_reply"`
+    Signals      []string  `json:"signals"`
+    DetectedAt   time.Time `json:"detected_at"`
+}
+
+// GetAnsweredThreads - Get open threads that look answered, oldest detection first, to resolve or dismiss. Signals muted in a channel are left out
+func (c *Container) GetAnsweredThreads(ctx echo.Context) error {
+    answered, err := c.answerSignals.AnsweredThreads(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query answered threads")
+    }
+
+    sort.Slice(answered, func(i, j int) bool { return answered[i].DetectedAt.Before(answered[j].DetectedAt) })
+    return ctx.JSON(http.StatusOK, answered)
+}
+
+// DismissAnsweredThread - Take a thread off the answered queue when it still needs work
+func (c *Container) DismissAnsweredThread(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    dismissed, err := c.answerSignals.DismissAnswerSignals(ctx.Request().Context(), channelID, threadTS, actorFrom(ctx))
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to dismiss thread")
+    }
+    if !dismissed {
+        return apierror.New(http.StatusNotFound, "Thread is not in the answered queue")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "thread.answered_dismiss", channelID, threadTS, nil)
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+func (s postgresStore) RecordAnswerSignal(ctx context.Context, channelID, threadTS, signal, userID string, detectedAt time.Time) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    result, err := db.ExecContext(ctx, `
+        INSERT INTO thread_answer_signals (channel_id, thread_ts, signal, user_id, detected_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (channel_id, thread_ts, signal) DO NOTHING
+    `, channelID, threadTS, signal, userID, detectedAt.UTC())
+    if err != nil {
+        return false, err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return false, nil
+    }
+    var muted bool
+    err = db.QueryRowContext(ctx, `
+        SELECT EXISTS (SELECT 1 FROM answer_signal_accuracy acc
+                       WHERE acc.channel_id = $1 AND acc.signal = $2 AND `+answerSignalMuted+`)
+    `, channelID, signal).Scan(&muted)
+    return !muted, err
+}
+
+func (s postgresStore) AnsweredThreads(ctx context.Context) ([]AnsweredThread, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT channel_id, thread_ts, ARRAY_AGG(signal ORDER BY signal), MIN(detected_at)
+        FROM thread_answer_signals sig
+        WHERE dismissed_at IS NULL AND `+answerSignalTrusted+`
+        GROUP BY channel_id, thread_ts
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    candidates := make(map[string]map[string]*AnsweredThread)
+    for rows.Next() {
+        thread := &AnsweredThread{}
+        if err := rows.Scan(&thread.ChannelID, &thread.ThreadTS, pq.Array(&thread.Signals), &thread.DetectedAt); err != nil {
+            continue
+        }
+        if candidates[thread.ChannelID] == nil {
+            candidates[thread.ChannelID] = make(map[string]*AnsweredThread)
+        }
+        candidates[thread.ChannelID][thread.ThreadTS] = thread
+    }
+    rows.Close()
+
+    channels, err := trackedChannels(ctx, db)
+    if err != nil {
+        return nil, err
+    }
+
+    answered := []AnsweredThread{}
+    for _, ch := range channels {
+        threads := candidates[ch.ID]
+        if len(threads) == 0 {
+            continue
+        }
+        tsList := make([]string, 0, len(threads))
+        for ts := range threads {
+            tsList = append(tsList, ts)
+        }
+
+        // Threads resolved since the signal drop out of the queue
+        threadRows, err := db.QueryContext(ctx, `
+            SELECT thread_ts, user_id, ai_thread_name, latest_reply
+            FROM threads WHERE channel_id = $1 AND status = 'open' AND thread_ts = ANY($2)
+        `, ch.ID, pq.Array(tsList))
+        if err != nil {
+            s.c.logger.Warnf("failed to query answered threads of channel %s: %v", ch.ID, err)
+            continue
+        }
+        for threadRows.Next() {
+            var ts, userID string
+            var name *string
+            var latestReply time.Time
+            if err := threadRows.Scan(&ts, &userID, &name, &latestReply); err != nil {
+                continue
+            }
+            thread := threads[ts]
+            thread.ChannelName = ch.Name
+            thread.UserID = userID
+            thread.LatestReply = latestReply
+            if ch.TextIndexing {
+                thread.AIThreadName = name
+            }
+            answered = append(answered, *thread)
+        }
+        threadRows.Close()
+    }
+    return answered, nil
+}
+
+func (s postgresStore) DismissAnswerSignals(ctx context.Context, channelID string, threadTS string, actor string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    result, err := db.ExecContext(ctx, `
+        UPDATE thread_answer_signals SET dismissed_at = NOW(), dismissed_by = $3
+        WHERE channel_id = $1 AND thread_ts = $2 AND dismissed_at IS NULL
+    `, channelID, threadTS, actor)
+    if err != nil {
+        return false, err
+    }
+    n, _ := result.RowsAffected()
+    return n > 0, nil
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+)
+
+// apiKeyActor prefixes the name of an API key where a user ID is expected,
+// e.g. in the audit log.
+const apiKeyActor = "api-key:"
+
+var errAPIKeyNotFound = errors.New("API key not found")
+
+// APIKey represents an API key, without its secret
+type APIKey struct {
+    ID         int64      `json:"id"`
+    Name       string     `json:"name"`
+    KeyPrefix  string     `json:"key_prefix"`
+    Scopes     []string   `json:"scopes"`
+    CreatedBy  string     `json:"created_by"`
+    CreatedAt  time.Time  `json:"created_at"`
+    ExpiresAt  *time.Time `json:"expires_at"`
+    LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// CreateAPIKeyRequest is the body accepted by CreateAPIKey. Keys without
+// expires_in_days never expire.
+type CreateAPIKeyRequest struct {
+    Name          string   `json:"name"`
+    Scopes        []string `json:"scopes"`
+    ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// GetAPIKeys - List the active API keys
+func (c *Container) GetAPIKeys(ctx echo.Context) error {
+    keys, err := c.apiKeys.APIKeys(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query API keys")
+    }
+
+    return ctx.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey - Create an API key for a CI job or bot
+func (c *Container) CreateAPIKey(ctx echo.Context) error {
+    principal, _ := auth.PrincipalFrom(ctx)
+    // A leaked key must not be able to outlive its revocation
+    if principal.KeyID != 0 {
+        return apierror.New(http.StatusForbidden, "API keys cannot create API keys")
+    }
+
+    var req CreateAPIKeyRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+
+    req.Name = strings.TrimSpace(req.Name)
+    if req.Name == "" || len(req.Name) > 100 {
+        return apierror.New(http.StatusBadRequest, "name is required and must be at most 100 characters")
+    }
+    if len(req.Scopes) == 0 {
+        return apierror.New(http.StatusBadRequest, "at least one scope is required")
+    }
+
+    scopes := make([]auth.Scope, 0, len(req.Scopes))
+    for _, s := range req.Scopes {
+        scope, err := auth.ParseScope(s)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, err.Error())
+        }
+        if !principal.Has(scope) {
+            return apierror.New(http.StatusForbidden, "cannot grant scope "+string(scope)+" that you do not hold")
+        }
+        scopes = append(scopes, scope)
+    }
+
+    if req.ExpiresInDays < 0 {
+        return apierror.New(http.StatusBadRequest, "expires_in_days must be positive")
+    }
+    var expiresAt *time.Time
+    if req.ExpiresInDays > 0 {
+        expires := time.Now().UTC().AddDate(0, 0, req.ExpiresInDays)
+        expiresAt = &expires
+    }
+
+    plaintext, hash, err := auth.GenerateKey()
+    if err != nil {
+        c.logger.Errorf("failed to generate API key: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to generate API key")
+    }
+
+    key := APIKey{
+        Name:      req.Name,
+        KeyPrefix: auth.DisplayPrefix(plaintext),
+        Scopes:    strings.Split(auth.JoinScopes(scopes), ","),
+        CreatedBy: principal.UserID,
+        ExpiresAt: expiresAt,
+    }
+    key, err = c.apiKeys.CreateAPIKey(ctx.Request().Context(), key, hash)
+    if err != nil {
+        c.logger.Errorf("failed to store API key: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to create API key")
+    }
+
+    c.logger.Infof("user %s created API key %d with scopes %s", principal.UserID, key.ID, auth.JoinScopes(scopes))
+    c.audit(sharedContext(ctx.Request().Context()), principal.UserID, "api_key.create", "", "", map[string]interface{}{
+        "key_id": key.ID,
+        "name":   key.Name,
+        "scopes": key.Scopes,
+    })
+
+    return ctx.JSON(http.StatusCreated, map[string]interface{}{
+        "key":     plaintext,
+        "details": key,
+    })
+}
+
+// RevokeAPIKey - Revoke an API key
+func (c *Container) RevokeAPIKey(ctx echo.Context) error {
+    principal, _ := auth.PrincipalFrom(ctx)
+
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid API key id")
+    }
+
+    name, err := c.apiKeys.RevokeAPIKey(ctx.Request().Context(), id, principal.UserID)
+    if err == errAPIKeyNotFound {
+        return apierror.New(http.StatusNotFound, "API key not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to revoke API key")
+    }
+
+    c.logger.Infof("user %s revoked API key %d", principal.UserID, id)
+    c.audit(sharedContext(ctx.Request().Context()), principal.UserID, "api_key.revoke", "", "", map[string]interface{}{
+        "key_id": id,
+        "name":   name,
+    })
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// LookupAPIKey resolves a key hash to its scopes, acting as "api-key:<name>".
+// Unknown, expired and revoked keys resolve to nil.
+func (c *Container) LookupAPIKey(hash string) (*auth.Principal, error) {
+    return c.apiKeys.LookupAPIKey(context.Background(), hash)
+}
+
+func (s postgresStore) APIKeys(ctx context.Context) ([]APIKey, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT id, name, key_prefix, scopes, created_by, created_at, expires_at, last_used_at
+        FROM api_keys
+        WHERE revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+        ORDER BY created_at DESC
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    keys := []APIKey{}
+    for rows.Next() {
+        var key APIKey
+        var scopes string
+        err := rows.Scan(&key.ID, &key.Name, &key.KeyPrefix, &scopes, &key.CreatedBy,
+            &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt)
+        if err != nil {
+            continue
+        }
+        key.Scopes = strings.Split(scopes, ",")
+        keys = append(keys, key)
+    }
+    return keys, rows.Err()
+}
+
+func (s postgresStore) CreateAPIKey(ctx context.Context, key APIKey, hash string) (APIKey, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return APIKey{}, err
+    }
+
+    err = db.QueryRowContext(ctx, `
+        INSERT INTO api_keys (name, key_prefix, key_hash, scopes, created_by, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `, key.Name, key.KeyPrefix, hash, strings.Join(key.Scopes, ","), key.CreatedBy, key.ExpiresAt,
+    ).Scan(&key.ID, &key.CreatedAt)
+    return key, err
+}
+
+func (s postgresStore) RevokeAPIKey(ctx context.Context, id int64, actor string) (string, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return "", err
+    }
+
+    var name string
+    err = db.QueryRowContext(ctx, `
+        UPDATE api_keys SET revoked_at = NOW(), revoked_by = $2
+        WHERE id = $1 AND revoked_at IS NULL
+        RETURNING name
+    `, id, actor).Scan(&name)
+    if err == sql.ErrNoRows {
+        return "", errAPIKeyNotFound
+    }
+    return name, err
+}
+
+func (s postgresStore) LookupAPIKey(ctx context.Context, hash string) (*auth.Principal, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+
+    var id int64
+    var name, scopes string
+    err = db.QueryRowContext(ctx, `
+        UPDATE api_keys SET last_used_at = NOW()
+        WHERE key_hash = $1 AND revoked_at IS NULL
+          AND (expires_at IS NULL OR expires_at > NOW())
+        RETURNING id, name, scopes
+    `, hash).Scan(&id, &name, &scopes)
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    parsed, err := auth.ParseScopes(scopes)
+    if err != nil {
+        return nil, err
+    }
+    return &auth.Principal{UserID: apiKeyActor + name, Name: name, Scopes: parsed, KeyID: id, Method: auth.MethodAPIKey}, nil
+}
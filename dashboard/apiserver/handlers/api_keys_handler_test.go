@@ -0,0 +1,58 @@
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+    "testing"
+
+    "dashboard/apiserver/auth"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+    store := NewMemoryStore()
+    c := newTestContainer(t, store, &MemorySlack{})
+    admin := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeRead, auth.ScopeTriage, auth.ScopeAdmin}, Method: auth.MethodSession}
+
+    rec := serveAs(c, admin, http.MethodPost, "/api/admin/api-keys", "/api/admin/api-keys", `{"name": "ci", "scopes": ["read-only"], "expires_in_days": 30}`, c.CreateAPIKey)
+    if rec.Code != http.StatusCreated {
+        t.Fatalf("creating the key got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var created struct {
+        Key     string `json:"key"`
+        Details APIKey `json:"details"`
+    }
+    decodeResponse(t, rec, &created)
+
+    principal, err := c.LookupAPIKey(auth.HashToken(created.Key))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if principal == nil || principal.UserID != "api-key:ci" || principal.KeyID != created.Details.ID || !principal.Has(auth.ScopeRead) || principal.Has(auth.ScopeTriage) {
+        t.Fatalf("got principal %+v for the key", principal)
+    }
+    // Keys cannot mint other keys
+    rec = serveAs(c, principal, http.MethodPost, "/api/admin/api-keys", "/api/admin/api-keys", `{"name": "other", "scopes": ["read-only"]}`, c.CreateAPIKey)
+    if rec.Code != http.StatusForbidden {
+        t.Fatalf("creating a key with a key got status %d", rec.Code)
+    }
+
+    target := "/api/admin/api-keys/" + strconv.FormatInt(created.Details.ID, 10)
+    if rec := serveAs(c, admin, http.MethodDelete, "/api/admin/api-keys/:id", target, "", c.RevokeAPIKey); rec.Code != http.StatusNoContent {
+        t.Fatalf("revoking the key got status %d", rec.Code)
+    }
+    if rec := serveAs(c, admin, http.MethodDelete, "/api/admin/api-keys/:id", target, "", c.RevokeAPIKey); rec.Code != http.StatusNotFound {
+        t.Fatalf("revoking the key twice got status %d", rec.Code)
+    }
+    if principal, err := c.LookupAPIKey(auth.HashToken(created.Key)); err != nil || principal != nil {
+        t.Fatalf("got principal %+v and %v for a revoked key", principal, err)
+    }
+    rec = serveAs(c, admin, http.MethodGet, "/api/admin/api-keys", "/api/admin/api-keys", "", c.GetAPIKeys)
+    if rec.Body.String() != "[]\n" {
+        t.Fatalf("listed %s, want no active keys", rec.Body.String())
+    }
+
+    audited := store.Audited()
+    if len(audited) != 2 || audited[0].Action != "api_key.create" || audited[1].Action != "api_key.revoke" {
+        t.Fatalf("audited %+v", audited)
+    }
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/notify"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// assigneeMe is the assignee filter standing for the signed in user.
+const assigneeMe = "me"
+
+// AssignRequest is the body accepted by AssignThread. The assignee is a user
+// ID, or a usergroup by @handle or ID, and an empty assignee unassigns the
+// thread. Notify sends the assignee a direct message, true
+// when omitted.
+type AssignRequest struct {
+    Assignee string `json:"assignee"`
+    Notify   *bool  `json:"notify"`
+}
+
+// ThreadAssignment is who a thread is assigned to.
+type ThreadAssignment struct {
+    ChannelID  string     `json:"channel_id"`
+    ThreadTS   string     `json:"thread_ts"`
+    Assignee   *string    `json:"assignee"`
+    AssignedBy *string    `json:"assigned_by"`
+    AssignedAt *time.Time `json:"assigned_at"`
+}
+
+// notifyAssignee tells a user a thread was assigned to them, or every
+// member of a usergroup it was assigned to. Failures are only logged.
+func (c *Container) notifyAssignee(channelID, threadTS, assignee, assignedBy string) {
+    if slack.IsUsergroupID(assignee) {
+        go c.notifyUsergroup(channelID, threadTS, assignee, assignedBy)
+        return
+    }
+    go func() {
+        text := fmt.Sprintf("<%s|A thread> was assigned to you.", slack.Permalink(channelID, threadTS))
+        if assignedBy != "" && assignedBy != "anonymous" && assignedBy != assignee {
+            text = fmt.Sprintf("<@%s> assigned <%s|a thread> to you.", assignedBy, slack.Permalink(channelID, threadTS))
+        }
+        err := c.notifier.Notify(context.Background(), notify.Notification{
+            Event:     notify.EventAssignment,
+            Recipient: assignee,
+            Text:      text,
+            ChannelID: channelID,
+            ThreadTS:  threadTS,
+        })
+        if err != nil {
+            c.logger.Warnf("failed to notify %s of thread assignment: %v", assignee, err)
+        }
+    }()
+}
+
+// notifyUsergroup tells every member of a usergroup a thread was assigned to
+// it.
+func (c *Container) notifyUsergroup(channelID, threadTS, usergroupID, assignedBy string) {
+    group, err := c.users.Usergroup(context.Background(), usergroupID)
+    if err != nil {
+        c.logger.Warnf("failed to look up members of usergroup %s: %v", usergroupID, err)
+        return
+    }
+    to := slack.Mention(usergroupID)
+    for _, member := range group.Members {
+        text := fmt.Sprintf("<%s|A thread> was assigned to %s.", slack.Permalink(channelID, threadTS), to)
+        if assignedBy != "" && assignedBy != "anonymous" && assignedBy != member {
+            text = fmt.Sprintf("<@%s> assigned <%s|a thread> to %s.", assignedBy, slack.Permalink(channelID, threadTS), to)
+        }
+        err := c.notifier.Notify(context.Background(), notify.Notification{
+            Event:     notify.EventAssignment,
+            Recipient: member,
+            Text:      text,
+            ChannelID: channelID,
+            ThreadTS:  threadTS,
+        })
+        if err != nil {
+            c.logger.Warnf("failed to notify %s of thread assignment: %v", member, err)
+        }
+    }
+}
+
+// AssignThread - Assign a thread to a user, optionally notifying them in Slack, or unassign it
+func (c *Container) AssignThread(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req AssignRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    req.Assignee = strings.TrimSpace(req.Assignee)
+    if req.Assignee == assigneeMe {
+        req.Assignee = actorFrom(ctx)
+        if req.Assignee == "anonymous" {
+            return apierror.New(http.StatusUnauthorized, "Assigning a thread to yourself requires a signed in user")
+        }
+    }
+
+    var err error
+    if req.Assignee, err = c.resolveAssignee(ctx.Request().Context(), req.Assignee); err == errUsergroupNotFound {
+        return apierror.New(http.StatusBadRequest, "Usergroup not found")
+    } else if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up usergroup")
+    }
+
+    if _, err := c.threads.Thread(ctx.Request().Context(), channelID, threadTS); err != nil {
+        return threadLookupError(ctx, err)
+    }
+
+    actor := actorFrom(ctx)
+    previous, err := c.assignments.AssignThread(ctx.Request().Context(), channelID, threadTS, req.Assignee, actor)
+    if err != nil {
+        c.logger.Errorf("failed to assign thread %s/%s: %v", channelID, threadTS, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to assign thread")
+    }
+
+    assignment := ThreadAssignment{ChannelID: channelID, ThreadTS: threadTS}
+    if req.Assignee == "" {
+        if previous != "" {
+            c.audit(ctx.Request().Context(), actor, "thread.unassign", channelID, threadTS, map[string]interface{}{
+                "previous_assignee": previous,
+            })
+        }
+        return ctx.JSON(http.StatusOK, assignment)
+    }
+
+    now := time.Now().UTC()
+    assignment.Assignee, assignment.AssignedBy, assignment.AssignedAt = &req.Assignee, &actor, &now
+    if previous != req.Assignee {
+        c.audit(ctx.Request().Context(), actor, "thread.assign", channelID, threadTS, map[string]interface{}{
+            "assignee":          req.Assignee,
+            "previous_assignee": previous,
+        })
+        if req.Notify == nil || *req.Notify {
+            c.notifyAssignee(channelID, threadTS, req.Assignee, actor)
+        }
+    }
+    return ctx.JSON(http.StatusOK, assignment)
+}
+
+func (s postgresStore) AssignThread(ctx context.Context, channelID, threadTS, assignee, assignedBy string) (string, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return "", err
+    }
+
+    var previous sql.NullString
+    err = db.QueryRowContext(ctx, `
+        SELECT assignee FROM thread_assignments WHERE channel_id = $1 AND thread_ts = $2
+    `, channelID, threadTS).Scan(&previous)
+    if err != nil && err != sql.ErrNoRows {
+        return "", err
+    }
+
+    if assignee == "" {
+        _, err = db.ExecContext(ctx, "DELETE FROM thread_assignments WHERE channel_id = $1 AND thread_ts = $2",
+            channelID, threadTS)
+    } else {
+        _, err = db.ExecContext(ctx, `
+            INSERT INTO thread_assignments (channel_id, thread_ts, assignee, assigned_by, assigned_at)
+            VALUES ($1, $2, $3, $4, NOW())
+            ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+                assignee = EXCLUDED.assignee,
+                assigned_by = EXCLUDED.assigned_by,
+                assigned_at = EXCLUDED.assigned_at
+        `, channelID, threadTS, assignee, assignedBy)
+    }
+    return previous.String, err
+}
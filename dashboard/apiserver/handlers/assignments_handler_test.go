@@ -0,0 +1,84 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestAssignThreadToUsergroup(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: time.Now()}})
+    store.AddUsergroup(Usergroup{ID: "S1", Handle: "oncall", Name: "On call", Members: []string{"U2"}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    agent := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+    assign := func(target string, body string) (int, ThreadAssignment) {
+        rec := serveAs(c, agent, http.MethodPost, "/api/threads/:channel_id/:thread_ts/assign", target, body, c.AssignThread)
+        var assignment ThreadAssignment
+        if rec.Code == http.StatusOK {
+            decodeResponse(t, rec, &assignment)
+        }
+        return rec.Code, assignment
+    }
+
+    if code, _ := assign("/api/threads/C1/1700000000.000100/assign", `{"assignee": "@nobody", "notify": false}`); code != http.StatusBadRequest {
+        t.Fatalf("unknown usergroup got status %d", code)
+    }
+    if code, _ := assign("/api/threads/C1/1700000000.000200/assign", `{"assignee": "U2", "notify": false}`); code != http.StatusNotFound {
+        t.Fatalf("missing thread got status %d", code)
+    }
+    code, assignment := assign("/api/threads/C1/1700000000.000100/assign", `{"assignee": "@oncall", "notify": false}`)
+    if code != http.StatusOK || assignment.Assignee == nil || *assignment.Assignee != "S1" || *assignment.AssignedBy != "U1" {
+        t.Fatalf("got status %d and assignment %+v, want the usergroup ID", code, assignment)
+    }
+
+    stats, err := store.UsergroupStats(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(stats) != 1 || stats[0].AssignedThreads != 1 || stats[0].OpenThreads != 1 {
+        t.Fatalf("got stats %+v, want the thread counted for the group", stats)
+    }
+
+    code, assignment = assign("/api/threads/C1/1700000000.000100/assign", `{"assignee": ""}`)
+    if code != http.StatusOK || assignment.Assignee != nil {
+        t.Fatalf("got status %d and assignment %+v after unassigning", code, assignment)
+    }
+    audited := store.Audited()
+    if len(audited) != 2 || audited[1].Action != "thread.unassign" || audited[1].Details["previous_assignee"] != "S1" {
+        t.Fatalf("audited %+v", audited)
+    }
+}
+
+func TestClaimThreadOnce(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: time.Now()}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    ctx := context.Background()
+
+    tests := []struct {
+        userID string
+        value  string
+        want   string
+    }{
+        {"U1", "C2:1700000000.000100", "This channel is no longer tracked."},
+        {"U1", "C1:1700000000.000100", "You claimed <https://slack.com/archives/C1/p1700000000000100|this thread>."},
+        {"U1", "C1:1700000000.000100", "You already claimed this thread."},
+        {"U2", "C1:1700000000.000100", "<@U1> already claimed this thread."},
+    }
+    for _, tt := range tests {
+        got, err := c.claimThread(ctx, tt.userID, tt.value)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got != tt.want {
+            t.Errorf("claimThread(%s, %s) = %q, want %q", tt.userID, tt.value, got, tt.want)
+        }
+    }
+}
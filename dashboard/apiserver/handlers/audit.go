@@ -0,0 +1,56 @@
+package handlers
+
+import (
+    "context"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/events"
+
+    "github.com/labstack/echo/v4"
+)
+
+// AuditEntry is an action recorded in the audit log.
+type AuditEntry struct {
+    Actor     string
+    Action    string
+    ChannelID string
+    ThreadTS  string
+    Details   map[string]interface{}
+}
+
+// actorFrom returns the user performing a request, for audit records.
+func actorFrom(ctx echo.Context) string {
+    if principal, ok := auth.PrincipalFrom(ctx); ok {
+        return principal.UserID
+    }
+    return "anonymous"
+}
+
+// audit appends an entry to the audit log and publishes it on the event
+// bus, for outgoing webhooks. Failures are logged, an audit write never
+// fails the action it describes.
+func (c *Container) audit(ctx context.Context, actor string, action string, channelID string, threadTS string, details map[string]interface{}) {
+    err := c.auditLog.Audit(ctx, AuditEntry{
+        Actor:     actor,
+        Action:    action,
+        ChannelID: channelID,
+        ThreadTS:  threadTS,
+        Details:   details,
+    })
+    if err != nil {
+        c.logger.Errorf("failed to write audit entry %s for %s/%s: %v", action, channelID, threadTS, err)
+    }
+
+    // Audited actions are the events outgoing webhooks subscribe to
+    c.publish(context.Background(), events.Event{
+        Topic:      events.TopicAction,
+        Workspace:  c.channelWorkspace(ctx, channelID),
+        Action:     action,
+        Actor:      actor,
+        ChannelID:  channelID,
+        ThreadTS:   threadTS,
+        Details:    details,
+        OccurredAt: time.Now().UTC(),
+    })
+}
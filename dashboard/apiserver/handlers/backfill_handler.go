@@ -0,0 +1,203 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/ingest"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// BackfillRequest is the body accepted by StartBackfills
+type BackfillRequest struct {
+    ChannelIDs []string `json:"channel_ids"`
+    // OldestDays limits the import to recent history, 0 imports everything
+    OldestDays int `json:"oldest_days"`
+}
+
+// writeHistoryMessages stores the thread roots of a page of channel
+// history.
+func (c *Container) writeHistoryMessages(ctx context.Context, channelID string, messages []slack.Message) error {
+    threads := []domain.Thread{}
+    for _, msg := range messages {
+        if msg.Subtype != "" || !msg.IsThreadRoot() {
+            continue
+        }
+        createdAt, err := slackTSTime(msg.TS)
+        if err != nil {
+            continue
+        }
+        thread, err := domain.NewThread(channelID, msg.TS, c.globalUserID(ctx, msg.Team, msg.User), createdAt)
+        if err != nil {
+            continue
+        }
+        thread.ReplyCount = msg.ReplyCount
+        if msg.LatestReply != "" {
+            if parsed, err := slackTSTime(msg.LatestReply); err == nil {
+                thread.LatestReply = parsed
+            }
+        }
+        threads = append(threads, thread)
+    }
+    return c.backfills.ImportThreads(ctx, channelID, threads)
+}
+
+// ResumeBackfills restarts backfills interrupted by a restart.
+func (c *Container) ResumeBackfills(ctx context.Context) {
+    if err := c.backfiller.Resume(ctx); err != nil {
+        c.logger.Warnf("failed to resume backfills: %v", err)
+    }
+}
+
+// RunChannelPolling polls the history of the tracked channels of the
+// workspace, picking up messages whose Slack events were missed, until ctx
+// is cancelled. It does nothing unless polling is enabled, and polls no
+// channel while the bot token lacks the channels:history scope.
+func (c *Container) RunChannelPolling(ctx context.Context) {
+    if c.poller == nil {
+        return
+    }
+
+    c.poller.Run(ctx, func(ctx context.Context) ([]string, error) {
+        if !c.slackFeatureReady(slackFeatureThreadHistory) {
+            return nil, nil
+        }
+        channels, err := c.channels.Channels(ctx)
+        if err != nil {
+            return nil, err
+        }
+        ids := make([]string, len(channels))
+        for i, ch := range channels {
+            ids[i] = ch.ChannelID
+        }
+        return ids, nil
+    })
+}
+
+// GetBackfills - Get backfill progress per channel
+func (c *Container) GetBackfills(ctx echo.Context) error {
+    checkpoints, err := c.backfills.ListCheckpoints()
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query backfills")
+    }
+    return ctx.JSON(http.StatusOK, checkpoints)
+}
+
+// StartBackfills - Start importing the history of tracked channels
+func (c *Container) StartBackfills(ctx echo.Context) error {
+    var req BackfillRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if len(req.ChannelIDs) == 0 {
+        return apierror.New(http.StatusBadRequest, "channel_ids is required")
+    }
+
+    oldest := ""
+    if req.OldestDays > 0 {
+        oldest = strconv.FormatInt(time.Now().AddDate(0, 0, -req.OldestDays).Unix(), 10)
+    }
+
+    if c.slack.Configured() && !c.slackFeatureReady(slackFeatureThreadHistory) {
+        return apierror.New(http.StatusServiceUnavailable, "Backfills need the channels:history scope of the Slack bot token")
+    }
+
+    // Backfills outlive the request that started them
+    scheduled, err := c.backfiller.Schedule(context.Background(), req.ChannelIDs, oldest)
+    if err == slack.ErrNotConfigured {
+        return apierror.New(http.StatusServiceUnavailable, "Slack bot token is not configured")
+    }
+    if err != nil {
+        c.logger.Errorf("failed to schedule backfills: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to schedule backfills")
+    }
+
+    return ctx.JSON(http.StatusAccepted, scheduled)
+}
+
+func (s postgresStore) SaveCheckpoint(cp *ingest.Checkpoint) error {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return err
+    }
+
+    _, err = db.Exec(`
+        INSERT INTO backfill_checkpoints (channel_id, status, oldest, cursor, pages,
+            messages_imported, last_error, started_at, updated_at, finished_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        ON CONFLICT (channel_id) DO UPDATE SET
+            status = EXCLUDED.status,
+            oldest = EXCLUDED.oldest,
+            cursor = EXCLUDED.cursor,
+            pages = EXCLUDED.pages,
+            messages_imported = EXCLUDED.messages_imported,
+            last_error = EXCLUDED.last_error,
+            started_at = EXCLUDED.started_at,
+            updated_at = EXCLUDED.updated_at,
+            finished_at = EXCLUDED.finished_at
+    `, cp.ChannelID, cp.Status, cp.Oldest, cp.Cursor, cp.Pages, cp.MessagesImported,
+        cp.LastError, cp.StartedAt, cp.UpdatedAt, cp.FinishedAt)
+    return err
+}
+
+func (s postgresStore) ListCheckpoints() ([]ingest.Checkpoint, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.Query(`
+        SELECT channel_id, status, oldest, cursor, pages, messages_imported,
+               last_error, started_at, updated_at, finished_at
+        FROM backfill_checkpoints
+        ORDER BY started_at DESC
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    checkpoints := []ingest.Checkpoint{}
+    for rows.Next() {
+        var cp ingest.Checkpoint
+        err := rows.Scan(&cp.ChannelID, &cp.Status, &cp.Oldest, &cp.Cursor, &cp.Pages,
+            &cp.MessagesImported, &cp.LastError, &cp.StartedAt, &cp.UpdatedAt, &cp.FinishedAt)
+        if err != nil {
+            return nil, err
+        }
+        checkpoints = append(checkpoints, cp)
+    }
+    return checkpoints, rows.Err()
+}
+
+func (s postgresStore) ImportThreads(ctx context.Context, channelID string, threads []domain.Thread) error {
+    db, err := s.c.channelDB(ctx, channelID)
+    if err != nil {
+        return err
+    }
+    if err := channelTracked(db, channelID); err != nil {
+        return err
+    }
+
+    for _, thread := range threads {
+        _, err := db.ExecContext(ctx, `
+            INSERT INTO threads (thread_ts, channel_id, user_id, reply_count, latest_reply, status, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+            ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+                reply_count = GREATEST(threads.reply_count, EXCLUDED.reply_count),
+                latest_reply = GREATEST(threads.latest_reply, EXCLUDED.latest_reply)
+        `, thread.ThreadTS, thread.ChannelID, thread.UserID, thread.ReplyCount,
+            thread.LatestReply, thread.Status, thread.CreatedAt)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
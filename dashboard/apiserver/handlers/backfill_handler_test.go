@@ -0,0 +1,67 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/ingest"
+    "dashboard/apiserver/slack"
+)
+
+func TestWriteHistoryMessagesOnlyGrowsCounts(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    ctx := context.Background()
+
+    page := []slack.Message{
+        {TS: "1700000000.000100", User: "U1", ReplyCount: 3, LatestReply: "1700000300.000000"},
+        {TS: "1700000100.000000", User: "U2", ThreadTS: "1700000000.000100"},
+        {TS: "1700000200.000000", User: "U3", Subtype: "channel_join"},
+    }
+    if err := c.writeHistoryMessages(ctx, "C1", page); err != nil {
+        t.Fatal(err)
+    }
+    // An older copy of the page leaves the counts as they are
+    page[0].ReplyCount, page[0].LatestReply = 1, "1700000100.000000"
+    if err := c.writeHistoryMessages(ctx, "C1", page); err != nil {
+        t.Fatal(err)
+    }
+
+    thread, err := store.Thread(ctx, "C1", "1700000000.000100")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if thread.ReplyCount != 3 || !thread.LatestReply.Equal(time.Unix(1700000300, 0)) || thread.Status != statusOpen {
+        t.Fatalf("got thread %+v", thread)
+    }
+    for _, ts := range []string{"1700000100.000000", "1700000200.000000"} {
+        if _, err := store.Thread(ctx, "C1", ts); err != errThreadNotFound {
+            t.Fatalf("imported %s, which is not a thread root: %v", ts, err)
+        }
+    }
+    if err := c.writeHistoryMessages(ctx, "C2", page); err != errChannelNotTracked {
+        t.Fatalf("importing an untracked channel got error %v", err)
+    }
+}
+
+func TestGetBackfillsNewestFirst(t *testing.T) {
+    store := NewMemoryStore()
+    c := newTestContainer(t, store, &MemorySlack{})
+    started := time.Now().Add(-time.Hour)
+    store.SaveCheckpoint(&ingest.Checkpoint{ChannelID: "C1", Status: "done", StartedAt: started})
+    store.SaveCheckpoint(&ingest.Checkpoint{ChannelID: "C2", Status: "running", StartedAt: started.Add(time.Minute)})
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/backfills", "/api/backfills", c.GetBackfills)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var checkpoints []ingest.Checkpoint
+    decodeResponse(t, rec, &checkpoints)
+    if len(checkpoints) != 2 || checkpoints[0].ChannelID != "C2" || checkpoints[1].Status != "done" {
+        t.Fatalf("got checkpoints %+v", checkpoints)
+    }
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "math"
+    "net/http"
+    "sort"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+const (
+    calibrationInterval = 6 * time.Hour
+    // calibrationWindow is how far back settled threads are sampled
+    calibrationWindow = 90 * 24 * time.Hour
+    // calibrationSettle is how old an open thread must be for its outcome
+    // to count
+    calibrationSettle = 7 * 24 * time.Hour
+    // calibrationMinSamples is the number of AI high priority threads a
+    // channel needs before its threshold is adjusted
+    calibrationMinSamples = 20
+    // calibrationKeepPercentile is the share of confirmed high priority
+    // threads whose confidence must stay above the threshold
+    calibrationKeepPercentile = 0.9
+    // engagementPercentile is the reply count percentile from which a
+    // thread counts as having needed attention
+    engagementPercentile = 0.75
+)
+
+// PriorityCalibration is the outcome of calibrating the AI priority of a
+// channel. AI high priority threads with a confidence below MinConfidence
+// are shown as medium.
+type PriorityCalibration struct {
+    ChannelID       string    `json:"channel_id"`
+    ChannelName     string    `json:"channel_name"`
+    Samples         int       `json:"samples"`
+    PredictedHigh   int       `json:"predicted_high"`
+    ConfirmedHigh   int       `json:"confirmed_high"`
+    EngagedReplies  int       `json:"engaged_replies"`
+    MinConfidence   float64   `json:"min_confidence"`
+    PrecisionBefore float64   `json:"precision_before"`
+    PrecisionAfter  float64   `json:"precision_after"`
+    Demoted         int       `json:"demoted"`
+    CalibratedAt    time.Time `json:"calibrated_at"`
+}
+
+// parsePriorityCalibration decodes a stored calibration. Channels without
+// one keep every AI high priority.
+func parsePriorityCalibration(stored sql.NullString) PriorityCalibration {
+    var calibration PriorityCalibration
+    if stored.Valid {
+        json.Unmarshal([]byte(stored.String), &calibration)
+    }
+    return calibration
+}
+
+// Apply returns the priority to show for an AI priority and confidence.
+func (p PriorityCalibration) Apply(aiPriority string, confidence *float64) (string, bool) {
+    if aiPriority != string(domain.PriorityHigh) || p.MinConfidence <= 0 || confidence == nil || *confidence >= p.MinConfidence {
+        return aiPriority, false
+    }
+    return string(domain.PriorityMedium), true
+}
+
+// percentile returns the p-th percentile of sorted values using the
+// nearest rank.
+func percentile(sorted []float64, p float64) float64 {
+    if len(sorted) == 0 {
+        return 0
+    }
+    rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+    if rank < 0 {
+        rank = 0
+    }
+    return sorted[rank]
+}
+
+// RunCalibration periodically recalibrates the AI priority threshold of
+// every channel until ctx is cancelled.
+func (c *Container) RunCalibration(ctx context.Context) {
+    ticker := time.NewTicker(calibrationInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.calibratePriorities(ctx); err != nil {
+            c.logger.Warnf("failed to calibrate priorities: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (c *Container) calibratePriorities(ctx context.Context) error {
+    channels, err := c.channels.Channels(ctx)
+    if err != nil {
+        return err
+    }
+    now := time.Now().UTC()
+    for _, ch := range channels {
+        samples, err := c.calibration.CalibrationSamples(ctx, ch.ChannelID, now.Add(-calibrationWindow), now.Add(-calibrationSettle))
+        if err != nil {
+            c.logger.Warnf("failed to calibrate priorities of channel %s: %v", ch.ChannelID, err)
+            continue
+        }
+        calibration := calibrateChannel(ch.ChannelID, ch.ChannelName, samples)
+        err = c.configs.SetChannelConfig(ctx, ch.ChannelID, ChannelConfig{PriorityCalibration: calibration}, settingPriorityCalibration)
+        if err != nil {
+            c.logger.Warnf("failed to store priority calibration of channel %s: %v", ch.ChannelID, err)
+        }
+    }
+    return nil
+}
+
+// calibrationSample is the AI priority of a settled thread and whether it
+// needed attention.
+type calibrationSample struct {
+    replies    int
+    high       bool
+    confidence float64
+    linked     bool
+}
+
+// calibrateChannel compares the AI priorities of the settled threads of a
+// channel against their outcomes. A thread needed attention when someone
+// linked an issue or ticket to it or its reply count reached the channel's
+// engagement percentile. The confidence threshold keeps most of the high
+// priority threads that needed attention and demotes the rest.
+func calibrateChannel(channelID string, channelName string, samples []calibrationSample) PriorityCalibration {
+    calibration := PriorityCalibration{ChannelID: channelID, ChannelName: channelName, CalibratedAt: time.Now().UTC()}
+
+    replies := make([]float64, 0, len(samples))
+    for _, s := range samples {
+        replies = append(replies, float64(s.replies))
+    }
+    sort.Float64s(replies)
+    calibration.Samples = len(samples)
+    calibration.EngagedReplies = int(percentile(replies, engagementPercentile))
+
+    confirmed := []float64{}
+    for _, s := range samples {
+        if !s.high {
+            continue
+        }
+        calibration.PredictedHigh++
+        if s.linked || (s.replies > 0 && s.replies >= calibration.EngagedReplies) {
+            confirmed = append(confirmed, s.confidence)
+        }
+    }
+    calibration.ConfirmedHigh = len(confirmed)
+    if calibration.PredictedHigh == 0 {
+        return calibration
+    }
+    calibration.PrecisionBefore = float64(calibration.ConfirmedHigh) / float64(calibration.PredictedHigh)
+    calibration.PrecisionAfter = calibration.PrecisionBefore
+    if calibration.PredictedHigh < calibrationMinSamples || len(confirmed) == 0 {
+        return calibration
+    }
+
+    sort.Float64s(confirmed)
+    calibration.MinConfidence = percentile(confirmed, 1-calibrationKeepPercentile)
+
+    kept, keptConfirmed := 0, 0
+    for _, s := range samples {
+        if !s.high {
+            continue
+        }
+        if s.confidence < calibration.MinConfidence {
+            calibration.Demoted++
+            continue
+        }
+        kept++
+        if s.linked || (s.replies > 0 && s.replies >= calibration.EngagedReplies) {
+            keptConfirmed++
+        }
+    }
+    if kept > 0 {
+        calibration.PrecisionAfter = float64(keptConfirmed) / float64(kept)
+    }
+    return calibration
+}
+
+// GetPriorityCalibration - Get the latest AI priority calibration of every channel
+func (c *Container) GetPriorityCalibration(ctx echo.Context) error {
+    channels, err := c.channels.Channels(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query calibration")
+    }
+    configs, err := c.configs.ChannelConfigs(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query calibration")
+    }
+
+    report := make([]PriorityCalibration, 0, len(channels))
+    for _, ch := range channels {
+        calibration := configs[ch.ChannelID].PriorityCalibration
+        calibration.ChannelID = ch.ChannelID
+        calibration.ChannelName = ch.ChannelName
+        report = append(report, calibration)
+    }
+
+    return ctx.JSON(http.StatusOK, report)
+}
+
+func (s postgresStore) CalibrationSamples(ctx context.Context, channelID string, since time.Time, settledBefore time.Time) ([]calibrationSample, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT reply_count, COALESCE(ai_priority, ''), ai_confidence,
+               github_issue IS NOT NULL OR jira_ticket IS NOT NULL
+        FROM threads
+        WHERE channel_id = $1 AND created_at > $2 AND (status <> 'open' OR created_at < $3)
+    `, channelID, since, settledBefore)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    samples := []calibrationSample{}
+    for rows.Next() {
+        var s calibrationSample
+        var aiPriority string
+        var confidence sql.NullFloat64
+        if err := rows.Scan(&s.replies, &aiPriority, &confidence, &s.linked); err != nil {
+            continue
+        }
+        s.high = aiPriority == string(domain.PriorityHigh) && confidence.Valid
+        s.confidence = confidence.Float64
+        samples = append(samples, s)
+    }
+    return samples, rows.Err()
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+)
+
+func TestCalibrateChannelDemotesUnconfidentHighPriorities(t *testing.T) {
+    var samples []calibrationSample
+    for i := 0; i < calibrationMinSamples; i++ {
+        // Confident high priorities needed attention, the others did not
+        if i%2 == 0 {
+            samples = append(samples, calibrationSample{replies: 8, high: true, confidence: 0.9})
+        } else {
+            samples = append(samples, calibrationSample{replies: 0, high: true, confidence: 0.4})
+        }
+        samples = append(samples, calibrationSample{replies: 1})
+    }
+
+    calibration := calibrateChannel("C1", "support", samples)
+    if calibration.PredictedHigh != calibrationMinSamples || calibration.ConfirmedHigh != calibrationMinSamples/2 {
+        t.Fatalf("got %d predicted and %d confirmed high priorities", calibration.PredictedHigh, calibration.ConfirmedHigh)
+    }
+    if calibration.MinConfidence != 0.9 || calibration.Demoted != calibrationMinSamples/2 {
+        t.Fatalf("got threshold %v demoting %d threads", calibration.MinConfidence, calibration.Demoted)
+    }
+    if calibration.PrecisionBefore != 0.5 || calibration.PrecisionAfter != 1 {
+        t.Fatalf("got precision %v before and %v after", calibration.PrecisionBefore, calibration.PrecisionAfter)
+    }
+}
+
+func TestGetPriorityCalibrationListsStoredCalibrations(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "alerts"}})
+    settled := time.Now().Add(-2 * calibrationSettle)
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: settled, LatestReply: settled}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: statusOpen, CreatedAt: time.Now(), LatestReply: time.Now()}})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    if err := c.calibratePriorities(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    rec := serveRequest(t, c, http.MethodGet, "/api/calibration", "/api/calibration", c.GetPriorityCalibration)
+    var report []PriorityCalibration
+    decodeResponse(t, rec, &report)
+    if len(report) != 2 || report[0].ChannelName != "alerts" || report[1].ChannelID != "C1" {
+        t.Fatalf("got report %+v, want every channel by name", report)
+    }
+    // The thread opened recently has not settled yet
+    if report[1].Samples != 1 || report[1].CalibratedAt.IsZero() {
+        t.Fatalf("got calibration %+v", report[1])
+    }
+}
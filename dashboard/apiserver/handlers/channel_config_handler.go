@@ -0,0 +1,324 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/reminders"
+
+    "github.com/labstack/echo/v4"
+)
+
+// ChannelConfig is the configuration of a tracked channel. Settings the
+// channel never set hold their default.
+type ChannelConfig struct {
+    TextIndexing        bool
+    View                ChannelView
+    HeatThresholds      HeatThresholds
+    ResolveApproval     ResolveApproval
+    ResponderRotation   *ResponderRotation
+    ReminderSchedule    *reminders.Schedule
+    QualityPrompts      QualityPrompts
+    GitHubRouting       *GitHubRouting
+    JiraMapping         *JiraMapping
+    PriorityCalibration PriorityCalibration
+    SLAHours            SLAHours
+    DefaultAssignees    []string
+    Muted               bool
+    NoteSync            bool
+    // SlackConnect is set with SetChannelSlackConnect only
+    SlackConnect SlackConnect
+}
+
+// defaultChannelConfig is the configuration of channels that set nothing.
+func defaultChannelConfig() ChannelConfig {
+    return ChannelConfig{
+        TextIndexing:     true,
+        View:             defaultChannelView(),
+        HeatThresholds:   defaultHeatThresholds,
+        ResolveApproval:  defaultResolveApproval(),
+        QualityPrompts:   defaultQualityPrompts(),
+        DefaultAssignees: []string{},
+    }
+}
+
+// channelSetting names a setting of ChannelConfig by its channel_config
+// column.
+type channelSetting string
+
+const (
+    settingTextIndexing        channelSetting = "text_indexing"
+    settingView                channelSetting = "view_config"
+    settingHeatThresholds      channelSetting = "heat_thresholds"
+    settingResolveApproval     channelSetting = "resolve_approval"
+    settingResponderRotation   channelSetting = "responder_rotation"
+    settingReminderSchedule    channelSetting = "reminder_schedule"
+    settingQualityPrompts      channelSetting = "quality_prompts"
+    settingGitHubRouting       channelSetting = "github_routing"
+    settingJiraMapping         channelSetting = "jira_mapping"
+    settingPriorityCalibration channelSetting = "priority_calibration"
+    settingSLAHours            channelSetting = "sla_hours"
+    settingDefaultAssignees    channelSetting = "default_assignees"
+    settingMuted               channelSetting = "muted"
+    settingNoteSync            channelSetting = "note_sync"
+)
+
+// TextIndexingRequest is the body accepted by SetChannelTextIndexing
+type TextIndexingRequest struct {
+    Enabled *bool `json:"enabled"`
+}
+
+// clearTextAssignments resets the thread columns holding message text or AI
+// analysis derived from it.
+const clearTextAssignments = `ai_thread_name = NULL, ai_description = NULL, ai_stakeholders = '[]',
+    ai_analysis_json = NULL, thread_issue = NULL`
+
+// channelTextIndexing reports whether text and AI analysis may be stored for
+// a channel. Channels without configuration keep text.
+func (c *Container) channelTextIndexing(ctx context.Context, channelID string) (bool, error) {
+    return c.channels.TextIndexing(ctx, channelID)
+}
+
+// SetChannelTextIndexing - Enable or disable storing message text and AI analysis for a channel
+func (c *Container) SetChannelTextIndexing(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var req TextIndexingRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil || req.Enabled == nil {
+        return apierror.New(http.StatusBadRequest, "expected a boolean enabled field")
+    }
+
+    reqCtx := ctx.Request().Context()
+    err := c.channels.Tracked(reqCtx, channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    actor := actorFrom(ctx)
+    if !*req.Enabled {
+        // Clearing stored text is refused while anything in the channel is held
+        if err := c.checkLegalHold(reqCtx, actor, "channel.clear_text", channelID, ""); err == errLegalHold {
+            return apierror.New(http.StatusConflict, "Channel is under legal hold, its stored text cannot be cleared")
+        } else if err != nil {
+            return apierror.New(http.StatusInternalServerError, "Failed to check legal holds")
+        }
+    }
+
+    config := ChannelConfig{TextIndexing: *req.Enabled}
+    if err := c.configs.SetChannelConfig(reqCtx, channelID, config, settingTextIndexing); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    cleared := int64(0)
+    if !*req.Enabled {
+        // Opting out also drops what was stored before, keeping only
+        // counts and timestamps
+        cleared, err = c.configs.ClearChannelText(reqCtx, channelID)
+        if err != nil {
+            c.logger.Errorf("failed to clear stored text of channel %s: %v", channelID, err)
+            return apierror.New(http.StatusInternalServerError, "Failed to clear stored text")
+        }
+    }
+
+    c.audit(reqCtx, actor, "channel.text_indexing", channelID, "", map[string]interface{}{
+        "enabled":         *req.Enabled,
+        "threads_cleared": cleared,
+    })
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "channel_id":      channelID,
+        "text_indexing":   *req.Enabled,
+        "threads_cleared": cleared,
+    })
+}
+
+// channelConfigColumns are the columns of channel_config read into a
+// ChannelConfig by scanChannelConfig, for a query joining channels ch.
+const channelConfigColumns = `ch.channel_id, COALESCE(cc.text_indexing, TRUE), cc.view_config, cc.heat_thresholds,
+    cc.resolve_approval, cc.responder_rotation, cc.reminder_schedule, cc.quality_prompts, cc.github_routing,
+    cc.jira_mapping, cc.priority_calibration, cc.sla_hours, cc.default_assignees, COALESCE(cc.muted, FALSE),
+    COALESCE(cc.note_sync, FALSE), COALESCE(cc.slack_connect, FALSE), COALESCE(cc.slack_connect_ai, FALSE)`
+
+func scanChannelConfig(row interface{ Scan(...interface{}) error }) (string, ChannelConfig, error) {
+    var channelID string
+    var config ChannelConfig
+    var viewJSON, heatJSON, approvalJSON, rotationJSON, scheduleJSON, promptsJSON sql.NullString
+    var routingJSON, jiraJSON, calibrationJSON, slaJSON, assigneesJSON sql.NullString
+    err := row.Scan(&channelID, &config.TextIndexing, &viewJSON, &heatJSON, &approvalJSON, &rotationJSON,
+        &scheduleJSON, &promptsJSON, &routingJSON, &jiraJSON, &calibrationJSON, &slaJSON, &assigneesJSON,
+        &config.Muted, &config.NoteSync, &config.SlackConnect.Shared, &config.SlackConnect.AIAnalysis)
+    if err != nil {
+        return "", ChannelConfig{}, err
+    }
+
+    config.View = defaultChannelView()
+    if viewJSON.Valid {
+        if err := json.Unmarshal([]byte(viewJSON.String), &config.View); err != nil {
+            config.View = defaultChannelView()
+        }
+    }
+    config.HeatThresholds = parseHeatThresholds(heatJSON)
+    config.ResolveApproval = parseResolveApproval(approvalJSON)
+    config.ResponderRotation = parseResponderRotation(rotationJSON)
+    config.ReminderSchedule = parseReminderSchedule(scheduleJSON)
+    config.QualityPrompts = parseQualityPrompts(promptsJSON)
+    config.GitHubRouting = parseGitHubRouting(routingJSON)
+    config.JiraMapping = parseJiraMapping(jiraJSON)
+    config.PriorityCalibration = parsePriorityCalibration(calibrationJSON)
+    config.SLAHours = parseSLAHours(slaJSON)
+    config.DefaultAssignees = []string{}
+    if assigneesJSON.Valid {
+        json.Unmarshal([]byte(assigneesJSON.String), &config.DefaultAssignees)
+    }
+    return channelID, config, nil
+}
+
+// stored returns the channel_config value of a setting of config. Unset
+// settings are stored as NULL so that channels keep following the default.
+func (config ChannelConfig) stored(setting channelSetting) interface{} {
+    encode := func(value interface{}) interface{} {
+        encoded, _ := json.Marshal(value)
+        return string(encoded)
+    }
+    switch setting {
+    case settingTextIndexing:
+        return config.TextIndexing
+    case settingView:
+        return encode(config.View)
+    case settingHeatThresholds:
+        return encode(config.HeatThresholds)
+    case settingResolveApproval:
+        return encode(config.ResolveApproval)
+    case settingResponderRotation:
+        if config.ResponderRotation != nil && len(config.ResponderRotation.Users) > 0 {
+            return encode(config.ResponderRotation)
+        }
+    case settingReminderSchedule:
+        if config.ReminderSchedule != nil {
+            return encode(config.ReminderSchedule)
+        }
+    case settingQualityPrompts:
+        return encode(config.QualityPrompts)
+    case settingGitHubRouting:
+        if config.GitHubRouting != nil {
+            return encode(config.GitHubRouting)
+        }
+    case settingJiraMapping:
+        if config.JiraMapping != nil {
+            return encode(config.JiraMapping)
+        }
+    case settingPriorityCalibration:
+        return encode(config.PriorityCalibration)
+    case settingSLAHours:
+        if config.SLAHours != nil {
+            return encode(config.SLAHours)
+        }
+    case settingDefaultAssignees:
+        if len(config.DefaultAssignees) > 0 {
+            return encode(config.DefaultAssignees)
+        }
+    case settingMuted:
+        return config.Muted
+    case settingNoteSync:
+        return config.NoteSync
+    }
+    return nil
+}
+
+func (s postgresStore) ChannelConfig(ctx context.Context, channelID string) (ChannelConfig, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ChannelConfig{}, err
+    }
+
+    _, config, err := scanChannelConfig(db.QueryRowContext(ctx, `
+        SELECT `+channelConfigColumns+`
+        FROM channels ch
+        LEFT JOIN channel_config cc ON cc.channel_id = ch.channel_id
+        WHERE ch.channel_id = $1
+    `, channelID))
+    if err == sql.ErrNoRows {
+        return ChannelConfig{}, errChannelNotTracked
+    }
+    return config, err
+}
+
+func (s postgresStore) ChannelConfigs(ctx context.Context) (map[string]ChannelConfig, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT `+channelConfigColumns+`
+        FROM channels ch
+        LEFT JOIN channel_config cc ON cc.channel_id = ch.channel_id
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    configs := make(map[string]ChannelConfig)
+    for rows.Next() {
+        channelID, config, err := scanChannelConfig(rows)
+        if err != nil {
+            return nil, err
+        }
+        configs[channelID] = config
+    }
+    return configs, rows.Err()
+}
+
+func (s postgresStore) SetChannelConfig(ctx context.Context, channelID string, config ChannelConfig, settings ...channelSetting) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    if err := channelTracked(db, channelID); err != nil {
+        return err
+    }
+
+    columns := []string{"channel_id"}
+    values := []string{"$1"}
+    updates := []string{}
+    args := []interface{}{channelID}
+    for _, setting := range settings {
+        args = append(args, config.stored(setting))
+        columns = append(columns, string(setting))
+        values = append(values, fmt.Sprintf("$%d", len(args)))
+        updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", setting, setting))
+    }
+    updates = append(updates, "updated_at = EXCLUDED.updated_at")
+    _, err = db.ExecContext(ctx, fmt.Sprintf(`
+        INSERT INTO channel_config (%s, updated_at)
+        VALUES (%s, NOW())
+        ON CONFLICT (channel_id) DO UPDATE SET %s
+    `, strings.Join(columns, ", "), strings.Join(values, ", "), strings.Join(updates, ", ")), args...)
+    return err
+}
+
+func (s postgresStore) ClearChannelText(ctx context.Context, channelID string) (int64, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return 0, err
+    }
+
+    result, err := db.ExecContext(ctx, "UPDATE threads SET "+clearTextAssignments+" WHERE channel_id = $1", channelID)
+    if err != nil {
+        return 0, err
+    }
+    cleared, _ := result.RowsAffected()
+    if _, err := db.ExecContext(ctx, "UPDATE thread_messages SET text = NULL WHERE channel_id = $1", channelID); err != nil {
+        return 0, err
+    }
+    return cleared, nil
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "math"
+    "net/http"
+    "path"
+    "sort"
+    "strconv"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/ingest"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// maxDiscoveryEstimates bounds the channels whose volume a discovery
+// estimates, each costing a conversations.history call.
+const maxDiscoveryEstimates = 50
+
+// discoverySampleDays is the history sampled to estimate the volume of a
+// channel.
+const discoverySampleDays = 7
+
+// DiscoverChannelsRequest is the body accepted by DiscoverChannels. Pattern
+// limits discovery to channel names matching a glob such as support-*.
+// Onboard lists the discovered channels to start tracking, by ID or name,
+// and BackfillDays how much of their history to import, 30 days when unset
+// and none when 0.
+type DiscoverChannelsRequest struct {
+    Pattern      string   `json:"pattern"`
+    Onboard      []string `json:"onboard"`
+    BackfillDays *int     `json:"backfill_days"`
+}
+
+// DiscoveredChannel is a channel of the bot. ThreadsPerWeek estimates how
+// many threads are started in it a week, from the last week of its history,
+// nil when not estimated.
+type DiscoveredChannel struct {
+    ChannelID      string `json:"channel_id"`
+    ChannelName    string `json:"channel_name"`
+    Tracked        bool   `json:"tracked"`
+    ThreadsPerWeek *int   `json:"threads_per_week"`
+}
+
+// DiscoverChannelsResponse lists the discovered channels, those onboarded
+// and their backfills. BackfillMinutes estimates how long the backfills take
+// within the Slack rate limits of the bot.
+type DiscoverChannelsResponse struct {
+    Channels        []DiscoveredChannel `json:"channels"`
+    Onboarded       []string            `json:"onboarded"`
+    Backfills       []ingest.Checkpoint `json:"backfills"`
+    BackfillMinutes int                 `json:"backfill_minutes"`
+}
+
+// estimateThreadsPerWeek estimates the threads started in a channel a week
+// from a page of its last week of history. A full page covering less than
+// the week is extrapolated.
+func (c *Container) estimateThreadsPerWeek(ctx context.Context, channelID string) (int, error) {
+    now := time.Now()
+    since := now.AddDate(0, 0, -discoverySampleDays)
+    page, err := c.slack.ConversationsHistory(ctx, channelID, strconv.FormatInt(since.Unix(), 10), "", 200)
+    if err != nil {
+        return 0, err
+    }
+    roots := 0
+    oldest := now
+    for _, msg := range page.Messages {
+        if msg.Subtype != "" || !msg.IsThreadRoot() {
+            continue
+        }
+        roots++
+        if postedAt, err := slackTSTime(msg.TS); err == nil && postedAt.Before(oldest) {
+            oldest = postedAt
+        }
+    }
+    if page.HasMore && roots > 0 && oldest.After(since) {
+        sampled := now.Sub(oldest)
+        return int(math.Round(float64(roots) * float64(now.Sub(since)) / float64(sampled))), nil
+    }
+    return roots, nil
+}
+
+// DiscoverChannels - List the channels of the bot, optionally matching a name pattern, with their estimated volume, and onboard a selection of them with backfills
+func (c *Container) DiscoverChannels(ctx echo.Context) error {
+    var req DiscoverChannelsRequest
+    if ctx.Request().ContentLength != 0 {
+        if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+            return apierror.New(http.StatusBadRequest, "Invalid request body")
+        }
+    }
+    if _, err := path.Match(req.Pattern, ""); err != nil {
+        return apierror.New(http.StatusBadRequest, "pattern is not a valid glob")
+    }
+    backfillDays := quickstartBackfillDays
+    if req.BackfillDays != nil {
+        backfillDays = *req.BackfillDays
+    }
+    if backfillDays < 0 {
+        return apierror.New(http.StatusBadRequest, "backfill_days must not be negative")
+    }
+    if !c.slackFeatureReady(slackFeatureDiscovery) {
+        return apierror.New(http.StatusServiceUnavailable, "Discovering channels needs the channels:read and groups:read scopes of the Slack bot token")
+    }
+    if len(req.Onboard) > 0 && backfillDays > 0 && !c.slackFeatureReady(slackFeatureThreadHistory) {
+        return apierror.New(http.StatusServiceUnavailable, "Backfills need the channels:history scope of the Slack bot token")
+    }
+
+    reqCtx := ctx.Request().Context()
+    tracked := map[string]bool{}
+    channels, err := c.channels.Channels(reqCtx)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to get channels")
+    }
+    for _, ch := range channels {
+        tracked[ch.ChannelID] = true
+    }
+
+    resp := DiscoverChannelsResponse{Channels: []DiscoveredChannel{}, Onboarded: []string{}, Backfills: []ingest.Checkpoint{}}
+    cursor := ""
+    for {
+        page, err := c.slack.UsersConversations(reqCtx, workspaceFrom(reqCtx), cursor, 200)
+        if err != nil {
+            c.logger.Errorf("failed to discover channels: %v", err)
+            return apierror.New(http.StatusBadGateway, "Failed to list the channels of the bot")
+        }
+        for _, ch := range page.Channels {
+            if req.Pattern != "" {
+                if ok, _ := path.Match(req.Pattern, ch.Name); !ok {
+                    continue
+                }
+            }
+            resp.Channels = append(resp.Channels, DiscoveredChannel{
+                ChannelID:   ch.ID,
+                ChannelName: ch.Name,
+                Tracked:     tracked[ch.ID],
+            })
+        }
+        cursor = page.ResponseMetadata.NextCursor
+        if cursor == "" {
+            break
+        }
+    }
+
+    selected := map[string]bool{}
+    for _, id := range req.Onboard {
+        selected[id] = true
+    }
+    var unknown []string
+    for id := range selected {
+        found := false
+        for _, ch := range resp.Channels {
+            if ch.ChannelID != id && ch.ChannelName != id {
+                continue
+            }
+            found = true
+        }
+        if !found {
+            unknown = append(unknown, id)
+        }
+    }
+    if len(unknown) > 0 {
+        sort.Strings(unknown)
+        return apierror.New(http.StatusBadRequest, "Some channels to onboard were not discovered, the bot must be a member of them").
+            WithDetails(map[string]interface{}{"unknown": unknown})
+    }
+
+    // Channels to onboard are estimated first, their backfills are
+    // estimated from it
+    if c.slackFeatureReady(slackFeatureThreadHistory) {
+        estimated := 0
+        for _, onboarding := range []bool{true, false} {
+            for i := range resp.Channels {
+                ch := &resp.Channels[i]
+                isSelected := selected[ch.ChannelID] || selected[ch.ChannelName]
+                if ch.Tracked || isSelected != onboarding || estimated >= maxDiscoveryEstimates {
+                    continue
+                }
+                estimated++
+                threads, err := c.estimateThreadsPerWeek(reqCtx, ch.ChannelID)
+                if err != nil {
+                    c.logger.Warnf("failed to estimate the volume of channel %s: %v", ch.ChannelID, err)
+                    continue
+                }
+                ch.ThreadsPerWeek = &threads
+            }
+        }
+    }
+
+    if len(selected) == 0 {
+        return ctx.JSON(http.StatusOK, resp)
+    }
+
+    actor := actorFrom(ctx)
+    pages := 0
+    for _, ch := range resp.Channels {
+        if !selected[ch.ChannelID] && !selected[ch.ChannelName] {
+            continue
+        }
+        added, err := c.channels.TrackChannel(reqCtx, ch.ChannelID, ch.ChannelName)
+        if err != nil {
+            c.logger.Errorf("failed to onboard channel %s: %v", ch.ChannelID, err)
+            return apierror.New(http.StatusInternalServerError, "Failed to onboard channel #"+ch.ChannelName)
+        }
+        if !added {
+            continue
+        }
+        resp.Onboarded = append(resp.Onboarded, ch.ChannelID)
+        c.audit(reqCtx, actor, "channel.onboard", ch.ChannelID, "", map[string]interface{}{
+            "backfill_days": backfillDays,
+        })
+
+        // Backfills fetch a page of history per call
+        pages++
+        if ch.ThreadsPerWeek != nil {
+            pages += *ch.ThreadsPerWeek * backfillDays / 7 / 200
+        }
+    }
+
+    if len(resp.Onboarded) > 0 && backfillDays > 0 {
+        oldest := strconv.FormatInt(time.Now().AddDate(0, 0, -backfillDays).Unix(), 10)
+        // Backfills outlive the request that started them, and share the
+        // Slack rate limits of the bot however many run at once
+        resp.Backfills, err = c.backfiller.Schedule(context.Background(), resp.Onboarded, oldest)
+        if err == slack.ErrNotConfigured {
+            return apierror.New(http.StatusServiceUnavailable, "Slack bot token is not configured")
+        }
+        if err != nil {
+            c.logger.Errorf("failed to schedule backfills: %v", err)
+            return apierror.New(http.StatusInternalServerError, "Failed to schedule backfills")
+        }
+        resp.BackfillMinutes = int(math.Ceil(float64(pages) / c.slack.CallsPerMinute("conversations.history")))
+    }
+
+    c.logger.Infof("%s onboarded %d channels", actor, len(resp.Onboarded))
+    return ctx.JSON(http.StatusOK, resp)
+}
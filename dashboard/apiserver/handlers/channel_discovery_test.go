@@ -0,0 +1,46 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/slack"
+)
+
+func TestDiscoverChannelsOnboardsSelection(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    slackClient := &MemorySlack{Conversations: []slack.Conversation{
+        {ID: "C1", Name: "support"},
+        {ID: "C2", Name: "support-eu"},
+        {ID: "C3", Name: "random"},
+    }}
+    c := newTestContainer(t, store, slackClient)
+    admin := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+
+    route := "/api/channels/discover"
+    if rec := serveAs(c, admin, http.MethodPost, route, route, `{"onboard": ["random"], "pattern": "support*"}`, c.DiscoverChannels); rec.Code != http.StatusBadRequest {
+        t.Fatalf("onboarding a channel outside the pattern got status %d", rec.Code)
+    }
+    rec := serveAs(c, admin, http.MethodPost, route, route, `{"pattern": "support*", "onboard": ["support", "support-eu"], "backfill_days": 0}`, c.DiscoverChannels)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var resp DiscoverChannelsResponse
+    decodeResponse(t, rec, &resp)
+    if len(resp.Channels) != 2 || !resp.Channels[0].Tracked || resp.Channels[1].Tracked {
+        t.Fatalf("discovered %+v", resp.Channels)
+    }
+    if len(resp.Onboarded) != 1 || resp.Onboarded[0] != "C2" {
+        t.Fatalf("onboarded %v, want the untracked channel only", resp.Onboarded)
+    }
+    if ch, err := store.Channel(context.Background(), "C2"); err != nil || ch.ChannelName != "support-eu" {
+        t.Fatalf("got channel %+v, %v", ch, err)
+    }
+    if err := store.Tracked(context.Background(), "C3"); err != errChannelNotTracked {
+        t.Fatalf("tracked a channel that was not selected: %v", err)
+    }
+}
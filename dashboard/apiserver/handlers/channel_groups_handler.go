@@ -0,0 +1,350 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+var (
+    errGroupNotFound  = errors.New("channel group not found")
+    errGroupNameTaken = errors.New("channel group name taken")
+)
+
+// ChannelGroup is a named set of channels, e.g. all customer channels, that
+// stats, thread lists and reminder policies can be scoped to.
+type ChannelGroup struct {
+    ID          int64     `json:"id"`
+    Name        string    `json:"name"`
+    Description string    `json:"description"`
+    ChannelIDs  []string  `json:"channel_ids"`
+    RemindAfter string    `json:"remind_after"`
+    CreatedBy   string    `json:"created_by"`
+    CreatedAt   time.Time `json:"created_at"`
+}
+
+// validate normalizes a group from a request and returns an error message
+// for invalid ones.
+func (g *ChannelGroup) validate() string {
+    g.Name = strings.TrimSpace(g.Name)
+    if g.Name == "" {
+        return "name is required"
+    }
+    if g.ChannelIDs == nil {
+        g.ChannelIDs = []string{}
+    }
+    if g.RemindAfter != "" {
+        d, err := time.ParseDuration(g.RemindAfter)
+        if err != nil || d <= 0 {
+            return "remind_after must be a positive duration such as 24h"
+        }
+    }
+    return ""
+}
+
+// groupChannelFilter returns a condition on the channel ID column limiting
+// a query to the members of group, a group ID or name, numbering its
+// argument after the first argOffset ones. An empty group matches every
+// channel.
+func groupChannelFilter(db *sql.DB, group string, column string, argOffset int) (string, []interface{}, error) {
+    if group == "" {
+        return "TRUE", nil, nil
+    }
+
+    var id int64
+    err := db.QueryRow(`
+        SELECT id FROM channel_groups WHERE id::TEXT = $1 OR name = $1
+    `, group).Scan(&id)
+    if err == sql.ErrNoRows {
+        return "", nil, errGroupNotFound
+    }
+    if err != nil {
+        return "", nil, err
+    }
+    return column + " IN (SELECT channel_id FROM channel_group_members WHERE group_id = $" +
+        strconv.Itoa(argOffset+1) + ")", []interface{}{id}, nil
+}
+
+// groupRemindAfter returns the shortest reminder threshold of the groups
+// each channel belongs to.
+func groupRemindAfter(groups []ChannelGroup) map[string]time.Duration {
+    thresholds := make(map[string]time.Duration)
+    for _, group := range groups {
+        d, err := time.ParseDuration(group.RemindAfter)
+        if err != nil || d <= 0 {
+            continue
+        }
+        for _, channelID := range group.ChannelIDs {
+            if current, ok := thresholds[channelID]; !ok || d < current {
+                thresholds[channelID] = d
+            }
+        }
+    }
+    return thresholds
+}
+
+// GetChannelGroups - List channel groups
+func (c *Container) GetChannelGroups(ctx echo.Context) error {
+    groups, err := c.groups.ChannelGroups(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query channel groups")
+    }
+
+    return ctx.JSON(http.StatusOK, groups)
+}
+
+// GetChannelGroup - Get a channel group
+func (c *Container) GetChannelGroup(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid channel group id")
+    }
+
+    g, err := c.groups.ChannelGroup(ctx.Request().Context(), id)
+    if err == errGroupNotFound {
+        return apierror.New(http.StatusNotFound, "Channel group not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query channel group")
+    }
+
+    return ctx.JSON(http.StatusOK, g)
+}
+
+// CreateChannelGroup - Create a channel group
+func (c *Container) CreateChannelGroup(ctx echo.Context) error {
+    var g ChannelGroup
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&g); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if msg := g.validate(); msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    actor := actorFrom(ctx)
+    g.CreatedBy = actor
+    g, err := c.groups.CreateChannelGroup(ctx.Request().Context(), g)
+    if err == errGroupNameTaken {
+        return apierror.New(http.StatusConflict, "A channel group with this name already exists")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to create channel group")
+    }
+
+    c.audit(ctx.Request().Context(), actor, "channel_group.create", "", "", map[string]interface{}{
+        "group": g,
+    })
+
+    return ctx.JSON(http.StatusCreated, g)
+}
+
+// UpdateChannelGroup - Replace the name, channels and reminder policy of a channel group
+func (c *Container) UpdateChannelGroup(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid channel group id")
+    }
+
+    var g ChannelGroup
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&g); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if msg := g.validate(); msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    g.ID = id
+    g, err = c.groups.UpdateChannelGroup(ctx.Request().Context(), g)
+    switch err {
+    case nil:
+    case errGroupNotFound:
+        return apierror.New(http.StatusNotFound, "Channel group not found")
+    case errGroupNameTaken:
+        return apierror.New(http.StatusConflict, "A channel group with this name already exists")
+    default:
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel group")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel_group.update", "", "", map[string]interface{}{
+        "group": g,
+    })
+
+    return ctx.JSON(http.StatusOK, g)
+}
+
+// DeleteChannelGroup - Delete a channel group, leaving its channels untouched
+func (c *Container) DeleteChannelGroup(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid channel group id")
+    }
+
+    err = c.groups.DeleteChannelGroup(ctx.Request().Context(), id)
+    if err == errGroupNotFound {
+        return apierror.New(http.StatusNotFound, "Channel group not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to delete channel group")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel_group.delete", "", "", map[string]interface{}{
+        "group_id": id,
+    })
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// channelGroupColumns are the columns scanChannelGroup reads from
+// channel_groups g.
+const channelGroupColumns = `g.id, g.name, g.description, g.remind_after, g.created_by, g.created_at,
+    ARRAY(SELECT m.channel_id FROM channel_group_members m WHERE m.group_id = g.id ORDER BY m.channel_id)`
+
+func scanChannelGroup(row interface{ Scan(...interface{}) error }) (ChannelGroup, error) {
+    var g ChannelGroup
+    err := row.Scan(&g.ID, &g.Name, &g.Description, &g.RemindAfter, &g.CreatedBy, &g.CreatedAt, pq.Array(&g.ChannelIDs))
+    return g, err
+}
+
+func (s postgresStore) ChannelGroups(ctx context.Context) ([]ChannelGroup, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, "SELECT "+channelGroupColumns+" FROM channel_groups g ORDER BY g.name")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    groups := []ChannelGroup{}
+    for rows.Next() {
+        g, err := scanChannelGroup(rows)
+        if err != nil {
+            continue
+        }
+        groups = append(groups, g)
+    }
+    return groups, rows.Err()
+}
+
+func (s postgresStore) ChannelGroup(ctx context.Context, id int64) (ChannelGroup, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ChannelGroup{}, err
+    }
+    g, err := scanChannelGroup(db.QueryRowContext(ctx, "SELECT "+channelGroupColumns+" FROM channel_groups g WHERE g.id = $1", id))
+    if err == sql.ErrNoRows {
+        return ChannelGroup{}, errGroupNotFound
+    }
+    return g, err
+}
+
+// saveGroupMembers replaces the channels of a group.
+func saveGroupMembers(ctx context.Context, tx *sql.Tx, id int64, channelIDs []string) error {
+    if _, err := tx.ExecContext(ctx, "DELETE FROM channel_group_members WHERE group_id = $1", id); err != nil {
+        return err
+    }
+    _, err := tx.ExecContext(ctx, `
+        INSERT INTO channel_group_members (group_id, channel_id)
+        SELECT $1, ch.channel_id FROM channels ch WHERE ch.channel_id = ANY($2)
+    `, id, pq.Array(channelIDs))
+    return err
+}
+
+func (s postgresStore) CreateChannelGroup(ctx context.Context, g ChannelGroup) (ChannelGroup, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ChannelGroup{}, err
+    }
+
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return ChannelGroup{}, err
+    }
+    defer tx.Rollback()
+
+    var id int64
+    err = tx.QueryRowContext(ctx, `
+        INSERT INTO channel_groups (name, description, remind_after, created_by)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (name) DO NOTHING
+        RETURNING id
+    `, g.Name, g.Description, g.RemindAfter, g.CreatedBy).Scan(&id)
+    if err == sql.ErrNoRows {
+        return ChannelGroup{}, errGroupNameTaken
+    }
+    if err != nil {
+        return ChannelGroup{}, err
+    }
+    if err := saveGroupMembers(ctx, tx, id, g.ChannelIDs); err != nil {
+        return ChannelGroup{}, err
+    }
+    if err := tx.Commit(); err != nil {
+        return ChannelGroup{}, err
+    }
+    return s.ChannelGroup(ctx, id)
+}
+
+func (s postgresStore) UpdateChannelGroup(ctx context.Context, g ChannelGroup) (ChannelGroup, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ChannelGroup{}, err
+    }
+
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return ChannelGroup{}, err
+    }
+    defer tx.Rollback()
+
+    result, err := tx.ExecContext(ctx, `
+        UPDATE channel_groups SET name = $2, description = $3, remind_after = $4
+        WHERE id = $1
+    `, g.ID, g.Name, g.Description, g.RemindAfter)
+    if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+        return ChannelGroup{}, errGroupNameTaken
+    }
+    if err != nil {
+        return ChannelGroup{}, err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ChannelGroup{}, errGroupNotFound
+    }
+    if err := saveGroupMembers(ctx, tx, g.ID, g.ChannelIDs); err != nil {
+        return ChannelGroup{}, err
+    }
+    if err := tx.Commit(); err != nil {
+        return ChannelGroup{}, err
+    }
+    return s.ChannelGroup(ctx, g.ID)
+}
+
+func (s postgresStore) DeleteChannelGroup(ctx context.Context, id int64) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    if _, err := db.ExecContext(ctx, "DELETE FROM channel_group_members WHERE group_id = $1", id); err != nil {
+        return err
+    }
+    result, err := db.ExecContext(ctx, "DELETE FROM channel_groups WHERE id = $1", id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return errGroupNotFound
+    }
+    return nil
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestChannelGroupLifecycle(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "billing"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: time.Now()}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C2", ThreadTS: "1700000000.000200", Status: statusOpen, CreatedAt: time.Now()}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    admin := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+
+    // Untracked channels are left out of the group
+    rec := serveAs(c, admin, http.MethodPost, "/api/channel-groups", "/api/channel-groups",
+        `{"name": " customers ", "channel_ids": ["C1", "C9"], "remind_after": "4h"}`, c.CreateChannelGroup)
+    if rec.Code != http.StatusCreated {
+        t.Fatalf("creating the group got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var group ChannelGroup
+    decodeResponse(t, rec, &group)
+    if group.ID == 0 || group.Name != "customers" || group.CreatedBy != "U1" || len(group.ChannelIDs) != 1 || group.ChannelIDs[0] != "C1" {
+        t.Fatalf("got group %+v", group)
+    }
+    rec = serveAs(c, admin, http.MethodPost, "/api/channel-groups", "/api/channel-groups", `{"name": "customers"}`, c.CreateChannelGroup)
+    if rec.Code != http.StatusConflict {
+        t.Fatalf("creating a second group named alike got status %d", rec.Code)
+    }
+
+    rec = serveAs(c, admin, http.MethodGet, "/api/stats", "/api/stats?group=customers", "", c.GetDashboardStats)
+    var stats DashboardStats
+    decodeResponse(t, rec, &stats)
+    if stats.Channels != 1 || stats.TotalThreads != 1 {
+        t.Fatalf("got stats %+v, want those of the group", stats)
+    }
+
+    rec = serveAs(c, admin, http.MethodPut, "/api/channel-groups/:id", "/api/channel-groups/99", `{"name": "other"}`, c.UpdateChannelGroup)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("updating a missing group got status %d", rec.Code)
+    }
+    target := "/api/channel-groups/" + strconv.FormatInt(group.ID, 10)
+    rec = serveAs(c, admin, http.MethodPut, "/api/channel-groups/:id", target, `{"name": "customers", "channel_ids": ["C2", "C1"]}`, c.UpdateChannelGroup)
+    decodeResponse(t, rec, &group)
+    if rec.Code != http.StatusOK || len(group.ChannelIDs) != 2 || group.RemindAfter != "" || group.CreatedBy != "U1" {
+        t.Fatalf("got status %d and group %+v", rec.Code, group)
+    }
+
+    rec = serveAs(c, admin, http.MethodDelete, "/api/channel-groups/:id", target, "", c.DeleteChannelGroup)
+    if rec.Code != http.StatusNoContent {
+        t.Fatalf("deleting the group got status %d", rec.Code)
+    }
+    rec = serveAs(c, admin, http.MethodGet, "/api/channel-groups/:id", target, "", c.GetChannelGroup)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("got status %d for a deleted group", rec.Code)
+    }
+    rec = serveAs(c, admin, http.MethodGet, "/api/stats", "/api/stats?group=customers", "", c.GetDashboardStats)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("got status %d for the stats of a deleted group", rec.Code)
+    }
+}
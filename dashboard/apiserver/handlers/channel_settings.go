@@ -0,0 +1,156 @@
+package handlers
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/reminders"
+
+    "github.com/labstack/echo/v4"
+)
+
+// maxSLAHours bounds the SLA of a priority to a year.
+const maxSLAHours = 24 * 365
+
+// SLAHours are the hours by priority within which the threads of a channel
+// are due, replacing the red heat threshold for threads of those priorities.
+type SLAHours map[string]float64
+
+func (h SLAHours) validate() string {
+    for priority, hours := range h {
+        if _, err := domain.ParsePriority(priority); err != nil {
+            return fmt.Sprintf("unknown priority %q in sla_hours, expected high, medium, low or none", priority)
+        }
+        if hours <= 0 || hours > maxSLAHours {
+            return fmt.Sprintf("sla_hours of %s must be between 0 and %d", priority, maxSLAHours)
+        }
+    }
+    return ""
+}
+
+// parseSLAHours decodes stored SLA hours, nil when the channel has none.
+func parseSLAHours(stored sql.NullString) SLAHours {
+    if !stored.Valid {
+        return nil
+    }
+    var hours SLAHours
+    if err := json.Unmarshal([]byte(stored.String), &hours); err != nil || hours.validate() != "" {
+        return nil
+    }
+    return hours
+}
+
+// thresholds returns the heat thresholds of a thread of priority, scaled to
+// turn red when its SLA is due.
+func (h SLAHours) thresholds(base HeatThresholds, priority string) HeatThresholds {
+    hours, ok := h[priority]
+    if !ok {
+        return base
+    }
+    scale := hours / base.RedHours
+    return HeatThresholds{YellowHours: base.YellowHours * scale, OrangeHours: base.OrangeHours * scale, RedHours: hours}
+}
+
+// ChannelSettings are the reminder and SLA settings of a channel, set at
+// once with SetChannelSettings.
+type ChannelSettings struct {
+    // ReminderSchedule is the same schedule set by
+    // SetChannelReminderSchedule, nil for the dashboard-wide threshold
+    ReminderSchedule *reminders.Schedule `json:"reminder_schedule"`
+    SLAHours         SLAHours            `json:"sla_hours"`
+    // DefaultAssignees own unacknowledged threads when the channel has
+    // no responder rotation
+    DefaultAssignees []string `json:"default_assignees"`
+    // Muted channels get no reminders
+    Muted bool `json:"muted"`
+}
+
+func (s *ChannelSettings) validate() string {
+    if s.ReminderSchedule != nil {
+        if s.ReminderSchedule.RemindAfter == "" {
+            s.ReminderSchedule = nil
+        } else if err := s.ReminderSchedule.Validate(); err != nil {
+            return err.Error()
+        }
+    }
+    if len(s.SLAHours) == 0 {
+        s.SLAHours = nil
+    }
+    if msg := s.SLAHours.validate(); msg != "" {
+        return msg
+    }
+    seen := make(map[string]bool, len(s.DefaultAssignees))
+    for _, user := range s.DefaultAssignees {
+        if !slackUserPattern.MatchString(user) {
+            return fmt.Sprintf("default_assignees must list Slack user IDs, got %q", user)
+        }
+        if seen[user] {
+            return "user " + user + " is listed twice in default_assignees"
+        }
+        seen[user] = true
+    }
+    if s.DefaultAssignees == nil {
+        s.DefaultAssignees = []string{}
+    }
+    return ""
+}
+
+// GetChannelSettings - Get the reminder schedule, SLA hours by priority, default assignees and mute flag of a channel
+func (c *Container) GetChannelSettings(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    config, err := c.configs.ChannelConfig(ctx.Request().Context(), channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query channel configuration")
+    }
+    settings := ChannelSettings{
+        ReminderSchedule: config.ReminderSchedule,
+        SLAHours:         config.SLAHours,
+        DefaultAssignees: config.DefaultAssignees,
+        Muted:            config.Muted,
+    }
+
+    return ctx.JSON(http.StatusOK, settings)
+}
+
+// SetChannelSettings - Replace the reminder schedule, SLA hours by priority, default assignees and mute flag of a channel
+func (c *Container) SetChannelSettings(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var settings ChannelSettings
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&settings); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if msg := settings.validate(); msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    err := c.configs.SetChannelConfig(ctx.Request().Context(), channelID, ChannelConfig{
+        ReminderSchedule: settings.ReminderSchedule,
+        SLAHours:         settings.SLAHours,
+        DefaultAssignees: settings.DefaultAssignees,
+        Muted:            settings.Muted,
+    }, settingReminderSchedule, settingSLAHours, settingDefaultAssignees, settingMuted)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.config", channelID, "", map[string]interface{}{
+        "reminder_schedule": settings.ReminderSchedule,
+        "sla_hours":         settings.SLAHours,
+        "default_assignees": settings.DefaultAssignees,
+        "muted":             settings.Muted,
+    })
+
+    return ctx.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestChannelSettingsKeepOtherConfiguration(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    admin := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+
+    rec := serveAs(c, admin, http.MethodPut, "/api/channels/:channel_id/config", "/api/channels/C2/config", `{"muted": true}`, c.SetChannelSettings)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("untracked channel got status %d", rec.Code)
+    }
+    rec = serveAs(c, admin, http.MethodPut, "/api/channels/:channel_id/heat", "/api/channels/C1/heat",
+        `{"yellow_hours": 1, "orange_hours": 2, "red_hours": 3}`, c.SetChannelHeatThresholds)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("setting thresholds got status %d: %s", rec.Code, rec.Body.String())
+    }
+    rec = serveAs(c, admin, http.MethodPut, "/api/channels/:channel_id/config", "/api/channels/C1/config",
+        `{"sla_hours": {"high": 4}, "default_assignees": ["U2"], "muted": true}`, c.SetChannelSettings)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("setting settings got status %d: %s", rec.Code, rec.Body.String())
+    }
+
+    rec = serveAs(c, admin, http.MethodGet, "/api/channels/:channel_id/config", "/api/channels/C1/config", "", c.GetChannelSettings)
+    var settings ChannelSettings
+    decodeResponse(t, rec, &settings)
+    if !settings.Muted || settings.SLAHours["high"] != 4 || len(settings.DefaultAssignees) != 1 || settings.ReminderSchedule != nil {
+        t.Fatalf("got settings %+v", settings)
+    }
+
+    // Settings replace their own fields only
+    rec = serveAs(c, admin, http.MethodGet, "/api/channels/:channel_id", "/api/channels/C1", "", c.GetChannel)
+    var channel struct {
+        ChannelName    string         `json:"channel_name"`
+        TextIndexing   bool           `json:"text_indexing"`
+        HeatThresholds HeatThresholds `json:"heat_thresholds"`
+        View           ChannelView    `json:"view"`
+        Muted          bool           `json:"muted"`
+    }
+    decodeResponse(t, rec, &channel)
+    if channel.ChannelName != "support" || !channel.TextIndexing || channel.HeatThresholds.RedHours != 3 || !channel.Muted {
+        t.Fatalf("got channel %+v", channel)
+    }
+    if channel.View.Sort != defaultChannelView().Sort {
+        t.Fatalf("got view %+v, want the default", channel.View)
+    }
+
+    rec = serveAs(c, admin, http.MethodPut, "/api/channels/:channel_id/text-indexing", "/api/channels/C1/text-indexing", `{"enabled": false}`, c.SetChannelTextIndexing)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("disabling text indexing got status %d: %s", rec.Code, rec.Body.String())
+    }
+    config, err := store.ChannelConfig(context.Background(), "C1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if config.TextIndexing || config.HeatThresholds.RedHours != 3 || !config.Muted {
+        t.Fatalf("got configuration %+v, want only text indexing off", config)
+    }
+}
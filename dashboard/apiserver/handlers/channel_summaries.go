@@ -0,0 +1,475 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/events"
+    "dashboard/apiserver/slack"
+    "dashboard/apiserver/summaries"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+// Modes of the summary of a channel: its canvas, a pinned message, or
+// none.
+const (
+    SummaryModeCanvas = "canvas"
+    SummaryModePinned = "pinned"
+    SummaryModeOff    = "off"
+)
+
+// summaryFlushInterval batches the changes of channels into one summary
+// update per channel at most this often.
+const summaryFlushInterval = 30 * time.Second
+
+// summaryRefreshInterval is how often every summary is refreshed, catching
+// changes no event told about such as threads written by the collector.
+const summaryRefreshInterval = 15 * time.Minute
+
+// SlackSummaryRequest is the body accepted by SetChannelSummary
+type SlackSummaryRequest struct {
+    Mode string `json:"mode"`
+}
+
+// SlackSummary is how the open threads of a channel are summarized in
+// Slack and how the last update went
+type SlackSummary struct {
+    ChannelID string     `json:"channel_id"`
+    Mode      string     `json:"mode"`
+    CanvasID  *string    `json:"canvas_id"`
+    MessageTS *string    `json:"message_ts"`
+    SyncedAt  *time.Time `json:"synced_at"`
+    LastError *string    `json:"last_error"`
+}
+
+// summaryState is where the summary of a channel was last written to
+// Slack and the hash of its content.
+type summaryState struct {
+    mode        string
+    canvasID    sql.NullString
+    messageTS   sql.NullString
+    contentHash sql.NullString
+}
+
+// summaryQueue holds the channels whose summary needs an update, by
+// workspace, until the next flush.
+type summaryQueue struct {
+    mu    sync.Mutex
+    dirty map[string]map[string]bool
+}
+
+func newSummaryQueue() *summaryQueue {
+    return &summaryQueue{dirty: make(map[string]map[string]bool)}
+}
+
+func (q *summaryQueue) add(workspace string, channelID string) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    if q.dirty[workspace] == nil {
+        q.dirty[workspace] = make(map[string]bool)
+    }
+    q.dirty[workspace][channelID] = true
+}
+
+// take returns and forgets the channels of workspace needing an update.
+func (q *summaryQueue) take(workspace string) []string {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    channels := make([]string, 0, len(q.dirty[workspace]))
+    for channelID := range q.dirty[workspace] {
+        channels = append(channels, channelID)
+    }
+    delete(q.dirty, workspace)
+    return channels
+}
+
+// queueChannelSummary marks the summary of the channel of an event for the
+// next update.
+func (c *Container) queueChannelSummary(ctx context.Context, event events.Event) {
+    if event.ChannelID != "" {
+        c.summaries.add(event.Workspace, event.ChannelID)
+    }
+}
+
+// RunChannelSummaries keeps the summaries of the channels opting in up to
+// date in Slack, flushing the channels changed since the last flush every
+// summaryFlushInterval and refreshing all of them every
+// summaryRefreshInterval, until ctx is cancelled. Unchanged summaries are
+// not written, and channels rate limited by Slack wait for the next flush.
+// It does nothing without a bot token.
+func (c *Container) RunChannelSummaries(ctx context.Context) {
+    if !c.slack.Configured() {
+        return
+    }
+
+    workspace := workspaceFrom(ctx)
+    ticker := time.NewTicker(summaryFlushInterval)
+    defer ticker.Stop()
+    var refreshedAt time.Time
+    for {
+        channels := c.summaries.take(workspace)
+        if time.Since(refreshedAt) >= summaryRefreshInterval {
+            channels, refreshedAt = nil, time.Now()
+        }
+        err := c.flushChannelSummaries(ctx, channels)
+        if err != nil && ctx.Err() == nil {
+            c.logger.Warnf("failed to update channel summaries: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// flushChannelSummaries updates the summaries of channels, of every
+// channel opting in when nil.
+func (c *Container) flushChannelSummaries(ctx context.Context, channels []string) error {
+    if channels != nil && len(channels) == 0 {
+        return nil
+    }
+    enabled, err := c.summaryStore.SummarizedChannels(ctx, channels)
+    if err != nil {
+        return err
+    }
+
+    workspace := workspaceFrom(ctx)
+    for _, channelID := range enabled {
+        err := c.updateChannelSummary(ctx, channelID)
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        var rateLimited *slack.RateLimitedError
+        if errors.As(err, &rateLimited) {
+            c.summaries.add(workspace, channelID)
+            continue
+        }
+        if err != nil {
+            c.logger.Warnf("failed to update the summary of channel %s: %v", channelID, err)
+        }
+    }
+    return nil
+}
+
+// loadSummary returns the open threads of a channel to summarize, the most
+// urgent first.
+func (c *Container) loadSummary(ctx context.Context, channelID string) (*summaries.Summary, error) {
+    ch, err := c.channels.Channel(ctx, channelID)
+    if err != nil {
+        return nil, err
+    }
+    filters := threadFilters{channels: []string{channelID}, statuses: []string{string(statusOpen)}}
+
+    summary := &summaries.Summary{ChannelID: channelID, ChannelName: ch.ChannelName, DashboardURL: c.publicURL, Threads: []summaries.Thread{}}
+    var threads []Thread
+    list := threadList{filters: filters, sort: "priority", order: "desc", limit: summaries.MaxListed}
+    threads, summary.OpenThreads, err = c.threadLists.ListThreads(ctx, list)
+    if err != nil {
+        return nil, err
+    }
+    if summary.ByPriority, err = c.threadLists.CountByPriority(ctx, filters); err != nil {
+        return nil, err
+    }
+
+    usergroups, err := c.users.Usergroups(ctx)
+    if err != nil {
+        return nil, err
+    }
+    handles := map[string]string{}
+    for _, group := range usergroups {
+        handles[group.ID] = "@" + group.Handle
+    }
+
+    for _, thread := range threads {
+        listedThread := summaries.Thread{
+            ThreadTS:    thread.ThreadTS,
+            Priority:    thread.Priority,
+            LatestReply: thread.LatestReply,
+            Overdue:     thread.SLABreached,
+        }
+        if thread.AIThreadName != nil {
+            listedThread.Title = *thread.AIThreadName
+        }
+        if thread.Assignee != nil {
+            listedThread.Assignee = *thread.Assignee
+            listedThread.AssigneeName = handles[*thread.Assignee]
+        }
+        summary.Threads = append(summary.Threads, listedThread)
+    }
+    return summary, nil
+}
+
+// updateChannelSummary writes the summary of a channel to Slack unless it
+// did not change. A canvas or pinned message deleted in Slack is created
+// again.
+func (c *Container) updateChannelSummary(ctx context.Context, channelID string) error {
+    state, err := c.summaryStore.SummaryState(ctx, channelID)
+    if err != nil || state.mode == SummaryModeOff {
+        return err
+    }
+
+    summary, err := c.loadSummary(ctx, channelID)
+    if err != nil {
+        return err
+    }
+    contentHash := summary.Hash()
+    if state.contentHash.Valid && state.contentHash.String == contentHash {
+        return nil
+    }
+
+    switch state.mode {
+    case SummaryModeCanvas:
+        err = c.writeSummaryCanvas(ctx, channelID, &state.canvasID, summary)
+    case SummaryModePinned:
+        err = c.writeSummaryMessage(ctx, channelID, &state.messageTS, summary)
+    }
+    if err != nil {
+        var rateLimited *slack.RateLimitedError
+        if !errors.As(err, &rateLimited) {
+            c.summaryStore.SummaryFailed(ctx, channelID, err.Error())
+        }
+        return err
+    }
+    state.contentHash = sql.NullString{String: contentHash, Valid: true}
+    return c.summaryStore.SummaryWritten(ctx, channelID, state)
+}
+
+// writeSummaryCanvas replaces the content of the canvas of a channel,
+// creating it first when needed.
+func (c *Container) writeSummaryCanvas(ctx context.Context, channelID string, canvasID *sql.NullString, summary *summaries.Summary) error {
+    if !c.slackFeatureReady(slackFeatureCanvases) {
+        return errors.New("the Slack bot token lacks the canvases:write scope")
+    }
+    markdown := summaries.Markdown(summary)
+    if canvasID.Valid {
+        err := c.slack.ReplaceCanvas(ctx, canvasID.String, markdown)
+        var apiErr *slack.APIError
+        if !errors.As(err, &apiErr) || apiErr.Code != "canvas_not_found" {
+            return err
+        }
+    }
+    id, err := c.slack.CreateChannelCanvas(ctx, channelID, markdown)
+    var apiErr *slack.APIError
+    if errors.As(err, &apiErr) && apiErr.Code == "channel_canvas_already_exists" {
+        return errors.New("the channel already has a canvas not created by the dashboard, use a pinned message instead")
+    }
+    if err != nil {
+        return err
+    }
+    *canvasID = sql.NullString{String: id, Valid: true}
+    return nil
+}
+
+// writeSummaryMessage updates the pinned summary message of a channel,
+// posting and pinning it first when needed.
+func (c *Container) writeSummaryMessage(ctx context.Context, channelID string, messageTS *sql.NullString, summary *summaries.Summary) error {
+    if !c.slackFeatureReady(slackFeatureSummaryPins) {
+        return errors.New("the Slack bot token lacks the chat:write or pins:write scope")
+    }
+    text, blocks := summaries.Message(summary)
+    if messageTS.Valid {
+        err := c.slack.UpdateMessage(ctx, channelID, messageTS.String, text, blocks)
+        var apiErr *slack.APIError
+        if !errors.As(err, &apiErr) || apiErr.Code != "message_not_found" {
+            return err
+        }
+    }
+    ts, err := c.slack.PostMessage(ctx, channelID, text, blocks)
+    if err != nil {
+        return err
+    }
+    *messageTS = sql.NullString{String: ts, Valid: true}
+    err = c.slack.PinMessage(ctx, channelID, ts)
+    var apiErr *slack.APIError
+    if errors.As(err, &apiErr) && apiErr.Code == "already_pinned" {
+        return nil
+    }
+    return err
+}
+
+// GetChannelSummary - Get how the open threads of a channel are summarized in Slack and how the last update went
+func (c *Container) GetChannelSummary(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    reqCtx := ctx.Request().Context()
+    if err := c.channels.Tracked(reqCtx, channelID); err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    } else if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    summary, err := c.summaryStore.SlackSummary(reqCtx, channelID)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query channel summary")
+    }
+    return ctx.JSON(http.StatusOK, summary)
+}
+
+// SetChannelSummary - Keep a summary of the open threads of a channel in its Slack canvas or a pinned message, or stop
+func (c *Container) SetChannelSummary(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var req SlackSummaryRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    switch req.Mode {
+    case SummaryModeCanvas:
+        if !c.slackFeatureReady(slackFeatureCanvases) {
+            return apierror.New(http.StatusServiceUnavailable, "Canvas summaries need the canvases:write scope of the Slack bot token")
+        }
+    case SummaryModePinned:
+        if !c.slackFeatureReady(slackFeatureSummaryPins) {
+            return apierror.New(http.StatusServiceUnavailable, "Pinned summaries need the chat:write and pins:write scopes of the Slack bot token")
+        }
+    case SummaryModeOff:
+    default:
+        return apierror.New(http.StatusBadRequest, "mode must be canvas, pinned or off")
+    }
+
+    reqCtx := ctx.Request().Context()
+    if err := c.channels.Tracked(reqCtx, channelID); err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    } else if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    actor := actorFrom(ctx)
+    if err := c.summaryStore.SetSummaryMode(reqCtx, channelID, req.Mode, actor); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel summary")
+    }
+
+    // The audited action queues the first update of the summary
+    c.audit(reqCtx, actor, "channel.summary", channelID, "", map[string]interface{}{
+        "mode": req.Mode,
+    })
+
+    summary, err := c.summaryStore.SlackSummary(reqCtx, channelID)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query channel summary")
+    }
+    return ctx.JSON(http.StatusOK, summary)
+}
+
+func (s postgresStore) SlackSummary(ctx context.Context, channelID string) (SlackSummary, error) {
+    summary := SlackSummary{ChannelID: channelID, Mode: SummaryModeOff}
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return summary, err
+    }
+    var canvasID, messageTS, lastError sql.NullString
+    var syncedAt sql.NullTime
+    err = db.QueryRowContext(ctx, `
+        SELECT mode, canvas_id, message_ts, synced_at, last_error FROM channel_summaries WHERE channel_id = $1
+    `, channelID).Scan(&summary.Mode, &canvasID, &messageTS, &syncedAt, &lastError)
+    if err == sql.ErrNoRows {
+        return summary, nil
+    }
+    if err != nil {
+        return summary, err
+    }
+    if canvasID.Valid {
+        summary.CanvasID = &canvasID.String
+    }
+    if messageTS.Valid {
+        summary.MessageTS = &messageTS.String
+    }
+    if syncedAt.Valid {
+        summary.SyncedAt = &syncedAt.Time
+    }
+    if lastError.Valid {
+        summary.LastError = &lastError.String
+    }
+    return summary, nil
+}
+
+func (s postgresStore) SetSummaryMode(ctx context.Context, channelID, mode, actor string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO channel_summaries (channel_id, mode, updated_by, updated_at)
+        VALUES ($1, $2, $3, NOW())
+        ON CONFLICT (channel_id) DO UPDATE SET
+            mode = EXCLUDED.mode,
+            content_hash = CASE WHEN channel_summaries.mode = EXCLUDED.mode THEN channel_summaries.content_hash END,
+            last_error = NULL,
+            updated_by = EXCLUDED.updated_by,
+            updated_at = EXCLUDED.updated_at
+    `, channelID, mode, actor)
+    return err
+}
+
+func (s postgresStore) SummarizedChannels(ctx context.Context, channels []string) ([]string, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    query := "SELECT channel_id FROM channel_summaries WHERE mode <> $1"
+    args := []interface{}{SummaryModeOff}
+    if channels != nil {
+        query += " AND channel_id = ANY($2)"
+        args = append(args, pq.Array(channels))
+    }
+    rows, err := db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var enabled []string
+    for rows.Next() {
+        var channelID string
+        if err := rows.Scan(&channelID); err == nil {
+            enabled = append(enabled, channelID)
+        }
+    }
+    return enabled, rows.Err()
+}
+
+func (s postgresStore) SummaryState(ctx context.Context, channelID string) (summaryState, error) {
+    state := summaryState{mode: SummaryModeOff}
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return state, err
+    }
+    err = db.QueryRowContext(ctx, `
+        SELECT mode, canvas_id, message_ts, content_hash FROM channel_summaries WHERE channel_id = $1
+    `, channelID).Scan(&state.mode, &state.canvasID, &state.messageTS, &state.contentHash)
+    if err == sql.ErrNoRows {
+        return summaryState{mode: SummaryModeOff}, nil
+    }
+    return state, err
+}
+
+func (s postgresStore) SummaryWritten(ctx context.Context, channelID string, state summaryState) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        UPDATE channel_summaries
+        SET canvas_id = $2, message_ts = $3, content_hash = $4, synced_at = NOW(), last_error = NULL
+        WHERE channel_id = $1
+    `, channelID, state.canvasID, state.messageTS, state.contentHash)
+    return err
+}
+
+func (s postgresStore) SummaryFailed(ctx context.Context, channelID, message string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, "UPDATE channel_summaries SET last_error = $2 WHERE channel_id = $1", channelID, message)
+    return err
+}
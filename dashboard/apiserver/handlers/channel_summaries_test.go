@@ -0,0 +1,53 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestChannelSummaryWrittenWhenChanged(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, LatestReply: time.Now()}})
+    slack := &MemorySlack{}
+    c := newTestContainer(t, store, slack)
+    admin := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+
+    route := "/api/channels/:channel_id/summary"
+    if rec := serveAs(c, admin, http.MethodPut, route, "/api/channels/C9/summary", `{"mode": "pinned"}`, c.SetChannelSummary); rec.Code != http.StatusNotFound {
+        t.Fatalf("an untracked channel got status %d", rec.Code)
+    }
+    if rec := serveAs(c, admin, http.MethodPut, route, "/api/channels/C1/summary", `{"mode": "pinned"}`, c.SetChannelSummary); rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+
+    ctx := context.Background()
+    for range 2 {
+        if err := c.flushChannelSummaries(ctx, nil); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if posted := slack.Messages(); len(posted) != 1 || posted[0].Channel != "C1" {
+        t.Fatalf("got Slack messages %+v, want the summary written once", posted)
+    }
+
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: statusOpen, LatestReply: time.Now()}})
+    if err := c.flushChannelSummaries(ctx, []string{"C1"}); err != nil {
+        t.Fatal(err)
+    }
+    if posted := slack.Messages(); len(posted) != 2 {
+        t.Fatalf("got %d Slack messages, want the changed summary written again", len(posted))
+    }
+
+    rec := serveAs(c, admin, http.MethodGet, route, "/api/channels/C1/summary", "", c.GetChannelSummary)
+    var summary SlackSummary
+    decodeResponse(t, rec, &summary)
+    if summary.Mode != SummaryModePinned || summary.MessageTS == nil || summary.SyncedAt == nil || summary.LastError != nil {
+        t.Fatalf("got summary %+v", summary)
+    }
+}
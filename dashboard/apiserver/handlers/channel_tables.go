@@ -0,0 +1,70 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// errChannelNotTracked is returned for channels missing from the channels table.
+var errChannelNotTracked = errors.New("channel is not tracked")
+
+// channelTracked fails with errChannelNotTracked for channels missing from
+// the channels table. The threads of every channel are in the threads table.
+func channelTracked(db *sql.DB, channelID string) error {
+    var tracked string
+    err := db.QueryRow("SELECT channel_id FROM channels WHERE channel_id = $1", channelID).Scan(&tracked)
+    if err == sql.ErrNoRows {
+        return errChannelNotTracked
+    }
+    return err
+}
+
+// slackTSTime converts a Slack message ts ("1712345678.123456") to a time.
+func slackTSTime(ts string) (time.Time, error) {
+    secStr, fracStr, _ := strings.Cut(ts, ".")
+    sec, err := strconv.ParseInt(secStr, 10, 64)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("invalid Slack ts %q", ts)
+    }
+    var nsec int64
+    if fracStr != "" {
+        fracStr = (fracStr + "000000000")[:9]
+        if nsec, err = strconv.ParseInt(fracStr, 10, 64); err != nil {
+            return time.Time{}, fmt.Errorf("invalid Slack ts %q", ts)
+        }
+    }
+    return time.Unix(sec, nsec), nil
+}
+
+// trackedChannel is a channel of the channels table.
+type trackedChannel struct {
+    ID           string
+    Name         string
+    TextIndexing bool
+}
+
+// trackedChannels returns every tracked channel.
+func trackedChannels(ctx context.Context, db *sql.DB) ([]trackedChannel, error) {
+    rows, err := db.QueryContext(ctx, `
+        SELECT ch.channel_id, ch.channel_name, COALESCE(cc.text_indexing, TRUE)
+        FROM channels ch
+        LEFT JOIN channel_config cc ON cc.channel_id = ch.channel_id`)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    channels := []trackedChannel{}
+    for rows.Next() {
+        var ch trackedChannel
+        if err := rows.Scan(&ch.ID, &ch.Name, &ch.TextIndexing); err == nil {
+            channels = append(channels, ch)
+        }
+    }
+    return channels, rows.Err()
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Thread list columns and filters a channel view may use. Views sort by
+// the sorts of thread lists.
+var (
+    viewColumns = threadFieldColumns()
+    viewFilters = map[string]bool{
+        "priority": true,
+        "status":   true,
+    }
+)
+
+// ChannelView is the default sort, filters and visible columns of a
+// channel's thread list
+type ChannelView struct {
+    Sort    string            `json:"sort"`
+    Order   string            `json:"order"`
+    Filters map[string]string `json:"filters"`
+    Columns []string          `json:"columns"`
+}
+
+// defaultChannelView is used by channels without a configured view.
+func defaultChannelView() ChannelView {
+    return ChannelView{
+        Sort:    "latest_reply",
+        Order:   "desc",
+        Filters: map[string]string{},
+        Columns: []string{"thread", "author", "stakeholders", "priority", "status", "replies", "latest_reply"},
+    }
+}
+
+// validate checks a view and fills in omitted fields from the default.
+func (v *ChannelView) validate() string {
+    defaults := defaultChannelView()
+    if v.Sort == "" {
+        v.Sort = defaults.Sort
+    }
+    if _, ok := threadSortColumns[v.Sort]; !ok {
+        return "unknown sort " + v.Sort
+    }
+    if v.Order == "" {
+        v.Order = defaults.Order
+    }
+    if v.Order != "asc" && v.Order != "desc" {
+        return "order must be asc or desc"
+    }
+    if v.Filters == nil {
+        v.Filters = map[string]string{}
+    }
+    for name := range v.Filters {
+        if !viewFilters[name] {
+            return "unknown filter " + name
+        }
+    }
+    if len(v.Columns) == 0 {
+        v.Columns = defaults.Columns
+    }
+    for _, column := range v.Columns {
+        if !viewColumns[column] {
+            return "unknown column " + column
+        }
+    }
+    return ""
+}
+
+// GetChannel - Get a channel with its default thread list view
+func (c *Container) GetChannel(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    ch, err := c.channels.Channel(ctx.Request().Context(), channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query channel")
+    }
+    config, err := c.configs.ChannelConfig(ctx.Request().Context(), channelID)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query channel")
+    }
+
+    var firstResponder *FirstResponder
+    if config.ResponderRotation != nil {
+        current := config.ResponderRotation.Current(time.Now().UTC())
+        firstResponder = &current
+    }
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "channel_id":          channelID,
+        "channel_name":        ch.ChannelName,
+        "thread_count":        ch.ThreadCount,
+        "active_thread_count": ch.ActiveThreadCount,
+        "last_activity":       ch.LastActivity,
+        "created_at":          ch.CreatedAt,
+        "text_indexing":       config.TextIndexing,
+        "view":                config.View,
+        "heat_thresholds":     config.HeatThresholds,
+        "resolve_approval":    config.ResolveApproval,
+        "responder_rotation":  config.ResponderRotation,
+        "first_responder":     firstResponder,
+        "reminder_schedule":   config.ReminderSchedule,
+        "quality_prompts":     config.QualityPrompts,
+        "slack_connect":       config.SlackConnect,
+        "github_routing":      config.GitHubRouting,
+        "jira_mapping":        config.JiraMapping,
+        "note_sync":           config.NoteSync,
+        "sla_hours":           config.SLAHours,
+        "default_assignees":   config.DefaultAssignees,
+        "muted":               config.Muted,
+    })
+}
+
+// SetChannelView - Set the default sort, filters and columns of a channel's thread list
+func (c *Container) SetChannelView(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var view ChannelView
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&view); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if msg := view.validate(); msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    err := c.configs.SetChannelConfig(ctx.Request().Context(), channelID, ChannelConfig{View: view}, settingView)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.view", channelID, "", map[string]interface{}{
+        "view": view,
+    })
+
+    return ctx.JSON(http.StatusOK, view)
+}
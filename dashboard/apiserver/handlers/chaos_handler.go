@@ -0,0 +1,89 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/chaos"
+
+    "github.com/labstack/echo/v4"
+)
+
+// faultInjection is set before containers are created to let admins inject
+// faults.
+var faultInjection bool
+
+// EnableFaultInjection makes the containers created afterwards able to
+// inject faults, and their chaos endpoints available.
+func EnableFaultInjection() {
+    faultInjection = true
+}
+
+// FaultInjectionEnabled reports whether the chaos endpoints may be mounted.
+func (c *Container) FaultInjectionEnabled() bool {
+    return c.chaos != nil
+}
+
+// ChaosRequest is the body accepted by StartChaos
+type ChaosRequest struct {
+    Duration            string  `json:"duration"`
+    DBLatency           string  `json:"db_latency"`
+    SlackFailurePercent float64 `json:"slack_failure_percent"`
+    EventDropPercent    float64 `json:"event_drop_percent"`
+}
+
+// GetChaos - Get the faults being injected, or last injected, and how many were
+func (c *Container) GetChaos(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, c.chaos.Status())
+}
+
+// StartChaos - Inject database latency, Slack call failures and dropped Slack events for a time window
+func (c *Container) StartChaos(ctx echo.Context) error {
+    var req ChaosRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    duration, err := time.ParseDuration(req.Duration)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "duration must be a duration such as 10m")
+    }
+    var faults chaos.Faults
+    if req.DBLatency != "" {
+        faults.DBLatency, err = time.ParseDuration(req.DBLatency)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, "db_latency must be a duration such as 500ms")
+        }
+    }
+    faults.SlackFailurePercent = req.SlackFailurePercent
+    faults.EventDropPercent = req.EventDropPercent
+
+    actor := actorFrom(ctx)
+    status, err := c.chaos.Start(actor, faults, duration)
+    if err == chaos.ErrInvalidFaults {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+    c.logger.Warnf("fault injection started by %s for %s: %s database latency, %g%% failed Slack calls, %g%% dropped Slack events",
+        actor, duration, faults.DBLatency, faults.SlackFailurePercent, faults.EventDropPercent)
+    c.audit(sharedContext(ctx.Request().Context()), actor, "chaos.start", "", "", map[string]interface{}{
+        "duration":              duration.String(),
+        "db_latency":            faults.DBLatency.String(),
+        "slack_failure_percent": faults.SlackFailurePercent,
+        "event_drop_percent":    faults.EventDropPercent,
+    })
+    return ctx.JSON(http.StatusOK, status)
+}
+
+// StopChaos - Stop injecting faults
+func (c *Container) StopChaos(ctx echo.Context) error {
+    status := c.chaos.Stop()
+    actor := actorFrom(ctx)
+    c.logger.Infof("fault injection stopped by %s", actor)
+    c.audit(sharedContext(ctx.Request().Context()), actor, "chaos.stop", "", "", map[string]interface{}{
+        "delayed_statements":   status.DelayedStatements,
+        "failed_slack_calls":   status.FailedSlackCalls,
+        "dropped_slack_events": status.DroppedSlackEvents,
+    })
+    return ctx.JSON(http.StatusOK, status)
+}
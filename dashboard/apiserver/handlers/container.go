@@ -1,16 +1,492 @@
 package handlers
 
 import (
+    "context"
+    "database/sql"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "dashboard/apiserver/analysis"
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/chaos"
+    "dashboard/apiserver/config"
+    "dashboard/apiserver/debugbundle"
+    "dashboard/apiserver/digest"
+    "dashboard/apiserver/events"
+    "dashboard/apiserver/exports"
+    "dashboard/apiserver/github"
+    "dashboard/apiserver/inbound"
+    "dashboard/apiserver/ingest"
+    "dashboard/apiserver/jira"
     "dashboard/apiserver/logger"
+    "dashboard/apiserver/notify"
+    "dashboard/apiserver/slack"
+    "dashboard/apiserver/webhooks"
+
+    "golang.org/x/time/rate"
 )
 
 // Container will hold all dependencies for your application.
 type Container struct {
-    logger logger.Logger
+    logger  logger.Logger
+    inbound *inbound.Guard
+
+    // db is the connection pool shared by all requests and jobs, dbHealthy
+    // is the outcome of the latest check that the database is reachable
+    database  config.Database
+    db        *sql.DB
+    dbHealthy atomic.Bool
+    // workspaces are the databases of workspaces whose threads are stored
+    // apart, keyed by Slack team ID
+    workspaces map[string]*workspaceDatabase
+
+    // The stores handlers read and write, in the database unless replaced
+    // by an Option
+    threads       ThreadStore
+    notes         NoteStore
+    efforts       EffortStore
+    watchers      WatcherStore
+    threadLists   ThreadListStore
+    threadStatus  ThreadStatusStore
+    overrides     ThreadOverrideStore
+    analyses      AnalysisStore
+    bulk          BulkThreadStore
+    resolutions   ResolutionStore
+    assignments   AssignmentStore
+    acks          AckStore
+    reporterWaits ReporterWaitStore
+    releases      ReleaseStore
+    answerSignals AnswerSignalStore
+    stats         StatsStore
+    channels      ChannelStore
+    configs       ChannelConfigStore
+    summaryStore  ChannelSummaryStore
+    calibration   CalibrationStore
+    groups        ChannelGroupStore
+    goals         GoalStore
+    templates     ReplyTemplateStore
+    users         UserStore
+    directory     DirectoryStore
+    messages      MessageStore
+    backfills     BackfillStore
+    reminderStore ReminderStore
+    qualityStore  QualityPromptStore
+    suggestions   SuggestionStore
+    liveThreads   LiveThreadStore
+    githubIssues  GitHubIssueStore
+    jiraTickets   JiraTicketStore
+    slackConnect  SlackConnectStore
+    holds         LegalHoldStore
+    exportRecords ExportJobStore
+    webhookStore  WebhookStore
+    storage       StorageStore
+    adoption      AdoptionStore
+    digests       DigestStore
+    auditLog      AuditStore
+    tokens        TokenStore
+    apiKeys       APIKeyStore
+    sessions      SessionStore
+    emailPrefs    EmailPreferenceStore
+    preferences   PreferenceStore
+
+    // slackEvents deduplicates Slack event deliveries across replicas
+    slackEvents   *eventDedupStore
+    slackPipeline *ingest.Pipeline
+    ingestQueue   *ingest.Queue
+
+    slack      SlackClient
+    backfiller *ingest.Backfiller
+    // poller polls the history of tracked channels, nil unless polling is
+    // enabled
+    poller *ingest.Poller
+    github *github.Client
+    // jiraURL is the address of the Jira site tickets are linked to, empty
+    // when unknown
+    jiraURL string
+    jira    *jira.Client
+
+    // grid is the Enterprise Grid of the bot token, found by
+    // RunGridDiscovery
+    grid enterpriseGrid
+    // slackScopes are the scopes of the bot token features are disabled
+    // without, found by RunSlackScopeAudit. quickstart is set once the
+    // channels of the bot are tracked without the collector
+    slackScopes slackScopeAudit
+    quickstart  atomic.Bool
+
+    // bus carries what happens between ingestion, analysis, reminders,
+    // webhooks and live updates
+    bus events.Bus
+    // webhooks posts audited actions to the registered outgoing webhooks
+    webhooks *webhooks.Dispatcher
+    // notifier delivers reminders and alerts through the sinks of their
+    // notification rule
+    notifier *notify.Router
+    // mailer sends notification and digest emails
+    mailer notify.Email
+
+    // The digest is sent to digestChannels and digestEmails on
+    // digestSchedule, read in digestLocation
+    digestSchedule *digest.Schedule
+    digestLocation *time.Location
+    digestChannels []string
+    digestEmails   []string
+
+    // suggestAfter is how long a thread stays unanswered before its likely
+    // stakeholders get a suggestion, zero disables suggestions
+    suggestAfter time.Duration
+    // remindAfter is how long a thread may stay idle before its owners are
+    // reminded, zero disables direct message reminders
+    remindAfter    time.Duration
+    reminderWindow time.Duration
+    // reporterNudgeAfter is how long a thread waits on its author before
+    // they are nudged
+    reporterNudgeAfter time.Duration
+
+    // Exports larger than exportThreshold rows are generated by workers
+    // into exportStore and kept for exportTTL
+    exportStore     exports.Store
+    exportSigner    *exports.Signer
+    exportJobs      chan exportRef
+    exportThreshold int
+    exportTTL       time.Duration
+
+    // publicURL is the address of the dashboard used in links sent outside
+    // of it, emailSigner signs the preference links of notification emails
+    publicURL   string
+    emailSigner *exports.Signer
+
+    // watchEmoji is the reaction name marking a thread as watched
+    watchEmoji string
+
+    // oidc signs users in to sessions lasting sessionTTL, nil when sign-in
+    // is not configured. signInTeam and signInDomains restrict who may
+    // sign in, at least one of them is set with sign-in
+    oidc          *auth.OIDCProvider
+    sessionTTL    time.Duration
+    signInTeam    string
+    signInDomains []string
+    // defaultRole is the role of users without an assigned one
+    defaultRole auth.Role
+
+    // live pushes thread changes, found every liveInterval, to WebSocket
+    // clients
+    live         *liveHub
+    liveInterval time.Duration
+    // summaries queues the channels whose Slack summary needs an update
+    summaries *summaryQueue
+
+    // recorder captures exchanges and slow queries for debug bundles
+    recorder *debugbundle.Recorder
+    // chaos injects faults into the database, Slack calls and events, nil
+    // unless fault injection is enabled
+    chaos *chaos.Injector
+    // usage counts API calls for the adoption report
+    usage *usageCounter
+
+    // analyzer classifies the threads queued on analysisJobs, nil when no
+    // AI provider is configured. analysisLimiter paces its requests
+    analyzer        analysis.Provider
+    analysisJobs    chan analysisJob
+    analysisPending sync.Map
+    analysisLimiter *rate.Limiter
+    analysisWorkers int
+
+    // storageQuota and storageMaxGrowth are the soft limits in bytes, and
+    // bytes a day, the storage monitor warns about, 0 when unlimited
+    storageQuota        int64
+    storageMaxGrowth    int64
+    storageAlertChannel string
+
+    // ui holds the palette, theme tokens and feature flags the SPA loads
+    ui uiSettings
+
+    // readOnly refuses requests changing anything, see EnforceReadOnly
+    readOnly atomic.Bool
+    // rateLimits paces the API requests of every client, see RateLimit
+    rateLimits *rateLimiter
+    // staleCache answers reads while the database is unreachable, nil when
+    // disabled, see ServeStale
+    staleCache *staleCache
+    // statsCache answers the stats and channel list of each workspace until
+    // something changes in it, nil when disabled
+    statsCache *statsCache
 }
 
 // NewContainer returns an empty or an initialized container for your handlers.
-func NewContainer(logger logger.Logger) (Container, error) {
-        c := Container{logger}
+// Options replace the stores and Slack client handlers depend on.
+func NewContainer(logger logger.Logger, options ...Option) (*Container, error) {
+        guard := inbound.NewGuard(logger,
+            inbound.SlackSource(getEnv(slackSigningSecretEnv, "")),
+            inbound.SlackInteractionsSource(getEnv(slackSigningSecretEnv, "")),
+            inbound.GitHubSource(getEnv(githubWebhookSecretEnv, "")),
+            inbound.JiraSource(getEnv(jiraWebhookSecretEnv, "")),
+            inbound.EmailSource(getEnv(emailWebhookSecretEnv, "")),
+        )
+        cfg, err := config.Load()
+        if err != nil {
+            return nil, err
+        }
+        c := &Container{logger: logger, inbound: guard, recorder: debugbundle.NewRecorder(), database: cfg.Database}
+        store := postgresStore{c}
+        c.threads, c.notes, c.threadLists, c.threadStatus, c.stats = store, store, store, store, store
+        c.acks, c.reporterWaits, c.answerSignals, c.efforts, c.watchers = store, store, store, store, store
+        c.channels, c.users, c.messages, c.slackConnect, c.resolutions = store, store, store, store, store
+        c.holds, c.exportRecords, c.webhookStore, c.auditLog, c.preferences = store, store, store, store, store
+        c.tokens, c.sessions, c.emailPrefs, c.configs, c.groups = store, store, store, store, store
+        c.goals, c.templates, c.apiKeys, c.directory, c.assignments = store, store, store, store, store
+        c.jiraTickets, c.overrides, c.bulk, c.releases = store, store, store, store
+        c.summaryStore, c.storage, c.adoption, c.calibration, c.digests = store, store, store, store, store
+        c.analyses, c.githubIssues, c.backfills, c.reminderStore, c.qualityStore = store, store, store, store, store
+        c.suggestions, c.liveThreads = store, store
+        if faultInjection {
+            c.chaos = chaos.NewInjector()
+        }
+        c.slackEvents = newEventDedupStore(c, ingest.DefaultDedupTTL)
+        c.slackPipeline = ingest.NewPipeline(logger, c.slackEvents, c.channelTextIndexing, c.applySlackMessage)
+
+        queueSize, _ := strconv.Atoi(getEnv(ingestQueueSizeEnv, "1000"))
+        workers, _ := strconv.Atoi(getEnv(ingestWorkersEnv, "4"))
+        maxSpilled, _ := strconv.Atoi(getEnv(ingestMaxSpilledEnv, "100000"))
+        queue, err := ingest.NewQueue(logger, ingest.QueueConfig{
+            Capacity: queueSize,
+            Workers:  workers,
+            SpillDir: getEnv(ingestSpillDirEnv, filepath.Join(os.TempDir(), "open-threads-reminder")),
+
+            MaxSpilled: maxSpilled,
+        }, c.processSlackDelivery)
+        if err != nil {
+            return nil, err
+        }
+        c.ingestQueue = queue
+
+        rateShare, _ := strconv.ParseFloat(getEnv(slackRateShareEnv, "0.5"), 64)
+        slackClient := slack.NewClient(getEnv(slackBotTokenEnv, ""), slack.NewBudget(rateShare))
+        if c.chaos != nil {
+            slackClient.WrapTransport(c.chaos.Transport)
+        }
+        c.slack = slackClient
+        if pollInterval := getEnv(pollIntervalEnv, ""); pollInterval != "" {
+            var config ingest.PollerConfig
+            config.MinInterval, err = time.ParseDuration(pollInterval)
+            if err != nil {
+                return nil, err
+            }
+            config.MaxInterval, err = time.ParseDuration(getEnv(pollMaxIntervalEnv, "30m"))
+            if err != nil {
+                return nil, err
+            }
+            c.poller = ingest.NewPoller(logger, slackClient, c.writeHistoryMessages, config)
+        }
+        c.github = github.NewClient(getEnv(githubTokenEnv, ""), getEnv(githubAPIURLEnv, ""))
+        c.jiraURL = strings.TrimSuffix(getEnv(jiraURLEnv, ""), "/")
+        c.jira = jira.NewClient(c.jiraURL, getEnv(jiraEmailEnv, ""), getEnv(jiraTokenEnv, ""))
+        c.bus, err = newEventBus(logger)
+        if err != nil {
+            return nil, err
+        }
+        c.webhooks = webhooks.NewDispatcher(logger, 1000)
+
+        if suggestAfter := getEnv(suggestAfterEnv, ""); suggestAfter != "" {
+            c.suggestAfter, err = time.ParseDuration(suggestAfter)
+            if err != nil {
+                return nil, err
+            }
+        }
+        if remindAfter := getEnv(remindAfterEnv, ""); remindAfter != "" {
+            c.remindAfter, err = time.ParseDuration(remindAfter)
+            if err != nil {
+                return nil, err
+            }
+        }
+        c.reporterNudgeAfter, err = time.ParseDuration(getEnv(reporterNudgeAfterEnv, "48h"))
+        if err != nil {
+            return nil, err
+        }
+        c.reminderWindow, err = time.ParseDuration(getEnv(reminderBatchWindowEnv, "10m"))
+        if err != nil {
+            return nil, err
+        }
+
+        // Exports go to a bucket when one is configured, else to a directory
+        var exportStore exports.Store
+        if endpoint := getEnv(exportS3EndpointEnv, ""); endpoint != "" {
+            exportStore, err = exports.NewS3Store(exports.S3Config{
+                Endpoint:        endpoint,
+                Bucket:          getEnv(exportS3BucketEnv, ""),
+                Prefix:          getEnv(exportS3PrefixEnv, ""),
+                Region:          getEnv(exportS3RegionEnv, ""),
+                AccessKeyID:     getEnv(exportS3KeyIDEnv, ""),
+                SecretAccessKey: getEnv(exportS3SecretEnv, ""),
+            })
+        } else {
+            exportStore, err = exports.NewLocalStore(getEnv(exportDirEnv, filepath.Join(os.TempDir(), "open-threads-reminder", "exports")))
+        }
+        if err != nil {
+            return nil, err
+        }
+        c.exportStore = exportStore
+        c.exportSigner = exports.NewSigner(getEnv(exportSigningKeyEnv, ""))
+        c.exportJobs = make(chan exportRef)
+        c.exportThreshold, _ = strconv.Atoi(getEnv(exportThresholdEnv, "5000"))
+        c.exportTTL, err = time.ParseDuration(getEnv(exportTTLEnv, "24h"))
+        if err != nil {
+            return nil, err
+        }
+        c.publicURL = strings.TrimSuffix(getEnv(publicURLEnv, ""), "/")
+        c.emailSigner = exports.NewSigner(getEnv(emailSigningKeyEnv, ""))
+        c.watchEmoji = strings.Trim(getEnv(watchEmojiEnv, "eyes"), ":")
+        if clientID := getEnv(oidcClientIDEnv, ""); clientID != "" {
+            c.oidc = auth.NewOIDCProvider(auth.OIDCConfig{
+                Issuer:       getEnv(oidcIssuerEnv, auth.SlackIssuer),
+                ClientID:     clientID,
+                ClientSecret: getEnv(oidcClientSecretEnv, ""),
+                UserClaim:    getEnv(oidcUserClaimEnv, auth.SlackUserClaim),
+            })
+        }
+        c.sessionTTL, err = time.ParseDuration(getEnv(sessionTTLEnv, "168h"))
+        if err != nil {
+            return nil, err
+        }
+        c.defaultRole, err = auth.ParseRole(getEnv(defaultRoleEnv, string(auth.RoleViewer)))
+        if err != nil {
+            return nil, err
+        }
+        c.signInTeam = getEnv(signInTeamEnv, "")
+        for _, domain := range strings.Split(getEnv(signInDomainsEnv, ""), ",") {
+            if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+                c.signInDomains = append(c.signInDomains, domain)
+            }
+        }
+        // Without a restriction any Slack or provider account could sign in
+        if c.oidc != nil && c.signInTeam == "" && len(c.signInDomains) == 0 {
+            return nil, fmt.Errorf("%s or %s must be set when sign-in is configured", signInTeamEnv, signInDomainsEnv)
+        }
+        c.live = newLiveHub()
+        c.summaries = newSummaryQueue()
+        c.usage = newUsageCounter()
+        if provider := getEnv(aiProviderEnv, ""); provider != "" {
+            c.analyzer, err = analysis.NewProvider(analysis.Config{
+                Provider: provider,
+                APIKey:   getEnv(aiAPIKeyEnv, ""),
+                Model:    getEnv(aiModelEnv, ""),
+                BaseURL:  getEnv(aiBaseURLEnv, ""),
+            })
+            if err != nil {
+                return nil, err
+            }
+        }
+        c.analysisJobs = make(chan analysisJob, analysisQueueSize)
+        c.analysisWorkers, _ = strconv.Atoi(getEnv(aiWorkersEnv, "2"))
+        perMinute, _ := strconv.ParseFloat(getEnv(aiRateEnv, "30"), 64)
+        if perMinute <= 0 {
+            perMinute = 30
+        }
+        c.analysisLimiter = rate.NewLimiter(rate.Limit(perMinute/60), 1)
+        quotaMB, _ := strconv.ParseInt(getEnv(storageQuotaEnv, "10240"), 10, 64)
+        c.storageQuota = quotaMB << 20
+        growthMB, _ := strconv.ParseInt(getEnv(storageMaxGrowthEnv, "512"), 10, 64)
+        c.storageMaxGrowth = growthMB << 20
+        c.storageAlertChannel = getEnv(storageAlertChannelEnv, "")
+        c.ui, err = loadUISettings()
+        if err != nil {
+            return nil, err
+        }
+        rules, err := notify.ParseRules(getEnv(notificationRulesEnv, ""))
+        if err != nil {
+            return nil, err
+        }
+        c.mailer = notify.Email{
+            Addr:     getEnv(smtpAddrEnv, ""),
+            Username: getEnv(smtpUsernameEnv, ""),
+            Password: getEnv(smtpPasswordEnv, ""),
+            From:     getEnv(emailFromEnv, ""),
+            Address:  c.userEmail,
+        }
+        c.notifier, err = notify.NewRouter(logger, rules,
+            notify.SlackDM{Client: slackClient},
+            notify.SlackChannel{Client: slackClient, Channel: getEnv(notificationChannelEnv, "")},
+            c.mailer,
+            notify.Webhook{URL: getEnv(notificationURLEnv, "")},
+            notify.Teams{URL: getEnv(teamsWebhookURLEnv, "")},
+        )
+        if err != nil {
+            return nil, err
+        }
+        c.liveInterval, err = time.ParseDuration(getEnv(liveIntervalEnv, "5s"))
+        if err != nil {
+            return nil, err
+        }
+        c.digestSchedule, err = digest.ParseSchedule(getEnv(digestScheduleEnv, "0 9 * * *"))
+        if err != nil {
+            return nil, err
+        }
+        c.digestLocation, err = time.LoadLocation(getEnv(digestTimeZoneEnv, "UTC"))
+        if err != nil {
+            return nil, err
+        }
+        c.digestChannels = listParam(getEnv(digestChannelsEnv, ""))
+        c.digestEmails = listParam(getEnv(digestEmailsEnv, ""))
+        readOnly, _ := strconv.ParseBool(getEnv(readOnlyEnv, "false"))
+        c.readOnly.Store(readOnly)
+        reads, _ := strconv.Atoi(getEnv(rateLimitReadsEnv, "600"))
+        writes, _ := strconv.Atoi(getEnv(rateLimitWritesEnv, "60"))
+        c.rateLimits = newRateLimiter(reads, writes)
+        if staleMB, _ := strconv.Atoi(getEnv(staleCacheEnv, "64")); staleMB > 0 {
+            c.staleCache = newStaleCache(staleMB << 20)
+        }
+        statsCacheTTL, err := time.ParseDuration(getEnv(statsCacheTTLEnv, "30s"))
+        if err != nil {
+            return nil, err
+        }
+        if statsCacheTTL > 0 {
+            c.statsCache = newStatsCache(statsCacheTTL)
+        }
+        for _, option := range options {
+            option(c)
+        }
+        // Backfills keep their progress in the store options may have
+        // replaced
+        concurrency, _ := strconv.Atoi(getEnv(backfillConcurrencyEnv, "2"))
+        c.backfiller = ingest.NewBackfiller(logger, slackClient, c.backfills, c.writeHistoryMessages, concurrency)
+        // The database is opened once options may have replaced its settings
+        c.db, err = openDatabase(c.database, c.recorder, c.chaos)
+        if err != nil {
+            return nil, err
+        }
+        if err := c.checkDatabase(context.Background()); err != nil {
+            logger.Warnf("database is not reachable yet: %v", err)
+        }
+        if err := c.openWorkspaceDatabases(cfg.Workspaces); err != nil {
+            return nil, err
+        }
+        if err := c.subscribeEvents(); err != nil {
+            return nil, err
+        }
         return c, nil
 }
+
+// InboundGuard returns the guard that inbound webhook routes must be mounted
+// behind.
+func (c *Container) InboundGuard() *inbound.Guard {
+    return c.inbound
+}
+
+// DebugRecorder returns the recorder whose middleware API routes must be
+// mounted behind to appear in debug bundles.
+func (c *Container) DebugRecorder() *debugbundle.Recorder {
+    return c.recorder
+}
+
+// PruneSlackEventDedup forgets Slack event keys older than the dedup TTL.
+func (c *Container) PruneSlackEventDedup() {
+    if err := c.slackEvents.Prune(); err != nil {
+        c.logger.Warnf("failed to prune Slack event dedup keys: %v", err)
+    }
+}
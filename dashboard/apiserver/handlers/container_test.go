@@ -0,0 +1,98 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http/httptest"
+    "testing"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/events"
+    "dashboard/apiserver/logger"
+
+    "github.com/labstack/echo/v4"
+)
+
+// newTestContainer returns a container reading from store and posting to
+// slack, with events on a bus in memory and without a database.
+func newTestContainer(t *testing.T, store *MemoryStore, slack *MemorySlack) *Container {
+    t.Helper()
+    log, err := logger.NewLogger(logger.Error)
+    if err != nil {
+        t.Fatal(err)
+    }
+    bus := events.NewMemoryBus(log)
+    t.Cleanup(func() { bus.Close() })
+    return &Container{
+        logger:        log,
+        threads:       store,
+        notes:         store,
+        efforts:       store,
+        watchers:      store,
+        threadLists:   store,
+        threadStatus:  store,
+        overrides:     store,
+        analyses:      store,
+        bulk:          store,
+        resolutions:   store,
+        assignments:   store,
+        acks:          store,
+        reporterWaits: store,
+        releases:      store,
+        answerSignals: store,
+        stats:         store,
+        channels:      store,
+        configs:       store,
+        summaryStore:  store,
+        calibration:   store,
+        groups:        store,
+        goals:         store,
+        templates:     store,
+        users:         store,
+        directory:     store,
+        messages:      store,
+        githubIssues:  store,
+        backfills:     store,
+        reminderStore: store,
+        qualityStore:  store,
+        suggestions:   store,
+        liveThreads:   store,
+        jiraTickets:   store,
+        slackConnect:  store,
+        holds:         store,
+        exportRecords: store,
+        webhookStore:  store,
+        storage:       store,
+        adoption:      store,
+        digests:       store,
+        auditLog:      store,
+        tokens:        store,
+        apiKeys:       store,
+        sessions:      store,
+        emailPrefs:    store,
+        preferences:   store,
+        slack:         slack,
+        bus:           bus,
+        defaultRole:   auth.RoleAdmin,
+    }
+}
+
+// serveRequest sends a request for target to handler mounted on route,
+// answering errors with the error envelope like the server does.
+func serveRequest(t *testing.T, c *Container, method string, route string, target string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+    t.Helper()
+    e := echo.New()
+    e.HTTPErrorHandler = apierror.Handler(c.logger)
+    e.Add(method, route, handler)
+    rec := httptest.NewRecorder()
+    e.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+    return rec
+}
+
+// decodeResponse decodes the JSON body of a response into v.
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+    t.Helper()
+    if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+        t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+    }
+}
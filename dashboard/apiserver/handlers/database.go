@@ -0,0 +1,133 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "net/http"
+    "sync/atomic"
+    "time"
+
+    "dashboard/apiserver/chaos"
+    "dashboard/apiserver/config"
+    "dashboard/apiserver/debugbundle"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+const dbHealthCheckInterval = 30 * time.Second
+
+// openDatabase creates the connection pool described by cfg. Connections are
+// only opened when first needed. Statements are delayed by injector, which
+// is nil unless fault injection is enabled.
+func openDatabase(cfg config.Database, recorder *debugbundle.Recorder, injector *chaos.Injector) (*sql.DB, error) {
+    connector, err := pq.NewConnector(cfg.ConnString())
+    if err != nil {
+        return nil, err
+    }
+    db := sql.OpenDB(injector.Connector(recorder.Connector(connector)))
+    db.SetMaxOpenConns(cfg.MaxOpenConns)
+    db.SetMaxIdleConns(cfg.MaxIdleConns)
+    db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+    return db, nil
+}
+
+// checkDatabase pings the shared database and records whether it is
+// reachable.
+func (c *Container) checkDatabase(ctx context.Context) error {
+    return c.pingDatabase(ctx, "database", c.db, c.database, &c.dbHealthy)
+}
+
+// pingDatabase pings db and records in healthy whether it is reachable,
+// logging when that changes.
+func (c *Container) pingDatabase(ctx context.Context, name string, db *sql.DB, cfg config.Database, healthy *atomic.Bool) error {
+    ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout+time.Second)
+    defer cancel()
+    err := db.PingContext(ctx)
+
+    reachable := err == nil
+    if was := healthy.Swap(reachable); was != reachable {
+        if reachable {
+            c.logger.Infof("%s is reachable", name)
+        } else {
+            c.logger.Warnf("%s became unreachable: %v", name, err)
+        }
+    }
+    return err
+}
+
+// getDBConnection returns the shared connection pool. While the latest
+// health check failed the database is pinged again first, so callers fail
+// fast and notice as soon as it is back. Callers must not close it.
+func (c *Container) getDBConnection() (*sql.DB, error) {
+    if !c.dbHealthy.Load() {
+        if err := c.checkDatabase(context.Background()); err != nil {
+            return nil, err
+        }
+    }
+    return c.db, nil
+}
+
+// RunDBHealthCheck periodically checks that the shared and workspace
+// databases are reachable until ctx is cancelled.
+func (c *Container) RunDBHealthCheck(ctx context.Context) {
+    ticker := time.NewTicker(dbHealthCheckInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            c.checkDatabase(ctx)
+            for team, workspace := range c.workspaces {
+                c.pingDatabase(ctx, "database of workspace "+team, workspace.db, workspace.config, &workspace.healthy)
+            }
+        }
+    }
+}
+
+// Close closes the shared and workspace connection pools, once the requests
+// and background jobs using them are done and the events published were
+// handled.
+func (c *Container) Close() {
+    if err := c.bus.Close(); err != nil {
+        c.logger.Warnf("failed to close event bus: %v", err)
+    }
+    for team, workspace := range c.workspaces {
+        if err := workspace.db.Close(); err != nil {
+            c.logger.Warnf("failed to close database of workspace %s: %v", team, err)
+        }
+    }
+    if err := c.db.Close(); err != nil {
+        c.logger.Warnf("failed to close database: %v", err)
+    }
+}
+
+// GetDatabaseStats - Get the health and usage of the database connection pools
+func (c *Container) GetDatabaseStats(ctx echo.Context) error {
+    workspaces := make(map[string]interface{}, len(c.workspaces))
+    for team, workspace := range c.workspaces {
+        stats := workspace.db.Stats()
+        workspaces[team] = map[string]interface{}{
+            "healthy":          workspace.healthy.Load(),
+            "open_connections": stats.OpenConnections,
+            "in_use":           stats.InUse,
+            "idle":             stats.Idle,
+            "wait_count":       stats.WaitCount,
+        }
+    }
+
+    stats := c.db.Stats()
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "workspaces":           workspaces,
+        "healthy":              c.dbHealthy.Load(),
+        "max_open_connections": stats.MaxOpenConnections,
+        "open_connections":     stats.OpenConnections,
+        "in_use":               stats.InUse,
+        "idle":                 stats.Idle,
+        "wait_count":           stats.WaitCount,
+        "wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+        "max_idle_closed":      stats.MaxIdleClosed,
+        "max_lifetime_closed":  stats.MaxLifetimeClosed,
+    })
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "net"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/config"
+    "dashboard/apiserver/debugbundle"
+    "dashboard/apiserver/logger"
+)
+
+// unreachableDatabase returns the settings of a database nothing listens
+// on.
+func unreachableDatabase(t *testing.T) config.Database {
+    t.Helper()
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    port := listener.Addr().(*net.TCPAddr).Port
+    listener.Close()
+
+    cfg := config.Default().Database
+    cfg.Port = port
+    cfg.ConnectTimeout = time.Second
+    cfg.MaxOpenConns = 7
+    cfg.MaxIdleConns = 3
+    return cfg
+}
+
+func TestOpenDatabase(t *testing.T) {
+    cfg := unreachableDatabase(t)
+    db, err := openDatabase(cfg, debugbundle.NewRecorder(), nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+
+    // The pool opens no connection until one is needed
+    if stats := db.Stats(); stats.MaxOpenConnections != cfg.MaxOpenConns || stats.OpenConnections != 0 {
+        t.Fatalf("got %d open of at most %d connections, want none of at most %d",
+            stats.OpenConnections, stats.MaxOpenConnections, cfg.MaxOpenConns)
+    }
+}
+
+func TestGetDBConnectionFailsFast(t *testing.T) {
+    cfg := unreachableDatabase(t)
+    db, err := openDatabase(cfg, debugbundle.NewRecorder(), nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+    c := newTestContainer(t, NewMemoryStore(), &MemorySlack{})
+    c.db, c.database = db, cfg
+
+    if _, err := c.getDBConnection(); err == nil {
+        t.Fatalf("got the pool of a database nothing listens on at port %d", cfg.Port)
+    }
+    if c.dbHealthy.Load() {
+        t.Fatal("the unreachable database is recorded as healthy")
+    }
+    // Callers keep getting an error rather than a pool failing later
+    if _, err := c.getDBConnection(); err == nil {
+        t.Fatal("got the pool on the second call")
+    }
+}
+
+func TestNewContainerWithDatabaseDSN(t *testing.T) {
+    log, err := logger.NewLogger(logger.Error)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Setenv(ingestSpillDirEnv, t.TempDir())
+    t.Setenv("YB_OPEN_THREADS_REMINDER_DB_HOST", "configured.invalid")
+    cfg := unreachableDatabase(t)
+    dsn := fmt.Sprintf("host=127.0.0.1 port=%d user=postgres dbname=open_thread_db sslmode=disable connect_timeout=1", cfg.Port)
+
+    c, err := NewContainer(log, WithDatabaseDSN(dsn))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer c.Close()
+    // The database is opened with the DSN rather than the configured host
+    err = c.checkDatabase(context.Background())
+    if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("127.0.0.1:%d", cfg.Port)) {
+        t.Fatalf("got %v, want the database of the DSN unreachable", err)
+    }
+}
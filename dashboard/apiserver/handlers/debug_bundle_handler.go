@@ -0,0 +1,84 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/debugbundle"
+
+    "github.com/labstack/echo/v4"
+)
+
+const defaultSlowQuery = 200 * time.Millisecond
+
+// DebugRecordingRequest is the body accepted by StartDebugRecording
+type DebugRecordingRequest struct {
+    Duration  string `json:"duration"`
+    SlowQuery string `json:"slow_query"`
+}
+
+// GetDebugRecording - Get the state of the current or last debug recording
+func (c *Container) GetDebugRecording(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, c.recorder.Status())
+}
+
+// StartDebugRecording - Start recording API exchanges and slow queries for a time window
+func (c *Container) StartDebugRecording(ctx echo.Context) error {
+    var req DebugRecordingRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+
+    duration, err := time.ParseDuration(req.Duration)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "duration must be a duration such as 15m")
+    }
+    slowQuery := defaultSlowQuery
+    if req.SlowQuery != "" {
+        slowQuery, err = time.ParseDuration(req.SlowQuery)
+        if err != nil || slowQuery < 0 {
+            return apierror.New(http.StatusBadRequest, "slow_query must be a duration such as 200ms")
+        }
+    }
+
+    actor := actorFrom(ctx)
+    status, err := c.recorder.Start(actor, duration, slowQuery)
+    if err == debugbundle.ErrInvalidDuration {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+    c.logger.Infof("debug recording started by %s for %s", actor, duration)
+    c.audit(sharedContext(ctx.Request().Context()), actor, "debug_recording.start", "", "", map[string]interface{}{
+        "duration":   duration.String(),
+        "slow_query": slowQuery.String(),
+    })
+
+    return ctx.JSON(http.StatusOK, status)
+}
+
+// StopDebugRecording - Stop the running debug recording, keeping what was captured
+func (c *Container) StopDebugRecording(ctx echo.Context) error {
+    status := c.recorder.Stop()
+    c.audit(sharedContext(ctx.Request().Context()), actorFrom(ctx), "debug_recording.stop", "", "", nil)
+    return ctx.JSON(http.StatusOK, status)
+}
+
+// GetDebugBundle - Download the current or last debug recording as a zip archive
+func (c *Container) GetDebugBundle(ctx echo.Context) error {
+    status := c.recorder.Status()
+    if status.StartedAt == nil {
+        return apierror.New(http.StatusNotFound, "Nothing was recorded, start a recording first")
+    }
+
+    resp := ctx.Response()
+    resp.Header().Set(echo.HeaderContentType, "application/zip")
+    resp.Header().Set(echo.HeaderContentDisposition,
+        fmt.Sprintf(`attachment; filename="debug-bundle-%s.zip"`, status.StartedAt.Format("20060102-150405")))
+    resp.WriteHeader(http.StatusOK)
+    if err := c.recorder.WriteBundle(resp); err != nil {
+        c.logger.Errorf("failed to write debug bundle: %v", err)
+    }
+    return nil
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+// staleRoutes are the reads answered from their last response while the
+// database is unreachable.
+var staleRoutes = map[string]bool{
+    "/api/stats":             true,
+    "/api/stats/diff":        true,
+    "/api/stats/timeseries":  true,
+    "/api/stats/resolutions": true,
+    "/api/stats/usergroups":  true,
+    "/api/threads":           true,
+    "/api/threads/answered":  true,
+    "/api/channels":          true,
+    "/api/me/threads":        true,
+
+    // Backlogs of other users
+    "/api/users/:user_id/threads": true,
+}
+
+// headerStale marks responses served from the stale cache, and
+// headerStaleSince tells when they were cached.
+const (
+    headerStale      = "X-Stale"
+    headerStaleSince = "X-Stale-Since"
+)
+
+// staleResponse is the last successful response to a read.
+type staleResponse struct {
+    body     []byte
+    cachedAt time.Time
+}
+
+// staleCache keeps the last successful responses to staleRoutes, up to
+// maxBytes of bodies, forgetting the oldest first.
+type staleCache struct {
+    maxBytes int
+
+    mu        sync.Mutex
+    size      int
+    responses map[string]staleResponse
+}
+
+func newStaleCache(maxBytes int) *staleCache {
+    return &staleCache{maxBytes: maxBytes, responses: make(map[string]staleResponse)}
+}
+
+func (s *staleCache) store(key string, body []byte) {
+    if len(body) > s.maxBytes {
+        return
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if previous, ok := s.responses[key]; ok {
+        s.size -= len(previous.body)
+    }
+    s.responses[key] = staleResponse{body: body, cachedAt: time.Now()}
+    s.size += len(body)
+    for s.size > s.maxBytes {
+        oldest := ""
+        for k, r := range s.responses {
+            if oldest == "" || r.cachedAt.Before(s.responses[oldest].cachedAt) {
+                oldest = k
+            }
+        }
+        s.size -= len(s.responses[oldest].body)
+        delete(s.responses, oldest)
+    }
+}
+
+func (s *staleCache) load(key string) (staleResponse, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    r, ok := s.responses[key]
+    return r, ok
+}
+
+// cacheWriter keeps a copy of a response while passing it through.
+type cacheWriter struct {
+    http.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+    w.body.Write(b)
+    return w.ResponseWriter.Write(b)
+}
+
+// databaseHealthy tells whether the latest check found the database of the
+// workspace ctx was routed to reachable, without checking again.
+func (c *Container) databaseHealthy(ctx echo.Context) bool {
+    if workspace, ok := c.workspaces[workspaceFrom(ctx.Request().Context())]; ok {
+        return workspace.healthy.Load()
+    }
+    return c.dbHealthy.Load()
+}
+
+// databaseLost tells whether a request failed because its database is
+// unreachable. Other server errors are checked against the database, which
+// may have gone away since the latest check.
+func (c *Container) databaseLost(ctx echo.Context, err error) bool {
+    var apiErr *apierror.Error
+    if !errors.As(err, &apiErr) {
+        return false
+    }
+    if apiErr.Code == apierror.CodeDatabaseUnavailable {
+        return true
+    }
+    if apiErr.Status < http.StatusInternalServerError {
+        return false
+    }
+    reqCtx := ctx.Request().Context()
+    if workspace, ok := c.workspaces[workspaceFrom(reqCtx)]; ok {
+        return c.pingDatabase(reqCtx, "database of workspace "+workspaceFrom(reqCtx), workspace.db, workspace.config, &workspace.healthy) != nil
+    }
+    return c.checkDatabase(reqCtx) != nil
+}
+
+// retryAtHealthCheck tells the client to retry once the database is checked
+// again.
+func retryAtHealthCheck(ctx echo.Context) {
+    ctx.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(dbHealthCheckInterval.Seconds())))
+}
+
+// serveStale answers a read with its last response, flagged as stale by
+// headers. Objects also get stale and stale_since fields, arrays are left
+// as they are so clients keep reading them the same.
+func serveStale(ctx echo.Context, cached staleResponse) error {
+    body := cached.body
+    var fields map[string]json.RawMessage
+    if json.Unmarshal(body, &fields) == nil {
+        fields["stale"] = json.RawMessage("true")
+        fields["stale_since"], _ = json.Marshal(cached.cachedAt.UTC())
+        if flagged, err := json.Marshal(fields); err == nil {
+            body = flagged
+        }
+    }
+    ctx.Response().Header().Set(headerStale, "true")
+    ctx.Response().Header().Set(headerStaleSince, cached.cachedAt.UTC().Format(time.RFC3339))
+    return ctx.JSONBlob(http.StatusOK, body)
+}
+
+// ServeStale keeps the API useful while the database is unreachable: reads
+// of stats and thread lists are answered with the last response the same
+// user got, and every request failing for want of the database tells when
+// to retry, the next health check. The database is not waited for while
+// known to be down and a stale response is at hand, requests are served
+// fresh again as soon as a health check finds it back.
+func (c *Container) ServeStale(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+        if c.staleCache == nil || ctx.Request().Method != http.MethodGet || !staleRoutes[ctx.Path()] {
+            err := next(ctx)
+            if err != nil && c.databaseLost(ctx, err) {
+                retryAtHealthCheck(ctx)
+            }
+            return err
+        }
+
+        key := workspaceFrom(ctx.Request().Context()) + "|" + actorFrom(ctx) + "|" + ctx.Request().URL.RequestURI()
+        if !c.databaseHealthy(ctx) {
+            if cached, ok := c.staleCache.load(key); ok {
+                retryAtHealthCheck(ctx)
+                return serveStale(ctx, cached)
+            }
+        }
+
+        resp := ctx.Response()
+        capture := &cacheWriter{ResponseWriter: resp.Writer}
+        resp.Writer = capture
+        err := next(ctx)
+        resp.Writer = capture.ResponseWriter
+        if err == nil {
+            if resp.Status == http.StatusOK {
+                c.staleCache.store(key, capture.body.Bytes())
+            }
+            return nil
+        }
+        if !c.databaseLost(ctx, err) {
+            return err
+        }
+        retryAtHealthCheck(ctx)
+        if cached, ok := c.staleCache.load(key); ok && !resp.Committed {
+            return serveStale(ctx, cached)
+        }
+        return err
+    }
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "github.com/labstack/echo/v4"
+)
+
+func TestServeStale(t *testing.T) {
+    c := newTestContainer(t, NewMemoryStore(), &MemorySlack{})
+    c.staleCache = newStaleCache(1 << 20)
+    // No health check found the database reachable, so the first response
+    // is cached and the second served from the cache
+    fresh := true
+    handler := func(body interface{}) echo.HandlerFunc {
+        return c.ServeStale(func(ctx echo.Context) error {
+            if !fresh {
+                t.Fatal("the handler was called while a stale response was at hand")
+            }
+            return ctx.JSON(http.StatusOK, body)
+        })
+    }
+
+    for _, tt := range []struct {
+        route string
+        body  interface{}
+        check func(body map[string]interface{}) bool
+    }{
+        {"/api/channels", []string{"C1", "C2"}, nil},
+        {"/api/stats", map[string]int{"total_threads": 3}, func(body map[string]interface{}) bool {
+            return body["stale"] == true && body["stale_since"] != nil && body["total_threads"] == float64(3)
+        }},
+    } {
+        fresh = true
+        rec := serveRequest(t, c, http.MethodGet, tt.route, tt.route, handler(tt.body))
+        if rec.Code != http.StatusOK || rec.Header().Get(headerStale) != "" {
+            t.Fatalf("%s: got status %d, stale %q for a fresh response", tt.route, rec.Code, rec.Header().Get(headerStale))
+        }
+
+        fresh = false
+        rec = serveRequest(t, c, http.MethodGet, tt.route, tt.route, handler(tt.body))
+        if rec.Code != http.StatusOK || rec.Header().Get(headerStale) != "true" {
+            t.Fatalf("%s: got status %d, stale %q, want a stale response", tt.route, rec.Code, rec.Header().Get(headerStale))
+        }
+        if since, err := time.Parse(time.RFC3339, rec.Header().Get(headerStaleSince)); err != nil || time.Since(since) > time.Minute {
+            t.Fatalf("%s: got %s %q", tt.route, headerStaleSince, rec.Header().Get(headerStaleSince))
+        }
+        if tt.check == nil {
+            // Lists are served as they were, flagged by the headers only
+            var list []string
+            decodeResponse(t, rec, &list)
+            if len(list) != 2 {
+                t.Fatalf("%s: got %v, want the cached list", tt.route, list)
+            }
+            continue
+        }
+        var body map[string]interface{}
+        decodeResponse(t, rec, &body)
+        if !tt.check(body) {
+            t.Fatalf("%s: got %v, want the cached object flagged as stale", tt.route, body)
+        }
+    }
+}
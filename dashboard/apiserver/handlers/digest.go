@@ -0,0 +1,263 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "net/http"
+    "sort"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/digest"
+    "dashboard/apiserver/notify"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// digestWindow is how far back a digest looks for new threads.
+const digestWindow = 24 * time.Hour
+
+// DigestPreviewRequest is the body accepted by PreviewDigest. Window is how
+// far back new threads are counted, 24h when empty.
+type DigestPreviewRequest struct {
+    Window string `json:"window"`
+}
+
+// DigestPreview is a digest with the message it is sent as.
+type DigestPreview struct {
+    Digest     *digest.Digest `json:"digest"`
+    Text       string         `json:"text"`
+    Blocks     []slack.Block  `json:"blocks"`
+    Email      string         `json:"email"`
+    Channels   []string       `json:"channels"`
+    Emails     []string       `json:"emails"`
+    NextSendAt *time.Time     `json:"next_send_at"`
+}
+
+// composeDigest summarizes the threads created since since and the open
+// threads of every channel at now.
+func (c *Container) composeDigest(ctx context.Context, since time.Time, now time.Time) (*digest.Digest, error) {
+    d := &digest.Digest{
+        Since:        since,
+        Until:        now,
+        Channels:     []digest.ChannelSummary{},
+        TopPriority:  []digest.Thread{},
+        DashboardURL: c.publicURL,
+    }
+
+    channels := map[string]*digest.ChannelSummary{}
+    open := []digest.Thread{}
+    count := func(thread Thread) error {
+        ch, ok := channels[thread.ChannelID]
+        if !ok {
+            ch = &digest.ChannelSummary{ChannelID: thread.ChannelID, ChannelName: thread.ChannelName}
+            channels[thread.ChannelID] = ch
+        }
+        if !thread.CreatedAt.Before(since) {
+            ch.NewThreads++
+            d.NewThreads++
+        }
+        if thread.Status != statusOpen {
+            return nil
+        }
+        ch.OpenThreads++
+        d.OpenThreads++
+        if thread.SLABreached {
+            ch.Overdue++
+            d.Overdue++
+        }
+        title := ""
+        if thread.AIThreadName != nil {
+            title = *thread.AIThreadName
+        }
+        open = append(open, digest.Thread{
+            ChannelID:   thread.ChannelID,
+            ChannelName: thread.ChannelName,
+            ThreadTS:    thread.ThreadTS,
+            Title:       title,
+            Priority:    thread.Priority,
+            CreatedAt:   thread.CreatedAt,
+            DueAt:       thread.DueAt,
+            Overdue:     thread.SLABreached,
+        })
+        return nil
+    }
+    openList := threadList{filters: threadFilters{statuses: []string{string(statusOpen)}}, sort: "created_at", order: "asc"}
+    if err := c.threadLists.StreamThreads(ctx, openList, count); err != nil {
+        return nil, err
+    }
+    // The threads created since that are still open were counted already
+    newList := threadList{filters: threadFilters{createdAfter: &since}, sort: "created_at", order: "asc"}
+    err := c.threadLists.StreamThreads(ctx, newList, func(thread Thread) error {
+        if thread.Status == statusOpen {
+            return nil
+        }
+        return count(thread)
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    for _, ch := range channels {
+        d.Channels = append(d.Channels, *ch)
+    }
+    // Channels with the most overdue threads come first
+    sort.Slice(d.Channels, func(i, j int) bool {
+        a, b := d.Channels[i], d.Channels[j]
+        if a.Overdue != b.Overdue {
+            return a.Overdue > b.Overdue
+        }
+        if a.NewThreads != b.NewThreads {
+            return a.NewThreads > b.NewThreads
+        }
+        return a.ChannelName < b.ChannelName
+    })
+    // Top priorities are the most urgent, overdue and oldest first
+    sort.Slice(open, func(i, j int) bool {
+        a, b := open[i], open[j]
+        if a.Priority.Rank() != b.Priority.Rank() {
+            return a.Priority.Rank() > b.Priority.Rank()
+        }
+        if a.Overdue != b.Overdue {
+            return a.Overdue
+        }
+        return a.CreatedAt.Before(b.CreatedAt)
+    })
+    for _, t := range open {
+        if len(d.TopPriority) == digest.MaxTopThreads || !t.Priority.Valid() {
+            break
+        }
+        d.TopPriority = append(d.TopPriority, t)
+    }
+    return d, nil
+}
+
+// sendDigest posts a digest in the digest channels and emails it to the
+// digest recipients. Failing recipients do not keep the others from
+// getting it.
+func (c *Container) sendDigest(ctx context.Context, d *digest.Digest) {
+    text, blocks := digest.Message(d)
+    channels := c.digestChannels
+    if len(channels) > 0 && !c.slackFeatureReady(slackFeatureReplies) {
+        c.logger.Warnf("the digest is not posted in Slack: %v", errSlackFeatureUnavailable)
+        channels = nil
+    }
+    for _, channel := range channels {
+        if _, err := c.slack.PostMessage(ctx, channel, text, blocks); err != nil {
+            c.logger.Warnf("failed to post digest in %s: %v", channel, err)
+        }
+    }
+    if len(c.digestEmails) == 0 {
+        return
+    }
+    if c.mailer.Addr == "" || c.mailer.From == "" {
+        c.logger.Warnf("digest emails need %s and %s", smtpAddrEnv, emailFromEnv)
+        return
+    }
+    n := notify.Notification{Text: text, Body: digest.Summary(d)}
+    for _, to := range c.digestEmails {
+        if err := c.mailer.SendTo(to, n); err != nil {
+            c.logger.Warnf("failed to email digest to %s: %v", to, err)
+        }
+    }
+}
+
+// RunDigest sends the digest of the last day on its schedule until ctx is
+// cancelled. It does nothing unless digest channels or emails are
+// configured.
+func (c *Container) RunDigest(ctx context.Context) {
+    if len(c.digestChannels) == 0 && len(c.digestEmails) == 0 {
+        return
+    }
+
+    for {
+        next := c.digestSchedule.Next(time.Now().In(c.digestLocation))
+        if next.IsZero() {
+            c.logger.Warnf("the digest schedule never runs")
+            return
+        }
+        timer := time.NewTimer(time.Until(next))
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return
+        case <-timer.C:
+        }
+        if err := c.runDigest(ctx, next); err != nil && ctx.Err() == nil {
+            c.logger.Warnf("failed to send the digest: %v", err)
+        }
+    }
+}
+
+func (c *Container) runDigest(ctx context.Context, scheduledAt time.Time) error {
+    // Only the first replica to claim a run sends it
+    claimed, err := c.digests.ClaimDigestRun(ctx, scheduledAt.UTC())
+    if err != nil || !claimed {
+        return err
+    }
+
+    now := time.Now().UTC()
+    d, err := c.composeDigest(ctx, now.Add(-digestWindow), now)
+    if err != nil {
+        return err
+    }
+    c.sendDigest(ctx, d)
+    return nil
+}
+
+// PreviewDigest - Compose the digest without sending it, to test its content and recipients
+func (c *Container) PreviewDigest(ctx echo.Context) error {
+    var req DigestPreviewRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil && err != io.EOF {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    window := digestWindow
+    if req.Window != "" {
+        parsed, err := time.ParseDuration(req.Window)
+        if err != nil || parsed <= 0 {
+            return apierror.New(http.StatusBadRequest, "window must be a positive duration, e.g. 24h")
+        }
+        window = parsed
+    }
+
+    now := time.Now().UTC()
+    d, err := c.composeDigest(ctx.Request().Context(), now.Add(-window), now)
+    if err != nil {
+        c.logger.Errorf("failed to compose digest: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to compose digest")
+    }
+
+    text, blocks := digest.Message(d)
+    preview := DigestPreview{
+        Digest:   d,
+        Text:     text,
+        Blocks:   blocks,
+        Email:    digest.Summary(d),
+        Channels: append([]string{}, c.digestChannels...),
+        Emails:   append([]string{}, c.digestEmails...),
+    }
+    if len(c.digestChannels) > 0 || len(c.digestEmails) > 0 {
+        if next := c.digestSchedule.Next(now.In(c.digestLocation)); !next.IsZero() {
+            preview.NextSendAt = &next
+        }
+    }
+    return ctx.JSON(http.StatusOK, preview)
+}
+
+func (s postgresStore) ClaimDigestRun(ctx context.Context, scheduledAt time.Time) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+    result, err := db.ExecContext(ctx, `
+        INSERT INTO digest_runs (scheduled_at, sent_at) VALUES ($1, NOW())
+        ON CONFLICT (scheduled_at) DO NOTHING
+    `, scheduledAt)
+    if err != nil {
+        return false, err
+    }
+    n, _ := result.RowsAffected()
+    return n > 0, nil
+}
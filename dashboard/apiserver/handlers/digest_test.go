@@ -0,0 +1,49 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestDigestCountsNewAndOpenThreads(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "alerts"}})
+    old, recent := time.Now().Add(-3*digestWindow), time.Now().Add(-time.Hour)
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: old, LatestReply: old}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: statusOpen, CreatedAt: recent, LatestReply: recent}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C2", ThreadTS: "1700000000.000300", Status: statusResolved, CreatedAt: recent, LatestReply: recent}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C2", ThreadTS: "1700000000.000400", Status: statusResolved, CreatedAt: old, LatestReply: old}})
+    slack := &MemorySlack{}
+    c := newTestContainer(t, store, slack)
+    admin := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+
+    rec := serveAs(c, admin, http.MethodPost, "/api/digest/preview", "/api/digest/preview", "", c.PreviewDigest)
+    var preview DigestPreview
+    decodeResponse(t, rec, &preview)
+    d := preview.Digest
+    if d.NewThreads != 2 || d.OpenThreads != 2 || len(d.Channels) != 2 {
+        t.Fatalf("got %d new and %d open threads in %d channels", d.NewThreads, d.OpenThreads, len(d.Channels))
+    }
+    for _, ch := range d.Channels {
+        if ch.ChannelID == "C2" && (ch.NewThreads != 1 || ch.OpenThreads != 0) {
+            t.Fatalf("got channel %+v, want the thread resolved since counted as new", ch)
+        }
+    }
+
+    c.digestChannels = []string{"C9"}
+    scheduledAt := time.Now().Truncate(time.Hour)
+    for range 2 {
+        if err := c.runDigest(context.Background(), scheduledAt); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if posted := slack.Messages(); len(posted) != 1 || posted[0].Channel != "C9" {
+        t.Fatalf("got Slack messages %+v, want the scheduled digest posted once", posted)
+    }
+}
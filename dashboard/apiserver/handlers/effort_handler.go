@@ -0,0 +1,360 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+)
+
+// maxEstimateMinutes bounds effort estimates to a working month.
+const maxEstimateMinutes = 160 * 60
+
+// ThreadEffortRequest is the body accepted by SetThreadEffort, a null
+// estimate clears it
+type ThreadEffortRequest struct {
+    EstimateMinutes *int `json:"estimate_minutes"`
+}
+
+// TimeEntry is a span of time a person spent on a thread
+type TimeEntry struct {
+    ID        int64      `json:"id"`
+    UserID    string     `json:"user_id"`
+    StartedAt time.Time  `json:"started_at"`
+    StoppedAt *time.Time `json:"stopped_at"`
+}
+
+// ThreadEffort is the estimate and tracked time of a thread
+type ThreadEffort struct {
+    ChannelID       string      `json:"channel_id"`
+    ThreadTS        string      `json:"thread_ts"`
+    EstimateMinutes *int        `json:"estimate_minutes"`
+    TrackedMinutes  float64     `json:"tracked_minutes"`
+    Entries         []TimeEntry `json:"entries"`
+}
+
+// EffortTotals aggregates estimated and tracked effort of a channel or person
+type EffortTotals struct {
+    ChannelID        string  `json:"channel_id,omitempty"`
+    UserID           string  `json:"user_id,omitempty"`
+    Threads          int     `json:"threads"`
+    EstimatedMinutes int     `json:"estimated_minutes"`
+    TrackedMinutes   float64 `json:"tracked_minutes"`
+}
+
+// checkThread fails with errChannelNotTracked or errThreadNotFound unless
+// the thread exists.
+func checkThread(db *sql.DB, channelID, threadTS string) error {
+    if err := channelTracked(db, channelID); err != nil {
+        return err
+    }
+    var exists bool
+    err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM threads WHERE thread_ts = $1 AND channel_id = $2)",
+        threadTS, channelID).Scan(&exists)
+    if err != nil {
+        return err
+    }
+    if !exists {
+        return errThreadNotFound
+    }
+    return nil
+}
+
+// threadLookupError writes the response for an error of checkThread.
+func threadLookupError(ctx echo.Context, err error) error {
+    switch err {
+    case errChannelNotTracked:
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    case errThreadNotFound:
+        return apierror.New(http.StatusNotFound, "Thread not found")
+    }
+    return apierror.New(http.StatusInternalServerError, "Failed to look up thread")
+}
+
+// loadThreadEffort returns the estimate and time entries of a thread.
+func loadThreadEffort(ctx context.Context, db *sql.DB, channelID, threadTS string) (ThreadEffort, error) {
+    effort := ThreadEffort{ChannelID: channelID, ThreadTS: threadTS, Entries: []TimeEntry{}}
+    var estimate sql.NullInt64
+    err := db.QueryRowContext(ctx, "SELECT estimate_minutes FROM thread_effort WHERE channel_id = $1 AND thread_ts = $2",
+        channelID, threadTS).Scan(&estimate)
+    if err != nil && err != sql.ErrNoRows {
+        return effort, err
+    }
+    if estimate.Valid {
+        minutes := int(estimate.Int64)
+        effort.EstimateMinutes = &minutes
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT id, user_id, started_at, stopped_at FROM thread_time_entries
+        WHERE channel_id = $1 AND thread_ts = $2
+        ORDER BY started_at
+    `, channelID, threadTS)
+    if err != nil {
+        return effort, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var entry TimeEntry
+        if err := rows.Scan(&entry.ID, &entry.UserID, &entry.StartedAt, &entry.StoppedAt); err != nil {
+            continue
+        }
+        effort.Entries = append(effort.Entries, entry)
+    }
+    effort.TrackedMinutes = trackedMinutes(effort.Entries, time.Time{}, time.Now().UTC())
+    return effort, rows.Err()
+}
+
+// trackedMinutes sums the minutes of entries spent since a time, running
+// entries counting up to now.
+func trackedMinutes(entries []TimeEntry, since time.Time, now time.Time) float64 {
+    minutes := 0.0
+    for _, entry := range entries {
+        start, end := entry.StartedAt, now
+        if entry.StoppedAt != nil {
+            end = *entry.StoppedAt
+        }
+        if start.Before(since) {
+            start = since
+        }
+        if end.After(start) {
+            minutes += end.Sub(start).Minutes()
+        }
+    }
+    return minutes
+}
+
+// GetThreadEffort - Get the effort estimate and tracked time of a thread
+func (c *Container) GetThreadEffort(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+    reqCtx := ctx.Request().Context()
+
+    if _, err := c.threads.Thread(reqCtx, channelID, threadTS); err != nil {
+        return threadLookupError(ctx, err)
+    }
+
+    effort, err := c.threads.Effort(reqCtx, channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread effort")
+    }
+    return ctx.JSON(http.StatusOK, effort)
+}
+
+// SetThreadEffort - Set or clear the effort estimate of a thread
+func (c *Container) SetThreadEffort(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req ThreadEffortRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if req.EstimateMinutes != nil && (*req.EstimateMinutes <= 0 || *req.EstimateMinutes > maxEstimateMinutes) {
+        return apierror.Newf(http.StatusBadRequest, "estimate_minutes must be between 1 and %d", maxEstimateMinutes)
+    }
+
+    reqCtx := ctx.Request().Context()
+    if _, err := c.threads.Thread(reqCtx, channelID, threadTS); err != nil {
+        return threadLookupError(ctx, err)
+    }
+
+    actor := actorFrom(ctx)
+    if err := c.efforts.SetEstimate(reqCtx, channelID, threadTS, req.EstimateMinutes, actor); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update thread effort")
+    }
+
+    c.audit(reqCtx, actor, "thread.effort", channelID, threadTS, map[string]interface{}{
+        "estimate_minutes": req.EstimateMinutes,
+    })
+
+    effort, err := c.threads.Effort(reqCtx, channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread effort")
+    }
+    return ctx.JSON(http.StatusOK, effort)
+}
+
+// StartThreadTimer - Start tracking the signed in user's time on a thread
+func (c *Container) StartThreadTimer(ctx echo.Context) error {
+    return c.toggleThreadTimer(ctx, true)
+}
+
+// StopThreadTimer - Stop tracking the signed in user's time on a thread
+func (c *Container) StopThreadTimer(ctx echo.Context) error {
+    return c.toggleThreadTimer(ctx, false)
+}
+
+// toggleThreadTimer starts or stops the running time entry of the signed in
+// user on a thread. Starting an already running timer is a no-op.
+func (c *Container) toggleThreadTimer(ctx echo.Context, start bool) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    // Time is tracked per person, anonymous callers have nobody to track
+    principal, ok := auth.PrincipalFrom(ctx)
+    if !ok || principal.UserID == "" {
+        return apierror.New(http.StatusUnauthorized, "Tracking time requires a signed in user")
+    }
+
+    reqCtx := ctx.Request().Context()
+    if _, err := c.threads.Thread(reqCtx, channelID, threadTS); err != nil {
+        return threadLookupError(ctx, err)
+    }
+
+    var err error
+    if start {
+        err = c.efforts.StartTimer(reqCtx, channelID, threadTS, principal.UserID)
+    } else {
+        var stopped bool
+        stopped, err = c.efforts.StopTimer(reqCtx, channelID, threadTS, principal.UserID)
+        if err == nil && !stopped {
+            return apierror.New(http.StatusConflict, "No timer is running on this thread")
+        }
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update time tracking")
+    }
+
+    effort, err := c.threads.Effort(reqCtx, channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread effort")
+    }
+    return ctx.JSON(http.StatusOK, effort)
+}
+
+// GetEffortAnalytics - Get estimated and tracked effort per channel and person
+func (c *Container) GetEffortAnalytics(ctx echo.Context) error {
+    since := time.Now().UTC().AddDate(0, 0, -30)
+    if sinceStr := ctx.QueryParam("since"); sinceStr != "" {
+        parsed, err := time.Parse(time.RFC3339, sinceStr)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, "since must be an RFC3339 timestamp")
+        }
+        since = parsed.UTC()
+    }
+
+    channels, people, err := c.efforts.EffortTotals(ctx.Request().Context(), since)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query effort")
+    }
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "since":    since,
+        "channels": channels,
+        "people":   people,
+    })
+}
+
+func (s postgresStore) SetEstimate(ctx context.Context, channelID, threadTS string, minutes *int, actor string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    if minutes == nil {
+        _, err = db.ExecContext(ctx, "DELETE FROM thread_effort WHERE channel_id = $1 AND thread_ts = $2", channelID, threadTS)
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO thread_effort (channel_id, thread_ts, estimate_minutes, set_by, updated_at)
+        VALUES ($1, $2, $3, $4, NOW())
+        ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+            estimate_minutes = EXCLUDED.estimate_minutes,
+            set_by = EXCLUDED.set_by,
+            updated_at = EXCLUDED.updated_at
+    `, channelID, threadTS, *minutes, actor)
+    return err
+}
+
+func (s postgresStore) StartTimer(ctx context.Context, channelID, threadTS, userID string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO thread_time_entries (channel_id, thread_ts, user_id, started_at)
+        SELECT $1, $2, $3, NOW()
+        WHERE NOT EXISTS (SELECT 1 FROM thread_time_entries
+            WHERE channel_id = $1 AND thread_ts = $2 AND user_id = $3 AND stopped_at IS NULL)
+    `, channelID, threadTS, userID)
+    return err
+}
+
+func (s postgresStore) StopTimer(ctx context.Context, channelID, threadTS, userID string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+    result, err := db.ExecContext(ctx, `
+        UPDATE thread_time_entries SET stopped_at = NOW()
+        WHERE channel_id = $1 AND thread_ts = $2 AND user_id = $3 AND stopped_at IS NULL
+    `, channelID, threadTS, userID)
+    if err != nil {
+        return false, err
+    }
+    n, err := result.RowsAffected()
+    return n > 0, err
+}
+
+func (s postgresStore) EffortTotals(ctx context.Context, since time.Time) ([]EffortTotals, []EffortTotals, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    // Running timers count up to now, entries spanning since only from it
+    const tracked = `EXTRACT(EPOCH FROM COALESCE(stopped_at, NOW()) - GREATEST(started_at, $1)) / 60`
+
+    channels := []EffortTotals{}
+    rows, err := db.QueryContext(ctx, `
+        SELECT COALESCE(e.channel_id, t.channel_id), COUNT(DISTINCT COALESCE(e.thread_ts, t.thread_ts)),
+               COALESCE(SUM(e.estimate_minutes), 0), COALESCE(SUM(t.minutes), 0)
+        FROM (SELECT channel_id, thread_ts, estimate_minutes FROM thread_effort WHERE updated_at >= $1) e
+        FULL JOIN (
+            SELECT channel_id, thread_ts, SUM(`+tracked+`) AS minutes
+            FROM thread_time_entries
+            WHERE COALESCE(stopped_at, NOW()) >= $1
+            GROUP BY channel_id, thread_ts
+        ) t ON t.channel_id = e.channel_id AND t.thread_ts = e.thread_ts
+        GROUP BY 1
+    `, since)
+    if err != nil {
+        return nil, nil, err
+    }
+    for rows.Next() {
+        var totals EffortTotals
+        if err := rows.Scan(&totals.ChannelID, &totals.Threads, &totals.EstimatedMinutes, &totals.TrackedMinutes); err != nil {
+            continue
+        }
+        channels = append(channels, totals)
+    }
+    rows.Close()
+
+    people := []EffortTotals{}
+    rows, err = db.QueryContext(ctx, `
+        SELECT user_id, COUNT(DISTINCT channel_id || ':' || thread_ts), SUM(`+tracked+`)
+        FROM thread_time_entries
+        WHERE COALESCE(stopped_at, NOW()) >= $1
+        GROUP BY user_id
+        ORDER BY 3 DESC
+    `, since)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var totals EffortTotals
+        if err := rows.Scan(&totals.UserID, &totals.Threads, &totals.TrackedMinutes); err != nil {
+            continue
+        }
+        people = append(people, totals)
+    }
+    return channels, people, rows.Err()
+}
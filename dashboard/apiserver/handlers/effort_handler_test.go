@@ -0,0 +1,109 @@
+package handlers
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+func TestGetThreadEffort(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100"}})
+    started := time.Now().UTC().Add(-90 * time.Minute)
+    stopped := started.Add(30 * time.Minute)
+    estimate := 60
+    store.SetEffort(ThreadEffort{
+        ChannelID:       "C1",
+        ThreadTS:        "1700000000.000100",
+        EstimateMinutes: &estimate,
+        TrackedMinutes:  30,
+        Entries:         []TimeEntry{{ID: 1, UserID: "U1", StartedAt: started, StoppedAt: &stopped}},
+    })
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    tests := []struct {
+        name   string
+        target string
+        status int
+        error  string
+    }{
+        {"tracked thread", "/api/threads/C1/1700000000.000100/effort", http.StatusOK, ""},
+        {"untracked channel", "/api/threads/C2/1700000000.000100/effort", http.StatusNotFound, "Channel not found"},
+        {"missing thread", "/api/threads/C1/1700000000.000200/effort", http.StatusNotFound, "Thread not found"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rec := serveRequest(t, c, http.MethodGet, "/api/threads/:channel_id/:thread_ts/effort", tt.target, c.GetThreadEffort)
+            if rec.Code != tt.status {
+                t.Fatalf("got status %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+            }
+            if tt.error != "" {
+                if !strings.Contains(rec.Body.String(), tt.error) {
+                    t.Fatalf("got %s, want %q", rec.Body.String(), tt.error)
+                }
+                return
+            }
+            var effort ThreadEffort
+            decodeResponse(t, rec, &effort)
+            if effort.EstimateMinutes == nil || *effort.EstimateMinutes != estimate || len(effort.Entries) != 1 {
+                t.Fatalf("got effort %+v", effort)
+            }
+        })
+    }
+}
+
+func TestThreadEffortTracking(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100"}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    agent := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+    const target = "/api/threads/C1/1700000000.000100/effort"
+
+    rec := serveAs(c, agent, http.MethodPut, "/api/threads/:channel_id/:thread_ts/effort", target, `{"estimate_minutes": 90}`, c.SetThreadEffort)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("setting the estimate got status %d: %s", rec.Code, rec.Body.String())
+    }
+    timer := func(action string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+        return serveAs(c, agent, http.MethodPost, "/api/threads/:channel_id/:thread_ts/time/"+action,
+            "/api/threads/C1/1700000000.000100/time/"+action, "", handler)
+    }
+    if rec := timer("stop", c.StopThreadTimer); rec.Code != http.StatusConflict {
+        t.Fatalf("stopping without a timer got status %d", rec.Code)
+    }
+    // Starting twice keeps the running timer
+    timer("start", c.StartThreadTimer)
+    if rec := timer("start", c.StartThreadTimer); rec.Code != http.StatusOK {
+        t.Fatalf("starting the timer got status %d: %s", rec.Code, rec.Body.String())
+    }
+    rec = timer("stop", c.StopThreadTimer)
+    var effort ThreadEffort
+    decodeResponse(t, rec, &effort)
+    if effort.EstimateMinutes == nil || *effort.EstimateMinutes != 90 || len(effort.Entries) != 1 || effort.Entries[0].StoppedAt == nil {
+        t.Fatalf("got effort %+v, want the estimate and one stopped entry", effort)
+    }
+
+    rec = serveAs(c, agent, http.MethodGet, "/api/effort", "/api/effort", "", c.GetEffortAnalytics)
+    var analytics struct {
+        Channels []EffortTotals `json:"channels"`
+        People   []EffortTotals `json:"people"`
+    }
+    decodeResponse(t, rec, &analytics)
+    if len(analytics.Channels) != 1 || analytics.Channels[0].EstimatedMinutes != 90 || len(analytics.People) != 1 || analytics.People[0].UserID != "U1" {
+        t.Fatalf("got analytics %+v", analytics)
+    }
+
+    rec = serveAs(c, agent, http.MethodPut, "/api/threads/:channel_id/:thread_ts/effort", target, `{"estimate_minutes": null}`, c.SetThreadEffort)
+    decodeResponse(t, rec, &effort)
+    if effort.EstimateMinutes != nil || len(effort.Entries) != 1 {
+        t.Fatalf("got effort %+v, want the estimate cleared and the entry kept", effort)
+    }
+}
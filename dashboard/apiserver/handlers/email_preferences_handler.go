@@ -0,0 +1,270 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/mail"
+    "net/url"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+// emailLinkTTL is how long the unsubscribe and preference links of an email
+// keep working.
+const emailLinkTTL = 90 * 24 * time.Hour
+
+// EmailPreferences are the email notifications an address receives
+type EmailPreferences struct {
+    Email     string     `json:"email"`
+    Digest    bool       `json:"digest"`
+    UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// EmailPreferencesRequest is the body accepted by UpdateEmailPreferences
+type EmailPreferencesRequest struct {
+    Digest *bool `json:"digest"`
+}
+
+// emailLinks are the links added to every notification email.
+type emailLinks struct {
+    Unsubscribe string
+    Preferences string
+}
+
+func normalizeEmail(address string) string {
+    return strings.ToLower(strings.TrimSpace(address))
+}
+
+// emailLinksFor returns the signed links letting address manage its email
+// notifications without signing in to the dashboard.
+func (c *Container) emailLinksFor(address string) emailLinks {
+    address = normalizeEmail(address)
+    expires := time.Now().Add(emailLinkTTL)
+    query := url.Values{}
+    query.Set("email", address)
+    query.Set("expires", fmt.Sprint(expires.Unix()))
+    query.Set("signature", c.emailSigner.Sign("email:"+address, expires))
+    return emailLinks{
+        Unsubscribe: c.publicURL + "/email/unsubscribe?" + query.Encode(),
+        Preferences: c.publicURL + "/email/preferences?" + query.Encode(),
+    }
+}
+
+// listUnsubscribeHeaders returns the headers offering mail clients a one-click
+// unsubscribe (RFC 8058) for an email sent to address.
+func (c *Container) listUnsubscribeHeaders(address string) map[string]string {
+    return map[string]string{
+        "List-Unsubscribe":      "<" + c.emailLinksFor(address).Unsubscribe + ">",
+        "List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+    }
+}
+
+// emailDigestEnabled reports whether address still wants digest emails.
+// Addresses without preferences receive them.
+func (c *Container) emailDigestEnabled(ctx context.Context, address string) (bool, error) {
+    prefs, err := c.emailPrefs.EmailPreferences(ctx, address)
+    return prefs.Digest, err
+}
+
+// verifiedEmail returns the address of a signed preference link, or "" when
+// the link is invalid or expired.
+func (c *Container) verifiedEmail(ctx echo.Context) string {
+    address := normalizeEmail(ctx.QueryParam("email"))
+    if address == "" || !c.emailSigner.Verify("email:"+address, ctx.QueryParam("expires"), ctx.QueryParam("signature")) {
+        return ""
+    }
+    return address
+}
+
+// emailActor returns the Slack user of address for the audit log, falling
+// back to "email" for addresses missing from the user directory.
+func (c *Container) emailActor(ctx context.Context, address string) string {
+    userID, err := c.users.UserByEmail(ctx, address)
+    if err != nil || userID == "" {
+        return "email"
+    }
+    return userID
+}
+
+// UnsubscribeEmail - Stop digest emails to the address of a signed link, as a one-click unsubscribe
+func (c *Container) UnsubscribeEmail(ctx echo.Context) error {
+    address := c.verifiedEmail(ctx)
+    if address == "" {
+        return apierror.New(http.StatusForbidden, "Invalid or expired unsubscribe link")
+    }
+
+    prefs, err := c.emailPrefs.SetEmailPreferences(ctx.Request().Context(), EmailPreferences{Email: address, Digest: false})
+    if err != nil {
+        c.logger.Errorf("failed to unsubscribe email address: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to unsubscribe")
+    }
+
+    c.audit(sharedContext(ctx.Request().Context()), c.emailActor(ctx.Request().Context(), address), "email.unsubscribe", "", "", map[string]interface{}{
+        "email": address,
+    })
+
+    return ctx.JSON(http.StatusOK, prefs)
+}
+
+// inboundEmail is a message forwarded by the email gateway.
+type inboundEmail struct {
+    From    string `json:"from"`
+    Subject string `json:"subject"`
+    Text    string `json:"text"`
+}
+
+// unsubscribeRequest reports whether a message asks to stop notification
+// emails, with "unsubscribe" as its subject, e.g. in a reply, or as the
+// first line of its text.
+func (m inboundEmail) unsubscribeRequest() bool {
+    subject := strings.TrimSpace(m.Subject)
+    for len(subject) > 3 && strings.EqualFold(subject[:3], "re:") {
+        subject = strings.TrimSpace(subject[3:])
+    }
+    if strings.EqualFold(subject, "unsubscribe") {
+        return true
+    }
+    for _, line := range strings.Split(m.Text, "\n") {
+        if line = strings.TrimSpace(line); line != "" {
+            return strings.EqualFold(line, "unsubscribe")
+        }
+    }
+    return false
+}
+
+// HandleEmailWebhook - Stop digest emails to senders replying to them with unsubscribe, as forwarded by the email gateway
+func (c *Container) HandleEmailWebhook(ctx echo.Context) error {
+    var message inboundEmail
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&message); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid webhook payload")
+    }
+    // Other messages are acknowledged so the gateway does not retry them
+    if !message.unsubscribeRequest() {
+        return ctx.NoContent(http.StatusOK)
+    }
+    from, err := mail.ParseAddress(message.From)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid sender address")
+    }
+    address := normalizeEmail(from.Address)
+
+    if _, err := c.emailPrefs.SetEmailPreferences(ctx.Request().Context(), EmailPreferences{Email: address, Digest: false}); err != nil {
+        c.logger.Errorf("failed to unsubscribe email address: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to unsubscribe")
+    }
+    c.audit(sharedContext(ctx.Request().Context()), c.emailActor(ctx.Request().Context(), address), "email.unsubscribe", "", "", map[string]interface{}{
+        "email":  address,
+        "source": "email",
+    })
+    return ctx.NoContent(http.StatusOK)
+}
+
+// GetEmailPreferences - Get the email notifications of the address of a signed link
+func (c *Container) GetEmailPreferences(ctx echo.Context) error {
+    address := c.verifiedEmail(ctx)
+    if address == "" {
+        return apierror.New(http.StatusForbidden, "Invalid or expired preferences link")
+    }
+
+    prefs, err := c.emailPrefs.EmailPreferences(ctx.Request().Context(), address)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query email preferences")
+    }
+
+    return ctx.JSON(http.StatusOK, prefs)
+}
+
+// UpdateEmailPreferences - Choose the email notifications of the address of a signed link
+func (c *Container) UpdateEmailPreferences(ctx echo.Context) error {
+    address := c.verifiedEmail(ctx)
+    if address == "" {
+        return apierror.New(http.StatusForbidden, "Invalid or expired preferences link")
+    }
+
+    var req EmailPreferencesRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+
+    prefs, err := c.emailPrefs.EmailPreferences(ctx.Request().Context(), address)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query email preferences")
+    }
+    previous := prefs.Digest
+    if req.Digest != nil {
+        prefs.Digest = *req.Digest
+    }
+    prefs, err = c.emailPrefs.SetEmailPreferences(ctx.Request().Context(), prefs)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update email preferences")
+    }
+
+    if previous != prefs.Digest {
+        c.audit(sharedContext(ctx.Request().Context()), c.emailActor(ctx.Request().Context(), address), "email.preferences", "", "", map[string]interface{}{
+            "email":  address,
+            "digest": prefs.Digest,
+        })
+    }
+
+    return ctx.JSON(http.StatusOK, prefs)
+}
+
+func (s postgresStore) EmailPreferences(ctx context.Context, address string) (EmailPreferences, error) {
+    prefs := EmailPreferences{Email: address, Digest: true}
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return prefs, err
+    }
+
+    var updatedAt time.Time
+    err = db.QueryRowContext(ctx, "SELECT digest, updated_at FROM email_preferences WHERE email = $1", address).
+        Scan(&prefs.Digest, &updatedAt)
+    if err == sql.ErrNoRows {
+        return prefs, nil
+    }
+    if err != nil {
+        return prefs, err
+    }
+    prefs.UpdatedAt = &updatedAt
+    return prefs, nil
+}
+
+func (s postgresStore) SetEmailPreferences(ctx context.Context, prefs EmailPreferences) (EmailPreferences, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return prefs, err
+    }
+
+    var updatedAt time.Time
+    err = db.QueryRowContext(ctx, `
+        INSERT INTO email_preferences (email, digest, updated_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (email) DO UPDATE SET
+            digest = EXCLUDED.digest,
+            updated_at = EXCLUDED.updated_at
+        RETURNING updated_at
+    `, prefs.Email, prefs.Digest).Scan(&updatedAt)
+    prefs.UpdatedAt = &updatedAt
+    return prefs, err
+}
+
+func (s postgresStore) UserByEmail(ctx context.Context, address string) (string, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return "", err
+    }
+
+    var userID string
+    err = db.QueryRowContext(ctx, "SELECT user_id FROM user_directory WHERE LOWER(email) = $1 LIMIT 1", address).Scan(&userID)
+    if err == sql.ErrNoRows {
+        return "", nil
+    }
+    return userID, err
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/exports"
+)
+
+func TestUnsubscribeLinksAreSigned(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddProfile(UserProfile{UserProfile: domain.UserProfile{UserID: "U1", Email: "Alice@example.com"}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.publicURL = "https://threads.example.com"
+    c.emailSigner = exports.NewSigner("key")
+
+    links := c.emailLinksFor(" Alice@Example.com ")
+    if !strings.HasPrefix(links.Unsubscribe, "https://threads.example.com/email/unsubscribe?") ||
+        !strings.HasPrefix(links.Preferences, "https://threads.example.com/email/preferences?") {
+        t.Fatalf("got links %+v", links)
+    }
+    if header := c.listUnsubscribeHeaders("alice@example.com")["List-Unsubscribe"]; !strings.Contains(header, "/email/unsubscribe?") {
+        t.Fatalf("got List-Unsubscribe %q", header)
+    }
+    signed, err := url.Parse(links.Unsubscribe)
+    if err != nil {
+        t.Fatal(err)
+    }
+    query := signed.Query()
+    if query.Get("email") != "alice@example.com" {
+        t.Fatalf("link is for %q, want the normalized address", query.Get("email"))
+    }
+
+    // with returns the query of the signed link with key set to value
+    with := func(key string, value string) string {
+        changed := url.Values{}
+        for k, v := range query {
+            changed[k] = v
+        }
+        changed.Set(key, value)
+        return "/email/unsubscribe?" + changed.Encode()
+    }
+    expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expired := time.Now().Add(-time.Minute)
+    tampered := []byte(query.Get("signature"))
+    tampered[0] ^= 1
+
+    tests := []struct {
+        name   string
+        target string
+    }{
+        {"other address", with("email", "bob@example.com")},
+        {"extended expiry", with("expires", strconv.FormatInt(time.Now().Add(emailLinkTTL+time.Hour).Unix(), 10))},
+        {"tampered signature", with("signature", string(tampered))},
+        {"expired link", "/email/unsubscribe?" + url.Values{
+            "email":     {"alice@example.com"},
+            "expires":   {strconv.FormatInt(expired.Unix(), 10)},
+            "signature": {c.emailSigner.Sign("email:alice@example.com", expired)},
+        }.Encode()},
+        {"link signed for an export", with("signature", c.emailSigner.Sign("alice@example.com", time.Unix(expires, 0)))},
+        {"unsigned link", "/email/unsubscribe?email=alice%40example.com"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rec := serveRequest(t, c, http.MethodPost, "/email/unsubscribe", tt.target, c.UnsubscribeEmail)
+            if rec.Code != http.StatusForbidden {
+                t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body.String())
+            }
+            if prefs, _ := store.EmailPreferences(context.Background(), "alice@example.com"); prefs.UpdatedAt != nil {
+                t.Fatal("a refused link changed the preferences")
+            }
+        })
+    }
+
+    rec := serveRequest(t, c, http.MethodPost, "/email/unsubscribe", "/email/unsubscribe?"+signed.RawQuery, c.UnsubscribeEmail)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    if prefs, _ := store.EmailPreferences(context.Background(), "alice@example.com"); prefs.Digest {
+        t.Fatal("the signed link did not unsubscribe the address")
+    }
+    if audited := store.Audited(); len(audited) != 1 || audited[0].Action != "email.unsubscribe" || audited[0].Actor != "U1" {
+        t.Fatalf("audited %+v, want the unsubscribe by the user of the address", audited)
+    }
+    // The same link opens the preferences of the address
+    rec = serveRequest(t, c, http.MethodGet, "/email/preferences", "/email/preferences?"+signed.RawQuery, c.GetEmailPreferences)
+    var prefs EmailPreferences
+    decodeResponse(t, rec, &prefs)
+    if rec.Code != http.StatusOK || prefs.Email != "alice@example.com" || prefs.Digest {
+        t.Fatalf("got status %d and preferences %+v", rec.Code, prefs)
+    }
+}
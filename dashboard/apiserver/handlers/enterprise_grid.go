@@ -0,0 +1,183 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+const gridDiscoveryInterval = time.Hour
+
+// enterpriseGrid is the Enterprise Grid the bot token belongs to. An
+// org-wide install covers every workspace of the grid it was granted, a
+// workspace install only its own.
+type enterpriseGrid struct {
+    mu                  sync.RWMutex
+    enterpriseID        string
+    isEnterpriseInstall bool
+    teams               []slack.Team
+    discoveredAt        time.Time
+
+    // userIDs caches the global ID of local user IDs, keyed by team and
+    // user
+    userIDs sync.Map
+}
+
+// GridStatus is the Enterprise Grid reported by GetEnterpriseGrid.
+type GridStatus struct {
+    EnterpriseID        string       `json:"enterprise_id"`
+    IsEnterpriseInstall bool         `json:"is_enterprise_install"`
+    Workspaces          []slack.Team `json:"workspaces"`
+    DiscoveredAt        *time.Time   `json:"discovered_at"`
+}
+
+// discoverGrid looks up the grid of the bot token and the workspaces the
+// install covers.
+func (c *Container) discoverGrid(ctx context.Context) error {
+    identity, err := c.slack.Identity(ctx)
+    if err != nil {
+        return err
+    }
+
+    teams := []slack.Team{}
+    if identity.IsEnterpriseInstall {
+        cursor := ""
+        for {
+            page, err := c.slack.AuthTeamsList(ctx, cursor, 100)
+            if err != nil {
+                return err
+            }
+            teams = append(teams, page.Teams...)
+            cursor = page.ResponseMetadata.NextCursor
+            if cursor == "" {
+                break
+            }
+        }
+    } else if identity.TeamID != "" {
+        teams = append(teams, slack.Team{ID: identity.TeamID, Name: identity.Team})
+    }
+
+    c.grid.mu.Lock()
+    changed := len(teams) != len(c.grid.teams)
+    c.grid.enterpriseID = identity.EnterpriseID
+    c.grid.isEnterpriseInstall = identity.IsEnterpriseInstall
+    c.grid.teams = teams
+    c.grid.discoveredAt = time.Now().UTC()
+    c.grid.mu.Unlock()
+
+    if identity.IsEnterpriseInstall && changed {
+        c.logger.Infof("org-wide install of Enterprise Grid %s covers %d workspaces", identity.EnterpriseID, len(teams))
+    }
+    return nil
+}
+
+// RunGridDiscovery looks up the workspaces of the grid hourly, picking up
+// workspaces the org-wide install is granted later, until ctx is
+// cancelled. It does nothing without a bot token.
+func (c *Container) RunGridDiscovery(ctx context.Context) {
+    if !c.slack.Configured() {
+        return
+    }
+
+    ticker := time.NewTicker(gridDiscoveryInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.discoverGrid(ctx); err != nil && ctx.Err() == nil {
+            c.logger.Warnf("failed to discover the workspaces of the Slack install: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// gridTeams returns the workspaces whose channels are enumerated. A single
+// empty ID stands for the workspace of the token when it is not an
+// org-wide install.
+func (c *Container) gridTeams() []string {
+    c.grid.mu.RLock()
+    defer c.grid.mu.RUnlock()
+
+    if !c.grid.isEnterpriseInstall {
+        return []string{""}
+    }
+    teams := make([]string, 0, len(c.grid.teams))
+    for _, team := range c.grid.teams {
+        teams = append(teams, team.ID)
+    }
+    return teams
+}
+
+// sameGrid reports whether two workspaces belong to the grid of the
+// install, whose users are colleagues rather than Slack Connect guests.
+func (c *Container) sameGrid(teamA string, teamB string) bool {
+    c.grid.mu.RLock()
+    defer c.grid.mu.RUnlock()
+
+    if c.grid.enterpriseID == "" {
+        return false
+    }
+    var foundA, foundB bool
+    for _, team := range c.grid.teams {
+        foundA = foundA || team.ID == teamA
+        foundB = foundB || team.ID == teamB
+    }
+    return foundA && foundB
+}
+
+// globalUserID translates a user ID local to a workspace to the ID the user
+// has across the grid, so a user is the same in every workspace. IDs are
+// kept as they are outside of a grid or when Slack cannot translate them.
+func (c *Container) globalUserID(ctx context.Context, teamID string, userID string) string {
+    // Only workspaces migrated into a grid have local IDs, starting with U
+    if teamID == "" || !strings.HasPrefix(userID, "U") {
+        return userID
+    }
+    c.grid.mu.RLock()
+    enterpriseID := c.grid.enterpriseID
+    c.grid.mu.RUnlock()
+    if enterpriseID == "" || !c.slackFeatureReady(slackFeatureGridUserIDs) {
+        return userID
+    }
+
+    key := teamID + ":" + userID
+    if global, ok := c.grid.userIDs.Load(key); ok {
+        return global.(string)
+    }
+    ids, err := c.slack.MigrationExchange(ctx, teamID, []string{userID})
+    if err != nil {
+        c.logger.Warnf("failed to translate user %s of workspace %s to a grid ID: %v", userID, teamID, err)
+        return userID
+    }
+    global := userID
+    if id, ok := ids[userID]; ok && id != "" {
+        global = id
+    }
+    c.grid.userIDs.Store(key, global)
+    return global
+}
+
+// GetEnterpriseGrid - Get the Enterprise Grid of the Slack install and the workspaces it covers
+func (c *Container) GetEnterpriseGrid(ctx echo.Context) error {
+    c.grid.mu.RLock()
+    status := GridStatus{
+        EnterpriseID:        c.grid.enterpriseID,
+        IsEnterpriseInstall: c.grid.isEnterpriseInstall,
+        Workspaces:          append([]slack.Team{}, c.grid.teams...),
+    }
+    if !c.grid.discoveredAt.IsZero() {
+        discoveredAt := c.grid.discoveredAt
+        status.DiscoveredAt = &discoveredAt
+    }
+    c.grid.mu.RUnlock()
+
+    return ctx.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+    "os"
+)
+
+// Secrets and settings read by the handlers.
+const (
+    slackSigningSecretEnv  = "YB_OPEN_THREADS_REMINDER_SLACK_SIGNING_SECRET"
+    githubWebhookSecretEnv = "YB_OPEN_THREADS_REMINDER_GITHUB_WEBHOOK_SECRET"
+    jiraWebhookSecretEnv   = "YB_OPEN_THREADS_REMINDER_JIRA_WEBHOOK_SECRET"
+    emailWebhookSecretEnv  = "YB_OPEN_THREADS_REMINDER_EMAIL_WEBHOOK_SECRET"
+    ingestQueueSizeEnv     = "YB_OPEN_THREADS_REMINDER_INGEST_QUEUE_SIZE"
+    ingestWorkersEnv       = "YB_OPEN_THREADS_REMINDER_INGEST_WORKERS"
+    ingestSpillDirEnv      = "YB_OPEN_THREADS_REMINDER_INGEST_SPILL_DIR"
+    ingestMaxSpilledEnv    = "YB_OPEN_THREADS_REMINDER_INGEST_MAX_SPILLED"
+    slackBotTokenEnv       = "YB_OPEN_THREADS_REMINDER_SLACK_BOT_TOKEN"
+    slackRateShareEnv      = "YB_OPEN_THREADS_REMINDER_SLACK_RATE_SHARE"
+    backfillConcurrencyEnv = "YB_OPEN_THREADS_REMINDER_BACKFILL_CONCURRENCY"
+    pollIntervalEnv        = "YB_OPEN_THREADS_REMINDER_POLL_INTERVAL"
+    pollMaxIntervalEnv     = "YB_OPEN_THREADS_REMINDER_POLL_MAX_INTERVAL"
+    suggestAfterEnv        = "YB_OPEN_THREADS_REMINDER_SUGGEST_AFTER"
+    remindAfterEnv         = "YB_OPEN_THREADS_REMINDER_REMIND_AFTER"
+    reminderBatchWindowEnv = "YB_OPEN_THREADS_REMINDER_BATCH_WINDOW"
+    exportDirEnv           = "YB_OPEN_THREADS_REMINDER_EXPORT_DIR"
+    exportSigningKeyEnv    = "YB_OPEN_THREADS_REMINDER_EXPORT_SIGNING_KEY"
+    exportThresholdEnv     = "YB_OPEN_THREADS_REMINDER_EXPORT_ASYNC_THRESHOLD"
+    exportTTLEnv           = "YB_OPEN_THREADS_REMINDER_EXPORT_TTL"
+    exportS3EndpointEnv    = "YB_OPEN_THREADS_REMINDER_EXPORT_S3_ENDPOINT"
+    exportS3BucketEnv      = "YB_OPEN_THREADS_REMINDER_EXPORT_S3_BUCKET"
+    exportS3PrefixEnv      = "YB_OPEN_THREADS_REMINDER_EXPORT_S3_PREFIX"
+    exportS3RegionEnv      = "YB_OPEN_THREADS_REMINDER_EXPORT_S3_REGION"
+    exportS3KeyIDEnv       = "YB_OPEN_THREADS_REMINDER_EXPORT_S3_ACCESS_KEY_ID"
+    exportS3SecretEnv      = "YB_OPEN_THREADS_REMINDER_EXPORT_S3_SECRET_ACCESS_KEY"
+    watchEmojiEnv          = "YB_OPEN_THREADS_REMINDER_WATCH_EMOJI"
+    githubTokenEnv         = "YB_OPEN_THREADS_REMINDER_GITHUB_TOKEN"
+    githubAPIURLEnv        = "YB_OPEN_THREADS_REMINDER_GITHUB_API_URL"
+    publicURLEnv           = "YB_OPEN_THREADS_REMINDER_PUBLIC_URL"
+    emailSigningKeyEnv     = "YB_OPEN_THREADS_REMINDER_EMAIL_SIGNING_KEY"
+    liveIntervalEnv        = "YB_OPEN_THREADS_REMINDER_LIVE_INTERVAL"
+    oidcIssuerEnv          = "YB_OPEN_THREADS_REMINDER_OIDC_ISSUER"
+    oidcClientIDEnv        = "YB_OPEN_THREADS_REMINDER_OIDC_CLIENT_ID"
+    oidcClientSecretEnv    = "YB_OPEN_THREADS_REMINDER_OIDC_CLIENT_SECRET"
+    oidcUserClaimEnv       = "YB_OPEN_THREADS_REMINDER_OIDC_USER_CLAIM"
+    signInTeamEnv          = "YB_OPEN_THREADS_REMINDER_SIGN_IN_TEAM"
+    signInDomainsEnv       = "YB_OPEN_THREADS_REMINDER_SIGN_IN_DOMAINS"
+    sessionTTLEnv          = "YB_OPEN_THREADS_REMINDER_SESSION_TTL"
+    defaultRoleEnv         = "YB_OPEN_THREADS_REMINDER_DEFAULT_ROLE"
+    reporterNudgeAfterEnv  = "YB_OPEN_THREADS_REMINDER_REPORTER_NUDGE_AFTER"
+    jiraURLEnv             = "YB_OPEN_THREADS_REMINDER_JIRA_URL"
+    jiraEmailEnv           = "YB_OPEN_THREADS_REMINDER_JIRA_EMAIL"
+    jiraTokenEnv           = "YB_OPEN_THREADS_REMINDER_JIRA_TOKEN"
+    aiProviderEnv          = "YB_OPEN_THREADS_REMINDER_AI_PROVIDER"
+    aiAPIKeyEnv            = "YB_OPEN_THREADS_REMINDER_AI_API_KEY"
+    aiModelEnv             = "YB_OPEN_THREADS_REMINDER_AI_MODEL"
+    aiBaseURLEnv           = "YB_OPEN_THREADS_REMINDER_AI_BASE_URL"
+    aiWorkersEnv           = "YB_OPEN_THREADS_REMINDER_AI_WORKERS"
+    aiRateEnv              = "YB_OPEN_THREADS_REMINDER_AI_REQUESTS_PER_MINUTE"
+    storageQuotaEnv        = "YB_OPEN_THREADS_REMINDER_STORAGE_QUOTA_MB"
+    storageMaxGrowthEnv    = "YB_OPEN_THREADS_REMINDER_STORAGE_MAX_GROWTH_MB_PER_DAY"
+    storageAlertChannelEnv = "YB_OPEN_THREADS_REMINDER_STORAGE_ALERT_CHANNEL"
+    uiPaletteEnv           = "YB_OPEN_THREADS_REMINDER_UI_PALETTE"
+    uiThemeEnv             = "YB_OPEN_THREADS_REMINDER_UI_THEME"
+    uiFeaturesEnv          = "YB_OPEN_THREADS_REMINDER_UI_FEATURES"
+    notificationRulesEnv   = "YB_OPEN_THREADS_REMINDER_NOTIFICATION_RULES"
+    notificationChannelEnv = "YB_OPEN_THREADS_REMINDER_NOTIFICATION_CHANNEL"
+    notificationURLEnv     = "YB_OPEN_THREADS_REMINDER_NOTIFICATION_WEBHOOK_URL"
+    teamsWebhookURLEnv     = "YB_OPEN_THREADS_REMINDER_TEAMS_WEBHOOK_URL"
+    smtpAddrEnv            = "YB_OPEN_THREADS_REMINDER_SMTP_ADDR"
+    smtpUsernameEnv        = "YB_OPEN_THREADS_REMINDER_SMTP_USERNAME"
+    smtpPasswordEnv        = "YB_OPEN_THREADS_REMINDER_SMTP_PASSWORD"
+    emailFromEnv           = "YB_OPEN_THREADS_REMINDER_EMAIL_FROM"
+    readOnlyEnv            = "YB_OPEN_THREADS_REMINDER_READ_ONLY"
+    digestScheduleEnv      = "YB_OPEN_THREADS_REMINDER_DIGEST_SCHEDULE"
+    digestTimeZoneEnv      = "YB_OPEN_THREADS_REMINDER_DIGEST_TIME_ZONE"
+    digestChannelsEnv      = "YB_OPEN_THREADS_REMINDER_DIGEST_CHANNELS"
+    digestEmailsEnv        = "YB_OPEN_THREADS_REMINDER_DIGEST_EMAILS"
+    rateLimitReadsEnv      = "YB_OPEN_THREADS_REMINDER_RATE_LIMIT_READS"
+    rateLimitWritesEnv     = "YB_OPEN_THREADS_REMINDER_RATE_LIMIT_WRITES"
+    staleCacheEnv          = "YB_OPEN_THREADS_REMINDER_STALE_CACHE_MB"
+    statsCacheTTLEnv       = "YB_OPEN_THREADS_REMINDER_STATS_CACHE_TTL"
+    eventBusEnv            = "YB_OPEN_THREADS_REMINDER_EVENT_BUS"
+    eventBusURLEnv         = "YB_OPEN_THREADS_REMINDER_EVENT_BUS_URL"
+    eventBusPrefixEnv      = "YB_OPEN_THREADS_REMINDER_EVENT_BUS_PREFIX"
+)
+
+func getEnv(key, fallback string) string {
+    if value, ok := os.LookupEnv(key); ok {
+        return value
+    }
+    return fallback
+}
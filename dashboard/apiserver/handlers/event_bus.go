@@ -0,0 +1,116 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "dashboard/apiserver/events"
+    "dashboard/apiserver/logger"
+    "dashboard/apiserver/webhooks"
+)
+
+// newEventBus returns the bus configured by YB_OPEN_THREADS_REMINDER_EVENT_BUS,
+// in memory unless instances deployed apart share NATS JetStream or Kafka.
+func newEventBus(log logger.Logger) (events.Bus, error) {
+    kind := getEnv(eventBusEnv, "memory")
+    prefix := getEnv(eventBusPrefixEnv, "open-threads")
+    switch kind {
+    case "memory":
+        return events.NewMemoryBus(log), nil
+    case "nats":
+        ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+        defer cancel()
+        return events.NewNATSBus(ctx, log, events.NATSConfig{
+            URL:    getEnv(eventBusURLEnv, "nats://localhost:4222"),
+            Prefix: prefix,
+        })
+    case "kafka":
+        return events.NewKafkaBus(log, events.KafkaConfig{
+            Brokers: listParam(getEnv(eventBusURLEnv, "localhost:9092")),
+            Prefix:  prefix,
+        }), nil
+    }
+    return nil, fmt.Errorf("unknown event bus %q, use memory, nats or kafka", kind)
+}
+
+// publish puts event on the bus. Failures are logged, publishing never
+// fails what the event tells about.
+func (c *Container) publish(ctx context.Context, event events.Event) {
+    if err := c.bus.Publish(ctx, event); err != nil {
+        c.logger.Warnf("failed to publish %s event: %v", event.Topic, err)
+    }
+}
+
+// subscribeEvents connects the subsystems reacting to events to the bus.
+// Reminders subscribe while they run, see RunReminders.
+func (c *Container) subscribeEvents() error {
+    // Webhooks are delivered and summaries queued by one instance, every
+    // instance updates its own live clients and stats cache
+    subscriptions := []struct {
+        topic   string
+        name    string
+        handler events.Handler
+        options []events.SubscribeOption
+    }{
+        {events.TopicAction, "webhooks", c.deliverActionWebhooks, nil},
+        {events.TopicThreadMessage, "thread webhooks", c.deliverMessageWebhooks, nil},
+        {events.TopicThreadMessage, "live updates", c.pokeLiveUpdates, []events.SubscribeOption{events.PerInstance()}},
+        {events.TopicThreadAnalyzed, "live updates", c.pokeLiveUpdates, []events.SubscribeOption{events.PerInstance()}},
+        {events.TopicAction, "channel summaries", c.queueChannelSummary, nil},
+        {events.TopicAction, "stats cache", c.invalidateStatsCache, []events.SubscribeOption{events.PerInstance()}},
+        {events.TopicThreadMessage, "stats cache", c.invalidateStatsCache, []events.SubscribeOption{events.PerInstance()}},
+        {events.TopicThreadAnalyzed, "stats cache", c.invalidateStatsCache, []events.SubscribeOption{events.PerInstance()}},
+        {events.TopicThreadMessage, "channel summaries", c.queueChannelSummary, nil},
+        {events.TopicThreadAnalyzed, "channel summaries", c.queueChannelSummary, nil},
+    }
+    for _, sub := range subscriptions {
+        if _, err := c.bus.Subscribe(sub.topic, sub.name, sub.handler, sub.options...); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// deliverActionWebhooks posts audited actions to the webhooks subscribed
+// to them.
+func (c *Container) deliverActionWebhooks(ctx context.Context, event events.Event) {
+    c.publishEvent(withWorkspace(ctx, event.Workspace), webhooks.Event{
+        Action:     event.Action,
+        Actor:      event.Actor,
+        ChannelID:  event.ChannelID,
+        ThreadTS:   event.ThreadTS,
+        Details:    event.Details,
+        OccurredAt: event.OccurredAt,
+    })
+}
+
+// deliverMessageWebhooks posts the replies of threads to their webhooks.
+func (c *Container) deliverMessageWebhooks(ctx context.Context, event events.Event) {
+    if reply, _ := event.Details["reply"].(bool); !reply {
+        return
+    }
+    messageTS, _ := event.Details["message_ts"].(string)
+    c.publishThreadEvent(withWorkspace(ctx, event.Workspace), threadMessageEvent(event.ChannelID, event.ThreadTS, event.Actor, messageTS, event.OccurredAt))
+}
+
+// pokeLiveUpdates has live updates look for the change an event tells
+// about without waiting for the next poll.
+func (c *Container) pokeLiveUpdates(ctx context.Context, event events.Event) {
+    c.live.poke(event.Workspace)
+}
+
+// publishMessage publishes a message ingested in a thread, its root when
+// not a reply.
+func (c *Container) publishMessage(ctx context.Context, channelID, threadTS, user, ts string, postedAt time.Time, reply bool) {
+    c.publish(ctx, events.Event{
+        Topic:      events.TopicThreadMessage,
+        Workspace:  workspaceFrom(ctx),
+        Action:     threadEventMessage,
+        Actor:      user,
+        ChannelID:  channelID,
+        ThreadTS:   threadTS,
+        Details:    map[string]interface{}{"message_ts": ts, "reply": reply},
+        OccurredAt: postedAt.UTC(),
+    })
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+    "fmt"
+    "time"
+
+    "dashboard/apiserver/ingest"
+)
+
+// eventDedupStore is an ingest.DedupStore shared by every apiserver replica,
+// so a Slack retry landing on another replica is still recognized.
+type eventDedupStore struct {
+    c   *Container
+    ttl time.Duration
+}
+
+func newEventDedupStore(c *Container, ttl time.Duration) *eventDedupStore {
+    return &eventDedupStore{c: c, ttl: ttl}
+}
+
+func (s *eventDedupStore) MarkSeen(key string) (bool, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return false, err
+    }
+
+    // An expired key is taken over as if it was new
+    result, err := db.Exec(fmt.Sprintf(`
+        INSERT INTO slack_event_dedup (dedup_key, seen_at) VALUES ($1, NOW())
+        ON CONFLICT (dedup_key) DO UPDATE SET seen_at = EXCLUDED.seen_at
+        WHERE slack_event_dedup.seen_at < NOW() - INTERVAL '%d seconds'
+    `, int(s.ttl.Seconds())), key)
+    if err != nil {
+        return false, err
+    }
+    n, err := result.RowsAffected()
+    if err != nil {
+        return false, err
+    }
+    return n == 0, nil
+}
+
+func (s *eventDedupStore) Forget(key string) error {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return err
+    }
+
+    _, err = db.Exec("DELETE FROM slack_event_dedup WHERE dedup_key = $1", key)
+    return err
+}
+
+// Prune deletes keys older than the TTL.
+func (s *eventDedupStore) Prune() error {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return err
+    }
+
+    _, err = db.Exec(fmt.Sprintf(
+        "DELETE FROM slack_event_dedup WHERE seen_at < NOW() - INTERVAL '%d seconds'",
+        int(s.ttl.Seconds())))
+    return err
+}
+
+// Ensure that DedupStore interface is implemented
+var _ ingest.DedupStore = (*eventDedupStore)(nil)
@@ -0,0 +1,698 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/exports"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Export job states.
+const (
+    exportPending   = "pending"
+    exportRunning   = "running"
+    exportCompleted = "completed"
+    exportFailed    = "failed"
+    exportExpired   = "expired"
+)
+
+// errExportNotFound is returned for export jobs that do not exist.
+var errExportNotFound = errors.New("export not found")
+
+const (
+    exportWorkers         = 2
+    exportPollInterval    = 30 * time.Second
+    exportCleanupInterval = time.Hour
+)
+
+// exportColumns are the columns of a thread export, in order.
+var exportColumns = []string{
+    "channel_id", "channel_name", "thread_ts", "user_id", "status", "priority",
+    "reply_count", "created_at", "latest_reply", "ai_thread_name", "ai_description",
+    "github_issue", "jira_ticket",
+}
+
+// exportContentTypes lists the supported export formats.
+var exportContentTypes = map[string]string{
+    "csv":  "text/csv",
+    "json": "application/json",
+}
+
+// ExportRequest is the body accepted by CreateExport
+type ExportRequest struct {
+    Format   string `json:"format"`
+    Channel  string `json:"channel"`
+    Priority string `json:"priority"`
+    Status   string `json:"status"`
+}
+
+// ExportJob represents an asynchronous export
+type ExportJob struct {
+    ID          int64      `json:"id"`
+    Status      string     `json:"status"`
+    Format      string     `json:"format"`
+    RequestedBy string     `json:"requested_by"`
+    RowCount    int        `json:"row_count"`
+    SizeBytes   int64      `json:"size_bytes"`
+    Error       string     `json:"error,omitempty"`
+    CreatedAt   time.Time  `json:"created_at"`
+    FinishedAt  *time.Time `json:"finished_at"`
+    ExpiresAt   *time.Time `json:"expires_at"`
+    DownloadURL string     `json:"download_url,omitempty"`
+
+    filters   ExportRequest
+    objectKey string
+}
+
+// exportRow is a thread as written to an export.
+type exportRow struct {
+    ChannelID     string    `json:"channel_id"`
+    ChannelName   string    `json:"channel_name"`
+    ThreadTS      string    `json:"thread_ts"`
+    UserID        string    `json:"user_id"`
+    Status        string    `json:"status"`
+    Priority      string    `json:"priority"`
+    ReplyCount    int       `json:"reply_count"`
+    CreatedAt     time.Time `json:"created_at"`
+    LatestReply   time.Time `json:"latest_reply"`
+    AIThreadName  *string   `json:"ai_thread_name"`
+    AIDescription *string   `json:"ai_description"`
+    GithubIssue   *string   `json:"github_issue"`
+    JiraTicket    *string   `json:"jira_ticket"`
+}
+
+func (r exportRow) record() []string {
+    deref := func(s *string) string {
+        if s == nil {
+            return ""
+        }
+        return *s
+    }
+    return []string{
+        r.ChannelID, r.ChannelName, r.ThreadTS, r.UserID, r.Status, r.Priority,
+        strconv.Itoa(r.ReplyCount), r.CreatedAt.UTC().Format(time.RFC3339), r.LatestReply.UTC().Format(time.RFC3339),
+        deref(r.AIThreadName), deref(r.AIDescription), deref(r.GithubIssue), deref(r.JiraTicket),
+    }
+}
+
+// exportChannel is an exported channel with what changes how its threads
+// are shown, like in thread lists.
+type exportChannel struct {
+    trackedChannel
+    minConfidence float64
+    // external is set on Slack Connect channels whose content the AI may
+    // not use
+    external bool
+}
+
+// redact drops the text of a thread its channel does not let be served.
+func (ch exportChannel) redact(row *exportRow) {
+    // Text the channel opted out of, and the analysis of content shared by
+    // other workspaces, as thread lists hide them
+    if !ch.TextIndexing || ch.external {
+        row.AIThreadName = nil
+        row.AIDescription = nil
+    }
+}
+
+// exportQuery returns the query and arguments selecting the threads of a
+// channel for a request, with their effective priority, which the priority
+// filter applies to.
+func exportQuery(req ExportRequest, ch exportChannel) (string, []interface{}) {
+    where := "WHERE 1=1"
+    args := []interface{}{ch.ID, ch.minConfidence}
+    if req.Priority != "" {
+        args = append(args, req.Priority)
+        where += fmt.Sprintf(" AND priority = $%d", len(args))
+    }
+    if req.Status != "" {
+        args = append(args, req.Status)
+        where += fmt.Sprintf(" AND status = $%d", len(args))
+    }
+    return fmt.Sprintf(`
+        SELECT * FROM (
+            SELECT t.thread_ts, t.user_id, t.status, %s, t.reply_count,
+                   t.created_at, t.latest_reply, t.ai_thread_name, t.ai_description, t.github_issue, t.jira_ticket
+            FROM threads t WHERE t.channel_id = $1) exported
+        %s`, priorityColumn("$2"), where), args
+}
+
+// countExportRows returns how many threads an export would contain.
+func (c *Container) countExportRows(ctx context.Context, req ExportRequest) (int, error) {
+    channels, err := c.exportRecords.ExportChannels(ctx, req)
+    if err != nil {
+        return 0, err
+    }
+
+    total := 0
+    for _, ch := range channels {
+        count, err := c.exportRecords.CountExportRows(ctx, req, ch)
+        if err != nil {
+            return 0, err
+        }
+        total += count
+    }
+    return total, nil
+}
+
+// writeExport streams the threads selected by req to w and returns how
+// many were written.
+func (c *Container) writeExport(ctx context.Context, req ExportRequest, w io.Writer) (int, error) {
+    channels, err := c.exportRecords.ExportChannels(ctx, req)
+    if err != nil {
+        return 0, err
+    }
+
+    var csvWriter *csv.Writer
+    if req.Format == "csv" {
+        csvWriter = csv.NewWriter(w)
+        if err := csvWriter.Write(exportColumns); err != nil {
+            return 0, err
+        }
+    } else if _, err := io.WriteString(w, "["); err != nil {
+        return 0, err
+    }
+
+    written := 0
+    for _, ch := range channels {
+        err := c.exportRecords.ExportRows(ctx, req, ch, func(row exportRow) error {
+            ch.redact(&row)
+
+            var err error
+            if csvWriter != nil {
+                err = csvWriter.Write(row.record())
+            } else {
+                var encoded []byte
+                encoded, err = json.Marshal(row)
+                if err == nil && written > 0 {
+                    _, err = io.WriteString(w, ",")
+                }
+                if err == nil {
+                    _, err = w.Write(encoded)
+                }
+            }
+            if err != nil {
+                return err
+            }
+            written++
+            return nil
+        })
+        if err != nil {
+            return written, err
+        }
+    }
+
+    if csvWriter != nil {
+        csvWriter.Flush()
+        return written, csvWriter.Error()
+    }
+    _, err = io.WriteString(w, "]")
+    return written, err
+}
+
+// CreateExport - Export threads, asynchronously when the export is large
+//
+// Exports of at most the async threshold rows are returned directly. Larger
+// ones create a job answered with 202, to be polled with GetExport.
+func (c *Container) CreateExport(ctx echo.Context) error {
+    var req ExportRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if req.Format == "" {
+        req.Format = "csv"
+    }
+    contentType, ok := exportContentTypes[req.Format]
+    if !ok {
+        return apierror.New(http.StatusBadRequest, "format must be csv or json")
+    }
+
+    count, err := c.countExportRows(ctx.Request().Context(), req)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to count threads")
+    }
+
+    if count <= c.exportThreshold {
+        resp := ctx.Response()
+        resp.Header().Set(echo.HeaderContentType, contentType)
+        resp.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="threads.%s"`, req.Format))
+        resp.WriteHeader(http.StatusOK)
+        if _, err := c.writeExport(ctx.Request().Context(), req, resp); err != nil {
+            c.logger.Errorf("failed to write export: %v", err)
+        }
+        return nil
+    }
+
+    job, err := c.exportRecords.CreateExport(ctx.Request().Context(), ExportJob{
+        Status:      exportPending,
+        Format:      req.Format,
+        RequestedBy: actorFrom(ctx),
+        RowCount:    count,
+        filters:     req,
+    })
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to create export job")
+    }
+
+    c.queueExport(exportRef{workspace: workspaceFrom(ctx.Request().Context()), id: job.ID})
+
+    return ctx.JSON(http.StatusAccepted, job)
+}
+
+// GetExport - Get the status of an export job and, once done, its download link
+func (c *Container) GetExport(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid export id")
+    }
+
+    job, err := c.exportRecords.Export(ctx.Request().Context(), id)
+    if err == errExportNotFound {
+        return apierror.New(http.StatusNotFound, "Export not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to load export")
+    }
+
+    // Jobs are private to their requester, admins see all of them
+    if principal, ok := auth.PrincipalFrom(ctx); ok && principal.UserID != job.RequestedBy && !principal.Has(auth.ScopeAdmin) {
+        return apierror.New(http.StatusNotFound, "Export not found")
+    }
+
+    if job.Status == exportCompleted && job.ExpiresAt != nil {
+        id := strconv.FormatInt(job.ID, 10)
+        workspace := workspaceFrom(ctx.Request().Context())
+        job.DownloadURL = fmt.Sprintf("/exports/%s/download?expires=%d&signature=%s",
+            id, job.ExpiresAt.Unix(), c.exportSigner.Sign(exportSubject(workspace, id), *job.ExpiresAt))
+        if workspace != "" {
+            job.DownloadURL += "&" + workspaceParam + "=" + url.QueryEscape(workspace)
+        }
+    }
+
+    return ctx.JSON(http.StatusOK, job)
+}
+
+// exportSubject is what download links of an export sign, binding the
+// link to the database the job is in.
+func exportSubject(workspace string, id string) string {
+    if workspace == "" {
+        return id
+    }
+    return workspace + ":" + id
+}
+
+// DownloadExport - Download the file of a completed export through a signed link
+func (c *Container) DownloadExport(ctx echo.Context) error {
+    workspace := ctx.QueryParam(workspaceParam)
+    if !c.exportSigner.Verify(exportSubject(workspace, ctx.Param("id")), ctx.QueryParam("expires"), ctx.QueryParam("signature")) {
+        return apierror.New(http.StatusForbidden, "Invalid or expired download link")
+    }
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid export id")
+    }
+
+    job, err := c.exportRecords.Export(withWorkspace(ctx.Request().Context(), workspace), id)
+    if err != nil || job.Status != exportCompleted {
+        return apierror.New(http.StatusNotFound, "Export not found")
+    }
+
+    file, err := c.exportStore.Open(job.objectKey)
+    if err == exports.ErrNotFound {
+        return apierror.New(http.StatusNotFound, "Export file no longer exists")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to open export file")
+    }
+    defer file.Close()
+
+    ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="threads-%d.%s"`, job.ID, job.Format))
+    return ctx.Stream(http.StatusOK, exportContentTypes[job.Format], file)
+}
+
+// exportRef identifies an export job in the database of its workspace.
+type exportRef struct {
+    workspace string
+    id        int64
+}
+
+// queueExport hands an export job to a free worker without waiting for one.
+// Jobs no worker was free for stay pending and are queued again when
+// RunExports next polls.
+func (c *Container) queueExport(ref exportRef) {
+    select {
+    case c.exportJobs <- ref:
+    default:
+    }
+}
+
+// queuePendingExports queues the pending export jobs of every workspace.
+// Jobs left running by a restart are made pending again first when resume
+// is set.
+func (c *Container) queuePendingExports(ctx context.Context, resume bool) {
+    for _, dbCtx := range c.WorkspaceContexts(ctx) {
+        ids, err := c.exportRecords.PendingExports(dbCtx, resume)
+        if err != nil {
+            c.logger.Warnf("failed to list pending exports: %v", err)
+            continue
+        }
+        for _, id := range ids {
+            c.queueExport(exportRef{workspace: workspaceFrom(dbCtx), id: id})
+        }
+    }
+}
+
+// RunExports processes export jobs and removes expired export files until
+// ctx is cancelled, returning once the exports in progress have finished.
+// Jobs interrupted by a restart are started again.
+func (c *Container) RunExports(ctx context.Context) {
+    var wg sync.WaitGroup
+    for i := 0; i < exportWorkers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for {
+                select {
+                case <-ctx.Done():
+                    return
+                case ref := <-c.exportJobs:
+                    c.processExport(withWorkspace(ctx, ref.workspace), ref.id)
+                }
+            }
+        }()
+    }
+
+    poll := time.NewTicker(exportPollInterval)
+    defer poll.Stop()
+    cleanup := time.NewTicker(exportCleanupInterval)
+    defer cleanup.Stop()
+    c.queuePendingExports(ctx, true)
+    for {
+        select {
+        case <-ctx.Done():
+            wg.Wait()
+            return
+        case <-poll.C:
+            c.queuePendingExports(ctx, false)
+        case <-cleanup.C:
+            for _, dbCtx := range c.WorkspaceContexts(ctx) {
+                c.cleanupExports(dbCtx)
+            }
+        }
+    }
+}
+
+func (c *Container) processExport(ctx context.Context, id int64) {
+    // A job queued twice, e.g. by a poll, runs once
+    started, err := c.exportRecords.StartExport(ctx, id)
+    if err != nil {
+        c.logger.Errorf("failed to start export %d: %v", id, err)
+        return
+    }
+    if !started {
+        return
+    }
+    job, err := c.exportRecords.Export(ctx, id)
+    if err != nil {
+        c.logger.Errorf("failed to load export %d: %v", id, err)
+        return
+    }
+
+    // Job IDs are only unique within a database
+    key := fmt.Sprintf("export-%d.%s", id, job.Format)
+    if workspace := workspaceFrom(ctx); workspace != "" {
+        key = fmt.Sprintf("export-%s-%d.%s", workspace, id, job.Format)
+    }
+    pr, pw := io.Pipe()
+    var rowCount int
+    go func() {
+        var err error
+        rowCount, err = c.writeExport(ctx, job.filters, pw)
+        pw.CloseWithError(err)
+    }()
+    size, err := c.exportStore.Put(key, pr)
+    pr.Close()
+
+    if err != nil {
+        if ctx.Err() != nil {
+            // Shutting down, the job stays running and is resumed
+            return
+        }
+        c.logger.Errorf("export %d failed: %v", id, err)
+        if err := c.exportRecords.FailExport(ctx, id, err.Error()); err != nil {
+            c.logger.Warnf("failed to record the failure of export %d: %v", id, err)
+        }
+        return
+    }
+
+    err = c.exportRecords.CompleteExport(ctx, id, rowCount, size, key, time.Now().UTC().Add(c.exportTTL))
+    if err != nil {
+        c.logger.Errorf("failed to complete export %d: %v", id, err)
+        return
+    }
+    c.logger.Infof("export %d completed with %d threads", id, rowCount)
+}
+
+// cleanupExports deletes the files of expired exports. Exports of held
+// channels or threads are kept until the hold is released.
+func (c *Container) cleanupExports(ctx context.Context) {
+    expired, err := c.exportRecords.ExpiredExports(ctx)
+    if err != nil {
+        c.logger.Warnf("failed to clean up exports: %v", err)
+        return
+    }
+
+    removed := 0
+    for _, job := range expired {
+        // An export without a channel filter holds every channel
+        if err := c.checkLegalHold(ctx, "system", "export.expire", job.filters.Channel, ""); err == errLegalHold {
+            continue
+        } else if err != nil {
+            c.logger.Warnf("failed to check legal holds of export %d: %v", job.ID, err)
+            continue
+        }
+        if err := c.exportStore.Delete(job.objectKey); err != nil {
+            c.logger.Warnf("failed to delete export file %s: %v", job.objectKey, err)
+            continue
+        }
+        if err := c.exportRecords.ExpireExport(ctx, job.ID); err != nil {
+            c.logger.Warnf("failed to expire export %d: %v", job.ID, err)
+            continue
+        }
+        removed++
+    }
+    if removed > 0 {
+        c.logger.Infof("removed %d expired exports", removed)
+    }
+}
+
+func (s postgresStore) ExportChannels(ctx context.Context, req ExportRequest) ([]exportChannel, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    channels, err := trackedChannels(ctx, db)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT channel_id, priority_calibration, COALESCE(slack_connect, FALSE) AND NOT COALESCE(slack_connect_ai, FALSE)
+        FROM channel_config`)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    calibrations := map[string]PriorityCalibration{}
+    external := map[string]bool{}
+    for rows.Next() {
+        var channelID string
+        var storedCalibration sql.NullString
+        var shared bool
+        if err := rows.Scan(&channelID, &storedCalibration, &shared); err != nil {
+            return nil, err
+        }
+        calibrations[channelID] = parsePriorityCalibration(storedCalibration)
+        external[channelID] = shared
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    selected := []exportChannel{}
+    for _, ch := range channels {
+        if req.Channel == "" || ch.Name == req.Channel || ch.ID == req.Channel {
+            selected = append(selected, exportChannel{
+                trackedChannel: ch,
+                minConfidence:  calibrations[ch.ID].MinConfidence,
+                external:       external[ch.ID],
+            })
+        }
+    }
+    return selected, nil
+}
+
+func (s postgresStore) CountExportRows(ctx context.Context, req ExportRequest, ch exportChannel) (int, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return 0, err
+    }
+
+    query, args := exportQuery(req, ch)
+    var count int
+    err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+query+") counted", args...).Scan(&count)
+    return count, err
+}
+
+func (s postgresStore) ExportRows(ctx context.Context, req ExportRequest, ch exportChannel, fn func(exportRow) error) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    query, args := exportQuery(req, ch)
+    rows, err := db.QueryContext(ctx, query+" ORDER BY created_at", args...)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        row := exportRow{ChannelID: ch.ID, ChannelName: ch.Name}
+        if err := rows.Scan(&row.ThreadTS, &row.UserID, &row.Status, &row.Priority, &row.ReplyCount,
+            &row.CreatedAt, &row.LatestReply, &row.AIThreadName, &row.AIDescription,
+            &row.GithubIssue, &row.JiraTicket); err != nil {
+            continue
+        }
+        if err := fn(row); err != nil {
+            return err
+        }
+    }
+    return rows.Err()
+}
+
+func (s postgresStore) CreateExport(ctx context.Context, job ExportJob) (ExportJob, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ExportJob{}, err
+    }
+
+    filters, _ := json.Marshal(job.filters)
+    err = db.QueryRowContext(ctx, `
+        INSERT INTO export_jobs (requested_by, format, filters, status, row_count)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at
+    `, job.RequestedBy, job.Format, string(filters), job.Status, job.RowCount).Scan(&job.ID, &job.CreatedAt)
+    return job, err
+}
+
+func (s postgresStore) Export(ctx context.Context, id int64) (ExportJob, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ExportJob{}, err
+    }
+
+    var job ExportJob
+    var filters string
+    var errMsg, objectKey sql.NullString
+    var sizeBytes sql.NullInt64
+    err = db.QueryRowContext(ctx, `
+        SELECT id, status, format, requested_by, row_count, size_bytes, error, filters,
+               object_key, created_at, finished_at, expires_at
+        FROM export_jobs WHERE id = $1
+    `, id).Scan(&job.ID, &job.Status, &job.Format, &job.RequestedBy, &job.RowCount, &sizeBytes,
+        &errMsg, &filters, &objectKey, &job.CreatedAt, &job.FinishedAt, &job.ExpiresAt)
+    if err == sql.ErrNoRows {
+        return ExportJob{}, errExportNotFound
+    }
+    if err != nil {
+        return ExportJob{}, err
+    }
+    job.SizeBytes = sizeBytes.Int64
+    job.Error = errMsg.String
+    job.objectKey = objectKey.String
+    json.Unmarshal([]byte(filters), &job.filters)
+    return job, nil
+}
+
+func (s postgresStore) PendingExports(ctx context.Context, resume bool) ([]int64, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    if resume {
+        if _, err := db.ExecContext(ctx, "UPDATE export_jobs SET status = $1 WHERE status = $2", exportPending, exportRunning); err != nil {
+            s.c.logger.Warnf("failed to resume interrupted exports: %v", err)
+        }
+    }
+    rows, err := db.QueryContext(ctx, "SELECT id FROM export_jobs WHERE status = $1 ORDER BY id", exportPending)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var ids []int64
+    for rows.Next() {
+        var id int64
+        if rows.Scan(&id) == nil {
+            ids = append(ids, id)
+        }
+    }
+    return ids, rows.Err()
+}
+
+func (s postgresStore) StartExport(ctx context.Context, id int64) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    result, err := db.ExecContext(ctx, "UPDATE export_jobs SET status = $1 WHERE id = $2 AND status = $3", exportRunning, id, exportPending)
+    if err != nil {
+        return false, err
+    }
+    n, _ := result.RowsAffected()
+    return n > 0, nil
+}
+
+func (s postgresStore) CompleteExport(ctx context.Context, id int64, rowCount int, size int64, objectKey string, expiresAt time.Time) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    _, err = db.ExecContext(ctx, `
+        UPDATE export_jobs
+        SET status = $1, row_count = $2, size_bytes = $3, object_key = $4,
+            finished_at = NOW(), expires_at = $5
+        WHERE id = $6
+    `, exportCompleted, rowCount, size, objectKey, expiresAt, id)
+    return err
+}
+
+func (s postgresStore) FailExport(ctx context.Context, id int64, message string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    _, err = db.ExecContext(ctx, `
+        UPDATE export_jobs SET status = $1, error = $2, finished_at = NOW() WHERE id = $3
+    `, exportFailed, message, id)
+    return err
+}
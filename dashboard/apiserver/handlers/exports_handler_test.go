@@ -0,0 +1,214 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/exports"
+)
+
+func TestExportRedactsLikeThreadLists(t *testing.T) {
+    store := NewMemoryStore()
+    for _, ch := range []domain.Channel{
+        {ChannelID: "C1", ChannelName: "c1", TextIndexing: true},
+        {ChannelID: "C2", ChannelName: "c2", TextIndexing: false},
+        {ChannelID: "C3", ChannelName: "c3", TextIndexing: true},
+        {ChannelID: "C4", ChannelName: "c4", TextIndexing: true},
+    } {
+        store.AddChannel(ChannelSummary{Channel: ch})
+        store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: ch.ChannelID, ThreadTS: "1700000000.000100", UserID: "U1",
+            Status: statusOpen, CreatedAt: time.Now()}})
+    }
+    ctx := context.Background()
+    for _, channelID := range []string{"C3", "C4"} {
+        if _, err := store.MarkSlackConnect(ctx, channelID, true); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if _, err := store.SetSlackConnectAI(ctx, "C4", true); err != nil {
+        t.Fatal(err)
+    }
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    var out bytes.Buffer
+    written, err := c.writeExport(ctx, ExportRequest{Format: "json"}, &out)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var rows []exportRow
+    if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+        t.Fatal(err)
+    }
+    if written != 4 || len(rows) != 4 {
+        t.Fatalf("wrote %d threads and %d rows, want 4", written, len(rows))
+    }
+
+    // Channels redact the AI text of their threads like thread lists
+    channels, err := store.ExportChannels(ctx, ExportRequest{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := map[string]bool{"C1": true, "C2": false, "C3": false, "C4": true}
+    for _, ch := range channels {
+        name, description := "Name", "Description"
+        row := exportRow{AIThreadName: &name, AIDescription: &description}
+        ch.redact(&row)
+        if shown := row.AIThreadName != nil || row.AIDescription != nil; shown != want[ch.ID] {
+            t.Errorf("channel %s: AI text exported %v, want %v", ch.ID, shown, want[ch.ID])
+        }
+    }
+}
+
+func TestExportQueryFiltersEffectivePriority(t *testing.T) {
+    ch := exportChannel{trackedChannel: trackedChannel{ID: "C1"}, minConfidence: 0.8}
+    query, args := exportQuery(ExportRequest{Priority: "medium", Status: "open"}, ch)
+    query = strings.Join(strings.Fields(query), " ")
+
+    // Threads are exported and filtered with the priority thread lists show
+    if !strings.Contains(query, strings.Join(strings.Fields(priorityColumn("$2")), " ")) || !strings.Contains(query, "AND priority = $3") {
+        t.Errorf("export query does not use the effective priority: %s", query)
+    }
+    if !strings.Contains(query, "FROM threads t WHERE t.channel_id = $1") || !strings.Contains(query, "AND status = $4") {
+        t.Errorf("export query does not read the threads of the channel: %s", query)
+    }
+    if fmt.Sprint(args) != "[C1 0.8 medium open]" {
+        t.Errorf("got arguments %v", args)
+    }
+}
+
+func TestExportJobLifecycle(t *testing.T) {
+    files, err := exports.NewLocalStore(t.TempDir())
+    if err != nil {
+        t.Fatal(err)
+    }
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    for _, ts := range []string{"1700000000.000100", "1700000000.000200"} {
+        store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: ts, UserID: "U1", Status: statusOpen, CreatedAt: time.Now()}})
+    }
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.exportStore = files
+    c.exportSigner = exports.NewSigner("key")
+    c.exportJobs = make(chan exportRef, 1)
+    c.exportThreshold = 1
+    c.exportTTL = time.Hour
+    requester := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+    other := &auth.Principal{UserID: "U2", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    rec := serveAs(c, requester, http.MethodPost, "/api/exports", "/api/exports", `{"format": "csv"}`, c.CreateExport)
+    if rec.Code != http.StatusAccepted {
+        t.Fatalf("got status %d, want the export queued: %s", rec.Code, rec.Body.String())
+    }
+    var job ExportJob
+    decodeResponse(t, rec, &job)
+    if job.Status != exportPending || job.RowCount != 2 || job.RequestedBy != "U1" {
+        t.Fatalf("got job %+v, want a pending export of 2 threads by U1", job)
+    }
+    if ref := <-c.exportJobs; ref.id != job.ID {
+        t.Fatalf("queued export %d, want %d", ref.id, job.ID)
+    }
+
+    c.processExport(context.Background(), job.ID)
+
+    target := "/api/exports/" + strconv.FormatInt(job.ID, 10)
+    if rec := serveAs(c, other, http.MethodGet, "/api/exports/:id", target, "", c.GetExport); rec.Code != http.StatusNotFound {
+        t.Fatalf("export of another user got status %d", rec.Code)
+    }
+    rec = serveAs(c, requester, http.MethodGet, "/api/exports/:id", target, "", c.GetExport)
+    decodeResponse(t, rec, &job)
+    if job.Status != exportCompleted || job.DownloadURL == "" {
+        t.Fatalf("got job %+v, want it completed with a download link", job)
+    }
+    rec = serveRequest(t, c, http.MethodGet, "/exports/:id/download", job.DownloadURL, c.DownloadExport)
+    if lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n"); rec.Code != http.StatusOK || len(lines) != 3 {
+        t.Fatalf("got status %d and file %q, want the header and 2 threads", rec.Code, rec.Body.String())
+    }
+}
+
+func TestDownloadExportChecksLink(t *testing.T) {
+    store, err := exports.NewLocalStore(t.TempDir())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := store.Put("export-1.csv", strings.NewReader("thread_ts")); err != nil {
+        t.Fatal(err)
+    }
+    records := NewMemoryStore()
+    expiresAt := time.Now().Add(time.Hour)
+    records.AddExport(ExportJob{ID: 1, Status: exportCompleted, Format: "csv", ExpiresAt: &expiresAt, objectKey: "export-1.csv"})
+    c := newTestContainer(t, records, &MemorySlack{})
+    c.exportStore = store
+    c.exportSigner = exports.NewSigner("key")
+
+    link := func(id string, expires time.Time, signature string) string {
+        query := url.Values{}
+        query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+        query.Set("signature", signature)
+        return "/exports/" + id + "/download?" + query.Encode()
+    }
+    expires := time.Now().Add(time.Hour)
+    expired := time.Now().Add(-time.Minute)
+    tampered := []byte(c.exportSigner.Sign("1", expires))
+    tampered[0] ^= 1
+
+    tests := []struct {
+        name   string
+        target string
+        want   int
+    }{
+        {"signed link", link("1", expires, c.exportSigner.Sign("1", expires)), http.StatusOK},
+        {"expired link", link("1", expired, c.exportSigner.Sign("1", expired)), http.StatusForbidden},
+        {"extended expiry", link("1", expires.Add(time.Hour), c.exportSigner.Sign("1", expires)), http.StatusForbidden},
+        {"tampered signature", link("1", expires, string(tampered)), http.StatusForbidden},
+        {"link of another export", link("2", expires, c.exportSigner.Sign("1", expires)), http.StatusForbidden},
+        {"link of another workspace", link("1", expires, c.exportSigner.Sign(exportSubject("T1", "1"), expires)), http.StatusForbidden},
+        {"unsigned link", "/exports/1/download", http.StatusForbidden},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rec := serveRequest(t, c, http.MethodGet, "/exports/:id/download", tt.target, c.DownloadExport)
+            if rec.Code != tt.want {
+                t.Fatalf("got status %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+            }
+            if tt.want == http.StatusOK && rec.Body.String() != "thread_ts" {
+                t.Fatalf("got file %q", rec.Body.String())
+            }
+        })
+    }
+}
+
+func TestQueueExportDoesNotWaitForWorkers(t *testing.T) {
+    c := newTestContainer(t, NewMemoryStore(), &MemorySlack{})
+    c.exportJobs = make(chan exportRef)
+
+    // No worker is free, the job stays pending for the next poll
+    c.queueExport(exportRef{id: 1})
+
+    received := make(chan exportRef)
+    go func() { received <- <-c.exportJobs }()
+    deadline := time.Now().Add(time.Second)
+    for {
+        c.queueExport(exportRef{id: 2})
+        select {
+        case ref := <-received:
+            if ref.id != 2 {
+                t.Fatalf("got export %d, want 2", ref.id)
+            }
+            return
+        case <-time.After(10 * time.Millisecond):
+        }
+        if time.Now().After(deadline) {
+            t.Fatal("a free worker never got the export")
+        }
+    }
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/github"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+const issueCreateTimeout = 20 * time.Second
+
+// maxIssueTitle keeps generated titles readable in GitHub lists.
+const maxIssueTitle = 120
+
+// GitHubRouting is the per-channel policy for issues created from threads.
+// The first rule matching a thread picks the repository, Repo is used when
+// none does.
+type GitHubRouting struct {
+    Repo   string        `json:"repo"`
+    Labels []string      `json:"labels"`
+    Rules  []GitHubRoute `json:"rules"`
+}
+
+// GitHubRoute sends threads with Priority, or mentioning Keyword in their
+// name or description, to Repo. An empty condition matches every thread.
+type GitHubRoute struct {
+    Priority string   `json:"priority"`
+    Keyword  string   `json:"keyword"`
+    Repo     string   `json:"repo"`
+    Labels   []string `json:"labels"`
+}
+
+// validate checks a routing policy, returning a message for the caller or
+// "" when it is usable.
+func (r *GitHubRouting) validate() string {
+    r.Repo = strings.TrimSpace(r.Repo)
+    if !github.ValidRepo(r.Repo) {
+        return "repo must be owner/name"
+    }
+    for i := range r.Rules {
+        rule := &r.Rules[i]
+        rule.Repo = strings.TrimSpace(rule.Repo)
+        rule.Keyword = strings.TrimSpace(rule.Keyword)
+        if !github.ValidRepo(rule.Repo) {
+            return fmt.Sprintf("rule %d: repo must be owner/name", i+1)
+        }
+        if rule.Priority != "" && !domain.Priority(rule.Priority).Valid() {
+            return fmt.Sprintf("rule %d: priority must be high, medium or low", i+1)
+        }
+    }
+    return ""
+}
+
+// parseGitHubRouting decodes a stored policy, nil when the channel has none.
+func parseGitHubRouting(stored sql.NullString) *GitHubRouting {
+    if !stored.Valid {
+        return nil
+    }
+    var routing GitHubRouting
+    if err := json.Unmarshal([]byte(stored.String), &routing); err != nil || !github.ValidRepo(routing.Repo) {
+        return nil
+    }
+    return &routing
+}
+
+// routeMatches reports whether a routing rule for rulePriority and keyword
+// matches a thread with priority whose name and description are text.
+func routeMatches(rulePriority string, keyword string, priority string, text string) bool {
+    if rulePriority != "" && rulePriority != priority {
+        return false
+    }
+    return keyword == "" || strings.Contains(strings.ToLower(text), strings.ToLower(keyword))
+}
+
+// route returns the repository and labels for a thread.
+func (r *GitHubRouting) route(priority string, text string) (string, []string) {
+    for _, rule := range r.Rules {
+        if !routeMatches(rule.Priority, rule.Keyword, priority, text) {
+            continue
+        }
+        return rule.Repo, append(append([]string{}, r.Labels...), rule.Labels...)
+    }
+    return r.Repo, r.Labels
+}
+
+// allows reports whether repo is one of the repositories of the policy.
+func (r *GitHubRouting) allows(repo string) bool {
+    if repo == r.Repo {
+        return true
+    }
+    for _, rule := range r.Rules {
+        if rule.Repo == repo {
+            return true
+        }
+    }
+    return false
+}
+
+// CreateGitHubIssueRequest is the body accepted by CreateThreadGitHubIssue.
+// Repo overrides the channel's routing with another of its repositories.
+type CreateGitHubIssueRequest struct {
+    Repo string `json:"repo"`
+}
+
+// issueTitle returns the title of an issue for a thread.
+func issueTitle(name string, channelName string) string {
+    name = strings.TrimSpace(name)
+    if name == "" {
+        return "Slack thread in #" + channelName
+    }
+    if len([]rune(name)) > maxIssueTitle {
+        name = string([]rune(name)[:maxIssueTitle-1]) + "…"
+    }
+    return name
+}
+
+// issueBody returns the body of an issue for a thread.
+func issueBody(description string, permalink string) string {
+    var body strings.Builder
+    if description = strings.TrimSpace(description); description != "" {
+        body.WriteString(description)
+        body.WriteString("\n\n")
+    }
+    fmt.Fprintf(&body, "Slack thread: %s", permalink)
+    return body.String()
+}
+
+// issueSource returns the name, description and priority an issue or
+// ticket is created from, the priority being the one set by hand or else
+// the uncalibrated AI priority.
+func issueSource(thread Thread) (string, string, string) {
+    var name, description, priority string
+    if thread.AIThreadName != nil {
+        name = *thread.AIThreadName
+    }
+    if thread.AIDescription != nil {
+        description = *thread.AIDescription
+    }
+    if thread.ManualPriority != nil {
+        priority = *thread.ManualPriority
+    } else if thread.AIPriority != nil {
+        priority = *thread.AIPriority
+    }
+    return name, description, priority
+}
+
+// CreateThreadGitHubIssue - Open a GitHub issue for a thread in the repository its channel routes to, and link it
+func (c *Container) CreateThreadGitHubIssue(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req CreateGitHubIssueRequest
+    if ctx.Request().ContentLength != 0 {
+        if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+            return apierror.New(http.StatusBadRequest, "Invalid request body")
+        }
+    }
+    req.Repo = strings.TrimSpace(req.Repo)
+
+    if !c.github.Authenticated() {
+        return apierror.New(http.StatusServiceUnavailable, "GitHub token is not configured")
+    }
+
+    reqCtx := ctx.Request().Context()
+    config, err := c.configs.ChannelConfig(reqCtx, channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    ref := ThreadRef{ChannelID: channelID, ThreadTS: threadTS}
+    threads, err := c.threadLists.ThreadsByRef(reqCtx, []ThreadRef{ref})
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread")
+    }
+    thread, ok := threads[ref]
+    if !ok {
+        return apierror.New(http.StatusNotFound, "Thread not found")
+    }
+    if thread.GithubIssue != nil && *thread.GithubIssue != "" {
+        return apierror.New(http.StatusConflict, "Thread already has a GitHub issue").
+            WithDetails(map[string]interface{}{"github_issue": *thread.GithubIssue})
+    }
+    name, description, priority := issueSource(thread)
+
+    routing := config.GitHubRouting
+    if routing == nil {
+        return apierror.New(http.StatusConflict, "Channel has no GitHub repository configured")
+    }
+    repo, labels := routing.route(priority, name+"\n"+description)
+    if req.Repo != "" {
+        if !routing.allows(req.Repo) {
+            return apierror.New(http.StatusBadRequest, "repo is not one of the channel's repositories")
+        }
+        repo = req.Repo
+    }
+
+    createCtx, cancel := context.WithTimeout(reqCtx, issueCreateTimeout)
+    defer cancel()
+    issue, err := c.github.CreateIssue(createCtx, repo, github.NewIssue{
+        Title:  issueTitle(name, thread.ChannelName),
+        Body:   issueBody(description, slack.Permalink(channelID, threadTS)),
+        Labels: labels,
+    })
+    if err != nil {
+        c.logger.Warnf("failed to create GitHub issue in %s for %s/%s: %v", repo, channelID, threadTS, err)
+        return apierror.New(http.StatusBadGateway, "Failed to create GitHub issue")
+    }
+
+    linked, err := c.githubIssues.LinkGitHubIssue(reqCtx, channelID, threadTS, issue.HTMLURL)
+    if err != nil {
+        c.logger.Errorf("created GitHub issue %s but failed to link it to %s/%s: %v", issue.HTMLURL, channelID, threadTS, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to link GitHub issue")
+    }
+    if !linked {
+        c.logger.Warnf("thread %s/%s was linked concurrently, leaving new issue %s unlinked", channelID, threadTS, issue.HTMLURL)
+        return apierror.New(http.StatusConflict, "Thread was linked to another GitHub issue meanwhile").
+            WithDetails(map[string]interface{}{"github_issue": issue.HTMLURL})
+    }
+
+    c.audit(reqCtx, actorFrom(ctx), "thread.github_issue", channelID, threadTS, map[string]interface{}{
+        "repo":         repo,
+        "github_issue": issue.HTMLURL,
+    })
+
+    return ctx.JSON(http.StatusCreated, map[string]interface{}{
+        "repo":         repo,
+        "number":       issue.Number,
+        "github_issue": issue.HTMLURL,
+    })
+}
+
+// SetChannelGitHubRouting - Set or, with an empty body, remove the repositories a channel's issues are created in
+func (c *Container) SetChannelGitHubRouting(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var routing *GitHubRouting
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&routing); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if routing != nil && routing.Repo == "" && len(routing.Rules) == 0 {
+        routing = nil
+    }
+    if routing != nil {
+        if msg := routing.validate(); msg != "" {
+            return apierror.New(http.StatusBadRequest, msg)
+        }
+    }
+
+    err := c.configs.SetChannelConfig(ctx.Request().Context(), channelID, ChannelConfig{GitHubRouting: routing}, settingGitHubRouting)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.github_routing", channelID, "", map[string]interface{}{
+        "github_routing": routing,
+    })
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "github_routing": routing,
+    })
+}
+
+func (s postgresStore) LinkGitHubIssue(ctx context.Context, channelID string, threadTS string, url string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+    result, err := db.ExecContext(ctx, `
+        UPDATE threads SET github_issue = $1, updated_at = NOW()
+        WHERE thread_ts = $2 AND channel_id = $3 AND (github_issue IS NULL OR github_issue = '')
+    `, url, threadTS, channelID)
+    if err != nil {
+        return false, err
+    }
+    n, _ := result.RowsAffected()
+    return n > 0, nil
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/github"
+)
+
+func TestCreateThreadGitHubIssueLinksThread(t *testing.T) {
+    var created []github.NewIssue
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var issue github.NewIssue
+        json.NewDecoder(r.Body).Decode(&issue)
+        created = append(created, issue)
+        w.WriteHeader(http.StatusCreated)
+        w.Write([]byte(`{"number": 7, "html_url": "https://github.com/acme/app/issues/7"}`))
+    }))
+    t.Cleanup(server.Close)
+
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "random"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen}, Title: "Deploys time out"})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C2", ThreadTS: "1700000000.000200", Status: statusOpen}})
+    store.SetChannelConfig(context.Background(), "C1", ChannelConfig{GitHubRouting: &GitHubRouting{Repo: "acme/app"}}, settingGitHubRouting)
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.github = github.NewClient("token", server.URL)
+    user := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    route := "/api/threads/:channel_id/:thread_ts/github-issue"
+    if rec := serveAs(c, user, http.MethodPost, route, "/api/threads/C2/1700000000.000200/github-issue", "", c.CreateThreadGitHubIssue); rec.Code != http.StatusConflict {
+        t.Fatalf("a channel without repository got status %d", rec.Code)
+    }
+    rec := serveAs(c, user, http.MethodPost, route, "/api/threads/C1/1700000000.000100/github-issue", "", c.CreateThreadGitHubIssue)
+    if rec.Code != http.StatusCreated {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    if len(created) != 1 || created[0].Title != issueTitle("Deploys time out", "support") {
+        t.Fatalf("created issues %+v", created)
+    }
+    if thread, _ := store.Thread(context.Background(), "C1", "1700000000.000100"); thread.GithubIssue != "https://github.com/acme/app/issues/7" {
+        t.Fatalf("got GitHub issue %q linked", thread.GithubIssue)
+    }
+
+    rec = serveAs(c, user, http.MethodPost, route, "/api/threads/C1/1700000000.000100/github-issue", "", c.CreateThreadGitHubIssue)
+    if rec.Code != http.StatusConflict || len(created) != 1 {
+        t.Fatalf("a linked thread got status %d after %d issues", rec.Code, len(created))
+    }
+}
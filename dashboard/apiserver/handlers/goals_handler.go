@@ -0,0 +1,395 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+// goalMetrics maps the metrics a goal can track to their snapshot column.
+var goalMetrics = map[string]string{
+    "open_threads":      "open_threads",
+    "open_high_threads": "open_high_threads",
+}
+
+const dateLayout = "2006-01-02"
+
+var errGoalNotFound = errors.New("goal not found")
+
+// Goal is a target for a snapshot metric, e.g. at most 20 open threads in
+// a channel by the end of the quarter. Goals without a channel cover all
+// channels.
+type Goal struct {
+    ID        int64     `json:"id"`
+    Name      string    `json:"name"`
+    ChannelID *string   `json:"channel_id"`
+    Metric    string    `json:"metric"`
+    Target    int       `json:"target"`
+    StartDate string    `json:"start_date"`
+    DueDate   string    `json:"due_date"`
+    CreatedBy string    `json:"created_by"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// GoalPoint is the value of a goal metric on a day.
+type GoalPoint struct {
+    Date  string  `json:"date"`
+    Value float64 `json:"value"`
+}
+
+// GoalProgress is the burn-down of a goal. Ideal is the straight line from
+// the baseline on the start date to the target on the due date.
+type GoalProgress struct {
+    Goal     Goal        `json:"goal"`
+    Baseline *float64    `json:"baseline"`
+    Current  *float64    `json:"current"`
+    Achieved bool        `json:"achieved"`
+    OnTrack  bool        `json:"on_track"`
+    Points   []GoalPoint `json:"points"`
+    Ideal    []GoalPoint `json:"ideal"`
+}
+
+// validate normalizes a goal from a request and returns an error message
+// for invalid ones.
+func (g *Goal) validate() string {
+    g.Name = strings.TrimSpace(g.Name)
+    if g.Name == "" {
+        return "name is required"
+    }
+    if g.Metric == "" {
+        g.Metric = "open_threads"
+    }
+    if _, ok := goalMetrics[g.Metric]; !ok {
+        return "metric must be open_threads or open_high_threads"
+    }
+    if g.Target < 0 {
+        return "target must not be negative"
+    }
+    if g.StartDate == "" {
+        g.StartDate = time.Now().UTC().Format(dateLayout)
+    }
+    start, err := time.Parse(dateLayout, g.StartDate)
+    if err != nil {
+        return "start_date must be a date such as 2024-01-31"
+    }
+    due, err := time.Parse(dateLayout, g.DueDate)
+    if err != nil {
+        return "due_date must be a date such as 2024-03-31"
+    }
+    if !due.After(start) {
+        return "due_date must be after start_date"
+    }
+    if g.ChannelID != nil && *g.ChannelID == "" {
+        g.ChannelID = nil
+    }
+    return ""
+}
+
+const goalColumns = `id, name, channel_id, metric, target, start_date, due_date, created_by, created_at`
+
+func scanGoal(row interface{ Scan(...interface{}) error }) (Goal, error) {
+    var g Goal
+    var start, due time.Time
+    err := row.Scan(&g.ID, &g.Name, &g.ChannelID, &g.Metric, &g.Target, &start, &due, &g.CreatedBy, &g.CreatedAt)
+    g.StartDate = start.Format(dateLayout)
+    g.DueDate = due.Format(dateLayout)
+    return g, err
+}
+
+// checkGoalChannel returns errChannelNotTracked when the goal names an
+// unknown channel.
+func (c *Container) checkGoalChannel(ctx context.Context, g Goal) error {
+    if g.ChannelID == nil {
+        return nil
+    }
+    return c.channels.Tracked(ctx, *g.ChannelID)
+}
+
+// GetGoals - List goals
+func (c *Container) GetGoals(ctx echo.Context) error {
+    goals, err := c.goals.Goals(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query goals")
+    }
+
+    return ctx.JSON(http.StatusOK, goals)
+}
+
+// GetGoal - Get a goal
+func (c *Container) GetGoal(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid goal id")
+    }
+
+    g, err := c.goals.Goal(ctx.Request().Context(), id)
+    if err == errGoalNotFound {
+        return apierror.New(http.StatusNotFound, "Goal not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query goal")
+    }
+
+    return ctx.JSON(http.StatusOK, g)
+}
+
+// CreateGoal - Create a goal
+func (c *Container) CreateGoal(ctx echo.Context) error {
+    var g Goal
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&g); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if msg := g.validate(); msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    reqCtx := ctx.Request().Context()
+    if err := c.checkGoalChannel(reqCtx, g); err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    } else if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    actor := actorFrom(ctx)
+    g.CreatedBy = actor
+    g, err := c.goals.CreateGoal(reqCtx, g)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to create goal")
+    }
+
+    c.audit(reqCtx, actor, "goal.create", "", "", map[string]interface{}{
+        "goal": g,
+    })
+
+    return ctx.JSON(http.StatusCreated, g)
+}
+
+// UpdateGoal - Replace the definition of a goal
+func (c *Container) UpdateGoal(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid goal id")
+    }
+
+    var g Goal
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&g); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if msg := g.validate(); msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    reqCtx := ctx.Request().Context()
+    if err := c.checkGoalChannel(reqCtx, g); err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    } else if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    g.ID = id
+    g, err = c.goals.UpdateGoal(reqCtx, g)
+    if err == errGoalNotFound {
+        return apierror.New(http.StatusNotFound, "Goal not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update goal")
+    }
+
+    c.audit(reqCtx, actorFrom(ctx), "goal.update", "", "", map[string]interface{}{
+        "goal": g,
+    })
+
+    return ctx.JSON(http.StatusOK, g)
+}
+
+// DeleteGoal - Delete a goal
+func (c *Container) DeleteGoal(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid goal id")
+    }
+
+    err = c.goals.DeleteGoal(ctx.Request().Context(), id)
+    if err == errGoalNotFound {
+        return apierror.New(http.StatusNotFound, "Goal not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to delete goal")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "goal.delete", "", "", map[string]interface{}{
+        "goal_id": id,
+    })
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// GetGoalProgress - Get the daily burn-down of a goal
+func (c *Container) GetGoalProgress(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid goal id")
+    }
+
+    reqCtx := ctx.Request().Context()
+    g, err := c.goals.Goal(reqCtx, id)
+    if err == errGoalNotFound {
+        return apierror.New(http.StatusNotFound, "Goal not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query goal")
+    }
+
+    points, err := c.goals.GoalPoints(reqCtx, g)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query stats snapshots")
+    }
+
+    progress := GoalProgress{Goal: g, Points: points, Ideal: []GoalPoint{}}
+    if len(progress.Points) == 0 {
+        return ctx.JSON(http.StatusOK, progress)
+    }
+
+    baseline := progress.Points[0].Value
+    current := progress.Points[len(progress.Points)-1].Value
+    progress.Baseline = &baseline
+    progress.Current = &current
+    progress.Achieved = current <= float64(g.Target)
+
+    start, _ := time.Parse(dateLayout, g.StartDate)
+    due, _ := time.Parse(dateLayout, g.DueDate)
+    days := due.Sub(start).Hours() / 24
+    ideal := func(date string) float64 {
+        day, _ := time.Parse(dateLayout, date)
+        done := day.Sub(start).Hours() / 24 / days
+        return baseline - (baseline-float64(g.Target))*done
+    }
+    for day := start; !day.After(due); day = day.AddDate(0, 0, 1) {
+        date := day.Format(dateLayout)
+        progress.Ideal = append(progress.Ideal, GoalPoint{Date: date, Value: ideal(date)})
+    }
+    progress.OnTrack = progress.Achieved || current <= ideal(progress.Points[len(progress.Points)-1].Date)
+
+    return ctx.JSON(http.StatusOK, progress)
+}
+
+func (s postgresStore) Goals(ctx context.Context) ([]Goal, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, "SELECT "+goalColumns+" FROM goals ORDER BY due_date, id")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    goals := []Goal{}
+    for rows.Next() {
+        g, err := scanGoal(rows)
+        if err != nil {
+            continue
+        }
+        goals = append(goals, g)
+    }
+    return goals, rows.Err()
+}
+
+func (s postgresStore) Goal(ctx context.Context, id int64) (Goal, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return Goal{}, err
+    }
+    g, err := scanGoal(db.QueryRowContext(ctx, "SELECT "+goalColumns+" FROM goals WHERE id = $1", id))
+    if err == sql.ErrNoRows {
+        return Goal{}, errGoalNotFound
+    }
+    return g, err
+}
+
+func (s postgresStore) CreateGoal(ctx context.Context, g Goal) (Goal, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return Goal{}, err
+    }
+    return scanGoal(db.QueryRowContext(ctx, `
+        INSERT INTO goals (name, channel_id, metric, target, start_date, due_date, created_by)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING `+goalColumns, g.Name, g.ChannelID, g.Metric, g.Target, g.StartDate, g.DueDate, g.CreatedBy))
+}
+
+func (s postgresStore) UpdateGoal(ctx context.Context, g Goal) (Goal, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return Goal{}, err
+    }
+    g, err = scanGoal(db.QueryRowContext(ctx, `
+        UPDATE goals SET name = $2, channel_id = $3, metric = $4, target = $5, start_date = $6, due_date = $7
+        WHERE id = $1
+        RETURNING `+goalColumns, g.ID, g.Name, g.ChannelID, g.Metric, g.Target, g.StartDate, g.DueDate))
+    if err == sql.ErrNoRows {
+        return Goal{}, errGoalNotFound
+    }
+    return g, err
+}
+
+func (s postgresStore) DeleteGoal(ctx context.Context, id int64) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    result, err := db.ExecContext(ctx, "DELETE FROM goals WHERE id = $1", id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return errGoalNotFound
+    }
+    return nil
+}
+
+func (s postgresStore) GoalPoints(ctx context.Context, g Goal) ([]GoalPoint, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    // The metric column comes from goalMetrics, never from the request
+    query := fmt.Sprintf(`
+        SELECT day, SUM(%s) FROM stats_snapshots
+        WHERE day >= $1 AND day <= $2`, goalMetrics[g.Metric])
+    args := []interface{}{g.StartDate, g.DueDate}
+    if g.ChannelID != nil {
+        query += " AND channel_id = $3"
+        args = append(args, *g.ChannelID)
+    }
+    query += " GROUP BY day ORDER BY day"
+
+    rows, err := db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    points := []GoalPoint{}
+    for rows.Next() {
+        var day time.Time
+        var value float64
+        if err := rows.Scan(&day, &value); err != nil {
+            continue
+        }
+        points = append(points, GoalPoint{Date: day.Format(dateLayout), Value: value})
+    }
+    return points, rows.Err()
+}
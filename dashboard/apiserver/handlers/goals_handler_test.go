@@ -0,0 +1,70 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "strconv"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestGoalProgress(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    for _, ts := range []string{"1700000000.000100", "1700000000.000200", "1700000000.000300", "1700000000.000400"} {
+        store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: ts, Status: statusOpen, CreatedAt: time.Now()}})
+    }
+    c := newTestContainer(t, store, &MemorySlack{})
+    agent := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    rec := serveAs(c, agent, http.MethodPost, "/api/goals", "/api/goals",
+        `{"name": "Inbox zero", "channel_id": "C2", "target": 0, "start_date": "2024-01-01", "due_date": "2024-01-05"}`, c.CreateGoal)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("creating a goal of an untracked channel got status %d", rec.Code)
+    }
+    rec = serveAs(c, agent, http.MethodPost, "/api/goals", "/api/goals",
+        `{"name": "Inbox zero", "channel_id": "C1", "target": 0, "start_date": "2024-01-01", "due_date": "2024-01-05"}`, c.CreateGoal)
+    if rec.Code != http.StatusCreated {
+        t.Fatalf("creating the goal got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var goal Goal
+    decodeResponse(t, rec, &goal)
+    if goal.ID == 0 || goal.CreatedBy != "U1" || goal.Metric != "open_threads" {
+        t.Fatalf("got goal %+v", goal)
+    }
+
+    // Four open threads on the first day, two on the third
+    ctx := context.Background()
+    if err := store.CaptureStatsSnapshot(ctx, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+        t.Fatal(err)
+    }
+    for _, ts := range []string{"1700000000.000100", "1700000000.000200"} {
+        store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: ts, Status: statusResolved, CreatedAt: time.Now()}})
+    }
+    if err := store.CaptureStatsSnapshot(ctx, time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)); err != nil {
+        t.Fatal(err)
+    }
+
+    target := "/api/goals/" + strconv.FormatInt(goal.ID, 10) + "/progress"
+    rec = serveAs(c, agent, http.MethodGet, "/api/goals/:id/progress", target, "", c.GetGoalProgress)
+    var progress GoalProgress
+    decodeResponse(t, rec, &progress)
+    if len(progress.Points) != 2 || *progress.Baseline != 4 || *progress.Current != 2 || progress.Achieved || len(progress.Ideal) != 5 {
+        t.Fatalf("got progress %+v", progress)
+    }
+    // The ideal line is at 2 by the third day
+    if !progress.OnTrack {
+        t.Fatalf("got progress %+v, want it on track", progress)
+    }
+
+    rec = serveAs(c, agent, http.MethodDelete, "/api/goals/:id", "/api/goals/"+strconv.FormatInt(goal.ID, 10), "", c.DeleteGoal)
+    if rec.Code != http.StatusNoContent {
+        t.Fatalf("deleting the goal got status %d", rec.Code)
+    }
+    if rec = serveAs(c, agent, http.MethodGet, "/api/goals/:id/progress", target, "", c.GetGoalProgress); rec.Code != http.StatusNotFound {
+        t.Fatalf("got status %d for the progress of a deleted goal", rec.Code)
+    }
+}
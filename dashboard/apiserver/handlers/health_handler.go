@@ -0,0 +1,42 @@
+package handlers
+
+import (
+    "net/http"
+
+    "github.com/labstack/echo/v4"
+)
+
+// GetLiveness - Report that the server is up, for liveness probes
+func GetLiveness(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, map[string]string{
+        "status": "ok",
+    })
+}
+
+// GetReadiness - Report whether the shared and workspace databases are reachable, for readiness probes
+func (c *Container) GetReadiness(ctx echo.Context) error {
+    ready := true
+    databases := map[string]string{"shared": "ok"}
+    if err := c.checkDatabase(ctx.Request().Context()); err != nil {
+        ready = false
+        databases["shared"] = "unreachable"
+    }
+    for team, workspace := range c.workspaces {
+        databases[team] = "ok"
+        if err := c.pingDatabase(ctx.Request().Context(), "database of workspace "+team, workspace.db, workspace.config, &workspace.healthy); err != nil {
+            ready = false
+            databases[team] = "unreachable"
+        }
+    }
+
+    if !ready {
+        return ctx.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+            "status":    "unavailable",
+            "databases": databases,
+        })
+    }
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "status":    "ok",
+        "databases": databases,
+    })
+}
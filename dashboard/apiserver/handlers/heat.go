@@ -0,0 +1,89 @@
+package handlers
+
+import (
+    "database/sql"
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Heat levels of a thread, from fresh to overdue.
+const (
+    HeatGreen  = "green"
+    HeatYellow = "yellow"
+    HeatOrange = "orange"
+    HeatRed    = "red"
+)
+
+// HeatThresholds are the ages in hours at which an open thread of a channel
+// turns yellow, orange and red
+type HeatThresholds struct {
+    YellowHours float64 `json:"yellow_hours"`
+    OrangeHours float64 `json:"orange_hours"`
+    RedHours    float64 `json:"red_hours"`
+}
+
+// defaultHeatThresholds is used by channels without configured thresholds.
+var defaultHeatThresholds = HeatThresholds{YellowHours: 24, OrangeHours: 72, RedHours: 168}
+
+// parseHeatThresholds decodes stored thresholds, falling back to the default.
+func parseHeatThresholds(stored sql.NullString) HeatThresholds {
+    thresholds := defaultHeatThresholds
+    if stored.Valid {
+        if err := json.Unmarshal([]byte(stored.String), &thresholds); err != nil {
+            return defaultHeatThresholds
+        }
+    }
+    return thresholds
+}
+
+// Heat returns the heat of a thread created at createdAt. Only open threads
+// heat up.
+func (t HeatThresholds) Heat(status domain.Status, createdAt time.Time, now time.Time) string {
+    if status != domain.StatusOpen {
+        return HeatGreen
+    }
+    age := now.Sub(createdAt).Hours()
+    switch {
+    case age >= t.RedHours:
+        return HeatRed
+    case age >= t.OrangeHours:
+        return HeatOrange
+    case age >= t.YellowHours:
+        return HeatYellow
+    default:
+        return HeatGreen
+    }
+}
+
+// SetChannelHeatThresholds - Set the thread ages at which a channel's threads change heat
+func (c *Container) SetChannelHeatThresholds(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var thresholds HeatThresholds
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&thresholds); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if thresholds.YellowHours <= 0 || thresholds.OrangeHours < thresholds.YellowHours || thresholds.RedHours < thresholds.OrangeHours {
+        return apierror.New(http.StatusBadRequest, "thresholds must be positive and yellow_hours <= orange_hours <= red_hours")
+    }
+
+    err := c.configs.SetChannelConfig(ctx.Request().Context(), channelID, ChannelConfig{HeatThresholds: thresholds}, settingHeatThresholds)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.heat_thresholds", channelID, "", map[string]interface{}{
+        "thresholds": thresholds,
+    })
+
+    return ctx.JSON(http.StatusOK, thresholds)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/ingest"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// IngestStats is the response of GetIngestStats. Polling is only reported
+// when enabled
+type IngestStats struct {
+    Queue    ingest.QueueStats    `json:"queue"`
+    Pipeline ingest.PipelineStats `json:"pipeline"`
+    Polling  *ingest.PollerStats  `json:"polling,omitempty"`
+}
+
+// applySlackMessage stores a deduplicated Slack message. Thread roots are
+// inserted once, replies bump the reply count of their thread once, and new
+// messages are published on the event bus. The first reply by someone else
+// acknowledges the thread and the author replying reopens it if it waited
+// on them, saying thanks marks it as likely answered. These follow-ups run
+// again for a redelivered reply, in case they failed the first time.
+func (c *Container) applySlackMessage(ctx context.Context, env ingest.Envelope, msg ingest.MessageEvent) error {
+    err := c.channels.Tracked(ctx, msg.Channel)
+    if err == errChannelNotTracked {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    if c.poller != nil {
+        c.poller.Touch(msg.Channel)
+    }
+
+    postedAt, err := slackTSTime(msg.TS)
+    if err != nil {
+        return err
+    }
+    if err := c.noteSlackConnect(ctx, env.IsExtSharedChannel, env.TeamID, msg.Channel, msg.User, msg.UserTeam); err != nil {
+        return err
+    }
+    // Users keep one ID across the workspaces of an Enterprise Grid
+    if msg.UserTeam != "" {
+        msg.User = c.globalUserID(ctx, msg.UserTeam, msg.User)
+    } else {
+        msg.User = c.globalUserID(ctx, env.TeamID, msg.User)
+    }
+
+    textIndexing, err := c.channelTextIndexing(ctx, msg.Channel)
+    if err != nil {
+        return err
+    }
+    message := ThreadMessage{TS: msg.TS, UserID: msg.User, PostedAt: postedAt}
+    if textIndexing {
+        message.Text = &msg.Text
+    }
+
+    if !msg.IsReply() {
+        thread, err := domain.NewThread(msg.Channel, msg.TS, msg.User, postedAt)
+        if err != nil {
+            return err
+        }
+        if err := c.messages.StartThread(ctx, thread, message); err != nil {
+            return err
+        }
+        c.publishMessage(ctx, msg.Channel, msg.TS, msg.User, msg.TS, postedAt, false)
+        return nil
+    }
+
+    thread, added, err := c.messages.AddReply(ctx, msg.Channel, msg.ThreadTS, message)
+    if err == errThreadNotFound {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    if added {
+        c.publishMessage(ctx, msg.Channel, msg.ThreadTS, msg.User, msg.TS, postedAt, true)
+    }
+
+    // Replies of bots change nothing else
+    if msg.User == "" {
+        return nil
+    }
+    author, createdAt := thread.UserID, thread.CreatedAt
+    if msg.User == author {
+        if err := c.reporterReplied(ctx, msg.Channel, msg.ThreadTS, author); err != nil {
+            return err
+        }
+        if looksAnswered(msg.Text) {
+            return c.recordAnswerSignal(ctx, msg.Channel, msg.ThreadTS, answerSignalThanks, msg.User, postedAt)
+        }
+        return nil
+    }
+
+    // The first reply by someone other than the author acknowledges the
+    // thread
+    _, err = c.acks.RecordAck(ctx, msg.Channel, msg.ThreadTS, msg.User, ackSourceReply, createdAt, postedAt)
+    return err
+}
+
+// HandleSlackEvent - Receive Slack Events API deliveries and queue them for ingestion
+func (c *Container) HandleSlackEvent(ctx echo.Context) error {
+    var env ingest.Envelope
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&env); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid event payload")
+    }
+
+    switch env.Type {
+    case "url_verification":
+        return ctx.JSON(http.StatusOK, map[string]string{
+            "challenge": env.Challenge,
+        })
+    case "event_callback":
+    default:
+        return ctx.NoContent(http.StatusOK)
+    }
+
+    // Slack expects an answer within three seconds and retries failed
+    // deliveries, which the pipeline drops if they were already applied
+    if err := c.ingestQueue.Enqueue(env); err != nil {
+        c.logger.Errorf("failed to queue Slack delivery %s: %v", env.EventID, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to queue event")
+    }
+    return ctx.NoContent(http.StatusOK)
+}
+
+// processSlackDelivery is the consumer of the ingestion queue. Workflow
+// steps reaching the app arrive as events too.
+func (c *Container) processSlackDelivery(ctx context.Context, env ingest.Envelope) error {
+    if c.chaos != nil && c.chaos.DropEvent() {
+        c.logger.Warnf("dropped Slack delivery %s to test fault tolerance", env.EventID)
+        return nil
+    }
+    // Deliveries are stored in the database of the workspace they come from
+    ctx = withWorkspace(ctx, env.TeamID)
+    var event struct {
+        Type string `json:"type"`
+    }
+    if json.Unmarshal(env.Event, &event) == nil && event.Type == slack.WorkflowStepExecute {
+        return c.executeWorkflowStep(ctx, env)
+    }
+    return c.slackPipeline.Process(ctx, env)
+}
+
+// RunIngestion processes queued Slack deliveries until ctx is cancelled.
+func (c *Container) RunIngestion(ctx context.Context) {
+    c.ingestQueue.Run(ctx)
+}
+
+// GetIngestStats - Get ingestion queue depth, lag and processing rate, and the poll metrics of each channel
+func (c *Container) GetIngestStats(ctx echo.Context) error {
+    stats := IngestStats{
+        Queue:    c.ingestQueue.Stats(),
+        Pipeline: c.slackPipeline.Stats(),
+    }
+    if c.poller != nil {
+        polling := c.poller.Stats()
+        stats.Polling = &polling
+    }
+    return ctx.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/events"
+    "dashboard/apiserver/ingest"
+)
+
+func slackDelivery(eventID string, msg ingest.MessageEvent) ingest.Envelope {
+    msg.Type = "message"
+    msg.Channel = "C1"
+    event, _ := json.Marshal(msg)
+    return ingest.Envelope{Type: "event_callback", EventID: eventID, Event: event}
+}
+
+func TestRedeliveredReplyCountsOnce(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.bus = events.NewMemoryBus(c.logger)
+    defer c.bus.Close()
+    c.slackPipeline = ingest.NewPipeline(c.logger, ingest.NewMemoryDedupStore(time.Hour), c.channelTextIndexing, c.applySlackMessage)
+    var published atomic.Int32
+    unsubscribe, err := c.bus.Subscribe(events.TopicThreadMessage, "test", func(ctx context.Context, event events.Event) {
+        published.Add(1)
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    ctx := context.Background()
+    root := ingest.MessageEvent{User: "U1", Text: "help", TS: "1700000000.000100"}
+    if err := c.slackPipeline.Process(ctx, slackDelivery("Ev1", root)); err != nil {
+        t.Fatal(err)
+    }
+
+    // The first delivery of a reply by a bot fails, Slack delivers it again
+    reply := ingest.MessageEvent{Text: "on it", TS: "1700000060.000200", ThreadTS: root.TS}
+    store.FailNext(errors.New("connection reset"))
+    if err := c.slackPipeline.Process(ctx, slackDelivery("Ev2", reply)); err == nil {
+        t.Fatal("the failed delivery reported success")
+    }
+    if err := c.slackPipeline.Process(ctx, slackDelivery("Ev2", reply)); err != nil {
+        t.Fatal(err)
+    }
+    // The poller or an expired dedup entry applies the same reply again
+    again := reply
+    again.Type, again.Channel = "message", "C1"
+    if err := c.applySlackMessage(ctx, ingest.Envelope{}, again); err != nil {
+        t.Fatal(err)
+    }
+
+    thread, err := store.Thread(ctx, "C1", root.TS)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if thread.ReplyCount != 1 || !thread.LatestReply.Equal(time.Unix(1700000060, 200000)) {
+        t.Fatalf("got %d replies, the latest at %v, want the reply counted once", thread.ReplyCount, thread.LatestReply)
+    }
+    messages, err := store.Messages(ctx, "C1", root.TS)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(messages) != 2 || messages[1].TS != reply.TS || messages[1].Text == nil || *messages[1].Text != "on it" {
+        t.Fatalf("got messages %+v, want the root and the reply", messages)
+    }
+    unsubscribe()
+    if n := published.Load(); n != 2 {
+        t.Fatalf("published %d messages, want the root and the reply once", n)
+    }
+}
+
+func TestRepliesFollowUp(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    ctx := context.Background()
+    root := ingest.MessageEvent{Type: "message", Channel: "C1", User: "U1", Text: "help", TS: "1700000000.000100"}
+    if err := c.applySlackMessage(ctx, ingest.Envelope{}, root); err != nil {
+        t.Fatal(err)
+    }
+    if _, _, err := c.reporterWaits.WaitOnReporter(ctx, "C1", root.TS, "U2", time.Hour); err != nil {
+        t.Fatal(err)
+    }
+
+    // A reply by someone else acknowledges the thread, the author replying
+    // reopens it and thanking marks it as answered
+    for _, reply := range []ingest.MessageEvent{
+        {User: "U2", Text: "which version?", TS: "1700000060.000200"},
+        {User: "U3", Text: "same here", TS: "1700000120.000300"},
+        {User: "U1", Text: "2.20", TS: "1700000180.000400"},
+        {User: "U1", Text: "that worked, thanks", TS: "1700000240.000500"},
+    } {
+        reply.Type, reply.Channel, reply.ThreadTS = "message", "C1", root.TS
+        if err := c.applySlackMessage(ctx, ingest.Envelope{}, reply); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    latencies, err := store.AckLatency(ctx, "C1", time.Time{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(latencies) != 1 || latencies[0].UserID != "U2" || latencies[0].Replies != 1 || int(latencies[0].MedianSeconds) != 60 {
+        t.Fatalf("got latencies %+v, want the reply of U2 after a minute", latencies)
+    }
+    thread, err := store.Thread(ctx, "C1", root.TS)
+    if err != nil {
+        t.Fatal(err)
+    }
+    audited := store.Audited()
+    if thread.Status != statusOpen || len(audited) != 1 || audited[0].Action != "thread.reporter_replied" {
+        t.Fatalf("thread is %s after audits %+v, want it reopened by its author", thread.Status, audited)
+    }
+
+    answered, err := store.AnsweredThreads(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(answered) != 1 || fmt.Sprint(answered[0].Signals) != "[author_thanks]" || !answered[0].DetectedAt.Equal(time.Unix(1700000240, 500000)) {
+        t.Fatalf("got answered threads %+v, want the thanks of the author", answered)
+    }
+}
+
+func TestSharedChannelMarkedOnIngest(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C7", ChannelName: "partners", TextIndexing: true}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    t.Cleanup(func() {
+        sharedChannelsSeen.Delete("C7")
+        externalUsersSeen.Delete("U7")
+    })
+
+    ctx := context.Background()
+    env := ingest.Envelope{TeamID: "T1", IsExtSharedChannel: true}
+    for _, ts := range []string{"1700000000.000100", "1700000060.000200"} {
+        msg := ingest.MessageEvent{Type: "message", Channel: "C7", User: "U7", UserTeam: "T2", Text: "hello", TS: ts}
+        if err := c.applySlackMessage(ctx, env, msg); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if audited := store.Audited(); len(audited) != 1 || audited[0].Action != "channel.slack_connect" || audited[0].ChannelID != "C7" {
+        t.Fatalf("audited %+v, want the channel marked shared once", audited)
+    }
+
+    admin := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+    rec := serveAs(c, admin, http.MethodPut, "/api/channels/:channel_id/slack-connect", "/api/channels/C7/slack-connect",
+        `{"ai_analysis": true}`, c.SetChannelSlackConnect)
+    var settings SlackConnect
+    decodeResponse(t, rec, &settings)
+    if rec.Code != http.StatusOK || !settings.Shared || !settings.AIAnalysis {
+        t.Fatalf("got status %d and %+v, want the shared channel analyzed", rec.Code, settings)
+    }
+}
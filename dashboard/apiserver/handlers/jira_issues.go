@@ -0,0 +1,335 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/jira"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// jiraActor is the actor of audit entries for changes synced from Jira.
+const jiraActor = "jira"
+
+// defaultJiraIssueType is used by mappings that do not name one.
+const defaultJiraIssueType = "Task"
+
+// JiraMapping is the per-channel policy for tickets created from threads.
+// The first rule matching a thread picks the project and issue type,
+// Project and IssueType are used when none does. ResolveOnDone closes
+// threads once their ticket is done.
+type JiraMapping struct {
+    Project       string      `json:"project"`
+    IssueType     string      `json:"issue_type"`
+    Labels        []string    `json:"labels"`
+    ResolveOnDone bool        `json:"resolve_on_done"`
+    Rules         []JiraRoute `json:"rules"`
+}
+
+// JiraRoute sends threads with Priority, or mentioning Keyword in their name
+// or description, to Project as IssueType. An empty condition matches every
+// thread.
+type JiraRoute struct {
+    Priority  string   `json:"priority"`
+    Keyword   string   `json:"keyword"`
+    Project   string   `json:"project"`
+    IssueType string   `json:"issue_type"`
+    Labels    []string `json:"labels"`
+}
+
+// validate checks a mapping, returning a message for the caller or "" when
+// it is usable.
+func (m *JiraMapping) validate() string {
+    m.Project = strings.TrimSpace(m.Project)
+    m.IssueType = strings.TrimSpace(m.IssueType)
+    if !jira.ValidProject(m.Project) {
+        return "project must be a Jira project key"
+    }
+    if m.IssueType == "" {
+        m.IssueType = defaultJiraIssueType
+    }
+    for i := range m.Rules {
+        rule := &m.Rules[i]
+        rule.Project = strings.TrimSpace(rule.Project)
+        rule.IssueType = strings.TrimSpace(rule.IssueType)
+        rule.Keyword = strings.TrimSpace(rule.Keyword)
+        if rule.Project == "" {
+            rule.Project = m.Project
+        }
+        if !jira.ValidProject(rule.Project) {
+            return fmt.Sprintf("rule %d: project must be a Jira project key", i+1)
+        }
+        if rule.IssueType == "" {
+            rule.IssueType = m.IssueType
+        }
+        if rule.Priority != "" && !domain.Priority(rule.Priority).Valid() {
+            return fmt.Sprintf("rule %d: priority must be high, medium or low", i+1)
+        }
+    }
+    return ""
+}
+
+// parseJiraMapping decodes a stored mapping, nil when the channel has none.
+func parseJiraMapping(stored sql.NullString) *JiraMapping {
+    if !stored.Valid {
+        return nil
+    }
+    var mapping JiraMapping
+    if err := json.Unmarshal([]byte(stored.String), &mapping); err != nil || !jira.ValidProject(mapping.Project) {
+        return nil
+    }
+    return &mapping
+}
+
+// route returns the project, issue type and labels for a thread.
+func (m *JiraMapping) route(priority string, text string) (string, string, []string) {
+    for _, rule := range m.Rules {
+        if !routeMatches(rule.Priority, rule.Keyword, priority, text) {
+            continue
+        }
+        return rule.Project, rule.IssueType, append(append([]string{}, m.Labels...), rule.Labels...)
+    }
+    return m.Project, m.IssueType, m.Labels
+}
+
+// CreateThreadJiraTicket - Create a Jira ticket for a thread in the project its channel maps to, and link it
+func (c *Container) CreateThreadJiraTicket(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    if !c.jira.Configured() {
+        return apierror.New(http.StatusServiceUnavailable, "Jira site or token is not configured")
+    }
+
+    reqCtx := ctx.Request().Context()
+    config, err := c.configs.ChannelConfig(reqCtx, channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    ref := ThreadRef{ChannelID: channelID, ThreadTS: threadTS}
+    threads, err := c.threadLists.ThreadsByRef(reqCtx, []ThreadRef{ref})
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread")
+    }
+    thread, ok := threads[ref]
+    if !ok {
+        return apierror.New(http.StatusNotFound, "Thread not found")
+    }
+    if thread.JiraTicket != nil && *thread.JiraTicket != "" {
+        return apierror.New(http.StatusConflict, "Thread already has a Jira ticket").
+            WithDetails(map[string]interface{}{"jira_ticket": *thread.JiraTicket})
+    }
+    name, description, priority := issueSource(thread)
+
+    mapping := config.JiraMapping
+    if mapping == nil {
+        return apierror.New(http.StatusConflict, "Channel has no Jira project configured")
+    }
+    project, issueType, labels := mapping.route(priority, name+"\n"+description)
+
+    createCtx, cancel := context.WithTimeout(reqCtx, issueCreateTimeout)
+    defer cancel()
+    created, err := c.jira.CreateIssue(createCtx, jira.NewIssue{
+        Project:     project,
+        IssueType:   issueType,
+        Summary:     issueTitle(name, thread.ChannelName),
+        Description: issueBody(description, slack.Permalink(channelID, threadTS)),
+        Labels:      labels,
+    })
+    if err != nil {
+        c.logger.Warnf("failed to create Jira ticket in %s for %s/%s: %v", project, channelID, threadTS, err)
+        return apierror.New(http.StatusBadGateway, "Failed to create Jira ticket")
+    }
+
+    linked, err := c.jiraTickets.LinkJiraTicket(reqCtx, channelID, threadTS, created.Key)
+    if err != nil {
+        c.logger.Errorf("created Jira ticket %s but failed to link it to %s/%s: %v", created.Key, channelID, threadTS, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to link Jira ticket")
+    }
+    if !linked {
+        c.logger.Warnf("thread %s/%s was linked concurrently, leaving new ticket %s unlinked", channelID, threadTS, created.Key)
+        return apierror.New(http.StatusConflict, "Thread was linked to another Jira ticket meanwhile").
+            WithDetails(map[string]interface{}{"jira_ticket": created.Key})
+    }
+
+    c.audit(reqCtx, actorFrom(ctx), "thread.jira_ticket", channelID, threadTS, map[string]interface{}{
+        "project":     project,
+        "issue_type":  issueType,
+        "jira_ticket": created.Key,
+    })
+
+    return ctx.JSON(http.StatusCreated, map[string]interface{}{
+        "project":     project,
+        "issue_type":  issueType,
+        "jira_ticket": created.Key,
+        "url":         c.jira.BrowseURL(created.Key),
+    })
+}
+
+// SetChannelJiraMapping - Set or, with an empty body, remove the Jira projects and issue types of a channel's tickets
+func (c *Container) SetChannelJiraMapping(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var mapping *JiraMapping
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&mapping); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if mapping != nil && mapping.Project == "" && len(mapping.Rules) == 0 {
+        mapping = nil
+    }
+    if mapping != nil {
+        if msg := mapping.validate(); msg != "" {
+            return apierror.New(http.StatusBadRequest, msg)
+        }
+    }
+
+    err := c.configs.SetChannelConfig(ctx.Request().Context(), channelID, ChannelConfig{JiraMapping: mapping}, settingJiraMapping)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.jira_mapping", channelID, "", map[string]interface{}{
+        "jira_mapping": mapping,
+    })
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "jira_mapping": mapping,
+    })
+}
+
+// HandleJiraWebhook - Sync status transitions of tickets created from threads back to the threads
+func (c *Container) HandleJiraWebhook(ctx echo.Context) error {
+    body, err := io.ReadAll(ctx.Request().Body)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "Failed to read request body")
+    }
+    var event jira.IssueEvent
+    if err := json.Unmarshal(body, &event); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid webhook payload")
+    }
+    from, to, ok := event.StatusChange()
+    if !ok || !jira.ValidKey(event.Issue.Key) {
+        return ctx.NoContent(http.StatusOK)
+    }
+    done := event.Issue.Fields.Status.StatusCategory.Key == jira.StatusCategoryDone
+
+    // Jira does not know the workspace, so every database is asked for the
+    // ticket
+    for _, wsCtx := range c.WorkspaceContexts(ctx.Request().Context()) {
+        if err := c.syncJiraTransition(wsCtx, event.Issue.Key, from, to, done); err != nil {
+            c.logger.Errorf("failed to sync Jira transition of %s: %v", event.Issue.Key, err)
+            return apierror.New(http.StatusInternalServerError, "Failed to sync ticket status")
+        }
+    }
+    return ctx.NoContent(http.StatusOK)
+}
+
+// syncJiraTransition records the new status of a ticket tracked in the
+// database of ctx, tells its thread and, when the channel asks for it,
+// closes the thread once the ticket is done.
+func (c *Container) syncJiraTransition(ctx context.Context, key string, from string, to string, done bool) error {
+    ref, tracked, err := c.jiraTickets.SetJiraTicketStatus(ctx, key, to)
+    if err != nil || !tracked {
+        return err
+    }
+    channelID, threadTS := ref.ChannelID, ref.ThreadTS
+    config, err := c.configs.ChannelConfig(ctx, channelID)
+    if err != nil && err != errChannelNotTracked {
+        return err
+    }
+
+    c.audit(ctx, jiraActor, "thread.jira_status", channelID, threadTS, map[string]interface{}{
+        "jira_ticket": key,
+        "from":        from,
+        "to":          to,
+    })
+
+    closed := false
+    if mapping := config.JiraMapping; done && mapping != nil && mapping.ResolveOnDone {
+        closed, err = c.resolutions.CloseThread(ctx, channelID, threadTS, jiraActor, resolutionMovedToIssue, key)
+        if err != nil {
+            return err
+        }
+        if closed {
+            c.audit(ctx, jiraActor, "thread.resolve", channelID, threadTS, map[string]interface{}{
+                "jira_ticket": key,
+                "resolution":  resolutionMovedToIssue,
+            })
+        }
+    }
+
+    if c.slackFeatureReady(slackFeatureReplies) {
+        text := fmt.Sprintf("Jira ticket <%s|%s> moved from %s to %s.", c.jira.BrowseURL(key), key, from, to)
+        if closed {
+            text += " This thread is now resolved."
+        }
+        if err := c.slack.PostReply(ctx, channelID, threadTS, text); err != nil {
+            c.logger.Warnf("failed to post Jira transition in %s/%s: %v", channelID, threadTS, err)
+        }
+    }
+    return nil
+}
+
+func (s postgresStore) LinkJiraTicket(ctx context.Context, channelID string, threadTS string, key string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+    result, err := db.ExecContext(ctx, `
+        UPDATE threads SET jira_ticket = $1, updated_at = NOW()
+        WHERE thread_ts = $2 AND channel_id = $3 AND (jira_ticket IS NULL OR jira_ticket = '')
+    `, key, threadTS, channelID)
+    if err != nil {
+        return false, err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return false, nil
+    }
+
+    // Tickets are remembered to sync their transitions back to the thread
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO thread_jira_tickets (ticket_key, channel_id, thread_ts)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (ticket_key) DO NOTHING
+    `, key, channelID, threadTS)
+    if err != nil {
+        s.c.logger.Warnf("failed to track Jira ticket %s of %s/%s: %v", key, channelID, threadTS, err)
+    }
+    return true, nil
+}
+
+func (s postgresStore) SetJiraTicketStatus(ctx context.Context, key string, status string) (ThreadRef, bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ThreadRef{}, false, err
+    }
+    var ref ThreadRef
+    err = db.QueryRowContext(ctx, `
+        UPDATE thread_jira_tickets SET status = $2, updated_at = NOW()
+        WHERE ticket_key = $1
+        RETURNING channel_id, thread_ts
+    `, key, status).Scan(&ref.ChannelID, &ref.ThreadTS)
+    if err == sql.ErrNoRows {
+        return ThreadRef{}, false, nil
+    }
+    if err != nil {
+        return ThreadRef{}, false, err
+    }
+    return ref, true, nil
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/jira"
+)
+
+func TestJiraTicketLifecycle(t *testing.T) {
+    var created []map[string]interface{}
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var issue struct {
+            Fields map[string]interface{} `json:"fields"`
+        }
+        json.NewDecoder(r.Body).Decode(&issue)
+        created = append(created, issue.Fields)
+        w.WriteHeader(http.StatusCreated)
+        w.Write([]byte(`{"id": "10001", "key": "OPS-7"}`))
+    }))
+    t.Cleanup(server.Close)
+
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen}, Title: "Deploys time out"})
+    store.SetChannelConfig(context.Background(), "C1", ChannelConfig{JiraMapping: &JiraMapping{Project: "OPS", ResolveOnDone: true}}, settingJiraMapping)
+    slackClient := &MemorySlack{}
+    c := newTestContainer(t, store, slackClient)
+    c.jira = jira.NewClient(server.URL, "bot@example.com", "token")
+    user := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    route := "/api/threads/:channel_id/:thread_ts/jira-ticket"
+    target := "/api/threads/C1/1700000000.000100/jira-ticket"
+    rec := serveAs(c, user, http.MethodPost, route, target, "", c.CreateThreadJiraTicket)
+    if rec.Code != http.StatusCreated {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    if len(created) != 1 || created[0]["summary"] != issueTitle("Deploys time out", "support") {
+        t.Fatalf("created tickets %+v", created)
+    }
+    if thread, _ := store.Thread(context.Background(), "C1", "1700000000.000100"); thread.JiraTicket != "OPS-7" {
+        t.Fatalf("got Jira ticket %q linked", thread.JiraTicket)
+    }
+    if rec := serveAs(c, user, http.MethodPost, route, target, "", c.CreateThreadJiraTicket); rec.Code != http.StatusConflict || len(created) != 1 {
+        t.Fatalf("a linked thread got status %d after %d tickets", rec.Code, len(created))
+    }
+
+    webhook := func(key string) {
+        t.Helper()
+        body := `{"webhookEvent": "jira:issue_updated",
+            "issue": {"key": "` + key + `", "fields": {"status": {"name": "Done", "statusCategory": {"key": "done"}}}},
+            "changelog": {"items": [{"field": "status", "fromString": "In Progress", "toString": "Done"}]}}`
+        if rec := serveAs(c, nil, http.MethodPost, "/api/webhooks/jira", "/api/webhooks/jira", body, c.HandleJiraWebhook); rec.Code != http.StatusOK {
+            t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+        }
+    }
+    // Tickets not created from a thread are ignored
+    webhook("OPS-8")
+    if len(slackClient.Messages()) != 0 {
+        t.Fatalf("posted %+v for an untracked ticket", slackClient.Messages())
+    }
+
+    webhook("OPS-7")
+    if status, _ := store.JiraTicketStatus(context.Background(), "OPS-7"); status != "Done" {
+        t.Fatalf("got status %q recorded", status)
+    }
+    if thread, _ := store.Thread(context.Background(), "C1", "1700000000.000100"); thread.Status != statusClosed {
+        t.Fatalf("got thread %s once its ticket is done", thread.Status)
+    }
+    messages := slackClient.Messages()
+    if len(messages) != 1 || messages[0].ThreadTS != "1700000000.000100" || !strings.Contains(messages[0].Text, "now resolved") {
+        t.Fatalf("posted %+v", messages)
+    }
+}
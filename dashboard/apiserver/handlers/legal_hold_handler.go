@@ -0,0 +1,236 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+// errLegalHold is returned when a destructive action targets held data.
+var errLegalHold = errors.New("data is under legal hold")
+
+// errLegalHoldNotFound is returned when releasing a hold that is not active.
+var errLegalHoldNotFound = errors.New("legal hold not found")
+
+// LegalHold represents a legal hold on a channel or a single thread
+type LegalHold struct {
+    ID         int64      `json:"id"`
+    ChannelID  string     `json:"channel_id"`
+    ThreadTS   *string    `json:"thread_ts"`
+    Reason     string     `json:"reason"`
+    PlacedBy   string     `json:"placed_by"`
+    PlacedAt   time.Time  `json:"placed_at"`
+    ReleasedBy *string    `json:"released_by"`
+    ReleasedAt *time.Time `json:"released_at"`
+}
+
+// LegalHoldRequest is the body accepted by PlaceLegalHold
+type LegalHoldRequest struct {
+    ChannelID string `json:"channel_id"`
+    ThreadTS  string `json:"thread_ts"`
+    Reason    string `json:"reason"`
+}
+
+// activeHolds describes what is currently held in a channel.
+type activeHolds struct {
+    channel bool
+    threads map[string]bool
+}
+
+// covers reports whether a thread of the channel is held.
+func (h activeHolds) covers(threadTS string) bool {
+    return h.channel || h.threads[threadTS]
+}
+
+// loadActiveHolds returns the active holds of every channel.
+func loadActiveHolds(db *sql.DB) (map[string]activeHolds, error) {
+    rows, err := db.Query("SELECT channel_id, thread_ts FROM legal_holds WHERE released_at IS NULL")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    holds := make(map[string]activeHolds)
+    for rows.Next() {
+        var channelID string
+        var threadTS sql.NullString
+        if err := rows.Scan(&channelID, &threadTS); err != nil {
+            return nil, err
+        }
+        h := holds[channelID]
+        if h.threads == nil {
+            h.threads = make(map[string]bool)
+        }
+        if threadTS.Valid {
+            h.threads[threadTS.String] = true
+        } else {
+            h.channel = true
+        }
+        holds[channelID] = h
+    }
+    return holds, rows.Err()
+}
+
+// checkLegalHold must be called by archival, purge and retention code before
+// deleting or rewriting data. An empty threadTS targets the whole channel,
+// which is blocked if the channel or any of its threads is held, and an
+// empty channelID targets every channel. Blocked attempts are audited.
+func (c *Container) checkLegalHold(ctx context.Context, actor string, action string, channelID string, threadTS string) error {
+    held, err := c.holds.Block(ctx, actor, action, channelID, threadTS)
+    if err != nil {
+        return err
+    }
+    if !held {
+        return nil
+    }
+    c.logger.Warnf("blocked %s by %s on %s/%s: legal hold", action, actor, channelID, threadTS)
+    return errLegalHold
+}
+
+// GetLegalHolds - List legal holds, active ones only unless all=true
+func (c *Container) GetLegalHolds(ctx echo.Context) error {
+    all, _ := strconv.ParseBool(ctx.QueryParam("all"))
+    holds, err := c.holds.LegalHolds(ctx.Request().Context(), all)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query legal holds")
+    }
+
+    return ctx.JSON(http.StatusOK, holds)
+}
+
+// PlaceLegalHold - Put a channel or a single thread on legal hold
+func (c *Container) PlaceLegalHold(ctx echo.Context) error {
+    var req LegalHoldRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    req.Reason = strings.TrimSpace(req.Reason)
+    if req.ChannelID == "" || req.Reason == "" {
+        return apierror.New(http.StatusBadRequest, "channel_id and reason are required")
+    }
+
+    if err := c.channels.Tracked(ctx.Request().Context(), req.ChannelID); err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    } else if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    actor := actorFrom(ctx)
+    hold := LegalHold{ChannelID: req.ChannelID, Reason: req.Reason, PlacedBy: actor}
+    if req.ThreadTS != "" {
+        hold.ThreadTS = &req.ThreadTS
+    }
+    hold, err := c.holds.PlaceLegalHold(ctx.Request().Context(), hold)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to place legal hold")
+    }
+
+    c.audit(ctx.Request().Context(), actor, "legal_hold.placed", req.ChannelID, req.ThreadTS, map[string]interface{}{
+        "hold_id": hold.ID,
+        "reason":  req.Reason,
+    })
+
+    return ctx.JSON(http.StatusCreated, hold)
+}
+
+// ReleaseLegalHold - Release a legal hold
+func (c *Container) ReleaseLegalHold(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid legal hold id")
+    }
+
+    actor := actorFrom(ctx)
+    hold, err := c.holds.ReleaseLegalHold(ctx.Request().Context(), id, actor)
+    if err == errLegalHoldNotFound {
+        return apierror.New(http.StatusNotFound, "Active legal hold not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to release legal hold")
+    }
+
+    var threadTS string
+    if hold.ThreadTS != nil {
+        threadTS = *hold.ThreadTS
+    }
+    c.audit(ctx.Request().Context(), actor, "legal_hold.released", hold.ChannelID, threadTS, map[string]interface{}{
+        "hold_id": id,
+    })
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+func (s postgresStore) LegalHolds(ctx context.Context, all bool) ([]LegalHold, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    query := `
+        SELECT id, channel_id, thread_ts, reason, placed_by, placed_at, released_by, released_at
+        FROM legal_holds`
+    if !all {
+        query += " WHERE released_at IS NULL"
+    }
+    query += " ORDER BY placed_at DESC"
+
+    rows, err := db.QueryContext(ctx, query)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    holds := []LegalHold{}
+    for rows.Next() {
+        var hold LegalHold
+        err := rows.Scan(&hold.ID, &hold.ChannelID, &hold.ThreadTS, &hold.Reason,
+            &hold.PlacedBy, &hold.PlacedAt, &hold.ReleasedBy, &hold.ReleasedAt)
+        if err != nil {
+            continue
+        }
+        holds = append(holds, hold)
+    }
+    return holds, rows.Err()
+}
+
+func (s postgresStore) PlaceLegalHold(ctx context.Context, hold LegalHold) (LegalHold, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return LegalHold{}, err
+    }
+
+    err = db.QueryRowContext(ctx, `
+        INSERT INTO legal_holds (channel_id, thread_ts, reason, placed_by)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, placed_at
+    `, hold.ChannelID, hold.ThreadTS, hold.Reason, hold.PlacedBy).Scan(&hold.ID, &hold.PlacedAt)
+    return hold, err
+}
+
+func (s postgresStore) ReleaseLegalHold(ctx context.Context, id int64, actor string) (LegalHold, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return LegalHold{}, err
+    }
+
+    var hold LegalHold
+    err = db.QueryRowContext(ctx, `
+        UPDATE legal_holds SET released_at = NOW(), released_by = $2
+        WHERE id = $1 AND released_at IS NULL
+        RETURNING id, channel_id, thread_ts, reason, placed_by, placed_at, released_by, released_at
+    `, id, actor).Scan(&hold.ID, &hold.ChannelID, &hold.ThreadTS, &hold.Reason,
+        &hold.PlacedBy, &hold.PlacedAt, &hold.ReleasedBy, &hold.ReleasedAt)
+    if err == sql.ErrNoRows {
+        return LegalHold{}, errLegalHoldNotFound
+    }
+    return hold, err
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/exports"
+)
+
+func TestClearTextRefusedUnderHold(t *testing.T) {
+    threadTS := "1700000000.000100"
+    for _, hold := range []LegalHold{
+        {ChannelID: "C1"},
+        {ChannelID: "C1", ThreadTS: &threadTS},
+    } {
+        store := NewMemoryStore()
+        store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", TextIndexing: true}})
+        store.AddLegalHold(hold)
+        c := newTestContainer(t, store, &MemorySlack{})
+        admin := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+
+        rec := serveAs(c, admin, http.MethodPut, "/api/channels/:channel_id/text-indexing", "/api/channels/C1/text-indexing",
+            `{"enabled": false}`, c.SetChannelTextIndexing)
+        if rec.Code != http.StatusConflict {
+            t.Fatalf("got status %d, want the clearing refused: %s", rec.Code, rec.Body.String())
+        }
+        want := []BlockedAction{{Actor: "U1", Action: "channel.clear_text", ChannelID: "C1"}}
+        if got := store.Blocked(); fmt.Sprint(got) != fmt.Sprint(want) {
+            t.Fatalf("audited %+v, want %+v", got, want)
+        }
+    }
+}
+
+func TestCleanupExportsKeepsHeldExports(t *testing.T) {
+    files, err := exports.NewLocalStore(t.TempDir())
+    if err != nil {
+        t.Fatal(err)
+    }
+    threadTS := "1700000000.000100"
+    released := time.Now().Add(-time.Hour)
+    store := NewMemoryStore()
+    store.AddLegalHold(LegalHold{ChannelID: "C1", ThreadTS: &threadTS})
+    store.AddLegalHold(LegalHold{ChannelID: "C2", ReleasedAt: &released})
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.exportStore = files
+
+    expiredAt := time.Now().Add(-time.Minute)
+    for id, channel := range map[int64]string{1: "C1", 2: "C2", 3: ""} {
+        key := fmt.Sprintf("export-%d.csv", id)
+        if _, err := files.Put(key, strings.NewReader("channel_id\n")); err != nil {
+            t.Fatal(err)
+        }
+        store.AddExport(ExportJob{ID: id, Status: exportCompleted, ExpiresAt: &expiredAt,
+            filters: ExportRequest{Channel: channel}, objectKey: key})
+    }
+
+    c.cleanupExports(context.Background())
+
+    // Exports of the held channel and of every channel are kept, the
+    // released hold no longer keeps the export of its channel
+    for id, kept := range map[int64]bool{1: true, 2: false, 3: true} {
+        job, _ := store.Export(context.Background(), id)
+        file, err := files.Open(job.objectKey)
+        if err == nil {
+            file.Close()
+        }
+        if (job.Status == exportCompleted) != kept || (err == nil) != kept {
+            t.Errorf("export %d has status %s and file error %v, want kept %v", id, job.Status, err, kept)
+        }
+    }
+    want := []BlockedAction{
+        {Actor: "system", Action: "export.expire", ChannelID: "C1"},
+        {Actor: "system", Action: "export.expire"},
+    }
+    if got := store.Blocked(); fmt.Sprint(got) != fmt.Sprint(want) {
+        t.Fatalf("audited %+v, want %+v", got, want)
+    }
+}
+
+func TestLegalHoldLifecycle(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    admin := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+    list := func(target string) []LegalHold {
+        rec := serveAs(c, admin, http.MethodGet, "/api/admin/legal-holds", target, "", c.GetLegalHolds)
+        var holds []LegalHold
+        decodeResponse(t, rec, &holds)
+        return holds
+    }
+
+    rec := serveAs(c, admin, http.MethodPost, "/api/admin/legal-holds", "/api/admin/legal-holds", `{"channel_id": "C2", "reason": "Case 12"}`, c.PlaceLegalHold)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("holding an untracked channel got status %d", rec.Code)
+    }
+    rec = serveAs(c, admin, http.MethodPost, "/api/admin/legal-holds", "/api/admin/legal-holds",
+        `{"channel_id": "C1", "thread_ts": "1700000000.000100", "reason": " Case 12 "}`, c.PlaceLegalHold)
+    if rec.Code != http.StatusCreated {
+        t.Fatalf("placing the hold got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var hold LegalHold
+    decodeResponse(t, rec, &hold)
+    if hold.ID == 0 || hold.Reason != "Case 12" || hold.PlacedBy != "U1" || hold.ThreadTS == nil {
+        t.Fatalf("placed %+v", hold)
+    }
+    if held, _ := store.Block(context.Background(), "system", "thread.purge", "C1", "1700000000.000100"); !held {
+        t.Fatal("the held thread was not blocked")
+    }
+
+    target := fmt.Sprintf("/api/admin/legal-holds/%d", hold.ID)
+    if rec := serveAs(c, admin, http.MethodDelete, "/api/admin/legal-holds/:id", target, "", c.ReleaseLegalHold); rec.Code != http.StatusNoContent {
+        t.Fatalf("releasing the hold got status %d", rec.Code)
+    }
+    if rec := serveAs(c, admin, http.MethodDelete, "/api/admin/legal-holds/:id", target, "", c.ReleaseLegalHold); rec.Code != http.StatusNotFound {
+        t.Fatalf("releasing the hold twice got status %d", rec.Code)
+    }
+    if holds := list("/api/admin/legal-holds"); len(holds) != 0 {
+        t.Fatalf("listed active holds %+v after releasing", holds)
+    }
+    if holds := list("/api/admin/legal-holds?all=true"); len(holds) != 1 || holds[0].ReleasedBy == nil || *holds[0].ReleasedBy != "U1" {
+        t.Fatalf("listed holds %+v, want the released one", holds)
+    }
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/labstack/echo/v4"
+    "golang.org/x/net/websocket"
+)
+
+// Kinds of thread change pushed to live update subscribers.
+const (
+    LiveThreadCreated  = "thread.created"
+    LiveThreadUpdated  = "thread.updated"
+    LiveThreadStatus   = "thread.status"
+    LiveThreadAnalyzed = "thread.analyzed"
+    // livePing keeps idle connections from being closed by proxies
+    livePing = "ping"
+)
+
+// liveWindow bounds the threads watched for changes to open ones and those
+// active recently, so polling stays cheap on large channels.
+const liveWindow = 7 * 24 * time.Hour
+
+// liveMinInterval is the least time between polls woken up by events.
+const liveMinInterval = time.Second
+
+// livePingInterval is how often idle subscribers get a ping.
+const livePingInterval = 30 * time.Second
+
+// LiveEvent is a thread change pushed to dashboard clients
+type LiveEvent struct {
+    Type   string  `json:"type"`
+    Thread *Thread `json:"thread,omitempty"`
+    // workspace is the workspace database the thread is stored in, only
+    // clients of that workspace get the event
+    workspace string
+}
+
+// liveThreadState is what a poll remembers of a thread to tell what
+// changed by the next one.
+type liveThreadState struct {
+    status     string
+    replyCount int
+    analysis   string
+    createdAt  time.Time
+}
+
+// liveHub fans thread changes out to the connected clients. Channel tables
+// are written by the collector as well as by the API, and YugabyteDB has no
+// LISTEN/NOTIFY, so changes are found by diffing periodic polls. Nothing is
+// polled while no client is connected.
+type liveHub struct {
+    mu sync.Mutex
+    // subscribers are the channels of connected clients with the workspace
+    // they watch
+    subscribers map[chan LiveEvent]string
+    // pokes wake the poll of a workspace up early
+    pokes map[string]chan struct{}
+}
+
+func newLiveHub() *liveHub {
+    return &liveHub{subscribers: make(map[chan LiveEvent]string), pokes: make(map[string]chan struct{})}
+}
+
+// pokeChannel returns the channel waking the poll of workspace up.
+func (h *liveHub) pokeChannel(workspace string) chan struct{} {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    poke, ok := h.pokes[workspace]
+    if !ok {
+        poke = make(chan struct{}, 1)
+        h.pokes[workspace] = poke
+    }
+    return poke
+}
+
+// poke has the poll of workspace run without waiting for the next tick,
+// e.g. once a message was ingested.
+func (h *liveHub) poke(workspace string) {
+    select {
+    case h.pokeChannel(workspace) <- struct{}{}:
+    default:
+    }
+}
+
+func (h *liveHub) subscribe(workspace string) chan LiveEvent {
+    sub := make(chan LiveEvent, 64)
+    h.mu.Lock()
+    h.subscribers[sub] = workspace
+    h.mu.Unlock()
+    return sub
+}
+
+func (h *liveHub) unsubscribe(sub chan LiveEvent) {
+    h.mu.Lock()
+    delete(h.subscribers, sub)
+    h.mu.Unlock()
+}
+
+func (h *liveHub) active() bool {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    return len(h.subscribers) > 0
+}
+
+func (h *liveHub) publish(ev LiveEvent) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    for sub, workspace := range h.subscribers {
+        if workspace != ev.workspace {
+            continue
+        }
+        select {
+        case sub <- ev:
+        default:
+            // Slow subscribers miss events rather than block the others
+        }
+    }
+}
+
+// RunLiveUpdates polls the threads table for thread changes while live update
+// clients are connected, every liveInterval and whenever the event bus
+// tells a thread changed, until ctx is cancelled.
+func (c *Container) RunLiveUpdates(ctx context.Context) {
+    ticker := time.NewTicker(c.liveInterval)
+    defer ticker.Stop()
+
+    pokes := c.live.pokeChannel(workspaceFrom(ctx))
+    var previous map[string]map[string]liveThreadState
+    var polledAt time.Time
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        case <-pokes:
+            // Bursts of events poll at most once a second
+            if time.Since(polledAt) < liveMinInterval {
+                continue
+            }
+        }
+        if !c.live.active() {
+            // Start over from a fresh baseline once someone connects
+            previous = nil
+            continue
+        }
+        started := time.Now()
+        current, err := c.pollLiveUpdates(ctx, previous, polledAt)
+        if err != nil {
+            if ctx.Err() == nil {
+                c.logger.Warnf("failed to poll thread changes: %v", err)
+            }
+            continue
+        }
+        previous, polledAt = current, started
+    }
+}
+
+// pollLiveUpdates snapshots the watched threads of every channel and
+// publishes how they differ from previous. Channels missing from previous
+// only get their baseline recorded.
+func (c *Container) pollLiveUpdates(ctx context.Context, previous map[string]map[string]liveThreadState, polledAt time.Time) (map[string]map[string]liveThreadState, error) {
+    channels, err := c.channels.Channels(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    current := make(map[string]map[string]liveThreadState, len(channels))
+    changes := make(map[ThreadRef]string)
+    since := time.Now().UTC().Add(-liveWindow)
+    for _, ch := range channels {
+        // Analysis is compared by digest, and not at all when the channel
+        // opted out of text indexing since clients never see it
+        states, err := c.liveThreads.WatchedThreads(ctx, ch.ChannelID, since, ch.TextIndexing)
+        if err != nil {
+            c.logger.Warnf("failed to poll threads of channel %s: %v", ch.ChannelID, err)
+            if states, ok := previous[ch.ChannelID]; ok {
+                current[ch.ChannelID] = states
+            }
+            continue
+        }
+        current[ch.ChannelID] = states
+        if _, tracked := previous[ch.ChannelID]; !tracked {
+            // Channels tracked since the last poll start from a baseline
+            continue
+        }
+
+        for ts, state := range states {
+            ref := ThreadRef{ChannelID: ch.ChannelID, ThreadTS: ts}
+            before, seen := previous[ch.ChannelID][ts]
+            switch {
+            case !seen && !state.createdAt.Before(polledAt.UTC().Add(-c.liveInterval)):
+                changes[ref] = LiveThreadCreated
+            case !seen || before.replyCount != state.replyCount:
+                changes[ref] = LiveThreadUpdated
+            case before.status != state.status:
+                changes[ref] = LiveThreadStatus
+            case before.analysis != state.analysis:
+                changes[ref] = LiveThreadAnalyzed
+            }
+        }
+        // A thread idle for longer than the window leaves it when resolved,
+        // its status change is still sent
+        for ts, before := range previous[ch.ChannelID] {
+            if _, ok := states[ts]; !ok && before.status == "open" {
+                changes[ThreadRef{ChannelID: ch.ChannelID, ThreadTS: ts}] = LiveThreadStatus
+            }
+        }
+    }
+    if len(changes) == 0 {
+        return current, nil
+    }
+
+    refs := make([]ThreadRef, 0, len(changes))
+    for ref := range changes {
+        refs = append(refs, ref)
+    }
+    sort.Slice(refs, func(i, j int) bool {
+        return refs[i].ChannelID < refs[j].ChannelID || (refs[i].ChannelID == refs[j].ChannelID && refs[i].ThreadTS < refs[j].ThreadTS)
+    })
+    threads, err := c.threadLists.ThreadsByRef(ctx, refs)
+    if err != nil {
+        c.logger.Warnf("failed to load changed threads: %v", err)
+        return current, nil
+    }
+    for _, ref := range refs {
+        if thread, ok := threads[ref]; ok {
+            c.live.publish(LiveEvent{Type: changes[ref], Thread: &thread, workspace: workspaceFrom(ctx)})
+        }
+    }
+    return current, nil
+}
+
+func (s postgresStore) WatchedThreads(ctx context.Context, channelID string, since time.Time, analyzed bool) (map[string]liveThreadState, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT thread_ts, status, reply_count, created_at,
+               CASE WHEN $2 THEN md5(COALESCE(ai_thread_name, '') || '|' || COALESCE(ai_description, '') || '|' || COALESCE(ai_priority, ''))
+                    ELSE '' END
+        FROM threads WHERE channel_id = $3 AND (status = 'open' OR latest_reply > $1)
+    `, since, analyzed, channelID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    states := make(map[string]liveThreadState)
+    for rows.Next() {
+        var ts string
+        var state liveThreadState
+        if err := rows.Scan(&ts, &state.status, &state.replyCount, &state.createdAt, &state.analysis); err != nil {
+            continue
+        }
+        states[ts] = state
+    }
+    return states, rows.Err()
+}
+
+// StreamLiveUpdates - Push thread changes to the dashboard over a WebSocket as they happen
+func (c *Container) StreamLiveUpdates(ctx echo.Context) error {
+    workspace := workspaceFrom(ctx.Request().Context())
+    server := websocket.Server{
+        Handshake: sameOriginHandshake,
+        Handler: func(ws *websocket.Conn) {
+            subscribe := func() chan LiveEvent { return c.live.subscribe(workspace) }
+            ServeLiveUpdates(ws, subscribe, c.live.unsubscribe)
+        },
+    }
+    server.ServeHTTP(ctx.Response(), ctx.Request())
+    return nil
+}
+
+// sameOriginHandshake refuses WebSocket connections opened by pages of other
+// sites, which would otherwise ride on the credentials of the browser.
+// Clients that are not browsers send no Origin and are let through.
+func sameOriginHandshake(config *websocket.Config, req *http.Request) error {
+    origin := req.Header.Get("Origin")
+    if origin == "" {
+        return nil
+    }
+    parsed, err := url.Parse(origin)
+    if err != nil || parsed.Host != req.Host {
+        return fmt.Errorf("cross-origin WebSocket from %q refused", origin)
+    }
+    config.Origin = parsed
+    return nil
+}
+
+// ServeLiveUpdates writes the events of a subscription to ws as JSON
+// messages until the client goes away. It is shared with the demo server.
+func ServeLiveUpdates(ws *websocket.Conn, subscribe func() chan LiveEvent, unsubscribe func(chan LiveEvent)) {
+    defer ws.Close()
+    sub := subscribe()
+    defer unsubscribe(sub)
+
+    // Clients only ever close the connection, reading notices that
+    closed := make(chan struct{})
+    go func() {
+        defer close(closed)
+        var discard string
+        for websocket.Message.Receive(ws, &discard) == nil {
+        }
+    }()
+
+    ping := time.NewTicker(livePingInterval)
+    defer ping.Stop()
+    for {
+        var ev LiveEvent
+        select {
+        case <-closed:
+            return
+        case <-ping.C:
+            ev = LiveEvent{Type: livePing}
+        case ev = <-sub:
+        }
+        if err := websocket.JSON.Send(ws, ev); err != nil {
+            return
+        }
+    }
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+)
+
+func TestPollLiveUpdatesPublishesChanges(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    old := time.Now().Add(-time.Hour)
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", UserID: "U1", Status: statusOpen, CreatedAt: old, LatestReply: old}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", UserID: "U1", Status: statusOpen, CreatedAt: old, LatestReply: old}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000300", UserID: "U1", Status: statusOpen, CreatedAt: old, LatestReply: old}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.live, c.liveInterval = newLiveHub(), time.Minute
+    sub := c.live.subscribe("")
+    ctx := context.Background()
+
+    polledAt := time.Now()
+    previous, err := c.pollLiveUpdates(ctx, nil, polledAt)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(sub) != 0 {
+        t.Fatalf("published %d events for the baseline", len(sub))
+    }
+
+    now := time.Now()
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", UserID: "U1", Status: statusOpen, ReplyCount: 1, CreatedAt: old, LatestReply: now}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000300", UserID: "U1", Status: statusResolved, CreatedAt: old, LatestReply: now}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000400", UserID: "U2", Status: statusOpen, CreatedAt: now, LatestReply: now}})
+    if _, err := c.pollLiveUpdates(ctx, previous, polledAt); err != nil {
+        t.Fatal(err)
+    }
+
+    got := map[string]string{}
+    for len(sub) > 0 {
+        ev := <-sub
+        got[ev.Thread.ThreadTS] = ev.Type
+    }
+    want := map[string]string{
+        "1700000000.000100": LiveThreadUpdated,
+        "1700000000.000300": LiveThreadStatus,
+        "1700000000.000400": LiveThreadCreated,
+    }
+    if len(got) != len(want) {
+        t.Fatalf("published %v, want %v", got, want)
+    }
+    for ts, kind := range want {
+        if got[ts] != kind {
+            t.Fatalf("published %v, want %v", got, want)
+        }
+    }
+}
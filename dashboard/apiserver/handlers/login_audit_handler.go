@@ -0,0 +1,147 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+)
+
+// LoginAuditEntry represents a row of the login audit trail
+type LoginAuditEntry struct {
+    ID        int64     `json:"id"`
+    UserID    *string   `json:"user_id"`
+    RemoteIP  string    `json:"remote_ip"`
+    UserAgent string    `json:"user_agent"`
+    Method    string    `json:"method"`
+    Success   bool      `json:"success"`
+    Reason    string    `json:"reason"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// loginAuditFilter narrows the login audit trail down, empty fields
+// matching every entry.
+type loginAuditFilter struct {
+    userID   string
+    remoteIP string
+    success  *bool
+    since    time.Time
+    limit    int
+}
+
+// RecordLoginEvent stores an authentication attempt in the login audit trail.
+// Failures to write are logged rather than failing the request.
+func (c *Container) RecordLoginEvent(event auth.LoginEvent) {
+    if err := c.sessions.RecordLoginEvent(context.Background(), event); err != nil {
+        c.logger.Errorf("failed to record login event: %v", err)
+    }
+}
+
+// GetLoginAudit - Query the login audit trail (admin only)
+func (c *Container) GetLoginAudit(ctx echo.Context) error {
+    filter := loginAuditFilter{
+        userID:   ctx.QueryParam("user_id"),
+        remoteIP: ctx.QueryParam("remote_ip"),
+        limit:    100,
+    }
+    if limitStr := ctx.QueryParam("limit"); limitStr != "" {
+        if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 1000 {
+            filter.limit = parsedLimit
+        }
+    }
+    if successStr := ctx.QueryParam("success"); successStr != "" {
+        success, err := strconv.ParseBool(successStr)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, "success must be true or false")
+        }
+        filter.success = &success
+    }
+    if sinceStr := ctx.QueryParam("since"); sinceStr != "" {
+        since, err := time.Parse(time.RFC3339, sinceStr)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, "since must be an RFC3339 timestamp")
+        }
+        filter.since = since
+    }
+
+    entries, err := c.sessions.LoginAudit(ctx.Request().Context(), filter)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query login audit")
+    }
+    return ctx.JSON(http.StatusOK, entries)
+}
+
+func (s postgresStore) RecordLoginEvent(ctx context.Context, event auth.LoginEvent) error {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return err
+    }
+
+    var userID interface{}
+    if event.UserID != "" {
+        userID = event.UserID
+    }
+
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO login_audit (user_id, remote_ip, user_agent, method, success, reason)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, userID, event.RemoteIP, event.UserAgent, event.Method, event.Success, event.Reason)
+    return err
+}
+
+func (s postgresStore) LoginAudit(ctx context.Context, filter loginAuditFilter) ([]LoginAuditEntry, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+
+    query := `
+        SELECT id, user_id, remote_ip, user_agent, method, success, reason, created_at
+        FROM login_audit
+        WHERE 1=1`
+    args := []interface{}{}
+
+    if filter.userID != "" {
+        args = append(args, filter.userID)
+        query += fmt.Sprintf(" AND user_id = $%d", len(args))
+    }
+    if filter.remoteIP != "" {
+        args = append(args, filter.remoteIP)
+        query += fmt.Sprintf(" AND remote_ip = $%d", len(args))
+    }
+    if filter.success != nil {
+        args = append(args, *filter.success)
+        query += fmt.Sprintf(" AND success = $%d", len(args))
+    }
+    if !filter.since.IsZero() {
+        args = append(args, filter.since)
+        query += fmt.Sprintf(" AND created_at >= $%d", len(args))
+    }
+
+    args = append(args, filter.limit)
+    query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", len(args))
+
+    rows, err := db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    entries := []LoginAuditEntry{}
+    for rows.Next() {
+        var entry LoginAuditEntry
+        err := rows.Scan(&entry.ID, &entry.UserID, &entry.RemoteIP, &entry.UserAgent,
+            &entry.Method, &entry.Success, &entry.Reason, &entry.CreatedAt)
+        if err != nil {
+            continue
+        }
+        entries = append(entries, entry)
+    }
+    return entries, rows.Err()
+}
@@ -0,0 +1,3863 @@
+package handlers
+
+import (
+    "cmp"
+    "context"
+    "database/sql"
+    "encoding/json"
+    "math"
+    "slices"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/analysis"
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/ingest"
+    "dashboard/apiserver/similarity"
+    "dashboard/apiserver/slack"
+    "dashboard/apiserver/webhooks"
+)
+
+// MemoryStore implements the stores of handlers holding what was added to
+// it in memory, for handler tests without a database.
+type MemoryStore struct {
+    mu       sync.Mutex
+    channels map[string]ChannelSummary
+    // shared are the Slack Connect settings of channels, external the
+    // workspaces of users of other workspaces
+    shared   map[string]SlackConnect
+    external map[string]string
+    // configs are the settings channels set, their text indexing and Slack
+    // Connect settings being kept with the channel and in shared
+    configs  map[string]ChannelConfig
+    threads  map[string]StoredThread
+    // messages are keyed by channel and message ts
+    messages map[string]storedMessage
+    // failure is returned by the next change, see FailNext
+    failure error
+    // profiles are keyed by workspace and user, the shared database being
+    // the empty workspace
+    profiles map[string]UserProfile
+    roles    map[string]UserRole
+    // directory is keyed like profiles, precedence by workspace
+    directory  map[string]DirectoryEntry
+    precedence map[string][]string
+    // notes, watchers, efforts, resolutions, snoozes, acks, waits, signals,
+    // assignments, claims, priorities, slas and releases are keyed like
+    // threads
+    notes       map[string][]ThreadNote
+    watchers    map[string][]Watcher
+    efforts     map[string]ThreadEffort
+    resolutions map[string]StatusResult
+    snoozes     map[string]time.Time
+    acks        map[string]ThreadAck
+    waits       map[string]reporterWait
+    signals     map[string][]answerSignal
+    assignments map[string]ThreadAssignment
+    claims      map[string]string
+    priorities  map[string]ThreadPriority
+    slas        map[string]time.Time
+    releases    map[string]threadRelease
+    usergroups  map[string]Usergroup
+    // groups, goals and templates are keyed by ID
+    groups    map[int64]ChannelGroup
+    goals     map[int64]Goal
+    templates map[int64]ReplyTemplate
+    // snapshots are keyed by day and channel
+    snapshots map[string]map[string]snapshotCounts
+    // holds are the legal holds placed, blocked the actions they refused
+    holds   []LegalHold
+    blocked []BlockedAction
+    exports map[int64]ExportJob
+    // audits are the audit trail oldest first, auditedAt when each entry
+    // was appended
+    audits    []AuditEntry
+    auditedAt []time.Time
+    // webhooks, threadHooks, tokens, keys, requests and the exports, notes,
+    // time entries, channel groups, goals, reply templates and legal holds
+    // created share lastID
+    webhooks    map[int64]webhooks.Webhook
+    threadHooks map[int64]ThreadWebhook
+    tokens      map[int64]storedToken
+    keys        map[int64]storedAPIKey
+    requests    map[int64]ResolutionRequest
+    lastID      int64
+    // sessions are keyed by the hash of their cookie, logins are the login
+    // audit trail oldest first
+    sessions map[string]storedSession
+    logins   []LoginAuditEntry
+    // emailPrefs are keyed by address, preferences by user
+    emailPrefs  map[string]EmailPreferences
+    preferences map[string]UserPreferences
+    // qualities are the quality of analyzed threads and qualityPrompts
+    // the details their authors were asked for, keyed like threads
+    qualities      map[string]threadQuality
+    qualityPrompts map[string][]string
+    // stakeholders are those of analyzed threads and suggested the threads
+    // suggested to them, keyed like threads
+    stakeholders map[string][]string
+    suggested    map[string]bool
+    // nudges are when recipients were last reminded of threads
+    nudges map[nudgeKey]time.Time
+    // checkpoints are the progress of backfills keyed by channel
+    checkpoints map[string]ingest.Checkpoint
+    // jiraTickets are the Jira tickets tracked for their thread keyed by
+    // ticket
+    jiraTickets map[string]trackedJiraTicket
+    // slackSummaries are keyed by channel
+    slackSummaries map[string]memorySummary
+    // tables are the sizes MeasureTables returns, storageSnapshots are
+    // keyed by day and table
+    tables           []TableStorage
+    storageSnapshots map[string]storageSnapshot
+    // usage are the calls to the API, digestRuns the runs of the digest
+    // claimed by their schedule
+    usage      map[usageKey]int64
+    digestRuns map[time.Time]bool
+}
+
+// BlockedAction is an action refused by a legal hold, as audited.
+type BlockedAction struct {
+    Actor     string
+    Action    string
+    ChannelID string
+    ThreadTS  string
+}
+
+// NewMemoryStore returns an empty store.
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{
+        channels: map[string]ChannelSummary{},
+        shared:   map[string]SlackConnect{},
+        external: map[string]string{},
+        configs:  map[string]ChannelConfig{},
+        threads:  map[string]StoredThread{},
+        messages: map[string]storedMessage{},
+        profiles: map[string]UserProfile{},
+        roles:    map[string]UserRole{},
+
+        directory:   map[string]DirectoryEntry{},
+        precedence:  map[string][]string{},
+        notes:       map[string][]ThreadNote{},
+        watchers:    map[string][]Watcher{},
+        efforts:     map[string]ThreadEffort{},
+        resolutions: map[string]StatusResult{},
+        snoozes:     map[string]time.Time{},
+        acks:        map[string]ThreadAck{},
+        waits:       map[string]reporterWait{},
+        signals:     map[string][]answerSignal{},
+        assignments: map[string]ThreadAssignment{},
+        claims:      map[string]string{},
+        priorities:  map[string]ThreadPriority{},
+        slas:        map[string]time.Time{},
+        releases:    map[string]threadRelease{},
+        usergroups:  map[string]Usergroup{},
+        groups:      map[int64]ChannelGroup{},
+        goals:       map[int64]Goal{},
+        templates:   map[int64]ReplyTemplate{},
+        snapshots:   map[string]map[string]snapshotCounts{},
+        exports:     map[int64]ExportJob{},
+        webhooks:    map[int64]webhooks.Webhook{},
+        threadHooks: map[int64]ThreadWebhook{},
+        tokens:      map[int64]storedToken{},
+        keys:        map[int64]storedAPIKey{},
+        requests:    map[int64]ResolutionRequest{},
+        sessions:    map[string]storedSession{},
+        emailPrefs:  map[string]EmailPreferences{},
+        preferences: map[string]UserPreferences{},
+        nudges:      map[nudgeKey]time.Time{},
+
+        qualities:      map[string]threadQuality{},
+        qualityPrompts: map[string][]string{},
+        stakeholders:   map[string][]string{},
+        suggested:      map[string]bool{},
+        checkpoints: map[string]ingest.Checkpoint{},
+        jiraTickets: map[string]trackedJiraTicket{},
+
+        slackSummaries:   map[string]memorySummary{},
+        storageSnapshots: map[string]storageSnapshot{},
+        usage:            map[usageKey]int64{},
+        digestRuns:       map[time.Time]bool{},
+    }
+}
+
+// reporterWait is a thread waiting on its author since a time, zero once
+// the wait ended.
+type reporterWait struct {
+    since   time.Time
+    nudgeAt time.Time
+    nudged  bool
+}
+
+// threadRelease is the release a thread waits on, shipped once it has an
+// URL.
+type threadRelease struct {
+    repo string
+    tag  string
+    url  string
+}
+
+// memorySummary is the summary configuration of a channel with the hash
+// of the content last written.
+type memorySummary struct {
+    SlackSummary
+    contentHash *string
+}
+
+// trackedJiraTicket is the thread of a Jira ticket with the status it
+// last transitioned to.
+type trackedJiraTicket struct {
+    ThreadRef
+    status string
+}
+
+// nudgeKey is a recipient reminded of a thread.
+type nudgeKey struct {
+    recipient, channelID, threadTS string
+}
+
+// answerSignal is a signal that a thread looks answered.
+type answerSignal struct {
+    signal     string
+    detectedAt time.Time
+    dismissed  bool
+}
+
+// storedToken is a personal API token with the hash of its secret.
+type storedToken struct {
+    APIToken
+    userID  string
+    hash    string
+    revoked bool
+}
+
+// active reports whether the token is neither revoked nor expired.
+func (t storedToken) active() bool {
+    return !t.revoked && (t.ExpiresAt == nil || t.ExpiresAt.After(time.Now()))
+}
+
+// storedAPIKey is an API key with the hash of its secret.
+type storedAPIKey struct {
+    APIKey
+    hash    string
+    revoked bool
+}
+
+// active reports whether the key is neither revoked nor expired.
+func (k storedAPIKey) active() bool {
+    return !k.revoked && (k.ExpiresAt == nil || k.ExpiresAt.After(time.Now()))
+}
+
+// storedSession is the session of a signed in user.
+type storedSession struct {
+    userID    string
+    name      string
+    email     string
+    expiresAt time.Time
+}
+
+type storedMessage struct {
+    ThreadMessage
+    threadTS string
+}
+
+// FailNext makes the next change to the store other than an audit entry
+// fail with err, leaving the store as it was.
+func (s *MemoryStore) FailNext(err error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.failure = err
+}
+
+// failed returns and clears the failure set by FailNext. s.mu is held.
+func (s *MemoryStore) failed() error {
+    err := s.failure
+    s.failure = nil
+    return err
+}
+
+// AddChannel tracks a channel, replacing any with the same ID.
+func (s *MemoryStore) AddChannel(ch ChannelSummary) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.channels[ch.ChannelID] = ch
+}
+
+// AddThread stores a thread, replacing any with the same channel and ts.
+func (s *MemoryStore) AddThread(thread StoredThread) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.threads[thread.ChannelID+"/"+thread.ThreadTS] = thread
+}
+
+// AddProfile stores the profile of a user in the shared database.
+func (s *MemoryStore) AddProfile(profile UserProfile) {
+    s.AddWorkspaceProfile("", profile)
+}
+
+// AddWorkspaceProfile stores the profile of a user in the database of a
+// workspace.
+func (s *MemoryStore) AddWorkspaceProfile(team string, profile UserProfile) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.profiles[team+"/"+profile.UserID] = profile
+}
+
+// SetRole assigns a role name to a user.
+func (s *MemoryStore) SetRole(userID string, role string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.roles[userID] = UserRole{UserID: userID, Role: auth.Role(role)}
+}
+
+// AddNote adds a triage note to the thread it names.
+func (s *MemoryStore) AddNote(note ThreadNote) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    key := note.ChannelID + "/" + note.ThreadTS
+    s.notes[key] = append(s.notes[key], note)
+}
+
+// AddWatcher makes a user watch a thread.
+func (s *MemoryStore) AddWatcher(channelID string, threadTS string, watcher Watcher) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    key := channelID + "/" + threadTS
+    s.watchers[key] = append(s.watchers[key], watcher)
+}
+
+// SetEffort replaces the estimate and time entries of the thread it names.
+func (s *MemoryStore) SetEffort(effort ThreadEffort) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.efforts[effort.ChannelID+"/"+effort.ThreadTS] = effort
+}
+
+// AddUsergroup stores a usergroup, replacing any with the same ID.
+func (s *MemoryStore) AddUsergroup(group Usergroup) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.usergroups[group.ID] = group
+}
+
+// AddLegalHold places a legal hold, active unless ReleasedAt is set.
+func (s *MemoryStore) AddLegalHold(hold LegalHold) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.holds = append(s.holds, hold)
+}
+
+// Blocked returns the actions refused by legal holds so far, oldest first.
+func (s *MemoryStore) Blocked() []BlockedAction {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]BlockedAction(nil), s.blocked...)
+}
+
+// Audited returns the audit entries appended so far, oldest first.
+func (s *MemoryStore) Audited() []AuditEntry {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]AuditEntry(nil), s.audits...)
+}
+
+// AddExport stores an export job, replacing any with the same ID.
+func (s *MemoryStore) AddExport(job ExportJob) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.exports[job.ID] = job
+}
+
+// SetTables sets the sizes of the tables MeasureTables returns.
+func (s *MemoryStore) SetTables(tables ...TableStorage) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.tables = tables
+}
+
+func (s *MemoryStore) Thread(ctx context.Context, channelID string, threadTS string) (StoredThread, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.channels[channelID]; !ok {
+        return StoredThread{}, errChannelNotTracked
+    }
+    thread, ok := s.threads[channelID+"/"+threadTS]
+    if !ok {
+        return StoredThread{}, errThreadNotFound
+    }
+    return thread, nil
+}
+
+func (s *MemoryStore) Notes(ctx context.Context, channelID string, threadTS string) ([]ThreadNote, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]ThreadNote{}, s.notes[channelID+"/"+threadTS]...), nil
+}
+
+func (s *MemoryStore) CreateNote(ctx context.Context, note ThreadNote) (ThreadNote, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return ThreadNote{}, err
+    }
+    s.lastID++
+    note.ID, note.CreatedAt = s.lastID, time.Now()
+    key := note.ChannelID + "/" + note.ThreadTS
+    s.notes[key] = append(s.notes[key], note)
+    return note, nil
+}
+
+func (s *MemoryStore) RecordNoteSync(ctx context.Context, note ThreadNote) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    notes := s.notes[note.ChannelID+"/"+note.ThreadTS]
+    for i := range notes {
+        if notes[i].ID == note.ID {
+            notes[i].GithubComment, notes[i].JiraComment, notes[i].SyncError = note.GithubComment, note.JiraComment, note.SyncError
+        }
+    }
+    return nil
+}
+
+func (s *MemoryStore) Watchers(ctx context.Context, channelID string, threadTS string) ([]Watcher, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]Watcher{}, s.watchers[channelID+"/"+threadTS]...), nil
+}
+
+func (s *MemoryStore) Effort(ctx context.Context, channelID string, threadTS string) (ThreadEffort, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    effort, ok := s.efforts[channelID+"/"+threadTS]
+    if !ok {
+        return ThreadEffort{ChannelID: channelID, ThreadTS: threadTS, Entries: []TimeEntry{}}, nil
+    }
+    effort.Entries = slices.Clone(effort.Entries)
+    effort.TrackedMinutes = trackedMinutes(effort.Entries, time.Time{}, time.Now().UTC())
+    return effort, nil
+}
+
+// Resolution leaves the note out, the store not keeping it.
+func (s *MemoryStore) Resolution(ctx context.Context, channelID string, threadTS string) (threadResolution, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    key := channelID + "/" + threadTS
+    if _, ok := s.threads[key]; !ok {
+        return threadResolution{}, errThreadNotFound
+    }
+    var resolution threadResolution
+    result := s.resolutions[key]
+    if result.ResolvedBy != nil {
+        resolution.resolvedBy = sql.NullString{String: *result.ResolvedBy, Valid: true}
+    }
+    if result.ResolvedAt != nil {
+        resolution.resolvedAt = sql.NullTime{Time: *result.ResolvedAt, Valid: true}
+    }
+    if result.Resolution != nil {
+        resolution.resolution = sql.NullString{String: *result.Resolution, Valid: true}
+    }
+    return resolution, nil
+}
+
+// effort returns the stored effort of a thread, empty when it has none.
+func (s *MemoryStore) effort(channelID, threadTS string) ThreadEffort {
+    effort, ok := s.efforts[channelID+"/"+threadTS]
+    if !ok {
+        effort = ThreadEffort{ChannelID: channelID, ThreadTS: threadTS}
+    }
+    return effort
+}
+
+func (s *MemoryStore) SetEstimate(ctx context.Context, channelID, threadTS string, minutes *int, actor string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    effort := s.effort(channelID, threadTS)
+    effort.EstimateMinutes = minutes
+    s.efforts[channelID+"/"+threadTS] = effort
+    return nil
+}
+
+func (s *MemoryStore) StartTimer(ctx context.Context, channelID, threadTS, userID string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    effort := s.effort(channelID, threadTS)
+    for _, entry := range effort.Entries {
+        if entry.UserID == userID && entry.StoppedAt == nil {
+            return nil
+        }
+    }
+    s.lastID++
+    effort.Entries = append(effort.Entries, TimeEntry{ID: s.lastID, UserID: userID, StartedAt: time.Now().UTC()})
+    s.efforts[channelID+"/"+threadTS] = effort
+    return nil
+}
+
+func (s *MemoryStore) StopTimer(ctx context.Context, channelID, threadTS, userID string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    effort := s.effort(channelID, threadTS)
+    for i, entry := range effort.Entries {
+        if entry.UserID == userID && entry.StoppedAt == nil {
+            now := time.Now().UTC()
+            effort.Entries[i].StoppedAt = &now
+            return true, nil
+        }
+    }
+    return false, nil
+}
+
+// EffortTotals counts every estimate, the store does not remember when
+// they were set.
+func (s *MemoryStore) EffortTotals(ctx context.Context, since time.Time) ([]EffortTotals, []EffortTotals, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := time.Now().UTC()
+    channels := map[string]*EffortTotals{}
+    people := map[string]*EffortTotals{}
+    for _, effort := range s.efforts {
+        recent := []TimeEntry{}
+        counted := map[string]bool{}
+        for _, entry := range effort.Entries {
+            if entry.StoppedAt != nil && entry.StoppedAt.Before(since) {
+                continue
+            }
+            recent = append(recent, entry)
+            person, ok := people[entry.UserID]
+            if !ok {
+                person = &EffortTotals{UserID: entry.UserID}
+                people[entry.UserID] = person
+            }
+            if !counted[entry.UserID] {
+                person.Threads++
+                counted[entry.UserID] = true
+            }
+            person.TrackedMinutes += trackedMinutes([]TimeEntry{entry}, since, now)
+        }
+        if effort.EstimateMinutes == nil && len(recent) == 0 {
+            continue
+        }
+        channel, ok := channels[effort.ChannelID]
+        if !ok {
+            channel = &EffortTotals{ChannelID: effort.ChannelID}
+            channels[effort.ChannelID] = channel
+        }
+        channel.Threads++
+        if effort.EstimateMinutes != nil {
+            channel.EstimatedMinutes += *effort.EstimateMinutes
+        }
+        channel.TrackedMinutes += trackedMinutes(recent, since, now)
+    }
+
+    channelTotals := []EffortTotals{}
+    for _, totals := range channels {
+        channelTotals = append(channelTotals, *totals)
+    }
+    slices.SortFunc(channelTotals, func(a, b EffortTotals) int { return cmp.Compare(a.ChannelID, b.ChannelID) })
+    personTotals := []EffortTotals{}
+    for _, totals := range people {
+        personTotals = append(personTotals, *totals)
+    }
+    slices.SortFunc(personTotals, func(a, b EffortTotals) int { return cmp.Compare(b.TrackedMinutes, a.TrackedMinutes) })
+    return channelTotals, personTotals, nil
+}
+
+// RecentOpenThreads returns the threads sorted by channel and creation.
+func (s *MemoryStore) RecentOpenThreads(ctx context.Context, since time.Time) ([]domain.Thread, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    threads := []domain.Thread{}
+    for _, thread := range s.threads {
+        if _, tracked := s.channels[thread.ChannelID]; tracked && thread.Status == statusOpen && thread.CreatedAt.After(since) {
+            threads = append(threads, thread.Thread)
+        }
+    }
+    slices.SortFunc(threads, func(a, b domain.Thread) int {
+        return cmp.Or(cmp.Compare(a.ChannelID, b.ChannelID), a.CreatedAt.Compare(b.CreatedAt))
+    })
+    return threads, nil
+}
+
+func (s *MemoryStore) SetReactionWatchers(ctx context.Context, channelID, threadTS string, users []string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    key := channelID + "/" + threadTS
+    watchers := slices.DeleteFunc(s.watchers[key], func(watcher Watcher) bool {
+        return watcher.Source == watcherSourceReaction && !slices.Contains(users, watcher.UserID)
+    })
+    for _, userID := range users {
+        if !slices.ContainsFunc(watchers, func(watcher Watcher) bool { return watcher.UserID == userID }) {
+            watchers = append(watchers, Watcher{UserID: userID, Source: watcherSourceReaction, CreatedAt: time.Now().UTC()})
+        }
+    }
+    s.watchers[key] = watchers
+    return nil
+}
+
+// inGroup returns whether channels belong to a group, an ID or name, every
+// channel belonging to the empty group. s.mu is held.
+func (s *MemoryStore) inGroup(group string) (func(channelID string) bool, error) {
+    if group == "" {
+        return func(string) bool { return true }, nil
+    }
+    for _, g := range s.groups {
+        if strconv.FormatInt(g.ID, 10) == group || g.Name == group {
+            return func(channelID string) bool { return slices.Contains(g.ChannelIDs, channelID) }, nil
+        }
+    }
+    return nil, errGroupNotFound
+}
+
+func (s *MemoryStore) ChannelGroups(ctx context.Context) ([]ChannelGroup, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    groups := []ChannelGroup{}
+    for _, g := range s.groups {
+        g.ChannelIDs = slices.Clone(g.ChannelIDs)
+        groups = append(groups, g)
+    }
+    slices.SortFunc(groups, func(a, b ChannelGroup) int { return cmp.Compare(a.Name, b.Name) })
+    return groups, nil
+}
+
+func (s *MemoryStore) ChannelGroup(ctx context.Context, id int64) (ChannelGroup, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    g, ok := s.groups[id]
+    if !ok {
+        return ChannelGroup{}, errGroupNotFound
+    }
+    g.ChannelIDs = slices.Clone(g.ChannelIDs)
+    return g, nil
+}
+
+// saveGroup stores g with its tracked channels sorted unless another
+// group has its name. s.mu is held.
+func (s *MemoryStore) saveGroup(g ChannelGroup) (ChannelGroup, error) {
+    for _, other := range s.groups {
+        if other.ID != g.ID && other.Name == g.Name {
+            return ChannelGroup{}, errGroupNameTaken
+        }
+    }
+    members := []string{}
+    for _, channelID := range g.ChannelIDs {
+        if _, tracked := s.channels[channelID]; tracked && !slices.Contains(members, channelID) {
+            members = append(members, channelID)
+        }
+    }
+    slices.Sort(members)
+    g.ChannelIDs = members
+    s.groups[g.ID] = g
+    return g, nil
+}
+
+func (s *MemoryStore) CreateChannelGroup(ctx context.Context, g ChannelGroup) (ChannelGroup, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return ChannelGroup{}, err
+    }
+    s.lastID++
+    g.ID, g.CreatedAt = s.lastID, time.Now()
+    return s.saveGroup(g)
+}
+
+func (s *MemoryStore) UpdateChannelGroup(ctx context.Context, g ChannelGroup) (ChannelGroup, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return ChannelGroup{}, err
+    }
+    stored, ok := s.groups[g.ID]
+    if !ok {
+        return ChannelGroup{}, errGroupNotFound
+    }
+    stored.Name, stored.Description, stored.RemindAfter, stored.ChannelIDs = g.Name, g.Description, g.RemindAfter, g.ChannelIDs
+    return s.saveGroup(stored)
+}
+
+func (s *MemoryStore) DeleteChannelGroup(ctx context.Context, id int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    if _, ok := s.groups[id]; !ok {
+        return errGroupNotFound
+    }
+    delete(s.groups, id)
+    return nil
+}
+
+func (s *MemoryStore) Goals(ctx context.Context) ([]Goal, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    goals := []Goal{}
+    for _, g := range s.goals {
+        goals = append(goals, g)
+    }
+    slices.SortFunc(goals, func(a, b Goal) int {
+        return cmp.Or(cmp.Compare(a.DueDate, b.DueDate), cmp.Compare(a.ID, b.ID))
+    })
+    return goals, nil
+}
+
+func (s *MemoryStore) Goal(ctx context.Context, id int64) (Goal, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    g, ok := s.goals[id]
+    if !ok {
+        return Goal{}, errGoalNotFound
+    }
+    return g, nil
+}
+
+func (s *MemoryStore) CreateGoal(ctx context.Context, g Goal) (Goal, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return Goal{}, err
+    }
+    s.lastID++
+    g.ID, g.CreatedAt = s.lastID, time.Now()
+    s.goals[g.ID] = g
+    return g, nil
+}
+
+func (s *MemoryStore) UpdateGoal(ctx context.Context, g Goal) (Goal, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return Goal{}, err
+    }
+    stored, ok := s.goals[g.ID]
+    if !ok {
+        return Goal{}, errGoalNotFound
+    }
+    g.CreatedBy, g.CreatedAt = stored.CreatedBy, stored.CreatedAt
+    s.goals[g.ID] = g
+    return g, nil
+}
+
+func (s *MemoryStore) DeleteGoal(ctx context.Context, id int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    if _, ok := s.goals[id]; !ok {
+        return errGoalNotFound
+    }
+    delete(s.goals, id)
+    return nil
+}
+
+// GoalPoints counts no high priority threads, the snapshots of the store
+// keeping none.
+func (s *MemoryStore) GoalPoints(ctx context.Context, g Goal) ([]GoalPoint, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    points := []GoalPoint{}
+    for day, channels := range s.snapshots {
+        if day < g.StartDate || day > g.DueDate {
+            continue
+        }
+        point := GoalPoint{Date: day}
+        for channelID, counts := range channels {
+            if (g.ChannelID == nil || *g.ChannelID == channelID) && g.Metric == "open_threads" {
+                point.Value += float64(counts.open)
+            }
+        }
+        points = append(points, point)
+    }
+    slices.SortFunc(points, func(a, b GoalPoint) int { return cmp.Compare(a.Date, b.Date) })
+    return points, nil
+}
+
+func (s *MemoryStore) ReplyTemplates(ctx context.Context) ([]ReplyTemplate, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    templates := []ReplyTemplate{}
+    for _, t := range s.templates {
+        templates = append(templates, t)
+    }
+    slices.SortFunc(templates, func(a, b ReplyTemplate) int { return cmp.Compare(a.Name, b.Name) })
+    return templates, nil
+}
+
+func (s *MemoryStore) ReplyTemplate(ctx context.Context, name string) (ReplyTemplate, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, t := range s.templates {
+        if t.Name == name || strconv.FormatInt(t.ID, 10) == name {
+            return t, nil
+        }
+    }
+    return ReplyTemplate{}, errReplyTemplateNotFound
+}
+
+// saveTemplate stores t with the variables of its body unless another
+// template has its name. s.mu is held.
+func (s *MemoryStore) saveTemplate(t ReplyTemplate) (ReplyTemplate, error) {
+    for _, other := range s.templates {
+        if other.ID != t.ID && other.Name == t.Name {
+            return ReplyTemplate{}, errReplyTemplateNameTaken
+        }
+    }
+    t.Variables, t.UpdatedAt = replyVariables(t.Body), time.Now()
+    s.templates[t.ID] = t
+    return t, nil
+}
+
+func (s *MemoryStore) CreateReplyTemplate(ctx context.Context, t ReplyTemplate) (ReplyTemplate, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return ReplyTemplate{}, err
+    }
+    s.lastID++
+    t.ID, t.CreatedAt = s.lastID, time.Now()
+    return s.saveTemplate(t)
+}
+
+func (s *MemoryStore) UpdateReplyTemplate(ctx context.Context, t ReplyTemplate) (ReplyTemplate, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return ReplyTemplate{}, err
+    }
+    stored, ok := s.templates[t.ID]
+    if !ok {
+        return ReplyTemplate{}, errReplyTemplateNotFound
+    }
+    stored.Name, stored.Body = t.Name, t.Body
+    return s.saveTemplate(stored)
+}
+
+func (s *MemoryStore) DeleteReplyTemplate(ctx context.Context, id int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    if _, ok := s.templates[id]; !ok {
+        return errReplyTemplateNotFound
+    }
+    delete(s.templates, id)
+    return nil
+}
+
+// ListThreads only applies the group, channel, status, priority, author
+// and involvement filters, stakeholders being unknown to the store.
+func (s *MemoryStore) ListThreads(ctx context.Context, list threadList) ([]Thread, int, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    matching, err := s.listed(list)
+    if err != nil {
+        return nil, 0, err
+    }
+    page := matching
+    if list.cursor != nil {
+        cursor := Thread{Thread: domain.Thread{ChannelID: list.cursor.ChannelID, ThreadTS: list.cursor.ThreadTS}}
+        page = slices.DeleteFunc(slices.Clone(page), func(thread Thread) bool {
+            return !threadListBefore(list.sort, list.order, list.cursor.Value, cursor, thread)
+        })
+    }
+    page = page[min(list.offset, len(page)):]
+    return append([]Thread{}, page[:min(list.limit, len(page))]...), len(matching), nil
+}
+
+// StreamThreads filters threads like ListThreads.
+func (s *MemoryStore) StreamThreads(ctx context.Context, list threadList, fn func(Thread) error) error {
+    s.mu.Lock()
+    matching, err := s.listed(list)
+    s.mu.Unlock()
+    if err != nil {
+        return err
+    }
+    for _, thread := range matching {
+        if err := fn(thread); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// GroupThreads filters threads like ListThreads.
+func (s *MemoryStore) GroupThreads(ctx context.Context, list threadList) ([]ChannelThreads, int, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    matching, err := s.listed(list)
+    if err != nil {
+        return nil, 0, err
+    }
+    groups := map[string]*ChannelThreads{}
+    channels := []ChannelThreads{}
+    for _, thread := range matching {
+        group, ok := groups[thread.ChannelID]
+        if !ok {
+            group = &ChannelThreads{ChannelID: thread.ChannelID, ChannelName: thread.ChannelName, Items: []Thread{}}
+            groups[thread.ChannelID] = group
+        }
+        if len(group.Items) < list.limit {
+            group.Items = append(group.Items, thread)
+        }
+        group.Total++
+    }
+    for _, group := range groups {
+        channels = append(channels, *group)
+    }
+    return channels, len(matching), nil
+}
+
+// listed returns the threads of tracked channels matching the filters of a
+// list, in its sort and order. s.mu is held.
+func (s *MemoryStore) listed(list threadList) ([]Thread, error) {
+    f := list.filters
+    inGroup, err := s.inGroup(f.group)
+    if err != nil {
+        return nil, err
+    }
+    threads := []Thread{}
+    for _, stored := range s.threads {
+        ch, ok := s.channels[stored.ChannelID]
+        if !ok || !inGroup(ch.ChannelID) {
+            continue
+        }
+        thread := s.presented(stored, ch)
+        if (f.involves != "" && !s.involves(thread, f.involves)) || (f.backlogOf != "" && thread.UserID != f.backlogOf) {
+            continue
+        }
+        if len(f.channels) > 0 && !slices.Contains(f.channels, ch.ChannelID) && !slices.Contains(f.channels, ch.ChannelName) {
+            continue
+        }
+        if (len(f.statuses) > 0 && !slices.Contains(f.statuses, string(thread.Status))) ||
+            (len(f.priorities) > 0 && !slices.Contains(f.priorities, string(thread.Priority))) ||
+            (len(f.userIDs) > 0 && !slices.Contains(f.userIDs, thread.UserID)) {
+            continue
+        }
+        if (f.createdAfter != nil && thread.CreatedAt.Before(*f.createdAfter)) || (f.createdBefore != nil && !thread.CreatedAt.Before(*f.createdBefore)) {
+            continue
+        }
+        threads = append(threads, thread)
+    }
+    sort.Slice(threads, func(i, j int) bool {
+        return threadListBefore(list.sort, list.order, threadSortValue(list.sort, threads[i]), threads[i], threads[j])
+    })
+    return threads, nil
+}
+
+// presented returns a stored thread of a tracked channel as thread lists
+// present it. s.mu is held.
+func (s *MemoryStore) presented(stored StoredThread, ch ChannelSummary) Thread {
+    thread := Thread{Thread: stored.Thread, ChannelName: ch.ChannelName}
+    if stored.Title != "" {
+        thread.AIThreadName = &stored.Title
+    }
+    if stored.GithubIssue != "" {
+        thread.GithubIssue = &stored.GithubIssue
+    }
+    if stored.JiraTicket != "" {
+        thread.JiraTicket = &stored.JiraTicket
+    }
+    thread.EffectivePriority, thread.PrioritySource = thread.Priority, PrioritySourceAI
+    key := stored.ChannelID + "/" + stored.ThreadTS
+    if priority, ok := s.priorities[key]; ok {
+        thread.EffectivePriority, thread.PrioritySource = domain.Priority(*priority.ManualPriority), PrioritySourceManual
+    }
+    config := s.channelConfig(stored.ChannelID)
+    var override *time.Time
+    if dueAt, ok := s.slas[key]; ok {
+        override = &dueAt
+    }
+    thread.ApplySLA(config.SLAHours.thresholds(config.HeatThresholds, string(thread.Priority)), override, time.Now())
+    if assignment, ok := s.assignments[key]; ok {
+        thread.Assignee = assignment.Assignee
+    }
+    if claimedBy, ok := s.claims[key]; ok {
+        thread.ClaimedBy = &claimedBy
+    }
+    if ack, ok := s.acks[key]; ok {
+        thread.AcknowledgedBy = &ack.UserID
+    }
+    return thread
+}
+
+// CountByPriority filters threads like ListThreads.
+func (s *MemoryStore) CountByPriority(ctx context.Context, filters threadFilters) (map[string]int, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    matching, err := s.listed(threadList{filters: filters})
+    if err != nil {
+        return nil, err
+    }
+    total := map[string]int{"high": 0, "medium": 0, "low": 0, "none": 0}
+    for _, thread := range matching {
+        total[cmp.Or(string(thread.EffectivePriority), "none")]++
+    }
+    return total, nil
+}
+
+func (s *MemoryStore) ThreadsByRef(ctx context.Context, refs []ThreadRef) (map[ThreadRef]Thread, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    found := map[ThreadRef]Thread{}
+    for _, ref := range refs {
+        stored, ok := s.threads[ref.ChannelID+"/"+ref.ThreadTS]
+        if ch, tracked := s.channels[ref.ChannelID]; ok && tracked {
+            found[ref] = s.presented(stored, ch)
+        }
+    }
+    return found, nil
+}
+
+// SearchThreads matches the words of the query in thread titles, every
+// match ranking the same.
+func (s *MemoryStore) SearchThreads(ctx context.Context, search threadSearch) ([]ThreadMatch, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    matches := []ThreadMatch{}
+    for _, stored := range s.threads {
+        ch, ok := s.channels[stored.ChannelID]
+        if !ok || !ch.TextIndexing || (search.channel != "" && ch.ChannelName != search.channel) ||
+            (search.status != "" && string(stored.Status) != search.status) {
+            continue
+        }
+        title := strings.ToLower(stored.Title)
+        matched := true
+        for _, word := range strings.Fields(strings.ToLower(search.query)) {
+            matched = matched && strings.Contains(title, word)
+        }
+        if matched {
+            matches = append(matches, ThreadMatch{Thread: s.presented(stored, ch), Rank: 1})
+        }
+    }
+    sort.Slice(matches, func(i, j int) bool {
+        return matches[i].LatestReply.After(matches[j].LatestReply)
+    })
+    return matches[:min(search.limit, len(matches))], nil
+}
+
+// involves reports whether a user authored, is assigned, claimed or
+// acknowledged a thread, or is a member of the usergroup assigned to it.
+// s.mu is held.
+func (s *MemoryStore) involves(thread Thread, userID string) bool {
+    for _, by := range []*string{thread.Assignee, thread.ClaimedBy, thread.AcknowledgedBy} {
+        if by != nil && *by == userID {
+            return true
+        }
+    }
+    if thread.Assignee != nil && slices.Contains(s.usergroups[*thread.Assignee].Members, userID) {
+        return true
+    }
+    return thread.UserID == userID
+}
+
+// threadListBefore reports whether a thread with the sort key value comes
+// before other in a thread list. Ties are broken by channel and thread.
+func threadListBefore(sortBy string, order string, value int64, thread Thread, other Thread) bool {
+    compared := cmp.Or(cmp.Compare(value, threadSortValue(sortBy, other)),
+        strings.Compare(thread.ChannelID, other.ChannelID),
+        strings.Compare(thread.ThreadTS, other.ThreadTS))
+    if order == "asc" {
+        return compared < 0
+    }
+    return compared > 0
+}
+
+// SetStatus never asks for approval, the store has no resolution
+// policies.
+func (s *MemoryStore) SetStatus(ctx context.Context, change StatusChange, allow func(previous domain.Status, needsApproval bool) error) (StatusResult, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.channels[change.ChannelID]; !ok {
+        return StatusResult{}, errChannelNotTracked
+    }
+    key := change.ChannelID + "/" + change.ThreadTS
+    thread, ok := s.threads[key]
+    if !ok {
+        return StatusResult{}, errThreadNotFound
+    }
+    if err := allow(thread.Status, s.needsApproval(thread)); err != nil {
+        return StatusResult{}, err
+    }
+    if err := s.failed(); err != nil {
+        return StatusResult{}, err
+    }
+
+    result := s.resolutions[key]
+    if change.finished() {
+        if result.ResolvedBy == nil {
+            now := time.Now()
+            result.ResolvedBy, result.ResolvedAt = &change.Actor, &now
+        }
+        if change.Resolution != "" {
+            result.Resolution = &change.Resolution
+        }
+        s.resolutions[key] = result
+    } else {
+        result = StatusResult{}
+        delete(s.resolutions, key)
+    }
+    result.Previous = thread.Status
+    thread.Status = change.Status
+    s.threads[key] = thread
+    delete(s.snoozes, key)
+    return result, nil
+}
+
+// ThreadPriority has no AI priority, the store not keeping the analysis of
+// threads.
+func (s *MemoryStore) ThreadPriority(ctx context.Context, channelID, threadTS string) (ThreadPriority, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    _, ok := s.threads[channelID+"/"+threadTS]
+    if _, tracked := s.channels[channelID]; !tracked {
+        return ThreadPriority{}, errChannelNotTracked
+    }
+    if !ok {
+        return ThreadPriority{}, errThreadNotFound
+    }
+    priority, ok := s.priorities[channelID+"/"+threadTS]
+    if !ok {
+        priority = ThreadPriority{ChannelID: channelID, ThreadTS: threadTS}
+    }
+    priority.EffectivePriority, priority.PrioritySource = "none", PrioritySourceAI
+    if priority.ManualPriority != nil {
+        priority.EffectivePriority, priority.PrioritySource = *priority.ManualPriority, PrioritySourceManual
+    }
+    return priority, nil
+}
+
+func (s *MemoryStore) SetThreadPriority(ctx context.Context, channelID, threadTS string, priority *string, actor string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    if priority == nil {
+        delete(s.priorities, channelID+"/"+threadTS)
+        return nil
+    }
+    now := time.Now()
+    s.priorities[channelID+"/"+threadTS] = ThreadPriority{ChannelID: channelID, ThreadTS: threadTS, ManualPriority: priority, SetBy: &actor, SetAt: &now}
+    return nil
+}
+
+func (s *MemoryStore) SetThreadSLA(ctx context.Context, channelID, threadTS string, dueAt *time.Time, actor string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    if dueAt == nil {
+        delete(s.slas, channelID+"/"+threadTS)
+        return nil
+    }
+    s.slas[channelID+"/"+threadTS] = *dueAt
+    return nil
+}
+
+// BulkUpdate checks every thread before changing any, like a transaction.
+func (s *MemoryStore) BulkUpdate(ctx context.Context, update bulkUpdate) ([]bulkChange, []ThreadRef, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var notFound errThreadsNotFound
+    var approvals errNeedsApproval
+    for _, ref := range update.refs {
+        thread, ok := s.threads[ref.ChannelID+"/"+ref.ThreadTS]
+        if !ok {
+            notFound = append(notFound, ref)
+        } else if update.action == bulkResolve && thread.Status != statusResolved && thread.Status != statusClosed && s.needsApproval(thread) {
+            approvals = append(approvals, ref)
+        }
+    }
+    if len(notFound) > 0 {
+        return nil, nil, notFound
+    }
+    if len(approvals) > 0 {
+        return nil, nil, approvals
+    }
+    if err := s.failed(); err != nil {
+        return nil, nil, err
+    }
+
+    var changes []bulkChange
+    unchanged := []ThreadRef{}
+    now := time.Now()
+    for _, ref := range update.refs {
+        key := ref.ChannelID + "/" + ref.ThreadTS
+        thread := s.threads[key]
+        change := bulkChange{ThreadRef: ref, status: thread.Status}
+        switch update.action {
+        case bulkResolve, bulkSnooze:
+            if thread.Status == statusResolved || thread.Status == statusClosed {
+                unchanged = append(unchanged, ref)
+                continue
+            }
+            if update.action == bulkResolve {
+                thread.Status = statusResolved
+                s.resolutions[key] = StatusResult{ResolvedBy: &update.actor, ResolvedAt: &now, Resolution: &update.resolution}
+                delete(s.snoozes, key)
+            } else {
+                thread.Status = statusSnoozed
+                s.snoozes[key] = update.until
+            }
+            s.threads[key] = thread
+        case bulkSetPriority:
+            change.previous = "none"
+            if priority, ok := s.priorities[key]; ok {
+                change.previous = *priority.ManualPriority
+            }
+            if change.previous == update.priority {
+                unchanged = append(unchanged, ref)
+                continue
+            }
+            s.priorities[key] = ThreadPriority{ChannelID: ref.ChannelID, ThreadTS: ref.ThreadTS, ManualPriority: &update.priority, SetBy: &update.actor, SetAt: &now}
+        case bulkAssign:
+            if assignment, ok := s.assignments[key]; ok {
+                change.previous = *assignment.Assignee
+            }
+            if change.previous == update.assignee {
+                unchanged = append(unchanged, ref)
+                continue
+            }
+            if update.assignee == "" {
+                delete(s.assignments, key)
+            } else {
+                s.assignments[key] = ThreadAssignment{ChannelID: ref.ChannelID, ThreadTS: ref.ThreadTS, Assignee: &update.assignee, AssignedBy: &update.actor, AssignedAt: &now}
+            }
+        }
+        changes = append(changes, change)
+    }
+    return changes, unchanged, nil
+}
+
+// needsApproval reports whether resolving a thread needs approval under the
+// policy of its channel, the store keeping no priorities. s.mu is held.
+func (s *MemoryStore) needsApproval(thread StoredThread) bool {
+    linked := thread.GithubIssue != "" || thread.JiraTicket != ""
+    return s.channelConfig(thread.ChannelID).ResolveApproval.requires("", linked)
+}
+
+// closeThread closes an open thread like CloseThread. s.mu is held.
+func (s *MemoryStore) closeThread(key string, actor string, resolution string) bool {
+    thread, ok := s.threads[key]
+    if !ok || thread.Status != statusOpen {
+        return false
+    }
+    now := time.Now()
+    result := StatusResult{ResolvedBy: &actor, ResolvedAt: &now}
+    if resolution != "" {
+        result.Resolution = &resolution
+    }
+    s.resolutions[key] = result
+    thread.Status = statusClosed
+    s.threads[key] = thread
+    return true
+}
+
+func (s *MemoryStore) CloseThread(ctx context.Context, channelID, threadTS, actor, resolution, note string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    return s.closeThread(channelID+"/"+threadTS, actor, resolution), nil
+}
+
+func (s *MemoryStore) ResolveOrRequest(ctx context.Context, channelID, threadTS, actor, resolution, reason string) (int, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    key := channelID + "/" + threadTS
+    thread, ok := s.threads[key]
+    if !ok {
+        return 0, errThreadNotFound
+    }
+    if err := s.failed(); err != nil {
+        return 0, err
+    }
+    if thread.Status != statusOpen {
+        return resolveAlreadyClosed, nil
+    }
+    if !s.needsApproval(thread) {
+        s.closeThread(key, actor, resolution)
+        return resolveClosed, nil
+    }
+
+    for _, r := range s.requests {
+        if r.ChannelID == channelID && r.ThreadTS == threadTS && r.Status == ResolutionPending {
+            return resolveAlreadyRequested, nil
+        }
+    }
+    s.lastID++
+    s.requests[s.lastID] = ResolutionRequest{ID: s.lastID, ChannelID: channelID, ThreadTS: threadTS, RequestedBy: actor,
+        Resolution: resolution, Reason: reason, Status: ResolutionPending, CreatedAt: time.Now()}
+    return resolveRequested, nil
+}
+
+func (s *MemoryStore) PendingResolution(ctx context.Context, channelID, threadTS string) (ResolutionRequest, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, r := range s.requests {
+        if r.ChannelID == channelID && r.ThreadTS == threadTS && r.Status == ResolutionPending {
+            return r, nil
+        }
+    }
+    return ResolutionRequest{}, errResolutionNotPending
+}
+
+func (s *MemoryStore) DecideResolution(ctx context.Context, request ResolutionRequest, decision string, actor string, note string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    stored, ok := s.requests[request.ID]
+    if !ok || stored.Status != ResolutionPending {
+        return false, nil
+    }
+    now := time.Now()
+    stored.Status, stored.DecidedBy, stored.Note, stored.DecidedAt = decision, &actor, note, &now
+    s.requests[request.ID] = stored
+    if decision == ResolutionApproved {
+        s.closeThread(request.ChannelID+"/"+request.ThreadTS, stored.RequestedBy, stored.Resolution)
+    }
+    return true, nil
+}
+
+func (s *MemoryStore) ResolutionRequests(ctx context.Context, status string) ([]ResolutionRequest, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    requests := []ResolutionRequest{}
+    for _, r := range s.requests {
+        if r.Status == status {
+            requests = append(requests, r)
+        }
+    }
+    sort.Slice(requests, func(i, j int) bool {
+        return requests[i].ID > requests[j].ID
+    })
+    return requests, nil
+}
+
+func (s *MemoryStore) SnoozeThread(ctx context.Context, channelID string, threadTS string, until time.Time) (domain.Status, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.channels[channelID]; !ok {
+        return "", errChannelNotTracked
+    }
+    key := channelID + "/" + threadTS
+    thread, ok := s.threads[key]
+    if !ok {
+        return "", errThreadNotFound
+    }
+    if err := s.failed(); err != nil {
+        return "", err
+    }
+    previous := thread.Status
+    if previous == statusResolved || previous == statusClosed {
+        return previous, nil
+    }
+    if previous == statusWaitingReporter {
+        s.waits[key] = reporterWait{}
+    }
+    thread.Status = statusSnoozed
+    s.threads[key] = thread
+    s.snoozes[key] = until
+    return previous, nil
+}
+
+func (s *MemoryStore) WakeSnoozedThreads(ctx context.Context, now time.Time) ([]ThreadRef, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var woken []ThreadRef
+    for key, until := range s.snoozes {
+        thread := s.threads[key]
+        if thread.Status != statusSnoozed || until.After(now) {
+            continue
+        }
+        thread.Status = statusOpen
+        s.threads[key] = thread
+        delete(s.snoozes, key)
+        woken = append(woken, ThreadRef{ChannelID: thread.ChannelID, ThreadTS: thread.ThreadTS})
+    }
+    return woken, nil
+}
+
+func (s *MemoryStore) RecordAck(ctx context.Context, channelID, threadTS, userID, source string, threadCreatedAt, ackedAt time.Time) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    return s.recordAck(channelID, threadTS, userID, source, threadCreatedAt, ackedAt), nil
+}
+
+// recordAck stores the first acknowledgment of a thread. s.mu is held.
+func (s *MemoryStore) recordAck(channelID, threadTS, userID, source string, threadCreatedAt, ackedAt time.Time) bool {
+    key := channelID + "/" + threadTS
+    if _, ok := s.acks[key]; ok {
+        return false
+    }
+    s.acks[key] = ThreadAck{
+        ChannelID:      channelID,
+        ThreadTS:       threadTS,
+        UserID:         userID,
+        Source:         source,
+        AckedAt:        ackedAt,
+        LatencySeconds: ackedAt.Sub(threadCreatedAt).Seconds(),
+    }
+    return true
+}
+
+func (s *MemoryStore) Acknowledge(ctx context.Context, userID, source, channelID, threadTS string) (ThreadAck, bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.channels[channelID]; !ok {
+        return ThreadAck{}, false, errChannelNotTracked
+    }
+    thread, ok := s.threads[channelID+"/"+threadTS]
+    if !ok {
+        return ThreadAck{}, false, errThreadNotFound
+    }
+    if err := s.failed(); err != nil {
+        return ThreadAck{}, false, err
+    }
+    first := s.recordAck(channelID, threadTS, userID, source, thread.CreatedAt, time.Now())
+    return s.acks[channelID+"/"+threadTS], first, nil
+}
+
+// AckLatency interpolates the median and 90th percentile between the
+// nearest latencies like PERCENTILE_CONT.
+func (s *MemoryStore) AckLatency(ctx context.Context, channelID string, since time.Time) ([]AckLatency, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    byUser := map[string][]ThreadAck{}
+    for _, ack := range s.acks {
+        if (channelID == "" || ack.ChannelID == channelID) && !ack.AckedAt.Before(since) {
+            byUser[ack.UserID] = append(byUser[ack.UserID], ack)
+        }
+    }
+
+    latencies := []AckLatency{}
+    for userID, acks := range byUser {
+        seconds := make([]float64, len(acks))
+        l := AckLatency{UserID: userID, Acknowledged: len(acks)}
+        for i, ack := range acks {
+            seconds[i] = ack.LatencySeconds
+            l.AvgSeconds += ack.LatencySeconds / float64(len(acks))
+            if ack.Source == ackSourceReply {
+                l.Replies++
+            }
+        }
+        slices.Sort(seconds)
+        percentile := func(p float64) float64 {
+            rank := p * float64(len(seconds)-1)
+            lower := seconds[int(math.Floor(rank))]
+            return lower + (seconds[int(math.Ceil(rank))]-lower)*(rank-math.Floor(rank))
+        }
+        l.MedianSeconds, l.P90Seconds = percentile(0.5), percentile(0.9)
+        latencies = append(latencies, l)
+    }
+    sort.Slice(latencies, func(i, j int) bool { return latencies[i].MedianSeconds < latencies[j].MedianSeconds })
+    return latencies, nil
+}
+
+func (s *MemoryStore) WaitOnRelease(ctx context.Context, channelID, threadTS, repo, tag, actor string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    key := channelID + "/" + threadTS
+    thread, ok := s.threads[key]
+    if !ok || (thread.Status != statusOpen && thread.Status != statusWaitingRelease) {
+        return errThreadNotOpen
+    }
+    thread.Status = statusWaitingRelease
+    s.threads[key] = thread
+    s.releases[key] = threadRelease{repo: repo, tag: tag}
+    return nil
+}
+
+func (s *MemoryStore) StopWaitingOnRelease(ctx context.Context, channelID, threadTS string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    key := channelID + "/" + threadTS
+    if release, ok := s.releases[key]; !ok || release.url != "" {
+        return false, nil
+    }
+    delete(s.releases, key)
+    s.reopenReleased(key)
+    return true, nil
+}
+
+// reopenReleased opens again a thread waiting on a release. s.mu is held.
+func (s *MemoryStore) reopenReleased(key string) {
+    if thread, ok := s.threads[key]; ok && thread.Status == statusWaitingRelease {
+        thread.Status = statusOpen
+        s.threads[key] = thread
+    }
+}
+
+func (s *MemoryStore) PendingReleases(ctx context.Context) ([]pendingRelease, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    pending := []pendingRelease{}
+    for _, release := range s.releases {
+        p := pendingRelease{release.repo, release.tag}
+        if release.url == "" && !slices.Contains(pending, p) {
+            pending = append(pending, p)
+        }
+    }
+    return pending, nil
+}
+
+func (s *MemoryStore) ShipRelease(ctx context.Context, repo, tag, url string) ([]ThreadRef, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return nil, err
+    }
+    reopened := []ThreadRef{}
+    for key, release := range s.releases {
+        if release.url != "" || !strings.EqualFold(release.repo, repo) || release.tag != tag {
+            continue
+        }
+        release.url = url
+        s.releases[key] = release
+        thread := s.threads[key]
+        if _, tracked := s.channels[thread.ChannelID]; tracked {
+            s.reopenReleased(key)
+            reopened = append(reopened, ThreadRef{ChannelID: thread.ChannelID, ThreadTS: thread.ThreadTS})
+        }
+    }
+    return reopened, nil
+}
+
+func (s *MemoryStore) WaitOnReporter(ctx context.Context, channelID string, threadTS string, actor string, nudgeAfter time.Duration) (time.Time, time.Time, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return time.Time{}, time.Time{}, err
+    }
+    key := channelID + "/" + threadTS
+    thread, ok := s.threads[key]
+    if !ok || (thread.Status != statusOpen && thread.Status != statusWaitingReporter) {
+        return time.Time{}, time.Time{}, errThreadNotOpen
+    }
+    thread.Status = statusWaitingReporter
+    s.threads[key] = thread
+
+    wait := s.waits[key]
+    if wait.since.IsZero() {
+        wait.since = time.Now()
+    }
+    wait.nudgeAt, wait.nudged = wait.since.Add(nudgeAfter), false
+    s.waits[key] = wait
+    return wait.since, wait.nudgeAt, nil
+}
+
+func (s *MemoryStore) ResumeFromReporter(ctx context.Context, channelID string, threadTS string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    key := channelID + "/" + threadTS
+    thread, ok := s.threads[key]
+    if !ok || thread.Status != statusWaitingReporter {
+        return false, nil
+    }
+    thread.Status = statusOpen
+    s.threads[key] = thread
+    s.waits[key] = reporterWait{}
+    return true, nil
+}
+
+func (s *MemoryStore) DueReporterNudges(ctx context.Context) ([]ReporterNudge, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    due := []ReporterNudge{}
+    for key, wait := range s.waits {
+        if wait.since.IsZero() || wait.nudged || wait.nudgeAt.After(time.Now()) {
+            continue
+        }
+        thread, ok := s.threads[key]
+        if _, tracked := s.channels[thread.ChannelID]; !ok || !tracked {
+            continue
+        }
+        if thread.Status != statusWaitingReporter {
+            s.waits[key] = reporterWait{}
+            continue
+        }
+        due = append(due, ReporterNudge{ChannelID: thread.ChannelID, ThreadTS: thread.ThreadTS, Author: thread.UserID})
+    }
+    return due, nil
+}
+
+func (s *MemoryStore) MarkReporterNudged(ctx context.Context, channelID string, threadTS string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    wait := s.waits[channelID+"/"+threadTS]
+    wait.nudged = true
+    s.waits[channelID+"/"+threadTS] = wait
+    return nil
+}
+
+// RecordAnswerSignal surfaces every new signal, the store does not learn
+// which signals to mute.
+func (s *MemoryStore) RecordAnswerSignal(ctx context.Context, channelID, threadTS, signal, userID string, detectedAt time.Time) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    key := channelID + "/" + threadTS
+    for _, recorded := range s.signals[key] {
+        if recorded.signal == signal {
+            return false, nil
+        }
+    }
+    s.signals[key] = append(s.signals[key], answerSignal{signal: signal, detectedAt: detectedAt})
+    return true, nil
+}
+
+func (s *MemoryStore) AnsweredThreads(ctx context.Context) ([]AnsweredThread, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    answered := []AnsweredThread{}
+    for key, signals := range s.signals {
+        thread, ok := s.threads[key]
+        ch, tracked := s.channels[thread.ChannelID]
+        if !ok || !tracked || thread.Status != statusOpen {
+            continue
+        }
+        candidate := AnsweredThread{
+            ChannelID:   thread.ChannelID,
+            ChannelName: ch.ChannelName,
+            ThreadTS:    thread.ThreadTS,
+            UserID:      thread.UserID,
+            LatestReply: thread.LatestReply,
+            Signals:     []string{},
+        }
+        for _, signal := range signals {
+            if signal.dismissed {
+                continue
+            }
+            candidate.Signals = append(candidate.Signals, signal.signal)
+            if candidate.DetectedAt.IsZero() || signal.detectedAt.Before(candidate.DetectedAt) {
+                candidate.DetectedAt = signal.detectedAt
+            }
+        }
+        if len(candidate.Signals) > 0 {
+            sort.Strings(candidate.Signals)
+            answered = append(answered, candidate)
+        }
+    }
+    return answered, nil
+}
+
+func (s *MemoryStore) DismissAnswerSignals(ctx context.Context, channelID string, threadTS string, actor string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    dismissed := false
+    for i, signal := range s.signals[channelID+"/"+threadTS] {
+        if !signal.dismissed {
+            s.signals[channelID+"/"+threadTS][i].dismissed = true
+            dismissed = true
+        }
+    }
+    return dismissed, nil
+}
+
+// DashboardStats counts no Slack Connect channels nor AI analysis.
+func (s *MemoryStore) DashboardStats(ctx context.Context, group string) (DashboardStats, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    inGroup, err := s.inGroup(group)
+    if err != nil {
+        return DashboardStats{}, err
+    }
+    stats := DashboardStats{}
+    for id := range s.channels {
+        if inGroup(id) {
+            stats.Channels++
+        }
+    }
+    for _, thread := range s.threads {
+        if _, ok := s.channels[thread.ChannelID]; !ok || !inGroup(thread.ChannelID) {
+            continue
+        }
+        stats.TotalThreads++
+        if thread.Status == domain.StatusOpen {
+            stats.ActiveThreads++
+        }
+    }
+    return stats, nil
+}
+
+// UserStats counts the open threads users started.
+func (s *MemoryStore) UserStats(ctx context.Context, userIDs []string) (map[string]*UserStats, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    stats := make(map[string]*UserStats, len(userIDs))
+    for _, userID := range userIDs {
+        stats[userID] = &UserStats{}
+    }
+    for _, thread := range s.threads {
+        if userStats, ok := stats[thread.UserID]; ok && thread.Status == domain.StatusOpen {
+            userStats.OpenThreads++
+        }
+    }
+    return stats, nil
+}
+
+// scoped returns the tracked channels in scope sorted by name.
+func (s *MemoryStore) scoped(scope statsScope) ([]ChannelSummary, error) {
+    inGroup, err := s.inGroup(scope.group)
+    if err != nil {
+        return nil, err
+    }
+    var channels []ChannelSummary
+    for _, ch := range s.channels {
+        if !inGroup(ch.ChannelID) {
+            continue
+        }
+        if len(scope.channels) == 0 || slices.Contains(scope.channels, ch.ChannelID) || slices.Contains(scope.channels, ch.ChannelName) {
+            channels = append(channels, ch)
+        }
+    }
+    sort.Slice(channels, func(i, j int) bool {
+        return channels[i].ChannelName < channels[j].ChannelName
+    })
+    return channels, nil
+}
+
+// StatsTimeseries counts threads as closed when they were resolved, the
+// store keeping no update times.
+func (s *MemoryStore) StatsTimeseries(ctx context.Context, scope statsScope, first time.Time, interval time.Duration, points int) ([]ChannelTimeseries, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    channels, err := s.scoped(scope)
+    if err != nil {
+        return nil, err
+    }
+    series := make([]ChannelTimeseries, 0, len(channels))
+    index := map[string]int{}
+    for _, ch := range channels {
+        index[ch.ChannelID] = len(series)
+        series = append(series, ChannelTimeseries{ChannelID: ch.ChannelID, ChannelName: ch.ChannelName, Points: newStatsPoints(first, interval, points)})
+    }
+    for key, thread := range s.threads {
+        i, ok := index[thread.ChannelID]
+        if !ok {
+            continue
+        }
+        var closedAt *time.Time
+        if thread.Status != domain.StatusOpen {
+            closedAt = s.resolutions[key].ResolvedAt
+        }
+        for p := range series[i].Points {
+            point := &series[i].Points[p]
+            end := point.Start.Add(interval)
+            if !thread.CreatedAt.Before(end) || closedAt != nil && closedAt.Before(point.Start) {
+                continue
+            }
+            if !thread.CreatedAt.Before(point.Start) {
+                point.Opened++
+            }
+            if closedAt != nil && closedAt.Before(end) {
+                point.Resolved++
+            } else {
+                point.Active++
+            }
+        }
+    }
+    return series, nil
+}
+
+// ResolutionStats has no answer signal accuracy, the store labelling no
+// signals.
+func (s *MemoryStore) ResolutionStats(ctx context.Context, scope statsScope, since time.Time) ([]ResolutionMix, []AnswerSignalAccuracy, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    channels, err := s.scoped(scope)
+    if err != nil {
+        return nil, nil, err
+    }
+    mixes := make([]ResolutionMix, 0, len(channels))
+    index := map[string]int{}
+    for _, ch := range channels {
+        index[ch.ChannelID] = len(mixes)
+        mixes = append(mixes, newResolutionMix(ch.ChannelID, ch.ChannelName))
+    }
+    for key, thread := range s.threads {
+        i, ok := index[thread.ChannelID]
+        if !ok || thread.Status != domain.StatusResolved && thread.Status != domain.StatusClosed {
+            continue
+        }
+        result := s.resolutions[key]
+        if result.ResolvedAt == nil || result.ResolvedAt.Before(since) {
+            continue
+        }
+        resolution := resolutionUnknown
+        if result.Resolution != nil && validResolutions[*result.Resolution] {
+            resolution = *result.Resolution
+        }
+        mixes[i].Resolved++
+        mixes[i].Resolutions[resolution]++
+        if resolution == resolutionAnswered && len(s.signals[key]) == 0 {
+            mixes[i].UndetectedAnswers++
+        }
+    }
+    return mixes, []AnswerSignalAccuracy{}, nil
+}
+
+// UsergroupStats counts no stakeholder threads, the store keeping no
+// stakeholders.
+func (s *MemoryStore) UsergroupStats(ctx context.Context) ([]UsergroupStats, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    groups := make([]UsergroupStats, 0, len(s.usergroups))
+    for _, group := range s.usergroups {
+        stats := UsergroupStats{ID: group.ID, Handle: group.Handle, Name: group.Name, Members: len(group.Members)}
+        for key, thread := range s.threads {
+            if _, tracked := s.channels[thread.ChannelID]; !tracked || thread.Status != statusOpen {
+                continue
+            }
+            var assignee string
+            if assignment, ok := s.assignments[key]; ok {
+                assignee = *assignment.Assignee
+            }
+            assigned := assignee == group.ID
+            member := false
+            for _, user := range group.Members {
+                if user == assignee || user == s.claims[key] {
+                    member = true
+                }
+            }
+            if assigned {
+                stats.AssignedThreads++
+            }
+            if member {
+                stats.MemberThreads++
+            }
+            if assigned || member {
+                stats.OpenThreads++
+            }
+        }
+        groups = append(groups, stats)
+    }
+    return groups, nil
+}
+
+// CaptureStatsSnapshot counts open threads as stale past the default red
+// heat threshold, the store having no channel configuration.
+func (s *MemoryStore) CaptureStatsSnapshot(ctx context.Context, now time.Time) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    staleBefore := now.Add(-time.Duration(defaultHeatThresholds.RedHours * float64(time.Hour)))
+    snapshot := map[string]snapshotCounts{}
+    for channelID := range s.channels {
+        snapshot[channelID] = snapshotCounts{}
+    }
+    for _, thread := range s.threads {
+        counts, ok := snapshot[thread.ChannelID]
+        if !ok {
+            continue
+        }
+        counts.total++
+        if thread.Status == domain.StatusOpen {
+            counts.open++
+            if thread.CreatedAt.Before(staleBefore) {
+                counts.stale++
+            }
+        }
+        snapshot[thread.ChannelID] = counts
+    }
+    s.snapshots[now.Format(dateLayout)] = snapshot
+    return nil
+}
+
+func (s *MemoryStore) SnapshotsAsOf(ctx context.Context, day string) (map[string]snapshotCounts, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    days := make([]string, 0, len(s.snapshots))
+    for snapshotDay := range s.snapshots {
+        if snapshotDay <= day {
+            days = append(days, snapshotDay)
+        }
+    }
+    sort.Strings(days)
+    snapshots := map[string]snapshotCounts{}
+    for _, snapshotDay := range days {
+        for channelID, counts := range s.snapshots[snapshotDay] {
+            snapshots[channelID] = counts
+        }
+    }
+    return snapshots, nil
+}
+
+func (s *MemoryStore) Channels(ctx context.Context) ([]ChannelSummary, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var channels []ChannelSummary
+    for _, ch := range s.channels {
+        channels = append(channels, ch)
+    }
+    sort.Slice(channels, func(i, j int) bool {
+        return channels[i].ChannelName < channels[j].ChannelName
+    })
+    return channels, nil
+}
+
+func (s *MemoryStore) Channel(ctx context.Context, channelID string) (ChannelSummary, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    ch, ok := s.channels[channelID]
+    if !ok {
+        return ChannelSummary{}, errChannelNotTracked
+    }
+    return ch, nil
+}
+
+func (s *MemoryStore) Tracked(ctx context.Context, channelID string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.channels[channelID]; !ok {
+        return errChannelNotTracked
+    }
+    return nil
+}
+
+func (s *MemoryStore) TrackChannel(ctx context.Context, channelID string, channelName string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    if _, ok := s.channels[channelID]; ok {
+        return false, nil
+    }
+    s.channels[channelID] = ChannelSummary{
+        Channel:   domain.Channel{ChannelID: channelID, ChannelName: channelName, TextIndexing: true},
+        CreatedAt: time.Now(),
+    }
+    return true, nil
+}
+
+// TextIndexing keeps text of channels the store does not track.
+func (s *MemoryStore) TextIndexing(ctx context.Context, channelID string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    ch, ok := s.channels[channelID]
+    return !ok || ch.TextIndexing, nil
+}
+
+func (s *MemoryStore) ChannelConfig(ctx context.Context, channelID string) (ChannelConfig, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.channels[channelID]; !ok {
+        return ChannelConfig{}, errChannelNotTracked
+    }
+    return s.channelConfig(channelID), nil
+}
+
+// channelConfig returns the configuration of a tracked channel. s.mu is
+// held.
+func (s *MemoryStore) channelConfig(channelID string) ChannelConfig {
+    config, ok := s.configs[channelID]
+    if !ok {
+        config = defaultChannelConfig()
+    }
+    config.TextIndexing = s.channels[channelID].TextIndexing
+    config.SlackConnect = s.shared[channelID]
+    return config
+}
+
+func (s *MemoryStore) ChannelConfigs(ctx context.Context) (map[string]ChannelConfig, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    configs := make(map[string]ChannelConfig, len(s.channels))
+    for channelID := range s.channels {
+        configs[channelID] = s.channelConfig(channelID)
+    }
+    return configs, nil
+}
+
+func (s *MemoryStore) SetChannelConfig(ctx context.Context, channelID string, config ChannelConfig, settings ...channelSetting) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    ch, ok := s.channels[channelID]
+    if !ok {
+        return errChannelNotTracked
+    }
+    if err := s.failed(); err != nil {
+        return err
+    }
+    stored := s.channelConfig(channelID)
+    for _, setting := range settings {
+        switch setting {
+        case settingTextIndexing:
+            ch.TextIndexing = config.TextIndexing
+            s.channels[channelID] = ch
+        case settingView:
+            stored.View = config.View
+        case settingHeatThresholds:
+            stored.HeatThresholds = config.HeatThresholds
+        case settingResolveApproval:
+            stored.ResolveApproval = config.ResolveApproval
+        case settingResponderRotation:
+            stored.ResponderRotation = config.ResponderRotation
+            if stored.ResponderRotation != nil && len(stored.ResponderRotation.Users) == 0 {
+                stored.ResponderRotation = nil
+            }
+        case settingReminderSchedule:
+            stored.ReminderSchedule = config.ReminderSchedule
+        case settingQualityPrompts:
+            stored.QualityPrompts = config.QualityPrompts
+        case settingGitHubRouting:
+            stored.GitHubRouting = config.GitHubRouting
+        case settingJiraMapping:
+            stored.JiraMapping = config.JiraMapping
+        case settingPriorityCalibration:
+            stored.PriorityCalibration = config.PriorityCalibration
+        case settingSLAHours:
+            stored.SLAHours = config.SLAHours
+        case settingDefaultAssignees:
+            stored.DefaultAssignees = append([]string{}, config.DefaultAssignees...)
+        case settingMuted:
+            stored.Muted = config.Muted
+        case settingNoteSync:
+            stored.NoteSync = config.NoteSync
+        }
+    }
+    s.configs[channelID] = stored
+    return nil
+}
+
+// ClearChannelText drops the text of the stored messages of a channel, the
+// store keeps no AI analysis.
+func (s *MemoryStore) ClearChannelText(ctx context.Context, channelID string) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return 0, err
+    }
+    for key, message := range s.messages {
+        if strings.HasPrefix(key, channelID+"/") {
+            message.Text = nil
+            s.messages[key] = message
+        }
+    }
+    cleared := int64(0)
+    for _, thread := range s.threads {
+        if thread.ChannelID == channelID {
+            cleared++
+        }
+    }
+    return cleared, nil
+}
+
+func (s *MemoryStore) SlackSummary(ctx context.Context, channelID string) (SlackSummary, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if summary, ok := s.slackSummaries[channelID]; ok {
+        return summary.SlackSummary, nil
+    }
+    return SlackSummary{ChannelID: channelID, Mode: SummaryModeOff}, nil
+}
+
+func (s *MemoryStore) SetSummaryMode(ctx context.Context, channelID, mode, actor string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    summary, ok := s.slackSummaries[channelID]
+    if !ok {
+        summary.SlackSummary = SlackSummary{ChannelID: channelID}
+    }
+    if summary.Mode != mode {
+        summary.contentHash = nil
+    }
+    summary.Mode, summary.LastError = mode, nil
+    s.slackSummaries[channelID] = summary
+    return nil
+}
+
+func (s *MemoryStore) SummarizedChannels(ctx context.Context, channels []string) ([]string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var enabled []string
+    for channelID, summary := range s.slackSummaries {
+        if summary.Mode != SummaryModeOff && (channels == nil || slices.Contains(channels, channelID)) {
+            enabled = append(enabled, channelID)
+        }
+    }
+    sort.Strings(enabled)
+    return enabled, nil
+}
+
+func (s *MemoryStore) SummaryState(ctx context.Context, channelID string) (summaryState, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    summary, ok := s.slackSummaries[channelID]
+    if !ok {
+        return summaryState{mode: SummaryModeOff}, nil
+    }
+    state := summaryState{mode: summary.Mode}
+    if summary.CanvasID != nil {
+        state.canvasID = sql.NullString{String: *summary.CanvasID, Valid: true}
+    }
+    if summary.MessageTS != nil {
+        state.messageTS = sql.NullString{String: *summary.MessageTS, Valid: true}
+    }
+    if summary.contentHash != nil {
+        state.contentHash = sql.NullString{String: *summary.contentHash, Valid: true}
+    }
+    return state, nil
+}
+
+func (s *MemoryStore) SummaryWritten(ctx context.Context, channelID string, state summaryState) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    summary, ok := s.slackSummaries[channelID]
+    if !ok {
+        return nil
+    }
+    summary.CanvasID, summary.MessageTS, summary.contentHash = nil, nil, nil
+    if state.canvasID.Valid {
+        summary.CanvasID = &state.canvasID.String
+    }
+    if state.messageTS.Valid {
+        summary.MessageTS = &state.messageTS.String
+    }
+    if state.contentHash.Valid {
+        summary.contentHash = &state.contentHash.String
+    }
+    now := time.Now()
+    summary.SyncedAt, summary.LastError = &now, nil
+    s.slackSummaries[channelID] = summary
+    return nil
+}
+
+func (s *MemoryStore) SummaryFailed(ctx context.Context, channelID, message string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    if summary, ok := s.slackSummaries[channelID]; ok {
+        summary.LastError = &message
+        s.slackSummaries[channelID] = summary
+    }
+    return nil
+}
+
+func (s *MemoryStore) MeasureTables(ctx context.Context) ([]TableStorage, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]TableStorage{}, s.tables...), nil
+}
+
+func (s *MemoryStore) StorageSnapshots(ctx context.Context, since time.Time) (map[string]storageSnapshot, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    oldest := map[string]storageSnapshot{}
+    for key, snapshot := range s.storageSnapshots {
+        _, table, _ := strings.Cut(key, "/")
+        previous, ok := oldest[table]
+        if !snapshot.capturedAt.Before(since) && (!ok || snapshot.capturedAt.Before(previous.capturedAt)) {
+            oldest[table] = snapshot
+        }
+    }
+    return oldest, nil
+}
+
+func (s *MemoryStore) RecordStorage(ctx context.Context, measuredAt time.Time, tables []TableStorage) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    for _, table := range tables {
+        key := measuredAt.Format("2006-01-02") + "/" + table.Table
+        if _, ok := s.storageSnapshots[key]; !ok {
+            s.storageSnapshots[key] = storageSnapshot{rows: table.Rows, size: table.SizeBytes, capturedAt: measuredAt}
+        }
+    }
+    for key, snapshot := range s.storageSnapshots {
+        if snapshot.capturedAt.Before(measuredAt.Add(-2 * storageGrowthWindow)) {
+            delete(s.storageSnapshots, key)
+        }
+    }
+    return nil
+}
+
+func (s *MemoryStore) RecordUsage(ctx context.Context, key usageKey, calls int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    s.usage[key] += calls
+    return nil
+}
+
+func (s *MemoryStore) ActiveUsers(ctx context.Context, since time.Time) (int, []DailyCount, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    users := map[string]bool{}
+    days := map[string]map[string]bool{}
+    for key := range s.usage {
+        if key.day < since.Format(time.DateOnly) || key.userID == clientAnonymous {
+            continue
+        }
+        users[key.userID] = true
+        if days[key.day] == nil {
+            days[key.day] = map[string]bool{}
+        }
+        days[key.day][key.userID] = true
+    }
+    daily := []DailyCount{}
+    for day, active := range days {
+        daily = append(daily, DailyCount{Day: day, Count: int64(len(active))})
+    }
+    sort.Slice(daily, func(i, j int) bool {
+        return daily[i].Day < daily[j].Day
+    })
+    return len(users), daily, nil
+}
+
+func (s *MemoryStore) APIClients(ctx context.Context, since time.Time) ([]ClientUsage, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    byClient := map[string]*ClientUsage{}
+    users := map[string]map[string]bool{}
+    for key, calls := range s.usage {
+        if key.day < since.Format(time.DateOnly) {
+            continue
+        }
+        client, ok := byClient[key.client]
+        if !ok {
+            client = &ClientUsage{Client: key.client, Name: key.client}
+            for _, token := range s.tokens {
+                if key.client == "token:"+strconv.FormatInt(token.ID, 10) {
+                    userID := token.userID
+                    client.Name, client.UserID = token.Name, &userID
+                }
+            }
+            byClient[key.client], users[key.client] = client, map[string]bool{}
+        }
+        users[key.client][key.userID] = true
+        client.Calls += calls
+        client.LastUsed = max(client.LastUsed, key.day)
+    }
+    clients := []ClientUsage{}
+    for _, client := range byClient {
+        client.Users = len(users[client.Client])
+        clients = append(clients, *client)
+    }
+    sort.Slice(clients, func(i, j int) bool {
+        return clients[i].Calls > clients[j].Calls
+    })
+    return clients, nil
+}
+
+func (s *MemoryStore) ReminderAdoption(ctx context.Context, since time.Time) (ReminderAdoption, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    adoption := ReminderAdoption{Addresses: len(s.emailPrefs)}
+    recipients, threads := map[string]bool{}, map[ThreadRef]bool{}
+    for key, nudgedAt := range s.nudges {
+        if !nudgedAt.Before(since) {
+            recipients[key.recipient] = true
+            threads[ThreadRef{ChannelID: key.channelID, ThreadTS: key.threadTS}] = true
+        }
+    }
+    adoption.Recipients, adoption.Threads = len(recipients), len(threads)
+    for _, prefs := range s.emailPrefs {
+        if prefs.Digest {
+            continue
+        }
+        adoption.OptedOut++
+        if prefs.UpdatedAt != nil && !prefs.UpdatedAt.Before(since) {
+            adoption.OptedOutSince++
+        }
+    }
+    if adoption.Addresses > 0 {
+        adoption.OptOutRate = float64(adoption.OptedOut) / float64(adoption.Addresses)
+    }
+    return adoption, nil
+}
+
+func (s *MemoryStore) FeatureUsage(ctx context.Context, since time.Time) ([]FeatureUsage, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    type dailyFeature struct{ kind, feature, day string }
+    counts := map[dailyFeature]int64{}
+    for key, calls := range s.usage {
+        if key.day >= since.Format(time.DateOnly) {
+            counts[dailyFeature{"api", key.method + " " + key.route, key.day}] += calls
+        }
+    }
+    for i, entry := range s.audits {
+        if !s.auditedAt[i].Before(since) {
+            counts[dailyFeature{"action", entry.Action, s.auditedAt[i].UTC().Format(time.DateOnly)}]++
+        }
+    }
+    daily := make([]dailyFeature, 0, len(counts))
+    for key := range counts {
+        daily = append(daily, key)
+    }
+    sort.Slice(daily, func(i, j int) bool {
+        return daily[i].day < daily[j].day
+    })
+
+    features := []FeatureUsage{}
+    index := map[string]int{}
+    for _, key := range daily {
+        i, ok := index[key.kind+" "+key.feature]
+        if !ok {
+            i = len(features)
+            index[key.kind+" "+key.feature] = i
+            features = append(features, FeatureUsage{Feature: key.feature, Kind: key.kind})
+        }
+        features[i].Total += counts[key]
+        features[i].Daily = append(features[i].Daily, DailyCount{Day: key.day, Count: counts[key]})
+    }
+    return features, nil
+}
+
+// CalibrationSamples samples the threads of a channel, none of them high
+// priority as the store has no AI analysis.
+func (s *MemoryStore) CalibrationSamples(ctx context.Context, channelID string, since time.Time, settledBefore time.Time) ([]calibrationSample, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    samples := []calibrationSample{}
+    for _, thread := range s.threads {
+        if thread.ChannelID != channelID || !thread.CreatedAt.After(since) {
+            continue
+        }
+        if thread.Status == statusOpen && !thread.CreatedAt.Before(settledBefore) {
+            continue
+        }
+        samples = append(samples, calibrationSample{replies: thread.ReplyCount, linked: thread.GithubIssue != "" || thread.JiraTicket != ""})
+    }
+    return samples, nil
+}
+
+func (s *MemoryStore) ClaimDigestRun(ctx context.Context, scheduledAt time.Time) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    if s.digestRuns[scheduledAt] {
+        return false, nil
+    }
+    s.digestRuns[scheduledAt] = true
+    return true, nil
+}
+
+// SaveAnalysis keeps the name, quality and stakeholders of a thread, the
+// store having no other AI analysis.
+func (s *MemoryStore) SaveAnalysis(ctx context.Context, channelID string, threadTS string, result *analysis.Result, stakeholders []string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    key := channelID + "/" + threadTS
+    if thread, ok := s.threads[key]; ok {
+        thread.Title = result.ThreadName()
+        s.threads[key] = thread
+    }
+    encoded, _ := json.Marshal(result)
+    s.qualities[key] = parseThreadQuality(sql.NullString{String: string(encoded), Valid: true})
+    s.stakeholders[key] = stakeholders
+    return nil
+}
+
+func (s *MemoryStore) UnpromptedThreads(ctx context.Context, channelID string, since time.Time) ([]analyzedThread, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    threads := []analyzedThread{}
+    for key, thread := range s.threads {
+        quality, analyzed := s.qualities[key]
+        _, prompted := s.qualityPrompts[key]
+        if thread.ChannelID != channelID || thread.Status != statusOpen || !thread.CreatedAt.After(since) || !analyzed || prompted {
+            continue
+        }
+        threads = append(threads, analyzedThread{threadTS: thread.ThreadTS, author: thread.UserID, quality: quality})
+    }
+    sort.Slice(threads, func(i, j int) bool {
+        return threads[i].threadTS < threads[j].threadTS
+    })
+    return threads, nil
+}
+
+func (s *MemoryStore) RecordQualityPrompt(ctx context.Context, channelID string, threadTS string, missing []string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    key := channelID + "/" + threadTS
+    if _, ok := s.qualityPrompts[key]; ok {
+        return false, nil
+    }
+    s.qualityPrompts[key] = missing
+    return true, nil
+}
+
+func (s *MemoryStore) UnansweredThreads(ctx context.Context, channelID string, before time.Time) ([]unansweredThread, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    unanswered := []unansweredThread{}
+    for key, thread := range s.threads {
+        _, claimed := s.claims[key]
+        if thread.ChannelID != channelID || thread.Status != statusOpen || thread.ReplyCount != 0 ||
+            !thread.CreatedAt.Before(before) || s.suggested[key] || claimed {
+            continue
+        }
+        unanswered = append(unanswered, unansweredThread{
+            ThreadTS:     thread.ThreadTS,
+            UserID:       thread.UserID,
+            Title:        thread.Title,
+            Stakeholders: s.stakeholders[key],
+            CreatedAt:    thread.CreatedAt,
+        })
+    }
+    sort.Slice(unanswered, func(i, j int) bool {
+        return unanswered[i].ThreadTS < unanswered[j].ThreadTS
+    })
+    return unanswered, nil
+}
+
+func (s *MemoryStore) PastThreads(ctx context.Context, channelID string) ([]similarity.Document, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    past := []StoredThread{}
+    for _, thread := range s.threads {
+        if thread.ChannelID == channelID && thread.Status != statusOpen && thread.Title != "" {
+            past = append(past, thread)
+        }
+    }
+    sort.Slice(past, func(i, j int) bool {
+        return past[i].LatestReply.After(past[j].LatestReply)
+    })
+    documents := []similarity.Document{}
+    for _, thread := range past[:min(len(past), suggestionHistory)] {
+        documents = append(documents, similarity.Document{ID: thread.ThreadTS, Text: thread.Title + "\n"})
+    }
+    return documents, nil
+}
+
+func (s *MemoryStore) RecordSuggestion(ctx context.Context, channelID string, threadTS string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    s.suggested[channelID+"/"+threadTS] = true
+    return nil
+}
+
+// WatchedThreads digests the title of threads as their analysis, the store
+// having no other.
+func (s *MemoryStore) WatchedThreads(ctx context.Context, channelID string, since time.Time, analyzed bool) (map[string]liveThreadState, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    states := make(map[string]liveThreadState)
+    for _, thread := range s.threads {
+        if thread.ChannelID != channelID || (thread.Status != statusOpen && !thread.LatestReply.After(since)) {
+            continue
+        }
+        state := liveThreadState{status: string(thread.Status), replyCount: thread.ReplyCount, createdAt: thread.CreatedAt}
+        if analyzed {
+            state.analysis = thread.Title
+        }
+        states[thread.ThreadTS] = state
+    }
+    return states, nil
+}
+
+func (s *MemoryStore) StartThread(ctx context.Context, thread domain.Thread, first ThreadMessage) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    key := thread.ChannelID + "/" + thread.ThreadTS
+    if _, ok := s.threads[key]; !ok {
+        s.threads[key] = StoredThread{Thread: thread}
+    }
+    if _, ok := s.messages[thread.ChannelID+"/"+first.TS]; !ok {
+        s.messages[thread.ChannelID+"/"+first.TS] = storedMessage{first, thread.ThreadTS}
+    }
+    return nil
+}
+
+func (s *MemoryStore) TrackThread(ctx context.Context, thread domain.Thread) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    key := thread.ChannelID + "/" + thread.ThreadTS
+    if _, ok := s.threads[key]; ok {
+        return false, nil
+    }
+    s.threads[key] = StoredThread{Thread: thread}
+    return true, nil
+}
+
+// IdleThreads stands the authors of threads in for their stakeholders,
+// unknown to the store.
+func (s *MemoryStore) IdleThreads(ctx context.Context, channelID string, cutoff time.Time, slaHours SLAHours) ([]idleThread, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := time.Now()
+    threads := []idleThread{}
+    for key, stored := range s.threads {
+        if stored.ChannelID != channelID || stored.Status != statusOpen {
+            continue
+        }
+        thread := idleThread{
+            threadTS:    stored.ThreadTS,
+            title:       stored.Title,
+            claimedBy:   s.claims[key],
+            latestReply: stored.LatestReply,
+            createdAt:   stored.CreatedAt,
+        }
+        if stored.UserID != "" {
+            thread.stakeholders = []string{stored.UserID}
+        }
+        if priority, ok := s.priorities[key]; ok && priority.ManualPriority != nil {
+            thread.priority = *priority.ManualPriority
+        }
+        if dueAt, ok := s.slas[key]; ok {
+            thread.dueAt = &dueAt
+        }
+        _, thread.acknowledged = s.acks[key]
+        for _, watcher := range s.watchers[key] {
+            thread.watchers = append(thread.watchers, watcher.UserID)
+        }
+
+        due := thread.dueAt
+        if hours, ok := slaHours[cmp.Or(thread.priority, "none")]; ok && due == nil {
+            slaDue := thread.createdAt.Add(time.Duration(hours * float64(time.Hour)))
+            due = &slaDue
+        }
+        if !thread.latestReply.Before(cutoff) && (due == nil || now.Before(*due)) {
+            continue
+        }
+        threads = append(threads, thread)
+    }
+    sort.Slice(threads, func(i, j int) bool {
+        return threads[i].threadTS < threads[j].threadTS
+    })
+    return threads, nil
+}
+
+func (s *MemoryStore) Nudges(ctx context.Context, since time.Time) (map[string]time.Time, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    nudged := make(map[string]time.Time)
+    for key, nudgedAt := range s.nudges {
+        if nudgedAt.After(since) {
+            nudged[key.recipient+"|"+key.channelID+":"+key.threadTS] = nudgedAt
+        }
+    }
+    return nudged, nil
+}
+
+func (s *MemoryStore) RecordNudge(ctx context.Context, recipient string, channelID string, threadTS string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    s.nudges[nudgeKey{recipient, channelID, threadTS}] = time.Now()
+    return nil
+}
+
+func (s *MemoryStore) SaveCheckpoint(cp *ingest.Checkpoint) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    s.checkpoints[cp.ChannelID] = *cp
+    return nil
+}
+
+func (s *MemoryStore) ListCheckpoints() ([]ingest.Checkpoint, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    checkpoints := []ingest.Checkpoint{}
+    for _, cp := range s.checkpoints {
+        checkpoints = append(checkpoints, cp)
+    }
+    sort.Slice(checkpoints, func(i, j int) bool {
+        return checkpoints[i].StartedAt.After(checkpoints[j].StartedAt)
+    })
+    return checkpoints, nil
+}
+
+func (s *MemoryStore) ImportThreads(ctx context.Context, channelID string, threads []domain.Thread) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.channels[channelID]; !ok {
+        return errChannelNotTracked
+    }
+    if err := s.failed(); err != nil {
+        return err
+    }
+    for _, thread := range threads {
+        key := channelID + "/" + thread.ThreadTS
+        stored, ok := s.threads[key]
+        if !ok {
+            s.threads[key] = StoredThread{Thread: thread}
+            continue
+        }
+        stored.ReplyCount = max(stored.ReplyCount, thread.ReplyCount)
+        if thread.LatestReply.After(stored.LatestReply) {
+            stored.LatestReply = thread.LatestReply
+        }
+        s.threads[key] = stored
+    }
+    return nil
+}
+
+func (s *MemoryStore) AddReply(ctx context.Context, channelID string, threadTS string, reply ThreadMessage) (domain.Thread, bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return domain.Thread{}, false, err
+    }
+    thread, ok := s.threads[channelID+"/"+threadTS]
+    if !ok {
+        return domain.Thread{}, false, errThreadNotFound
+    }
+    if _, ok := s.messages[channelID+"/"+reply.TS]; ok {
+        return thread.Thread, false, nil
+    }
+    s.messages[channelID+"/"+reply.TS] = storedMessage{reply, threadTS}
+    thread.ReplyCount++
+    if reply.PostedAt.After(thread.LatestReply) {
+        thread.LatestReply = reply.PostedAt
+    }
+    s.threads[channelID+"/"+threadTS] = thread
+    return thread.Thread, true, nil
+}
+
+func (s *MemoryStore) KeepMessage(ctx context.Context, channelID string, threadTS string, msg ThreadMessage) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    if _, ok := s.messages[channelID+"/"+msg.TS]; !ok {
+        s.messages[channelID+"/"+msg.TS] = storedMessage{msg, threadTS}
+    }
+    return nil
+}
+
+func (s *MemoryStore) LinkGitHubIssue(ctx context.Context, channelID string, threadTS string, url string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    thread, ok := s.threads[channelID+"/"+threadTS]
+    if !ok || thread.GithubIssue != "" {
+        return false, nil
+    }
+    thread.GithubIssue = url
+    s.threads[channelID+"/"+threadTS] = thread
+    return true, nil
+}
+
+func (s *MemoryStore) JiraTicketStatus(ctx context.Context, key string) (string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.jiraTickets[key].status, nil
+}
+
+func (s *MemoryStore) LinkJiraTicket(ctx context.Context, channelID string, threadTS string, key string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    thread, ok := s.threads[channelID+"/"+threadTS]
+    if !ok || thread.JiraTicket != "" {
+        return false, nil
+    }
+    thread.JiraTicket = key
+    s.threads[channelID+"/"+threadTS] = thread
+    if _, ok := s.jiraTickets[key]; !ok {
+        s.jiraTickets[key] = trackedJiraTicket{ThreadRef: ThreadRef{ChannelID: channelID, ThreadTS: threadTS}}
+    }
+    return true, nil
+}
+
+func (s *MemoryStore) SetJiraTicketStatus(ctx context.Context, key string, status string) (ThreadRef, bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return ThreadRef{}, false, err
+    }
+    ticket, ok := s.jiraTickets[key]
+    if !ok {
+        return ThreadRef{}, false, nil
+    }
+    ticket.status = status
+    s.jiraTickets[key] = ticket
+    return ticket.ThreadRef, true, nil
+}
+
+func (s *MemoryStore) Messages(ctx context.Context, channelID string, threadTS string) ([]ThreadMessage, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    messages := []ThreadMessage{}
+    for key, msg := range s.messages {
+        if msg.threadTS == threadTS && key == channelID+"/"+msg.TS {
+            messages = append(messages, msg.ThreadMessage)
+        }
+    }
+    sort.Slice(messages, func(i, j int) bool {
+        if !messages[i].PostedAt.Equal(messages[j].PostedAt) {
+            return messages[i].PostedAt.Before(messages[j].PostedAt)
+        }
+        return messages[i].TS < messages[j].TS
+    })
+    return messages, nil
+}
+
+func (s *MemoryStore) MarkSlackConnect(ctx context.Context, channelID string, shared bool) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    settings, ok := s.shared[channelID]
+    if ok && settings.Shared == shared {
+        return false, nil
+    }
+    settings.Shared = shared
+    s.shared[channelID] = settings
+    return true, nil
+}
+
+func (s *MemoryStore) RecordExternalUser(ctx context.Context, userID string, teamID string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    if _, ok := s.external[userID]; !ok {
+        s.external[userID] = teamID
+    }
+    return nil
+}
+
+func (s *MemoryStore) SetSlackConnectAI(ctx context.Context, channelID string, enabled bool) (SlackConnect, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return SlackConnect{}, err
+    }
+    settings := s.shared[channelID]
+    settings.AIAnalysis = enabled
+    s.shared[channelID] = settings
+    return settings, nil
+}
+
+func (s *MemoryStore) Block(ctx context.Context, actor string, action string, channelID string, threadTS string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, hold := range s.holds {
+        if hold.ReleasedAt != nil || (channelID != "" && hold.ChannelID != channelID) {
+            continue
+        }
+        if channelID != "" && threadTS != "" && hold.ThreadTS != nil && *hold.ThreadTS != threadTS {
+            continue
+        }
+        s.blocked = append(s.blocked, BlockedAction{Actor: actor, Action: action, ChannelID: channelID, ThreadTS: threadTS})
+        return true, nil
+    }
+    return false, nil
+}
+
+func (s *MemoryStore) LegalHolds(ctx context.Context, all bool) ([]LegalHold, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    holds := []LegalHold{}
+    for i := len(s.holds) - 1; i >= 0; i-- {
+        if all || s.holds[i].ReleasedAt == nil {
+            holds = append(holds, s.holds[i])
+        }
+    }
+    return holds, nil
+}
+
+func (s *MemoryStore) PlaceLegalHold(ctx context.Context, hold LegalHold) (LegalHold, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return LegalHold{}, err
+    }
+    s.lastID++
+    hold.ID, hold.PlacedAt = s.lastID, time.Now()
+    s.holds = append(s.holds, hold)
+    return hold, nil
+}
+
+func (s *MemoryStore) ReleaseLegalHold(ctx context.Context, id int64, actor string) (LegalHold, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return LegalHold{}, err
+    }
+    for i, hold := range s.holds {
+        if hold.ID != id || hold.ReleasedAt != nil {
+            continue
+        }
+        now := time.Now()
+        hold.ReleasedBy, hold.ReleasedAt = &actor, &now
+        s.holds[i] = hold
+        return hold, nil
+    }
+    return LegalHold{}, errLegalHoldNotFound
+}
+
+// ExportChannels exports every channel as external that is shared without
+// AI analysis. The store has no priority calibrations.
+func (s *MemoryStore) ExportChannels(ctx context.Context, req ExportRequest) ([]exportChannel, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    selected := []exportChannel{}
+    for _, ch := range s.channels {
+        if req.Channel != "" && ch.ChannelName != req.Channel && ch.ChannelID != req.Channel {
+            continue
+        }
+        shared := s.shared[ch.ChannelID]
+        selected = append(selected, exportChannel{
+            trackedChannel: trackedChannel{ID: ch.ChannelID, Name: ch.ChannelName, TextIndexing: ch.TextIndexing},
+            external:       shared.Shared && !shared.AIAnalysis,
+        })
+    }
+    sort.Slice(selected, func(i, j int) bool {
+        return selected[i].Name < selected[j].Name
+    })
+    return selected, nil
+}
+
+func (s *MemoryStore) CountExportRows(ctx context.Context, req ExportRequest, ch exportChannel) (int, error) {
+    rows, err := s.exportRows(req, ch)
+    return len(rows), err
+}
+
+func (s *MemoryStore) ExportRows(ctx context.Context, req ExportRequest, ch exportChannel, fn func(exportRow) error) error {
+    rows, err := s.exportRows(req, ch)
+    if err != nil {
+        return err
+    }
+    for _, row := range rows {
+        if err := fn(row); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// exportRows returns the threads of a channel an export selects, oldest
+// first. The store keeps no priorities nor AI analysis, no thread matches
+// a priority filter.
+func (s *MemoryStore) exportRows(req ExportRequest, ch exportChannel) ([]exportRow, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    rows := []exportRow{}
+    for _, thread := range s.threads {
+        if thread.ChannelID != ch.ID || req.Priority != "" || req.Status != "" && string(thread.Status) != req.Status {
+            continue
+        }
+        row := exportRow{ChannelID: ch.ID, ChannelName: ch.Name, ThreadTS: thread.ThreadTS, UserID: thread.UserID,
+            Status: string(thread.Status), ReplyCount: thread.ReplyCount, CreatedAt: thread.CreatedAt, LatestReply: thread.LatestReply}
+        if thread.GithubIssue != "" {
+            row.GithubIssue = &thread.GithubIssue
+        }
+        if thread.JiraTicket != "" {
+            row.JiraTicket = &thread.JiraTicket
+        }
+        rows = append(rows, row)
+    }
+    sort.Slice(rows, func(i, j int) bool {
+        return rows[i].CreatedAt.Before(rows[j].CreatedAt)
+    })
+    return rows, nil
+}
+
+func (s *MemoryStore) CreateExport(ctx context.Context, job ExportJob) (ExportJob, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return ExportJob{}, err
+    }
+    s.lastID++
+    job.ID, job.CreatedAt = s.lastID, time.Now()
+    s.exports[job.ID] = job
+    return job, nil
+}
+
+func (s *MemoryStore) Export(ctx context.Context, id int64) (ExportJob, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    job, ok := s.exports[id]
+    if !ok {
+        return ExportJob{}, errExportNotFound
+    }
+    return job, nil
+}
+
+func (s *MemoryStore) PendingExports(ctx context.Context, resume bool) ([]int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var ids []int64
+    for id, job := range s.exports {
+        if resume && job.Status == exportRunning {
+            job.Status = exportPending
+            s.exports[id] = job
+        }
+        if job.Status == exportPending {
+            ids = append(ids, id)
+        }
+    }
+    slices.Sort(ids)
+    return ids, nil
+}
+
+func (s *MemoryStore) StartExport(ctx context.Context, id int64) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    job, ok := s.exports[id]
+    if !ok || job.Status != exportPending {
+        return false, nil
+    }
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    job.Status = exportRunning
+    s.exports[id] = job
+    return true, nil
+}
+
+func (s *MemoryStore) CompleteExport(ctx context.Context, id int64, rowCount int, size int64, objectKey string, expiresAt time.Time) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    job := s.exports[id]
+    now := time.Now()
+    job.Status, job.RowCount, job.SizeBytes, job.objectKey = exportCompleted, rowCount, size, objectKey
+    job.FinishedAt, job.ExpiresAt = &now, &expiresAt
+    s.exports[id] = job
+    return nil
+}
+
+func (s *MemoryStore) FailExport(ctx context.Context, id int64, message string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    job := s.exports[id]
+    now := time.Now()
+    job.Status, job.Error, job.FinishedAt = exportFailed, message, &now
+    s.exports[id] = job
+    return nil
+}
+
+func (s *MemoryStore) ExpiredExports(ctx context.Context) ([]ExportJob, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    jobs := []ExportJob{}
+    for _, job := range s.exports {
+        if job.Status == exportCompleted && job.ExpiresAt != nil && job.ExpiresAt.Before(time.Now()) {
+            jobs = append(jobs, job)
+        }
+    }
+    sort.Slice(jobs, func(i, j int) bool {
+        return jobs[i].ID < jobs[j].ID
+    })
+    return jobs, nil
+}
+
+func (s *MemoryStore) ExpireExport(ctx context.Context, id int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    job := s.exports[id]
+    job.Status = exportExpired
+    s.exports[id] = job
+    return nil
+}
+
+func (s *MemoryStore) Audit(ctx context.Context, entry AuditEntry) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.audits = append(s.audits, entry)
+    s.auditedAt = append(s.auditedAt, time.Now())
+    return nil
+}
+
+func (s *MemoryStore) ThreadActions(ctx context.Context, channelID string, threadTS string) ([]reportEvent, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var actions []reportEvent
+    for i, entry := range s.audits {
+        if entry.ChannelID != channelID || entry.ThreadTS != threadTS || len(actions) == reportTimelineLimit {
+            continue
+        }
+        details, _ := json.Marshal(entry.Details)
+        actions = append(actions, reportEvent{at: s.auditedAt[i], who: entry.Actor, text: entry.Action + describeActionDetails(string(details))})
+    }
+    return actions, nil
+}
+
+// Profiles overlays the directory on the profiles with an entry, others
+// keep the name they were added with.
+func (s *MemoryStore) Profiles(ctx context.Context, userIDs []string) ([]UserProfile, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    workspace := workspaceFrom(ctx)
+    precedence := s.precedence[workspace]
+    if len(precedence) == 0 {
+        precedence = defaultDisplayNamePrecedence
+    }
+    var profiles []UserProfile
+    for _, userID := range userIDs {
+        profile, ok := s.profiles[workspace+"/"+userID]
+        if !ok {
+            continue
+        }
+        if entry, ok := s.directory[workspace+"/"+userID]; ok {
+            profile.ResolvedName = resolveDisplayName(profile, entry, precedence)
+            profile.Title, profile.Team, profile.Email = entry.Title, entry.Team, entry.Email
+        }
+        if profile.ResolvedName == "" {
+            profile.ResolvedName = profile.UserID
+        }
+        profiles = append(profiles, profile)
+    }
+    return profiles, nil
+}
+
+func (s *MemoryStore) SaveProfile(ctx context.Context, profile UserProfile) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    s.profiles[workspaceFrom(ctx)+"/"+profile.UserID] = profile
+    return nil
+}
+
+func (s *MemoryStore) DirectoryEntries(ctx context.Context, source string) ([]DirectoryEntry, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    entries := []DirectoryEntry{}
+    for key, entry := range s.directory {
+        if strings.HasPrefix(key, workspaceFrom(ctx)+"/") && (source == "" || entry.Source == source) {
+            entries = append(entries, entry)
+        }
+    }
+    slices.SortFunc(entries, func(a, b DirectoryEntry) int { return cmp.Compare(a.UserID, b.UserID) })
+    return entries, nil
+}
+
+func (s *MemoryStore) ImportDirectory(ctx context.Context, source string, entries []DirectoryEntry, replace bool) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    workspace := workspaceFrom(ctx)
+    if replace {
+        for key, entry := range s.directory {
+            if strings.HasPrefix(key, workspace+"/") && entry.Source == source {
+                delete(s.directory, key)
+            }
+        }
+    }
+    for _, entry := range entries {
+        s.directory[workspace+"/"+entry.UserID] = entry
+    }
+    return nil
+}
+
+func (s *MemoryStore) DisplayNamePrecedence(ctx context.Context) ([]string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if precedence := s.precedence[workspaceFrom(ctx)]; len(precedence) > 0 {
+        return slices.Clone(precedence), nil
+    }
+    return defaultDisplayNamePrecedence, nil
+}
+
+func (s *MemoryStore) SetDisplayNamePrecedence(ctx context.Context, precedence []string, actor string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    s.precedence[workspaceFrom(ctx)] = slices.Clone(precedence)
+    return nil
+}
+
+// UserByEmail looks addresses up in the profiles of the shared database.
+func (s *MemoryStore) UserByEmail(ctx context.Context, address string) (string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for key, profile := range s.profiles {
+        if strings.HasPrefix(key, "/") && strings.EqualFold(profile.Email, address) {
+            return profile.UserID, nil
+        }
+    }
+    return "", nil
+}
+
+func (s *MemoryStore) Role(ctx context.Context, userID string) (string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return string(s.roles[userID].Role), nil
+}
+
+func (s *MemoryStore) AssignRole(ctx context.Context, userID string, role auth.Role, actor string) (UserRole, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return UserRole{}, err
+    }
+    assigned := UserRole{UserID: userID, Role: role, GrantedBy: actor, UpdatedAt: time.Now()}
+    s.roles[userID] = assigned
+    return assigned, nil
+}
+
+func (s *MemoryStore) RemoveRole(ctx context.Context, userID string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return false, err
+    }
+    _, ok := s.roles[userID]
+    delete(s.roles, userID)
+    return ok, nil
+}
+
+func (s *MemoryStore) Roles(ctx context.Context) ([]UserRole, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    roles := []UserRole{}
+    for _, role := range s.roles {
+        roles = append(roles, role)
+    }
+    sort.Slice(roles, func(i, j int) bool {
+        return roles[i].UserID < roles[j].UserID
+    })
+    return roles, nil
+}
+
+func (s *MemoryStore) Usergroups(ctx context.Context) ([]Usergroup, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    groups := []Usergroup{}
+    for _, group := range s.usergroups {
+        if group.Members == nil {
+            group.Members = []string{}
+        }
+        groups = append(groups, group)
+    }
+    sort.Slice(groups, func(i, j int) bool {
+        return groups[i].Handle < groups[j].Handle
+    })
+    return groups, nil
+}
+
+func (s *MemoryStore) Usergroup(ctx context.Context, ref string) (Usergroup, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, group := range s.usergroups {
+        if group.ID == ref || "@"+group.Handle == ref {
+            if group.Members == nil {
+                group.Members = []string{}
+            }
+            return group, nil
+        }
+    }
+    return Usergroup{}, errUsergroupNotFound
+}
+
+func (s *MemoryStore) ReplaceUsergroups(ctx context.Context, groups []slack.Usergroup) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    s.usergroups = make(map[string]Usergroup, len(groups))
+    for _, group := range groups {
+        members := append([]string{}, group.Users...)
+        sort.Strings(members)
+        s.usergroups[group.ID] = Usergroup{ID: group.ID, Handle: group.Handle, Name: group.Name, Members: members, SyncedAt: time.Now()}
+    }
+    return nil
+}
+
+func (s *MemoryStore) AssignThread(ctx context.Context, channelID, threadTS, assignee, assignedBy string) (string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return "", err
+    }
+    key := channelID + "/" + threadTS
+    var previous string
+    if assignment, ok := s.assignments[key]; ok {
+        previous = *assignment.Assignee
+    }
+    if assignee == "" {
+        delete(s.assignments, key)
+        return previous, nil
+    }
+    now := time.Now().UTC()
+    s.assignments[key] = ThreadAssignment{ChannelID: channelID, ThreadTS: threadTS, Assignee: &assignee, AssignedBy: &assignedBy, AssignedAt: &now}
+    return previous, nil
+}
+
+func (s *MemoryStore) ClaimThread(ctx context.Context, channelID, threadTS, userID string) (string, bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return "", false, err
+    }
+    key := channelID + "/" + threadTS
+    if claimedBy, ok := s.claims[key]; ok {
+        return claimedBy, false, nil
+    }
+    s.claims[key] = userID
+    return userID, true, nil
+}
+
+// SlackMessage is a message posted through a MemorySlack.
+type SlackMessage struct {
+    Channel  string
+    ThreadTS string
+    User     string
+    Text     string
+    Blocks   []slack.Block
+}
+
+func (s *MemoryStore) Webhooks(ctx context.Context, enabledOnly bool) ([]webhooks.Webhook, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    hooks := []webhooks.Webhook{}
+    for _, hook := range s.webhooks {
+        if hook.Enabled || !enabledOnly {
+            hooks = append(hooks, hook)
+        }
+    }
+    sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+    return hooks, nil
+}
+
+func (s *MemoryStore) Webhook(ctx context.Context, id int64) (webhooks.Webhook, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    hook, ok := s.webhooks[id]
+    if !ok {
+        return webhooks.Webhook{}, errWebhookNotFound
+    }
+    return hook, nil
+}
+
+func (s *MemoryStore) CreateWebhook(ctx context.Context, hook webhooks.Webhook) (webhooks.Webhook, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return webhooks.Webhook{}, err
+    }
+    s.lastID++
+    hook.ID, hook.CreatedAt = s.lastID, time.Now()
+    s.webhooks[hook.ID] = hook
+    return hook, nil
+}
+
+func (s *MemoryStore) UpdateWebhook(ctx context.Context, id int64, hook webhooks.Webhook) (webhooks.Webhook, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return webhooks.Webhook{}, err
+    }
+    stored, ok := s.webhooks[id]
+    if !ok {
+        return webhooks.Webhook{}, errWebhookNotFound
+    }
+    hook.ID, hook.CreatedBy, hook.CreatedAt = id, stored.CreatedBy, stored.CreatedAt
+    s.webhooks[id] = hook
+    return hook, nil
+}
+
+func (s *MemoryStore) DeleteWebhook(ctx context.Context, id int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    if _, ok := s.webhooks[id]; !ok {
+        return errWebhookNotFound
+    }
+    delete(s.webhooks, id)
+    return nil
+}
+
+func (s *MemoryStore) ThreadWebhooks(ctx context.Context, channelID string, threadTS string) ([]ThreadWebhook, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    hooks := []ThreadWebhook{}
+    for _, hook := range s.threadHooks {
+        if hook.ChannelID == channelID && hook.ThreadTS == threadTS {
+            hooks = append(hooks, hook)
+        }
+    }
+    sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+    return hooks, nil
+}
+
+func (s *MemoryStore) CreateThreadWebhook(ctx context.Context, hook ThreadWebhook) (ThreadWebhook, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return ThreadWebhook{}, err
+    }
+    s.lastID++
+    hook.ID, hook.CreatedAt = s.lastID, time.Now()
+    hook.Name, hook.Enabled = threadWebhookName(hook.ThreadTS), true
+    s.threadHooks[hook.ID] = hook
+    return hook, nil
+}
+
+func (s *MemoryStore) DeleteThreadWebhook(ctx context.Context, channelID string, threadTS string, id int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    hook, ok := s.threadHooks[id]
+    if !ok || hook.ChannelID != channelID || hook.ThreadTS != threadTS {
+        return errWebhookNotFound
+    }
+    delete(s.threadHooks, id)
+    return nil
+}
+
+func (s *MemoryStore) ExpireThreadWebhooks(ctx context.Context, channelID string, threadTS string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    for id, hook := range s.threadHooks {
+        if hook.ChannelID == channelID && hook.ThreadTS == threadTS {
+            delete(s.threadHooks, id)
+        }
+    }
+    return nil
+}
+
+func (s *MemoryStore) PruneThreadWebhooks(ctx context.Context, tracked []string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    for id, hook := range s.threadHooks {
+        thread, ok := s.threads[hook.ChannelID+"/"+hook.ThreadTS]
+        finished := !ok || thread.Status == statusResolved || thread.Status == statusClosed
+        if finished || !slices.Contains(tracked, hook.ChannelID) {
+            delete(s.threadHooks, id)
+        }
+    }
+    return nil
+}
+
+func (s *MemoryStore) Tokens(ctx context.Context, userID string) ([]APIToken, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    tokens := []APIToken{}
+    for _, token := range s.tokens {
+        if token.userID == userID && token.active() {
+            tokens = append(tokens, token.APIToken)
+        }
+    }
+    sort.Slice(tokens, func(i, j int) bool {
+        return tokens[i].ID > tokens[j].ID
+    })
+    return tokens, nil
+}
+
+func (s *MemoryStore) CreateToken(ctx context.Context, userID string, token APIToken, hash string) (APIToken, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return APIToken{}, err
+    }
+    s.lastID++
+    token.ID, token.CreatedAt = s.lastID, time.Now()
+    s.tokens[token.ID] = storedToken{APIToken: token, userID: userID, hash: hash}
+    return token, nil
+}
+
+func (s *MemoryStore) RevokeToken(ctx context.Context, userID string, id int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    token, ok := s.tokens[id]
+    if !ok || token.userID != userID || token.revoked {
+        return errTokenNotFound
+    }
+    if err := s.failed(); err != nil {
+        return err
+    }
+    token.revoked = true
+    s.tokens[id] = token
+    return nil
+}
+
+func (s *MemoryStore) LookupToken(ctx context.Context, hash string) (*auth.Principal, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for id, token := range s.tokens {
+        if token.hash != hash || !token.active() {
+            continue
+        }
+        now := time.Now()
+        token.LastUsedAt = &now
+        s.tokens[id] = token
+        scopes, err := auth.ParseScopes(strings.Join(token.Scopes, ","))
+        if err != nil {
+            return nil, err
+        }
+        return &auth.Principal{UserID: token.userID, Scopes: scopes, TokenID: id, Method: auth.MethodAPIToken}, nil
+    }
+    return nil, nil
+}
+
+func (s *MemoryStore) APIKeys(ctx context.Context) ([]APIKey, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    keys := []APIKey{}
+    for _, key := range s.keys {
+        if key.active() {
+            keys = append(keys, key.APIKey)
+        }
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        return keys[i].ID > keys[j].ID
+    })
+    return keys, nil
+}
+
+func (s *MemoryStore) CreateAPIKey(ctx context.Context, key APIKey, hash string) (APIKey, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return APIKey{}, err
+    }
+    s.lastID++
+    key.ID, key.CreatedAt = s.lastID, time.Now()
+    s.keys[key.ID] = storedAPIKey{APIKey: key, hash: hash}
+    return key, nil
+}
+
+func (s *MemoryStore) RevokeAPIKey(ctx context.Context, id int64, actor string) (string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    key, ok := s.keys[id]
+    if !ok || key.revoked {
+        return "", errAPIKeyNotFound
+    }
+    if err := s.failed(); err != nil {
+        return "", err
+    }
+    key.revoked = true
+    s.keys[id] = key
+    return key.Name, nil
+}
+
+func (s *MemoryStore) LookupAPIKey(ctx context.Context, hash string) (*auth.Principal, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for id, key := range s.keys {
+        if key.hash != hash || !key.active() {
+            continue
+        }
+        now := time.Now()
+        key.LastUsedAt = &now
+        s.keys[id] = key
+        scopes, err := auth.ParseScopes(strings.Join(key.Scopes, ","))
+        if err != nil {
+            return nil, err
+        }
+        return &auth.Principal{UserID: apiKeyActor + key.Name, Name: key.Name, Scopes: scopes, KeyID: id, Method: auth.MethodAPIKey}, nil
+    }
+    return nil, nil
+}
+
+func (s *MemoryStore) OpenSession(ctx context.Context, hash string, identity *auth.Identity, remoteIP string, userAgent string, expiresAt time.Time) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    s.sessions[hash] = storedSession{userID: identity.UserID, name: identity.Name, email: identity.Email, expiresAt: expiresAt}
+    return nil
+}
+
+func (s *MemoryStore) EndSession(ctx context.Context, hash string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    delete(s.sessions, hash)
+    return nil
+}
+
+func (s *MemoryStore) LookupSession(ctx context.Context, hash string) (*auth.Principal, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    session, ok := s.sessions[hash]
+    if !ok || !session.expiresAt.After(time.Now()) {
+        return nil, nil
+    }
+    return &auth.Principal{UserID: session.userID, Name: session.name, Email: session.email,
+        Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}, nil
+}
+
+func (s *MemoryStore) SessionExpiry(ctx context.Context, hash string) (*time.Time, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    session, ok := s.sessions[hash]
+    if !ok {
+        return nil, nil
+    }
+    return &session.expiresAt, nil
+}
+
+func (s *MemoryStore) PruneSessions(ctx context.Context) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    for hash, session := range s.sessions {
+        if !session.expiresAt.After(time.Now()) {
+            delete(s.sessions, hash)
+        }
+    }
+    return nil
+}
+
+func (s *MemoryStore) RecordLoginEvent(ctx context.Context, event auth.LoginEvent) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    entry := LoginAuditEntry{ID: int64(len(s.logins) + 1), RemoteIP: event.RemoteIP, UserAgent: event.UserAgent,
+        Method: event.Method, Success: event.Success, Reason: event.Reason, CreatedAt: time.Now()}
+    if event.UserID != "" {
+        entry.UserID = &event.UserID
+    }
+    s.logins = append(s.logins, entry)
+    return nil
+}
+
+func (s *MemoryStore) LoginAudit(ctx context.Context, filter loginAuditFilter) ([]LoginAuditEntry, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    entries := []LoginAuditEntry{}
+    for i := len(s.logins) - 1; i >= 0 && len(entries) < filter.limit; i-- {
+        entry := s.logins[i]
+        if filter.userID != "" && (entry.UserID == nil || *entry.UserID != filter.userID) ||
+            filter.remoteIP != "" && entry.RemoteIP != filter.remoteIP ||
+            filter.success != nil && entry.Success != *filter.success ||
+            entry.CreatedAt.Before(filter.since) {
+            continue
+        }
+        entries = append(entries, entry)
+    }
+    return entries, nil
+}
+
+func (s *MemoryStore) EmailPreferences(ctx context.Context, address string) (EmailPreferences, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if prefs, ok := s.emailPrefs[address]; ok {
+        return prefs, nil
+    }
+    return EmailPreferences{Email: address, Digest: true}, nil
+}
+
+func (s *MemoryStore) SetEmailPreferences(ctx context.Context, prefs EmailPreferences) (EmailPreferences, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return prefs, err
+    }
+    now := time.Now()
+    prefs.UpdatedAt = &now
+    s.emailPrefs[prefs.Email] = prefs
+    return prefs, nil
+}
+
+func (s *MemoryStore) UserPreferences(ctx context.Context, userID string) (UserPreferences, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if prefs, ok := s.preferences[userID]; ok {
+        return prefs, nil
+    }
+    return UserPreferences{InboxRanking: defaultInboxRanking}, nil
+}
+
+func (s *MemoryStore) SetUserPreferences(ctx context.Context, userID string, prefs UserPreferences) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.failed(); err != nil {
+        return err
+    }
+    s.preferences[userID] = prefs
+    return nil
+}
+
+// MemorySlack is a SlackClient recording the messages posted through it
+// instead of sending them. Lookups find nothing but Conversations, the
+// channels the bot is a member of, Usergroups and Users.
+type MemorySlack struct {
+    Conversations []slack.Conversation
+    Usergroups    []slack.Usergroup
+    Users         []slack.User
+
+    mu       sync.Mutex
+    messages []SlackMessage
+    // responses are the answers sent to interactions
+    responses []slack.ResponseMessage
+}
+
+// Messages returns the messages posted so far, oldest first.
+func (s *MemorySlack) Messages() []SlackMessage {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]SlackMessage(nil), s.messages...)
+}
+
+// Responses returns the answers sent to interactions so far, oldest first.
+func (s *MemorySlack) Responses() []slack.ResponseMessage {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]slack.ResponseMessage(nil), s.responses...)
+}
+
+func (s *MemorySlack) post(msg SlackMessage) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.messages = append(s.messages, msg)
+}
+
+func (s *MemorySlack) Configured() bool { return true }
+
+func (s *MemorySlack) AuthTest(ctx context.Context) ([]string, error) { return nil, nil }
+
+func (s *MemorySlack) Identity(ctx context.Context) (*slack.Identity, error) {
+    return &slack.Identity{}, nil
+}
+
+func (s *MemorySlack) AuthTeamsList(ctx context.Context, cursor string, limit int) (*slack.TeamsPage, error) {
+    return &slack.TeamsPage{}, nil
+}
+
+func (s *MemorySlack) MigrationExchange(ctx context.Context, teamID string, userIDs []string) (map[string]string, error) {
+    return map[string]string{}, nil
+}
+
+// CallsPerMinute is unlimited, nothing is sent.
+func (s *MemorySlack) CallsPerMinute(method string) float64 { return math.Inf(1) }
+
+func (s *MemorySlack) ConversationInfo(ctx context.Context, channel string) (*slack.Conversation, error) {
+    return &slack.Conversation{ID: channel}, nil
+}
+
+func (s *MemorySlack) ConversationsHistory(ctx context.Context, channel string, oldest string, cursor string, limit int) (*slack.HistoryPage, error) {
+    return &slack.HistoryPage{}, nil
+}
+
+func (s *MemorySlack) ConversationsReplies(ctx context.Context, channel string, ts string, cursor string, limit int) (*slack.HistoryPage, error) {
+    return &slack.HistoryPage{}, nil
+}
+
+func (s *MemorySlack) UsersConversations(ctx context.Context, teamID string, cursor string, limit int) (*slack.ConversationsPage, error) {
+    return &slack.ConversationsPage{Channels: s.Conversations}, nil
+}
+
+func (s *MemorySlack) UsersList(ctx context.Context, teamID string, cursor string, limit int) (*slack.UsersPage, error) {
+    return &slack.UsersPage{Members: s.Users}, nil
+}
+
+func (s *MemorySlack) ReactionsGet(ctx context.Context, channel string, ts string) ([]slack.Reaction, error) {
+    return nil, nil
+}
+
+func (s *MemorySlack) UsergroupsList(ctx context.Context, teamID string) ([]slack.Usergroup, error) {
+    return s.Usergroups, nil
+}
+
+func (s *MemorySlack) PostMessage(ctx context.Context, channel string, text string, blocks []slack.Block) (string, error) {
+    s.post(SlackMessage{Channel: channel, Text: text, Blocks: blocks})
+    return "", nil
+}
+
+func (s *MemorySlack) PostReply(ctx context.Context, channel string, threadTS string, text string) error {
+    s.post(SlackMessage{Channel: channel, ThreadTS: threadTS, Text: text})
+    return nil
+}
+
+func (s *MemorySlack) UpdateMessage(ctx context.Context, channel string, ts string, text string, blocks []slack.Block) error {
+    s.post(SlackMessage{Channel: channel, Text: text, Blocks: blocks})
+    return nil
+}
+
+func (s *MemorySlack) PinMessage(ctx context.Context, channel string, ts string) error { return nil }
+
+func (s *MemorySlack) CreateChannelCanvas(ctx context.Context, channel string, markdown string) (string, error) {
+    return "", nil
+}
+
+func (s *MemorySlack) ReplaceCanvas(ctx context.Context, canvasID string, markdown string) error {
+    return nil
+}
+
+func (s *MemorySlack) PostEphemeral(ctx context.Context, channel string, user string, text string, blocks []slack.Block) error {
+    s.post(SlackMessage{Channel: channel, User: user, Text: text, Blocks: blocks})
+    return nil
+}
+
+func (s *MemorySlack) Respond(ctx context.Context, responseURL string, msg slack.ResponseMessage) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.responses = append(s.responses, msg)
+    return nil
+}
+
+func (s *MemorySlack) OpenView(ctx context.Context, triggerID string, view slack.View) error {
+    return nil
+}
+
+func (s *MemorySlack) UpdateWorkflowStep(ctx context.Context, editID string, inputs map[string]slack.StepInput, outputs []slack.StepOutput) error {
+    return nil
+}
+
+func (s *MemorySlack) CompleteWorkflowStep(ctx context.Context, executeID string, outputs map[string]string) error {
+    return nil
+}
+
+func (s *MemorySlack) FailWorkflowStep(ctx context.Context, executeID string, message string) error {
+    return nil
+}
+
+// Ensure that the store interfaces are implemented
+var (
+    _ ThreadStore          = (*MemoryStore)(nil)
+    _ NoteStore            = (*MemoryStore)(nil)
+    _ EffortStore          = (*MemoryStore)(nil)
+    _ WatcherStore         = (*MemoryStore)(nil)
+    _ ThreadListStore      = (*MemoryStore)(nil)
+    _ ThreadStatusStore    = (*MemoryStore)(nil)
+    _ ThreadOverrideStore  = (*MemoryStore)(nil)
+    _ BulkThreadStore      = (*MemoryStore)(nil)
+    _ ResolutionStore      = (*MemoryStore)(nil)
+    _ AssignmentStore      = (*MemoryStore)(nil)
+    _ AckStore             = (*MemoryStore)(nil)
+    _ ReporterWaitStore    = (*MemoryStore)(nil)
+    _ ReleaseStore         = (*MemoryStore)(nil)
+    _ AnswerSignalStore    = (*MemoryStore)(nil)
+    _ StatsStore           = (*MemoryStore)(nil)
+    _ ChannelStore         = (*MemoryStore)(nil)
+    _ ChannelConfigStore   = (*MemoryStore)(nil)
+    _ ChannelGroupStore    = (*MemoryStore)(nil)
+    _ GoalStore            = (*MemoryStore)(nil)
+    _ ReplyTemplateStore   = (*MemoryStore)(nil)
+    _ UserStore            = (*MemoryStore)(nil)
+    _ DirectoryStore       = (*MemoryStore)(nil)
+    _ MessageStore         = (*MemoryStore)(nil)
+    _ BackfillStore        = (*MemoryStore)(nil)
+    _ ReminderStore        = (*MemoryStore)(nil)
+    _ QualityPromptStore   = (*MemoryStore)(nil)
+    _ SuggestionStore      = (*MemoryStore)(nil)
+    _ LiveThreadStore      = (*MemoryStore)(nil)
+    _ GitHubIssueStore     = (*MemoryStore)(nil)
+    _ JiraTicketStore      = (*MemoryStore)(nil)
+    _ ChannelSummaryStore  = (*MemoryStore)(nil)
+    _ StorageStore         = (*MemoryStore)(nil)
+    _ AdoptionStore        = (*MemoryStore)(nil)
+    _ CalibrationStore     = (*MemoryStore)(nil)
+    _ DigestStore          = (*MemoryStore)(nil)
+    _ AnalysisStore        = (*MemoryStore)(nil)
+    _ SlackConnectStore    = (*MemoryStore)(nil)
+    _ LegalHoldStore       = (*MemoryStore)(nil)
+    _ ExportJobStore       = (*MemoryStore)(nil)
+    _ WebhookStore         = (*MemoryStore)(nil)
+    _ AuditStore           = (*MemoryStore)(nil)
+    _ TokenStore           = (*MemoryStore)(nil)
+    _ APIKeyStore          = (*MemoryStore)(nil)
+    _ SessionStore         = (*MemoryStore)(nil)
+    _ EmailPreferenceStore = (*MemoryStore)(nil)
+    _ PreferenceStore      = (*MemoryStore)(nil)
+    _ SlackClient          = (*MemorySlack)(nil)
+)
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/webhooks"
+
+    "github.com/labstack/echo/v4"
+)
+
+const outgoingWebhookColumns = "id, name, url, events, payload_template, headers, enabled, created_by, created_at"
+
+func scanOutgoingWebhook(row interface{ Scan(...interface{}) error }) (webhooks.Webhook, error) {
+    var hook webhooks.Webhook
+    var events, headers string
+    err := row.Scan(&hook.ID, &hook.Name, &hook.URL, &events, &hook.Template, &headers, &hook.Enabled, &hook.CreatedBy, &hook.CreatedAt)
+    if err != nil {
+        return hook, err
+    }
+    json.Unmarshal([]byte(events), &hook.Events)
+    json.Unmarshal([]byte(headers), &hook.Headers)
+    if hook.Events == nil {
+        hook.Events = []string{}
+    }
+    if hook.Headers == nil {
+        hook.Headers = map[string]string{}
+    }
+    return hook, nil
+}
+
+// errWebhookNotFound is returned by stores for a webhook that does not
+// exist.
+var errWebhookNotFound = errors.New("webhook not found")
+
+// publishEvent queues event for every enabled webhook subscribed to it,
+// including those of its thread.
+func (c *Container) publishEvent(ctx context.Context, event webhooks.Event) {
+    c.publishThreadEvent(ctx, event)
+
+    hooks, err := c.webhookStore.Webhooks(ctx, true)
+    if err != nil {
+        c.logger.Warnf("failed to load webhooks for %s event: %v", event.Action, err)
+        return
+    }
+    for _, hook := range hooks {
+        if hook.Wants(event.Action) {
+            c.webhooks.Enqueue(hook, event)
+        }
+    }
+}
+
+// RunWebhookDelivery posts published events to webhooks until ctx is
+// cancelled.
+func (c *Container) RunWebhookDelivery(ctx context.Context) {
+    c.webhooks.Run(ctx)
+}
+
+// GetOutgoingWebhooks - Get the webhooks dashboard events are posted to
+func (c *Container) GetOutgoingWebhooks(ctx echo.Context) error {
+    hooks, err := c.webhookStore.Webhooks(ctx.Request().Context(), false)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query webhooks")
+    }
+
+    return ctx.JSON(http.StatusOK, hooks)
+}
+
+// decodeOutgoingWebhook reads and validates a webhook from the request body.
+// Webhooks are enabled unless the body says otherwise.
+func decodeOutgoingWebhook(ctx echo.Context) (webhooks.Webhook, string) {
+    hook := webhooks.Webhook{Enabled: true}
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&hook); err != nil {
+        return hook, "Invalid request body"
+    }
+    if hook.Events == nil {
+        hook.Events = []string{}
+    }
+    if hook.Headers == nil {
+        hook.Headers = map[string]string{}
+    }
+    if err := hook.Validate(); err != nil {
+        return hook, err.Error()
+    }
+    return hook, ""
+}
+
+// CreateOutgoingWebhook - Register a webhook with an optional payload template and headers
+func (c *Container) CreateOutgoingWebhook(ctx echo.Context) error {
+    hook, msg := decodeOutgoingWebhook(ctx)
+    if msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    actor := actorFrom(ctx)
+    hook.CreatedBy = actor
+    hook, err := c.webhookStore.CreateWebhook(ctx.Request().Context(), hook)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to create webhook")
+    }
+
+    // Header values often carry credentials and stay out of the audit log
+    c.audit(ctx.Request().Context(), actor, "webhook.create", "", "", map[string]interface{}{
+        "webhook_id": hook.ID,
+        "url":        hook.URL,
+        "events":     hook.Events,
+    })
+
+    return ctx.JSON(http.StatusCreated, hook)
+}
+
+// UpdateOutgoingWebhook - Replace the settings of a webhook
+func (c *Container) UpdateOutgoingWebhook(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid webhook id")
+    }
+
+    hook, msg := decodeOutgoingWebhook(ctx)
+    if msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    hook, err = c.webhookStore.UpdateWebhook(ctx.Request().Context(), id, hook)
+    if err == errWebhookNotFound {
+        return apierror.New(http.StatusNotFound, "Webhook not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update webhook")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "webhook.update", "", "", map[string]interface{}{
+        "webhook_id": hook.ID,
+        "url":        hook.URL,
+        "events":     hook.Events,
+        "enabled":    hook.Enabled,
+    })
+
+    return ctx.JSON(http.StatusOK, hook)
+}
+
+// DeleteOutgoingWebhook - Remove a webhook
+func (c *Container) DeleteOutgoingWebhook(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid webhook id")
+    }
+
+    err = c.webhookStore.DeleteWebhook(ctx.Request().Context(), id)
+    if err == errWebhookNotFound {
+        return apierror.New(http.StatusNotFound, "Webhook not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to delete webhook")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "webhook.delete", "", "", map[string]interface{}{
+        "webhook_id": id,
+    })
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// TestOutgoingWebhook - Post a sample event to a webhook and report the outcome
+func (c *Container) TestOutgoingWebhook(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid webhook id")
+    }
+
+    hook, err := c.webhookStore.Webhook(ctx.Request().Context(), id)
+    if err == errWebhookNotFound {
+        return apierror.New(http.StatusNotFound, "Webhook not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query webhook")
+    }
+
+    event := webhooks.SampleEvent()
+    payload, _ := hook.Render(event)
+    deliveryCtx, cancel := context.WithTimeout(ctx.Request().Context(), 15*time.Second)
+    defer cancel()
+    status, err := c.webhooks.Deliver(deliveryCtx, hook, event)
+
+    response := map[string]interface{}{
+        "delivered": err == nil,
+        "status":    status,
+        "payload":   string(payload),
+    }
+    if err != nil {
+        response["error"] = err.Error()
+    }
+    return ctx.JSON(http.StatusOK, response)
+}
+
+func (s postgresStore) Webhooks(ctx context.Context, enabledOnly bool) ([]webhooks.Webhook, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    query := "SELECT " + outgoingWebhookColumns + " FROM outgoing_webhooks ORDER BY id"
+    if enabledOnly {
+        query = "SELECT " + outgoingWebhookColumns + " FROM outgoing_webhooks WHERE enabled ORDER BY id"
+    }
+    rows, err := db.QueryContext(ctx, query)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    hooks := []webhooks.Webhook{}
+    for rows.Next() {
+        hook, err := scanOutgoingWebhook(rows)
+        if err != nil {
+            continue
+        }
+        hooks = append(hooks, hook)
+    }
+    return hooks, rows.Err()
+}
+
+func (s postgresStore) Webhook(ctx context.Context, id int64) (webhooks.Webhook, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return webhooks.Webhook{}, err
+    }
+
+    hook, err := scanOutgoingWebhook(db.QueryRowContext(ctx, "SELECT "+outgoingWebhookColumns+" FROM outgoing_webhooks WHERE id = $1", id))
+    if err == sql.ErrNoRows {
+        return webhooks.Webhook{}, errWebhookNotFound
+    }
+    return hook, err
+}
+
+func (s postgresStore) CreateWebhook(ctx context.Context, hook webhooks.Webhook) (webhooks.Webhook, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return webhooks.Webhook{}, err
+    }
+
+    events, _ := json.Marshal(hook.Events)
+    headers, _ := json.Marshal(hook.Headers)
+    return scanOutgoingWebhook(db.QueryRowContext(ctx, `
+        INSERT INTO outgoing_webhooks (name, url, events, payload_template, headers, enabled, created_by, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+        RETURNING `+outgoingWebhookColumns,
+        hook.Name, hook.URL, string(events), hook.Template, string(headers), hook.Enabled, hook.CreatedBy))
+}
+
+func (s postgresStore) UpdateWebhook(ctx context.Context, id int64, hook webhooks.Webhook) (webhooks.Webhook, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return webhooks.Webhook{}, err
+    }
+
+    events, _ := json.Marshal(hook.Events)
+    headers, _ := json.Marshal(hook.Headers)
+    hook, err = scanOutgoingWebhook(db.QueryRowContext(ctx, `
+        UPDATE outgoing_webhooks
+        SET name = $2, url = $3, events = $4, payload_template = $5, headers = $6, enabled = $7
+        WHERE id = $1
+        RETURNING `+outgoingWebhookColumns,
+        id, hook.Name, hook.URL, string(events), hook.Template, string(headers), hook.Enabled))
+    if err == sql.ErrNoRows {
+        return webhooks.Webhook{}, errWebhookNotFound
+    }
+    return hook, err
+}
+
+func (s postgresStore) DeleteWebhook(ctx context.Context, id int64) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    result, err := db.ExecContext(ctx, "DELETE FROM outgoing_webhooks WHERE id = $1", id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return errWebhookNotFound
+    }
+    return nil
+}
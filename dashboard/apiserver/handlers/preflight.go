@@ -0,0 +1,78 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+
+    "dashboard/apiserver/migrations"
+)
+
+// Preflight check statuses.
+const (
+    CheckOK      = "ok"
+    CheckFailed  = "failed"
+    CheckSkipped = "skipped"
+)
+
+// CheckResult is the outcome of one preflight check
+type CheckResult struct {
+    Name   string `json:"name"`
+    Status string `json:"status"`
+    Detail string `json:"detail,omitempty"`
+}
+
+
+// schemaTables returns the tables the dashboard reads, created by the
+// migrations, those of the collector included.
+func schemaTables() []string {
+    tables, _ := migrations.Tables()
+    return tables
+}
+
+// Preflight checks the database, schema and Slack token the container was
+// configured with. Configuration itself was validated by NewContainer.
+func (c *Container) Preflight(ctx context.Context) []CheckResult {
+    results := []CheckResult{}
+
+    db, err := c.getDBConnection()
+    if err != nil {
+        results = append(results,
+            CheckResult{Name: "database", Status: CheckFailed, Detail: err.Error()},
+            CheckResult{Name: "schema", Status: CheckSkipped, Detail: "database unavailable"},
+        )
+    } else {
+        results = append(results, CheckResult{Name: "database", Status: CheckOK})
+
+        missing := []string{}
+        for _, table := range schemaTables() {
+            var exists bool
+            if err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil || !exists {
+                missing = append(missing, table)
+            }
+        }
+        if len(missing) > 0 {
+            results = append(results, CheckResult{Name: "schema", Status: CheckFailed,
+                Detail: "missing tables: " + strings.Join(missing, ", ")})
+        } else {
+            results = append(results, CheckResult{Name: "schema", Status: CheckOK})
+        }
+    }
+
+    if !c.slack.Configured() {
+        results = append(results, CheckResult{Name: "slack", Status: CheckSkipped, Detail: "no bot token configured"})
+        return results
+    }
+    // Only the scopes of the enabled features are required
+    report, err := c.auditSlackScopes(ctx)
+    if err != nil {
+        results = append(results, CheckResult{Name: "slack", Status: CheckFailed, Detail: err.Error()})
+        return results
+    }
+    if len(report.Missing) > 0 {
+        results = append(results, CheckResult{Name: "slack", Status: CheckFailed,
+            Detail: "missing token scopes: " + strings.Join(report.Missing, ", ")})
+    } else {
+        results = append(results, CheckResult{Name: "slack", Status: CheckOK})
+    }
+    return results
+}
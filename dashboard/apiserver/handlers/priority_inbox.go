@@ -0,0 +1,262 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "math"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+// maxInboxThreads bounds the threads ranked by a priority inbox, the most
+// recently active first.
+const maxInboxThreads = 1000
+
+// inboxRecencyHalfLife is how long after its last reply a thread counts
+// half as recent.
+const inboxRecencyHalfLife = 24 * time.Hour
+
+// InboxRanking weights what ranks the threads of a user's priority inbox:
+// how recently they were active, their priority, and how involved the user
+// is. Weights are relative to each other.
+type InboxRanking struct {
+    Recency     float64 `json:"recency"`
+    Priority    float64 `json:"priority"`
+    Involvement float64 `json:"involvement"`
+}
+
+// defaultInboxRanking is used by users without preferences.
+var defaultInboxRanking = InboxRanking{Recency: 1, Priority: 1, Involvement: 1}
+
+// parseInboxRanking decodes a stored ranking, falling back to the default.
+func parseInboxRanking(stored sql.NullString) InboxRanking {
+    ranking := defaultInboxRanking
+    if stored.Valid {
+        if err := json.Unmarshal([]byte(stored.String), &ranking); err != nil {
+            return defaultInboxRanking
+        }
+    }
+    return ranking
+}
+
+func (r InboxRanking) validate() string {
+    for _, w := range []float64{r.Recency, r.Priority, r.Involvement} {
+        if w < 0 || w > 10 || math.IsNaN(w) {
+            return "weights must be between 0 and 10"
+        }
+    }
+    if r.Recency+r.Priority+r.Involvement == 0 {
+        return "at least one weight must be above 0"
+    }
+    return ""
+}
+
+// UserPreferences are the preferences of a dashboard user.
+type UserPreferences struct {
+    InboxRanking InboxRanking `json:"inbox_ranking"`
+}
+
+// InboxThread is a thread of a priority inbox with its rank.
+type InboxThread struct {
+    Thread
+    // Score ranks the thread, from 0 to 1, combining TriageScore and the
+    // ranking of the user
+    Score float64 `json:"score"`
+    // TriageScore is how urgent the thread is for anyone, from 0 to 1
+    TriageScore float64 `json:"triage_score"`
+    // Involvement is how the user takes part in the thread: assignee,
+    // claimer, acknowledger, author or stakeholder
+    Involvement string `json:"involvement"`
+}
+
+// InboxPage is a page of a priority inbox, best ranked first.
+type InboxPage struct {
+    Items        []InboxThread `json:"items"`
+    Total        int           `json:"total"`
+    InboxRanking InboxRanking  `json:"inbox_ranking"`
+}
+
+// priorityScores weight calibrated priorities.
+var priorityScores = map[domain.Priority]float64{domain.PriorityHigh: 1, domain.PriorityMedium: 0.6, domain.PriorityLow: 0.3}
+
+// heatScores weight how close a thread is to its due date.
+var heatScores = map[string]float64{HeatGreen: 0, HeatYellow: 0.4, HeatOrange: 0.7, HeatRed: 1}
+
+// triageScore is how urgent a presented thread is, the same for everyone:
+// its priority and its heat count equally.
+func triageScore(thread Thread) float64 {
+    priority, ok := priorityScores[thread.Priority]
+    if !ok {
+        priority = 0.1
+    }
+    heat := heatScores[thread.Heat]
+    if thread.SLABreached {
+        heat = 1
+    }
+    return (priority + heat) / 2
+}
+
+// involvement returns how userID takes part in a thread and how much that
+// counts, from 0 to 1.
+func involvement(thread Thread, userID string) (string, float64) {
+    switch {
+    case thread.Assignee != nil && *thread.Assignee == userID:
+        return "assignee", 1
+    case thread.ClaimedBy != nil && *thread.ClaimedBy == userID:
+        return "claimer", 0.8
+    case thread.AcknowledgedBy != nil && *thread.AcknowledgedBy == userID:
+        return "acknowledger", 0.7
+    case thread.UserID == userID:
+        return "author", 0.6
+    case strings.Contains(thread.AIStakeholders, `"`+userID+`"`):
+        return "stakeholder", 0.4
+    }
+    return "", 0
+}
+
+// rank scores a thread of userID's inbox, averaging its triage score and
+// the weighted recency, priority and involvement of the ranking.
+func (r InboxRanking) rank(thread Thread, userID string, now time.Time) InboxThread {
+    ranked := InboxThread{Thread: thread, TriageScore: triageScore(thread)}
+    var involved float64
+    ranked.Involvement, involved = involvement(thread, userID)
+    recency := math.Exp2(-float64(now.Sub(thread.LatestReply)) / float64(inboxRecencyHalfLife))
+    if recency > 1 {
+        recency = 1
+    }
+    personal := (r.Recency*recency + r.Priority*priorityScores[thread.Priority] + r.Involvement*involved) /
+        (r.Recency + r.Priority + r.Involvement)
+    ranked.Score = math.Round((ranked.TriageScore+personal)/2*1000) / 1000
+    ranked.TriageScore = math.Round(ranked.TriageScore*1000) / 1000
+    return ranked
+}
+
+// GetMyPreferences - Get the caller's preferences, such as how their priority inbox is ranked
+func (c *Container) GetMyPreferences(ctx echo.Context) error {
+    prefs, err := c.preferences.UserPreferences(ctx.Request().Context(), actorFrom(ctx))
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to get preferences")
+    }
+    return ctx.JSON(http.StatusOK, prefs)
+}
+
+// PutMyPreferences - Set the caller's preferences
+func (c *Container) PutMyPreferences(ctx echo.Context) error {
+    var prefs UserPreferences
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&prefs); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if msg := prefs.InboxRanking.validate(); msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    if err := c.preferences.SetUserPreferences(ctx.Request().Context(), actorFrom(ctx), prefs); err != nil {
+        c.logger.Errorf("failed to store preferences: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to store preferences")
+    }
+    return ctx.JSON(http.StatusOK, prefs)
+}
+
+// GetMyThreads - Get the caller's priority inbox: the open threads they authored or are a stakeholder of, ranked by their preferences
+func (c *Container) GetMyThreads(ctx echo.Context) error {
+    userID := actorFrom(ctx)
+
+    limit := 10
+    if parsed, err := strconv.Atoi(ctx.QueryParam("limit")); err == nil && parsed > 0 {
+        limit = parsed
+    }
+    if limit > maxThreadPageSize {
+        limit = maxThreadPageSize
+    }
+    offset := 0
+    if offsetStr := ctx.QueryParam("offset"); offsetStr != "" {
+        parsed, err := strconv.Atoi(offsetStr)
+        if err != nil || parsed < 0 {
+            return apierror.New(http.StatusBadRequest, "offset must be a non-negative integer")
+        }
+        offset = parsed
+    }
+
+    // The inbox narrows down like thread lists, open threads by default
+    filters, err := parseThreadFilters(ctx)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+    if len(filters.statuses) == 0 {
+        filters.statuses = []string{string(statusOpen)}
+    }
+    filters.involves = userID
+
+    prefs, err := c.preferences.UserPreferences(ctx.Request().Context(), userID)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to get preferences")
+    }
+
+    // Ranking needs the presented threads, so the most recently active are
+    // ranked in memory
+    page := InboxPage{Items: []InboxThread{}, InboxRanking: prefs.InboxRanking}
+    list := threadList{filters: filters, sort: "latest_reply", order: "desc", limit: maxInboxThreads}
+    threads, total, err := c.threadLists.ListThreads(ctx.Request().Context(), list)
+    if err == errGroupNotFound {
+        return apierror.New(http.StatusNotFound, "Channel group not found")
+    }
+    if err != nil {
+        c.logger.Errorf("failed to query inbox of %s: %v", userID, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to query threads")
+    }
+    page.Total = total
+
+    now := time.Now()
+    ranked := make([]InboxThread, 0, len(threads))
+    for _, thread := range threads {
+        ranked = append(ranked, prefs.InboxRanking.rank(thread, userID, now))
+    }
+    sort.SliceStable(ranked, func(i, j int) bool {
+        return ranked[i].Score > ranked[j].Score
+    })
+    if offset < len(ranked) {
+        ranked = ranked[offset:]
+        if len(ranked) > limit {
+            ranked = ranked[:limit]
+        }
+        page.Items = ranked
+    }
+    return ctx.JSON(http.StatusOK, page)
+}
+
+func (s postgresStore) UserPreferences(ctx context.Context, userID string) (UserPreferences, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return UserPreferences{}, err
+    }
+    var stored sql.NullString
+    err = db.QueryRowContext(ctx, "SELECT inbox_ranking FROM user_preferences WHERE user_id = $1", userID).Scan(&stored)
+    if err != nil && err != sql.ErrNoRows {
+        return UserPreferences{}, err
+    }
+    return UserPreferences{InboxRanking: parseInboxRanking(stored)}, nil
+}
+
+func (s postgresStore) SetUserPreferences(ctx context.Context, userID string, prefs UserPreferences) error {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return err
+    }
+
+    ranking, _ := json.Marshal(prefs.InboxRanking)
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO user_preferences (user_id, inbox_ranking, updated_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (user_id) DO UPDATE SET inbox_ranking = EXCLUDED.inbox_ranking, updated_at = EXCLUDED.updated_at
+    `, userID, string(ranking))
+    return err
+}
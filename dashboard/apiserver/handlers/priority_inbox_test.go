@@ -0,0 +1,54 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestMyThreadsRankedByPreferences(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    now := time.Now()
+    for _, thread := range []domain.Thread{
+        {ChannelID: "C1", ThreadTS: "1700000000.000100", UserID: "U1", Status: statusOpen, LatestReply: now.Add(-72 * time.Hour)},
+        {ChannelID: "C1", ThreadTS: "1700000000.000200", UserID: "U2", Status: statusOpen, LatestReply: now},
+        {ChannelID: "C1", ThreadTS: "1700000000.000300", UserID: "U2", Status: statusOpen, LatestReply: now},
+        {ChannelID: "C1", ThreadTS: "1700000000.000400", UserID: "U1", Status: statusResolved, LatestReply: now},
+    } {
+        store.AddThread(StoredThread{Thread: thread})
+    }
+    if _, err := store.AssignThread(context.Background(), "C1", "1700000000.000200", "U1", "U2"); err != nil {
+        t.Fatal(err)
+    }
+    c := newTestContainer(t, store, &MemorySlack{})
+    user := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeRead}, Method: auth.MethodSession}
+
+    rec := serveAs(c, user, http.MethodPut, "/api/me/preferences", "/api/me/preferences", `{"inbox_ranking": {"recency": 0, "priority": 0, "involvement": 0}}`, c.PutMyPreferences)
+    if rec.Code != http.StatusBadRequest {
+        t.Fatalf("all zero weights got status %d", rec.Code)
+    }
+    rec = serveAs(c, user, http.MethodPut, "/api/me/preferences", "/api/me/preferences", `{"inbox_ranking": {"recency": 0, "priority": 0, "involvement": 1}}`, c.PutMyPreferences)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("setting preferences got status %d: %s", rec.Code, rec.Body.String())
+    }
+
+    rec = serveAs(c, user, http.MethodGet, "/api/me/threads", "/api/me/threads", "", c.GetMyThreads)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var page InboxPage
+    decodeResponse(t, rec, &page)
+    if page.InboxRanking.Involvement != 1 || page.InboxRanking.Recency != 0 {
+        t.Fatalf("got ranking %+v, want the stored preferences", page.InboxRanking)
+    }
+    // The open threads of the user, the assigned one first
+    if page.Total != 2 || len(page.Items) != 2 || page.Items[0].ThreadTS != "1700000000.000200" || page.Items[0].Involvement != "assignee" ||
+        page.Items[1].Involvement != "author" {
+        t.Fatalf("got inbox %+v", page)
+    }
+}
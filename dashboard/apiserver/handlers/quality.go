@@ -0,0 +1,265 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Details the AI pipeline checks new threads for, as listed in the
+// missing_info of its analysis.
+const (
+    missingLogs       = "logs"
+    missingVersion    = "version"
+    missingQuestion   = "clear_question"
+    missingReproSteps = "steps_to_reproduce"
+)
+
+// missingInfoRequests is how the bot asks authors for each missing detail,
+// in the order they are asked.
+var missingInfoRequests = []struct{ kind, request string }{
+    {missingQuestion, "what you are trying to do and what you need from us"},
+    {missingVersion, "the version you are running"},
+    {missingLogs, "the relevant logs or error output"},
+    {missingReproSteps, "the steps to reproduce the problem"},
+}
+
+const qualityPromptInterval = 5 * time.Minute
+
+// qualityPromptWindow keeps authors from being asked about threads they
+// started long ago, e.g. when a channel turns prompts on.
+const qualityPromptWindow = 24 * time.Hour
+
+// threadQuality is how complete the AI pipeline found the first message of
+// a thread for triage.
+type threadQuality struct {
+    Score   *float64 `json:"quality_score"`
+    Missing []string `json:"missing_info"`
+}
+
+// parseThreadQuality extracts the quality from a stored AI analysis. Only
+// the details the dashboard knows to ask for are kept.
+func parseThreadQuality(analysis sql.NullString) threadQuality {
+    quality := threadQuality{Missing: []string{}}
+    if !analysis.Valid {
+        return quality
+    }
+    var stored threadQuality
+    if err := json.Unmarshal([]byte(analysis.String), &stored); err != nil {
+        return quality
+    }
+    if stored.Score != nil && *stored.Score >= 0 && *stored.Score <= 1 {
+        quality.Score = stored.Score
+    }
+    for _, known := range missingInfoRequests {
+        for _, kind := range stored.Missing {
+            if kind == known.kind {
+                quality.Missing = append(quality.Missing, kind)
+                break
+            }
+        }
+    }
+    return quality
+}
+
+// QualityPrompts is the per-channel policy for asking the authors of
+// incomplete threads for what is missing. Authors are asked once, in the
+// thread, when the quality score of a new open thread is below Below.
+type QualityPrompts struct {
+    Enabled bool    `json:"enabled"`
+    Below   float64 `json:"below"`
+}
+
+// defaultQualityPrompts is used by channels without a policy.
+func defaultQualityPrompts() QualityPrompts {
+    return QualityPrompts{Enabled: false, Below: 0.6}
+}
+
+// parseQualityPrompts decodes a stored policy, falling back to the default.
+func parseQualityPrompts(stored sql.NullString) QualityPrompts {
+    prompts := defaultQualityPrompts()
+    if stored.Valid {
+        if err := json.Unmarshal([]byte(stored.String), &prompts); err != nil {
+            return defaultQualityPrompts()
+        }
+    }
+    return prompts
+}
+
+// missingInfoText is the reply asking author for the details they left out.
+func missingInfoText(author string, missing []string) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "<@%s> Thanks for reaching out! To help us look into this, could you add:", author)
+    for _, known := range missingInfoRequests {
+        for _, kind := range missing {
+            if kind == known.kind {
+                b.WriteString("\n• " + known.request)
+                break
+            }
+        }
+    }
+    return b.String()
+}
+
+// SetChannelQualityPrompts - Set whether the bot asks the authors of incomplete threads for the missing details
+func (c *Container) SetChannelQualityPrompts(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    prompts := defaultQualityPrompts()
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&prompts); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if prompts.Below <= 0 || prompts.Below > 1 {
+        return apierror.New(http.StatusBadRequest, "below must be greater than 0 and at most 1")
+    }
+
+    err := c.configs.SetChannelConfig(ctx.Request().Context(), channelID, ChannelConfig{QualityPrompts: prompts}, settingQualityPrompts)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.quality_prompts", channelID, "", map[string]interface{}{
+        "quality_prompts": prompts,
+    })
+
+    return ctx.JSON(http.StatusOK, prompts)
+}
+
+// RunQualityPrompts periodically asks the authors of new incomplete threads
+// for the details they left out, in channels that enabled it, until ctx is
+// cancelled. It does nothing without a bot token.
+func (c *Container) RunQualityPrompts(ctx context.Context) {
+    if !c.slack.Configured() {
+        return
+    }
+
+    ticker := time.NewTicker(qualityPromptInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.promptForMissingInfo(ctx); err != nil {
+            c.logger.Warnf("failed to ask for missing thread details: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (c *Container) promptForMissingInfo(ctx context.Context) error {
+    if !c.slackFeatureReady(slackFeatureReplies) {
+        return nil
+    }
+    configs, err := c.configs.ChannelConfigs(ctx)
+    if err != nil {
+        return err
+    }
+
+    since := time.Now().UTC().Add(-qualityPromptWindow)
+    for channelID, config := range configs {
+        if !config.QualityPrompts.Enabled {
+            continue
+        }
+        if err := c.promptChannelForMissingInfo(ctx, channelID, config.QualityPrompts, since); err != nil {
+            c.logger.Warnf("failed to ask for missing details in channel %s: %v", channelID, err)
+        }
+    }
+    return nil
+}
+
+// analyzedThread is a thread with the quality the AI pipeline found.
+type analyzedThread struct {
+    threadTS string
+    author   string
+    quality  threadQuality
+}
+
+func (c *Container) promptChannelForMissingInfo(ctx context.Context, channelID string, prompts QualityPrompts, since time.Time) error {
+    threads, err := c.qualityStore.UnpromptedThreads(ctx, channelID, since)
+    if err != nil {
+        return err
+    }
+
+    for _, t := range threads {
+        quality := t.quality
+        if quality.Score == nil || *quality.Score >= prompts.Below || len(quality.Missing) == 0 {
+            continue
+        }
+        // Recorded first so a failing post is not retried every interval
+        recorded, err := c.qualityStore.RecordQualityPrompt(ctx, channelID, t.threadTS, quality.Missing)
+        if err != nil {
+            return err
+        }
+        if !recorded {
+            continue
+        }
+        if err := c.slack.PostReply(ctx, channelID, t.threadTS, missingInfoText(t.author, quality.Missing)); err != nil {
+            c.logger.Warnf("failed to ask for missing details in %s/%s: %v", channelID, t.threadTS, err)
+            continue
+        }
+        c.audit(ctx, "system", "thread.quality_prompt", channelID, t.threadTS, map[string]interface{}{
+            "missing_info": quality.Missing,
+        })
+    }
+    return nil
+}
+
+func (s postgresStore) UnpromptedThreads(ctx context.Context, channelID string, since time.Time) ([]analyzedThread, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT t.thread_ts, t.user_id, t.ai_analysis_json
+        FROM threads t
+        WHERE t.channel_id = $2 AND t.status = 'open' AND t.created_at > $1 AND t.ai_analysis_json IS NOT NULL
+          AND NOT EXISTS (SELECT 1 FROM thread_quality_prompts q
+                          WHERE q.channel_id = t.channel_id AND q.thread_ts = t.thread_ts)
+    `, since, channelID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    threads := []analyzedThread{}
+    for rows.Next() {
+        var t analyzedThread
+        var analysis sql.NullString
+        if err := rows.Scan(&t.threadTS, &t.author, &analysis); err != nil {
+            continue
+        }
+        t.quality = parseThreadQuality(analysis)
+        threads = append(threads, t)
+    }
+    return threads, rows.Err()
+}
+
+func (s postgresStore) RecordQualityPrompt(ctx context.Context, channelID string, threadTS string, missing []string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+    encoded, _ := json.Marshal(missing)
+    result, err := db.ExecContext(ctx, `
+        INSERT INTO thread_quality_prompts (channel_id, thread_ts, missing_info, asked_at)
+        VALUES ($1, $2, $3, NOW())
+        ON CONFLICT (channel_id, thread_ts) DO NOTHING
+    `, channelID, threadTS, string(encoded))
+    if err != nil {
+        return false, err
+    }
+    n, _ := result.RowsAffected()
+    return n > 0, nil
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/analysis"
+    "dashboard/apiserver/domain"
+)
+
+func TestPromptForMissingInfoAsksOnce(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "random"}})
+    store.SetChannelConfig(context.Background(), "C1", ChannelConfig{QualityPrompts: QualityPrompts{Enabled: true, Below: 0.6}}, settingQualityPrompts)
+    now := time.Now()
+    low, high := 0.3, 0.9
+    for _, thread := range []struct {
+        channelID, ts string
+        score         *float64
+    }{
+        {"C1", "1700000000.000100", &low},
+        {"C1", "1700000000.000200", &high},
+        {"C2", "1700000000.000300", &low},
+    } {
+        store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: thread.channelID, ThreadTS: thread.ts, UserID: "U1", Status: statusOpen, CreatedAt: now, LatestReply: now}})
+        result := &analysis.Result{QualityScore: thread.score, MissingInfo: []string{missingLogs, "mood", missingVersion}}
+        if err := store.SaveAnalysis(context.Background(), thread.channelID, thread.ts, result, nil); err != nil {
+            t.Fatal(err)
+        }
+    }
+    slackClient := &MemorySlack{}
+    c := newTestContainer(t, store, slackClient)
+
+    for range 2 {
+        if err := c.promptForMissingInfo(context.Background()); err != nil {
+            t.Fatal(err)
+        }
+    }
+    messages := slackClient.Messages()
+    if len(messages) != 1 || messages[0].ThreadTS != "1700000000.000100" {
+        t.Fatalf("posted %+v, want one prompt in the incomplete thread", messages)
+    }
+    if want := missingInfoText("U1", []string{missingLogs, missingVersion}); messages[0].Text != want {
+        t.Fatalf("asked %q, want %q", messages[0].Text, want)
+    }
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "os"
+    "strconv"
+    "time"
+)
+
+// Defaults of the quickstart mode, applied to settings left unset.
+const (
+    quickstartRemindAfter  = "48h"
+    quickstartBackfillDays = 30
+)
+
+// ApplyQuickstartDefaults turns on what the quickstart mode runs by default,
+// keeping any setting from the environment. It must be called before
+// NewContainer.
+func ApplyQuickstartDefaults() {
+    if _, ok := os.LookupEnv(remindAfterEnv); !ok {
+        os.Setenv(remindAfterEnv, quickstartRemindAfter)
+    }
+}
+
+// Quickstart tracks every channel the bot is a member of without the
+// collector: it registers the channels in the channels table created by the
+// migrations and backfills the recent history
+// of the new ones. Org-wide installs of an Enterprise Grid track the
+// channels of every workspace they cover, in the database of each.
+func (c *Container) Quickstart(ctx context.Context) error {
+    if !c.slack.Configured() {
+        return fmt.Errorf("quickstart needs the Slack bot token in %s", slackBotTokenEnv)
+    }
+    if err := c.discoverGrid(ctx); err != nil {
+        c.logger.Warnf("quickstart: failed to discover the workspaces of the Slack install: %v", err)
+    }
+    c.quickstart.Store(true)
+    if _, err := c.auditSlackScopes(ctx); err != nil {
+        c.logger.Warnf("quickstart: failed to audit the scopes of the Slack bot token: %v", err)
+    } else if !c.slackFeatureReady(slackFeatureQuickstart) {
+        return fmt.Errorf("quickstart needs the channels:read and groups:read scopes of the Slack bot token")
+    }
+
+    added := []string{}
+    tracked := 0
+    for _, team := range c.gridTeams() {
+        teamAdded, teamTracked, err := c.quickstartChannels(ctx, team)
+        if err != nil {
+            return err
+        }
+        added = append(added, teamAdded...)
+        tracked += teamTracked
+    }
+    if tracked == 0 {
+        c.logger.Warnf("quickstart: the bot is not a member of any channel yet, invite it with /invite and restart")
+        return nil
+    }
+
+    if len(added) > 0 {
+        oldest := strconv.FormatInt(time.Now().AddDate(0, 0, -quickstartBackfillDays).Unix(), 10)
+        // Backfills outlive the startup that scheduled them
+        if _, err := c.backfiller.Schedule(context.Background(), added, oldest); err != nil {
+            return err
+        }
+    }
+    c.logger.Infof("quickstart: tracking %d channels, backfilling the last %d days of %d new ones",
+        tracked, quickstartBackfillDays, len(added))
+    return nil
+}
+
+// trackChannel registers a channel in the channels table, reporting
+// whether it was not tracked before.
+func trackChannel(ctx context.Context, db *sql.DB, channelID string, channelName string) (bool, error) {
+    result, err := db.ExecContext(ctx, `
+        INSERT INTO channels (channel_id, channel_name, table_name)
+        VALUES ($1, $2, 'threads')
+        ON CONFLICT (channel_id) DO NOTHING
+    `, channelID, channelName)
+    if err != nil {
+        return false, err
+    }
+    n, _ := result.RowsAffected()
+    return n > 0, nil
+}
+
+// quickstartChannels tracks the channels of one workspace, empty for the
+// workspace of the token, returning those added and how many are tracked.
+func (c *Container) quickstartChannels(ctx context.Context, team string) ([]string, int, error) {
+    wsCtx := withWorkspace(ctx, team)
+    added := []string{}
+    tracked := 0
+    cursor := ""
+    for {
+        page, err := c.slack.UsersConversations(ctx, team, cursor, 200)
+        if err != nil {
+            return nil, 0, err
+        }
+        for _, ch := range page.Channels {
+            isNew, err := c.channels.TrackChannel(wsCtx, ch.ID, ch.Name)
+            if err != nil {
+                return nil, 0, err
+            }
+            if isNew {
+                added = append(added, ch.ID)
+            }
+            tracked++
+        }
+        cursor = page.ResponseMetadata.NextCursor
+        if cursor == "" {
+            break
+        }
+    }
+    return added, tracked, nil
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+    "math"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+    "golang.org/x/time/rate"
+)
+
+// rateLimitIdle is how long the bucket of a quiet client is kept, long
+// enough for it to have refilled.
+const rateLimitIdle = 10 * time.Minute
+
+// rateBucket paces the reads or writes of a client.
+type rateBucket struct {
+    limiter  *rate.Limiter
+    lastSeen time.Time
+}
+
+// rateLimiter gives every client a budget of reads and one of writes per
+// minute, each refilling continuously. Bursts may spend up to ten seconds
+// of budget at once. A zero budget is unlimited.
+type rateLimiter struct {
+    reads  int
+    writes int
+
+    mu      sync.Mutex
+    buckets map[string]*rateBucket
+}
+
+func newRateLimiter(reads int, writes int) *rateLimiter {
+    return &rateLimiter{reads: reads, writes: writes, buckets: make(map[string]*rateBucket)}
+}
+
+// reserve takes a request of client from its budget and returns how long
+// the client must wait when the budget is spent.
+func (l *rateLimiter) reserve(client string, write bool) time.Duration {
+    perMinute, kind := l.reads, "read"
+    if write {
+        perMinute, kind = l.writes, "write"
+    }
+    if perMinute <= 0 {
+        return 0
+    }
+
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    key := kind + "|" + client
+    bucket, ok := l.buckets[key]
+    if !ok {
+        burst := perMinute / 6
+        if burst < 1 {
+            burst = 1
+        }
+        bucket = &rateBucket{limiter: rate.NewLimiter(rate.Limit(float64(perMinute)/60), burst)}
+        l.buckets[key] = bucket
+    }
+    now := time.Now()
+    bucket.lastSeen = now
+    reservation := bucket.limiter.ReserveN(now, 1)
+    if delay := reservation.DelayFrom(now); delay > 0 {
+        reservation.CancelAt(now)
+        return delay
+    }
+    return 0
+}
+
+// prune forgets the buckets of clients quiet for rateLimitIdle.
+func (l *rateLimiter) prune() {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    for key, bucket := range l.buckets {
+        if time.Since(bucket.lastSeen) > rateLimitIdle {
+            delete(l.buckets, key)
+        }
+    }
+}
+
+// rateLimitClient returns who a request is paced as: its API token or key, else
+// its signed in user, else its address. The address is never taken from a
+// header the client set, only from the server's trusted extractor or else
+// the connection itself.
+func rateLimitClient(ctx echo.Context) string {
+    if principal, ok := auth.PrincipalFrom(ctx); ok {
+        if credential := principal.Credential(); credential != "" {
+            return credential
+        }
+        return "user:" + principal.UserID
+    }
+    if ctx.Echo().IPExtractor == nil {
+        return "ip:" + echo.ExtractIPDirect()(ctx.Request())
+    }
+    return "ip:" + ctx.RealIP()
+}
+
+// RateLimit answers requests of clients past their read or write budget
+// with a 429 telling when to retry, protecting the shared database from a
+// single busy client. Reads sent as POST count as reads.
+func (c *Container) RateLimit(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+        write := false
+        switch ctx.Request().Method {
+        case http.MethodGet, http.MethodHead, http.MethodOptions:
+        default:
+            // Of the routes kept in read-only mode, only the switch writes
+            write = !readOnlyAllowed[ctx.Path()] || ctx.Path() == "/api/admin/read-only"
+        }
+        retryAfter := c.rateLimits.reserve(rateLimitClient(ctx), write)
+        if retryAfter <= 0 {
+            return next(ctx)
+        }
+        seconds := int(math.Ceil(retryAfter.Seconds()))
+        ctx.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(seconds))
+        return apierror.Newf(http.StatusTooManyRequests, "Too many requests, try again in %d seconds", seconds)
+    }
+}
+
+// PruneRateLimits forgets the budgets of clients that went quiet.
+func (c *Container) PruneRateLimits() {
+    c.rateLimits.prune()
+}
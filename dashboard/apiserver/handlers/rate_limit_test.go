@@ -0,0 +1,113 @@
+package handlers
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/logger"
+
+    "github.com/labstack/echo/v4"
+)
+
+func TestRateLimiterBudgets(t *testing.T) {
+    limiter := newRateLimiter(60, 6)
+
+    // A budget of 60 reads a minute bursts up to ten
+    for i := 0; i < 10; i++ {
+        if wait := limiter.reserve("client", false); wait > 0 {
+            t.Fatalf("read %d waited %s", i+1, wait)
+        }
+    }
+    if wait := limiter.reserve("client", false); wait <= 0 {
+        t.Fatal("read past the burst was let through")
+    }
+
+    // Writes are paced apart from reads, and clients apart from each other
+    if wait := limiter.reserve("client", true); wait > 0 {
+        t.Fatalf("first write waited %s", wait)
+    }
+    if wait := limiter.reserve("client", true); wait <= 0 {
+        t.Fatal("write past the burst was let through")
+    }
+    if wait := limiter.reserve("other", false); wait > 0 {
+        t.Fatalf("other client waited %s", wait)
+    }
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+    limiter := newRateLimiter(0, 0)
+    for i := 0; i < 1000; i++ {
+        if wait := limiter.reserve("client", i%2 == 0); wait > 0 {
+            t.Fatalf("request %d waited %s", i+1, wait)
+        }
+    }
+}
+
+func TestRateLimitClient(t *testing.T) {
+    tests := []struct {
+        name      string
+        extractor echo.IPExtractor
+        principal *auth.Principal
+        want      string
+    }{
+        {"token", nil, &auth.Principal{UserID: "U1", TokenID: 7}, "token:7"},
+        {"key", nil, &auth.Principal{KeyID: 3}, "key:3"},
+        {"session", nil, &auth.Principal{UserID: "U1"}, "user:U1"},
+        {"no extractor ignores headers", nil, nil, "ip:203.0.113.7"},
+        {"direct extractor ignores headers", echo.ExtractIPDirect(), nil, "ip:203.0.113.7"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            e := echo.New()
+            e.IPExtractor = tt.extractor
+            req := httptest.NewRequest(http.MethodGet, "/api/threads", nil)
+            req.RemoteAddr = "203.0.113.7:5000"
+            req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.1")
+            req.Header.Set(echo.HeaderXRealIP, "198.51.100.2")
+            ctx := e.NewContext(req, httptest.NewRecorder())
+            if tt.principal != nil {
+                auth.SetPrincipal(ctx, tt.principal)
+            }
+            if got := rateLimitClient(ctx); got != tt.want {
+                t.Fatalf("got %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+    log, err := logger.NewLogger(logger.Error)
+    if err != nil {
+        t.Fatal(err)
+    }
+    c := &Container{rateLimits: newRateLimiter(6, 6)}
+    e := echo.New()
+    e.HTTPErrorHandler = apierror.Handler(log)
+    e.IPExtractor = echo.ExtractIPDirect()
+    e.GET("/api/threads", func(ctx echo.Context) error {
+        return ctx.NoContent(http.StatusOK)
+    }, c.RateLimit)
+
+    request := func(forwardedFor string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(http.MethodGet, "/api/threads", nil)
+        req.RemoteAddr = "203.0.113.7:5000"
+        req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+        rec := httptest.NewRecorder()
+        e.ServeHTTP(rec, req)
+        return rec
+    }
+    if rec := request("198.51.100.1"); rec.Code != http.StatusOK {
+        t.Fatalf("first request got status %d", rec.Code)
+    }
+    // A new forwarded address does not buy a new budget
+    rec := request("198.51.100.2")
+    if rec.Code != http.StatusTooManyRequests {
+        t.Fatalf("got status %d, want %d", rec.Code, http.StatusTooManyRequests)
+    }
+    if rec.Header().Get(echo.HeaderRetryAfter) == "" {
+        t.Fatal("no Retry-After header")
+    }
+}
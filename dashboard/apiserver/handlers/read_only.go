@@ -0,0 +1,70 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+// readOnlyMessage is the answer to requests refused in read-only mode.
+const readOnlyMessage = "The dashboard is in read-only mode, changes are disabled until an admin turns it off"
+
+// readOnlyAllowed are the routes that keep working in read-only mode
+// although their method could change something: reads sent as POST and the
+// switch itself.
+var readOnlyAllowed = map[string]bool{
+    "/api/threads/batch-get": true,
+    "/api/digest/preview":    true,
+    "/api/admin/read-only":   true,
+}
+
+// EnforceReadOnly refuses requests that could change anything with a 403
+// while the dashboard is in read-only mode. Reads keep working.
+func (c *Container) EnforceReadOnly(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+        if !c.readOnly.Load() || readOnlyAllowed[ctx.Path()] {
+            return next(ctx)
+        }
+        switch ctx.Request().Method {
+        case http.MethodGet, http.MethodHead, http.MethodOptions:
+            return next(ctx)
+        }
+        return apierror.New(http.StatusForbidden, readOnlyMessage)
+    }
+}
+
+// ReadOnlyRequest is the body accepted by SetReadOnly
+type ReadOnlyRequest struct {
+    ReadOnly *bool `json:"read_only"`
+}
+
+// GetReadOnly - Get whether the dashboard is in read-only mode
+func (c *Container) GetReadOnly(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, map[string]bool{
+        "read_only": c.readOnly.Load(),
+    })
+}
+
+// SetReadOnly - Turn read-only mode on or off until the server restarts
+func (c *Container) SetReadOnly(ctx echo.Context) error {
+    var req ReadOnlyRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil || req.ReadOnly == nil {
+        return apierror.New(http.StatusBadRequest, "read_only is required")
+    }
+
+    actor := actorFrom(ctx)
+    if was := c.readOnly.Swap(*req.ReadOnly); was != *req.ReadOnly {
+        action := "read_only.disable"
+        if *req.ReadOnly {
+            action = "read_only.enable"
+        }
+        c.logger.Warnf("%s by %s", action, actor)
+        c.audit(sharedContext(ctx.Request().Context()), actor, action, "", "", nil)
+    }
+    return ctx.JSON(http.StatusOK, map[string]bool{
+        "read_only": *req.ReadOnly,
+    })
+}
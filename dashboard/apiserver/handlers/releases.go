@@ -0,0 +1,369 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/github"
+    "dashboard/apiserver/notify"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// statusWaitingRelease is the status of threads parked until a release
+// ships. They are neither reminded about nor heat up.
+const statusWaitingRelease = domain.StatusWaitingRelease
+
+const releaseCheckInterval = 15 * time.Minute
+
+// pendingRelease is a release threads wait on that did not ship yet.
+type pendingRelease struct{ repo, tag string }
+
+// WaitingReleaseRequest is the body accepted by SetThreadWaitingRelease
+type WaitingReleaseRequest struct {
+    Repo string `json:"repo"`
+    Tag  string `json:"tag"`
+}
+
+// SetThreadWaitingRelease - Park a thread until a GitHub release of a repository ships
+func (c *Container) SetThreadWaitingRelease(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req WaitingReleaseRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    req.Repo = strings.TrimSpace(req.Repo)
+    req.Tag = strings.TrimSpace(req.Tag)
+    if !github.ValidRepo(req.Repo) || req.Tag == "" {
+        return apierror.New(http.StatusBadRequest, "repo must be owner/name and tag is required")
+    }
+
+    reqCtx := ctx.Request().Context()
+    err := c.channels.Tracked(reqCtx, channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    actor := actorFrom(ctx)
+    err = c.releases.WaitOnRelease(reqCtx, channelID, threadTS, req.Repo, req.Tag, actor)
+    if err == errThreadNotOpen {
+        return apierror.New(http.StatusConflict, "Only open threads can wait on a release")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to link release")
+    }
+
+    c.audit(ctx.Request().Context(), actor, "thread.waiting_release", channelID, threadTS, map[string]interface{}{
+        "repo": req.Repo,
+        "tag":  req.Tag,
+    })
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "channel_id": channelID,
+        "thread_ts":  threadTS,
+        "status":     statusWaitingRelease,
+        "repo":       req.Repo,
+        "tag":        req.Tag,
+    })
+}
+
+// ClearThreadWaitingRelease - Stop waiting on a release and reopen the thread
+func (c *Container) ClearThreadWaitingRelease(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    reqCtx := ctx.Request().Context()
+    err := c.channels.Tracked(reqCtx, channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    waiting, err := c.releases.StopWaitingOnRelease(reqCtx, channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to unlink release")
+    }
+    if !waiting {
+        return apierror.New(http.StatusNotFound, "Thread is not waiting on a release")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "thread.waiting_release_cleared", channelID, threadTS, nil)
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// RunReleaseWatch periodically checks the releases threads wait on, until
+// ctx is cancelled.
+func (c *Container) RunReleaseWatch(ctx context.Context) {
+    ticker := time.NewTicker(releaseCheckInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.checkReleases(ctx); err != nil {
+            c.logger.Warnf("failed to check releases: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// checkReleases reopens the threads whose release shipped for
+// verification and notifies their stakeholders.
+func (c *Container) checkReleases(ctx context.Context) error {
+    pending, err := c.releases.PendingReleases(ctx)
+    if err != nil {
+        return err
+    }
+
+    for _, p := range pending {
+        release, err := c.github.ReleaseByTag(ctx, p.repo, p.tag)
+        if err == github.ErrNotFound {
+            continue
+        }
+        if err != nil {
+            c.logger.Warnf("failed to look up release %s of %s: %v", p.tag, p.repo, err)
+            continue
+        }
+        if err := c.releaseShipped(ctx, p.repo, release); err != nil {
+            c.logger.Warnf("failed to reopen threads waiting on %s %s: %v", p.repo, p.tag, err)
+        }
+    }
+    return nil
+}
+
+// githubReleaseEvent is the payload of GitHub release webhooks.
+type githubReleaseEvent struct {
+    Action     string         `json:"action"`
+    Release    github.Release `json:"release"`
+    Repository struct {
+        FullName string `json:"full_name"`
+    } `json:"repository"`
+}
+
+// HandleGitHubWebhook - Reopen the threads waiting on a release as soon as GitHub publishes it
+func (c *Container) HandleGitHubWebhook(ctx echo.Context) error {
+    // Other events, e.g. the ping sent when the webhook is created, are
+    // acknowledged
+    if ctx.Request().Header.Get("X-GitHub-Event") != "release" {
+        return ctx.NoContent(http.StatusOK)
+    }
+    var event githubReleaseEvent
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&event); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid webhook payload")
+    }
+    repo := event.Repository.FullName
+    if event.Action != "published" || event.Release.Draft || event.Release.TagName == "" || !github.ValidRepo(repo) {
+        return ctx.NoContent(http.StatusOK)
+    }
+
+    // GitHub does not know the workspace, so every database is asked for
+    // the threads waiting on the release. The periodic check catches up
+    // with those failing here.
+    for _, wsCtx := range c.WorkspaceContexts(ctx.Request().Context()) {
+        if err := c.releaseShipped(wsCtx, repo, &event.Release); err != nil {
+            c.logger.Errorf("failed to reopen threads waiting on %s %s: %v", repo, event.Release.TagName, err)
+            return apierror.New(http.StatusInternalServerError, "Failed to reopen threads")
+        }
+    }
+    return ctx.NoContent(http.StatusOK)
+}
+
+// releaseShipped reopens the threads waiting on a release that shipped and
+// notifies their stakeholders.
+func (c *Container) releaseShipped(ctx context.Context, repo string, release *github.Release) error {
+    threads, err := c.releases.ShipRelease(ctx, repo, release.TagName, release.HTMLURL)
+    if err != nil {
+        return err
+    }
+    for _, t := range threads {
+        c.audit(ctx, "system", "thread.release_shipped", t.ChannelID, t.ThreadTS, map[string]interface{}{
+            "repo": repo,
+            "tag":  release.TagName,
+            "url":  release.HTMLURL,
+        })
+        c.notifyReleaseShipped(ctx, t.ChannelID, t.ThreadTS, repo, release)
+    }
+    if len(threads) > 0 {
+        c.logger.Infof("release %s of %s shipped, reopened %d threads", release.TagName, repo, len(threads))
+    }
+    return nil
+}
+
+// notifyReleaseShipped announces the release in the Slack thread and
+// notifies the owners of the thread.
+func (c *Container) notifyReleaseShipped(ctx context.Context, channelID string, threadTS string, repo string, release *github.Release) {
+    if c.slackFeatureReady(slackFeatureReplies) {
+        text := fmt.Sprintf("<%s|%s %s> shipped. This thread was reopened to verify the fix.", release.HTMLURL, repo, release.TagName)
+        if err := c.slack.PostReply(ctx, channelID, threadTS, text); err != nil {
+            c.logger.Warnf("failed to announce release in %s/%s: %v", channelID, threadTS, err)
+        }
+    }
+    if !c.notifier.Enabled(notify.EventRelease) {
+        return
+    }
+
+    ref := ThreadRef{ChannelID: channelID, ThreadTS: threadTS}
+    found, err := c.threadLists.ThreadsByRef(ctx, []ThreadRef{ref})
+    thread, ok := found[ref]
+    if err != nil || !ok {
+        return
+    }
+    recipients := []string{}
+    json.Unmarshal([]byte(thread.AIStakeholders), &recipients)
+    if thread.ClaimedBy != nil {
+        recipients = append(recipients, *thread.ClaimedBy)
+    }
+
+    seen := make(map[string]bool, len(recipients))
+    dm := fmt.Sprintf("<%s|%s %s> shipped. Please verify <%s|this thread> and resolve it.",
+        release.HTMLURL, repo, release.TagName, slack.Permalink(channelID, threadTS))
+    for _, recipient := range recipients {
+        if seen[recipient] {
+            continue
+        }
+        seen[recipient] = true
+        err := c.notifier.Notify(ctx, notify.Notification{
+            Event:     notify.EventRelease,
+            Recipient: recipient,
+            Text:      dm,
+            ChannelID: channelID,
+            ThreadTS:  threadTS,
+        })
+        if err != nil {
+            c.logger.Warnf("failed to notify %s of release %s: %v", recipient, release.TagName, err)
+        }
+    }
+}
+
+func (s postgresStore) WaitOnRelease(ctx context.Context, channelID, threadTS, repo, tag, actor string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    result, err := db.ExecContext(ctx, `
+        UPDATE threads SET status = $1, updated_at = NOW()
+        WHERE thread_ts = $2 AND channel_id = $3 AND status IN ('open', $1)
+    `, statusWaitingRelease, threadTS, channelID)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return errThreadNotOpen
+    }
+
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO thread_releases (channel_id, thread_ts, repo, tag, set_by, created_at)
+        VALUES ($1, $2, $3, $4, $5, NOW())
+        ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+            repo = EXCLUDED.repo,
+            tag = EXCLUDED.tag,
+            set_by = EXCLUDED.set_by,
+            created_at = EXCLUDED.created_at,
+            released_at = NULL,
+            release_url = NULL
+    `, channelID, threadTS, repo, tag, actor)
+    return err
+}
+
+func (s postgresStore) StopWaitingOnRelease(ctx context.Context, channelID, threadTS string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+    result, err := db.ExecContext(ctx, `
+        DELETE FROM thread_releases
+        WHERE channel_id = $1 AND thread_ts = $2 AND released_at IS NULL
+    `, channelID, threadTS)
+    if err != nil {
+        return false, err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return false, nil
+    }
+    return true, reopenThread(ctx, db, channelID, threadTS)
+}
+
+func (s postgresStore) PendingReleases(ctx context.Context) ([]pendingRelease, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT DISTINCT repo, tag FROM thread_releases WHERE released_at IS NULL
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    pending := []pendingRelease{}
+    for rows.Next() {
+        var p pendingRelease
+        if err := rows.Scan(&p.repo, &p.tag); err == nil {
+            pending = append(pending, p)
+        }
+    }
+    return pending, rows.Err()
+}
+
+func (s postgresStore) ShipRelease(ctx context.Context, repo, tag, url string) ([]ThreadRef, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        UPDATE thread_releases SET released_at = NOW(), release_url = $3
+        WHERE LOWER(repo) = LOWER($1) AND tag = $2 AND released_at IS NULL
+        RETURNING channel_id, thread_ts
+    `, repo, tag, url)
+    if err != nil {
+        return nil, err
+    }
+    threads := []ThreadRef{}
+    for rows.Next() {
+        var t ThreadRef
+        if err := rows.Scan(&t.ChannelID, &t.ThreadTS); err == nil {
+            threads = append(threads, t)
+        }
+    }
+    rows.Close()
+
+    reopened := []ThreadRef{}
+    for _, t := range threads {
+        if err := channelTracked(db, t.ChannelID); err != nil {
+            continue
+        }
+        if err := reopenThread(ctx, db, t.ChannelID, t.ThreadTS); err != nil {
+            s.c.logger.Warnf("failed to reopen thread %s/%s: %v", t.ChannelID, t.ThreadTS, err)
+            continue
+        }
+        reopened = append(reopened, t)
+    }
+    return reopened, nil
+}
+
+// reopenThread opens again a thread waiting on a release.
+func reopenThread(ctx context.Context, db *sql.DB, channelID string, threadTS string) error {
+    _, err := db.ExecContext(ctx, `
+        UPDATE threads SET status = 'open', updated_at = NOW()
+        WHERE thread_ts = $1 AND channel_id = $2 AND status = $3
+    `, threadTS, channelID, statusWaitingRelease)
+    return err
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/notify"
+
+    "github.com/labstack/echo/v4"
+)
+
+func TestReleaseShippedReopensWaitingThreads(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    now := time.Now()
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, LatestReply: now}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: statusResolved, LatestReply: now}})
+    slack := &MemorySlack{}
+    c := newTestContainer(t, store, slack)
+    c.notifier, _ = notify.NewRouter(c.logger, nil)
+    user := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    route := "/api/threads/:channel_id/:thread_ts/release"
+    body := `{"repo": "acme/app", "tag": "v1.2.0"}`
+    rec := serveAs(c, user, http.MethodPut, route, "/api/threads/C1/1700000000.000200/release", body, c.SetThreadWaitingRelease)
+    if rec.Code != http.StatusConflict {
+        t.Fatalf("a resolved thread got status %d", rec.Code)
+    }
+    rec = serveAs(c, user, http.MethodPut, route, "/api/threads/C1/1700000000.000100/release", body, c.SetThreadWaitingRelease)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+
+    e := echo.New()
+    req := httptest.NewRequest(http.MethodPost, "/api/webhooks/github", strings.NewReader(`{
+        "action": "published",
+        "release": {"tag_name": "v1.2.0", "html_url": "https://github.com/acme/app/releases/v1.2.0"},
+        "repository": {"full_name": "Acme/App"}
+    }`))
+    req.Header.Set("X-GitHub-Event", "release")
+    rec = httptest.NewRecorder()
+    if err := c.HandleGitHubWebhook(e.NewContext(req, rec)); err != nil || rec.Code != http.StatusOK {
+        t.Fatalf("got status %d, error %v", rec.Code, err)
+    }
+    if thread, _ := store.Thread(context.Background(), "C1", "1700000000.000100"); thread.Status != statusOpen {
+        t.Fatalf("got status %s after the release shipped", thread.Status)
+    }
+    if posted := slack.Messages(); len(posted) != 1 || !strings.Contains(posted[0].Text, "v1.2.0") {
+        t.Fatalf("got Slack messages %+v", posted)
+    }
+
+    rec = serveAs(c, user, http.MethodDelete, route, "/api/threads/C1/1700000000.000100/release", "", c.ClearThreadWaitingRelease)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("a thread whose release shipped got status %d", rec.Code)
+    }
+}
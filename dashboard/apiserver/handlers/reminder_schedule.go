@@ -0,0 +1,65 @@
+package handlers
+
+import (
+    "database/sql"
+    "encoding/json"
+    "net/http"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/reminders"
+
+    "github.com/labstack/echo/v4"
+)
+
+// threadRecipient stands for the thread itself in reminder_nudges when
+// reminders are posted as thread replies.
+const threadRecipient = "#thread"
+
+// parseReminderSchedule decodes a stored schedule, nil when the channel has
+// none.
+func parseReminderSchedule(stored sql.NullString) *reminders.Schedule {
+    if !stored.Valid {
+        return nil
+    }
+    var schedule reminders.Schedule
+    if err := json.Unmarshal([]byte(stored.String), &schedule); err != nil || schedule.Validate() != nil {
+        return nil
+    }
+    return &schedule
+}
+
+// SetChannelReminderSchedule - Set or, with an empty remind_after, remove the reminder schedule and quiet hours of a channel
+func (c *Container) SetChannelReminderSchedule(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var schedule reminders.Schedule
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&schedule); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if schedule.RemindAfter != "" {
+        if err := schedule.Validate(); err != nil {
+            return apierror.New(http.StatusBadRequest, err.Error())
+        }
+    }
+
+    var applied *reminders.Schedule
+    if schedule.RemindAfter != "" {
+        applied = &schedule
+    }
+    err := c.configs.SetChannelConfig(ctx.Request().Context(), channelID, ChannelConfig{ReminderSchedule: applied}, settingReminderSchedule)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.reminder_schedule", channelID, "", map[string]interface{}{
+        "reminder_schedule": applied,
+    })
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "channel_id":        channelID,
+        "reminder_schedule": applied,
+    })
+}
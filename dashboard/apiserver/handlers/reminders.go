@@ -0,0 +1,402 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/events"
+    "dashboard/apiserver/notify"
+    "dashboard/apiserver/reminders"
+    "dashboard/apiserver/slack"
+)
+
+const reminderScanInterval = time.Minute
+
+// RunReminders periodically sends each owner of idle threads one direct
+// message listing all of them. Threads are owned by whoever claimed them,
+// or else by their stakeholders, and watchers are reminded as well.
+// Usergroups among them are mentioned in threads and their members sent
+// direct messages. In
+// channels with a first-responder rotation, unacknowledged threads go to the
+// responder on shift first. Channel groups may set their own threshold, and
+// channels a schedule with its own threshold, quiet hours and whether
+// reminders are sent directly or posted in the thread. It does nothing
+// when reminders are not delivered through any sink.
+func (c *Container) RunReminders(ctx context.Context) {
+    if !c.notifier.Enabled(notify.EventReminder) {
+        return
+    }
+
+    // Pending batches are still delivered once ctx is cancelled
+    batcher := reminders.NewBatcher(context.WithoutCancel(ctx), c.logger, c.reminderWindow, c.sendReminders)
+    defer batcher.Flush()
+
+    // Threads replied to while their reminders wait are no longer idle, in
+    // the batch of whichever instance holds them
+    workspace := workspaceFrom(ctx)
+    unsubscribe, err := c.bus.Subscribe(events.TopicThreadMessage, "reminders", func(ctx context.Context, event events.Event) {
+        if event.Workspace == workspace {
+            batcher.Drop(reminders.Nudge{ChannelID: event.ChannelID, ThreadTS: event.ThreadTS}.Key())
+        }
+    }, events.PerInstance())
+    if err != nil {
+        c.logger.Warnf("failed to subscribe reminders to thread messages: %v", err)
+    } else {
+        defer unsubscribe()
+    }
+
+    ticker := time.NewTicker(reminderScanInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.queueReminders(ctx, batcher); err != nil {
+            c.logger.Warnf("failed to queue reminders: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (c *Container) queueReminders(ctx context.Context, batcher *reminders.Batcher) error {
+    groups, err := c.groups.ChannelGroups(ctx)
+    if err != nil {
+        return err
+    }
+    groupThresholds := groupRemindAfter(groups)
+    configs, err := c.configs.ChannelConfigs(ctx)
+    if err != nil {
+        return err
+    }
+    longest := c.remindAfter
+    for _, threshold := range groupThresholds {
+        if threshold > longest {
+            longest = threshold
+        }
+    }
+    for _, config := range configs {
+        if config.ReminderSchedule == nil {
+            continue
+        }
+        if threshold := config.ReminderSchedule.Threshold(); threshold > longest {
+            longest = threshold
+        }
+    }
+    if longest <= 0 {
+        return nil
+    }
+    now := time.Now().UTC()
+
+    // A recipient is nudged about a thread at most once per threshold
+    nudged, err := c.reminderStore.Nudges(ctx, now.Add(-longest))
+    if err != nil {
+        return err
+    }
+    channels, err := c.channels.Channels(ctx)
+    if err != nil {
+        return err
+    }
+    usergroups, err := c.users.Usergroups(ctx)
+    if err != nil {
+        return err
+    }
+    members := make(map[string][]string, len(usergroups))
+    for _, group := range usergroups {
+        members[group.ID] = group.Members
+    }
+
+    queued := 0
+    for _, ch := range channels {
+        config := configs[ch.ChannelID]
+        remindAfter := c.remindAfter
+        if threshold, ok := groupThresholds[ch.ChannelID]; ok {
+            remindAfter = threshold
+        }
+        schedule := config.ReminderSchedule
+        if schedule != nil {
+            remindAfter = schedule.Threshold()
+        }
+        // Reminders held back by quiet hours go out once they end
+        if remindAfter <= 0 || config.Muted || (schedule != nil && schedule.Quiet(now)) {
+            continue
+        }
+        cutoff := now.Add(-remindAfter)
+
+        responders := config.DefaultAssignees
+        if rotation := config.ResponderRotation; rotation != nil {
+            responders = []string{rotation.Current(now).UserID}
+        }
+        threads, err := c.reminderStore.IdleThreads(ctx, ch.ChannelID, cutoff, config.SLAHours)
+        if err != nil {
+            c.logger.Warnf("failed to find idle threads of channel %s: %v", ch.ChannelID, err)
+            continue
+        }
+        nudges := idleThreadNudges(ch.ChannelID, threads, now, cutoff.Add(-remindAfter), responders, config.SLAHours)
+        byThread := make(map[string][]reminders.Nudge)
+        threadOrder := []string{}
+        for _, nudge := range expandUsergroupNudges(nudges, members) {
+            if !ch.TextIndexing {
+                nudge.Title = ""
+            }
+            // Threads mention usergroups by their handle, their members
+            // get direct messages
+            if schedule != nil && schedule.InThread() && c.slackFeatureReady(slackFeatureReplies) && nudge.Usergroup == "" {
+                if _, ok := byThread[nudge.Key()]; !ok {
+                    threadOrder = append(threadOrder, nudge.Key())
+                }
+                byThread[nudge.Key()] = append(byThread[nudge.Key()], nudge)
+            }
+            if (schedule != nil && !schedule.Direct()) || slack.IsUsergroupID(nudge.Recipient) {
+                continue
+            }
+            if nudgedAt, ok := nudged[nudge.Recipient+"|"+nudge.Key()]; ok && nudgedAt.After(cutoff) {
+                continue
+            }
+            if batcher.Add(nudge) {
+                queued++
+            }
+        }
+
+        for _, key := range threadOrder {
+            if nudgedAt, ok := nudged[threadRecipient+"|"+key]; ok && nudgedAt.After(cutoff) {
+                continue
+            }
+            if err := c.remindInThread(ctx, byThread[key]); err != nil {
+                if ctx.Err() != nil {
+                    return ctx.Err()
+                }
+                c.logger.Warnf("failed to post reminder in thread %s: %v", key, err)
+                continue
+            }
+            queued++
+        }
+    }
+    if queued > 0 {
+        c.logger.Infof("queued %d thread reminders", queued)
+    }
+    return nil
+}
+
+// idleThread is an open thread reminders may be sent about. dueAt is the
+// due date set by hand, nil when none was.
+type idleThread struct {
+    threadTS     string
+    title        string
+    priority     string
+    stakeholders []string
+    watchers     []string
+    claimedBy    string
+    acknowledged bool
+    latestReply  time.Time
+    createdAt    time.Time
+    dueAt        *time.Time
+}
+
+// idleThreadNudges returns a nudge per owner of each idle thread of a
+// channel. Unacknowledged threads are owned by responders when set, and by
+// their stakeholders too once idle since escalateBefore. Threads without a
+// due date set by hand are due by the SLA of their priority.
+func idleThreadNudges(channelID string, threads []idleThread, now, escalateBefore time.Time, responders []string, slaHours SLAHours) []reminders.Nudge {
+    nudges := []reminders.Nudge{}
+    for _, thread := range threads {
+        slaPriority := thread.priority
+        if slaPriority == "" {
+            slaPriority = "none"
+        }
+        dueAt := thread.dueAt
+        if hours, ok := slaHours[slaPriority]; ok && dueAt == nil {
+            due := thread.createdAt.Add(time.Duration(hours * float64(time.Hour)))
+            dueAt = &due
+        }
+
+        recipients := []string{}
+        switch {
+        case thread.claimedBy != "":
+            recipients = append(recipients, thread.claimedBy)
+        case !thread.acknowledged && len(responders) > 0:
+            recipients = append(recipients, responders...)
+            if thread.latestReply.Before(escalateBefore) {
+                recipients = append(recipients, thread.stakeholders...)
+            }
+        default:
+            recipients = append(recipients, thread.stakeholders...)
+        }
+        recipients = append(recipients, thread.watchers...)
+        seen := make(map[string]bool, len(recipients))
+        for _, recipient := range recipients {
+            if seen[recipient] {
+                continue
+            }
+            seen[recipient] = true
+            nudges = append(nudges, reminders.Nudge{
+                Recipient:      recipient,
+                ChannelID:      channelID,
+                ThreadTS:       thread.threadTS,
+                Title:          thread.title,
+                Priority:       domain.Priority(thread.priority),
+                Idle:           now.Sub(thread.latestReply),
+                Overdue:        dueAt != nil && !now.Before(*dueAt),
+                Unacknowledged: !thread.acknowledged && thread.claimedBy == "",
+            })
+        }
+    }
+    return nudges
+}
+
+// expandUsergroupNudges adds a nudge for every member of the usergroups
+// among the recipients of nudges, each member being nudged once per thread.
+func expandUsergroupNudges(nudges []reminders.Nudge, groups map[string][]string) []reminders.Nudge {
+    expanded := make([]reminders.Nudge, 0, len(nudges))
+    seen := make(map[string]bool, len(nudges))
+    for _, nudge := range nudges {
+        seen[nudge.Recipient+"|"+nudge.Key()] = true
+    }
+    for _, nudge := range nudges {
+        expanded = append(expanded, nudge)
+        if !slack.IsUsergroupID(nudge.Recipient) {
+            continue
+        }
+        for _, member := range groups[nudge.Recipient] {
+            if seen[member+"|"+nudge.Key()] {
+                continue
+            }
+            seen[member+"|"+nudge.Key()] = true
+            memberNudge := nudge
+            memberNudge.Recipient, memberNudge.Usergroup = member, nudge.Recipient
+            expanded = append(expanded, memberNudge)
+        }
+    }
+    return expanded
+}
+
+// sendReminders delivers a batch of nudges as one notification.
+func (c *Container) sendReminders(ctx context.Context, recipient string, nudges []reminders.Nudge) error {
+    text, blocks := reminders.Message(nudges)
+    err := c.notifier.Notify(ctx, notify.Notification{
+        Event:     notify.EventReminder,
+        Recipient: recipient,
+        Text:      text,
+        Blocks:    blocks,
+        Body:      reminders.Summary(nudges),
+    })
+    if err != nil {
+        return err
+    }
+
+    for _, nudge := range nudges {
+        if err := c.reminderStore.RecordNudge(ctx, recipient, nudge.ChannelID, nudge.ThreadTS); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// remindInThread replies in an idle thread, mentioning the recipients of
+// its nudges.
+func (c *Container) remindInThread(ctx context.Context, nudges []reminders.Nudge) error {
+    nudge := nudges[0]
+    if err := c.slack.PostReply(ctx, nudge.ChannelID, nudge.ThreadTS, reminders.ThreadMessage(nudges)); err != nil {
+        return err
+    }
+    return c.reminderStore.RecordNudge(ctx, threadRecipient, nudge.ChannelID, nudge.ThreadTS)
+}
+
+func (s postgresStore) IdleThreads(ctx context.Context, channelID string, cutoff time.Time, slaHours SLAHours) ([]idleThread, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    now := time.Now().UTC()
+    args := []interface{}{cutoff, now, channelID}
+    due := ""
+    for priority, hours := range slaHours {
+        args = append(args, priority, now.Add(-time.Duration(hours*float64(time.Hour))))
+        due += fmt.Sprintf(" OR (s.due_at IS NULL AND COALESCE("+manualPriorityColumn+", t.ai_priority, 'none') = $%d AND t.created_at <= $%d)", len(args)-1, len(args))
+    }
+    rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+        SELECT t.thread_ts, COALESCE(t.ai_thread_name, ''), COALESCE(`+manualPriorityColumn+`, t.ai_priority, ''),
+               t.ai_stakeholders, t.latest_reply, t.created_at, tc.user_id,
+               (SELECT string_agg(w.user_id, ',') FROM thread_watchers w
+                WHERE w.channel_id = t.channel_id AND w.thread_ts = t.thread_ts),
+               s.due_at,
+               EXISTS (SELECT 1 FROM thread_acks a
+                WHERE a.channel_id = t.channel_id AND a.thread_ts = t.thread_ts)
+        FROM threads t
+        LEFT JOIN thread_claims tc ON tc.channel_id = t.channel_id AND tc.thread_ts = t.thread_ts
+        LEFT JOIN thread_sla s ON s.channel_id = t.channel_id AND s.thread_ts = t.thread_ts
+        WHERE t.channel_id = $3 AND t.status = 'open' AND (t.latest_reply < $1 OR s.due_at <= $2%s)
+    `, due), args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    threads := []idleThread{}
+    for rows.Next() {
+        var thread idleThread
+        var stakeholders sql.NullString
+        var claimedBy, watchers sql.NullString
+        var dueAt sql.NullTime
+        err := rows.Scan(&thread.threadTS, &thread.title, &thread.priority, &stakeholders, &thread.latestReply,
+            &thread.createdAt, &claimedBy, &watchers, &dueAt, &thread.acknowledged)
+        if err != nil {
+            continue
+        }
+        if stakeholders.Valid {
+            json.Unmarshal([]byte(stakeholders.String), &thread.stakeholders)
+        }
+        thread.claimedBy = claimedBy.String
+        if watchers.Valid {
+            thread.watchers = strings.Split(watchers.String, ",")
+        }
+        if dueAt.Valid {
+            thread.dueAt = &dueAt.Time
+        }
+        threads = append(threads, thread)
+    }
+    return threads, rows.Err()
+}
+
+func (s postgresStore) Nudges(ctx context.Context, since time.Time) (map[string]time.Time, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT user_id, channel_id, thread_ts, nudged_at FROM reminder_nudges WHERE nudged_at > $1
+    `, since)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    nudged := make(map[string]time.Time)
+    for rows.Next() {
+        var userID, channelID, threadTS string
+        var nudgedAt time.Time
+        if err := rows.Scan(&userID, &channelID, &threadTS, &nudgedAt); err == nil {
+            nudged[userID+"|"+channelID+":"+threadTS] = nudgedAt
+        }
+    }
+    return nudged, rows.Err()
+}
+
+func (s postgresStore) RecordNudge(ctx context.Context, recipient string, channelID string, threadTS string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO reminder_nudges (user_id, channel_id, thread_ts, nudged_at)
+        VALUES ($1, $2, $3, NOW())
+        ON CONFLICT (user_id, channel_id, thread_ts) DO UPDATE SET nudged_at = EXCLUDED.nudged_at
+    `, recipient, channelID, threadTS)
+    return err
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+    "context"
+    "sort"
+    "sync"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/reminders"
+)
+
+func TestQueueRemindersNudgesOwnersOnce(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "muted", TextIndexing: true}})
+    idle := time.Now().Add(-3 * time.Hour)
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", UserID: "U1", Status: statusOpen, CreatedAt: idle, LatestReply: idle}, Title: "Deploys time out"})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", UserID: "U1", Status: statusOpen, CreatedAt: time.Now(), LatestReply: time.Now()}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000300", UserID: "U1", Status: statusResolved, CreatedAt: idle, LatestReply: idle}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C2", ThreadTS: "1700000000.000400", UserID: "U1", Status: statusOpen, CreatedAt: idle, LatestReply: idle}})
+    store.AddWatcher("C1", "1700000000.000100", Watcher{UserID: "U2", Source: "reaction"})
+    store.SetChannelConfig(context.Background(), "C2", ChannelConfig{Muted: true}, settingMuted)
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.remindAfter = time.Hour
+
+    var mu sync.Mutex
+    sent := map[string][]reminders.Nudge{}
+    batcher := reminders.NewBatcher(context.Background(), c.logger, time.Hour, func(ctx context.Context, recipient string, nudges []reminders.Nudge) error {
+        mu.Lock()
+        defer mu.Unlock()
+        sent[recipient] = append(sent[recipient], nudges...)
+        for _, nudge := range nudges {
+            if err := store.RecordNudge(ctx, recipient, nudge.ChannelID, nudge.ThreadTS); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+
+    for range 2 {
+        if err := c.queueReminders(context.Background(), batcher); err != nil {
+            t.Fatal(err)
+        }
+        batcher.Flush()
+    }
+
+    mu.Lock()
+    defer mu.Unlock()
+    recipients := []string{}
+    for recipient, nudges := range sent {
+        recipients = append(recipients, recipient)
+        if len(nudges) != 1 || nudges[0].ThreadTS != "1700000000.000100" || nudges[0].Title != "Deploys time out" {
+            t.Fatalf("sent %s %+v", recipient, nudges)
+        }
+    }
+    sort.Strings(recipients)
+    if len(recipients) != 2 || recipients[0] != "U1" || recipients[1] != "U2" {
+        t.Fatalf("reminded %v, want the author and the watcher", recipients)
+    }
+
+    adoption, err := store.ReminderAdoption(context.Background(), time.Now().Add(-time.Hour))
+    if err != nil || adoption.Recipients != 2 || adoption.Threads != 1 {
+        t.Fatalf("got adoption %+v, %v", adoption, err)
+    }
+}
+
+func TestIdleThreadNudgesEscalateUnacknowledged(t *testing.T) {
+    now := time.Now()
+    threads := []idleThread{
+        {threadTS: "1", stakeholders: []string{"U1"}, latestReply: now.Add(-2 * time.Hour), createdAt: now.Add(-2 * time.Hour)},
+        {threadTS: "2", stakeholders: []string{"U1"}, latestReply: now.Add(-5 * time.Hour), createdAt: now.Add(-5 * time.Hour), priority: "high"},
+        {threadTS: "3", stakeholders: []string{"U1"}, claimedBy: "U3", latestReply: now.Add(-5 * time.Hour), createdAt: now.Add(-5 * time.Hour)},
+    }
+    nudges := idleThreadNudges("C1", threads, now, now.Add(-4*time.Hour), []string{"UR"}, SLAHours{"high": 4})
+
+    got := map[string][]string{}
+    overdue := map[string]bool{}
+    for _, nudge := range nudges {
+        got[nudge.ThreadTS] = append(got[nudge.ThreadTS], nudge.Recipient)
+        overdue[nudge.ThreadTS] = nudge.Overdue
+    }
+    if len(got["1"]) != 1 || got["1"][0] != "UR" {
+        t.Fatalf("thread 1 nudged %v, want the responder only", got["1"])
+    }
+    if len(got["2"]) != 2 || got["2"][1] != "U1" || !overdue["2"] {
+        t.Fatalf("thread 2 nudged %v, overdue %v, want the stakeholders too", got["2"], overdue["2"])
+    }
+    if len(got["3"]) != 1 || got["3"][0] != "U3" {
+        t.Fatalf("thread 3 nudged %v, want the claimer only", got["3"])
+    }
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+// maxReplyTemplateLength keeps rendered replies within Slack's message limit.
+const maxReplyTemplateLength = 3000
+
+// replyVariablePattern matches the {{variable}} placeholders of a template.
+var replyVariablePattern = regexp.MustCompile(`\{\{\s*([a-z][a-z0-9_]*)\s*\}\}`)
+
+// slackUserPattern matches actors that are Slack users, mentioned in
+// replies rather than named.
+var slackUserPattern = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+
+// builtinReplyVariables are filled in from the thread being replied to.
+var builtinReplyVariables = map[string]bool{
+    "author":      true,
+    "channel":     true,
+    "thread_link": true,
+    "title":       true,
+    "user":        true,
+}
+
+const replyTemplateColumns = "id, name, body, created_by, created_at, updated_at"
+
+var (
+    errReplyTemplateNotFound  = errors.New("reply template not found")
+    errReplyTemplateNameTaken = errors.New("reply template name taken")
+)
+
+// ReplyTemplate is a canned reply that can be posted to threads
+type ReplyTemplate struct {
+    ID        int64     `json:"id"`
+    Name      string    `json:"name"`
+    Body      string    `json:"body"`
+    Variables []string  `json:"variables"`
+    CreatedBy string    `json:"created_by"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ThreadReplyRequest is the optional body accepted by ReplyToThread
+type ThreadReplyRequest struct {
+    Variables map[string]string `json:"variables"`
+}
+
+// replyVariables returns the distinct variables used by body, sorted.
+func replyVariables(body string) []string {
+    seen := make(map[string]bool)
+    variables := []string{}
+    for _, match := range replyVariablePattern.FindAllStringSubmatch(body, -1) {
+        if !seen[match[1]] {
+            seen[match[1]] = true
+            variables = append(variables, match[1])
+        }
+    }
+    sort.Strings(variables)
+    return variables
+}
+
+// renderReply fills in the variables of body. It fails naming the variables
+// without a value.
+func renderReply(body string, values map[string]string) (string, error) {
+    missing := []string{}
+    for _, variable := range replyVariables(body) {
+        if _, ok := values[variable]; !ok {
+            missing = append(missing, variable)
+        }
+    }
+    if len(missing) > 0 {
+        return "", fmt.Errorf("missing values for %s", strings.Join(missing, ", "))
+    }
+    return replyVariablePattern.ReplaceAllStringFunc(body, func(placeholder string) string {
+        return values[replyVariablePattern.FindStringSubmatch(placeholder)[1]]
+    }), nil
+}
+
+func scanReplyTemplate(row interface{ Scan(...interface{}) error }) (ReplyTemplate, error) {
+    var t ReplyTemplate
+    err := row.Scan(&t.ID, &t.Name, &t.Body, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt)
+    t.Variables = replyVariables(t.Body)
+    return t, err
+}
+
+// decodeReplyTemplate reads and validates a template from the request body.
+func decodeReplyTemplate(ctx echo.Context) (ReplyTemplate, string) {
+    var t ReplyTemplate
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&t); err != nil {
+        return t, "Invalid request body"
+    }
+    t.Name = strings.TrimSpace(t.Name)
+    if t.Name == "" || len(t.Name) > 100 {
+        return t, "name must be between 1 and 100 characters"
+    }
+    if strings.TrimSpace(t.Body) == "" || len(t.Body) > maxReplyTemplateLength {
+        return t, fmt.Sprintf("body must be between 1 and %d characters", maxReplyTemplateLength)
+    }
+    return t, ""
+}
+
+// GetReplyTemplates - List the canned replies
+func (c *Container) GetReplyTemplates(ctx echo.Context) error {
+    templates, err := c.templates.ReplyTemplates(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query reply templates")
+    }
+
+    return ctx.JSON(http.StatusOK, templates)
+}
+
+// CreateReplyTemplate - Add a canned reply
+func (c *Container) CreateReplyTemplate(ctx echo.Context) error {
+    t, msg := decodeReplyTemplate(ctx)
+    if msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    actor := actorFrom(ctx)
+    t.CreatedBy = actor
+    t, err := c.templates.CreateReplyTemplate(ctx.Request().Context(), t)
+    if err == errReplyTemplateNameTaken {
+        return apierror.New(http.StatusConflict, "A reply template with this name already exists")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to create reply template")
+    }
+
+    c.audit(ctx.Request().Context(), actor, "reply_template.create", "", "", map[string]interface{}{
+        "template_id": t.ID,
+        "name":        t.Name,
+    })
+
+    return ctx.JSON(http.StatusCreated, t)
+}
+
+// UpdateReplyTemplate - Rename or rewrite a canned reply
+func (c *Container) UpdateReplyTemplate(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid reply template id")
+    }
+
+    t, msg := decodeReplyTemplate(ctx)
+    if msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    t.ID = id
+    t, err = c.templates.UpdateReplyTemplate(ctx.Request().Context(), t)
+    switch err {
+    case nil:
+    case errReplyTemplateNotFound:
+        return apierror.New(http.StatusNotFound, "Reply template not found")
+    case errReplyTemplateNameTaken:
+        return apierror.New(http.StatusConflict, "A reply template with this name already exists")
+    default:
+        return apierror.New(http.StatusInternalServerError, "Failed to update reply template")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "reply_template.update", "", "", map[string]interface{}{
+        "template_id": t.ID,
+        "name":        t.Name,
+    })
+
+    return ctx.JSON(http.StatusOK, t)
+}
+
+// DeleteReplyTemplate - Remove a canned reply
+func (c *Container) DeleteReplyTemplate(ctx echo.Context) error {
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid reply template id")
+    }
+
+    err = c.templates.DeleteReplyTemplate(ctx.Request().Context(), id)
+    if err == errReplyTemplateNotFound {
+        return apierror.New(http.StatusNotFound, "Reply template not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to delete reply template")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "reply_template.delete", "", "", map[string]interface{}{
+        "template_id": id,
+    })
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// ReplyToThread - Post a canned reply, chosen by name or id with the template parameter, to a Slack thread
+func (c *Container) ReplyToThread(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    name := ctx.QueryParam("template")
+    if name == "" {
+        return apierror.New(http.StatusBadRequest, "template is required")
+    }
+    var req ThreadReplyRequest
+    if ctx.Request().ContentLength != 0 {
+        if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+            return apierror.New(http.StatusBadRequest, "Invalid request body")
+        }
+    }
+    if !c.slack.Configured() {
+        return apierror.New(http.StatusServiceUnavailable, "Replying needs the Slack bot token")
+    }
+    if !c.slackFeatureReady(slackFeatureReplies) {
+        return apierror.New(http.StatusServiceUnavailable, "Replying needs the chat:write scope of the Slack bot token")
+    }
+
+    reqCtx := ctx.Request().Context()
+    t, err := c.templates.ReplyTemplate(reqCtx, name)
+    if err == errReplyTemplateNotFound {
+        return apierror.New(http.StatusNotFound, "Reply template not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query reply template")
+    }
+
+    thread, err := c.threads.Thread(reqCtx, channelID, threadTS)
+    if err != nil {
+        return threadLookupError(ctx, err)
+    }
+
+    actor := actorFrom(ctx)
+    values := make(map[string]string, len(req.Variables)+len(builtinReplyVariables))
+    for variable, value := range req.Variables {
+        if !builtinReplyVariables[variable] {
+            values[variable] = value
+        }
+    }
+    values["author"] = "<@" + thread.UserID + ">"
+    values["channel"] = "<#" + channelID + ">"
+    values["thread_link"] = slack.Permalink(channelID, threadTS)
+    values["title"] = thread.Title
+    values["user"] = actor
+    if slackUserPattern.MatchString(actor) {
+        values["user"] = "<@" + actor + ">"
+    }
+
+    text, err := renderReply(t.Body, values)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+
+    if err := c.slack.PostReply(ctx.Request().Context(), channelID, threadTS, text); err != nil {
+        c.logger.Errorf("failed to post reply to thread %s/%s: %v", channelID, threadTS, err)
+        return apierror.New(http.StatusBadGateway, "Failed to post the reply to Slack")
+    }
+
+    c.audit(ctx.Request().Context(), actor, "thread.reply", channelID, threadTS, map[string]interface{}{
+        "template_id": t.ID,
+        "template":    t.Name,
+    })
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "channel_id": channelID,
+        "thread_ts":  threadTS,
+        "template":   t.Name,
+        "text":       text,
+    })
+}
+
+func (s postgresStore) ReplyTemplates(ctx context.Context) ([]ReplyTemplate, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, "SELECT "+replyTemplateColumns+" FROM reply_templates ORDER BY name")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    templates := []ReplyTemplate{}
+    for rows.Next() {
+        t, err := scanReplyTemplate(rows)
+        if err != nil {
+            continue
+        }
+        templates = append(templates, t)
+    }
+    return templates, rows.Err()
+}
+
+func (s postgresStore) ReplyTemplate(ctx context.Context, name string) (ReplyTemplate, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ReplyTemplate{}, err
+    }
+
+    query := "SELECT " + replyTemplateColumns + " FROM reply_templates WHERE name = $1"
+    var lookup interface{} = name
+    if id, err := strconv.ParseInt(name, 10, 64); err == nil {
+        query = "SELECT " + replyTemplateColumns + " FROM reply_templates WHERE id = $1"
+        lookup = id
+    }
+    t, err := scanReplyTemplate(db.QueryRowContext(ctx, query, lookup))
+    if err == sql.ErrNoRows {
+        return ReplyTemplate{}, errReplyTemplateNotFound
+    }
+    return t, err
+}
+
+func (s postgresStore) CreateReplyTemplate(ctx context.Context, t ReplyTemplate) (ReplyTemplate, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ReplyTemplate{}, err
+    }
+    t, err = scanReplyTemplate(db.QueryRowContext(ctx, `
+        INSERT INTO reply_templates (name, body, created_by, created_at, updated_at)
+        VALUES ($1, $2, $3, NOW(), NOW())
+        RETURNING `+replyTemplateColumns, t.Name, t.Body, t.CreatedBy))
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+        return ReplyTemplate{}, errReplyTemplateNameTaken
+    }
+    return t, err
+}
+
+func (s postgresStore) UpdateReplyTemplate(ctx context.Context, t ReplyTemplate) (ReplyTemplate, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ReplyTemplate{}, err
+    }
+    t, err = scanReplyTemplate(db.QueryRowContext(ctx, `
+        UPDATE reply_templates SET name = $2, body = $3, updated_at = NOW()
+        WHERE id = $1
+        RETURNING `+replyTemplateColumns, t.ID, t.Name, t.Body))
+    if err == sql.ErrNoRows {
+        return ReplyTemplate{}, errReplyTemplateNotFound
+    }
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+        return ReplyTemplate{}, errReplyTemplateNameTaken
+    }
+    return t, err
+}
+
+func (s postgresStore) DeleteReplyTemplate(ctx context.Context, id int64) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    result, err := db.ExecContext(ctx, "DELETE FROM reply_templates WHERE id = $1", id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return errReplyTemplateNotFound
+    }
+    return nil
+}
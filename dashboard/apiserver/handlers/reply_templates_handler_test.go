@@ -0,0 +1,53 @@
+package handlers
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestReplyToThreadWithTemplate(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", UserID: "U2", Status: statusOpen, CreatedAt: time.Now()},
+        Title: "Login fails"})
+    slack := &MemorySlack{}
+    c := newTestContainer(t, store, slack)
+    agent := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    body := `{"name": "ack", "body": "Hi {{author}}, {{user}} is looking at {{title}} ({{ticket}})"}`
+    rec := serveAs(c, agent, http.MethodPost, "/api/reply-templates", "/api/reply-templates", body, c.CreateReplyTemplate)
+    if rec.Code != http.StatusCreated {
+        t.Fatalf("creating the template got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var template ReplyTemplate
+    decodeResponse(t, rec, &template)
+    if template.CreatedBy != "U1" || strings.Join(template.Variables, ",") != "author,ticket,title,user" {
+        t.Fatalf("got template %+v", template)
+    }
+    rec = serveAs(c, agent, http.MethodPost, "/api/reply-templates", "/api/reply-templates", body, c.CreateReplyTemplate)
+    if rec.Code != http.StatusConflict {
+        t.Fatalf("creating a second template named alike got status %d", rec.Code)
+    }
+
+    reply := func(target string, body string) int {
+        return serveAs(c, agent, http.MethodPost, "/api/threads/:channel_id/:thread_ts/reply", target, body, c.ReplyToThread).Code
+    }
+    if code := reply("/api/threads/C1/1700000000.000200/reply?template=ack", `{"variables": {"ticket": "OPS-1"}}`); code != http.StatusNotFound {
+        t.Fatalf("replying to a missing thread got status %d", code)
+    }
+    if code := reply("/api/threads/C1/1700000000.000100/reply?template=ack", ""); code != http.StatusBadRequest {
+        t.Fatalf("replying without the ticket got status %d", code)
+    }
+    if code := reply("/api/threads/C1/1700000000.000100/reply?template=ack", `{"variables": {"ticket": "OPS-1", "author": "me"}}`); code != http.StatusOK {
+        t.Fatalf("replying got status %d", code)
+    }
+    messages := slack.Messages()
+    if len(messages) != 1 || messages[0].ThreadTS != "1700000000.000100" || messages[0].Text != "Hi <@U2>, <@U1> is looking at Login fails (OPS-1)" {
+        t.Fatalf("posted %+v", messages)
+    }
+}
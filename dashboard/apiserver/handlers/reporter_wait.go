@@ -0,0 +1,302 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+// statusWaitingReporter is the status of threads waiting for their author
+// to answer a question. Their SLA clock is paused and responders are not
+// reminded about them, the author is nudged instead.
+const statusWaitingReporter = domain.StatusWaitingReporter
+
+// errThreadNotOpen is returned by stores for a thread that cannot start
+// waiting on its author or a release.
+var errThreadNotOpen = errors.New("thread is not open")
+
+// ReporterNudge is a thread whose author is due a nudge.
+type ReporterNudge struct {
+    ChannelID string
+    ThreadTS  string
+    Author    string
+}
+
+// WaitingReporterRequest is the body accepted by SetThreadWaitingReporter.
+// NudgeAfterHours replaces YB_OPEN_THREADS_REMINDER_REPORTER_NUDGE_AFTER for
+// the thread.
+type WaitingReporterRequest struct {
+    NudgeAfterHours *float64 `json:"nudge_after_hours"`
+}
+
+// pausedSLAColumn is how long a thread waited on its author, including the
+// wait in progress.
+const pausedSLAColumn = `COALESCE((SELECT w.paused_seconds +
+        CASE WHEN w.waiting_since IS NULL THEN 0
+             ELSE GREATEST(EXTRACT(EPOCH FROM (NOW() - w.waiting_since)), 0) END
+     FROM thread_reporter_waits w
+     WHERE w.channel_id = t.channel_id AND w.thread_ts = t.thread_ts), 0) AS sla_paused_seconds`
+
+// SetThreadWaitingReporter - Pause the SLA of an open thread until its author replies, nudging them if they do not
+func (c *Container) SetThreadWaitingReporter(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req WaitingReporterRequest
+    if ctx.Request().ContentLength != 0 {
+        if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+            return apierror.New(http.StatusBadRequest, "Invalid request body")
+        }
+    }
+    nudgeAfter := c.reporterNudgeAfter
+    if req.NudgeAfterHours != nil {
+        if *req.NudgeAfterHours <= 0 {
+            return apierror.New(http.StatusBadRequest, "nudge_after_hours must be positive")
+        }
+        nudgeAfter = time.Duration(*req.NudgeAfterHours * float64(time.Hour))
+    }
+
+    err := c.channels.Tracked(ctx.Request().Context(), channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    actor := actorFrom(ctx)
+    since, nudgeAt, err := c.reporterWaits.WaitOnReporter(ctx.Request().Context(), channelID, threadTS, actor, nudgeAfter)
+    if err == errThreadNotOpen {
+        return apierror.New(http.StatusConflict, "Only open threads can wait on their author")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to start waiting on the author")
+    }
+
+    c.audit(ctx.Request().Context(), actor, "thread.waiting_reporter", channelID, threadTS, map[string]interface{}{
+        "nudge_at": nudgeAt,
+    })
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "channel_id":    channelID,
+        "thread_ts":     threadTS,
+        "status":        statusWaitingReporter,
+        "waiting_since": since,
+        "nudge_at":      nudgeAt,
+    })
+}
+
+// ClearThreadWaitingReporter - Stop waiting on the author and reopen the thread
+func (c *Container) ClearThreadWaitingReporter(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    err := c.channels.Tracked(ctx.Request().Context(), channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    reopened, err := c.reporterWaits.ResumeFromReporter(ctx.Request().Context(), channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to reopen thread")
+    }
+    if !reopened {
+        return apierror.New(http.StatusNotFound, "Thread is not waiting on its author")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "thread.waiting_reporter_cleared", channelID, threadTS, nil)
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// reporterReplied reopens a thread waiting on its author once they reply.
+func (c *Container) reporterReplied(ctx context.Context, channelID string, threadTS string, author string) error {
+    reopened, err := c.reporterWaits.ResumeFromReporter(ctx, channelID, threadTS)
+    if err != nil || !reopened {
+        return err
+    }
+    c.audit(ctx, author, "thread.reporter_replied", channelID, threadTS, nil)
+    return nil
+}
+
+// RunReporterNudges periodically reminds the authors of threads waiting on
+// them once their nudge is due, until ctx is cancelled. Every wait nudges
+// at most once. It does nothing without a bot token.
+func (c *Container) RunReporterNudges(ctx context.Context) {
+    if !c.slack.Configured() {
+        return
+    }
+
+    ticker := time.NewTicker(reminderScanInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.nudgeReporters(ctx); err != nil {
+            c.logger.Warnf("failed to nudge thread authors: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (c *Container) nudgeReporters(ctx context.Context) error {
+    if !c.slackFeatureReady(slackFeatureReplies) {
+        return nil
+    }
+    due, err := c.reporterWaits.DueReporterNudges(ctx)
+    if err != nil {
+        return err
+    }
+
+    for _, t := range due {
+        text := fmt.Sprintf("<@%s> we are waiting on your reply to move this thread forward. Is this still an issue?", t.Author)
+        if err := c.slack.PostReply(ctx, t.ChannelID, t.ThreadTS, text); err != nil {
+            c.logger.Warnf("failed to nudge the author of %s/%s: %v", t.ChannelID, t.ThreadTS, err)
+            continue
+        }
+        if err := c.reporterWaits.MarkReporterNudged(ctx, t.ChannelID, t.ThreadTS); err != nil {
+            c.logger.Warnf("failed to record nudge of %s/%s: %v", t.ChannelID, t.ThreadTS, err)
+            continue
+        }
+        c.audit(ctx, "system", "thread.reporter_nudged", t.ChannelID, t.ThreadTS, map[string]interface{}{
+            "user_id": t.Author,
+        })
+    }
+    return nil
+}
+
+func (s postgresStore) WaitOnReporter(ctx context.Context, channelID string, threadTS string, actor string, nudgeAfter time.Duration) (time.Time, time.Time, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return time.Time{}, time.Time{}, err
+    }
+
+    result, err := db.ExecContext(ctx, `
+        UPDATE threads SET status = $1, updated_at = NOW()
+        WHERE thread_ts = $2 AND channel_id = $3 AND status IN ('open', $1)
+    `, statusWaitingReporter, threadTS, channelID)
+    if err != nil {
+        return time.Time{}, time.Time{}, err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return time.Time{}, time.Time{}, errThreadNotOpen
+    }
+
+    // Setting the status again only moves the nudge, the wait keeps counting
+    // from when it started
+    var since, nudgeAt time.Time
+    err = db.QueryRowContext(ctx, `
+        INSERT INTO thread_reporter_waits (channel_id, thread_ts, set_by, waiting_since, nudge_at)
+        VALUES ($1, $2, $3, NOW(), NOW() + $4::float8 * INTERVAL '1 second')
+        ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+            set_by = EXCLUDED.set_by,
+            waiting_since = COALESCE(thread_reporter_waits.waiting_since, EXCLUDED.waiting_since),
+            nudge_at = COALESCE(thread_reporter_waits.waiting_since, EXCLUDED.waiting_since) + $4::float8 * INTERVAL '1 second',
+            nudged_at = NULL
+        RETURNING waiting_since, nudge_at
+    `, channelID, threadTS, actor, nudgeAfter.Seconds()).Scan(&since, &nudgeAt)
+    return since, nudgeAt, err
+}
+
+func (s postgresStore) ResumeFromReporter(ctx context.Context, channelID string, threadTS string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    result, err := db.ExecContext(ctx, `
+        UPDATE threads SET status = 'open', updated_at = NOW()
+        WHERE thread_ts = $1 AND channel_id = $2 AND status = $3
+    `, threadTS, channelID, statusWaitingReporter)
+    if err != nil {
+        return false, err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return false, nil
+    }
+    return true, endReporterWait(ctx, db, channelID, threadTS)
+}
+
+// endReporterWait adds the wait in progress to the time the SLA of the
+// thread was paused.
+func endReporterWait(ctx context.Context, db *sql.DB, channelID string, threadTS string) error {
+    _, err := db.ExecContext(ctx, `
+        UPDATE thread_reporter_waits
+        SET paused_seconds = paused_seconds + GREATEST(EXTRACT(EPOCH FROM (NOW() - waiting_since)), 0),
+            waiting_since = NULL,
+            nudge_at = NULL,
+            nudged_at = NULL
+        WHERE channel_id = $1 AND thread_ts = $2 AND waiting_since IS NOT NULL
+    `, channelID, threadTS)
+    return err
+}
+
+func (s postgresStore) DueReporterNudges(ctx context.Context) ([]ReporterNudge, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT channel_id, thread_ts FROM thread_reporter_waits
+        WHERE waiting_since IS NOT NULL AND nudged_at IS NULL AND nudge_at <= NOW()
+    `)
+    if err != nil {
+        return nil, err
+    }
+    waits := []ReporterNudge{}
+    for rows.Next() {
+        var t ReporterNudge
+        if err := rows.Scan(&t.ChannelID, &t.ThreadTS); err == nil {
+            waits = append(waits, t)
+        }
+    }
+    rows.Close()
+
+    due := []ReporterNudge{}
+    for _, t := range waits {
+        err = channelTracked(db, t.ChannelID)
+        if err != nil {
+            continue
+        }
+        // Threads reopened outside of the dashboard no longer wait
+        err = db.QueryRowContext(ctx, "SELECT user_id FROM threads WHERE thread_ts = $1 AND channel_id = $2 AND status = $3",
+            t.ThreadTS, t.ChannelID, statusWaitingReporter).Scan(&t.Author)
+        if err == sql.ErrNoRows {
+            endReporterWait(ctx, db, t.ChannelID, t.ThreadTS)
+            continue
+        }
+        if err != nil {
+            s.c.logger.Warnf("failed to look up thread %s/%s: %v", t.ChannelID, t.ThreadTS, err)
+            continue
+        }
+        due = append(due, t)
+    }
+    return due, nil
+}
+
+func (s postgresStore) MarkReporterNudged(ctx context.Context, channelID string, threadTS string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        UPDATE thread_reporter_waits SET nudged_at = NOW()
+        WHERE channel_id = $1 AND thread_ts = $2
+    `, channelID, threadTS)
+    return err
+}
@@ -0,0 +1,502 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/notify"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Resolution request states.
+const (
+    ResolutionPending  = "pending"
+    ResolutionApproved = "approved"
+    ResolutionRejected = "rejected"
+)
+
+// Outcomes of resolveOrRequest.
+const (
+    resolveClosed = iota
+    resolveRequested
+    resolveAlreadyClosed
+    resolveAlreadyRequested
+)
+
+// Resolutions, why a thread was resolved.
+const (
+    resolutionAnswered     = "answered"
+    resolutionDuplicate    = "duplicate"
+    resolutionMovedToIssue = "moved_to_issue"
+    resolutionWontFix      = "wont_fix"
+    resolutionNoResponse   = "no_response"
+)
+
+var validResolutions = map[string]bool{
+    resolutionAnswered:     true,
+    resolutionDuplicate:    true,
+    resolutionMovedToIssue: true,
+    resolutionWontFix:      true,
+    resolutionNoResponse:   true,
+}
+
+// errInvalidResolution is the error of requests resolving threads without
+// a valid resolution.
+const errInvalidResolution = "resolution must be answered, duplicate, moved_to_issue, wont_fix or no_response"
+
+var errThreadNotFound = errors.New("thread not found")
+
+// errResolutionNotPending is returned when no resolution of a thread is
+// waiting for approval.
+var errResolutionNotPending = errors.New("no resolution is pending")
+
+// ResolveApproval is the per-channel policy for closing important threads.
+// When enabled, resolving a thread with one of Priorities, or with a linked
+// GitHub issue or Jira ticket if Linked is set, needs a second person.
+type ResolveApproval struct {
+    Enabled    bool     `json:"enabled"`
+    Priorities []string `json:"priorities"`
+    Linked     bool     `json:"linked"`
+}
+
+// defaultResolveApproval is used by channels without a policy.
+func defaultResolveApproval() ResolveApproval {
+    return ResolveApproval{Enabled: false, Priorities: []string{"high"}, Linked: true}
+}
+
+// parseResolveApproval decodes a stored policy, falling back to the default.
+func parseResolveApproval(stored sql.NullString) ResolveApproval {
+    approval := defaultResolveApproval()
+    if stored.Valid {
+        if err := json.Unmarshal([]byte(stored.String), &approval); err != nil {
+            return defaultResolveApproval()
+        }
+    }
+    return approval
+}
+
+// requires reports whether closing a thread needs approval under the policy.
+func (a ResolveApproval) requires(priority string, linked bool) bool {
+    if !a.Enabled {
+        return false
+    }
+    if a.Linked && linked {
+        return true
+    }
+    for _, p := range a.Priorities {
+        if p == priority {
+            return true
+        }
+    }
+    return false
+}
+
+// ResolutionRequest is a request to close a thread waiting for a second
+// person.
+type ResolutionRequest struct {
+    ID          int64      `json:"id"`
+    ChannelID   string     `json:"channel_id"`
+    ThreadTS    string     `json:"thread_ts"`
+    RequestedBy string     `json:"requested_by"`
+    Resolution  string     `json:"resolution"`
+    Reason      string     `json:"reason"`
+    Status      string     `json:"status"`
+    DecidedBy   *string    `json:"decided_by"`
+    Note        string     `json:"note"`
+    CreatedAt   time.Time  `json:"created_at"`
+    DecidedAt   *time.Time `json:"decided_at"`
+}
+
+// ResolutionDecision is the body accepted by ResolveThread and the approve
+// and reject endpoints. Resolution is required to resolve a thread, Reason
+// explains it in free text, or is the note of a decision.
+type ResolutionDecision struct {
+    Reason string `json:"reason"`
+    // Resolution is one of validResolutions
+    Resolution string `json:"resolution"`
+}
+
+// rowQuerier is implemented by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+    QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// resolutionPolicy returns the status of a thread and whether closing it
+// needs approval under its channel's policy. Within a transaction the
+// thread stays locked until it ends, so the status cannot change between
+// the check and an update.
+func resolutionPolicy(q rowQuerier, channelID string, threadTS string) (domain.Status, bool, error) {
+    var status domain.Status
+    var priority string
+    var linked bool
+    var storedApproval sql.NullString
+    err := q.QueryRow(`
+        SELECT t.status, COALESCE(`+manualPriorityColumn+`, t.ai_priority, ''), t.github_issue IS NOT NULL OR t.jira_ticket IS NOT NULL,
+               (SELECT cc.resolve_approval FROM channel_config cc WHERE cc.channel_id = t.channel_id)
+        FROM threads t
+        WHERE t.thread_ts = $1 AND t.channel_id = $2
+        FOR UPDATE OF t
+    `, threadTS, channelID).Scan(&status, &priority, &linked, &storedApproval)
+    if err == sql.ErrNoRows {
+        return "", false, errThreadNotFound
+    }
+    if err != nil {
+        return "", false, err
+    }
+    return status, parseResolveApproval(storedApproval).requires(priority, linked), nil
+}
+
+// resolveOrRequest closes a thread on behalf of actor, or opens a
+// resolution request when the channel requires approval for it. The
+// resolution and reason are kept on the request until it is approved.
+func (c *Container) resolveOrRequest(ctx context.Context, actor string, channelID string, threadTS string, resolution string, reason string) (int, error) {
+    outcome, err := c.resolutions.ResolveOrRequest(ctx, channelID, threadTS, actor, resolution, reason)
+    if err != nil {
+        return 0, err
+    }
+
+    switch outcome {
+    case resolveClosed:
+        c.audit(ctx, actor, "thread.resolve", channelID, threadTS, map[string]interface{}{
+            "resolution": resolution,
+            "reason":     reason,
+        })
+    case resolveRequested:
+        c.audit(ctx, actor, "thread.resolve_request", channelID, threadTS, map[string]interface{}{
+            "reason": reason,
+            // resolution is one of validResolutions
+            "resolution": resolution,
+        })
+        c.notifyResolution(channelID, threadTS, "",
+            fmt.Sprintf("<@%s> asked to resolve this thread. Someone else needs to approve it on the dashboard.", actor))
+    }
+    return outcome, nil
+}
+
+// notifyResolution posts text in the Slack thread and, when recipient is
+// set, notifies them. Failures are only logged.
+func (c *Container) notifyResolution(channelID string, threadTS string, recipient string, text string) {
+    go func() {
+        ctx := context.Background()
+        if c.slackFeatureReady(slackFeatureReplies) {
+            if err := c.slack.PostReply(ctx, channelID, threadTS, text); err != nil {
+                c.logger.Warnf("failed to post resolution update in %s/%s: %v", channelID, threadTS, err)
+            }
+        }
+        if recipient == "" || recipient == "anonymous" {
+            return
+        }
+        permalink := slack.Permalink(channelID, threadTS)
+        err := c.notifier.Notify(ctx, notify.Notification{
+            Event:     notify.EventResolution,
+            Recipient: recipient,
+            Text:      fmt.Sprintf("<%s|Thread>: %s", permalink, text),
+            ChannelID: channelID,
+            ThreadTS:  threadTS,
+        })
+        if err != nil {
+            c.logger.Warnf("failed to notify %s of resolution decision: %v", recipient, err)
+        }
+    }()
+}
+
+// ResolveThread - Resolve a thread for a resolution, or request approval when its channel requires it
+func (c *Container) ResolveThread(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req ResolutionDecision
+    if ctx.Request().ContentLength != 0 {
+        if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+            return apierror.New(http.StatusBadRequest, "Invalid request body")
+        }
+    }
+    if !validResolutions[req.Resolution] {
+        return apierror.New(http.StatusBadRequest, errInvalidResolution)
+    }
+
+    err := c.channels.Tracked(ctx.Request().Context(), channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    outcome, err := c.resolveOrRequest(ctx.Request().Context(), actorFrom(ctx), channelID, threadTS, req.Resolution, req.Reason)
+    if err == errThreadNotFound {
+        return apierror.New(http.StatusNotFound, "Thread not found")
+    }
+    if err != nil {
+        c.logger.Errorf("failed to resolve thread %s/%s: %v", channelID, threadTS, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to resolve thread")
+    }
+
+    switch outcome {
+    case resolveClosed:
+        return ctx.JSON(http.StatusOK, map[string]string{"status": "closed"})
+    case resolveRequested:
+        return ctx.JSON(http.StatusAccepted, map[string]string{"status": ResolutionPending})
+    case resolveAlreadyRequested:
+        return apierror.New(http.StatusConflict, "A resolution of this thread is already waiting for approval")
+    default:
+        return apierror.New(http.StatusConflict, "Thread is already resolved")
+    }
+}
+
+// ApproveResolution - Approve the pending resolution of a thread, closing it
+func (c *Container) ApproveResolution(ctx echo.Context) error {
+    return c.decideResolution(ctx, ResolutionApproved)
+}
+
+// RejectResolution - Reject the pending resolution of a thread, keeping it open
+func (c *Container) RejectResolution(ctx echo.Context) error {
+    return c.decideResolution(ctx, ResolutionRejected)
+}
+
+func (c *Container) decideResolution(ctx echo.Context, decision string) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+    actor := actorFrom(ctx)
+
+    var req ResolutionDecision
+    if ctx.Request().ContentLength != 0 {
+        if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+            return apierror.New(http.StatusBadRequest, "Invalid request body")
+        }
+    }
+
+    err := c.channels.Tracked(ctx.Request().Context(), channelID)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    request, err := c.resolutions.PendingResolution(ctx.Request().Context(), channelID, threadTS)
+    if err == errResolutionNotPending {
+        return apierror.New(http.StatusNotFound, "No resolution of this thread is waiting for approval")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query resolution requests")
+    }
+    if decision == ResolutionApproved && (actor == request.RequestedBy || actor == "anonymous") {
+        return apierror.New(http.StatusForbidden, "The resolution must be approved by a signed in user other than the requester")
+    }
+
+    decided, err := c.resolutions.DecideResolution(ctx.Request().Context(), request, decision, actor, req.Reason)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update resolution request")
+    }
+    if !decided {
+        return apierror.New(http.StatusConflict, "The resolution was decided concurrently")
+    }
+
+    text := fmt.Sprintf("<@%s> rejected resolving this thread, it stays open.", actor)
+    if decision == ResolutionApproved {
+        text = fmt.Sprintf("<@%s> approved resolving this thread.", actor)
+    }
+    if req.Reason != "" {
+        text += " " + req.Reason
+    }
+
+    c.audit(ctx.Request().Context(), actor, "thread.resolve_"+decision, channelID, threadTS, map[string]interface{}{
+        "request_id":   request.ID,
+        "requested_by": request.RequestedBy,
+        "note":         req.Reason,
+    })
+    c.notifyResolution(channelID, threadTS, request.RequestedBy, text)
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "id":     request.ID,
+        "status": decision,
+    })
+}
+
+// GetResolutionRequests - List resolution requests, pending ones by default
+func (c *Container) GetResolutionRequests(ctx echo.Context) error {
+    status := ctx.QueryParam("status")
+    if status == "" {
+        status = ResolutionPending
+    }
+
+    requests, err := c.resolutions.ResolutionRequests(ctx.Request().Context(), status)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query resolution requests")
+    }
+
+    return ctx.JSON(http.StatusOK, requests)
+}
+
+// SetChannelResolveApproval - Set which threads of a channel need a second person to resolve
+func (c *Container) SetChannelResolveApproval(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    approval := defaultResolveApproval()
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&approval); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    for _, p := range approval.Priorities {
+        if !domain.Priority(p).Valid() {
+            return apierror.New(http.StatusBadRequest, "priorities must be high, medium or low")
+        }
+    }
+
+    err := c.configs.SetChannelConfig(ctx.Request().Context(), channelID, ChannelConfig{ResolveApproval: approval}, settingResolveApproval)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.resolve_approval", channelID, "", map[string]interface{}{
+        "resolve_approval": approval,
+    })
+
+    return ctx.JSON(http.StatusOK, approval)
+}
+
+func (s postgresStore) CloseThread(ctx context.Context, channelID, threadTS, actor, resolution, note string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    result, err := db.ExecContext(ctx, `
+        UPDATE threads
+        SET status = 'closed', resolved_by = $3, resolved_at = NOW(),
+            resolution = NULLIF($4, ''), resolution_note = NULLIF($5, ''), updated_at = NOW()
+        WHERE thread_ts = $1 AND channel_id = $2 AND status = 'open'
+    `, threadTS, channelID, actor, resolution, note)
+    if err != nil {
+        return false, err
+    }
+    n, _ := result.RowsAffected()
+    if n > 0 {
+        labelAnswerSignals(db, channelID, threadTS, resolution)
+    }
+    return n > 0, nil
+}
+
+func (s postgresStore) ResolveOrRequest(ctx context.Context, channelID, threadTS, actor, resolution, reason string) (int, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return 0, err
+    }
+
+    status, needsApproval, err := resolutionPolicy(db, channelID, threadTS)
+    if err != nil {
+        return 0, err
+    }
+    if status != "open" {
+        return resolveAlreadyClosed, nil
+    }
+
+    if !needsApproval {
+        closed, err := s.CloseThread(ctx, channelID, threadTS, actor, resolution, reason)
+        if err != nil {
+            return 0, err
+        }
+        if !closed {
+            return resolveAlreadyClosed, nil
+        }
+        return resolveClosed, nil
+    }
+
+    result, err := db.ExecContext(ctx, `
+        INSERT INTO resolution_requests (channel_id, thread_ts, requested_by, resolution, reason)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT DO NOTHING
+    `, channelID, threadTS, actor, resolution, reason)
+    if err != nil {
+        return 0, err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return resolveAlreadyRequested, nil
+    }
+    return resolveRequested, nil
+}
+
+func (s postgresStore) PendingResolution(ctx context.Context, channelID, threadTS string) (ResolutionRequest, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ResolutionRequest{}, err
+    }
+
+    r := ResolutionRequest{ChannelID: channelID, ThreadTS: threadTS, Status: ResolutionPending}
+    err = db.QueryRowContext(ctx, `
+        SELECT id, requested_by, resolution, reason, created_at FROM resolution_requests
+        WHERE channel_id = $1 AND thread_ts = $2 AND status = $3
+    `, channelID, threadTS, ResolutionPending).Scan(&r.ID, &r.RequestedBy, &r.Resolution, &r.Reason, &r.CreatedAt)
+    if err == sql.ErrNoRows {
+        return ResolutionRequest{}, errResolutionNotPending
+    }
+    return r, err
+}
+
+func (s postgresStore) DecideResolution(ctx context.Context, request ResolutionRequest, decision string, actor string, note string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    result, err := db.ExecContext(ctx, `
+        UPDATE resolution_requests SET status = $1, decided_by = $2, note = $3, decided_at = NOW()
+        WHERE id = $4 AND status = $5
+    `, decision, actor, note, request.ID, ResolutionPending)
+    if err != nil {
+        return false, err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return false, nil
+    }
+
+    if decision == ResolutionApproved {
+        if _, err := s.CloseThread(ctx, request.ChannelID, request.ThreadTS, request.RequestedBy, request.Resolution, request.Reason); err != nil {
+            return false, err
+        }
+    }
+    return true, nil
+}
+
+func (s postgresStore) ResolutionRequests(ctx context.Context, status string) ([]ResolutionRequest, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT id, channel_id, thread_ts, requested_by, resolution, reason, status, decided_by, note, created_at, decided_at
+        FROM resolution_requests
+        WHERE status = $1
+        ORDER BY created_at DESC
+        LIMIT 500
+    `, status)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    requests := []ResolutionRequest{}
+    for rows.Next() {
+        var r ResolutionRequest
+        err := rows.Scan(&r.ID, &r.ChannelID, &r.ThreadTS, &r.RequestedBy, &r.Resolution, &r.Reason, &r.Status,
+            &r.DecidedBy, &r.Note, &r.CreatedAt, &r.DecidedAt)
+        if err != nil {
+            continue
+        }
+        requests = append(requests, r)
+    }
+    return requests, rows.Err()
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/notify"
+)
+
+func TestResolutionNeedsSecondPerson(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: time.Now()},
+        JiraTicket: "OPS-1"})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: statusOpen, CreatedAt: time.Now()}})
+    approval := ResolveApproval{Enabled: true, Linked: true}
+    if err := store.SetChannelConfig(context.Background(), "C1", ChannelConfig{ResolveApproval: approval}, settingResolveApproval); err != nil {
+        t.Fatal(err)
+    }
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.notifier, _ = notify.NewRouter(c.logger, nil)
+    requester := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+    approver := &auth.Principal{UserID: "U2", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+    resolve := func(threadTS string) int {
+        rec := serveAs(c, requester, http.MethodPost, "/api/threads/:channel_id/:thread_ts/resolve",
+            "/api/threads/C1/"+threadTS+"/resolve", `{"resolution": "answered", "reason": "Fixed upstream"}`, c.ResolveThread)
+        return rec.Code
+    }
+
+    // Only the linked thread needs approval
+    if code := resolve("1700000000.000200"); code != http.StatusOK {
+        t.Fatalf("resolving the unlinked thread got status %d", code)
+    }
+    if code := resolve("1700000000.000100"); code != http.StatusAccepted {
+        t.Fatalf("resolving the linked thread got status %d", code)
+    }
+    if code := resolve("1700000000.000100"); code != http.StatusConflict {
+        t.Fatalf("resolving the linked thread again got status %d", code)
+    }
+
+    rec := serveAs(c, approver, http.MethodGet, "/api/resolution-requests", "/api/resolution-requests", "", c.GetResolutionRequests)
+    var requests []ResolutionRequest
+    decodeResponse(t, rec, &requests)
+    if len(requests) != 1 || requests[0].RequestedBy != "U1" || requests[0].Resolution != resolutionAnswered {
+        t.Fatalf("listed requests %+v", requests)
+    }
+
+    approve := func(principal *auth.Principal) int {
+        rec := serveAs(c, principal, http.MethodPost, "/api/threads/:channel_id/:thread_ts/resolve/approve",
+            "/api/threads/C1/1700000000.000100/resolve/approve", "", c.ApproveResolution)
+        return rec.Code
+    }
+    if code := approve(requester); code != http.StatusForbidden {
+        t.Fatalf("approving your own request got status %d", code)
+    }
+    if code := approve(approver); code != http.StatusOK {
+        t.Fatalf("approving the request got status %d", code)
+    }
+    if code := approve(approver); code != http.StatusNotFound {
+        t.Fatalf("approving the request twice got status %d", code)
+    }
+
+    thread, err := store.Thread(context.Background(), "C1", "1700000000.000100")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if thread.Status != statusClosed {
+        t.Fatalf("got status %s after the approval, want closed", thread.Status)
+    }
+    rec = serveAs(c, approver, http.MethodGet, "/api/resolution-requests", "/api/resolution-requests?status=approved", "", c.GetResolutionRequests)
+    decodeResponse(t, rec, &requests)
+    if len(requests) != 1 || requests[0].DecidedBy == nil || *requests[0].DecidedBy != "U2" {
+        t.Fatalf("listed approved requests %+v", requests)
+    }
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+    "context"
+    "math"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+// resolutionUnknown counts the threads resolved without a resolution, e.g.
+// before resolutions were asked or from a Slack button.
+const resolutionUnknown = "unknown"
+
+// ResolutionMix counts the threads resolved in a channel over a range, per
+// resolution. UndetectedAnswers are threads resolved as answered that no
+// answer signal caught.
+type ResolutionMix struct {
+    ChannelID         string         `json:"channel_id,omitempty"`
+    ChannelName       string         `json:"channel_name,omitempty"`
+    Resolved          int            `json:"resolved"`
+    Resolutions       map[string]int `json:"resolutions"`
+    UndetectedAnswers int            `json:"undetected_answers"`
+}
+
+func newResolutionMix(channelID string, channelName string) ResolutionMix {
+    mix := ResolutionMix{ChannelID: channelID, ChannelName: channelName, Resolutions: map[string]int{resolutionUnknown: 0}}
+    for resolution := range validResolutions {
+        mix.Resolutions[resolution] = 0
+    }
+    return mix
+}
+
+// AnswerSignalAccuracy is how often an answer signal of a channel was
+// right: of the Labelled threads it was seen on that got resolved, how many
+// were resolved as answered. Muted signals are no longer surfaced.
+type AnswerSignalAccuracy struct {
+    ChannelID string  `json:"channel_id"`
+    Signal    string  `json:"signal"`
+    Labelled  int     `json:"labelled"`
+    Answered  int     `json:"answered"`
+    Precision float64 `json:"precision"`
+    Muted     bool    `json:"muted"`
+}
+
+// ResolutionStats is the resolution mix per channel and in total, with how
+// accurate the answer signals of these channels are.
+type ResolutionStats struct {
+    Range         string                 `json:"range"`
+    Channels      []ResolutionMix        `json:"channels"`
+    Total         ResolutionMix          `json:"total"`
+    AnswerSignals []AnswerSignalAccuracy `json:"answer_signals"`
+}
+
+// GetResolutionStats - Get why threads were resolved per channel over a range, and how accurate answer signals are
+func (c *Container) GetResolutionStats(ctx echo.Context) error {
+    rangeParam := ctx.QueryParam("range")
+    if rangeParam == "" {
+        rangeParam = "30d"
+    }
+    span, err := parseSpan("range", rangeParam)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+
+    scope := statsScope{group: ctx.QueryParam("group"), channels: listParam(ctx.QueryParam("channel"))}
+    mixes, accuracies, err := c.stats.ResolutionStats(ctx.Request().Context(), scope, time.Now().UTC().Add(-span))
+    if err == errGroupNotFound {
+        return apierror.New(http.StatusNotFound, "Channel group not found")
+    }
+    if err != nil {
+        c.logger.Errorf("failed to query resolution stats: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to query resolution stats")
+    }
+
+    stats := ResolutionStats{Range: rangeParam, Channels: mixes, Total: newResolutionMix("", ""), AnswerSignals: accuracies}
+    for _, mix := range mixes {
+        stats.Total.Resolved += mix.Resolved
+        stats.Total.UndetectedAnswers += mix.UndetectedAnswers
+        for resolution, count := range mix.Resolutions {
+            stats.Total.Resolutions[resolution] += count
+        }
+    }
+    return ctx.JSON(http.StatusOK, stats)
+}
+
+func (s postgresStore) ResolutionStats(ctx context.Context, scope statsScope, since time.Time) ([]ResolutionMix, []AnswerSignalAccuracy, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, nil, err
+    }
+    filter, args, err := scopeFilter(db, scope)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT ch.channel_id, ch.channel_name
+        FROM channels ch
+        WHERE `+filter+`
+        ORDER BY ch.channel_name`, args...)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer rows.Close()
+
+    mixes := []ResolutionMix{}
+    accuracies := []AnswerSignalAccuracy{}
+    index := map[string]int{}
+    var channelIDs []string
+    for rows.Next() {
+        var channelID, channelName string
+        if err := rows.Scan(&channelID, &channelName); err != nil {
+            continue
+        }
+        index[channelID] = len(mixes)
+        channelIDs = append(channelIDs, channelID)
+        mixes = append(mixes, newResolutionMix(channelID, channelName))
+    }
+    if err := rows.Err(); err != nil {
+        return nil, nil, err
+    }
+    rows.Close()
+    if len(channelIDs) == 0 {
+        return mixes, accuracies, nil
+    }
+
+    mixRows, err := db.QueryContext(ctx, `
+        SELECT t.channel_id, COALESCE(t.resolution, ''), COUNT(*),
+               COUNT(*) FILTER (WHERE t.resolution = $2 AND NOT EXISTS (
+                   SELECT 1 FROM thread_answer_signals sig
+                   WHERE sig.channel_id = t.channel_id AND sig.thread_ts = t.thread_ts))
+        FROM threads t
+        WHERE t.channel_id = ANY($3) AND t.status IN ('resolved', 'closed') AND t.resolved_at >= $1
+        GROUP BY 1, 2
+    `, since, resolutionAnswered, pq.Array(channelIDs))
+    if err != nil {
+        return nil, nil, err
+    }
+    defer mixRows.Close()
+
+    for mixRows.Next() {
+        var channelID, resolution string
+        var count, undetected int
+        if err := mixRows.Scan(&channelID, &resolution, &count, &undetected); err != nil {
+            continue
+        }
+        i, ok := index[channelID]
+        if !ok {
+            continue
+        }
+        if !validResolutions[resolution] {
+            resolution = resolutionUnknown
+        }
+        mixes[i].Resolved += count
+        mixes[i].Resolutions[resolution] += count
+        mixes[i].UndetectedAnswers += undetected
+    }
+    mixRows.Close()
+
+    accuracyRows, err := db.QueryContext(ctx, `
+        SELECT acc.channel_id, acc.signal, acc.labelled, acc.answered, (`+answerSignalMuted+`)
+        FROM answer_signal_accuracy acc
+        WHERE acc.channel_id = ANY($1) AND acc.labelled > 0
+        ORDER BY acc.channel_id, acc.signal
+    `, pq.Array(channelIDs))
+    if err != nil {
+        return nil, nil, err
+    }
+    defer accuracyRows.Close()
+
+    for accuracyRows.Next() {
+        var accuracy AnswerSignalAccuracy
+        if err := accuracyRows.Scan(&accuracy.ChannelID, &accuracy.Signal, &accuracy.Labelled,
+            &accuracy.Answered, &accuracy.Muted); err != nil {
+            continue
+        }
+        accuracy.Precision = math.Round(float64(accuracy.Answered)/float64(accuracy.Labelled)*1000) / 1000
+        accuracies = append(accuracies, accuracy)
+    }
+    return mixes, accuracies, nil
+}
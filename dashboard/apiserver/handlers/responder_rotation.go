@@ -0,0 +1,124 @@
+package handlers
+
+import (
+    "database/sql"
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+// ResponderRotation is a channel's first-responder schedule. Users take
+// turns of Shift each, starting with the first one at StartsAt. Reminders
+// about unacknowledged threads go to whoever is on shift first.
+type ResponderRotation struct {
+    Users    []string  `json:"users"`
+    Shift    string    `json:"shift"`
+    StartsAt time.Time `json:"starts_at"`
+}
+
+// FirstResponder is the user on shift and when their shift ends
+type FirstResponder struct {
+    UserID string    `json:"user_id"`
+    Until  time.Time `json:"until"`
+}
+
+// validate checks a rotation and fills in the start when omitted.
+func (r *ResponderRotation) validate(now time.Time) string {
+    if len(r.Users) == 0 {
+        return ""
+    }
+    seen := make(map[string]bool, len(r.Users))
+    for _, user := range r.Users {
+        if user == "" {
+            return "users must not be empty"
+        }
+        if seen[user] {
+            return "user " + user + " is listed twice"
+        }
+        seen[user] = true
+    }
+    shift, err := time.ParseDuration(r.Shift)
+    if err != nil || shift < time.Hour {
+        return "shift must be a duration of at least 1h, e.g. 24h or 168h"
+    }
+    if r.StartsAt.IsZero() {
+        r.StartsAt = now
+    }
+    r.StartsAt = r.StartsAt.UTC()
+    return ""
+}
+
+// parseResponderRotation decodes a stored rotation, nil when the channel has
+// none.
+func parseResponderRotation(stored sql.NullString) *ResponderRotation {
+    if !stored.Valid {
+        return nil
+    }
+    var rotation ResponderRotation
+    if err := json.Unmarshal([]byte(stored.String), &rotation); err != nil || len(rotation.Users) == 0 {
+        return nil
+    }
+    if _, err := time.ParseDuration(rotation.Shift); err != nil {
+        return nil
+    }
+    return &rotation
+}
+
+// Current returns who is on shift at now. Times before the start count as
+// earlier turns of the same rotation.
+func (r *ResponderRotation) Current(now time.Time) FirstResponder {
+    shift, _ := time.ParseDuration(r.Shift)
+    turn := int64(now.Sub(r.StartsAt) / shift)
+    if now.Before(r.StartsAt) && now.Sub(r.StartsAt)%shift != 0 {
+        turn--
+    }
+    index := turn % int64(len(r.Users))
+    if index < 0 {
+        index += int64(len(r.Users))
+    }
+    return FirstResponder{
+        UserID: r.Users[index],
+        Until:  r.StartsAt.Add(time.Duration(turn+1) * shift),
+    }
+}
+
+// SetChannelResponderRotation - Set or, with an empty user list, remove the first-responder rotation of a channel
+func (c *Container) SetChannelResponderRotation(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var rotation ResponderRotation
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&rotation); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    now := time.Now().UTC()
+    if msg := rotation.validate(now); msg != "" {
+        return apierror.New(http.StatusBadRequest, msg)
+    }
+
+    err := c.configs.SetChannelConfig(ctx.Request().Context(), channelID, ChannelConfig{ResponderRotation: &rotation}, settingResponderRotation)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.responder_rotation", channelID, "", map[string]interface{}{
+        "responder_rotation": rotation,
+    })
+
+    response := map[string]interface{}{
+        "channel_id":         channelID,
+        "responder_rotation": nil,
+        "first_responder":    nil,
+    }
+    if len(rotation.Users) > 0 {
+        response["responder_rotation"] = rotation
+        response["first_responder"] = rotation.Current(now)
+    }
+    return ctx.JSON(http.StatusOK, response)
+}
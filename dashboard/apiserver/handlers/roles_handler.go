@@ -0,0 +1,163 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+)
+
+// UserRole is the role assigned to a user
+type UserRole struct {
+    UserID    string    `json:"user_id"`
+    Role      auth.Role `json:"role"`
+    GrantedBy string    `json:"granted_by"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserRoles lists the assigned roles and the role of everyone else
+type UserRoles struct {
+    DefaultRole auth.Role  `json:"default_role"`
+    Items       []UserRole `json:"items"`
+}
+
+// SetRoleRequest is the body accepted by SetUserRole
+type SetRoleRequest struct {
+    Role string `json:"role"`
+}
+
+// LookupRole returns the role of a user, the default role when none is
+// assigned. It satisfies auth.RoleLookup.
+func (c *Container) LookupRole(userID string) (auth.Role, error) {
+    stored, err := c.users.Role(context.Background(), userID)
+    if err != nil {
+        return "", err
+    }
+    if stored == "" {
+        return c.defaultRole, nil
+    }
+    role, err := auth.ParseRole(stored)
+    if err != nil {
+        // Never grant more than the least role for a role we do not know
+        c.logger.Warnf("treating %s as a viewer: %v", userID, err)
+        return auth.RoleViewer, nil
+    }
+    return role, nil
+}
+
+// canTriage reports whether a Slack user may change threads from Slack,
+// holding at least the triager role. Users whose role cannot be looked up
+// may not.
+func (c *Container) canTriage(userID string) bool {
+    role, err := c.LookupRole(userID)
+    if err != nil {
+        c.logger.Errorf("failed to look up role of %s: %v", userID, err)
+        return false
+    }
+    return role.Scope().Implies(auth.ScopeTriage)
+}
+
+// GetUserRoles - List the users with an assigned role
+func (c *Container) GetUserRoles(ctx echo.Context) error {
+    items, err := c.users.Roles(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query roles")
+    }
+
+    return ctx.JSON(http.StatusOK, UserRoles{DefaultRole: c.defaultRole, Items: items})
+}
+
+// SetUserRole - Assign a role to a user
+func (c *Container) SetUserRole(ctx echo.Context) error {
+    userID := ctx.Param("user_id")
+
+    var req SetRoleRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    role, err := auth.ParseRole(req.Role)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+
+    // Admins demoting themselves could leave nobody to undo it
+    actor := actorFrom(ctx)
+    if actor == userID && role != auth.RoleAdmin {
+        return apierror.New(http.StatusConflict, "You cannot change your own role")
+    }
+
+    assigned, err := c.users.AssignRole(ctx.Request().Context(), userID, role, actor)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to assign role")
+    }
+
+    c.audit(sharedContext(ctx.Request().Context()), actor, "user.role", "", "", map[string]interface{}{
+        "user_id": userID,
+        "role":    role,
+    })
+
+    return ctx.JSON(http.StatusOK, assigned)
+}
+
+// DeleteUserRole - Return a user to the default role
+func (c *Container) DeleteUserRole(ctx echo.Context) error {
+    userID := ctx.Param("user_id")
+
+    actor := actorFrom(ctx)
+    if actor == userID && c.defaultRole != auth.RoleAdmin {
+        return apierror.New(http.StatusConflict, "You cannot change your own role")
+    }
+
+    removed, err := c.users.RemoveRole(ctx.Request().Context(), userID)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to remove role")
+    }
+    if !removed {
+        return apierror.New(http.StatusNotFound, "User has no assigned role")
+    }
+
+    c.audit(sharedContext(ctx.Request().Context()), actor, "user.role", "", "", map[string]interface{}{
+        "user_id": userID,
+        "role":    c.defaultRole,
+    })
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+func (s postgresStore) AssignRole(ctx context.Context, userID string, role auth.Role, actor string) (UserRole, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return UserRole{}, err
+    }
+
+    var assigned UserRole
+    err = db.QueryRowContext(ctx, `
+        INSERT INTO user_roles (user_id, role, granted_by, updated_at)
+        VALUES ($1, $2, $3, NOW())
+        ON CONFLICT (user_id) DO UPDATE SET
+            role = EXCLUDED.role,
+            granted_by = EXCLUDED.granted_by,
+            updated_at = EXCLUDED.updated_at
+        RETURNING user_id, role, granted_by, updated_at
+    `, userID, string(role), actor).Scan(&assigned.UserID, &assigned.Role, &assigned.GrantedBy, &assigned.UpdatedAt)
+    return assigned, err
+}
+
+func (s postgresStore) RemoveRole(ctx context.Context, userID string) (bool, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return false, err
+    }
+
+    result, err := db.ExecContext(ctx, "DELETE FROM user_roles WHERE user_id = $1", userID)
+    if err != nil {
+        return false, err
+    }
+    n, err := result.RowsAffected()
+    return n > 0, err
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+    "net/http"
+    "testing"
+
+    "dashboard/apiserver/auth"
+)
+
+func TestLookupRole(t *testing.T) {
+    store := NewMemoryStore()
+    store.SetRole("UVIEWER", "viewer")
+    store.SetRole("UTRIAGER", "Triager")
+    store.SetRole("UBROKEN", "superuser")
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.defaultRole = auth.RoleTriager
+
+    tests := []struct {
+        userID    string
+        want      auth.Role
+        canTriage bool
+    }{
+        {"UVIEWER", auth.RoleViewer, false},
+        {"UTRIAGER", auth.RoleTriager, true},
+        {"UNONE", auth.RoleTriager, true},
+        // A role that cannot be parsed grants the least
+        {"UBROKEN", auth.RoleViewer, false},
+    }
+    for _, tt := range tests {
+        role, err := c.LookupRole(tt.userID)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if role != tt.want {
+            t.Errorf("LookupRole(%s) = %s, want %s", tt.userID, role, tt.want)
+        }
+        if got := c.canTriage(tt.userID); got != tt.canTriage {
+            t.Errorf("canTriage(%s) = %v, want %v", tt.userID, got, tt.canTriage)
+        }
+    }
+}
+
+func TestGetUserRoles(t *testing.T) {
+    store := NewMemoryStore()
+    store.SetRole("UTRIAGER", "triager")
+    store.SetRole("UADMIN", "admin")
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.defaultRole = auth.RoleViewer
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/admin/roles", "/api/admin/roles", c.GetUserRoles)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var roles UserRoles
+    decodeResponse(t, rec, &roles)
+    if roles.DefaultRole != auth.RoleViewer {
+        t.Fatalf("got default role %s, want viewer", roles.DefaultRole)
+    }
+    if len(roles.Items) != 2 || roles.Items[0].UserID != "UADMIN" || roles.Items[1].Role != auth.RoleTriager {
+        t.Fatalf("got roles %+v, want them sorted by user", roles.Items)
+    }
+}
+
+func TestSetAndDeleteUserRole(t *testing.T) {
+    store := NewMemoryStore()
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.defaultRole = auth.RoleViewer
+    admin := &auth.Principal{UserID: "UADMIN", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+
+    rec := serveAs(c, admin, http.MethodPut, "/api/admin/roles/:user_id", "/api/admin/roles/U2", `{"role": "triager"}`, c.SetUserRole)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("assigning the role got status %d: %s", rec.Code, rec.Body.String())
+    }
+    if role, _ := c.LookupRole("U2"); role != auth.RoleTriager {
+        t.Fatalf("got role %s after assigning triager", role)
+    }
+    rec = serveAs(c, admin, http.MethodPut, "/api/admin/roles/:user_id", "/api/admin/roles/UADMIN", `{"role": "viewer"}`, c.SetUserRole)
+    if rec.Code != http.StatusConflict {
+        t.Fatalf("demoting yourself got status %d", rec.Code)
+    }
+
+    if rec := serveAs(c, admin, http.MethodDelete, "/api/admin/roles/:user_id", "/api/admin/roles/U2", "", c.DeleteUserRole); rec.Code != http.StatusNoContent {
+        t.Fatalf("removing the role got status %d", rec.Code)
+    }
+    if role, _ := c.LookupRole("U2"); role != auth.RoleViewer {
+        t.Fatalf("got role %s after removing it, want the default", role)
+    }
+    if rec := serveAs(c, admin, http.MethodDelete, "/api/admin/roles/:user_id", "/api/admin/roles/U2", "", c.DeleteUserRole); rec.Code != http.StatusNotFound {
+        t.Fatalf("removing a missing role got status %d", rec.Code)
+    }
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+    "context"
+
+    "dashboard/apiserver/migrations"
+)
+
+// EnsureSchema applies pending schema migrations, in the shared database and
+// every workspace database.
+func (c *Container) EnsureSchema() error {
+    for _, ctx := range c.WorkspaceContexts(context.Background()) {
+        db, err := c.workspaceDB(ctx)
+        if err != nil {
+            return err
+        }
+
+        name := "database"
+        if team := workspaceFrom(ctx); team != "" {
+            name = "database of workspace " + team
+        }
+        applied, err := migrations.Apply(ctx, migrations.Postgres(db))
+        for _, m := range applied {
+            c.logger.Infof("applied migration %d (%s) to the %s", m.Version, m.Name, name)
+        }
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// PendingMigrations returns how many migrations the shared and workspace
+// databases have not seen yet.
+func (c *Container) PendingMigrations() (int, error) {
+    count := 0
+    for _, ctx := range c.WorkspaceContexts(context.Background()) {
+        db, err := c.workspaceDB(ctx)
+        if err != nil {
+            return 0, err
+        }
+
+        pending, err := migrations.Pending(ctx, migrations.Postgres(db))
+        if err != nil {
+            return 0, err
+        }
+        count += len(pending)
+    }
+    return count, nil
+}
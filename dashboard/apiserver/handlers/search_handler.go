@@ -0,0 +1,162 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+// threadSearchDocument is the text of a thread searched by SearchThreads,
+// matches in the name ranking above the issue and then the description.
+const threadSearchDocument = `setweight(to_tsvector('english', COALESCE(t.ai_thread_name, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(t.thread_issue, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(t.ai_description, '')), 'C')`
+
+// ThreadMatch is a thread found by a search with the relevance of the match
+type ThreadMatch struct {
+    Thread
+    Rank float64 `json:"rank"`
+}
+
+// threadSearch searches the text of threads, narrowed down to a channel
+// by name and a status when set.
+type threadSearch struct {
+    query   string
+    channel string
+    status  string
+    limit   int
+}
+
+// ThreadSearchResults are the best matches of a search
+type ThreadSearchResults struct {
+    Query string        `json:"query"`
+    Items []ThreadMatch `json:"items"`
+}
+
+// SearchThreads - Search thread names, descriptions and issues across channels, best matches first
+func (c *Container) SearchThreads(ctx echo.Context) error {
+    q := strings.TrimSpace(ctx.QueryParam("q"))
+    if q == "" {
+        return apierror.New(http.StatusBadRequest, "q is required")
+    }
+    limit := 20
+    if limitStr := ctx.QueryParam("limit"); limitStr != "" {
+        if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+            limit = parsedLimit
+        }
+    }
+    if limit > maxThreadPageSize {
+        limit = maxThreadPageSize
+    }
+    channel := ctx.QueryParam("channel")
+    status := ctx.QueryParam("status")
+
+    matches, err := c.threadLists.SearchThreads(ctx.Request().Context(), threadSearch{query: q, channel: channel, status: status, limit: limit})
+    if err != nil {
+        c.logger.Errorf("failed to search threads: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to search threads")
+    }
+
+    return ctx.JSON(http.StatusOK, ThreadSearchResults{Query: q, Items: matches})
+}
+
+func (s postgresStore) SearchThreads(ctx context.Context, search threadSearch) ([]ThreadMatch, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    holds, err := loadActiveHolds(db)
+    if err != nil {
+        return nil, err
+    }
+
+    // Channels that opted out of text indexing are never searched
+    channelRows, err := db.QueryContext(ctx, `
+        SELECT ch.channel_id, ch.channel_name, cc.heat_thresholds, cc.priority_calibration,
+               cc.sla_hours
+        FROM channels ch
+        LEFT JOIN channel_config cc ON cc.channel_id = ch.channel_id
+        WHERE COALESCE(cc.text_indexing, TRUE)
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer channelRows.Close()
+
+    args := []interface{}{search.query}
+    bind := func(value interface{}) string {
+        args = append(args, value)
+        return fmt.Sprintf("$%d", len(args))
+    }
+    channels := make(map[string]threadListChannel)
+    var channelIDs []string
+    var minConfidences []float64
+    for channelRows.Next() {
+        var channelID, channelName string
+        var storedThresholds, storedCalibration, storedSLA sql.NullString
+        if err := channelRows.Scan(&channelID, &channelName, &storedThresholds, &storedCalibration, &storedSLA); err != nil {
+            continue
+        }
+        if search.channel != "" && channelName != search.channel {
+            continue
+        }
+
+        channels[channelID] = threadListChannel{
+            name:         channelName,
+            textIndexing: true,
+            thresholds:   parseHeatThresholds(storedThresholds),
+            slaHours:     parseSLAHours(storedSLA),
+        }
+        channelIDs = append(channelIDs, channelID)
+        minConfidences = append(minConfidences, parsePriorityCalibration(storedCalibration).MinConfidence)
+    }
+    channelRows.Close()
+
+    matches := []ThreadMatch{}
+    if len(channelIDs) == 0 {
+        return matches, nil
+    }
+
+    query := fmt.Sprintf(`SELECT u.* FROM (
+            SELECT %s,
+                   %s,
+                   ts_rank(%s, tsq) AS rank
+            FROM threads t
+            JOIN unnest(%s::TEXT[], %s::FLOAT8[]) AS cal(channel_id, min_confidence) ON cal.channel_id = t.channel_id,
+            websearch_to_tsquery('english', $1) tsq
+            WHERE (%s) @@ tsq
+        ) u WHERE 1=1`, threadListColumns, priorityColumn("cal.min_confidence"), threadSearchDocument,
+        bind(pq.Array(channelIDs)), bind(pq.Array(minConfidences)), threadSearchDocument)
+    if search.status != "" {
+        query += " AND u.status = " + bind(search.status)
+    }
+    query += " ORDER BY u.rank DESC, u.latest_reply DESC LIMIT " + bind(search.limit)
+
+    rows, err := db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    now := time.Now()
+    for rows.Next() {
+        var match ThreadMatch
+        var dueOverride sql.NullTime
+        if err := rows.Scan(append(threadListDest(&match.Thread, &dueOverride), &match.Rank)...); err != nil {
+            continue
+        }
+        channels[match.ChannelID].present(&match.Thread, holds, dueOverride, now)
+        matches = append(matches, match)
+    }
+    return matches, rows.Err()
+}
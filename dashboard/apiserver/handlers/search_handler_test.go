@@ -0,0 +1,35 @@
+package handlers
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+)
+
+func TestSearchSkipsChannelsWithoutTextIndexing(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "legal"}})
+    now := time.Now()
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, LatestReply: now}, Title: "Login fails after reset"})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: statusOpen, LatestReply: now}, Title: "Billing question"})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C2", ThreadTS: "1700000000.000300", Status: statusOpen, LatestReply: now}, Title: "Login audit"})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/search", "/api/search?q=login", c.SearchThreads)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var results ThreadSearchResults
+    decodeResponse(t, rec, &results)
+    if len(results.Items) != 1 || results.Items[0].ThreadTS != "1700000000.000100" {
+        t.Fatalf("got %+v, want only the thread of the indexed channel", results.Items)
+    }
+
+    rec = serveRequest(t, c, http.MethodGet, "/api/search", "/api/search", c.SearchThreads)
+    if rec.Code != http.StatusBadRequest {
+        t.Fatalf("a search without q got status %d", rec.Code)
+    }
+}
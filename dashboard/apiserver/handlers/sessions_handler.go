@@ -0,0 +1,320 @@
+package handlers
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/subtle"
+    "database/sql"
+    "encoding/base64"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+)
+
+// loginStateCookie carries the state of a sign-in in progress and where to
+// go once it completes.
+const loginStateCookie = "otr_login_state"
+
+// loginStateTTL is how long users have to complete the provider's sign-in.
+const loginStateTTL = 10 * time.Minute
+
+// Me is the caller of the API as shown by the dashboard
+type Me struct {
+    Authenticated bool       `json:"authenticated"`
+    UserID        string     `json:"user_id,omitempty"`
+    Name          string     `json:"name,omitempty"`
+    Email         string     `json:"email,omitempty"`
+    Method        string     `json:"method,omitempty"`
+    Role          auth.Role  `json:"role,omitempty"`
+    Scopes        []string   `json:"scopes"`
+    ExpiresAt     *time.Time `json:"session_expires_at,omitempty"`
+    // LoginURL is set when users can sign in to the dashboard
+    LoginURL string `json:"login_url,omitempty"`
+}
+
+// SignInConfigured reports whether users can sign in to the dashboard.
+func (c *Container) SignInConfigured() bool {
+    return c.oidc != nil
+}
+
+// callbackURL is where the provider sends users back to.
+func (c *Container) callbackURL(ctx echo.Context) string {
+    if c.publicURL != "" {
+        return c.publicURL + "/auth/callback"
+    }
+    return ctx.Scheme() + "://" + ctx.Request().Host + "/auth/callback"
+}
+
+// secureCookies reports whether cookies must only travel over HTTPS.
+func (c *Container) secureCookies(ctx echo.Context) bool {
+    return strings.HasPrefix(c.publicURL, "https://") || ctx.Scheme() == "https"
+}
+
+// localRedirect keeps redirects after sign-in on the dashboard.
+func localRedirect(target string) string {
+    if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.Contains(target, `\`) {
+        return "/"
+    }
+    return target
+}
+
+// signInAllowed reports why an identity may not use the dashboard, empty
+// when it may. Nobody may sign in without a workspace or domain
+// restriction, which NewContainer already refuses to start without.
+func (c *Container) signInAllowed(identity *auth.Identity) string {
+    if c.signInTeam == "" && len(c.signInDomains) == 0 {
+        return "sign-in is not restricted to a workspace or domain"
+    }
+    if c.signInTeam != "" && identity.Claim(auth.SlackTeamClaim) != c.signInTeam {
+        return "user of another Slack workspace"
+    }
+    if len(c.signInDomains) > 0 {
+        _, domain, _ := strings.Cut(strings.ToLower(identity.Email), "@")
+        for _, allowed := range c.signInDomains {
+            if domain == allowed {
+                return ""
+            }
+        }
+        return "email domain not allowed"
+    }
+    return ""
+}
+
+// Login - Start signing in with the configured provider, then return to the redirect parameter
+func (c *Container) Login(ctx echo.Context) error {
+    if !c.SignInConfigured() {
+        return apierror.New(http.StatusNotFound, "Sign-in is not configured")
+    }
+
+    buf := make([]byte, 24)
+    if _, err := rand.Read(buf); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to start sign-in")
+    }
+    state := base64.RawURLEncoding.EncodeToString(buf)
+
+    target, err := c.oidc.AuthCodeURL(ctx.Request().Context(), c.callbackURL(ctx), state)
+    if err != nil {
+        c.logger.Errorf("failed to discover sign-in provider: %v", err)
+        return apierror.New(http.StatusBadGateway, "Sign-in provider is unavailable")
+    }
+
+    ctx.SetCookie(&http.Cookie{
+        Name: loginStateCookie,
+        Value: url.Values{
+            "state":    {state},
+            "redirect": {localRedirect(ctx.QueryParam("redirect"))},
+        }.Encode(),
+        Path:     "/auth",
+        MaxAge:   int(loginStateTTL.Seconds()),
+        HttpOnly: true,
+        Secure:   c.secureCookies(ctx),
+        SameSite: http.SameSiteLaxMode,
+    })
+    return ctx.Redirect(http.StatusFound, target)
+}
+
+// LoginCallback - Complete a sign-in started by Login, opening a session
+func (c *Container) LoginCallback(ctx echo.Context) error {
+    if !c.SignInConfigured() {
+        return apierror.New(http.StatusNotFound, "Sign-in is not configured")
+    }
+
+    cookie, err := ctx.Cookie(loginStateCookie)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "Sign-in expired, please try again")
+    }
+    ctx.SetCookie(&http.Cookie{Name: loginStateCookie, Path: "/auth", MaxAge: -1})
+    stored, err := url.ParseQuery(cookie.Value)
+    state := ctx.QueryParam("state")
+    if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(stored.Get("state")), []byte(state)) != 1 {
+        return apierror.New(http.StatusBadRequest, "Sign-in state mismatch, please try again")
+    }
+    if reason := ctx.QueryParam("error"); reason != "" {
+        c.RecordLoginEvent(auth.LoginEvent{
+            RemoteIP: ctx.RealIP(), UserAgent: ctx.Request().UserAgent(),
+            Method: auth.MethodSession, Reason: "provider: " + reason,
+        })
+        return apierror.New(http.StatusUnauthorized, "Sign-in was cancelled or denied")
+    }
+
+    identity, err := c.oidc.Exchange(ctx.Request().Context(), c.callbackURL(ctx), ctx.QueryParam("code"))
+    if err != nil {
+        c.logger.Errorf("failed to complete sign-in: %v", err)
+        return apierror.New(http.StatusBadGateway, "Failed to complete sign-in")
+    }
+    if reason := c.signInAllowed(identity); reason != "" {
+        c.RecordLoginEvent(auth.LoginEvent{
+            UserID: identity.UserID, RemoteIP: ctx.RealIP(), UserAgent: ctx.Request().UserAgent(),
+            Method: auth.MethodSession, Reason: reason,
+        })
+        return apierror.New(http.StatusForbidden, "This account may not use the dashboard")
+    }
+
+    token, hash, err := auth.GenerateSessionToken()
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to open session")
+    }
+    expiresAt := time.Now().UTC().Add(c.sessionTTL)
+    err = c.sessions.OpenSession(ctx.Request().Context(), hash, identity, ctx.RealIP(), ctx.Request().UserAgent(), expiresAt)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to open session")
+    }
+
+    c.RecordLoginEvent(auth.LoginEvent{
+        UserID: identity.UserID, RemoteIP: ctx.RealIP(), UserAgent: ctx.Request().UserAgent(),
+        Method: auth.MethodSession, Success: true,
+    })
+
+    ctx.SetCookie(&http.Cookie{
+        Name:     auth.SessionCookie,
+        Value:    token,
+        Path:     "/",
+        Expires:  expiresAt,
+        HttpOnly: true,
+        Secure:   c.secureCookies(ctx),
+        SameSite: http.SameSiteLaxMode,
+    })
+    return ctx.Redirect(http.StatusFound, localRedirect(stored.Get("redirect")))
+}
+
+// Logout - End the caller's session
+func (c *Container) Logout(ctx echo.Context) error {
+    if cookie, err := ctx.Cookie(auth.SessionCookie); err == nil {
+        if err := c.sessions.EndSession(ctx.Request().Context(), auth.HashToken(cookie.Value)); err != nil {
+            return apierror.New(http.StatusInternalServerError, "Failed to end session")
+        }
+    }
+
+    ctx.SetCookie(&http.Cookie{
+        Name:     auth.SessionCookie,
+        Path:     "/",
+        MaxAge:   -1,
+        HttpOnly: true,
+        Secure:   c.secureCookies(ctx),
+        SameSite: http.SameSiteLaxMode,
+    })
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// LookupSession resolves a session cookie hash to the signed in user. It
+// satisfies auth.SessionLookup.
+func (c *Container) LookupSession(hash string) (*auth.Principal, error) {
+    return c.sessions.LookupSession(context.Background(), hash)
+}
+
+// PruneSessions deletes expired sessions.
+func (c *Container) PruneSessions() {
+    if err := c.sessions.PruneSessions(context.Background()); err != nil {
+        c.logger.Warnf("failed to prune expired sessions: %v", err)
+    }
+}
+
+// GetMe - Get the signed in user, or how to sign in
+func (c *Container) GetMe(ctx echo.Context) error {
+    me := Me{Scopes: []string{}}
+    if c.SignInConfigured() {
+        me.LoginURL = "/auth/login"
+    }
+
+    principal, ok := auth.PrincipalFrom(ctx)
+    if !ok {
+        return ctx.JSON(http.StatusOK, me)
+    }
+    me.Authenticated = true
+    me.UserID = principal.UserID
+    me.Name = principal.Name
+    me.Email = principal.Email
+    me.Method = principal.Method
+    me.Role = principal.Role
+    for _, scope := range principal.Scopes {
+        me.Scopes = append(me.Scopes, string(scope))
+    }
+
+    if principal.Method == auth.MethodSession {
+        if cookie, err := ctx.Cookie(auth.SessionCookie); err == nil {
+            if expiresAt, err := c.sessions.SessionExpiry(ctx.Request().Context(), auth.HashToken(cookie.Value)); err == nil {
+                me.ExpiresAt = expiresAt
+            }
+        }
+    }
+
+    return ctx.JSON(http.StatusOK, me)
+}
+
+func (s postgresStore) OpenSession(ctx context.Context, hash string, identity *auth.Identity, remoteIP string, userAgent string, expiresAt time.Time) error {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return err
+    }
+
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO sessions (session_hash, user_id, name, email, remote_ip, user_agent, created_at, last_seen_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW(), $7)
+    `, hash, identity.UserID, identity.Name, identity.Email, remoteIP, userAgent, expiresAt)
+    return err
+}
+
+func (s postgresStore) EndSession(ctx context.Context, hash string) error {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return err
+    }
+
+    _, err = db.ExecContext(ctx, "DELETE FROM sessions WHERE session_hash = $1", hash)
+    return err
+}
+
+func (s postgresStore) LookupSession(ctx context.Context, hash string) (*auth.Principal, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+
+    // The authenticator narrows the scopes down to the user's role
+    principal := &auth.Principal{Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+    err = db.QueryRowContext(ctx, `
+        UPDATE sessions SET last_seen_at = NOW()
+        WHERE session_hash = $1 AND expires_at > NOW()
+        RETURNING user_id, name, email
+    `, hash).Scan(&principal.UserID, &principal.Name, &principal.Email)
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return principal, nil
+}
+
+func (s postgresStore) SessionExpiry(ctx context.Context, hash string) (*time.Time, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+
+    var expiresAt time.Time
+    err = db.QueryRowContext(ctx, "SELECT expires_at FROM sessions WHERE session_hash = $1", hash).Scan(&expiresAt)
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &expiresAt, nil
+}
+
+func (s postgresStore) PruneSessions(ctx context.Context) error {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return err
+    }
+
+    _, err = db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= NOW()")
+    return err
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+)
+
+// newTestProvider serves an OpenID Connect provider signing in the user
+// info of the code it issued, and records the codes redeemed.
+func newTestProvider(t *testing.T, code string, userInfo map[string]interface{}) (*httptest.Server, *[]string) {
+    t.Helper()
+    redeemed := []string{}
+    mux := http.NewServeMux()
+    server := httptest.NewServer(mux)
+    t.Cleanup(server.Close)
+    mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+        json.NewEncoder(w).Encode(map[string]string{
+            "authorization_endpoint": server.URL + "/authorize",
+            "token_endpoint":         server.URL + "/token",
+            "userinfo_endpoint":      server.URL + "/userinfo",
+        })
+    })
+    mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+        redeemed = append(redeemed, r.FormValue("code"))
+        if r.FormValue("code") != code || r.FormValue("client_secret") != "secret" {
+            json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": "invalid_code"})
+            return
+        }
+        json.NewEncoder(w).Encode(map[string]string{"access_token": "access-" + code})
+    })
+    mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("Authorization") != "Bearer access-"+code {
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        json.NewEncoder(w).Encode(userInfo)
+    })
+    return server, &redeemed
+}
+
+func TestLoginCallbackChecksState(t *testing.T) {
+    provider, redeemed := newTestProvider(t, "code-1", map[string]interface{}{
+        "sub":               "sub-1",
+        auth.SlackUserClaim: "U1",
+        auth.SlackTeamClaim: "T1",
+        "name":              "Alice",
+        "email":             "alice@example.com",
+    })
+    store := NewMemoryStore()
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.publicURL = "https://threads.example.com"
+    c.sessionTTL = time.Hour
+    c.oidc = auth.NewOIDCProvider(auth.OIDCConfig{
+        Issuer:       provider.URL,
+        ClientID:     "client",
+        ClientSecret: "secret",
+        UserClaim:    auth.SlackUserClaim,
+    })
+
+    e := echo.New()
+    e.HTTPErrorHandler = apierror.Handler(c.logger)
+    e.GET("/auth/login", c.Login)
+    e.GET("/auth/callback", c.LoginCallback)
+    serve := func(target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(http.MethodGet, target, nil)
+        for _, cookie := range cookies {
+            req.AddCookie(cookie)
+        }
+        rec := httptest.NewRecorder()
+        e.ServeHTTP(rec, req)
+        return rec
+    }
+    cookie := func(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+        for _, cookie := range rec.Result().Cookies() {
+            if cookie.Name == name {
+                return cookie
+            }
+        }
+        return nil
+    }
+
+    // login starts a sign-in returning to redirect and gives its state
+    login := func(redirect string) (*http.Cookie, string) {
+        t.Helper()
+        rec := serve("/auth/login?redirect=" + url.QueryEscape(redirect))
+        if rec.Code != http.StatusFound {
+            t.Fatalf("login got status %d: %s", rec.Code, rec.Body.String())
+        }
+        location, err := url.Parse(rec.Header().Get("Location"))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !strings.HasPrefix(location.String(), provider.URL+"/authorize?") ||
+            location.Query().Get("redirect_uri") != "https://threads.example.com/auth/callback" {
+            t.Fatalf("login redirected to %s", location)
+        }
+        state := cookie(rec, loginStateCookie)
+        if state == nil || !state.HttpOnly || !state.Secure {
+            t.Fatalf("got state cookie %+v", state)
+        }
+        return state, location.Query().Get("state")
+    }
+
+    stateCookie, state := login("/threads?status=open")
+    otherCookie, otherState := login("/")
+    if state == "" || state == otherState {
+        t.Fatalf("sign-ins share state %q", state)
+    }
+
+    tests := []struct {
+        name    string
+        target  string
+        cookies []*http.Cookie
+        want    int
+    }{
+        {"no sign-in in progress", "/auth/callback?code=code-1&state=" + state, nil, http.StatusBadRequest},
+        {"state of another sign-in", "/auth/callback?code=code-1&state=" + otherState, []*http.Cookie{stateCookie}, http.StatusBadRequest},
+        {"missing state", "/auth/callback?code=code-1", []*http.Cookie{stateCookie}, http.StatusBadRequest},
+        {"forged state cookie", "/auth/callback?code=code-1&state=forged", []*http.Cookie{{Name: loginStateCookie, Value: "redirect=%2F"}}, http.StatusBadRequest},
+        {"denied by the provider", "/auth/callback?error=access_denied&state=" + otherState, []*http.Cookie{otherCookie}, http.StatusUnauthorized},
+        {"code not issued", "/auth/callback?code=code-2&state=" + state, []*http.Cookie{stateCookie}, http.StatusBadGateway},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rec := serve(tt.target, tt.cookies...)
+            if rec.Code != tt.want {
+                t.Fatalf("got status %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+            }
+            if session := cookie(rec, auth.SessionCookie); session != nil {
+                t.Fatalf("opened session %q", session.Value)
+            }
+        })
+    }
+    // Only the code of the state checked sign-in reached the provider
+    if len(*redeemed) != 1 || (*redeemed)[0] != "code-2" {
+        t.Fatalf("provider redeemed codes %v", *redeemed)
+    }
+
+    c.signInTeam = "T2"
+    if rec := serve("/auth/callback?code=code-1&state="+state, stateCookie); rec.Code != http.StatusForbidden {
+        t.Fatalf("user of another workspace got status %d", rec.Code)
+    }
+    c.signInTeam = "T1"
+
+    rec := serve("/auth/callback?code=code-1&state="+state, stateCookie)
+    if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/threads?status=open" {
+        t.Fatalf("got status %d to %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+    }
+    session := cookie(rec, auth.SessionCookie)
+    if session == nil || !strings.HasPrefix(session.Value, auth.SessionPrefix) || !session.HttpOnly {
+        t.Fatalf("got session cookie %+v", session)
+    }
+    if principal, err := store.LookupSession(context.Background(), auth.HashToken(session.Value)); err != nil || principal == nil || principal.UserID != "U1" {
+        t.Fatalf("cookie hash resolved to %+v, %v, want the session of U1", principal, err)
+    }
+    if cleared := cookie(rec, loginStateCookie); cleared == nil || cleared.MaxAge >= 0 {
+        t.Fatalf("state cookie not cleared: %+v", cleared)
+    }
+
+    logins, err := store.LoginAudit(context.Background(), loginAuditFilter{limit: 100})
+    if err != nil {
+        t.Fatal(err)
+    }
+    succeeded := 0
+    for _, event := range logins {
+        if event.Success {
+            succeeded++
+        }
+    }
+    if len(logins) != 3 || succeeded != 1 {
+        t.Fatalf("got login events %+v, want the denial, the other workspace and the sign-in", logins)
+    }
+}
+
+func TestSignInAllowed(t *testing.T) {
+    member := &auth.Identity{UserID: "U1", Email: "alice@example.com", Claims: map[string]interface{}{auth.SlackTeamClaim: "T1"}}
+    foreign := &auth.Identity{UserID: "U2", Email: "bob@example.org", Claims: map[string]interface{}{auth.SlackTeamClaim: "T2"}}
+    unclaimed := &auth.Identity{UserID: "U3", Email: "carol@example.com"}
+
+    tests := []struct {
+        name     string
+        team     string
+        domains  []string
+        identity *auth.Identity
+        allowed  bool
+    }{
+        {"member of the workspace", "T1", nil, member, true},
+        {"user of another workspace", "T1", nil, foreign, false},
+        {"identity without a workspace", "T1", nil, unclaimed, false},
+        {"allowed domain", "", []string{"example.com"}, unclaimed, true},
+        {"other domain", "", []string{"example.com"}, foreign, false},
+        {"workspace and other domain", "T2", []string{"example.com"}, foreign, false},
+        {"no restriction", "", nil, member, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := newTestContainer(t, NewMemoryStore(), &MemorySlack{})
+            c.signInTeam = tt.team
+            c.signInDomains = tt.domains
+            if reason := c.signInAllowed(tt.identity); (reason == "") != tt.allowed {
+                t.Fatalf("got reason %q, want allowed %v", reason, tt.allowed)
+            }
+        })
+    }
+}
+
+func TestLocalRedirect(t *testing.T) {
+    tests := map[string]string{
+        "/threads?status=open":   "/threads?status=open",
+        "":                       "/",
+        "https://evil.example":   "/",
+        "//evil.example/threads": "/",
+        `/\evil.example`:         "/",
+        "javascript:alert(1)":    "/",
+    }
+    for target, want := range tests {
+        if got := localRedirect(target); got != want {
+            t.Errorf("localRedirect(%q) = %q, want %q", target, got, want)
+        }
+    }
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+    "database/sql"
+    "encoding/json"
+)
+
+// getSetting decodes the JSON value of a dashboard setting into out. It
+// reports false when the setting was never stored.
+func getSetting(db *sql.DB, key string, out interface{}) (bool, error) {
+    var value string
+    err := db.QueryRow("SELECT value FROM settings WHERE key = $1", key).Scan(&value)
+    if err == sql.ErrNoRows {
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+    return true, json.Unmarshal([]byte(value), out)
+}
+
+// putSetting stores value as the JSON value of a dashboard setting.
+func putSetting(db *sql.DB, key string, value interface{}, actor string) error {
+    encoded, err := json.Marshal(value)
+    if err != nil {
+        return err
+    }
+    _, err = db.Exec(`
+        INSERT INTO settings (key, value, updated_by, updated_at)
+        VALUES ($1, $2, $3, NOW())
+        ON CONFLICT (key) DO UPDATE SET
+            value = EXCLUDED.value,
+            updated_by = EXCLUDED.updated_by,
+            updated_at = EXCLUDED.updated_at
+    `, key, string(encoded), actor)
+    return err
+}
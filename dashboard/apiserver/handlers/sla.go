@@ -0,0 +1,126 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+// ThreadSLARequest is the body accepted by SetThreadSLA. Either DueAt or
+// Hours, counted from the creation of the thread, overrides its SLA.
+// Sending neither removes the override.
+type ThreadSLARequest struct {
+    DueAt *time.Time `json:"due_at"`
+    Hours *float64   `json:"hours"`
+}
+
+// forDue scales the thresholds so that a thread created at createdAt turns
+// red at dueAt, keeping the proportions of the channel's heat steps.
+func (t HeatThresholds) forDue(createdAt time.Time, dueAt time.Time) HeatThresholds {
+    red := dueAt.Sub(createdAt).Hours()
+    if red <= 0 {
+        return HeatThresholds{}
+    }
+    scale := red / t.RedHours
+    return HeatThresholds{YellowHours: t.YellowHours * scale, OrangeHours: t.OrangeHours * scale, RedHours: red}
+}
+
+// ApplySLA sets the due date, breach and heat of a thread. A thread is due
+// when its channel turns it red, unless override sets its own due date.
+// Time spent waiting on the author pushes either due date back.
+func (t *Thread) ApplySLA(thresholds HeatThresholds, override *time.Time, now time.Time) {
+    paused := time.Duration(t.SLAPausedSeconds * float64(time.Second))
+    started := t.CreatedAt.Add(paused)
+    dueAt := started.Add(time.Duration(thresholds.RedHours * float64(time.Hour)))
+    t.SLAOverride = override != nil
+    if override != nil {
+        dueAt = override.Add(paused)
+        thresholds = thresholds.forDue(started, dueAt)
+    }
+    t.DueAt = &dueAt
+    t.SLABreached = t.Status == domain.StatusOpen && !now.Before(dueAt)
+    t.Heat = thresholds.Heat(t.Status, started, now)
+}
+
+// SetThreadSLA - Override the due date of a single thread, or remove the override
+func (c *Container) SetThreadSLA(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req ThreadSLARequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if req.DueAt != nil && req.Hours != nil {
+        return apierror.New(http.StatusBadRequest, "set either due_at or hours, not both")
+    }
+    if req.Hours != nil && *req.Hours <= 0 {
+        return apierror.New(http.StatusBadRequest, "hours must be positive")
+    }
+
+    reqCtx := ctx.Request().Context()
+    stored, err := c.threads.Thread(reqCtx, channelID, threadTS)
+    if err != nil {
+        return threadLookupError(ctx, err)
+    }
+
+    override := req.DueAt
+    if req.Hours != nil {
+        dueAt := stored.CreatedAt.Add(time.Duration(*req.Hours * float64(time.Hour)))
+        override = &dueAt
+    }
+    if override != nil {
+        utc := override.UTC()
+        override = &utc
+    }
+    if err := c.overrides.SetThreadSLA(reqCtx, channelID, threadTS, override, actorFrom(ctx)); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update thread SLA")
+    }
+
+    // The thread is read back like lists read it, so both agree on its heat
+    ref := ThreadRef{ChannelID: channelID, ThreadTS: threadTS}
+    found, err := c.threadLists.ThreadsByRef(reqCtx, []ThreadRef{ref})
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up thread")
+    }
+    thread := found[ref]
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "thread.sla", channelID, threadTS, map[string]interface{}{
+        "due_at": override,
+    })
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "channel_id":   channelID,
+        "thread_ts":    threadTS,
+        "due_at":       thread.DueAt,
+        "sla_override": thread.SLAOverride,
+        "sla_breached": thread.SLABreached,
+        "heat":         thread.Heat,
+    })
+}
+
+func (s postgresStore) SetThreadSLA(ctx context.Context, channelID, threadTS string, dueAt *time.Time, actor string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    if dueAt == nil {
+        _, err = db.ExecContext(ctx, "DELETE FROM thread_sla WHERE channel_id = $1 AND thread_ts = $2", channelID, threadTS)
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO thread_sla (channel_id, thread_ts, due_at, set_by, updated_at)
+        VALUES ($1, $2, $3, $4, NOW())
+        ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+            due_at = EXCLUDED.due_at,
+            set_by = EXCLUDED.set_by,
+            updated_at = EXCLUDED.updated_at
+    `, channelID, threadTS, *dueAt, actor)
+    return err
+}
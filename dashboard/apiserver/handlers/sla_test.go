@@ -0,0 +1,45 @@
+package handlers
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestThreadSLAOverride(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    created := time.Now().Add(-3 * time.Hour)
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: created, LatestReply: created}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    user := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    route := "/api/threads/:channel_id/:thread_ts/sla"
+    rec := serveAs(c, user, http.MethodPut, route, "/api/threads/C1/1700000000.000100/sla", `{"hours": 2}`, c.SetThreadSLA)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var sla struct {
+        DueAt       time.Time `json:"due_at"`
+        SLAOverride bool      `json:"sla_override"`
+        SLABreached bool      `json:"sla_breached"`
+    }
+    decodeResponse(t, rec, &sla)
+    if !sla.SLAOverride || !sla.SLABreached || !sla.DueAt.Equal(created.Add(2*time.Hour).UTC()) {
+        t.Fatalf("got %+v, want a breached override 2 hours after the thread was created", sla)
+    }
+
+    rec = serveAs(c, user, http.MethodPut, route, "/api/threads/C1/1700000000.000100/sla", `{}`, c.SetThreadSLA)
+    decodeResponse(t, rec, &sla)
+    if sla.SLAOverride {
+        t.Fatalf("got %+v after removing the override", sla)
+    }
+
+    rec = serveAs(c, user, http.MethodPut, route, "/api/threads/C1/1700000000.000100/sla", `{"hours": 2, "due_at": "2030-01-01T00:00:00Z"}`, c.SetThreadSLA)
+    if rec.Code != http.StatusBadRequest {
+        t.Fatalf("both hours and due_at got status %d", rec.Code)
+    }
+}
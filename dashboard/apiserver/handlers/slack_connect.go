@@ -0,0 +1,226 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+const slackConnectSyncInterval = time.Hour
+
+// SlackConnect is how a channel shared with other workspaces is handled.
+// Shared is detected from Slack. AIAnalysis lets the AI pipeline and the
+// dashboard use the content of its threads, which stays with the workspaces
+// that wrote it by default.
+type SlackConnect struct {
+    Shared     bool `json:"shared"`
+    AIAnalysis bool `json:"ai_analysis"`
+}
+
+// SlackConnectRequest is the body accepted by SetChannelSlackConnect
+type SlackConnectRequest struct {
+    AIAnalysis bool `json:"ai_analysis"`
+}
+
+// externalColumns tell the threads of shared channels and those started by
+// users of other workspaces apart, selected by thread lists.
+const externalColumns = `COALESCE((SELECT cc.slack_connect FROM channel_config cc
+     WHERE cc.channel_id = t.channel_id), FALSE) AS external,
+    EXISTS (SELECT 1 FROM external_users eu WHERE eu.user_id = t.user_id) AS external_author,
+    COALESCE((SELECT cc.slack_connect_ai FROM channel_config cc
+     WHERE cc.channel_id = t.channel_id), FALSE) AS external_ai`
+
+// Shared channels and external users already recorded, so ingesting their
+// messages does not write them again.
+var (
+    sharedChannelsSeen sync.Map
+    externalUsersSeen  sync.Map
+)
+
+// markSlackConnect records whether a channel is shared with other
+// workspaces, auditing changes.
+func (c *Container) markSlackConnect(ctx context.Context, channelID string, shared bool) error {
+    changed, err := c.slackConnect.MarkSlackConnect(ctx, channelID, shared)
+    if err != nil {
+        return err
+    }
+    if shared {
+        sharedChannelsSeen.Store(channelID, true)
+    } else {
+        sharedChannelsSeen.Delete(channelID)
+    }
+    if changed {
+        c.logger.Infof("channel %s is shared with other workspaces: %t", channelID, shared)
+        c.audit(ctx, "system", "channel.slack_connect", channelID, "", map[string]interface{}{
+            "shared": shared,
+        })
+    }
+    return nil
+}
+
+// recordExternalUser remembers a user of another workspace.
+func (c *Container) recordExternalUser(ctx context.Context, userID string, teamID string) error {
+    if _, ok := externalUsersSeen.Load(userID); ok {
+        return nil
+    }
+    if err := c.slackConnect.RecordExternalUser(ctx, userID, teamID); err != nil {
+        return err
+    }
+    externalUsersSeen.Store(userID, true)
+    return nil
+}
+
+// noteSlackConnect records what an ingested message tells about Slack
+// Connect: whether its channel is shared and whether its author belongs to
+// another workspace. Workspaces of the same Enterprise Grid are not
+// external to each other.
+func (c *Container) noteSlackConnect(ctx context.Context, sharedChannel bool, homeTeam string, channelID string, userID string, userTeam string) error {
+    markShared := false
+    if sharedChannel {
+        _, seen := sharedChannelsSeen.Load(channelID)
+        markShared = !seen
+    }
+    external := userID != "" && userTeam != "" && homeTeam != "" && userTeam != homeTeam && !c.sameGrid(homeTeam, userTeam)
+    if !markShared && !external {
+        return nil
+    }
+
+    if markShared {
+        if err := c.markSlackConnect(ctx, channelID, true); err != nil {
+            return err
+        }
+    }
+    if external {
+        return c.recordExternalUser(ctx, userID, userTeam)
+    }
+    return nil
+}
+
+// RunSlackConnectSync periodically checks which tracked channels are shared
+// with other workspaces, catching channels shared or disconnected while no
+// events came in, until ctx is cancelled. It does nothing without a bot
+// token.
+func (c *Container) RunSlackConnectSync(ctx context.Context) {
+    if !c.slack.Configured() {
+        return
+    }
+
+    ticker := time.NewTicker(slackConnectSyncInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.syncSlackConnect(ctx); err != nil && ctx.Err() == nil {
+            c.logger.Warnf("failed to sync Slack Connect channels: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (c *Container) syncSlackConnect(ctx context.Context) error {
+    if !c.slackFeatureReady(slackFeatureSharedChannels) {
+        return nil
+    }
+    channels, err := c.channels.Channels(ctx)
+    if err != nil {
+        return err
+    }
+    for _, ch := range channels {
+        info, err := c.slack.ConversationInfo(ctx, ch.ChannelID)
+        if err != nil {
+            c.logger.Warnf("failed to look up channel %s: %v", ch.ChannelID, err)
+            continue
+        }
+        if err := c.markSlackConnect(ctx, ch.ChannelID, info.IsExtShared); err != nil {
+            c.logger.Warnf("failed to record Slack Connect state of channel %s: %v", ch.ChannelID, err)
+        }
+    }
+    return nil
+}
+
+// SetChannelSlackConnect - Set whether the content of a shared channel may be analyzed by AI
+func (c *Container) SetChannelSlackConnect(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var req SlackConnectRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+
+    if err := c.channels.Tracked(ctx.Request().Context(), channelID); err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    } else if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    settings, err := c.slackConnect.SetSlackConnectAI(ctx.Request().Context(), channelID, req.AIAnalysis)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.slack_connect_ai", channelID, "", map[string]interface{}{
+        "ai_analysis": settings.AIAnalysis,
+    })
+
+    return ctx.JSON(http.StatusOK, settings)
+}
+
+func (s postgresStore) MarkSlackConnect(ctx context.Context, channelID string, shared bool) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    result, err := db.ExecContext(ctx, `
+        INSERT INTO channel_config (channel_id, slack_connect, updated_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (channel_id) DO UPDATE SET
+            slack_connect = EXCLUDED.slack_connect,
+            updated_at = EXCLUDED.updated_at
+        WHERE channel_config.slack_connect IS DISTINCT FROM EXCLUDED.slack_connect
+    `, channelID, shared)
+    if err != nil {
+        return false, err
+    }
+    n, _ := result.RowsAffected()
+    return n > 0, nil
+}
+
+func (s postgresStore) RecordExternalUser(ctx context.Context, userID string, teamID string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO external_users (user_id, team_id, first_seen_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (user_id) DO NOTHING
+    `, userID, teamID)
+    return err
+}
+
+func (s postgresStore) SetSlackConnectAI(ctx context.Context, channelID string, enabled bool) (SlackConnect, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return SlackConnect{}, err
+    }
+
+    var settings SlackConnect
+    err = db.QueryRowContext(ctx, `
+        INSERT INTO channel_config (channel_id, slack_connect_ai, updated_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (channel_id) DO UPDATE SET
+            slack_connect_ai = EXCLUDED.slack_connect_ai,
+            updated_at = EXCLUDED.updated_at
+        RETURNING COALESCE(slack_connect, FALSE), slack_connect_ai
+    `, channelID, enabled).Scan(&settings.Shared, &settings.AIAnalysis)
+    return settings, err
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/reminders"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Action IDs of the buttons posted by the dashboard.
+const (
+    claimThreadAction   = reminders.ClaimAction
+    resolveThreadAction = reminders.ResolveAction
+    ackThreadAction     = reminders.AckAction
+    snoozeThreadAction  = reminders.SnoozeAction
+    assignThreadAction  = reminders.AssignAction
+)
+
+// buttonSnooze is how long the Snooze button of reminders snoozes a thread.
+const buttonSnooze = 24 * time.Hour
+
+// triageRequiredMessage answers users without the triager role who click a
+// button or configure a workflow step.
+const triageRequiredMessage = "Only triagers and admins can change threads, ask an admin for the triager role."
+
+// HandleSlackInteraction - Handle button clicks on messages posted by the bot, such as reminders, and workflow step configuration
+func (c *Container) HandleSlackInteraction(ctx echo.Context) error {
+    body, err := io.ReadAll(ctx.Request().Body)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "Failed to read request body")
+    }
+    interaction, err := slack.ParseInteraction(body)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid interaction payload: "+err.Error())
+    }
+    switch interaction.Type {
+    case slack.WorkflowStepEdit:
+        return c.editWorkflowStep(ctx, interaction)
+    case slack.ViewSubmission:
+        return c.saveWorkflowStep(ctx, interaction)
+    }
+    if interaction.Type != "block_actions" {
+        return ctx.NoContent(http.StatusOK)
+    }
+
+    // Buttons act on the threads of the workspace they were clicked in
+    actionCtx := withWorkspace(ctx.Request().Context(), interaction.Team.ID)
+    for _, action := range interaction.Actions {
+        switch action.ActionID {
+        case claimThreadAction, resolveThreadAction, ackThreadAction, snoozeThreadAction, assignThreadAction:
+        default:
+            continue
+        }
+
+        var reply string
+        refused := false
+        switch {
+        case c.readOnly.Load():
+            reply, refused = readOnlyMessage, true
+        case !c.canTriage(interaction.User.ID):
+            reply, refused = triageRequiredMessage, true
+        case action.ActionID == claimThreadAction:
+            reply, err = c.claimThread(actionCtx, interaction.User.ID, action.Value)
+        case action.ActionID == resolveThreadAction:
+            reply, err = c.resolveThread(actionCtx, interaction.User.ID, action.Value)
+        case action.ActionID == ackThreadAction:
+            reply, err = c.ackThreadAction(actionCtx, interaction.User.ID, action.Value)
+        case action.ActionID == snoozeThreadAction:
+            reply, err = c.snoozeThreadAction(actionCtx, interaction.User.ID, action.Value)
+        case action.ActionID == assignThreadAction:
+            reply, err = c.assignThreadAction(actionCtx, interaction.User.ID, action.Value)
+        }
+        if err != nil {
+            c.logger.Errorf("failed to handle Slack action %s: %v", action.ActionID, err)
+            reply = "Something went wrong, please try again."
+        }
+
+        // Slack expects an acknowledgement within three seconds, the
+        // visible answer goes through the response URL. Suggestions are
+        // replaced by the answer. Reminder lists stay for the other threads,
+        // the answer shows in place of the buttons of the thread.
+        response := slack.ResponseMessage{Text: reply, ReplaceOriginal: action.BlockID == suggestionBlockID}
+        if err == nil && !refused && interaction.Message != nil && strings.HasPrefix(action.BlockID, reminders.BlockPrefix) {
+            dealt := action.ActionID == resolveThreadAction || action.ActionID == snoozeThreadAction
+            response = slack.ResponseMessage{
+                Text:            interaction.Message.Text,
+                Blocks:          reminders.Acted(interaction.Message.Blocks, action.BlockID, action.ActionID, reply, dealt),
+                ReplaceOriginal: true,
+            }
+        }
+        if interaction.ResponseURL != "" {
+            go func(responseURL string, response slack.ResponseMessage) {
+                if err := c.slack.Respond(context.Background(), responseURL, response); err != nil {
+                    c.logger.Warnf("failed to answer Slack interaction: %v", err)
+                }
+            }(interaction.ResponseURL, response)
+        }
+    }
+
+    return ctx.NoContent(http.StatusOK)
+}
+
+// claimThread records userID as the owner of the thread identified by value
+// ("channel_id:thread_ts") and returns the message to show them.
+func (c *Container) claimThread(ctx context.Context, userID string, value string) (string, error) {
+    channelID, threadTS, ok := strings.Cut(value, ":")
+    if !ok || userID == "" {
+        return "", fmt.Errorf("invalid claim value %q", value)
+    }
+
+    if err := c.channels.Tracked(ctx, channelID); err == errChannelNotTracked {
+        return "This channel is no longer tracked.", nil
+    } else if err != nil {
+        return "", err
+    }
+
+    claimedBy, claimed, err := c.assignments.ClaimThread(ctx, channelID, threadTS, userID)
+    if err != nil {
+        return "", err
+    }
+    if !claimed {
+        if claimedBy == userID {
+            return "You already claimed this thread.", nil
+        }
+        return fmt.Sprintf("<@%s> already claimed this thread.", claimedBy), nil
+    }
+
+    c.audit(ctx, userID, "thread.claim", channelID, threadTS, nil)
+
+    // Claiming a thread nobody acknowledged yet acknowledges it
+    if _, _, err := c.acks.Acknowledge(ctx, userID, ackSourceClaim, channelID, threadTS); err != nil && err != errThreadNotFound {
+        c.logger.Warnf("failed to record acknowledgment of thread %s/%s: %v", channelID, threadTS, err)
+    }
+    return fmt.Sprintf("You claimed <%s|this thread>.", slack.Permalink(channelID, threadTS)), nil
+}
+
+// resolveThread closes the thread identified by value ("channel_id:thread_ts")
+// on behalf of userID, or requests approval when its channel requires it,
+// and returns the message to show them.
+func (c *Container) resolveThread(ctx context.Context, userID string, value string) (string, error) {
+    channelID, threadTS, ok := strings.Cut(value, ":")
+    if !ok || userID == "" {
+        return "", fmt.Errorf("invalid resolve value %q", value)
+    }
+
+    err := c.channels.Tracked(ctx, channelID)
+    if err == errChannelNotTracked {
+        return "This channel is no longer tracked.", nil
+    }
+    if err != nil {
+        return "", err
+    }
+
+    // Buttons resolve without asking why, the resolution stays unknown
+    outcome, err := c.resolveOrRequest(ctx, userID, channelID, threadTS, "", "")
+    if err == errThreadNotFound {
+        return "This thread no longer exists.", nil
+    }
+    if err != nil {
+        return "", err
+    }
+    switch outcome {
+    case resolveRequested:
+        return fmt.Sprintf("Resolving <%s|this thread> needs approval from a second person, the request was posted in the thread.",
+            slack.Permalink(channelID, threadTS)), nil
+    case resolveAlreadyRequested:
+        return "A resolution of this thread is already waiting for approval.", nil
+    case resolveAlreadyClosed:
+        return "This thread is already resolved.", nil
+    }
+    return fmt.Sprintf("You marked <%s|this thread> as resolved.", slack.Permalink(channelID, threadTS)), nil
+}
+
+// snoozeThreadAction snoozes the thread identified by value
+// ("channel_id:thread_ts") for a day on behalf of userID and returns the
+// message to show them.
+func (c *Container) snoozeThreadAction(ctx context.Context, userID string, value string) (string, error) {
+    channelID, threadTS, ok := strings.Cut(value, ":")
+    if !ok || userID == "" {
+        return "", fmt.Errorf("invalid snooze value %q", value)
+    }
+
+    previous, err := c.snoozeThread(ctx, userID, channelID, threadTS, time.Now().Add(buttonSnooze).UTC())
+    if err == errChannelNotTracked {
+        return "This channel is no longer tracked.", nil
+    }
+    if err == errThreadNotFound {
+        return "This thread no longer exists.", nil
+    }
+    if err != nil {
+        return "", err
+    }
+    if previous == statusResolved || previous == statusClosed {
+        return "This thread is already resolved.", nil
+    }
+    return fmt.Sprintf("You snoozed <%s|this thread> until tomorrow.", slack.Permalink(channelID, threadTS)), nil
+}
+
+// assignThreadAction assigns the thread identified by value
+// ("channel_id:thread_ts") to userID and returns the message to show them.
+func (c *Container) assignThreadAction(ctx context.Context, userID string, value string) (string, error) {
+    channelID, threadTS, ok := strings.Cut(value, ":")
+    if !ok || userID == "" {
+        return "", fmt.Errorf("invalid assign value %q", value)
+    }
+
+    _, err := c.threads.Thread(ctx, channelID, threadTS)
+    if err == errChannelNotTracked {
+        return "This channel is no longer tracked.", nil
+    }
+    if err == errThreadNotFound {
+        return "This thread no longer exists.", nil
+    }
+    if err != nil {
+        return "", err
+    }
+
+    previous, err := c.assignments.AssignThread(ctx, channelID, threadTS, userID, userID)
+    if err != nil {
+        return "", err
+    }
+    if previous == userID {
+        return "This thread is already assigned to you.", nil
+    }
+    c.audit(ctx, userID, "thread.assign", channelID, threadTS, map[string]interface{}{
+        "assignee":          userID,
+        "previous_assignee": previous,
+    })
+    return fmt.Sprintf("You assigned <%s|this thread> to yourself.", slack.Permalink(channelID, threadTS)), nil
+}
+
+func (s postgresStore) ClaimThread(ctx context.Context, channelID, threadTS, userID string) (string, bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return "", false, err
+    }
+
+    var claimedBy string
+    err = db.QueryRowContext(ctx, `
+        INSERT INTO thread_claims (channel_id, thread_ts, user_id, claimed_at)
+        VALUES ($1, $2, $3, NOW())
+        ON CONFLICT (channel_id, thread_ts) DO NOTHING
+        RETURNING user_id
+    `, channelID, threadTS, userID).Scan(&claimedBy)
+    if err == sql.ErrNoRows {
+        err = db.QueryRowContext(ctx, `
+            SELECT user_id FROM thread_claims WHERE channel_id = $1 AND thread_ts = $2
+        `, channelID, threadTS).Scan(&claimedBy)
+        return claimedBy, false, err
+    }
+    return claimedBy, err == nil, err
+}
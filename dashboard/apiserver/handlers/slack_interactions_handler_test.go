@@ -0,0 +1,176 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// postInteraction sends an interaction payload to HandleSlackInteraction.
+func postInteraction(t *testing.T, c *Container, payload map[string]interface{}) *httptest.ResponseRecorder {
+    t.Helper()
+    encoded, err := json.Marshal(payload)
+    if err != nil {
+        t.Fatal(err)
+    }
+    body := url.Values{"payload": {string(encoded)}}.Encode()
+    req := httptest.NewRequest(http.MethodPost, "/slack/interactions", strings.NewReader(body))
+    req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+    rec := httptest.NewRecorder()
+    if err := c.HandleSlackInteraction(echo.New().NewContext(req, rec)); err != nil {
+        t.Fatal(err)
+    }
+    return rec
+}
+
+func waitForResponse(t *testing.T, client *MemorySlack) slack.ResponseMessage {
+    t.Helper()
+    deadline := time.Now().Add(2 * time.Second)
+    for time.Now().Before(deadline) {
+        if responses := client.Responses(); len(responses) > 0 {
+            return responses[0]
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+    t.Fatal("the interaction was not answered")
+    return slack.ResponseMessage{}
+}
+
+func TestSlackButtonsRequireTriager(t *testing.T) {
+    for _, actionID := range []string{claimThreadAction, resolveThreadAction, ackThreadAction, snoozeThreadAction, assignThreadAction} {
+        t.Run(actionID, func(t *testing.T) {
+            store := NewMemoryStore()
+            store.SetRole("UVIEWER", "viewer")
+            client := &MemorySlack{}
+            c := newTestContainer(t, store, client)
+
+            postInteraction(t, c, map[string]interface{}{
+                "type":         "block_actions",
+                "user":         map[string]string{"id": "UVIEWER"},
+                "team":         map[string]string{"id": "T1"},
+                "response_url": "https://hooks.slack.test/response",
+                "actions": []map[string]string{
+                    {"action_id": actionID, "block_id": "reminder:C1:1700000000.000100", "value": "C1:1700000000.000100"},
+                },
+                "message": map[string]interface{}{"ts": "1700000001.000100", "text": "Open threads"},
+            })
+
+            response := waitForResponse(t, client)
+            if response.Text != triageRequiredMessage {
+                t.Fatalf("got answer %q, want %q", response.Text, triageRequiredMessage)
+            }
+            if response.ReplaceOriginal {
+                t.Fatal("the reminder was replaced for a refused click")
+            }
+        })
+    }
+}
+
+func TestSaveWorkflowStepRequiresTriager(t *testing.T) {
+    store := NewMemoryStore()
+    store.SetRole("UVIEWER", "viewer")
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    rec := postInteraction(t, c, map[string]interface{}{
+        "type":          "view_submission",
+        "user":          map[string]string{"id": "UVIEWER"},
+        "workflow_step": map[string]string{"workflow_step_edit_id": "E1"},
+        "view":          map[string]string{"type": slack.WorkflowStepView, "callback_id": resolveThreadStep},
+    })
+
+    var answer slack.ViewErrors
+    if err := json.Unmarshal(rec.Body.Bytes(), &answer); err != nil {
+        t.Fatal(err)
+    }
+    if answer.ResponseAction != "errors" || answer.Errors[messageStepInput.name] != triageRequiredMessage {
+        t.Fatalf("got answer %+v", answer)
+    }
+}
+
+func TestWorkflowStepUserRequiresTriager(t *testing.T) {
+    store := NewMemoryStore()
+    store.SetRole("UVIEWER", "viewer")
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    step := &slack.WorkflowStep{
+        WorkflowID: "Wf1",
+        Inputs: map[string]slack.StepInput{
+            "message": {Value: "https://example.slack.com/archives/C1/p1700000000000100"},
+            "user":    {Value: "<@UVIEWER>"},
+        },
+    }
+    _, err := c.runWorkflowStep(context.Background(), resolveThreadStep, step)
+    var failed errStepFailed
+    if !errors.As(err, &failed) || !strings.Contains(failed.Error(), "needs the triager role") {
+        t.Fatalf("got error %v", err)
+    }
+}
+
+func TestTrackAndSnoozeWorkflowSteps(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    step := &slack.WorkflowStep{
+        WorkflowID: "Wf1",
+        Inputs: map[string]slack.StepInput{
+            "message": {Value: "https://example.slack.com/archives/C1/p1700000000000100"},
+            "author":  {Value: "<@U2>"},
+        },
+    }
+    if _, err := c.runWorkflowStep(context.Background(), snoozeThreadStep, step); err == nil || !strings.Contains(err.Error(), "not tracked") {
+        t.Fatalf("snoozing an untracked thread got error %v", err)
+    }
+    for range 2 {
+        outputs, err := c.runWorkflowStep(context.Background(), trackThreadStep, step)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if outputs["thread_status"] != string(statusOpen) {
+            t.Fatalf("got outputs %v", outputs)
+        }
+    }
+    thread, err := store.Thread(context.Background(), "C1", "1700000000.000100")
+    if err != nil || thread.UserID != "U2" {
+        t.Fatalf("got thread %+v, %v", thread, err)
+    }
+    audited := func(action string) int {
+        n := 0
+        for _, entry := range store.Audited() {
+            if entry.Action == action {
+                n++
+            }
+        }
+        return n
+    }
+    if n := audited("thread.track"); n != 1 {
+        t.Fatalf("audited %d tracks, want the first one only", n)
+    }
+
+    for range 2 {
+        outputs, err := c.runWorkflowStep(context.Background(), snoozeThreadStep, step)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if outputs["thread_status"] != string(statusSnoozed) {
+            t.Fatalf("got outputs %v", outputs)
+        }
+    }
+    if thread, _ := store.Thread(context.Background(), "C1", "1700000000.000100"); thread.Status != statusSnoozed {
+        t.Fatalf("got thread %s", thread.Status)
+    }
+    if n := audited("thread.status"); n != 1 {
+        t.Fatalf("audited %d snoozes, want the first one only", n)
+    }
+}
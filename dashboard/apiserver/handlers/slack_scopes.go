@@ -0,0 +1,250 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+const slackScopeAuditInterval = time.Hour
+
+// errSlackFeatureUnavailable is returned by features needing Slack when the
+// bot token is missing or lacks their scopes.
+var errSlackFeatureUnavailable = errors.New("slack bot token is not configured or lacks the scopes of the feature")
+
+// Features using the Slack Web API, each needing its own bot token scopes.
+const (
+    slackFeatureThreadHistory  = "thread_history"
+    slackFeatureSharedChannels = "shared_channels"
+    slackFeatureReplies        = "replies"
+    slackFeatureWatchers       = "watchers"
+    slackFeatureWorkflowSteps  = "workflow_steps"
+    slackFeatureGridUserIDs    = "grid_user_ids"
+    slackFeatureQuickstart     = "quickstart"
+    slackFeatureDiscovery      = "channel_discovery"
+    slackFeatureUsergroups     = "usergroups"
+    slackFeatureUserProfiles   = "user_profiles"
+    slackFeatureCanvases       = "canvases"
+    slackFeatureSummaryPins    = "summary_pins"
+)
+
+// slackFeature is a feature calling Slack with the scopes it needs.
+type slackFeature struct {
+    name        string
+    description string
+    scopes      []string
+    enabled     bool
+}
+
+// slackFeatures returns every feature calling Slack, and whether it is
+// enabled by the configuration. Only enabled features need their scopes.
+func (c *Container) slackFeatures() []slackFeature {
+    c.grid.mu.RLock()
+    inGrid := c.grid.enterpriseID != ""
+    c.grid.mu.RUnlock()
+
+    return []slackFeature{
+        {slackFeatureThreadHistory, "backfills, thread detail and AI analysis of threads whose messages were not stored",
+            []string{"channels:history"}, true},
+        {slackFeatureSharedChannels, "hourly check of which channels are shared through Slack Connect",
+            []string{"channels:read"}, true},
+        {slackFeatureReplies, "reminders, suggestions, nudges, quality prompts and replies posted in threads",
+            []string{"chat:write"}, true},
+        {slackFeatureWatchers, "watchers and answers recorded from reactions",
+            []string{"reactions:read"}, c.watchEmoji != ""},
+        {slackFeatureWorkflowSteps, "Workflow Builder steps",
+            []string{"workflow.steps:execute"}, true},
+        {slackFeatureGridUserIDs, "translation of local user IDs to Enterprise Grid IDs",
+            []string{"tokens.basic"}, inGrid},
+        {slackFeatureQuickstart, "tracking the public and private channels of the bot with --quickstart",
+            []string{"channels:read", "groups:read"}, c.quickstart.Load()},
+        {slackFeatureUsergroups, "hourly sync of the members of usergroups assigned threads, reminded and counted in analytics",
+            []string{"usergroups:read"}, true},
+        {slackFeatureUserProfiles, "hourly sync of the names and avatars of users, and of who left the workspace",
+            []string{"users:read"}, true},
+        // Summaries are opted into per channel, they never make their
+        // scopes required
+        {slackFeatureCanvases, "summaries of open threads kept in the canvas of channels",
+            []string{"canvases:write"}, false},
+        {slackFeatureSummaryPins, "summaries of open threads kept in a pinned message of channels",
+            []string{"chat:write", "pins:write"}, false},
+        // Discovery is only used on demand, it never makes its scopes required
+        {slackFeatureDiscovery, "discovering the channels of the bot to onboard them from the admin API",
+            []string{"channels:read", "groups:read"}, false},
+    }
+}
+
+// slackScopeAudit remembers the scopes granted to the bot token at the
+// last audit, nil until the first one.
+type slackScopeAudit struct {
+    mu        sync.RWMutex
+    granted   map[string]bool
+    checkedAt time.Time
+}
+
+// SlackFeatureScopes is whether a feature has the scopes it needs.
+type SlackFeatureScopes struct {
+    Feature     string   `json:"feature"`
+    Description string   `json:"description"`
+    Enabled     bool     `json:"enabled"`
+    Scopes      []string `json:"scopes"`
+    Missing     []string `json:"missing"`
+    Available   bool     `json:"available"`
+}
+
+// SlackScopeReport compares the scopes granted to the bot token with those
+// the features need. Unused scopes are granted but needed by no feature,
+// and may be removed from the app.
+type SlackScopeReport struct {
+    Granted   []string             `json:"granted"`
+    Required  []string             `json:"required"`
+    Missing   []string             `json:"missing"`
+    Unused    []string             `json:"unused"`
+    Features  []SlackFeatureScopes `json:"features"`
+    CheckedAt time.Time            `json:"checked_at"`
+}
+
+// auditSlackScopes looks up the scopes granted to the bot token, which
+// features are disabled without, and warns about enabled features missing
+// scopes they did not miss at the previous audit.
+func (c *Container) auditSlackScopes(ctx context.Context) (*SlackScopeReport, error) {
+    scopes, err := c.slack.AuthTest(ctx)
+    if err != nil {
+        return nil, err
+    }
+    granted := make(map[string]bool, len(scopes))
+    for _, scope := range scopes {
+        granted[scope] = true
+    }
+
+    c.slackScopes.mu.Lock()
+    previous := c.slackScopes.granted
+    c.slackScopes.granted = granted
+    c.slackScopes.checkedAt = time.Now().UTC()
+    checkedAt := c.slackScopes.checkedAt
+    c.slackScopes.mu.Unlock()
+
+    report := &SlackScopeReport{
+        Granted:   scopes,
+        Required:  []string{},
+        Missing:   []string{},
+        Unused:    []string{},
+        Features:  []SlackFeatureScopes{},
+        CheckedAt: checkedAt,
+    }
+    required := map[string]bool{}
+    needed := map[string]bool{}
+    for _, feature := range c.slackFeatures() {
+        status := SlackFeatureScopes{
+            Feature:     feature.name,
+            Description: feature.description,
+            Enabled:     feature.enabled,
+            Scopes:      feature.scopes,
+            Missing:     []string{},
+        }
+        // Scopes missing at the previous audit were already warned about
+        newlyMissing := false
+        for _, scope := range feature.scopes {
+            needed[scope] = true
+            if feature.enabled {
+                required[scope] = true
+            }
+            if !granted[scope] {
+                status.Missing = append(status.Missing, scope)
+                newlyMissing = newlyMissing || previous == nil || previous[scope]
+            }
+        }
+        status.Available = len(status.Missing) == 0
+        if feature.enabled && !status.Available && newlyMissing {
+            c.logger.Warnf("Slack feature %s is disabled, the bot token lacks the scopes %s",
+                feature.name, strings.Join(status.Missing, ", "))
+        }
+        report.Features = append(report.Features, status)
+    }
+
+    for scope := range required {
+        report.Required = append(report.Required, scope)
+        if !granted[scope] {
+            report.Missing = append(report.Missing, scope)
+        }
+    }
+    for _, scope := range scopes {
+        if !needed[scope] {
+            report.Unused = append(report.Unused, scope)
+        }
+    }
+    sort.Strings(report.Required)
+    sort.Strings(report.Missing)
+    sort.Strings(report.Unused)
+    return report, nil
+}
+
+// slackFeatureReady reports whether a feature may call Slack: a bot token
+// is configured and was granted the scopes of the feature. Features are
+// assumed ready until the first audit.
+func (c *Container) slackFeatureReady(name string) bool {
+    if !c.slack.Configured() {
+        return false
+    }
+    c.slackScopes.mu.RLock()
+    granted := c.slackScopes.granted
+    c.slackScopes.mu.RUnlock()
+    if granted == nil {
+        return true
+    }
+    for _, feature := range c.slackFeatures() {
+        if feature.name != name {
+            continue
+        }
+        for _, scope := range feature.scopes {
+            if !granted[scope] {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+// RunSlackScopeAudit checks the scopes of the bot token at startup and
+// hourly, picking up scopes added or removed by reinstalling the app, until
+// ctx is cancelled. It does nothing without a bot token.
+func (c *Container) RunSlackScopeAudit(ctx context.Context) {
+    if !c.slack.Configured() {
+        return
+    }
+
+    ticker := time.NewTicker(slackScopeAuditInterval)
+    defer ticker.Stop()
+    for {
+        if _, err := c.auditSlackScopes(ctx); err != nil && ctx.Err() == nil {
+            c.logger.Warnf("failed to audit the scopes of the Slack bot token: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// GetSlackScopes - Get the Slack scopes each feature needs, compared with those granted to the bot token
+func (c *Container) GetSlackScopes(ctx echo.Context) error {
+    if !c.slack.Configured() {
+        return apierror.New(http.StatusServiceUnavailable, "Slack bot token is not configured")
+    }
+
+    report, err := c.auditSlackScopes(ctx.Request().Context())
+    if err != nil {
+        c.logger.Errorf("failed to audit Slack scopes: %v", err)
+        return apierror.New(http.StatusBadGateway, "Failed to look up the scopes of the Slack bot token")
+    }
+    return ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+// maxSnooze bounds how long a thread may be snoozed.
+const maxSnooze = 365 * 24 * time.Hour
+
+// SnoozeRequest is the body accepted by SnoozeThread, setting one of hours,
+// days or until.
+type SnoozeRequest struct {
+    Hours *float64   `json:"hours"`
+    Days  *float64   `json:"days"`
+    Until *time.Time `json:"until"`
+}
+
+// until returns when a snooze requested at now ends.
+func (r SnoozeRequest) until(now time.Time) (time.Time, error) {
+    set := 0
+    var until time.Time
+    if r.Hours != nil {
+        set++
+        until = now.Add(time.Duration(*r.Hours * float64(time.Hour)))
+    }
+    if r.Days != nil {
+        set++
+        until = now.Add(time.Duration(*r.Days * float64(24*time.Hour)))
+    }
+    if r.Until != nil {
+        set++
+        until = *r.Until
+    }
+    if set != 1 {
+        return time.Time{}, fmt.Errorf("set one of hours, days or until")
+    }
+    if !until.After(now) || until.Sub(now) > maxSnooze {
+        return time.Time{}, fmt.Errorf("a thread can be snoozed for up to a year")
+    }
+    return until.UTC(), nil
+}
+
+// snoozeThread snoozes a thread until a time on behalf of actor and returns
+// its previous status, or errChannelNotTracked or errThreadNotFound.
+// Resolved and closed threads are left as they are.
+func (c *Container) snoozeThread(ctx context.Context, actor string, channelID string, threadTS string, until time.Time) (domain.Status, error) {
+    previous, err := c.threadStatus.SnoozeThread(ctx, channelID, threadTS, until)
+    if err != nil || previous == statusResolved || previous == statusClosed {
+        return previous, err
+    }
+    c.audit(ctx, actor, "thread.snooze", channelID, threadTS, map[string]interface{}{
+        "from":  previous,
+        "until": until,
+    })
+    return previous, nil
+}
+
+// SnoozeThread - Snooze a thread for a number of hours or days, or until a time, after which it is open again
+func (c *Container) SnoozeThread(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req SnoozeRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    return c.snooze(ctx, channelID, threadTS, req)
+}
+
+// snooze snoozes a thread for as long as req asks and answers the request,
+// for SnoozeThread and UpdateThreadStatus.
+func (c *Container) snooze(ctx echo.Context, channelID string, threadTS string, req SnoozeRequest) error {
+    until, err := req.until(time.Now())
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+
+    previous, err := c.snoozeThread(ctx.Request().Context(), actorFrom(ctx), channelID, threadTS, until)
+    if err == errChannelNotTracked || err == errThreadNotFound {
+        return threadLookupError(ctx, err)
+    }
+    if err != nil {
+        c.logger.Errorf("failed to snooze thread %s/%s: %v", channelID, threadTS, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to snooze thread")
+    }
+    if previous == statusResolved || previous == statusClosed {
+        return apierror.New(http.StatusConflict, "Resolved and closed threads cannot be snoozed")
+    }
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "channel_id":    channelID,
+        "thread_ts":     threadTS,
+        "status":        statusSnoozed,
+        "snoozed_until": until,
+    })
+}
+
+// RunSnoozeWakeups reopens the snoozed threads of the workspace of ctx once
+// their snooze ends, until ctx is cancelled.
+func (c *Container) RunSnoozeWakeups(ctx context.Context) {
+    ticker := time.NewTicker(reminderScanInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.wakeSnoozedThreads(ctx, time.Now()); err != nil {
+            c.logger.Warnf("failed to reopen snoozed threads: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// wakeSnoozedThreads reopens the threads whose snooze ended by now.
+func (c *Container) wakeSnoozedThreads(ctx context.Context, now time.Time) error {
+    threads, err := c.threadStatus.WakeSnoozedThreads(ctx, now)
+    if err != nil {
+        return err
+    }
+    for _, t := range threads {
+        c.audit(ctx, "system", "thread.status", t.ChannelID, t.ThreadTS, map[string]interface{}{
+            "from":   statusSnoozed,
+            "to":     statusOpen,
+            "reason": "snooze ended",
+        })
+    }
+    if woken := len(threads); woken > 0 {
+        c.logger.Infof("reopened %d snoozed threads", woken)
+    }
+    return nil
+}
+
+func (s postgresStore) SnoozeThread(ctx context.Context, channelID string, threadTS string, until time.Time) (domain.Status, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return "", err
+    }
+    if err := channelTracked(db, channelID); err != nil {
+        return "", err
+    }
+
+    // The status is checked and changed in one statement, so a thread
+    // resolved meanwhile is not snoozed again. Snoozing again only moves the
+    // end of the snooze.
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return "", err
+    }
+    defer tx.Rollback()
+
+    var previous domain.Status
+    err = tx.QueryRowContext(ctx, `
+        UPDATE threads t SET status = $1, snoozed_until = $2, updated_at = NOW()
+        FROM (SELECT thread_ts, channel_id, status FROM threads WHERE thread_ts = $3 AND channel_id = $4 FOR UPDATE) prev
+        WHERE t.thread_ts = prev.thread_ts AND t.channel_id = prev.channel_id AND t.status NOT IN ($5, $6)
+        RETURNING prev.status
+    `, statusSnoozed, until, threadTS, channelID, statusResolved, statusClosed).Scan(&previous)
+    if err == sql.ErrNoRows {
+        // Missing, or resolved or closed
+        err = tx.QueryRowContext(ctx, "SELECT status FROM threads WHERE thread_ts = $1 AND channel_id = $2",
+            threadTS, channelID).Scan(&previous)
+        if err == sql.ErrNoRows {
+            return "", errThreadNotFound
+        }
+        return previous, err
+    }
+    if err != nil {
+        return "", err
+    }
+
+    if previous == statusWaitingRelease {
+        _, err := tx.ExecContext(ctx, "DELETE FROM thread_releases WHERE channel_id = $1 AND thread_ts = $2 AND released_at IS NULL", channelID, threadTS)
+        if err != nil {
+            return "", err
+        }
+    }
+    if err := tx.Commit(); err != nil {
+        return "", err
+    }
+
+    if previous == statusWaitingReporter {
+        if err := endReporterWait(ctx, db, channelID, threadTS); err != nil {
+            s.c.logger.Warnf("failed to end the reporter wait of snoozed thread %s/%s: %v", channelID, threadTS, err)
+        }
+    }
+    return previous, nil
+}
+
+func (s postgresStore) WakeSnoozedThreads(ctx context.Context, now time.Time) ([]ThreadRef, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        UPDATE threads SET status = $1, snoozed_until = NULL, updated_at = NOW()
+        WHERE status = $2 AND snoozed_until <= $3
+        RETURNING channel_id, thread_ts
+    `, statusOpen, statusSnoozed, now)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var threads []ThreadRef
+    for rows.Next() {
+        var t ThreadRef
+        if err := rows.Scan(&t.ChannelID, &t.ThreadTS); err == nil {
+            threads = append(threads, t)
+        }
+    }
+    return threads, rows.Err()
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestPatchSnoozedThreadWakesUp(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: time.Now()}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    agent := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+    update := func(body string) *httptest.ResponseRecorder {
+        return serveAs(c, agent, http.MethodPatch, "/api/threads/:channel_id/:thread_ts/status",
+            "/api/threads/C1/1700000000.000100/status", body, c.UpdateThreadStatus)
+    }
+    status := func() domain.Status {
+        thread, err := store.Thread(context.Background(), "C1", "1700000000.000100")
+        if err != nil {
+            t.Fatal(err)
+        }
+        return thread.Status
+    }
+
+    // Without an end the thread would stay snoozed for good
+    if rec := update(`{"status": "snoozed"}`); rec.Code != http.StatusBadRequest {
+        t.Fatalf("snoozing without an end got status %d: %s", rec.Code, rec.Body.String())
+    }
+    if got := status(); got != statusOpen {
+        t.Fatalf("got status %s after a refused snooze, want open", got)
+    }
+
+    now := time.Now()
+    rec := update(`{"status": "snoozed", "hours": 2}`)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var snoozed struct {
+        Status       domain.Status `json:"status"`
+        SnoozedUntil time.Time     `json:"snoozed_until"`
+    }
+    decodeResponse(t, rec, &snoozed)
+    if snoozed.Status != statusSnoozed || snoozed.SnoozedUntil.Sub(now) < 2*time.Hour-time.Minute {
+        t.Fatalf("got %+v, want the thread snoozed for two hours", snoozed)
+    }
+
+    if err := c.wakeSnoozedThreads(context.Background(), now.Add(time.Hour)); err != nil {
+        t.Fatal(err)
+    }
+    if got := status(); got != statusSnoozed {
+        t.Fatalf("got status %s before the snooze ended, want snoozed", got)
+    }
+    if err := c.wakeSnoozedThreads(context.Background(), now.Add(3*time.Hour)); err != nil {
+        t.Fatal(err)
+    }
+    if got := status(); got != statusOpen {
+        t.Fatalf("got status %s once the snooze ended, want open again", got)
+    }
+
+    audited := store.Audited()
+    if len(audited) != 2 || audited[0].Action != "thread.snooze" || audited[1].Actor != "system" || audited[1].Details["reason"] != "snooze ended" {
+        t.Fatalf("audited %+v, want the snooze and its end", audited)
+    }
+}
+
+func TestSnoozeButtonLeavesResolvedThreads(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusResolved, CreatedAt: time.Now()}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: statusOpen, CreatedAt: time.Now()}})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    tests := []struct {
+        value string
+        want  string
+    }{
+        {"C2:1700000000.000100", "This channel is no longer tracked."},
+        {"C1:1700000000.000300", "This thread no longer exists."},
+        {"C1:1700000000.000100", "This thread is already resolved."},
+    }
+    for _, tt := range tests {
+        reply, err := c.snoozeThreadAction(context.Background(), "U1", tt.value)
+        if err != nil || reply != tt.want {
+            t.Errorf("%s: got %q and %v, want %q", tt.value, reply, err, tt.want)
+        }
+    }
+    if _, err := c.snoozeThreadAction(context.Background(), "U1", "C1:1700000000.000200"); err != nil {
+        t.Fatal(err)
+    }
+    if thread, _ := store.Thread(context.Background(), "C1", "1700000000.000200"); thread.Status != statusSnoozed {
+        t.Fatalf("got status %s, want the thread snoozed", thread.Status)
+    }
+    if audited := store.Audited(); len(audited) != 1 || audited[0].Actor != "U1" {
+        t.Fatalf("audited %+v, want only the snooze of the open thread", audited)
+    }
+}
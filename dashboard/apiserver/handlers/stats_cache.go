@@ -0,0 +1,175 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "dashboard/apiserver/events"
+
+    "github.com/labstack/echo/v4"
+)
+
+// headerCache tells whether a response was served from the stats cache.
+const headerCache = "X-Cache"
+
+// StatsCacheStats reports how well the stats cache works
+type StatsCacheStats struct {
+    Enabled    bool    `json:"enabled"`
+    TTLSeconds float64 `json:"ttl_seconds"`
+    Entries    int     `json:"entries"`
+    Hits       int64   `json:"hits"`
+    Misses     int64   `json:"misses"`
+    // Invalidations count the changes that emptied the cache of a workspace
+    Invalidations int64   `json:"invalidations"`
+    HitRatio      float64 `json:"hit_ratio"`
+}
+
+// statsCacheEntry is a cached response of a workspace.
+type statsCacheEntry struct {
+    workspace string
+    value     interface{}
+    expiresAt time.Time
+}
+
+// statsCache keeps the responses of the stats and channel list for ttl,
+// dropping those of a workspace as soon as something changes in it. The
+// TTL bounds how stale they get after writes no event tells about, such
+// as threads stored by the collector.
+type statsCache struct {
+    ttl time.Duration
+
+    mu      sync.Mutex
+    entries map[string]statsCacheEntry
+
+    hits          atomic.Int64
+    misses        atomic.Int64
+    invalidations atomic.Int64
+}
+
+func newStatsCache(ttl time.Duration) *statsCache {
+    return &statsCache{ttl: ttl, entries: make(map[string]statsCacheEntry)}
+}
+
+// load returns the cached response to key in workspace, counting the hit or
+// miss.
+func (s *statsCache) load(workspace string, key string) (interface{}, bool) {
+    s.mu.Lock()
+    entry, ok := s.entries[workspace+"|"+key]
+    s.mu.Unlock()
+    if !ok || time.Now().After(entry.expiresAt) {
+        s.misses.Add(1)
+        return nil, false
+    }
+    s.hits.Add(1)
+    return entry.value, true
+}
+
+func (s *statsCache) store(workspace string, key string, value interface{}) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := time.Now()
+    for k, entry := range s.entries {
+        if now.After(entry.expiresAt) {
+            delete(s.entries, k)
+        }
+    }
+    s.entries[workspace+"|"+key] = statsCacheEntry{workspace: workspace, value: value, expiresAt: now.Add(s.ttl)}
+}
+
+// invalidate drops the cached responses of workspace.
+func (s *statsCache) invalidate(workspace string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for k, entry := range s.entries {
+        if entry.workspace == workspace {
+            delete(s.entries, k)
+        }
+    }
+    s.invalidations.Add(1)
+}
+
+func (s *statsCache) stats() StatsCacheStats {
+    s.mu.Lock()
+    entries := len(s.entries)
+    s.mu.Unlock()
+
+    stats := StatsCacheStats{
+        Enabled:       true,
+        TTLSeconds:    s.ttl.Seconds(),
+        Entries:       entries,
+        Hits:          s.hits.Load(),
+        Misses:        s.misses.Load(),
+        Invalidations: s.invalidations.Load(),
+    }
+    if total := stats.Hits + stats.Misses; total > 0 {
+        stats.HitRatio = float64(stats.Hits) / float64(total)
+    }
+    return stats
+}
+
+// cacheWorkspace returns the workspace whose database team's threads are
+// stored in, empty for the shared database, so that requests and events of
+// every workspace in it share the same cached responses.
+func (c *Container) cacheWorkspace(team string) string {
+    if _, ok := c.workspaces[team]; ok {
+        return team
+    }
+    return ""
+}
+
+// loadCachedResponse returns the cached response to the request, if the
+// stats cache holds one for its workspace.
+func (c *Container) loadCachedResponse(ctx echo.Context) (interface{}, bool) {
+    if c.statsCache == nil {
+        return nil, false
+    }
+    workspace := c.cacheWorkspace(workspaceFrom(ctx.Request().Context()))
+    value, ok := c.statsCache.load(workspace, statsCacheKey(ctx))
+    if ok {
+        ctx.Response().Header().Set(headerCache, "HIT")
+    }
+    return value, ok
+}
+
+// storeCachedResponse caches the response to the request for its
+// workspace.
+func (c *Container) storeCachedResponse(ctx echo.Context, value interface{}) {
+    if c.statsCache == nil {
+        return
+    }
+    workspace := c.cacheWorkspace(workspaceFrom(ctx.Request().Context()))
+    c.statsCache.store(workspace, statsCacheKey(ctx), value)
+    ctx.Response().Header().Set(headerCache, "MISS")
+}
+
+// invalidateStatsCache drops the cached responses of the workspace of an
+// action, new thread or analysis. Replies change no count, those changing
+// the status of their thread are audited as actions.
+func (c *Container) invalidateStatsCache(ctx context.Context, event events.Event) {
+    if c.statsCache == nil {
+        return
+    }
+    if reply, _ := event.Details["reply"].(bool); event.Topic == events.TopicThreadMessage && reply {
+        return
+    }
+    c.statsCache.invalidate(c.cacheWorkspace(event.Workspace))
+}
+
+// statsCacheKey returns the cache key of a request, its path and query.
+func statsCacheKey(ctx echo.Context) string {
+    if query := ctx.QueryString(); query != "" {
+        return ctx.Path() + "?" + query
+    }
+    return ctx.Path()
+}
+
+// GetStatsCache - Get the hits, misses and invalidations of the cache of dashboard stats and channels
+func (c *Container) GetStatsCache(ctx echo.Context) error {
+    if c.statsCache == nil {
+        return ctx.JSON(http.StatusOK, StatsCacheStats{})
+    }
+    return ctx.JSON(http.StatusOK, c.statsCache.stats())
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+// statsDiffDefaultDays is the period compared when from is omitted, a week
+// for weekly reviews.
+const statsDiffDefaultDays = 7
+
+// snapshotCounts are the counts of a channel in a stats snapshot.
+type snapshotCounts struct {
+    total, open, stale int
+}
+
+// StatsDiffChannel is what changed in a channel between two snapshots.
+// Resolved counts the threads closed, and NewStale how many more threads
+// are stale, open past the red heat threshold of the channel.
+type StatsDiffChannel struct {
+    ChannelID     string `json:"channel_id,omitempty"`
+    ChannelName   string `json:"channel_name,omitempty"`
+    Opened        int    `json:"opened"`
+    Resolved      int    `json:"resolved"`
+    BacklogChange int    `json:"backlog_change"`
+    OpenFrom      int    `json:"open_from"`
+    OpenTo        int    `json:"open_to"`
+    NewStale      int    `json:"new_stale"`
+    StaleTo       int    `json:"stale_to"`
+}
+
+// StatsDiff compares the snapshots of two days, per channel and in total,
+// with a Markdown table of the changes for review documents.
+type StatsDiff struct {
+    From     string             `json:"from"`
+    To       string             `json:"to"`
+    Channels []StatsDiffChannel `json:"channels"`
+    Total    StatsDiffChannel   `json:"total"`
+    Markdown string             `json:"markdown"`
+}
+
+// diffSnapshots returns what changed in a channel from one snapshot to
+// another.
+func diffSnapshots(from snapshotCounts, to snapshotCounts) StatsDiffChannel {
+    diff := StatsDiffChannel{
+        Opened:        to.total - from.total,
+        BacklogChange: to.open - from.open,
+        OpenFrom:      from.open,
+        OpenTo:        to.open,
+        NewStale:      to.stale - from.stale,
+        StaleTo:       to.stale,
+    }
+    // Threads deleted from the threads table do not count as opened
+    if diff.Opened < 0 {
+        diff.Opened = 0
+    }
+    if diff.Resolved = diff.Opened - diff.BacklogChange; diff.Resolved < 0 {
+        diff.Resolved = 0
+    }
+    if diff.NewStale < 0 {
+        diff.NewStale = 0
+    }
+    return diff
+}
+
+// statsDiffMarkdown returns the changes of a diff as a Markdown table.
+func statsDiffMarkdown(diff *StatsDiff) string {
+    var md strings.Builder
+    fmt.Fprintf(&md, "### Open threads from %s to %s\n\n", diff.From, diff.To)
+    md.WriteString("| Channel | Opened | Resolved | Backlog | New stale |\n")
+    md.WriteString("|---|---:|---:|---:|---:|\n")
+    row := func(name string, d StatsDiffChannel) {
+        fmt.Fprintf(&md, "| %s | %d | %d | %+d (%d open) | %d |\n", name, d.Opened, d.Resolved, d.BacklogChange, d.OpenTo, d.NewStale)
+    }
+    for _, ch := range diff.Channels {
+        row("#"+ch.ChannelName, ch)
+    }
+    row("**Total**", diff.Total)
+    return md.String()
+}
+
+// GetStatsDiff - Compare the stats snapshots of two days per channel, as JSON or a Markdown table for weekly reviews
+func (c *Container) GetStatsDiff(ctx echo.Context) error {
+    format := ctx.QueryParam("format")
+    if format != "" && format != "json" && format != "markdown" {
+        return apierror.New(http.StatusBadRequest, "format must be json or markdown")
+    }
+
+    to := time.Now().UTC()
+    if value := ctx.QueryParam("to"); value != "" {
+        parsed, err := time.Parse(dateLayout, value)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, "to must be a date, e.g. 2024-01-31")
+        }
+        to = parsed
+    }
+    from := to.AddDate(0, 0, -statsDiffDefaultDays)
+    if value := ctx.QueryParam("from"); value != "" {
+        parsed, err := time.Parse(dateLayout, value)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, "from must be a date, e.g. 2024-01-24")
+        }
+        from = parsed
+    }
+    if !from.Before(to) {
+        return apierror.New(http.StatusBadRequest, "from must be before to")
+    }
+
+    diff := StatsDiff{From: from.Format(dateLayout), To: to.Format(dateLayout), Channels: []StatsDiffChannel{}}
+    before, err := c.stats.SnapshotsAsOf(ctx.Request().Context(), diff.From)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query stats snapshots")
+    }
+    after, err := c.stats.SnapshotsAsOf(ctx.Request().Context(), diff.To)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query stats snapshots")
+    }
+
+    channels, err := c.channels.Channels(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to get channels")
+    }
+    var totalFrom, totalTo snapshotCounts
+    for _, ch := range channels {
+        // Channels without a snapshot by to are left out, those tracked
+        // after from count from zero
+        counts, ok := after[ch.ChannelID]
+        if !ok {
+            continue
+        }
+        previous := before[ch.ChannelID]
+        channelDiff := diffSnapshots(previous, counts)
+        channelDiff.ChannelID, channelDiff.ChannelName = ch.ChannelID, ch.ChannelName
+        diff.Channels = append(diff.Channels, channelDiff)
+
+        totalFrom.total += previous.total
+        totalFrom.open += previous.open
+        totalFrom.stale += previous.stale
+        totalTo.total += counts.total
+        totalTo.open += counts.open
+        totalTo.stale += counts.stale
+    }
+    diff.Total = diffSnapshots(totalFrom, totalTo)
+
+    // Channels whose backlog grew the most come first
+    sort.Slice(diff.Channels, func(i, j int) bool {
+        a, b := diff.Channels[i], diff.Channels[j]
+        if a.BacklogChange != b.BacklogChange {
+            return a.BacklogChange > b.BacklogChange
+        }
+        return a.ChannelName < b.ChannelName
+    })
+    diff.Markdown = statsDiffMarkdown(&diff)
+
+    if format == "markdown" {
+        return ctx.Blob(http.StatusOK, "text/markdown; charset=utf-8", []byte(diff.Markdown))
+    }
+    return ctx.JSON(http.StatusOK, diff)
+}
+
+func (s postgresStore) SnapshotsAsOf(ctx context.Context, day string) (map[string]snapshotCounts, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT DISTINCT ON (channel_id) channel_id, total_threads, open_threads, COALESCE(stale_threads, 0)
+        FROM stats_snapshots
+        WHERE day <= $1
+        ORDER BY channel_id, day DESC
+    `, day)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    snapshots := map[string]snapshotCounts{}
+    for rows.Next() {
+        var channelID string
+        var counts snapshotCounts
+        if err := rows.Scan(&channelID, &counts.total, &counts.open, &counts.stale); err != nil {
+            return nil, err
+        }
+        snapshots[channelID] = counts
+    }
+    return snapshots, rows.Err()
+}
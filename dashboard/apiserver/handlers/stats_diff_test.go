@@ -0,0 +1,51 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+)
+
+func TestGetStatsDiff(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "sales"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: time.Now()}})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    // A week ago C1 had one open thread and C2 was not snapshotted yet
+    to := time.Now().UTC()
+    from := to.AddDate(0, 0, -statsDiffDefaultDays)
+    if err := store.CaptureStatsSnapshot(context.Background(), from); err != nil {
+        t.Fatal(err)
+    }
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusResolved, CreatedAt: time.Now()}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: statusOpen, CreatedAt: time.Now()}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C2", ThreadTS: "1700000000.000300", Status: statusOpen, CreatedAt: time.Now()}})
+    if err := store.CaptureStatsSnapshot(context.Background(), to); err != nil {
+        t.Fatal(err)
+    }
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/stats/diff", "/api/stats/diff", c.GetStatsDiff)
+    var diff StatsDiff
+    decodeResponse(t, rec, &diff)
+    want := []StatsDiffChannel{
+        {ChannelID: "C2", ChannelName: "sales", Opened: 1, BacklogChange: 1, OpenTo: 1},
+        {ChannelID: "C1", ChannelName: "support", Opened: 1, Resolved: 1, OpenFrom: 1, OpenTo: 1},
+    }
+    if len(diff.Channels) != len(want) || diff.Channels[0] != want[0] || diff.Channels[1] != want[1] {
+        t.Fatalf("got channels %+v, want %+v", diff.Channels, want)
+    }
+    if diff.Total.Opened != 2 || diff.Total.Resolved != 1 || diff.Total.BacklogChange != 1 {
+        t.Fatalf("got total %+v, want 2 opened, 1 resolved and the backlog up by 1", diff.Total)
+    }
+
+    rec = serveRequest(t, c, http.MethodGet, "/api/stats/diff", "/api/stats/diff?format=markdown", c.GetStatsDiff)
+    if !strings.Contains(rec.Body.String(), "| #support | 1 | 1 | +0 (1 open) | 0 |") {
+        t.Fatalf("got markdown %q, want a row for #support", rec.Body.String())
+    }
+}
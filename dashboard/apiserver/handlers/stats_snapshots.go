@@ -0,0 +1,77 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "time"
+)
+
+const snapshotInterval = time.Hour
+
+// RunStatsSnapshots records the open and stale thread counts of every
+// channel for the current day, refreshing them hourly so that each day keeps its last
+// value, until ctx is cancelled.
+func (c *Container) RunStatsSnapshots(ctx context.Context) {
+    ticker := time.NewTicker(snapshotInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.stats.CaptureStatsSnapshot(ctx, time.Now().UTC()); err != nil {
+            c.logger.Warnf("failed to capture stats snapshot: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (s postgresStore) CaptureStatsSnapshot(ctx context.Context, now time.Time) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    channels, err := trackedChannels(ctx, db)
+    if err != nil {
+        return err
+    }
+
+    day := now.Format("2006-01-02")
+    for _, ch := range channels {
+        // Open threads are stale once they turn red
+        var heatJSON sql.NullString
+        err := db.QueryRowContext(ctx, "SELECT heat_thresholds FROM channel_config WHERE channel_id = $1", ch.ID).Scan(&heatJSON)
+        if err != nil && err != sql.ErrNoRows {
+            return err
+        }
+        staleBefore := now.Add(-time.Duration(parseHeatThresholds(heatJSON).RedHours * float64(time.Hour)))
+
+        var total, open, openHigh, stale int
+        err = db.QueryRowContext(ctx, `
+            SELECT COUNT(*),
+                   COUNT(*) FILTER (WHERE status = 'open'),
+                   COUNT(*) FILTER (WHERE status = 'open' AND ai_priority = 'high'),
+                   COUNT(*) FILTER (WHERE status = 'open' AND created_at < $1)
+            FROM threads WHERE channel_id = $2
+        `, staleBefore, ch.ID).Scan(&total, &open, &openHigh, &stale)
+        if err != nil {
+            s.c.logger.Warnf("failed to count threads of channel %s: %v", ch.ID, err)
+            continue
+        }
+        _, err = db.ExecContext(ctx, `
+            INSERT INTO stats_snapshots (day, channel_id, total_threads, open_threads, open_high_threads, stale_threads, captured_at)
+            VALUES ($1, $2, $3, $4, $5, $6, NOW())
+            ON CONFLICT (day, channel_id) DO UPDATE SET
+                total_threads = EXCLUDED.total_threads,
+                open_threads = EXCLUDED.open_threads,
+                open_high_threads = EXCLUDED.open_high_threads,
+                stale_threads = EXCLUDED.stale_threads,
+                captured_at = EXCLUDED.captured_at
+        `, day, ch.ID, total, open, openHigh, stale)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+// maxTimeseriesPoints bounds how many intervals a time series covers.
+const maxTimeseriesPoints = 1000
+
+// spanUnits are the units of the range and interval of a time series.
+var spanUnits = map[byte]time.Duration{
+    'h': time.Hour,
+    'd': 24 * time.Hour,
+    'w': 7 * 24 * time.Hour,
+}
+
+// parseSpan parses a span such as 30d, 12h or 4w.
+func parseSpan(name string, value string) (time.Duration, error) {
+    if len(value) >= 2 {
+        if unit, ok := spanUnits[value[len(value)-1]]; ok {
+            if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n > 0 {
+                return time.Duration(n) * unit, nil
+            }
+        }
+    }
+    return 0, fmt.Errorf("%s must be a number of hours, days or weeks, e.g. 12h, 30d or 4w", name)
+}
+
+// statsScope limits stats to the channels of a channel group and to
+// channels by name or ID, when not empty.
+type statsScope struct {
+    group    string
+    channels []string
+}
+
+// scopeFilter returns a condition on the channels aliased ch limiting them
+// to scope, with its arguments. It returns errGroupNotFound when the group
+// of scope does not exist.
+func scopeFilter(db *sql.DB, scope statsScope) (string, []interface{}, error) {
+    groupFilter, args, err := groupChannelFilter(db, scope.group, "ch.channel_id", 0)
+    if err != nil {
+        return "", nil, err
+    }
+    channelFilter := "TRUE"
+    if len(scope.channels) > 0 {
+        args = append(args, pq.Array(scope.channels))
+        channelFilter = fmt.Sprintf("(ch.channel_name = ANY($%[1]d) OR ch.channel_id = ANY($%[1]d))", len(args))
+    }
+    return groupFilter + " AND " + channelFilter, args, nil
+}
+
+// StatsPoint counts the threads of an interval: those opened and resolved
+// during it, and those still active at its end.
+type StatsPoint struct {
+    Start    time.Time `json:"start"`
+    Opened   int       `json:"opened"`
+    Resolved int       `json:"resolved"`
+    Active   int       `json:"active"`
+}
+
+// ChannelTimeseries is the time series of a channel.
+type ChannelTimeseries struct {
+    ChannelID   string       `json:"channel_id"`
+    ChannelName string       `json:"channel_name"`
+    Points      []StatsPoint `json:"points"`
+}
+
+// StatsTimeseries counts threads interval by interval, per channel and in
+// total, for trend charts.
+type StatsTimeseries struct {
+    Range    string              `json:"range"`
+    Interval string              `json:"interval"`
+    Channels []ChannelTimeseries `json:"channels"`
+    Total    []StatsPoint        `json:"total"`
+}
+
+// newStatsPoints returns points intervals from first, with no threads.
+func newStatsPoints(first time.Time, interval time.Duration, points int) []StatsPoint {
+    series := make([]StatsPoint, points)
+    for i := range series {
+        series[i].Start = first.Add(time.Duration(i) * interval)
+    }
+    return series
+}
+
+// GetStatsTimeseries - Get the threads opened, resolved and active per interval and channel over a range, for trend charts
+func (c *Container) GetStatsTimeseries(ctx echo.Context) error {
+    rangeParam := ctx.QueryParam("range")
+    if rangeParam == "" {
+        rangeParam = "30d"
+    }
+    intervalParam := ctx.QueryParam("interval")
+    if intervalParam == "" {
+        intervalParam = "1d"
+    }
+    span, err := parseSpan("range", rangeParam)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+    interval, err := parseSpan("interval", intervalParam)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+    points := int((span + interval - 1) / interval)
+    if points > maxTimeseriesPoints {
+        return apierror.Newf(http.StatusBadRequest, "range covers more than %d intervals, use a longer interval", maxTimeseriesPoints)
+    }
+
+    // Intervals are aligned on UTC hours, days or Mondays and the last one
+    // is the current one
+    first := time.Now().UTC().Truncate(interval).Add(-time.Duration(points-1) * interval)
+    scope := statsScope{group: ctx.QueryParam("group"), channels: listParam(ctx.QueryParam("channel"))}
+    channels, err := c.stats.StatsTimeseries(ctx.Request().Context(), scope, first, interval, points)
+    if err == errGroupNotFound {
+        return apierror.New(http.StatusNotFound, "Channel group not found")
+    }
+    if err != nil {
+        c.logger.Errorf("failed to query stats time series: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to query stats time series")
+    }
+
+    result := StatsTimeseries{Range: rangeParam, Interval: intervalParam, Channels: channels, Total: newStatsPoints(first, interval, points)}
+    for _, ch := range channels {
+        for i, point := range ch.Points {
+            result.Total[i].Opened += point.Opened
+            result.Total[i].Resolved += point.Resolved
+            result.Total[i].Active += point.Active
+        }
+    }
+    sort.Slice(result.Channels, func(i, j int) bool {
+        return result.Channels[i].ChannelName < result.Channels[j].ChannelName
+    })
+    return ctx.JSON(http.StatusOK, result)
+}
+
+func (s postgresStore) StatsTimeseries(ctx context.Context, scope statsScope, first time.Time, interval time.Duration, points int) ([]ChannelTimeseries, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    filter, args, err := scopeFilter(db, scope)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT ch.channel_id, ch.channel_name
+        FROM channels ch
+        WHERE `+filter, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    series := map[string]*ChannelTimeseries{}
+    var channelIDs []string
+    for rows.Next() {
+        var channelID, channelName string
+        if err := rows.Scan(&channelID, &channelName); err != nil {
+            continue
+        }
+        channelIDs = append(channelIDs, channelID)
+        series[channelID] = &ChannelTimeseries{ChannelID: channelID, ChannelName: channelName, Points: newStatsPoints(first, interval, points)}
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+    rows.Close()
+
+    if len(channelIDs) > 0 {
+        // A thread counts in the intervals from the one it was opened in
+        // to the one it was closed in. Closed threads without a resolution
+        // time count as closed at their last update
+        pointRows, err := db.QueryContext(ctx, `
+            SELECT t.channel_id, b.start,
+                   COUNT(*) FILTER (WHERE t.created_at >= b.start),
+                   COUNT(*) FILTER (WHERE t.closed_at < b.start + $3::INTERVAL),
+                   COUNT(*) FILTER (WHERE t.closed_at IS NULL OR t.closed_at >= b.start + $3::INTERVAL)
+            FROM generate_series($1::TIMESTAMP, $2::TIMESTAMP, $3::INTERVAL) AS b(start)
+            JOIN (
+                SELECT channel_id, created_at, COALESCE(resolved_at, CASE WHEN status <> 'open' THEN updated_at END) AS closed_at
+                FROM threads WHERE channel_id = ANY($4)
+            ) t
+              ON t.created_at < b.start + $3::INTERVAL AND (t.closed_at IS NULL OR t.closed_at >= b.start)
+            GROUP BY t.channel_id, b.start
+        `, first, first.Add(time.Duration(points-1)*interval), fmt.Sprintf("%d seconds", int64(interval/time.Second)), pq.Array(channelIDs))
+        if err != nil {
+            return nil, err
+        }
+        defer pointRows.Close()
+
+        for pointRows.Next() {
+            var channelID string
+            var start time.Time
+            var opened, resolved, active int
+            if err := pointRows.Scan(&channelID, &start, &opened, &resolved, &active); err != nil {
+                continue
+            }
+            i := int(start.UTC().Sub(first) / interval)
+            ch, ok := series[channelID]
+            if !ok || i < 0 || i >= points {
+                continue
+            }
+            ch.Points[i].Opened, ch.Points[i].Resolved, ch.Points[i].Active = opened, resolved, active
+        }
+        if err := pointRows.Err(); err != nil {
+            return nil, err
+        }
+    }
+
+    channels := make([]ChannelTimeseries, 0, len(channelIDs))
+    for _, channelID := range channelIDs {
+        channels = append(channels, *series[channelID])
+    }
+    return channels, nil
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "net/http"
+    "sort"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/notify"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+const (
+    storageInterval = time.Hour
+    // storageGrowthWindow is how far back growth rates are measured
+    storageGrowthWindow = 7 * 24 * time.Hour
+)
+
+// Kinds of storage alerts.
+const (
+    storageAlertQuota  = "quota"
+    storageAlertGrowth = "growth"
+)
+
+// TableStorage is the size of a table and how fast it grows. Rows are the
+// planner's estimate, exact counts being too slow on large tables.
+type TableStorage struct {
+    Table        string  `json:"table"`
+    Rows         int64   `json:"rows"`
+    SizeBytes    int64   `json:"size_bytes"`
+    RowsPerDay   float64 `json:"rows_per_day"`
+    BytesPerDay  float64 `json:"bytes_per_day"`
+    MeasuredDays float64 `json:"measured_days"`
+}
+
+// StorageAlert is a soft quota crossed by the database.
+type StorageAlert struct {
+    Kind    string `json:"kind"`
+    Message string `json:"message"`
+}
+
+// StorageReport is the storage used by the tables of the dashboard and
+// the collector in a database.
+type StorageReport struct {
+    Tables         []TableStorage `json:"tables"`
+    TotalRows      int64          `json:"total_rows"`
+    TotalBytes     int64          `json:"total_bytes"`
+    BytesPerDay    float64        `json:"bytes_per_day"`
+    QuotaBytes     int64          `json:"quota_bytes"`
+    MaxBytesPerDay int64          `json:"max_bytes_per_day"`
+    DaysUntilQuota *float64       `json:"days_until_quota"`
+    Alerts         []StorageAlert `json:"alerts"`
+    MeasuredAt     time.Time      `json:"measured_at"`
+}
+
+// measureStorage returns the size and estimated rows of tables.
+func measureStorage(ctx context.Context, db *sql.DB, tables []string) ([]TableStorage, error) {
+    rows, err := db.QueryContext(ctx, `
+        SELECT c.relname, GREATEST(c.reltuples, 0)::BIGINT, pg_total_relation_size(c.oid)
+        FROM pg_class c
+        JOIN pg_namespace n ON n.oid = c.relnamespace
+        WHERE c.relkind = 'r' AND n.nspname = current_schema() AND c.relname = ANY($1)
+    `, pq.Array(tables))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    measured := []TableStorage{}
+    for rows.Next() {
+        var table TableStorage
+        if err := rows.Scan(&table.Table, &table.Rows, &table.SizeBytes); err != nil {
+            return nil, err
+        }
+        measured = append(measured, table)
+    }
+    return measured, rows.Err()
+}
+
+// storageSnapshot is the size of a table when it was captured.
+type storageSnapshot struct {
+    rows, size int64
+    capturedAt time.Time
+}
+
+// storageReport measures the tables of the database and compares them with
+// the oldest snapshot of the growth window.
+func (c *Container) storageReport(ctx context.Context) (*StorageReport, error) {
+    measured, err := c.storage.MeasureTables(ctx)
+    if err != nil {
+        return nil, err
+    }
+    now := time.Now().UTC()
+    previous, err := c.storage.StorageSnapshots(ctx, now.Add(-storageGrowthWindow))
+    if err != nil {
+        return nil, err
+    }
+
+    report := &StorageReport{
+        Tables:         measured,
+        QuotaBytes:     c.storageQuota,
+        MaxBytesPerDay: c.storageMaxGrowth,
+        Alerts:         []StorageAlert{},
+        MeasuredAt:     now,
+    }
+    for i := range report.Tables {
+        table := &report.Tables[i]
+        report.TotalRows += table.Rows
+        report.TotalBytes += table.SizeBytes
+        // Growth needs an hour of history to mean anything
+        if s, ok := previous[table.Table]; ok && now.Sub(s.capturedAt) >= storageInterval {
+            table.MeasuredDays = now.Sub(s.capturedAt).Hours() / 24
+            table.RowsPerDay = float64(table.Rows-s.rows) / table.MeasuredDays
+            table.BytesPerDay = float64(table.SizeBytes-s.size) / table.MeasuredDays
+            report.BytesPerDay += table.BytesPerDay
+        }
+    }
+    sort.Slice(report.Tables, func(i, j int) bool {
+        return report.Tables[i].SizeBytes > report.Tables[j].SizeBytes
+    })
+
+    if report.QuotaBytes > 0 {
+        if report.TotalBytes >= report.QuotaBytes {
+            report.Alerts = append(report.Alerts, StorageAlert{
+                Kind: storageAlertQuota,
+                Message: fmt.Sprintf("tables use %s, over the soft quota of %s",
+                    formatBytes(report.TotalBytes), formatBytes(report.QuotaBytes)),
+            })
+        } else if report.BytesPerDay > 0 {
+            days := float64(report.QuotaBytes-report.TotalBytes) / report.BytesPerDay
+            report.DaysUntilQuota = &days
+        }
+    }
+    if report.MaxBytesPerDay > 0 && report.BytesPerDay >= float64(report.MaxBytesPerDay) {
+        report.Alerts = append(report.Alerts, StorageAlert{
+            Kind: storageAlertGrowth,
+            Message: fmt.Sprintf("tables grow by %s a day, over the limit of %s",
+                formatBytes(int64(report.BytesPerDay)), formatBytes(report.MaxBytesPerDay)),
+        })
+    }
+    return report, nil
+}
+
+// formatBytes formats a size with a binary unit, e.g. "1.5 GiB".
+func formatBytes(bytes int64) string {
+    const unit = 1024
+    if bytes < unit {
+        return fmt.Sprintf("%d B", bytes)
+    }
+    div, exp := int64(unit), 0
+    for n := bytes / unit; n >= unit; n /= unit {
+        div *= unit
+        exp++
+    }
+    return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// RunStorageMonitor records the size of the tables hourly and warns once a
+// day about each soft quota crossed, until ctx is cancelled.
+func (c *Container) RunStorageMonitor(ctx context.Context) {
+    ticker := time.NewTicker(storageInterval)
+    defer ticker.Stop()
+    // alerted remembers the day each kind of alert was last sent
+    alerted := map[string]string{}
+    for {
+        if err := c.checkStorage(ctx, alerted); err != nil {
+            c.logger.Warnf("failed to check database storage: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (c *Container) checkStorage(ctx context.Context, alerted map[string]string) error {
+    report, err := c.storageReport(ctx)
+    if err != nil {
+        return err
+    }
+    if err := c.storage.RecordStorage(ctx, report.MeasuredAt, report.Tables); err != nil {
+        return err
+    }
+
+    day := report.MeasuredAt.Format("2006-01-02")
+    for _, alert := range report.Alerts {
+        if alerted[alert.Kind] == day {
+            continue
+        }
+        alerted[alert.Kind] = day
+
+        name := "database"
+        if team := workspaceFrom(ctx); team != "" {
+            name = "database of workspace " + team
+        }
+        c.logger.Warnf("%s: %s, consider archiving or exporting old threads", name, alert.Message)
+        c.audit(ctx, "system", "storage.alert", "", "", map[string]interface{}{
+            "kind":          alert.Kind,
+            "message":       alert.Message,
+            "total_bytes":   report.TotalBytes,
+            "bytes_per_day": report.BytesPerDay,
+        })
+        err := c.notifier.Notify(ctx, notify.Notification{
+            Event:   notify.EventStorageAlert,
+            Channel: c.storageAlertChannel,
+            Text:    fmt.Sprintf(":warning: Open Threads Reminder database storage: %s. Consider archiving or exporting old threads.", alert.Message),
+        })
+        if err != nil {
+            c.logger.Warnf("failed to deliver storage alert: %v", err)
+        }
+    }
+    return nil
+}
+
+// GetStorage - Get the size and growth of the dashboard and collector tables, with the soft quotas crossed
+func (c *Container) GetStorage(ctx echo.Context) error {
+    report, err := c.storageReport(ctx.Request().Context())
+    if err != nil {
+        c.logger.Errorf("failed to measure database storage: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to measure database storage")
+    }
+    return ctx.JSON(http.StatusOK, report)
+}
+
+func (s postgresStore) MeasureTables(ctx context.Context) ([]TableStorage, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return measureStorage(ctx, db, schemaTables())
+}
+
+func (s postgresStore) StorageSnapshots(ctx context.Context, since time.Time) (map[string]storageSnapshot, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT DISTINCT ON (table_name) table_name, row_count, size_bytes, captured_at
+        FROM storage_snapshots
+        WHERE captured_at >= $1
+        ORDER BY table_name, captured_at
+    `, since)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    snapshots := map[string]storageSnapshot{}
+    for rows.Next() {
+        var table string
+        var snapshot storageSnapshot
+        if err := rows.Scan(&table, &snapshot.rows, &snapshot.size, &snapshot.capturedAt); err == nil {
+            snapshots[table] = snapshot
+        }
+    }
+    return snapshots, rows.Err()
+}
+
+func (s postgresStore) RecordStorage(ctx context.Context, measuredAt time.Time, tables []TableStorage) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    for _, table := range tables {
+        _, err := db.ExecContext(ctx, `
+            INSERT INTO storage_snapshots (day, table_name, row_count, size_bytes, captured_at)
+            VALUES ($1, $2, $3, $4, $5)
+            ON CONFLICT (day, table_name) DO NOTHING
+        `, measuredAt.Format("2006-01-02"), table.Table, table.Rows, table.SizeBytes, measuredAt)
+        if err != nil {
+            return err
+        }
+    }
+    // Snapshots older than the growth window are never read again
+    _, err = db.ExecContext(ctx, "DELETE FROM storage_snapshots WHERE captured_at < $1", measuredAt.Add(-2*storageGrowthWindow))
+    return err
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+
+    "dashboard/apiserver/notify"
+)
+
+func TestStorageAlertsOncePerDay(t *testing.T) {
+    store := NewMemoryStore()
+    store.SetTables(TableStorage{Table: "threads", Rows: 10, SizeBytes: 3 << 20}, TableStorage{Table: "thread_messages", Rows: 40, SizeBytes: 1 << 20})
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.notifier, _ = notify.NewRouter(c.logger, nil)
+    c.storageQuota = 2 << 20
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/storage", "/api/storage", c.GetStorage)
+    var report StorageReport
+    decodeResponse(t, rec, &report)
+    if report.TotalBytes != 4<<20 || report.Tables[0].Table != "threads" || len(report.Alerts) != 1 || report.Alerts[0].Kind != storageAlertQuota {
+        t.Fatalf("got report %+v", report)
+    }
+
+    alerted := map[string]string{}
+    for range 2 {
+        if err := c.checkStorage(context.Background(), alerted); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if audited := store.Audited(); len(audited) != 1 || audited[0].Action != "storage.alert" {
+        t.Fatalf("got audit entries %+v, want one storage alert", audited)
+    }
+    snapshots, _ := store.StorageSnapshots(context.Background(), report.MeasuredAt.Add(-storageGrowthWindow))
+    if len(snapshots) != 2 || snapshots["threads"].size != 3<<20 {
+        t.Fatalf("got snapshots %+v", snapshots)
+    }
+}
@@ -0,0 +1,1571 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "time"
+
+    "dashboard/apiserver/analysis"
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/events"
+    "dashboard/apiserver/ingest"
+    "dashboard/apiserver/similarity"
+    "dashboard/apiserver/slack"
+    "dashboard/apiserver/webhooks"
+)
+
+// StoredThread is a stored thread with its AI title and the issues linked
+// to it.
+type StoredThread struct {
+    domain.Thread
+    Title       string
+    GithubIssue string
+    JiraTicket  string
+}
+
+// ChannelSummary is a tracked channel with its thread counts.
+type ChannelSummary struct {
+    domain.Channel
+    ThreadCount       int       `json:"thread_count"`
+    ActiveThreadCount int       `json:"active_thread_count"`
+    LastActivity      time.Time `json:"last_activity"`
+    CreatedAt         time.Time `json:"created_at"`
+}
+
+// ThreadStore reads the threads of tracked channels.
+type ThreadStore interface {
+    // Thread returns a thread, errChannelNotTracked or errThreadNotFound
+    // when it is missing.
+    Thread(ctx context.Context, channelID string, threadTS string) (StoredThread, error)
+    // Notes returns the triage notes of a thread, oldest first.
+    Notes(ctx context.Context, channelID string, threadTS string) ([]ThreadNote, error)
+    // Watchers returns the users watching a thread, oldest first.
+    Watchers(ctx context.Context, channelID string, threadTS string) ([]Watcher, error)
+    // Effort returns the estimate and time entries of a thread.
+    Effort(ctx context.Context, channelID string, threadTS string) (ThreadEffort, error)
+    // Resolution returns how a thread was resolved, errThreadNotFound when
+    // it is missing.
+    Resolution(ctx context.Context, channelID string, threadTS string) (threadResolution, error)
+}
+
+// NoteStore keeps the triage notes of threads.
+type NoteStore interface {
+    // CreateNote stores a note and returns it with its ID and creation
+    // time.
+    CreateNote(ctx context.Context, note ThreadNote) (ThreadNote, error)
+    // RecordNoteSync records the comments a note was mirrored as and why
+    // mirroring it failed.
+    RecordNoteSync(ctx context.Context, note ThreadNote) error
+}
+
+// EffortStore keeps the effort estimates of threads and the time people
+// track on them.
+type EffortStore interface {
+    // SetEstimate sets the estimate of a thread on behalf of actor, nil
+    // clears it.
+    SetEstimate(ctx context.Context, channelID, threadTS string, minutes *int, actor string) error
+    // StartTimer starts tracking the time of a user on a thread. Starting
+    // a running timer does nothing.
+    StartTimer(ctx context.Context, channelID, threadTS, userID string) error
+    // StopTimer stops the running timer of a user on a thread and reports
+    // false when none was running.
+    StopTimer(ctx context.Context, channelID, threadTS, userID string) (bool, error)
+    // EffortTotals returns the effort estimated and tracked since a time
+    // per channel and per person, the most tracked person first.
+    EffortTotals(ctx context.Context, since time.Time) ([]EffortTotals, []EffortTotals, error)
+}
+
+// WatcherStore keeps the users watching threads.
+type WatcherStore interface {
+    // RecentOpenThreads returns the open threads of tracked channels
+    // created after since.
+    RecentOpenThreads(ctx context.Context, since time.Time) ([]domain.Thread, error)
+    // SetReactionWatchers replaces the reaction based watchers of a thread.
+    // Watchers from other sources are kept.
+    SetReactionWatchers(ctx context.Context, channelID, threadTS string, users []string) error
+}
+
+// ThreadListStore lists the threads of tracked channels. Methods taking
+// filters return errGroupNotFound when they name a channel group that does
+// not exist.
+type ThreadListStore interface {
+    // ListThreads returns a page of threads, presented for their channel,
+    // legal holds and SLA, and the count of the threads matching the
+    // filters of the list.
+    ListThreads(ctx context.Context, list threadList) ([]Thread, int, error)
+    // GroupThreads returns the channels with threads matching the filters
+    // of a list, each with up to the limit of the list in its sort and
+    // order, and the count of those threads across channels.
+    GroupThreads(ctx context.Context, list threadList) ([]ChannelThreads, int, error)
+    // StreamThreads calls fn with every thread matching the filters of a
+    // list, in its sort and order, until fn returns an error.
+    StreamThreads(ctx context.Context, list threadList, fn func(Thread) error) error
+    // CountByPriority counts the threads matching filters by their
+    // calibrated priority: high, medium, low and none.
+    CountByPriority(ctx context.Context, filters threadFilters) (map[string]int, error)
+    // ThreadsByRef returns the threads of tracked channels among refs,
+    // presented like lists present them. Missing threads are left out.
+    ThreadsByRef(ctx context.Context, refs []ThreadRef) (map[ThreadRef]Thread, error)
+    // SearchThreads returns the best matches of a search among the threads
+    // of channels indexing their text, best first.
+    SearchThreads(ctx context.Context, search threadSearch) ([]ThreadMatch, error)
+}
+
+// ThreadStatusStore changes the status of threads.
+type ThreadStatusStore interface {
+    // SetStatus changes the status of a thread once allow accepted the
+    // change from the status the thread had, the thread staying locked in
+    // between. Leaving a release or the wait on its author ends them, and
+    // a resolution labels the answer signals of the thread. It returns
+    // errChannelNotTracked or errThreadNotFound when the thread is
+    // missing, and the error of allow when it refused the change.
+    SetStatus(ctx context.Context, change StatusChange, allow func(previous domain.Status, needsApproval bool) error) (StatusResult, error)
+    // SnoozeThread snoozes a thread until a time and returns the status it
+    // had. Resolved and closed threads are left as they are, snoozing again
+    // only moves the end of the snooze. Leaving a release or the wait on
+    // its author ends them. It returns errChannelNotTracked or
+    // errThreadNotFound when the thread is missing.
+    SnoozeThread(ctx context.Context, channelID string, threadTS string, until time.Time) (domain.Status, error)
+    // WakeSnoozedThreads opens again the snoozed threads whose snooze ended
+    // by now and returns them.
+    WakeSnoozedThreads(ctx context.Context, now time.Time) ([]ThreadRef, error)
+}
+
+// ThreadOverrideStore keeps the priorities and due dates set by hand on
+// threads.
+type ThreadOverrideStore interface {
+    // ThreadPriority returns the priorities of a thread, errChannelNotTracked
+    // or errThreadNotFound when it is missing.
+    ThreadPriority(ctx context.Context, channelID, threadTS string) (ThreadPriority, error)
+    // SetThreadPriority overrides the priority of a thread on behalf of
+    // actor, nil clears the override.
+    SetThreadPriority(ctx context.Context, channelID, threadTS string, priority *string, actor string) error
+    // SetThreadSLA overrides the due date of a thread on behalf of actor,
+    // nil clears the override.
+    SetThreadSLA(ctx context.Context, channelID, threadTS string, dueAt *time.Time, actor string) error
+}
+
+// BulkThreadStore changes many threads at once.
+type BulkThreadStore interface {
+    // BulkUpdate applies a bulk operation to all of its threads or none,
+    // the threads staying locked in between. It returns the threads it
+    // changed and those it left as they were, errThreadsNotFound when some
+    // threads do not exist and errNeedsApproval when resolving some needs
+    // approval.
+    BulkUpdate(ctx context.Context, update bulkUpdate) ([]bulkChange, []ThreadRef, error)
+}
+
+// ResolutionStore resolves threads and keeps the resolution requests of
+// those needing approval.
+type ResolutionStore interface {
+    // CloseThread marks an open thread as resolved by actor for a
+    // resolution, which may be empty when unknown, and note. It reports
+    // false when the thread was not open.
+    CloseThread(ctx context.Context, channelID, threadTS, actor, resolution, note string) (bool, error)
+    // ResolveOrRequest closes an open thread like CloseThread or, when its
+    // channel requires approval, requests its resolution. It returns the
+    // outcome, one of resolveClosed, resolveRequested, resolveAlreadyClosed
+    // and resolveAlreadyRequested, or errThreadNotFound.
+    ResolveOrRequest(ctx context.Context, channelID, threadTS, actor, resolution, reason string) (int, error)
+    // PendingResolution returns the request of a thread waiting for
+    // approval, errResolutionNotPending when none is.
+    PendingResolution(ctx context.Context, channelID, threadTS string) (ResolutionRequest, error)
+    // DecideResolution approves or rejects a pending request on behalf of
+    // actor, an approval closing the thread for the requester. It reports
+    // false when the request was decided concurrently.
+    DecideResolution(ctx context.Context, request ResolutionRequest, decision string, actor string, note string) (bool, error)
+    // ResolutionRequests returns the latest 500 requests with a status,
+    // newest first.
+    ResolutionRequests(ctx context.Context, status string) ([]ResolutionRequest, error)
+}
+
+// AssignmentStore keeps who threads are assigned to and claimed by.
+type AssignmentStore interface {
+    // AssignThread records assignee as the owner of a thread on behalf of
+    // assignedBy, or unassigns it when assignee is empty, and returns the
+    // previous assignee, empty when there was none.
+    AssignThread(ctx context.Context, channelID, threadTS, assignee, assignedBy string) (string, error)
+    // ClaimThread records userID as the claimer of a thread unless someone
+    // claimed it before. It returns the claimer and whether userID just
+    // claimed it.
+    ClaimThread(ctx context.Context, channelID, threadTS, userID string) (string, bool, error)
+}
+
+// AckStore keeps the first acknowledgment of threads.
+type AckStore interface {
+    // RecordAck stores the first acknowledgment of a thread and reports
+    // whether it was the first. Later acknowledgments are ignored.
+    RecordAck(ctx context.Context, channelID, threadTS, userID, source string, threadCreatedAt, ackedAt time.Time) (bool, error)
+    // Acknowledge records userID as acknowledging a thread now unless
+    // someone did before, and returns the acknowledgment of the thread and
+    // whether it was the first. It returns errChannelNotTracked or
+    // errThreadNotFound when the thread is missing.
+    Acknowledge(ctx context.Context, userID, source, channelID, threadTS string) (ThreadAck, bool, error)
+    // AckLatency summarizes how quickly each person acknowledges threads,
+    // in a channel and since a time when not empty.
+    AckLatency(ctx context.Context, channelID string, since time.Time) ([]AckLatency, error)
+}
+
+// ReporterWaitStore keeps the threads waiting on their author.
+type ReporterWaitStore interface {
+    // WaitOnReporter sets an open thread waiting on its author, to be
+    // nudged nudgeAfter since the wait started, and returns when it started
+    // and the nudge. Setting it again only moves the nudge. It returns
+    // errThreadNotOpen unless the thread is open or already waiting.
+    WaitOnReporter(ctx context.Context, channelID string, threadTS string, actor string, nudgeAfter time.Duration) (time.Time, time.Time, error)
+    // ResumeFromReporter reopens a thread waiting on its author and stops
+    // the wait. It reports false when the thread was not waiting.
+    ResumeFromReporter(ctx context.Context, channelID string, threadTS string) (bool, error)
+    // DueReporterNudges returns the threads of tracked channels still
+    // waiting on their author whose nudge is due and not sent yet. Waits
+    // of threads reopened outside of the dashboard end.
+    DueReporterNudges(ctx context.Context) ([]ReporterNudge, error)
+    // MarkReporterNudged records that the author of a thread was nudged.
+    MarkReporterNudged(ctx context.Context, channelID string, threadTS string) error
+}
+
+// ReleaseStore keeps the GitHub releases threads wait on.
+type ReleaseStore interface {
+    // WaitOnRelease parks an open thread until a release ships on behalf
+    // of actor, replacing the release it waited on. It returns
+    // errThreadNotOpen unless the thread is open or waiting on a release.
+    WaitOnRelease(ctx context.Context, channelID, threadTS, repo, tag, actor string) error
+    // StopWaitingOnRelease reopens a thread waiting on a release that did
+    // not ship yet and reports false when it was not waiting on one.
+    StopWaitingOnRelease(ctx context.Context, channelID, threadTS string) (bool, error)
+    // PendingReleases returns the releases threads wait on that did not
+    // ship yet.
+    PendingReleases(ctx context.Context) ([]pendingRelease, error)
+    // ShipRelease records that a release of a repository shipped at url
+    // and reopens the threads of tracked channels waiting on it, which it
+    // returns.
+    ShipRelease(ctx context.Context, repo, tag, url string) ([]ThreadRef, error)
+}
+
+// AnswerSignalStore keeps the signals that threads look answered.
+type AnswerSignalStore interface {
+    // RecordAnswerSignal notes that a thread looks answered and reports
+    // whether to surface the signal: new for the thread and not muted in
+    // its channel. Signals dismissed before stay dismissed.
+    RecordAnswerSignal(ctx context.Context, channelID, threadTS, signal, userID string, detectedAt time.Time) (bool, error)
+    // AnsweredThreads returns the open threads of tracked channels with
+    // signals neither dismissed nor muted.
+    AnsweredThreads(ctx context.Context) ([]AnsweredThread, error)
+    // DismissAnswerSignals dismisses the signals of a thread on behalf of
+    // actor. It reports false when none was left to dismiss.
+    DismissAnswerSignals(ctx context.Context, channelID string, threadTS string, actor string) (bool, error)
+}
+
+// StatsStore counts the threads of tracked channels.
+type StatsStore interface {
+    // DashboardStats counts the threads of the tracked channels, of a
+    // channel group when not empty. It returns errGroupNotFound when the
+    // group does not exist.
+    DashboardStats(ctx context.Context, group string) (DashboardStats, error)
+    // UserStats returns the involvement of users in tracked threads, keyed
+    // by user.
+    UserStats(ctx context.Context, userIDs []string) (map[string]*UserStats, error)
+    // StatsTimeseries returns the series of points intervals from first of
+    // the tracked channels in scope. It returns errGroupNotFound when the
+    // group of scope does not exist.
+    StatsTimeseries(ctx context.Context, scope statsScope, first time.Time, interval time.Duration, points int) ([]ChannelTimeseries, error)
+    // ResolutionStats returns the resolution mix since a time of the
+    // tracked channels in scope sorted by name, with the accuracy of their
+    // answer signals. It returns errGroupNotFound when the group of scope
+    // does not exist.
+    ResolutionStats(ctx context.Context, scope statsScope, since time.Time) ([]ResolutionMix, []AnswerSignalAccuracy, error)
+    // UsergroupStats counts the open threads of every usergroup.
+    UsergroupStats(ctx context.Context) ([]UsergroupStats, error)
+    // CaptureStatsSnapshot records the thread counts of every tracked
+    // channel for the day of now, replacing those recorded earlier that
+    // day.
+    CaptureStatsSnapshot(ctx context.Context, now time.Time) error
+    // SnapshotsAsOf returns the latest snapshot of every channel taken on
+    // or before day, keyed by channel.
+    SnapshotsAsOf(ctx context.Context, day string) (map[string]snapshotCounts, error)
+}
+
+// ChannelStore lists the tracked channels.
+type ChannelStore interface {
+    // Channels returns the tracked channels sorted by name.
+    Channels(ctx context.Context) ([]ChannelSummary, error)
+    // Channel returns a tracked channel, errChannelNotTracked when it is
+    // not.
+    Channel(ctx context.Context, channelID string) (ChannelSummary, error)
+    // Tracked returns errChannelNotTracked unless a channel is tracked.
+    Tracked(ctx context.Context, channelID string) error
+    // TrackChannel starts tracking a channel, false when it already is.
+    TrackChannel(ctx context.Context, channelID string, channelName string) (bool, error)
+    // TextIndexing reports whether message text of a channel may be kept,
+    // true unless the channel opted out.
+    TextIndexing(ctx context.Context, channelID string) (bool, error)
+}
+
+// ChannelGroupStore keeps the named sets of channels. Methods taking the
+// ID of a group return errGroupNotFound when it does not exist, and those
+// naming one errGroupNameTaken when another group has its name.
+type ChannelGroupStore interface {
+    // ChannelGroups returns the groups sorted by name.
+    ChannelGroups(ctx context.Context) ([]ChannelGroup, error)
+    // ChannelGroup returns a group.
+    ChannelGroup(ctx context.Context, id int64) (ChannelGroup, error)
+    // CreateChannelGroup stores a new group of the tracked channels among
+    // its channels and returns it with its ID.
+    CreateChannelGroup(ctx context.Context, g ChannelGroup) (ChannelGroup, error)
+    // UpdateChannelGroup replaces the name, description, reminder policy
+    // and channels of the group with the ID of g and returns it.
+    UpdateChannelGroup(ctx context.Context, g ChannelGroup) (ChannelGroup, error)
+    // DeleteChannelGroup deletes a group, leaving its channels untouched.
+    DeleteChannelGroup(ctx context.Context, id int64) error
+}
+
+// GoalStore keeps the goals set on snapshot metrics. Methods taking the ID
+// of a goal return errGoalNotFound when it does not exist.
+type GoalStore interface {
+    // Goals returns the goals, the first due first.
+    Goals(ctx context.Context) ([]Goal, error)
+    // Goal returns a goal.
+    Goal(ctx context.Context, id int64) (Goal, error)
+    // CreateGoal stores a new goal and returns it with its ID.
+    CreateGoal(ctx context.Context, g Goal) (Goal, error)
+    // UpdateGoal replaces the definition of the goal with the ID of g and
+    // returns it.
+    UpdateGoal(ctx context.Context, g Goal) (Goal, error)
+    // DeleteGoal deletes a goal.
+    DeleteGoal(ctx context.Context, id int64) error
+    // GoalPoints returns the daily value of the metric of a goal from its
+    // start to its due date, as far as snapshots were taken.
+    GoalPoints(ctx context.Context, g Goal) ([]GoalPoint, error)
+}
+
+// ReplyTemplateStore keeps the canned replies. Methods taking a template
+// return errReplyTemplateNotFound when it does not exist, and those naming
+// one errReplyTemplateNameTaken when another template has its name.
+type ReplyTemplateStore interface {
+    // ReplyTemplates returns the templates sorted by name.
+    ReplyTemplates(ctx context.Context) ([]ReplyTemplate, error)
+    // ReplyTemplate returns the template with a name, or with an ID when
+    // name is a number.
+    ReplyTemplate(ctx context.Context, name string) (ReplyTemplate, error)
+    // CreateReplyTemplate stores a new template and returns it with its ID.
+    CreateReplyTemplate(ctx context.Context, t ReplyTemplate) (ReplyTemplate, error)
+    // UpdateReplyTemplate replaces the name and body of the template with
+    // the ID of t and returns it.
+    UpdateReplyTemplate(ctx context.Context, t ReplyTemplate) (ReplyTemplate, error)
+    // DeleteReplyTemplate deletes a template.
+    DeleteReplyTemplate(ctx context.Context, id int64) error
+}
+
+// ChannelConfigStore keeps the configuration of tracked channels. Methods
+// taking a channel return errChannelNotTracked when it is not tracked.
+type ChannelConfigStore interface {
+    // ChannelConfig returns the configuration of a channel.
+    ChannelConfig(ctx context.Context, channelID string) (ChannelConfig, error)
+    // ChannelConfigs returns the configuration of every tracked channel,
+    // keyed by channel.
+    ChannelConfigs(ctx context.Context) (map[string]ChannelConfig, error)
+    // SetChannelConfig stores the named settings of config for a channel,
+    // leaving its other settings as they are.
+    SetChannelConfig(ctx context.Context, channelID string, config ChannelConfig, settings ...channelSetting) error
+    // ClearChannelText drops the message text and AI analysis stored for
+    // the threads of a channel and returns how many threads it cleared.
+    ClearChannelText(ctx context.Context, channelID string) (int64, error)
+}
+
+// AnalysisStore keeps the AI analysis of threads.
+type AnalysisStore interface {
+    // SaveAnalysis stores the AI analysis of a thread with its
+    // stakeholders, like the collector does.
+    SaveAnalysis(ctx context.Context, channelID string, threadTS string, result *analysis.Result, stakeholders []string) error
+}
+
+// CalibrationStore samples the outcomes of threads to calibrate the AI
+// priority of channels.
+type CalibrationStore interface {
+    // CalibrationSamples returns the AI priority and outcome of the
+    // threads of a channel created since a time, either settled or opened
+    // before settledBefore.
+    CalibrationSamples(ctx context.Context, channelID string, since time.Time, settledBefore time.Time) ([]calibrationSample, error)
+}
+
+// ChannelSummaryStore keeps how the open threads of channels are
+// summarized in Slack and what the dashboard last wrote there.
+type ChannelSummaryStore interface {
+    // SlackSummary returns the summary configuration of a channel, off
+    // when never set.
+    SlackSummary(ctx context.Context, channelID string) (SlackSummary, error)
+    // SetSummaryMode sets the summary mode of a channel on behalf of
+    // actor, clearing the last error. The canvas and pinned message are
+    // kept, a changed mode writes the summary anew.
+    SetSummaryMode(ctx context.Context, channelID, mode, actor string) error
+    // SummarizedChannels returns the channels among channels whose summary
+    // is on, of every channel when nil.
+    SummarizedChannels(ctx context.Context, channels []string) ([]string, error)
+    // SummaryState returns where the summary of a channel was written and
+    // the hash of its content, off when never set.
+    SummaryState(ctx context.Context, channelID string) (summaryState, error)
+    // SummaryWritten records the summary of a channel written to Slack
+    // with the hash of its content.
+    SummaryWritten(ctx context.Context, channelID string, state summaryState) error
+    // SummaryFailed records why the summary of a channel was not written.
+    SummaryFailed(ctx context.Context, channelID, message string) error
+}
+
+// MessageStore keeps the Slack messages of tracked threads.
+type MessageStore interface {
+    // StartThread stores a new thread with its first message. A thread
+    // stored before is kept as it is.
+    StartThread(ctx context.Context, thread domain.Thread, first ThreadMessage) error
+    // TrackThread stores a thread whose messages are not known, e.g. one
+    // posted before its channel was tracked. It reports false when the
+    // thread was stored before, leaving it as it is.
+    TrackThread(ctx context.Context, thread domain.Thread) (bool, error)
+    // AddReply stores a reply and counts it on its thread in one
+    // transaction. A reply stored before is not counted again, so that
+    // redelivered messages leave the thread as it is. It returns the
+    // thread and whether the reply was new, errThreadNotFound when the
+    // thread is not stored.
+    AddReply(ctx context.Context, channelID string, threadTS string, reply ThreadMessage) (domain.Thread, bool, error)
+    // Messages returns the stored messages of a thread, oldest first.
+    Messages(ctx context.Context, channelID string, threadTS string) ([]ThreadMessage, error)
+    // KeepMessage stores a message of a thread fetched from Slack without
+    // counting it on the thread. A message stored before is kept as it is.
+    KeepMessage(ctx context.Context, channelID string, threadTS string, msg ThreadMessage) error
+}
+
+// BackfillStore keeps the progress of backfills and the threads they
+// import from channel history.
+type BackfillStore interface {
+    ingest.CheckpointStore
+    // ImportThreads stores thread roots found in the history of a channel.
+    // Counts of threads stored before only ever grow, so that importing a
+    // page again is harmless. It returns errChannelNotTracked when the
+    // channel is not tracked.
+    ImportThreads(ctx context.Context, channelID string, threads []domain.Thread) error
+}
+
+// ReminderStore finds the threads reminders are sent about and remembers
+// who was reminded of them.
+type ReminderStore interface {
+    // IdleThreads returns the open threads of a channel without activity
+    // since cutoff or past their due date, set by hand or by the SLA hours
+    // of their priority.
+    IdleThreads(ctx context.Context, channelID string, cutoff time.Time, slaHours SLAHours) ([]idleThread, error)
+    // Nudges returns when recipients were last reminded of threads since
+    // a time, keyed by recipient and nudge key, e.g. "U1|C1:1700000000.000100".
+    Nudges(ctx context.Context, since time.Time) (map[string]time.Time, error)
+    // RecordNudge remembers that recipient was just reminded of a thread.
+    RecordNudge(ctx context.Context, recipient string, channelID string, threadTS string) error
+}
+
+// QualityPromptStore finds the incomplete threads whose authors are asked
+// for what is missing, and remembers what they were asked.
+type QualityPromptStore interface {
+    // UnpromptedThreads returns the open threads of a channel started
+    // since a time, analyzed and never prompted, with their quality.
+    UnpromptedThreads(ctx context.Context, channelID string, since time.Time) ([]analyzedThread, error)
+    // RecordQualityPrompt remembers the details the author of a thread
+    // was asked for, false when they were asked before.
+    RecordQualityPrompt(ctx context.Context, channelID string, threadTS string, missing []string) (bool, error)
+}
+
+// SuggestionStore finds the unanswered threads suggested to their likely
+// stakeholders and the past threads they are compared with.
+type SuggestionStore interface {
+    // UnansweredThreads returns the open threads of a channel started
+    // before a time without replies, never suggested nor claimed.
+    UnansweredThreads(ctx context.Context, channelID string, before time.Time) ([]unansweredThread, error)
+    // PastThreads returns the most recently active analyzed threads of a
+    // channel that are no longer open, up to suggestionHistory, with their
+    // title on the first line of their text.
+    PastThreads(ctx context.Context, channelID string) ([]similarity.Document, error)
+    // RecordSuggestion remembers that a thread was suggested.
+    RecordSuggestion(ctx context.Context, channelID string, threadTS string) error
+}
+
+// LiveThreadStore snapshots the threads watched for live updates.
+type LiveThreadStore interface {
+    // WatchedThreads returns the state of the open threads of a channel
+    // and of those active since a time, by thread. Their analysis is only
+    // digested when analyzed is set.
+    WatchedThreads(ctx context.Context, channelID string, since time.Time, analyzed bool) (map[string]liveThreadState, error)
+}
+
+// GitHubIssueStore links threads to GitHub issues.
+type GitHubIssueStore interface {
+    // LinkGitHubIssue links a thread to the issue at url and reports false
+    // when the thread was linked to an issue already.
+    LinkGitHubIssue(ctx context.Context, channelID string, threadTS string, url string) (bool, error)
+}
+
+// JiraTicketStore keeps the Jira tickets created from the dashboard.
+type JiraTicketStore interface {
+    // JiraTicketStatus returns the status a ticket last transitioned to,
+    // empty when unknown.
+    JiraTicketStatus(ctx context.Context, key string) (string, error)
+    // LinkJiraTicket links a ticket to a thread that has none and tracks it
+    // to sync its transitions, false when the thread is already linked.
+    LinkJiraTicket(ctx context.Context, channelID string, threadTS string, key string) (bool, error)
+    // SetJiraTicketStatus records the status a tracked ticket transitioned
+    // to and returns its thread, false when the ticket is not tracked.
+    SetJiraTicketStatus(ctx context.Context, key string, status string) (ThreadRef, bool, error)
+}
+
+// SlackConnectStore keeps which channels are shared with other workspaces
+// and the users of those workspaces.
+type SlackConnectStore interface {
+    // MarkSlackConnect records whether a channel is shared and reports
+    // whether that changed.
+    MarkSlackConnect(ctx context.Context, channelID string, shared bool) (bool, error)
+    // RecordExternalUser remembers a user of another workspace.
+    RecordExternalUser(ctx context.Context, userID string, teamID string) error
+    // SetSlackConnectAI sets whether the content of a shared channel may be
+    // analyzed by AI and returns the Slack Connect settings of the channel.
+    SetSlackConnectAI(ctx context.Context, channelID string, enabled bool) (SlackConnect, error)
+}
+
+// LegalHoldStore keeps the legal holds and data under them from being
+// deleted.
+type LegalHoldStore interface {
+    // Block reports whether a thread is under an active legal hold and,
+    // when it is, audits the action attempted on it. An empty threadTS
+    // targets the whole channel, held when the channel or any of its
+    // threads is, and an empty channelID targets every channel.
+    Block(ctx context.Context, actor string, action string, channelID string, threadTS string) (bool, error)
+    // LegalHolds returns the legal holds newest first, the active ones only
+    // unless all is set.
+    LegalHolds(ctx context.Context, all bool) ([]LegalHold, error)
+    // PlaceLegalHold places hold and returns it with its ID and time set.
+    PlaceLegalHold(ctx context.Context, hold LegalHold) (LegalHold, error)
+    // ReleaseLegalHold releases an active hold and returns it, or
+    // errLegalHoldNotFound when no active hold has the ID.
+    ReleaseLegalHold(ctx context.Context, id int64, actor string) (LegalHold, error)
+}
+
+// ExportJobStore keeps the jobs of asynchronous exports and reads the
+// threads they export. Methods taking the ID of a job return
+// errExportNotFound when it does not exist.
+type ExportJobStore interface {
+    // ExportChannels returns the tracked channels an export selects.
+    ExportChannels(ctx context.Context, req ExportRequest) ([]exportChannel, error)
+    // CountExportRows counts the threads of a channel an export selects.
+    CountExportRows(ctx context.Context, req ExportRequest, ch exportChannel) (int, error)
+    // ExportRows calls fn with the threads of a channel an export selects,
+    // oldest first, until fn returns an error.
+    ExportRows(ctx context.Context, req ExportRequest, ch exportChannel, fn func(exportRow) error) error
+    // CreateExport stores a job and returns it with its ID and creation
+    // time.
+    CreateExport(ctx context.Context, job ExportJob) (ExportJob, error)
+    // Export returns a job.
+    Export(ctx context.Context, id int64) (ExportJob, error)
+    // PendingExports returns the IDs of the pending jobs in order, making
+    // the running ones pending again first when resume is set.
+    PendingExports(ctx context.Context, resume bool) ([]int64, error)
+    // StartExport marks a pending job as running. It reports false when
+    // the job was not pending.
+    StartExport(ctx context.Context, id int64) (bool, error)
+    // CompleteExport records the file a job wrote and until when it may be
+    // downloaded.
+    CompleteExport(ctx context.Context, id int64, rowCount int, size int64, objectKey string, expiresAt time.Time) error
+    // FailExport records why a job failed.
+    FailExport(ctx context.Context, id int64, message string) error
+    // ExpiredExports returns the completed exports past their expiry.
+    ExpiredExports(ctx context.Context) ([]ExportJob, error)
+    // ExpireExport records that the file of an export was deleted.
+    ExpireExport(ctx context.Context, id int64) error
+}
+
+// AdoptionStore counts the calls to the API and reports how the dashboard
+// is adopted. Calls are counted in the shared database, reminders and
+// audited actions in the database of the workspace.
+type AdoptionStore interface {
+    // RecordUsage adds calls to the usage of the API.
+    RecordUsage(ctx context.Context, key usageKey, calls int64) error
+    // ActiveUsers counts the users calling the API since a time, in all
+    // and by day.
+    ActiveUsers(ctx context.Context, since time.Time) (int, []DailyCount, error)
+    // APIClients returns the clients calling the API since a time, the
+    // most used first.
+    APIClients(ctx context.Context, since time.Time) ([]ClientUsage, error)
+    // ReminderAdoption counts the reminder recipients since a time and the
+    // email opt-outs.
+    ReminderAdoption(ctx context.Context, since time.Time) (ReminderAdoption, error)
+    // FeatureUsage returns the routes called and the actions audited since
+    // a time, counted by day.
+    FeatureUsage(ctx context.Context, since time.Time) ([]FeatureUsage, error)
+}
+
+// DigestStore keeps the runs of the scheduled digest.
+type DigestStore interface {
+    // ClaimDigestRun claims the run of the digest scheduled at a time and
+    // reports false when another replica claimed it first.
+    ClaimDigestRun(ctx context.Context, scheduledAt time.Time) (bool, error)
+}
+
+// StorageStore measures the storage used by the tables of the database.
+type StorageStore interface {
+    // MeasureTables returns the size and estimated rows of the tables of
+    // the dashboard and the collector.
+    MeasureTables(ctx context.Context) ([]TableStorage, error)
+    // StorageSnapshots returns the oldest snapshot of every table captured
+    // since a time, keyed by table.
+    StorageSnapshots(ctx context.Context, since time.Time) (map[string]storageSnapshot, error)
+    // RecordStorage keeps the first snapshot of the day of tables and
+    // forgets the snapshots older than twice the growth window.
+    RecordStorage(ctx context.Context, measuredAt time.Time, tables []TableStorage) error
+}
+
+// WebhookStore keeps the webhooks events are posted to. Methods taking the
+// ID of a webhook return errWebhookNotFound when it does not exist.
+type WebhookStore interface {
+    // Webhooks returns the outgoing webhooks, only the enabled ones when
+    // enabledOnly is set.
+    Webhooks(ctx context.Context, enabledOnly bool) ([]webhooks.Webhook, error)
+    // Webhook returns an outgoing webhook.
+    Webhook(ctx context.Context, id int64) (webhooks.Webhook, error)
+    // CreateWebhook stores an outgoing webhook and returns it with its ID.
+    CreateWebhook(ctx context.Context, hook webhooks.Webhook) (webhooks.Webhook, error)
+    // UpdateWebhook replaces the settings of an outgoing webhook.
+    UpdateWebhook(ctx context.Context, id int64, hook webhooks.Webhook) (webhooks.Webhook, error)
+    // DeleteWebhook removes an outgoing webhook.
+    DeleteWebhook(ctx context.Context, id int64) error
+    // ThreadWebhooks returns the webhooks registered on a thread, oldest
+    // first.
+    ThreadWebhooks(ctx context.Context, channelID string, threadTS string) ([]ThreadWebhook, error)
+    // CreateThreadWebhook stores a webhook of a thread and returns it with
+    // its ID.
+    CreateThreadWebhook(ctx context.Context, hook ThreadWebhook) (ThreadWebhook, error)
+    // DeleteThreadWebhook removes a webhook of a thread.
+    DeleteThreadWebhook(ctx context.Context, channelID string, threadTS string, id int64) error
+    // ExpireThreadWebhooks removes the webhooks of a finished thread.
+    ExpireThreadWebhooks(ctx context.Context, channelID string, threadTS string) error
+    // PruneThreadWebhooks removes the webhooks of finished threads in the
+    // tracked channels and those of every other channel.
+    PruneThreadWebhooks(ctx context.Context, tracked []string) error
+}
+
+// AuditStore keeps the audit log.
+type AuditStore interface {
+    // Audit appends an entry to the audit log in the database of its
+    // channel, that of the workspace of ctx for entries without one.
+    Audit(ctx context.Context, entry AuditEntry) error
+    // ThreadActions returns the audited actions on a thread, oldest first
+    // and at most reportTimelineLimit.
+    ThreadActions(ctx context.Context, channelID string, threadTS string) ([]reportEvent, error)
+}
+
+// TokenStore keeps the personal API tokens of users by the hash of their
+// secret.
+type TokenStore interface {
+    // Tokens returns the active tokens of a user, newest first.
+    Tokens(ctx context.Context, userID string) ([]APIToken, error)
+    // CreateToken stores a token of a user with the hash of its secret and
+    // returns it with its ID and creation time.
+    CreateToken(ctx context.Context, userID string, token APIToken, hash string) (APIToken, error)
+    // RevokeToken revokes an active token of a user. It returns
+    // errTokenNotFound when the user has no such token.
+    RevokeToken(ctx context.Context, userID string, id int64) error
+    // LookupToken returns the principal of the active token with a hash,
+    // recording that it was used, or nil when there is none.
+    LookupToken(ctx context.Context, hash string) (*auth.Principal, error)
+}
+
+// APIKeyStore keeps the API keys of CI jobs and bots, in the shared
+// database.
+type APIKeyStore interface {
+    // APIKeys returns the active keys, newest first.
+    APIKeys(ctx context.Context) ([]APIKey, error)
+    // CreateAPIKey stores a key with the hash of its secret and returns it
+    // with its ID and creation time.
+    CreateAPIKey(ctx context.Context, key APIKey, hash string) (APIKey, error)
+    // RevokeAPIKey revokes an active key on behalf of actor and returns its
+    // name. It returns errAPIKeyNotFound when there is no such key.
+    RevokeAPIKey(ctx context.Context, id int64, actor string) (string, error)
+    // LookupAPIKey returns the principal of the active key with a hash,
+    // recording that it was used, or nil when there is none.
+    LookupAPIKey(ctx context.Context, hash string) (*auth.Principal, error)
+}
+
+// SessionStore keeps the sessions of users signed in to the dashboard by
+// the hash of their cookie, and the login audit trail.
+type SessionStore interface {
+    // OpenSession stores a session of identity lasting until expiresAt.
+    OpenSession(ctx context.Context, hash string, identity *auth.Identity, remoteIP string, userAgent string, expiresAt time.Time) error
+    // EndSession deletes a session, if there is one.
+    EndSession(ctx context.Context, hash string) error
+    // LookupSession returns the user of an unexpired session, recording
+    // that it was seen, or nil when there is none.
+    LookupSession(ctx context.Context, hash string) (*auth.Principal, error)
+    // SessionExpiry returns when a session expires, nil when there is no
+    // such session.
+    SessionExpiry(ctx context.Context, hash string) (*time.Time, error)
+    // PruneSessions deletes the expired sessions.
+    PruneSessions(ctx context.Context) error
+    // RecordLoginEvent appends an authentication attempt to the login
+    // audit trail.
+    RecordLoginEvent(ctx context.Context, event auth.LoginEvent) error
+    // LoginAudit returns the entries of the login audit trail matching
+    // filter, newest first.
+    LoginAudit(ctx context.Context, filter loginAuditFilter) ([]LoginAuditEntry, error)
+}
+
+// EmailPreferenceStore keeps the email notifications addresses receive.
+type EmailPreferenceStore interface {
+    // EmailPreferences returns the preferences of an address, with the
+    // digest on for addresses that never chose.
+    EmailPreferences(ctx context.Context, address string) (EmailPreferences, error)
+    // SetEmailPreferences stores the preferences of an address and returns
+    // them with when they were updated.
+    SetEmailPreferences(ctx context.Context, prefs EmailPreferences) (EmailPreferences, error)
+}
+
+// PreferenceStore keeps the preferences of dashboard users.
+type PreferenceStore interface {
+    // UserPreferences returns the preferences of a user, the defaults when
+    // they have none.
+    UserPreferences(ctx context.Context, userID string) (UserPreferences, error)
+    // SetUserPreferences stores the preferences of a user.
+    SetUserPreferences(ctx context.Context, userID string, prefs UserPreferences) error
+}
+
+// UserStore reads the Slack profiles of users and keeps their roles.
+type UserStore interface {
+    // Profiles returns the profiles of users, with the custom directory
+    // overlaid. Users without a profile are left out.
+    Profiles(ctx context.Context, userIDs []string) ([]UserProfile, error)
+    // SaveProfile stores the Slack names and avatars of a user, marking
+    // them deactivated while Deactivated is set.
+    SaveProfile(ctx context.Context, profile UserProfile) error
+    // Role returns the role name assigned to a user, empty when none is.
+    Role(ctx context.Context, userID string) (string, error)
+    // Roles returns the assigned roles sorted by user.
+    Roles(ctx context.Context) ([]UserRole, error)
+    // AssignRole assigns a role to a user on behalf of actor and returns
+    // the assignment.
+    AssignRole(ctx context.Context, userID string, role auth.Role, actor string) (UserRole, error)
+    // RemoveRole returns a user to the default role and reports false when
+    // no role was assigned.
+    RemoveRole(ctx context.Context, userID string) (bool, error)
+    // Usergroups returns the synced Slack usergroups with their members,
+    // sorted by handle.
+    Usergroups(ctx context.Context) ([]Usergroup, error)
+    // Usergroup returns the usergroup named by @handle or ID with its
+    // members, errUsergroupNotFound when none is.
+    Usergroup(ctx context.Context, ref string) (Usergroup, error)
+    // ReplaceUsergroups replaces the synced usergroups and their members
+    // with groups, forgetting the others.
+    ReplaceUsergroups(ctx context.Context, groups []slack.Usergroup) error
+    // UserByEmail returns the user of an email address in the user
+    // directory, empty when the address is not in it.
+    UserByEmail(ctx context.Context, address string) (string, error)
+}
+
+// DirectoryStore keeps the custom user directory overlaid on Slack
+// profiles and how the names of users are picked.
+type DirectoryStore interface {
+    // DirectoryEntries returns the entries of a source, of every source
+    // when empty, sorted by user.
+    DirectoryEntries(ctx context.Context, source string) ([]DirectoryEntry, error)
+    // ImportDirectory stores entries, replacing those of their user. With
+    // replace, entries of the source missing from entries are removed.
+    ImportDirectory(ctx context.Context, source string, entries []DirectoryEntry, replace bool) error
+    // DisplayNamePrecedence returns the profile fields the names of users
+    // are picked from, in order, the default unless set.
+    DisplayNamePrecedence(ctx context.Context) ([]string, error)
+    // SetDisplayNamePrecedence sets the profile fields the names of users
+    // are picked from on behalf of actor.
+    SetDisplayNamePrecedence(ctx context.Context, precedence []string, actor string) error
+}
+
+// SlackClient is the part of the Slack Web API handlers call, implemented
+// by *slack.Client.
+type SlackClient interface {
+    Configured() bool
+    AuthTest(ctx context.Context) ([]string, error)
+    Identity(ctx context.Context) (*slack.Identity, error)
+    AuthTeamsList(ctx context.Context, cursor string, limit int) (*slack.TeamsPage, error)
+    MigrationExchange(ctx context.Context, teamID string, userIDs []string) (map[string]string, error)
+    CallsPerMinute(method string) float64
+    ConversationInfo(ctx context.Context, channel string) (*slack.Conversation, error)
+    ConversationsHistory(ctx context.Context, channel string, oldest string, cursor string, limit int) (*slack.HistoryPage, error)
+    ConversationsReplies(ctx context.Context, channel string, ts string, cursor string, limit int) (*slack.HistoryPage, error)
+    UsersConversations(ctx context.Context, teamID string, cursor string, limit int) (*slack.ConversationsPage, error)
+    UsersList(ctx context.Context, teamID string, cursor string, limit int) (*slack.UsersPage, error)
+    ReactionsGet(ctx context.Context, channel string, ts string) ([]slack.Reaction, error)
+    UsergroupsList(ctx context.Context, teamID string) ([]slack.Usergroup, error)
+    PostMessage(ctx context.Context, channel string, text string, blocks []slack.Block) (string, error)
+    PostReply(ctx context.Context, channel string, threadTS string, text string) error
+    UpdateMessage(ctx context.Context, channel string, ts string, text string, blocks []slack.Block) error
+    PinMessage(ctx context.Context, channel string, ts string) error
+    CreateChannelCanvas(ctx context.Context, channel string, markdown string) (string, error)
+    ReplaceCanvas(ctx context.Context, canvasID string, markdown string) error
+    PostEphemeral(ctx context.Context, channel string, user string, text string, blocks []slack.Block) error
+    Respond(ctx context.Context, responseURL string, msg slack.ResponseMessage) error
+    OpenView(ctx context.Context, triggerID string, view slack.View) error
+    UpdateWorkflowStep(ctx context.Context, editID string, inputs map[string]slack.StepInput, outputs []slack.StepOutput) error
+    CompleteWorkflowStep(ctx context.Context, executeID string, outputs map[string]string) error
+    FailWorkflowStep(ctx context.Context, executeID string, message string) error
+}
+
+// Option replaces a dependency of the container, e.g. with an in-memory
+// fake in tests.
+type Option func(c *Container)
+
+// WithThreadStore makes handlers read threads from store.
+func WithThreadStore(store ThreadStore) Option {
+    return func(c *Container) { c.threads = store }
+}
+
+// WithThreadListStore makes handlers list threads from store.
+func WithThreadListStore(store ThreadListStore) Option {
+    return func(c *Container) { c.threadLists = store }
+}
+
+// WithNoteStore makes handlers keep triage notes in store.
+func WithNoteStore(store NoteStore) Option {
+    return func(c *Container) { c.notes = store }
+}
+
+// WithEffortStore makes handlers keep effort estimates and tracked time
+// in store.
+func WithEffortStore(store EffortStore) Option {
+    return func(c *Container) { c.efforts = store }
+}
+
+// WithWatcherStore makes handlers keep thread watchers in store.
+func WithWatcherStore(store WatcherStore) Option {
+    return func(c *Container) { c.watchers = store }
+}
+
+// WithThreadStatusStore makes handlers change the status of threads in
+// store.
+func WithThreadStatusStore(store ThreadStatusStore) Option {
+    return func(c *Container) { c.threadStatus = store }
+}
+
+// WithThreadOverrideStore makes handlers keep the priorities and due dates
+// set on threads in store.
+func WithThreadOverrideStore(store ThreadOverrideStore) Option {
+    return func(c *Container) { c.overrides = store }
+}
+
+// WithBulkThreadStore makes handlers change many threads at once through
+// store.
+func WithBulkThreadStore(store BulkThreadStore) Option {
+    return func(c *Container) { c.bulk = store }
+}
+
+// WithReleaseStore makes handlers keep the releases threads wait on in
+// store.
+func WithReleaseStore(store ReleaseStore) Option {
+    return func(c *Container) { c.releases = store }
+}
+
+// WithResolutionStore makes handlers resolve threads and keep resolution
+// requests in store.
+func WithResolutionStore(store ResolutionStore) Option {
+    return func(c *Container) { c.resolutions = store }
+}
+
+// WithAssignmentStore makes handlers keep thread assignments and claims in
+// store.
+func WithAssignmentStore(store AssignmentStore) Option {
+    return func(c *Container) { c.assignments = store }
+}
+
+// WithAckStore makes handlers keep acknowledgments in store.
+func WithAckStore(store AckStore) Option {
+    return func(c *Container) { c.acks = store }
+}
+
+// WithReporterWaitStore makes handlers keep the waits on thread authors in
+// store.
+func WithReporterWaitStore(store ReporterWaitStore) Option {
+    return func(c *Container) { c.reporterWaits = store }
+}
+
+// WithAnswerSignalStore makes handlers keep answer signals in store.
+func WithAnswerSignalStore(store AnswerSignalStore) Option {
+    return func(c *Container) { c.answerSignals = store }
+}
+
+// WithStatsStore makes handlers count threads in store.
+func WithStatsStore(store StatsStore) Option {
+    return func(c *Container) { c.stats = store }
+}
+
+// WithChannelStore makes handlers list channels from store.
+func WithChannelStore(store ChannelStore) Option {
+    return func(c *Container) { c.channels = store }
+}
+
+// WithChannelGroupStore makes handlers keep channel groups in store.
+func WithChannelGroupStore(store ChannelGroupStore) Option {
+    return func(c *Container) { c.groups = store }
+}
+
+// WithGoalStore makes handlers keep goals in store.
+func WithGoalStore(store GoalStore) Option {
+    return func(c *Container) { c.goals = store }
+}
+
+// WithReplyTemplateStore makes handlers keep canned replies in store.
+func WithReplyTemplateStore(store ReplyTemplateStore) Option {
+    return func(c *Container) { c.templates = store }
+}
+
+// WithAnalysisStore makes handlers keep the AI analysis of threads in
+// store.
+func WithAnalysisStore(store AnalysisStore) Option {
+    return func(c *Container) { c.analyses = store }
+}
+
+// WithCalibrationStore makes handlers sample threads to calibrate
+// priorities from store.
+func WithCalibrationStore(store CalibrationStore) Option {
+    return func(c *Container) { c.calibration = store }
+}
+
+// WithChannelSummaryStore makes handlers keep the Slack summaries of
+// channels in store.
+func WithChannelSummaryStore(store ChannelSummaryStore) Option {
+    return func(c *Container) { c.summaryStore = store }
+}
+
+// WithChannelConfigStore makes handlers keep channel configuration in
+// store.
+func WithChannelConfigStore(store ChannelConfigStore) Option {
+    return func(c *Container) { c.configs = store }
+}
+
+// WithMessageStore makes handlers keep Slack messages in store.
+func WithMessageStore(store MessageStore) Option {
+    return func(c *Container) { c.messages = store }
+}
+
+// WithBackfillStore makes backfills and polling keep their progress and
+// threads in store.
+func WithBackfillStore(store BackfillStore) Option {
+    return func(c *Container) { c.backfills = store }
+}
+
+// WithReminderStore makes reminders find idle threads and remember their
+// nudges in store.
+func WithReminderStore(store ReminderStore) Option {
+    return func(c *Container) { c.reminderStore = store }
+}
+
+// WithQualityPromptStore makes quality prompts find incomplete threads
+// and remember what their authors were asked in store.
+func WithQualityPromptStore(store QualityPromptStore) Option {
+    return func(c *Container) { c.qualityStore = store }
+}
+
+// WithSuggestionStore makes suggestions find unanswered and past threads
+// in store.
+func WithSuggestionStore(store SuggestionStore) Option {
+    return func(c *Container) { c.suggestions = store }
+}
+
+// WithLiveThreadStore makes live updates poll threads from store.
+func WithLiveThreadStore(store LiveThreadStore) Option {
+    return func(c *Container) { c.liveThreads = store }
+}
+
+// WithGitHubIssueStore makes handlers link threads to GitHub issues in
+// store.
+func WithGitHubIssueStore(store GitHubIssueStore) Option {
+    return func(c *Container) { c.githubIssues = store }
+}
+
+// WithJiraTicketStore makes handlers keep Jira tickets in store.
+func WithJiraTicketStore(store JiraTicketStore) Option {
+    return func(c *Container) { c.jiraTickets = store }
+}
+
+// WithSlackConnectStore makes handlers keep the Slack Connect state of
+// channels in store.
+func WithSlackConnectStore(store SlackConnectStore) Option {
+    return func(c *Container) { c.slackConnect = store }
+}
+
+// WithLegalHoldStore makes handlers check legal holds in store.
+func WithLegalHoldStore(store LegalHoldStore) Option {
+    return func(c *Container) { c.holds = store }
+}
+
+// WithExportJobStore makes handlers keep export jobs in store.
+func WithExportJobStore(store ExportJobStore) Option {
+    return func(c *Container) { c.exportRecords = store }
+}
+
+// WithAdoptionStore makes handlers count API usage and report adoption
+// with store.
+func WithAdoptionStore(store AdoptionStore) Option {
+    return func(c *Container) { c.adoption = store }
+}
+
+// WithDigestStore makes handlers claim the runs of the digest in store.
+func WithDigestStore(store DigestStore) Option {
+    return func(c *Container) { c.digests = store }
+}
+
+// WithStorageStore makes handlers measure database storage with store.
+func WithStorageStore(store StorageStore) Option {
+    return func(c *Container) { c.storage = store }
+}
+
+// WithWebhookStore makes handlers keep webhooks in store.
+func WithWebhookStore(store WebhookStore) Option {
+    return func(c *Container) { c.webhookStore = store }
+}
+
+// WithAuditStore makes handlers append audit entries to store.
+func WithAuditStore(store AuditStore) Option {
+    return func(c *Container) { c.auditLog = store }
+}
+
+// WithTokenStore makes handlers keep personal API tokens in store.
+func WithTokenStore(store TokenStore) Option {
+    return func(c *Container) { c.tokens = store }
+}
+
+// WithAPIKeyStore makes handlers keep API keys in store.
+func WithAPIKeyStore(store APIKeyStore) Option {
+    return func(c *Container) { c.apiKeys = store }
+}
+
+// WithSessionStore makes handlers keep dashboard sessions and the login
+// audit trail in store.
+func WithSessionStore(store SessionStore) Option {
+    return func(c *Container) { c.sessions = store }
+}
+
+// WithEmailPreferenceStore makes handlers keep email preferences in store.
+func WithEmailPreferenceStore(store EmailPreferenceStore) Option {
+    return func(c *Container) { c.emailPrefs = store }
+}
+
+// WithPreferenceStore makes handlers keep user preferences in store.
+func WithPreferenceStore(store PreferenceStore) Option {
+    return func(c *Container) { c.preferences = store }
+}
+
+// WithUserStore makes handlers read user profiles from store.
+func WithUserStore(store UserStore) Option {
+    return func(c *Container) { c.users = store }
+}
+
+// WithDirectoryStore makes handlers keep the user directory in store.
+func WithDirectoryStore(store DirectoryStore) Option {
+    return func(c *Container) { c.directory = store }
+}
+
+// WithSlackClient makes handlers call Slack through client. The collector
+// backfill and notifications keep the client of the bot token.
+func WithSlackClient(client SlackClient) Option {
+    return func(c *Container) { c.slack = client }
+}
+
+// WithDatabaseDSN connects the shared database with dsn instead of the
+// configured one, e.g. to the database embedded by quickstart. The pool
+// settings are kept.
+func WithDatabaseDSN(dsn string) Option {
+    return func(c *Container) { c.database.DSN = dsn }
+}
+
+// WithEventBus makes subsystems publish and subscribe to events on bus.
+func WithEventBus(bus events.Bus) Option {
+    return func(c *Container) { c.bus = bus }
+}
+
+// postgresStore implements the stores of handlers with the database of the
+// workspace of a request. Methods specific to a feature are next to its
+// handlers.
+type postgresStore struct {
+    c *Container
+}
+
+func (s postgresStore) Thread(ctx context.Context, channelID string, threadTS string) (StoredThread, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return StoredThread{}, err
+    }
+    err = channelTracked(db, channelID)
+    if err != nil {
+        return StoredThread{}, err
+    }
+
+    var thread StoredThread
+    err = db.QueryRowContext(ctx, `
+        SELECT thread_ts, channel_id, user_id, status, created_at, COALESCE(ai_thread_name, ''),
+               COALESCE(github_issue, ''), COALESCE(jira_ticket, '')
+        FROM threads
+        WHERE thread_ts = $1 AND channel_id = $2
+    `, threadTS, channelID).Scan(&thread.ThreadTS, &thread.ChannelID, &thread.UserID, &thread.Status,
+        &thread.CreatedAt, &thread.Title, &thread.GithubIssue, &thread.JiraTicket)
+    if err == sql.ErrNoRows {
+        return StoredThread{}, errThreadNotFound
+    }
+    return thread, err
+}
+
+func (s postgresStore) Notes(ctx context.Context, channelID string, threadTS string) ([]ThreadNote, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, "SELECT "+threadNoteColumns+" FROM thread_notes WHERE channel_id = $1 AND thread_ts = $2 ORDER BY id",
+        channelID, threadTS)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    notes := []ThreadNote{}
+    for rows.Next() {
+        note, err := scanThreadNote(rows)
+        if err != nil {
+            return nil, err
+        }
+        notes = append(notes, note)
+    }
+    return notes, rows.Err()
+}
+
+func (s postgresStore) Watchers(ctx context.Context, channelID string, threadTS string) ([]Watcher, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT user_id, source, created_at FROM thread_watchers
+        WHERE channel_id = $1 AND thread_ts = $2
+        ORDER BY created_at
+    `, channelID, threadTS)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    watchers := []Watcher{}
+    for rows.Next() {
+        var watcher Watcher
+        if err := rows.Scan(&watcher.UserID, &watcher.Source, &watcher.CreatedAt); err != nil {
+            return nil, err
+        }
+        watchers = append(watchers, watcher)
+    }
+    return watchers, rows.Err()
+}
+
+func (s postgresStore) Effort(ctx context.Context, channelID string, threadTS string) (ThreadEffort, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ThreadEffort{}, err
+    }
+    return loadThreadEffort(ctx, db, channelID, threadTS)
+}
+
+func (s postgresStore) Channels(ctx context.Context) ([]ChannelSummary, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT ch.channel_id, ch.channel_name, ch.thread_count, ch.active_thread_count,
+               ch.last_activity, ch.created_at, COALESCE(cc.text_indexing, TRUE)
+        FROM channels ch
+        LEFT JOIN channel_config cc ON cc.channel_id = ch.channel_id
+        ORDER BY ch.channel_name
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var channels []ChannelSummary
+    for rows.Next() {
+        var ch ChannelSummary
+        if err := rows.Scan(&ch.ChannelID, &ch.ChannelName, &ch.ThreadCount, &ch.ActiveThreadCount,
+            &ch.LastActivity, &ch.CreatedAt, &ch.TextIndexing); err != nil {
+            continue
+        }
+        channels = append(channels, ch)
+    }
+    return channels, rows.Err()
+}
+
+func (s postgresStore) Channel(ctx context.Context, channelID string) (ChannelSummary, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ChannelSummary{}, err
+    }
+
+    var ch ChannelSummary
+    err = db.QueryRowContext(ctx, `
+        SELECT ch.channel_id, ch.channel_name, ch.thread_count, ch.active_thread_count,
+               ch.last_activity, ch.created_at, COALESCE(cc.text_indexing, TRUE)
+        FROM channels ch
+        LEFT JOIN channel_config cc ON cc.channel_id = ch.channel_id
+        WHERE ch.channel_id = $1
+    `, channelID).Scan(&ch.ChannelID, &ch.ChannelName, &ch.ThreadCount, &ch.ActiveThreadCount,
+        &ch.LastActivity, &ch.CreatedAt, &ch.TextIndexing)
+    if err == sql.ErrNoRows {
+        return ChannelSummary{}, errChannelNotTracked
+    }
+    return ch, err
+}
+
+func (s postgresStore) Tracked(ctx context.Context, channelID string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    return channelTracked(db, channelID)
+}
+
+func (s postgresStore) TrackChannel(ctx context.Context, channelID string, channelName string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+    return trackChannel(ctx, db, channelID, channelName)
+}
+
+func (s postgresStore) TextIndexing(ctx context.Context, channelID string) (bool, error) {
+    db, err := s.c.channelDB(ctx, channelID)
+    if err != nil {
+        return false, err
+    }
+
+    var enabled bool
+    err = db.QueryRowContext(ctx, "SELECT text_indexing FROM channel_config WHERE channel_id = $1", channelID).Scan(&enabled)
+    if err == sql.ErrNoRows {
+        return true, nil
+    }
+    return enabled, err
+}
+
+func (s postgresStore) StartThread(ctx context.Context, thread domain.Thread, first ThreadMessage) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    _, err = tx.ExecContext(ctx, `
+        INSERT INTO threads (thread_ts, channel_id, user_id, reply_count, latest_reply, status, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (channel_id, thread_ts) DO NOTHING
+    `, thread.ThreadTS, thread.ChannelID, thread.UserID, thread.ReplyCount,
+        thread.LatestReply, thread.Status, thread.CreatedAt)
+    if err != nil {
+        return err
+    }
+    if _, err := insertThreadMessage(ctx, tx, thread.ChannelID, thread.ThreadTS, first); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+func (s postgresStore) TrackThread(ctx context.Context, thread domain.Thread) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+    result, err := db.ExecContext(ctx, `
+        INSERT INTO threads (thread_ts, channel_id, user_id, reply_count, latest_reply, status, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (channel_id, thread_ts) DO NOTHING
+    `, thread.ThreadTS, thread.ChannelID, thread.UserID, thread.ReplyCount,
+        thread.LatestReply, thread.Status, thread.CreatedAt)
+    if err != nil {
+        return false, err
+    }
+    n, _ := result.RowsAffected()
+    return n > 0, nil
+}
+
+func (s postgresStore) AddReply(ctx context.Context, channelID string, threadTS string, reply ThreadMessage) (domain.Thread, bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return domain.Thread{}, false, err
+    }
+
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return domain.Thread{}, false, err
+    }
+    defer tx.Rollback()
+
+    // The thread is locked so that concurrent replies count one by one
+    thread := domain.Thread{ChannelID: channelID, ThreadTS: threadTS}
+    err = tx.QueryRowContext(ctx, `
+        SELECT user_id, reply_count, latest_reply, status, created_at FROM threads
+        WHERE thread_ts = $1 AND channel_id = $2
+        FOR UPDATE
+    `, threadTS, channelID).Scan(&thread.UserID, &thread.ReplyCount, &thread.LatestReply, &thread.Status, &thread.CreatedAt)
+    if err == sql.ErrNoRows {
+        return domain.Thread{}, false, errThreadNotFound
+    }
+    if err != nil {
+        return domain.Thread{}, false, err
+    }
+    added, err := insertThreadMessage(ctx, tx, channelID, threadTS, reply)
+    if err != nil || !added {
+        return thread, false, err
+    }
+    err = tx.QueryRowContext(ctx, `
+        UPDATE threads
+        SET reply_count = reply_count + 1,
+            latest_reply = GREATEST(latest_reply, $1),
+            updated_at = NOW()
+        WHERE thread_ts = $2 AND channel_id = $3
+        RETURNING reply_count, latest_reply
+    `, reply.PostedAt, threadTS, channelID).Scan(&thread.ReplyCount, &thread.LatestReply)
+    if err != nil {
+        return domain.Thread{}, false, err
+    }
+    return thread, true, tx.Commit()
+}
+
+func (s postgresStore) Messages(ctx context.Context, channelID string, threadTS string) ([]ThreadMessage, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return storedThreadMessages(ctx, db, channelID, threadTS)
+}
+
+func (s postgresStore) Block(ctx context.Context, actor string, action string, channelID string, threadTS string) (bool, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    query := "SELECT COUNT(*) FROM legal_holds WHERE released_at IS NULL"
+    args := []interface{}{}
+    if channelID != "" {
+        args = append(args, channelID)
+        query += " AND channel_id = $1"
+    }
+    if channelID != "" && threadTS != "" {
+        args = append(args, threadTS)
+        query += " AND (thread_ts IS NULL OR thread_ts = $2)"
+    }
+
+    var held int
+    if err := db.QueryRowContext(ctx, query, args...).Scan(&held); err != nil {
+        return false, err
+    }
+    if held == 0 {
+        return false, nil
+    }
+    s.c.audit(ctx, actor, "legal_hold.blocked", channelID, threadTS, map[string]interface{}{
+        "attempted_action": action,
+    })
+    return true, nil
+}
+
+func (s postgresStore) ExpiredExports(ctx context.Context) ([]ExportJob, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT id, filters, object_key FROM export_jobs WHERE status = $1 AND expires_at < NOW()
+    `, exportCompleted)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    jobs := []ExportJob{}
+    for rows.Next() {
+        job := ExportJob{Status: exportCompleted}
+        var filters string
+        if err := rows.Scan(&job.ID, &filters, &job.objectKey); err != nil {
+            return nil, err
+        }
+        json.Unmarshal([]byte(filters), &job.filters)
+        jobs = append(jobs, job)
+    }
+    return jobs, rows.Err()
+}
+
+func (s postgresStore) ExpireExport(ctx context.Context, id int64) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, "UPDATE export_jobs SET status = $1 WHERE id = $2", exportExpired, id)
+    return err
+}
+
+func (s postgresStore) Audit(ctx context.Context, entry AuditEntry) error {
+    var db *sql.DB
+    var err error
+    if entry.ChannelID != "" {
+        db, err = s.c.channelDB(ctx, entry.ChannelID)
+    } else {
+        db, err = s.c.workspaceDB(ctx)
+    }
+    if err != nil {
+        return err
+    }
+
+    details := []byte("{}")
+    if entry.Details != nil {
+        if encoded, err := json.Marshal(entry.Details); err == nil {
+            details = encoded
+        }
+    }
+    var thread interface{}
+    if entry.ThreadTS != "" {
+        thread = entry.ThreadTS
+    }
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO audit_log (actor, action, channel_id, thread_ts, details)
+        VALUES ($1, $2, $3, $4, $5)
+    `, entry.Actor, entry.Action, entry.ChannelID, thread, string(details))
+    return err
+}
+
+func (s postgresStore) Profiles(ctx context.Context, userIDs []string) ([]UserProfile, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return s.c.loadUserProfiles(db, userIDs)
+}
+
+func (s postgresStore) Role(ctx context.Context, userID string) (string, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return "", err
+    }
+
+    var role string
+    err = db.QueryRowContext(ctx, "SELECT role FROM user_roles WHERE user_id = $1", userID).Scan(&role)
+    if err == sql.ErrNoRows {
+        return "", nil
+    }
+    return role, err
+}
+
+func (s postgresStore) Roles(ctx context.Context) ([]UserRole, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, "SELECT user_id, role, granted_by, updated_at FROM user_roles ORDER BY user_id")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    roles := []UserRole{}
+    for rows.Next() {
+        var role UserRole
+        if err := rows.Scan(&role.UserID, &role.Role, &role.GrantedBy, &role.UpdatedAt); err != nil {
+            return nil, err
+        }
+        roles = append(roles, role)
+    }
+    return roles, rows.Err()
+}
+
+func (s postgresStore) Usergroups(ctx context.Context) ([]Usergroup, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    members, err := usergroupMembers(ctx, db)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, "SELECT usergroup_id, handle, name, synced_at FROM slack_usergroups ORDER BY handle")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    groups := []Usergroup{}
+    for rows.Next() {
+        var group Usergroup
+        if err := rows.Scan(&group.ID, &group.Handle, &group.Name, &group.SyncedAt); err != nil {
+            return nil, err
+        }
+        group.Members = members[group.ID]
+        if group.Members == nil {
+            group.Members = []string{}
+        }
+        groups = append(groups, group)
+    }
+    return groups, rows.Err()
+}
+
+// Ensure that the store interfaces are implemented
+var (
+    _ ThreadStore          = postgresStore{}
+    _ NoteStore            = postgresStore{}
+    _ EffortStore          = postgresStore{}
+    _ WatcherStore         = postgresStore{}
+    _ ThreadListStore      = postgresStore{}
+    _ ThreadStatusStore    = postgresStore{}
+    _ ThreadOverrideStore  = postgresStore{}
+    _ BulkThreadStore      = postgresStore{}
+    _ ResolutionStore      = postgresStore{}
+    _ AssignmentStore      = postgresStore{}
+    _ AckStore             = postgresStore{}
+    _ ReporterWaitStore    = postgresStore{}
+    _ ReleaseStore         = postgresStore{}
+    _ AnswerSignalStore    = postgresStore{}
+    _ StatsStore           = postgresStore{}
+    _ ChannelStore         = postgresStore{}
+    _ ChannelConfigStore   = postgresStore{}
+    _ ChannelGroupStore    = postgresStore{}
+    _ GoalStore            = postgresStore{}
+    _ ReplyTemplateStore   = postgresStore{}
+    _ UserStore            = postgresStore{}
+    _ DirectoryStore       = postgresStore{}
+    _ MessageStore         = postgresStore{}
+    _ BackfillStore        = postgresStore{}
+    _ ReminderStore        = postgresStore{}
+    _ QualityPromptStore   = postgresStore{}
+    _ SuggestionStore      = postgresStore{}
+    _ LiveThreadStore      = postgresStore{}
+    _ GitHubIssueStore     = postgresStore{}
+    _ JiraTicketStore      = postgresStore{}
+    _ ChannelSummaryStore  = postgresStore{}
+    _ StorageStore         = postgresStore{}
+    _ AdoptionStore        = postgresStore{}
+    _ CalibrationStore     = postgresStore{}
+    _ DigestStore          = postgresStore{}
+    _ AnalysisStore        = postgresStore{}
+    _ SlackConnectStore    = postgresStore{}
+    _ LegalHoldStore       = postgresStore{}
+    _ ExportJobStore       = postgresStore{}
+    _ WebhookStore         = postgresStore{}
+    _ AuditStore           = postgresStore{}
+    _ TokenStore           = postgresStore{}
+    _ APIKeyStore          = postgresStore{}
+    _ SessionStore         = postgresStore{}
+    _ EmailPreferenceStore = postgresStore{}
+    _ PreferenceStore      = postgresStore{}
+    _ SlackClient          = (*slack.Client)(nil)
+)
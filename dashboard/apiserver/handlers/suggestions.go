@@ -0,0 +1,226 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/similarity"
+    "dashboard/apiserver/slack"
+)
+
+const (
+    suggestionInterval = 5 * time.Minute
+    suggestionMatches  = 3
+    suggestionMinScore = 0.15
+    // suggestionHistory bounds how many past threads of a channel are
+    // compared against each unanswered one
+    suggestionHistory = 500
+    suggestionBlockID = "suggestion"
+)
+
+// unansweredThread is an open thread without replies that may deserve a
+// suggestion.
+type unansweredThread struct {
+    ThreadTS     string
+    UserID       string
+    Title        string
+    Description  string
+    Stakeholders []string
+    CreatedAt    time.Time
+}
+
+// RunSuggestions periodically shows likely stakeholders of unanswered
+// threads an ephemeral message with similar past threads and a claim
+// button. It does nothing unless a threshold and a bot token are configured.
+func (c *Container) RunSuggestions(ctx context.Context) {
+    if c.suggestAfter <= 0 || !c.slack.Configured() {
+        return
+    }
+
+    ticker := time.NewTicker(suggestionInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.postSuggestions(ctx); err != nil {
+            c.logger.Warnf("failed to post thread suggestions: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (c *Container) postSuggestions(ctx context.Context) error {
+    if !c.slackFeatureReady(slackFeatureReplies) {
+        return nil
+    }
+    channels, err := c.channels.Channels(ctx)
+    if err != nil {
+        return err
+    }
+
+    cutoff := time.Now().UTC().Add(-c.suggestAfter)
+    for _, ch := range channels {
+        // Channels that opted out of text have no titles to compare
+        if !ch.TextIndexing {
+            continue
+        }
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        if err := c.suggestForChannel(ctx, ch.ChannelID, cutoff); err != nil {
+            c.logger.Warnf("failed to post suggestions for channel %s: %v", ch.ChannelID, err)
+        }
+    }
+    return nil
+}
+
+func (c *Container) suggestForChannel(ctx context.Context, channelID string, cutoff time.Time) error {
+    unanswered, err := c.suggestions.UnansweredThreads(ctx, channelID, cutoff)
+    if err != nil {
+        return err
+    }
+    if len(unanswered) == 0 {
+        return nil
+    }
+
+    history, err := c.suggestions.PastThreads(ctx, channelID)
+    if err != nil {
+        return err
+    }
+
+    for _, thread := range unanswered {
+        matches := similarity.Rank(thread.Title+" "+thread.Description, history, suggestionMatches, suggestionMinScore)
+        blocks := suggestionBlocks(channelID, thread, matches, titles(history))
+
+        for _, userID := range thread.Stakeholders {
+            if userID == thread.UserID {
+                continue
+            }
+            err := c.slack.PostEphemeral(ctx, channelID, userID, "An unanswered thread may need you", blocks)
+            if err != nil {
+                c.logger.Warnf("failed to suggest thread %s of channel %s to %s: %v", thread.ThreadTS, channelID, userID, err)
+            }
+        }
+
+        // Each thread is only suggested once, even to nobody
+        if err := c.suggestions.RecordSuggestion(ctx, channelID, thread.ThreadTS); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func titles(documents []similarity.Document) map[string]string {
+    byID := make(map[string]string, len(documents))
+    for _, document := range documents {
+        title, _, _ := strings.Cut(document.Text, "\n")
+        byID[document.ID] = title
+    }
+    return byID
+}
+
+func suggestionBlocks(channelID string, thread unansweredThread, matches []similarity.Match, titles map[string]string) []slack.Block {
+    title := thread.Title
+    if title == "" {
+        title = "this thread"
+    }
+    age := time.Since(thread.CreatedAt).Round(time.Minute)
+    blocks := []slack.Block{
+        slack.Section(fmt.Sprintf("<%s|%s> has had no reply for %s.",
+            slack.Permalink(channelID, thread.ThreadTS), title, age)),
+    }
+
+    if len(matches) > 0 {
+        lines := []string{"*Similar past threads*"}
+        for _, match := range matches {
+            lines = append(lines, fmt.Sprintf("• <%s|%s>", slack.Permalink(channelID, match.ID), titles[match.ID]))
+        }
+        blocks = append(blocks, slack.Section(strings.Join(lines, "\n")))
+    }
+
+    claim := slack.Button("Claim", claimThreadAction, channelID+":"+thread.ThreadTS)
+    claim.Style = "primary"
+    blocks = append(blocks, slack.Actions(suggestionBlockID, claim))
+    return blocks
+}
+
+func (s postgresStore) UnansweredThreads(ctx context.Context, channelID string, before time.Time) ([]unansweredThread, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT t.thread_ts, t.user_id, COALESCE(t.ai_thread_name, ''), COALESCE(t.ai_description, ''),
+               t.ai_stakeholders, t.created_at
+        FROM threads t
+        WHERE t.channel_id = $2 AND t.status = 'open' AND t.reply_count = 0 AND t.created_at < $1
+          AND NOT EXISTS (SELECT 1 FROM thread_suggestions s
+                          WHERE s.channel_id = t.channel_id AND s.thread_ts = t.thread_ts)
+          AND NOT EXISTS (SELECT 1 FROM thread_claims tc
+                          WHERE tc.channel_id = t.channel_id AND tc.thread_ts = t.thread_ts)
+    `, before, channelID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    unanswered := []unansweredThread{}
+    for rows.Next() {
+        var thread unansweredThread
+        var stakeholders string
+        if err := rows.Scan(&thread.ThreadTS, &thread.UserID, &thread.Title, &thread.Description,
+            &stakeholders, &thread.CreatedAt); err != nil {
+            continue
+        }
+        json.Unmarshal([]byte(stakeholders), &thread.Stakeholders)
+        unanswered = append(unanswered, thread)
+    }
+    return unanswered, rows.Err()
+}
+
+func (s postgresStore) PastThreads(ctx context.Context, channelID string) ([]similarity.Document, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT thread_ts, ai_thread_name, COALESCE(ai_description, '')
+        FROM threads
+        WHERE channel_id = $2 AND status <> 'open' AND ai_thread_name IS NOT NULL
+        ORDER BY latest_reply DESC
+        LIMIT $1
+    `, suggestionHistory, channelID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    documents := []similarity.Document{}
+    for rows.Next() {
+        var ts, title, description string
+        if err := rows.Scan(&ts, &title, &description); err != nil {
+            continue
+        }
+        // The title is kept on its own first line for display
+        documents = append(documents, similarity.Document{ID: ts, Text: title + "\n" + description})
+    }
+    return documents, rows.Err()
+}
+
+func (s postgresStore) RecordSuggestion(ctx context.Context, channelID string, threadTS string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO thread_suggestions (channel_id, thread_ts, suggested_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (channel_id, thread_ts) DO NOTHING
+    `, channelID, threadTS)
+    return err
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/analysis"
+    "dashboard/apiserver/domain"
+)
+
+func TestPostSuggestionsOncePerThread(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "private"}})
+    old := time.Now().Add(-3 * time.Hour)
+    for _, thread := range []domain.Thread{
+        {ChannelID: "C1", ThreadTS: "1700000000.000100", UserID: "U1", Status: statusOpen, CreatedAt: old, LatestReply: old},
+        {ChannelID: "C1", ThreadTS: "1700000000.000200", UserID: "U1", Status: statusOpen, ReplyCount: 1, CreatedAt: old, LatestReply: old},
+        {ChannelID: "C1", ThreadTS: "1700000000.000300", UserID: "U1", Status: statusResolved, CreatedAt: old, LatestReply: old},
+        {ChannelID: "C2", ThreadTS: "1700000000.000400", UserID: "U1", Status: statusOpen, CreatedAt: old, LatestReply: old},
+    } {
+        store.AddThread(StoredThread{Thread: thread})
+        result := &analysis.Result{Reasoning: "Deploys time out."}
+        if err := store.SaveAnalysis(context.Background(), thread.ChannelID, thread.ThreadTS, result, []string{"U1", "U2", "U3"}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    slackClient := &MemorySlack{}
+    c := newTestContainer(t, store, slackClient)
+    c.suggestAfter = time.Hour
+
+    for range 2 {
+        if err := c.postSuggestions(context.Background()); err != nil {
+            t.Fatal(err)
+        }
+    }
+    messages := slackClient.Messages()
+    if len(messages) != 2 || messages[0].User != "U2" || messages[1].User != "U3" {
+        t.Fatalf("posted %+v, want one suggestion to each other stakeholder", messages)
+    }
+    if messages[0].Channel != "C1" {
+        t.Fatalf("suggested in %s, want C1", messages[0].Channel)
+    }
+}
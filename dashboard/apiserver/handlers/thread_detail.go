@@ -0,0 +1,330 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/github"
+
+    "github.com/labstack/echo/v4"
+)
+
+// threadFetchLimit caps the messages fetched from Slack for a thread whose
+// messages were not all stored, e.g. because it predates message storage.
+const threadFetchLimit = 1000
+
+// linkLookupTimeout bounds looking up linked issues, so a slow tracker does
+// not hold up the thread.
+const linkLookupTimeout = 10 * time.Second
+
+// ThreadMessage is the root or a reply of a thread.
+type ThreadMessage struct {
+    TS       string    `json:"ts"`
+    UserID   string    `json:"user_id"`
+    Text     *string   `json:"text"`
+    PostedAt time.Time `json:"posted_at"`
+}
+
+// LinkedGitHubIssue is the GitHub issue linked to a thread. Only URL is set
+// when the link is not a GitHub issue, Error when it could not be looked up.
+type LinkedGitHubIssue struct {
+    URL       string     `json:"url"`
+    Repo      string     `json:"repo,omitempty"`
+    Number    int        `json:"number,omitempty"`
+    Title     string     `json:"title,omitempty"`
+    State     string     `json:"state,omitempty"`
+    Labels    []string   `json:"labels,omitempty"`
+    Assignees []string   `json:"assignees,omitempty"`
+    UpdatedAt *time.Time `json:"updated_at,omitempty"`
+    ClosedAt  *time.Time `json:"closed_at,omitempty"`
+    Error     string     `json:"error,omitempty"`
+}
+
+// LinkedJiraTicket is the Jira ticket linked to a thread. URL is only known
+// with YB_OPEN_THREADS_REMINDER_JIRA_URL or when the link is one, Status
+// only for tickets created from the dashboard once they transitioned.
+type LinkedJiraTicket struct {
+    Key    string `json:"key"`
+    URL    string `json:"url,omitempty"`
+    Status string `json:"status,omitempty"`
+}
+
+// ThreadDetail is everything the dashboard shows about a single thread.
+// MessagesSource tells whether Messages were "stored" or fetched from
+// "slack".
+type ThreadDetail struct {
+    Thread         Thread             `json:"thread"`
+    Messages       []ThreadMessage    `json:"messages"`
+    MessagesSource string             `json:"messages_source"`
+    Stakeholders   []UserProfile      `json:"stakeholders"`
+    GitHub         *LinkedGitHubIssue `json:"github"`
+    Jira           *LinkedJiraTicket  `json:"jira"`
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+    ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertThreadMessage keeps a message of a tracked thread and reports
+// whether it was not stored before. A message without text is stored
+// without it.
+func insertThreadMessage(ctx context.Context, db execer, channelID string, threadTS string, msg ThreadMessage) (bool, error) {
+    result, err := db.ExecContext(ctx, `
+        INSERT INTO thread_messages (channel_id, thread_ts, ts, user_id, text, posted_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (channel_id, ts) DO NOTHING
+    `, channelID, threadTS, msg.TS, msg.UserID, msg.Text, msg.PostedAt)
+    if err != nil {
+        return false, err
+    }
+    inserted, _ := result.RowsAffected()
+    return inserted > 0, nil
+}
+
+// storedThreadMessages returns the stored messages of a thread, oldest first.
+func storedThreadMessages(ctx context.Context, db *sql.DB, channelID string, threadTS string) ([]ThreadMessage, error) {
+    rows, err := db.QueryContext(ctx, `
+        SELECT ts, COALESCE(user_id, ''), text, posted_at
+        FROM thread_messages
+        WHERE channel_id = $1 AND thread_ts = $2
+        ORDER BY posted_at, ts
+    `, channelID, threadTS)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    messages := []ThreadMessage{}
+    for rows.Next() {
+        var message ThreadMessage
+        if err := rows.Scan(&message.TS, &message.UserID, &message.Text, &message.PostedAt); err != nil {
+            continue
+        }
+        messages = append(messages, message)
+    }
+    return messages, rows.Err()
+}
+
+// fetchThreadMessages fetches the messages of a thread from Slack and stores
+// them, so the thread is only fetched once.
+func (c *Container) fetchThreadMessages(ctx context.Context, channelID string, threadTS string, textIndexing bool) ([]ThreadMessage, error) {
+    messages := []ThreadMessage{}
+    cursor := ""
+    for len(messages) < threadFetchLimit {
+        page, err := c.slack.ConversationsReplies(ctx, channelID, threadTS, cursor, 200)
+        if err != nil {
+            return nil, err
+        }
+        for _, msg := range page.Messages {
+            postedAt, err := slackTSTime(msg.TS)
+            if err != nil {
+                continue
+            }
+            message := ThreadMessage{TS: msg.TS, UserID: msg.User, PostedAt: postedAt}
+            if textIndexing {
+                text := msg.Text
+                message.Text = &text
+            }
+            if err := c.messages.KeepMessage(ctx, channelID, threadTS, message); err != nil {
+                c.logger.Warnf("failed to store message %s of %s/%s: %v", msg.TS, channelID, threadTS, err)
+            }
+            messages = append(messages, message)
+        }
+        cursor = page.ResponseMetadata.NextCursor
+        if !page.HasMore || cursor == "" {
+            break
+        }
+    }
+    return messages, nil
+}
+
+// threadStakeholderIDs returns everyone involved in a thread: its author,
+// the stakeholders identified by AI, whoever claimed or acknowledged it and
+// everyone who replied.
+func threadStakeholderIDs(thread Thread, messages []ThreadMessage) []string {
+    ids := []string{thread.UserID}
+    var stakeholders []string
+    json.Unmarshal([]byte(thread.AIStakeholders), &stakeholders)
+    ids = append(ids, stakeholders...)
+    if thread.ClaimedBy != nil {
+        ids = append(ids, *thread.ClaimedBy)
+    }
+    if thread.AcknowledgedBy != nil {
+        ids = append(ids, *thread.AcknowledgedBy)
+    }
+    for _, message := range messages {
+        ids = append(ids, message.UserID)
+    }
+
+    seen := make(map[string]bool, len(ids))
+    unique := []string{}
+    for _, id := range ids {
+        if id != "" && !seen[id] {
+            seen[id] = true
+            unique = append(unique, id)
+        }
+    }
+    return unique
+}
+
+// linkedGitHubIssue looks up the GitHub issue a thread links to.
+func (c *Container) linkedGitHubIssue(ctx context.Context, link string) *LinkedGitHubIssue {
+    linked := &LinkedGitHubIssue{URL: link}
+    repo, number, ok := github.ParseIssueRef(link)
+    if !ok {
+        return linked
+    }
+    linked.Repo, linked.Number = repo, number
+
+    ctx, cancel := context.WithTimeout(ctx, linkLookupTimeout)
+    defer cancel()
+    issue, err := c.github.Issue(ctx, repo, number)
+    if err == github.ErrNotFound {
+        linked.Error = "Issue not found"
+        return linked
+    }
+    if err != nil {
+        c.logger.Warnf("failed to look up GitHub issue %s#%d: %v", repo, number, err)
+        linked.Error = "Failed to look up issue"
+        return linked
+    }
+
+    linked.URL = issue.HTMLURL
+    linked.Title = issue.Title
+    linked.State = issue.State
+    for _, label := range issue.Labels {
+        linked.Labels = append(linked.Labels, label.Name)
+    }
+    for _, assignee := range issue.Assignees {
+        linked.Assignees = append(linked.Assignees, assignee.Login)
+    }
+    linked.UpdatedAt = &issue.UpdatedAt
+    linked.ClosedAt = issue.ClosedAt
+    return linked
+}
+
+// linkedJiraTicket links the Jira ticket of a thread, stored as a key or a
+// browse link.
+func (c *Container) linkedJiraTicket(ticket string) *LinkedJiraTicket {
+    ticket = strings.TrimSpace(ticket)
+    if strings.HasPrefix(ticket, "http://") || strings.HasPrefix(ticket, "https://") {
+        key := ticket[strings.LastIndex(strings.TrimSuffix(ticket, "/"), "/")+1:]
+        return &LinkedJiraTicket{Key: strings.TrimSuffix(key, "/"), URL: ticket}
+    }
+    linked := &LinkedJiraTicket{Key: ticket}
+    if c.jiraURL != "" {
+        linked.URL = c.jiraURL + "/browse/" + ticket
+    }
+    return linked
+}
+
+// GetThread - Get a thread with its messages, stakeholders and linked issues
+func (c *Container) GetThread(ctx echo.Context) error {
+    detail, err := c.loadThreadDetail(ctx)
+    if err != nil {
+        return err
+    }
+    return ctx.JSON(http.StatusOK, detail)
+}
+
+// loadThreadDetail loads the thread of a request with its messages,
+// stakeholders and linked issues.
+func (c *Container) loadThreadDetail(ctx echo.Context) (*ThreadDetail, error) {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+    reqCtx := ctx.Request().Context()
+
+    ch, err := c.channels.Channel(reqCtx, channelID)
+    if err == errChannelNotTracked {
+        return nil, apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return nil, apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+    }
+
+    ref := ThreadRef{ChannelID: channelID, ThreadTS: threadTS}
+    found, err := c.threadLists.ThreadsByRef(reqCtx, []ThreadRef{ref})
+    if err != nil {
+        c.logger.Errorf("failed to query thread %s/%s: %v", channelID, threadTS, err)
+        return nil, apierror.New(http.StatusInternalServerError, "Failed to query thread")
+    }
+    thread, ok := found[ref]
+    if !ok {
+        return nil, apierror.New(http.StatusNotFound, "Thread not found")
+    }
+    detail := ThreadDetail{Thread: thread}
+
+    detail.Messages, err = c.messages.Messages(reqCtx, channelID, threadTS)
+    if err != nil {
+        return nil, apierror.New(http.StatusInternalServerError, "Failed to query thread messages")
+    }
+    detail.MessagesSource = "stored"
+    // Threads started before messages were stored, or with replies missed
+    // while the app was down, are fetched from Slack
+    if len(detail.Messages) < detail.Thread.ReplyCount+1 && c.slackFeatureReady(slackFeatureThreadHistory) {
+        fetched, err := c.fetchThreadMessages(reqCtx, channelID, threadTS, ch.TextIndexing)
+        if err != nil {
+            c.logger.Warnf("failed to fetch messages of %s/%s: %v", channelID, threadTS, err)
+        } else {
+            detail.Messages = fetched
+            detail.MessagesSource = "slack"
+        }
+    }
+    // Text stored before the channel opted out is never served
+    if !ch.TextIndexing {
+        for i := range detail.Messages {
+            detail.Messages[i].Text = nil
+        }
+    }
+
+    detail.Stakeholders, err = c.users.Profiles(reqCtx, threadStakeholderIDs(detail.Thread, detail.Messages))
+    if err != nil {
+        c.logger.Warnf("failed to load stakeholders of %s/%s: %v", channelID, threadTS, err)
+    }
+    if detail.Stakeholders == nil {
+        detail.Stakeholders = []UserProfile{}
+    }
+
+    if detail.Thread.GithubIssue != nil && *detail.Thread.GithubIssue != "" {
+        detail.GitHub = c.linkedGitHubIssue(reqCtx, *detail.Thread.GithubIssue)
+    }
+    if detail.Thread.JiraTicket != nil && *detail.Thread.JiraTicket != "" {
+        detail.Jira = c.linkedJiraTicket(*detail.Thread.JiraTicket)
+        detail.Jira.Status, err = c.jiraTickets.JiraTicketStatus(reqCtx, detail.Jira.Key)
+        if err != nil {
+            c.logger.Warnf("failed to look up status of Jira ticket %s: %v", detail.Jira.Key, err)
+        }
+    }
+
+    return &detail, nil
+}
+
+func (s postgresStore) KeepMessage(ctx context.Context, channelID string, threadTS string, msg ThreadMessage) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = insertThreadMessage(ctx, db, channelID, threadTS, msg)
+    return err
+}
+
+func (s postgresStore) JiraTicketStatus(ctx context.Context, key string) (string, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return "", err
+    }
+    var status string
+    err = db.QueryRowContext(ctx, `
+        SELECT COALESCE(status, '') FROM thread_jira_tickets WHERE ticket_key = $1
+    `, key).Scan(&status)
+    if err == sql.ErrNoRows {
+        return "", nil
+    }
+    return status, err
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+)
+
+func TestThreadDetailHidesTextOfChannelsWithoutIndexing(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "legal"}})
+    now := time.Now()
+    text := "Can someone look at the contract?"
+    thread := domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", UserID: "U1", Status: statusOpen, LatestReply: now}
+    if err := store.StartThread(context.Background(), thread, ThreadMessage{TS: thread.ThreadTS, UserID: "U1", Text: &text, PostedAt: now}); err != nil {
+        t.Fatal(err)
+    }
+    store.AddProfile(UserProfile{UserProfile: domain.UserProfile{UserID: "U1", ResolvedName: "Ada"}})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    route := "/api/threads/:channel_id/:thread_ts"
+    rec := serveRequest(t, c, http.MethodGet, route, "/api/threads/C1/1700000000.000100", c.GetThread)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var detail ThreadDetail
+    decodeResponse(t, rec, &detail)
+    if detail.Thread.ChannelName != "legal" || detail.MessagesSource != "stored" || len(detail.Messages) != 1 ||
+        detail.Messages[0].Text != nil || len(detail.Stakeholders) != 1 {
+        t.Fatalf("got detail %+v", detail)
+    }
+
+    rec = serveRequest(t, c, http.MethodGet, route, "/api/threads/C1/1700000000.000200", c.GetThread)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("missing thread got status %d", rec.Code)
+    }
+    rec = serveRequest(t, c, http.MethodGet, route, "/api/threads/C2/1700000000.000100", c.GetThread)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("untracked channel got status %d", rec.Code)
+    }
+}
+
+func TestThreadReportIsPDF(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", UserID: "U1", Status: statusOpen, LatestReply: time.Now()}})
+    if _, err := store.CloseThread(context.Background(), "C1", "1700000000.000100", "U2", "fixed", ""); err != nil {
+        t.Fatal(err)
+    }
+    store.Audit(context.Background(), AuditEntry{Actor: "U2", Action: "thread.resolve", ChannelID: "C1", ThreadTS: "1700000000.000100"})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    route := "/api/threads/:channel_id/:thread_ts/report"
+    rec := serveRequest(t, c, http.MethodGet, route, "/api/threads/C1/1700000000.000100/report", c.GetThreadReport)
+    if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+        t.Fatalf("got status %d, content type %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+    }
+}
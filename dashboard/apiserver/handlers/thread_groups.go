@@ -0,0 +1,116 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// groupByChannel is the group_by parameter of GetThreads nesting threads
+// under their channel.
+const groupByChannel = "channel"
+
+// ChannelThreads is a channel of a thread list grouped by channel, with its
+// first threads.
+type ChannelThreads struct {
+    ChannelID   string   `json:"channel_id"`
+    ChannelName string   `json:"channel_name"`
+    Items       []Thread `json:"items"`
+    // Total is the number of threads of the channel matching the filters
+    Total int `json:"total"`
+}
+
+// threadGroup is the first threads of a channel read by a grouped thread
+// list, before they are presented.
+type threadGroup struct {
+    listed []listedThread
+    total  int
+}
+
+// queryThreadGroups returns up to limit threads of each channel of q, in the
+// sort and order of the list, with the count of the threads of each.
+func queryThreadGroups(ctx context.Context, db *sql.DB, q *threadQuery, sort string, order string, limit int) (map[string]*threadGroup, error) {
+    // Arguments are copied to leave q as it was
+    args := append(append([]interface{}{}, q.args...), limit)
+    query := fmt.Sprintf(`
+        SELECT g.* FROM (
+            SELECT u.*,
+                   ROW_NUMBER() OVER (PARTITION BY u.channel_id ORDER BY %[1]s %[2]s NULLS LAST, u.thread_ts %[2]s) AS group_rank,
+                   COUNT(*) OVER (PARTITION BY u.channel_id) AS group_total
+            FROM %[3]s
+        ) g
+        WHERE g.group_rank <= $%[4]d
+        ORDER BY g.channel_id, g.group_rank`, threadSortColumns[sort], strings.ToUpper(order), q.from, len(args))
+
+    rows, err := db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    groups := map[string]*threadGroup{}
+    for rows.Next() {
+        var l listedThread
+        var rank, total int
+        if err := rows.Scan(append(threadListDest(&l.thread, &l.dueOverride), &rank, &total)...); err != nil {
+            return nil, err
+        }
+        group, ok := groups[l.thread.ChannelID]
+        if !ok {
+            group = &threadGroup{total: total}
+            groups[l.thread.ChannelID] = group
+        }
+        group.listed = append(group.listed, l)
+    }
+    return groups, rows.Err()
+}
+
+func (s postgresStore) GroupThreads(ctx context.Context, list threadList) ([]ChannelThreads, int, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, 0, err
+    }
+    holds, err := loadActiveHolds(db)
+    if err != nil {
+        return nil, 0, err
+    }
+    q, err := threadListQuery(db, list.filters)
+    if err != nil || len(q.channels) == 0 {
+        return []ChannelThreads{}, 0, err
+    }
+    groups, err := queryThreadGroups(ctx, db, q, list.sort, list.order, list.limit)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    now := time.Now()
+    total := 0
+    channels := make([]ChannelThreads, 0, len(groups))
+    for channelID, group := range groups {
+        ch := q.channels[channelID]
+        threads := ChannelThreads{ChannelID: channelID, ChannelName: ch.name, Items: []Thread{}, Total: group.total}
+        for _, l := range group.listed {
+            thread := l.thread
+            ch.present(&thread, holds, l.dueOverride, now)
+            threads.Items = append(threads.Items, thread)
+        }
+        channels = append(channels, threads)
+        total += group.total
+    }
+    return channels, total, nil
+}
+
+// sortChannelThreads sorts the channels of a grouped thread list in the
+// sort and order of their first thread.
+func sortChannelThreads(channels []ChannelThreads, sortBy string, order string) {
+    sort.Slice(channels, func(i, j int) bool {
+        a, b := threadSortValue(sortBy, channels[i].Items[0]), threadSortValue(sortBy, channels[j].Items[0])
+        if a != b {
+            return (a > b) == (order != "asc")
+        }
+        return channels[i].ChannelName < channels[j].ChannelName
+    })
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/github"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// maxNoteLength keeps notes within what GitHub and Jira accept as comments.
+const maxNoteLength = 10000
+
+// ThreadNote is a triage note written on a thread. GithubComment and
+// JiraComment link the comments it was mirrored as, and SyncError tells why
+// mirroring failed.
+type ThreadNote struct {
+    ID            int64     `json:"id"`
+    ChannelID     string    `json:"channel_id"`
+    ThreadTS      string    `json:"thread_ts"`
+    Author        string    `json:"author"`
+    Body          string    `json:"body"`
+    GithubComment *string   `json:"github_comment"`
+    JiraComment   *string   `json:"jira_comment"`
+    SyncError     *string   `json:"sync_error"`
+    CreatedAt     time.Time `json:"created_at"`
+}
+
+// ThreadNoteRequest is the body accepted by AddThreadNote
+type ThreadNoteRequest struct {
+    Body string `json:"body"`
+}
+
+// NoteSyncRequest is the body accepted by SetChannelNoteSync
+type NoteSyncRequest struct {
+    Enabled bool `json:"enabled"`
+}
+
+const threadNoteColumns = "id, channel_id, thread_ts, author, body, github_comment, jira_comment, sync_error, created_at"
+
+func scanThreadNote(row interface{ Scan(...interface{}) error }) (ThreadNote, error) {
+    var note ThreadNote
+    err := row.Scan(&note.ID, &note.ChannelID, &note.ThreadTS, &note.Author, &note.Body,
+        &note.GithubComment, &note.JiraComment, &note.SyncError, &note.CreatedAt)
+    return note, err
+}
+
+// noteComment returns the comment a note is mirrored as, attributed to its
+// author. GitHub renders Markdown, Jira its own wiki markup.
+func noteComment(author string, body string, permalink string, markdown bool) string {
+    if markdown {
+        return fmt.Sprintf("**%s** added a triage note on the [Slack thread](%s):\n\n%s", author, permalink, body)
+    }
+    return fmt.Sprintf("*%s* added a triage note on the [Slack thread|%s]:\n\n%s", author, permalink, body)
+}
+
+// syncThreadNote mirrors a note as a comment on the GitHub issue and Jira
+// ticket linked to its thread, recording the comments and any failure on
+// the note. A failure on one side does not keep the other from syncing.
+func (c *Container) syncThreadNote(ctx context.Context, note *ThreadNote, githubIssue string, jiraTicket string) {
+    names, err := c.resolveUserNames(ctx, []string{note.Author})
+    if err != nil {
+        c.logger.Warnf("failed to resolve the name of %s: %v", note.Author, err)
+    }
+    author := names[note.Author]
+    permalink := slack.Permalink(note.ChannelID, note.ThreadTS)
+
+    failures := []string{}
+    if githubIssue != "" {
+        repo, number, ok := github.ParseIssueRef(githubIssue)
+        switch {
+        case !ok:
+            failures = append(failures, "GitHub issue "+githubIssue+" is not an issue link")
+        case !c.github.Authenticated():
+            failures = append(failures, "GitHub token is not configured")
+        default:
+            comment, err := c.github.CreateComment(ctx, repo, number, noteComment(author, note.Body, permalink, true))
+            if err != nil {
+                c.logger.Warnf("failed to comment note %d on %s: %v", note.ID, githubIssue, err)
+                failures = append(failures, "GitHub: "+err.Error())
+            } else {
+                note.GithubComment = &comment.HTMLURL
+            }
+        }
+    }
+    if jiraTicket != "" {
+        if !c.jira.Configured() {
+            failures = append(failures, "Jira site or token is not configured")
+        } else {
+            comment, err := c.jira.AddComment(ctx, jiraTicket, noteComment(author, note.Body, permalink, false))
+            if err != nil {
+                c.logger.Warnf("failed to comment note %d on %s: %v", note.ID, jiraTicket, err)
+                failures = append(failures, "Jira: "+err.Error())
+            } else {
+                link := c.jira.BrowseURL(jiraTicket) + "?focusedCommentId=" + comment.ID
+                note.JiraComment = &link
+            }
+        }
+    }
+    if len(failures) > 0 {
+        syncError := strings.Join(failures, "; ")
+        note.SyncError = &syncError
+    }
+
+    if err := c.notes.RecordNoteSync(ctx, *note); err != nil {
+        c.logger.Warnf("failed to record the sync of note %d: %v", note.ID, err)
+    }
+}
+
+// GetThreadNotes - List the triage notes of a thread, oldest first
+func (c *Container) GetThreadNotes(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    notes, err := c.threads.Notes(ctx.Request().Context(), channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread notes")
+    }
+
+    return ctx.JSON(http.StatusOK, notes)
+}
+
+// AddThreadNote - Add a triage note to a thread, mirrored on its linked GitHub issue and Jira ticket when the channel syncs notes
+func (c *Container) AddThreadNote(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req ThreadNoteRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    req.Body = strings.TrimSpace(req.Body)
+    if req.Body == "" {
+        return apierror.New(http.StatusBadRequest, "body is required")
+    }
+    if len(req.Body) > maxNoteLength {
+        return apierror.Newf(http.StatusBadRequest, "body must be at most %d bytes", maxNoteLength)
+    }
+
+    reqCtx := ctx.Request().Context()
+    thread, err := c.threads.Thread(reqCtx, channelID, threadTS)
+    if err != nil {
+        return threadLookupError(ctx, err)
+    }
+    config, err := c.configs.ChannelConfig(reqCtx, channelID)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread")
+    }
+
+    actor := actorFrom(ctx)
+    note, err := c.notes.CreateNote(reqCtx, ThreadNote{ChannelID: channelID, ThreadTS: threadTS, Author: actor, Body: req.Body})
+    if err != nil {
+        c.logger.Errorf("failed to add note to %s/%s: %v", channelID, threadTS, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to add note")
+    }
+
+    if config.NoteSync && (thread.GithubIssue != "" || thread.JiraTicket != "") {
+        syncCtx, cancel := context.WithTimeout(reqCtx, issueCreateTimeout)
+        defer cancel()
+        c.syncThreadNote(syncCtx, &note, thread.GithubIssue, thread.JiraTicket)
+    }
+
+    c.audit(reqCtx, actor, "thread.note", channelID, threadTS, map[string]interface{}{
+        "note_id":        note.ID,
+        "github_comment": note.GithubComment,
+        "jira_comment":   note.JiraComment,
+    })
+
+    return ctx.JSON(http.StatusCreated, note)
+}
+
+// SetChannelNoteSync - Set whether the notes of a channel's threads are mirrored on their GitHub issues and Jira tickets
+func (c *Container) SetChannelNoteSync(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+
+    var req NoteSyncRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+
+    err := c.configs.SetChannelConfig(ctx.Request().Context(), channelID, ChannelConfig{NoteSync: req.Enabled}, settingNoteSync)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update channel configuration")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "channel.note_sync", channelID, "", map[string]interface{}{
+        "note_sync": req.Enabled,
+    })
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "note_sync": req.Enabled,
+    })
+}
+
+func (s postgresStore) CreateNote(ctx context.Context, note ThreadNote) (ThreadNote, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ThreadNote{}, err
+    }
+    return scanThreadNote(db.QueryRowContext(ctx, `
+        INSERT INTO thread_notes (channel_id, thread_ts, author, body, created_at)
+        VALUES ($1, $2, $3, $4, NOW())
+        RETURNING `+threadNoteColumns, note.ChannelID, note.ThreadTS, note.Author, note.Body))
+}
+
+func (s postgresStore) RecordNoteSync(ctx context.Context, note ThreadNote) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        UPDATE thread_notes SET github_comment = $1, jira_comment = $2, sync_error = $3 WHERE id = $4
+    `, note.GithubComment, note.JiraComment, note.SyncError, note.ID)
+    return err
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/jira"
+)
+
+func TestGetThreadNotes(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddNote(ThreadNote{ID: 1, ChannelID: "C1", ThreadTS: "1700000000.000100", Author: "U1", Body: "first"})
+    store.AddNote(ThreadNote{ID: 2, ChannelID: "C1", ThreadTS: "1700000000.000100", Author: "U2", Body: "second"})
+    store.AddNote(ThreadNote{ID: 3, ChannelID: "C1", ThreadTS: "1700000000.000200", Author: "U1", Body: "other thread"})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/threads/:channel_id/:thread_ts/notes",
+        "/api/threads/C1/1700000000.000100/notes", c.GetThreadNotes)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var notes []ThreadNote
+    decodeResponse(t, rec, &notes)
+    if len(notes) != 2 || notes[0].Body != "first" || notes[1].Body != "second" {
+        t.Fatalf("got notes %+v, want the two of the thread oldest first", notes)
+    }
+
+    rec = serveRequest(t, c, http.MethodGet, "/api/threads/:channel_id/:thread_ts/notes",
+        "/api/threads/C1/1700000000.000300/notes", c.GetThreadNotes)
+    if rec.Code != http.StatusOK || rec.Body.String() != "[]\n" {
+        t.Fatalf("got %d %q for a thread without notes, want an empty list", rec.Code, rec.Body.String())
+    }
+}
+
+func TestAddThreadNoteRecordsSyncFailure(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: time.Now()},
+        JiraTicket: "OPS-1"})
+    if err := store.SetChannelConfig(context.Background(), "C1", ChannelConfig{NoteSync: true}, settingNoteSync); err != nil {
+        t.Fatal(err)
+    }
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.jira = jira.NewClient("", "", "")
+    agent := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+    add := func(target string) (int, ThreadNote) {
+        rec := serveAs(c, agent, http.MethodPost, "/api/threads/:channel_id/:thread_ts/notes", target, `{"body": "Waiting on a fix"}`, c.AddThreadNote)
+        var note ThreadNote
+        if rec.Code == http.StatusCreated {
+            decodeResponse(t, rec, &note)
+        }
+        return rec.Code, note
+    }
+
+    if code, _ := add("/api/threads/C2/1700000000.000100/notes"); code != http.StatusNotFound {
+        t.Fatalf("untracked channel got status %d", code)
+    }
+    if code, _ := add("/api/threads/C1/1700000000.000200/notes"); code != http.StatusNotFound {
+        t.Fatalf("missing thread got status %d", code)
+    }
+
+    // The note is kept when mirroring it on the linked ticket fails
+    code, note := add("/api/threads/C1/1700000000.000100/notes")
+    if code != http.StatusCreated || note.ID == 0 || note.Author != "U1" || note.SyncError == nil || note.JiraComment != nil {
+        t.Fatalf("got status %d and note %+v, want it added with the sync failure", code, note)
+    }
+    notes, err := store.Notes(context.Background(), "C1", "1700000000.000100")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(notes) != 1 || notes[0].SyncError == nil || *notes[0].SyncError != "Jira site or token is not configured" {
+        t.Fatalf("stored notes %+v, want the sync failure recorded", notes)
+    }
+}
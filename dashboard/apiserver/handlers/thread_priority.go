@@ -0,0 +1,156 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+// PrioritySource tells where the effective priority of a thread comes from
+type PrioritySource string
+
+const (
+    // PrioritySourceAI is the priority the AI assigned, as calibrated for
+    // its channel
+    PrioritySourceAI PrioritySource = "ai"
+    // PrioritySourceManual is a priority set by hand
+    PrioritySourceManual PrioritySource = "manual"
+)
+
+// manualPriorityColumn selects the priority set by hand on threads t.
+const manualPriorityColumn = `(SELECT p.manual_priority FROM thread_priorities p
+     WHERE p.channel_id = t.channel_id AND p.thread_ts = t.thread_ts)`
+
+// priorityColumn returns the effective priority of threads t: the one set by
+// hand, else the AI's, lowered to medium when the AI was less confident than
+// minConfidence that it is high.
+func priorityColumn(minConfidence string) string {
+    return `COALESCE(` + manualPriorityColumn + `,
+                   CASE WHEN t.ai_priority = 'high' AND t.ai_confidence < ` + minConfidence + ` THEN 'medium'
+                        ELSE COALESCE(t.ai_priority, 'none') END) AS priority`
+}
+
+// upsertManualPriority overrides the priority of a thread, keeping who did.
+const upsertManualPriority = `
+    INSERT INTO thread_priorities (channel_id, thread_ts, manual_priority, set_by, set_at)
+    VALUES ($1, $2, $3, $4, NOW())
+    ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+        manual_priority = EXCLUDED.manual_priority,
+        set_by = EXCLUDED.set_by,
+        set_at = EXCLUDED.set_at`
+
+// ThreadPriorityRequest is the body accepted by SetThreadPriority, a null
+// priority clears the override
+type ThreadPriorityRequest struct {
+    Priority *string `json:"priority"`
+}
+
+// ThreadPriority is the priority of a thread and where it comes from
+type ThreadPriority struct {
+    ChannelID         string         `json:"channel_id"`
+    ThreadTS          string         `json:"thread_ts"`
+    AIPriority        *string        `json:"ai_priority"`
+    ManualPriority    *string        `json:"manual_priority"`
+    EffectivePriority string         `json:"effective_priority"`
+    PrioritySource    PrioritySource `json:"priority_source"`
+    SetBy             *string        `json:"set_by"`
+    SetAt             *time.Time     `json:"set_at"`
+}
+
+// SetThreadPriority - Override the AI priority of a thread, or clear the override with a null priority
+func (c *Container) SetThreadPriority(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req ThreadPriorityRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if req.Priority != nil {
+        if _, err := domain.ParsePriority(*req.Priority); err != nil {
+            return apierror.New(http.StatusBadRequest, "priority must be high, medium, low, none or null")
+        }
+    }
+
+    reqCtx := ctx.Request().Context()
+    before, err := c.overrides.ThreadPriority(reqCtx, channelID, threadTS)
+    if err != nil {
+        return threadLookupError(ctx, err)
+    }
+
+    actor := actorFrom(ctx)
+    if err := c.overrides.SetThreadPriority(reqCtx, channelID, threadTS, req.Priority, actor); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to update thread priority")
+    }
+
+    after, err := c.overrides.ThreadPriority(reqCtx, channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread priority")
+    }
+    if after.EffectivePriority != before.EffectivePriority || after.PrioritySource != before.PrioritySource {
+        c.audit(ctx.Request().Context(), actor, "thread.priority", channelID, threadTS, map[string]interface{}{
+            "from":   before.EffectivePriority,
+            "to":     after.EffectivePriority,
+            "source": after.PrioritySource,
+        })
+    }
+    return ctx.JSON(http.StatusOK, after)
+}
+
+func (s postgresStore) ThreadPriority(ctx context.Context, channelID, threadTS string) (ThreadPriority, error) {
+    priority := ThreadPriority{ChannelID: channelID, ThreadTS: threadTS}
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return priority, err
+    }
+    if err := checkThread(db, channelID, threadTS); err != nil {
+        return priority, err
+    }
+
+    var storedCalibration sql.NullString
+    err = db.QueryRowContext(ctx, "SELECT priority_calibration FROM channel_config WHERE channel_id = $1", channelID).Scan(&storedCalibration)
+    if err != nil && err != sql.ErrNoRows {
+        return priority, err
+    }
+    err = db.QueryRowContext(ctx, fmt.Sprintf(`
+        SELECT t.ai_priority, %s, %s,
+               (SELECT p.set_by FROM thread_priorities p
+                WHERE p.channel_id = t.channel_id AND p.thread_ts = t.thread_ts),
+               (SELECT p.set_at FROM thread_priorities p
+                WHERE p.channel_id = t.channel_id AND p.thread_ts = t.thread_ts)
+        FROM threads t WHERE t.thread_ts = $2 AND t.channel_id = $3`, manualPriorityColumn, priorityColumn("$1")),
+        parsePriorityCalibration(storedCalibration).MinConfidence, threadTS, channelID).
+        Scan(&priority.AIPriority, &priority.ManualPriority, &priority.EffectivePriority, &priority.SetBy, &priority.SetAt)
+    if err == sql.ErrNoRows {
+        return priority, errThreadNotFound
+    }
+    if err != nil {
+        return priority, err
+    }
+    priority.PrioritySource = PrioritySourceAI
+    if priority.ManualPriority != nil {
+        priority.PrioritySource = PrioritySourceManual
+    }
+    return priority, nil
+}
+
+func (s postgresStore) SetThreadPriority(ctx context.Context, channelID, threadTS string, priority *string, actor string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    if priority == nil {
+        _, err = db.ExecContext(ctx, "DELETE FROM thread_priorities WHERE channel_id = $1 AND thread_ts = $2", channelID, threadTS)
+        return err
+    }
+    _, err = db.ExecContext(ctx, upsertManualPriority, channelID, threadTS, *priority, actor)
+    return err
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestSetAndClearThreadPriority(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, LatestReply: time.Now()}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    user := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    route := "/api/threads/:channel_id/:thread_ts/priority"
+    rec := serveAs(c, user, http.MethodPut, route, "/api/threads/C1/1700000000.000100/priority", `{"priority": "high"}`, c.SetThreadPriority)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var priority ThreadPriority
+    decodeResponse(t, rec, &priority)
+    if priority.EffectivePriority != "high" || priority.PrioritySource != PrioritySourceManual || priority.SetBy == nil || *priority.SetBy != "U1" {
+        t.Fatalf("got priority %+v", priority)
+    }
+
+    rec = serveAs(c, user, http.MethodPut, route, "/api/threads/C1/1700000000.000100/priority", `{"priority": null}`, c.SetThreadPriority)
+    decodeResponse(t, rec, &priority)
+    if priority.EffectivePriority != "none" || priority.PrioritySource != PrioritySourceAI {
+        t.Fatalf("got priority %+v after clearing it", priority)
+    }
+    if audited := store.Audited(); len(audited) != 2 || audited[0].Action != "thread.priority" {
+        t.Fatalf("got audit entries %+v", audited)
+    }
+
+    rec = serveAs(c, user, http.MethodPut, route, "/api/threads/C1/1700000000.000200/priority", `{"priority": "low"}`, c.SetThreadPriority)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("missing thread got status %d", rec.Code)
+    }
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+    "net/http"
+    "net/url"
+    "strconv"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+    qrcode "github.com/skip2/go-qrcode"
+)
+
+// Bounds and default of the side of thread QR codes, in pixels.
+const (
+    minQRSize     = 64
+    maxQRSize     = 1024
+    defaultQRSize = 256
+)
+
+// GetThreadQRCode - Get a PNG QR code linking to a thread in Slack or on the dashboard
+func (c *Container) GetThreadQRCode(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    size := defaultQRSize
+    if sizeStr := ctx.QueryParam("size"); sizeStr != "" {
+        parsed, err := strconv.Atoi(sizeStr)
+        if err != nil || parsed < minQRSize || parsed > maxQRSize {
+            return apierror.Newf(http.StatusBadRequest, "size must be between %d and %d", minQRSize, maxQRSize)
+        }
+        size = parsed
+    }
+
+    var link string
+    switch target := ctx.QueryParam("target"); target {
+    case "", "slack":
+        link = slack.Permalink(channelID, threadTS)
+    case "dashboard":
+        if c.publicURL == "" {
+            return apierror.New(http.StatusConflict, "Dashboard links need the public URL of the dashboard to be configured")
+        }
+        link = c.publicURL + "/channels/" + url.PathEscape(channelID) + "/threads?thread=" + url.QueryEscape(threadTS)
+    default:
+        return apierror.New(http.StatusBadRequest, "target must be slack or dashboard")
+    }
+
+    _, err := c.threads.Thread(ctx.Request().Context(), channelID, threadTS)
+    if err == errChannelNotTracked {
+        return apierror.New(http.StatusNotFound, "Channel not found")
+    }
+    if err == errThreadNotFound {
+        return apierror.New(http.StatusNotFound, "Thread not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread")
+    }
+
+    // Medium error correction survives a printout that got a little worn
+    png, err := qrcode.Encode(link, qrcode.Medium, size)
+    if err != nil {
+        c.logger.Errorf("failed to encode QR code of %s: %v", link, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to generate QR code")
+    }
+    ctx.Response().Header().Set("Cache-Control", "private, max-age=86400")
+    return ctx.Blob(http.StatusOK, "image/png", png)
+}
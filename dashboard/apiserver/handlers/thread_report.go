@@ -0,0 +1,307 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/exports"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// reportTimeLayout is how times are written in thread reports.
+const reportTimeLayout = "2006-01-02 15:04 UTC"
+
+// reportTimelineLimit caps the dashboard actions listed in a thread report.
+const reportTimelineLimit = 500
+
+// reportEvent is a message or a dashboard action on the timeline of a
+// thread report.
+type reportEvent struct {
+    at   time.Time
+    who  string
+    text string
+}
+
+// threadResolution is how a thread was resolved, if it was.
+type threadResolution struct {
+    resolvedBy sql.NullString
+    resolvedAt sql.NullTime
+    resolution sql.NullString
+    note       sql.NullString
+}
+
+// describeActionDetails summarizes the details of an audited action, e.g.
+// " (from: open, to: resolved)".
+func describeActionDetails(details string) string {
+    var fields map[string]interface{}
+    if json.Unmarshal([]byte(details), &fields) != nil || len(fields) == 0 {
+        return ""
+    }
+    keys := make([]string, 0, len(fields))
+    for key, value := range fields {
+        if value != nil && value != "" {
+            keys = append(keys, key)
+        }
+    }
+    if len(keys) == 0 {
+        return ""
+    }
+    sort.Strings(keys)
+    parts := make([]string, len(keys))
+    for i, key := range keys {
+        value := fields[key]
+        if _, ok := value.(string); !ok {
+            encoded, _ := json.Marshal(value)
+            value = string(encoded)
+        }
+        parts[i] = fmt.Sprintf("%s: %v", strings.ReplaceAll(key, "_", " "), value)
+    }
+    return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// reportName returns the name of a user for a report, their ID when unknown.
+func reportName(names map[string]string, userID string) string {
+    if name, ok := names[userID]; ok && name != "" {
+        return name
+    }
+    return userID
+}
+
+// reportTime formats a time for a report, a dash when unknown.
+func reportTime(t *time.Time) string {
+    if t == nil || t.IsZero() {
+        return "-"
+    }
+    return t.UTC().Format(reportTimeLayout)
+}
+
+// describePriority tells the priority of a thread and where it comes from.
+func describePriority(thread Thread) string {
+    switch {
+    case thread.PrioritySource == PrioritySourceManual:
+        return string(thread.EffectivePriority) + ", set by hand"
+    case thread.PriorityCalibrated:
+        return string(thread.EffectivePriority) + ", lowered from " + *thread.AIPriority + " by calibration"
+    case thread.AIPriority == nil:
+        return "none"
+    }
+    return string(thread.EffectivePriority) + ", assigned by AI"
+}
+
+// writeThreadReport lays out the summary, resolution, participants and
+// timeline of a thread.
+func writeThreadReport(doc *exports.PDFDocument, detail *ThreadDetail, resolution threadResolution, actions []reportEvent, names map[string]string, now time.Time) {
+    thread := detail.Thread
+
+    title := "Thread in #" + thread.ChannelName
+    if thread.AIThreadName != nil && *thread.AIThreadName != "" {
+        title = *thread.AIThreadName
+    }
+    doc.Title(title)
+    doc.Note("Report generated " + now.UTC().Format(reportTimeLayout) + " from the open threads dashboard. " +
+        "Slack thread: " + slack.Permalink(thread.ChannelID, thread.ThreadTS))
+
+    doc.Heading("Summary")
+    doc.Field("Channel", "#"+thread.ChannelName+" ("+thread.ChannelID+")")
+    doc.Field("Started", reportTime(&thread.CreatedAt)+" by "+reportName(names, thread.UserID))
+    doc.Field("Status", string(thread.Status))
+    doc.Field("Priority", describePriority(thread))
+    doc.Field("Replies", strconv.Itoa(thread.ReplyCount)+", latest "+reportTime(&thread.LatestReply))
+    if thread.Assignee != nil {
+        doc.Field("Assignee", reportName(names, *thread.Assignee))
+    }
+    if thread.DueAt != nil {
+        due := reportTime(thread.DueAt)
+        if thread.SLABreached {
+            due += ", breached"
+        }
+        doc.Field("Due", due)
+    }
+    if detail.GitHub != nil {
+        issue := detail.GitHub.URL
+        if detail.GitHub.Title != "" {
+            issue = detail.GitHub.Title + " (" + detail.GitHub.State + "), " + issue
+        }
+        doc.Field("GitHub issue", issue)
+    }
+    if detail.Jira != nil {
+        ticket := detail.Jira.Key
+        if detail.Jira.Status != "" {
+            ticket += " (" + detail.Jira.Status + ")"
+        }
+        if detail.Jira.URL != "" {
+            ticket += ", " + detail.Jira.URL
+        }
+        doc.Field("Jira ticket", ticket)
+    }
+    if thread.ThreadIssue != nil && *thread.ThreadIssue != "" {
+        doc.Field("Issue", *thread.ThreadIssue)
+    }
+    if thread.AIDescription != nil && *thread.AIDescription != "" {
+        doc.Space(6)
+        doc.Paragraph(*thread.AIDescription)
+    }
+
+    doc.Heading("Resolution")
+    if !resolution.resolvedAt.Valid {
+        doc.Paragraph("The thread is not resolved, its status is " + string(thread.Status) + ".")
+    } else {
+        doc.Field("Resolved", reportTime(&resolution.resolvedAt.Time)+" by "+reportName(names, resolution.resolvedBy.String))
+        if resolution.resolution.Valid {
+            doc.Field("Resolution", strings.ReplaceAll(resolution.resolution.String, "_", " "))
+        }
+        if resolution.note.Valid && resolution.note.String != "" {
+            doc.Field("Note", resolution.note.String)
+        }
+    }
+
+    doc.Heading("Participants")
+    messages := map[string]int{}
+    for _, message := range detail.Messages {
+        messages[message.UserID]++
+    }
+    for _, profile := range detail.Stakeholders {
+        var roles []string
+        if profile.UserID == thread.UserID {
+            roles = append(roles, "author")
+        }
+        if thread.Assignee != nil && profile.UserID == *thread.Assignee {
+            roles = append(roles, "assignee")
+        }
+        if thread.ClaimedBy != nil && profile.UserID == *thread.ClaimedBy {
+            roles = append(roles, "claimed")
+        }
+        if thread.AcknowledgedBy != nil && profile.UserID == *thread.AcknowledgedBy {
+            roles = append(roles, "acknowledged")
+        }
+        if count := messages[profile.UserID]; count > 0 {
+            roles = append(roles, fmt.Sprintf("%d messages", count))
+        }
+        if len(roles) == 0 {
+            roles = append(roles, "stakeholder")
+        }
+        doc.Field(reportName(names, profile.UserID), strings.Join(roles, ", "))
+    }
+
+    doc.Heading("Timeline")
+    events := append([]reportEvent{}, actions...)
+    for i, message := range detail.Messages {
+        text := "(text not stored)"
+        if message.Text != nil {
+            text = *message.Text
+        }
+        verb := "replied: "
+        if i == 0 && message.TS == thread.ThreadTS {
+            verb = "posted: "
+        }
+        events = append(events, reportEvent{at: message.PostedAt, who: message.UserID, text: verb + text})
+    }
+    sort.SliceStable(events, func(i, j int) bool {
+        return events[i].at.Before(events[j].at)
+    })
+    for _, event := range events {
+        doc.Field(event.at.UTC().Format(reportTimeLayout), reportName(names, event.who)+" "+event.text)
+    }
+    if len(events) == 0 {
+        doc.Paragraph("Nothing happened on the thread yet.")
+    }
+}
+
+// GetThreadReport - Get a PDF report of a thread with its summary, resolution, participants and timeline, e.g. for postmortems
+func (c *Container) GetThreadReport(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    detail, err := c.loadThreadDetail(ctx)
+    if err != nil {
+        return err
+    }
+
+    resolution, err := c.threads.Resolution(ctx.Request().Context(), channelID, threadTS)
+    if err != nil {
+        c.logger.Errorf("failed to query resolution of %s/%s: %v", channelID, threadTS, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread resolution")
+    }
+    actions, err := c.auditLog.ThreadActions(ctx.Request().Context(), channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread timeline")
+    }
+
+    userIDs := threadStakeholderIDs(detail.Thread, detail.Messages)
+    if detail.Thread.Assignee != nil {
+        userIDs = append(userIDs, *detail.Thread.Assignee)
+    }
+    if resolution.resolvedBy.Valid {
+        userIDs = append(userIDs, resolution.resolvedBy.String)
+    }
+    for _, action := range actions {
+        userIDs = append(userIDs, action.who)
+    }
+    names, err := c.resolveUserNames(ctx.Request().Context(), userIDs)
+    if err != nil {
+        c.logger.Warnf("failed to resolve names of the participants of %s/%s: %v", channelID, threadTS, err)
+    }
+
+    doc := exports.NewPDFDocument(fmt.Sprintf("Thread %s in #%s", threadTS, detail.Thread.ChannelName))
+    writeThreadReport(doc, detail, resolution, actions, names, time.Now())
+
+    resp := ctx.Response()
+    resp.Header().Set(echo.HeaderContentDisposition,
+        fmt.Sprintf(`attachment; filename="thread-%s-%s.pdf"`, channelID, threadTS))
+    return ctx.Blob(http.StatusOK, "application/pdf", doc.Bytes())
+}
+
+func (s postgresStore) Resolution(ctx context.Context, channelID string, threadTS string) (threadResolution, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return threadResolution{}, err
+    }
+    var resolution threadResolution
+    err = db.QueryRowContext(ctx, `
+        SELECT resolved_by, resolved_at, resolution, resolution_note
+        FROM threads WHERE thread_ts = $1 AND channel_id = $2
+    `, threadTS, channelID).Scan(&resolution.resolvedBy, &resolution.resolvedAt, &resolution.resolution, &resolution.note)
+    if err == sql.ErrNoRows {
+        return threadResolution{}, errThreadNotFound
+    }
+    return resolution, err
+}
+
+func (s postgresStore) ThreadActions(ctx context.Context, channelID string, threadTS string) ([]reportEvent, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := db.QueryContext(ctx, `
+        SELECT actor, action, details, created_at FROM audit_log
+        WHERE channel_id = $1 AND thread_ts = $2
+        ORDER BY created_at, id
+        LIMIT $3
+    `, channelID, threadTS, reportTimelineLimit)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var actions []reportEvent
+    for rows.Next() {
+        var action, details string
+        var event reportEvent
+        if err := rows.Scan(&event.who, &action, &details, &event.at); err != nil {
+            continue
+        }
+        event.text = action + describeActionDetails(details)
+        actions = append(actions, event)
+    }
+    return actions, rows.Err()
+}
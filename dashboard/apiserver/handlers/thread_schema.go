@@ -0,0 +1,146 @@
+package handlers
+
+import (
+    "net/http"
+    "sort"
+
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Types of thread fields.
+const (
+    fieldString    = "string"
+    fieldInteger   = "integer"
+    fieldNumber    = "number"
+    fieldBoolean   = "boolean"
+    fieldTimestamp = "timestamp"
+    fieldEnum      = "enum"
+    // fieldUser holds a Slack user ID, fieldUsers a JSON array of them
+    fieldUser  = "user"
+    fieldUsers = "users"
+)
+
+// ThreadField describes a field of the threads of thread lists.
+type ThreadField struct {
+    // Name is the field in thread lists
+    Name  string `json:"name"`
+    Label string `json:"label"`
+    // Type is string, integer, number, boolean, timestamp, enum, user or
+    // users
+    Type string `json:"type"`
+    // Values are the values of enum fields
+    Values []string `json:"values,omitempty"`
+    // Column is the column of thread tables and channel views showing the
+    // field, empty when none does. Several fields may share a column
+    Column string `json:"column,omitempty"`
+    // Filters are the query parameters of thread lists filtering on the
+    // field
+    Filters []string `json:"filters"`
+    // Sortable is set when thread lists sort by the field
+    Sortable bool `json:"sortable"`
+    // Exportable is set when thread exports have a column of the field
+    Exportable bool `json:"exportable"`
+}
+
+// ThreadSchema describes the fields of threads and what thread tables,
+// exports and channel views can do with them.
+type ThreadSchema struct {
+    Fields []ThreadField `json:"fields"`
+    // ExportFormats are the formats of GET /api/threads/export
+    ExportFormats []string `json:"export_formats"`
+    // DefaultView is the view of channels without one of their own
+    DefaultView ChannelView `json:"default_view"`
+}
+
+// threadFields are the fields of listed threads offered to thread tables,
+// in the order of their columns. Sortable and Exportable are set from
+// threadSortColumns and threadExportColumns.
+var threadFields = []ThreadField{
+    {Name: "ai_thread_name", Label: "Thread", Type: fieldString, Column: "thread"},
+    {Name: "channel_name", Label: "Channel", Type: fieldString, Column: "channel", Filters: []string{"channel", "group"}},
+    {Name: "user_id", Label: "Author", Type: fieldUser, Column: "author", Filters: []string{"user_id"}},
+    {Name: "ai_stakeholders", Label: "Stakeholders", Type: fieldUsers, Column: "stakeholders", Filters: []string{"stakeholder"}},
+    {Name: "priority", Label: "Priority", Type: fieldEnum, Values: priorityValues(), Column: "priority", Filters: []string{"priority"}},
+    {Name: "status", Label: "Status", Type: fieldEnum, Values: statusValues(), Column: "status", Filters: []string{"status"}},
+    {Name: "reply_count", Label: "Replies", Type: fieldInteger, Column: "replies"},
+    {Name: "latest_reply", Label: "Latest reply", Type: fieldTimestamp, Column: "latest_reply"},
+    {Name: "created_at", Label: "Created", Type: fieldTimestamp, Column: "created_at", Filters: []string{"created_after", "created_before"}},
+    {Name: "github_issue", Label: "GitHub issue", Type: fieldString, Column: "issues", Filters: []string{"has_github_issue"}},
+    {Name: "jira_ticket", Label: "Jira ticket", Type: fieldString, Column: "issues", Filters: []string{"has_jira_ticket"}},
+    {Name: "claimed_by", Label: "Claimed by", Type: fieldUser, Column: "claimed_by"},
+    {Name: "assignee", Label: "Assignee", Type: fieldUser, Column: "assignee", Filters: []string{"assignee"}},
+    {Name: "thread_ts", Label: "Thread timestamp", Type: fieldString},
+    {Name: "channel_id", Label: "Channel ID", Type: fieldString, Filters: []string{"channel"}},
+    {Name: "ai_description", Label: "Description", Type: fieldString},
+    {Name: "ai_priority", Label: "AI priority", Type: fieldEnum, Values: priorityValues()},
+    {Name: "ai_confidence", Label: "AI confidence", Type: fieldNumber},
+    {Name: "acknowledged_by", Label: "Acknowledged by", Type: fieldUser},
+    {Name: "due_at", Label: "Due", Type: fieldTimestamp},
+    {Name: "sla_breached", Label: "Overdue", Type: fieldBoolean},
+    {Name: "heat", Label: "Heat", Type: fieldEnum, Values: []string{HeatGreen, HeatYellow, HeatOrange, HeatRed}},
+    {Name: "likely_answered", Label: "Likely answered", Type: fieldBoolean, Filters: []string{"answered"}},
+    {Name: "external", Label: "Slack Connect", Type: fieldBoolean, Filters: []string{"external"}},
+    {Name: "estimate_minutes", Label: "Estimate (minutes)", Type: fieldInteger},
+    {Name: "quality_score", Label: "Quality", Type: fieldNumber},
+}
+
+// priorityValues returns the priorities of threads, PriorityNone included.
+func priorityValues() []string {
+    values := []string{}
+    for _, priority := range domain.Priorities {
+        values = append(values, string(priority))
+    }
+    return append(values, string(domain.PriorityNone))
+}
+
+// statusValues returns the statuses of threads.
+func statusValues() []string {
+    values := []string{}
+    for _, status := range domain.Statuses {
+        values = append(values, string(status))
+    }
+    return values
+}
+
+// threadFieldColumns returns the columns of thread tables, which channel
+// views may show.
+func threadFieldColumns() map[string]bool {
+    columns := map[string]bool{}
+    for _, field := range threadFields {
+        if field.Column != "" {
+            columns[field.Column] = true
+        }
+    }
+    return columns
+}
+
+// GetThreadSchema - Get the fields of threads with their type, the columns showing them and whether thread lists filter and sort by them, to lay out thread tables, exports and channel views
+func (c *Container) GetThreadSchema(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, DescribeThreads())
+}
+
+// DescribeThreads returns the fields of threads and the formats they can
+// be exported in.
+func DescribeThreads() ThreadSchema {
+    exported := map[string]bool{}
+    for _, column := range threadExportColumns {
+        exported[column] = true
+    }
+
+    schema := ThreadSchema{Fields: []ThreadField{}, ExportFormats: []string{}, DefaultView: defaultChannelView()}
+    for _, field := range threadFields {
+        _, field.Sortable = threadSortColumns[field.Name]
+        field.Exportable = exported[field.Name]
+        if field.Filters == nil {
+            field.Filters = []string{}
+        }
+        schema.Fields = append(schema.Fields, field)
+    }
+    for format := range threadExportContentTypes {
+        schema.ExportFormats = append(schema.ExportFormats, format)
+    }
+    sort.Strings(schema.ExportFormats)
+    return schema
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Thread statuses the dashboard may set. The collector creates threads as
+// open, releases park them as statusWaitingRelease and questions to their
+// author as statusWaitingReporter.
+const (
+    statusOpen     = domain.StatusOpen
+    statusResolved = domain.StatusResolved
+    statusSnoozed  = domain.StatusSnoozed
+    statusClosed   = domain.StatusClosed
+)
+
+// ThreadStatusRequest is the body accepted by UpdateThreadStatus.
+// Resolution is required to resolve or close a thread, Reason optionally
+// explains it. Snoozing takes one of hours, days or until, like
+// SnoozeThread.
+type ThreadStatusRequest struct {
+    Status domain.Status `json:"status"`
+    // Resolution is one of validResolutions
+    Resolution string `json:"resolution"`
+    Reason     string `json:"reason"`
+    SnoozeRequest
+}
+
+// StatusChange is a change of the status of a thread by Actor. Resolved and
+// closed threads keep Resolution and Reason, and who first resolved them.
+type StatusChange struct {
+    ChannelID  string
+    ThreadTS   string
+    Status     domain.Status
+    Actor      string
+    Resolution string
+    Reason     string
+}
+
+// finished tells whether the change resolves or closes the thread.
+func (change StatusChange) finished() bool {
+    return change.Status == statusResolved || change.Status == statusClosed
+}
+
+// StatusResult is a thread after a change of its status.
+type StatusResult struct {
+    Previous   domain.Status
+    ResolvedBy *string
+    ResolvedAt *time.Time
+    Resolution *string
+}
+
+// UpdateThreadStatus - Mark a thread as resolved, snoozed, closed or open again
+func (c *Container) UpdateThreadStatus(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    var req ThreadStatusRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if !req.Status.Settable() {
+        return apierror.New(http.StatusBadRequest, "status must be open, resolved, snoozed or closed")
+    }
+    if req.Resolution != "" && !validResolutions[req.Resolution] {
+        return apierror.New(http.StatusBadRequest, errInvalidResolution)
+    }
+    // A snooze without an end would never open the thread again
+    if req.Status == statusSnoozed {
+        return c.snooze(ctx, channelID, threadTS, req.SnoozeRequest)
+    }
+
+    change := StatusChange{
+        ChannelID:  channelID,
+        ThreadTS:   threadTS,
+        Status:     req.Status,
+        Actor:      actorFrom(ctx),
+        Resolution: req.Resolution,
+        Reason:     req.Reason,
+    }
+    result, err := c.threadStatus.SetStatus(ctx.Request().Context(), change, func(previous domain.Status, needsApproval bool) error {
+        reopened := previous != statusResolved && previous != statusClosed
+        if change.finished() && needsApproval && reopened {
+            return apierror.New(http.StatusConflict, "Resolving this thread needs approval, request it with POST /api/threads/:channel_id/:thread_ts/resolve")
+        }
+        if change.finished() && req.Resolution == "" && reopened {
+            return apierror.New(http.StatusBadRequest, errInvalidResolution)
+        }
+        return nil
+    })
+    var apiErr *apierror.Error
+    if errors.As(err, &apiErr) {
+        return apiErr
+    }
+    if err == errChannelNotTracked || err == errThreadNotFound {
+        return threadLookupError(ctx, err)
+    }
+    if err != nil {
+        c.logger.Errorf("failed to update status of thread %s/%s: %v", channelID, threadTS, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to update thread")
+    }
+
+    if result.Previous != req.Status {
+        details := map[string]interface{}{
+            "from": result.Previous,
+            "to":   req.Status,
+        }
+        if change.finished() {
+            details["resolution"] = ""
+            if result.Resolution != nil {
+                details["resolution"] = *result.Resolution
+            }
+        }
+        c.audit(ctx.Request().Context(), change.Actor, "thread.status", channelID, threadTS, details)
+    }
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "channel_id":  channelID,
+        "thread_ts":   threadTS,
+        "status":      req.Status,
+        "resolved_by": result.ResolvedBy,
+        "resolved_at": result.ResolvedAt,
+        "resolution":  result.Resolution,
+    })
+}
+
+func (s postgresStore) SetStatus(ctx context.Context, change StatusChange, allow func(previous domain.Status, needsApproval bool) error) (StatusResult, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return StatusResult{}, err
+    }
+    if err := channelTracked(db, change.ChannelID); err != nil {
+        return StatusResult{}, err
+    }
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return StatusResult{}, err
+    }
+    defer tx.Rollback()
+
+    // The thread is locked until the update commits, so its status cannot
+    // change between the approval check and the update
+    previous, needsApproval, err := resolutionPolicy(tx, change.ChannelID, change.ThreadTS)
+    if err != nil {
+        return StatusResult{}, err
+    }
+    if err := allow(previous, needsApproval); err != nil {
+        return StatusResult{}, err
+    }
+
+    var resolvedBy interface{}
+    if change.finished() {
+        resolvedBy = change.Actor
+    }
+    // resolved_at is kept when switching between resolved and closed, and
+    // so is the resolution unless a new one is given. A snooze set before
+    // ends with any change of status.
+    var updatedBy, resolution sql.NullString
+    var resolvedAt sql.NullTime
+    err = tx.QueryRowContext(ctx, `
+        UPDATE threads
+        SET status = $1,
+            resolved_by = CASE WHEN $3 THEN COALESCE(resolved_by, $2) END,
+            resolved_at = CASE WHEN $3 THEN COALESCE(resolved_at, NOW()) END,
+            resolution = CASE WHEN $3 THEN COALESCE(NULLIF($6, ''), resolution) END,
+            resolution_note = CASE WHEN $3 THEN COALESCE(NULLIF($7, ''), resolution_note) END,
+            snoozed_until = NULL,
+            updated_at = NOW()
+        WHERE thread_ts = $4 AND channel_id = $5
+        RETURNING resolved_by, resolved_at, resolution
+    `, change.Status, resolvedBy, change.finished(), change.ThreadTS, change.ChannelID, change.Resolution, change.Reason).Scan(&updatedBy, &resolvedAt, &resolution)
+    if err == sql.ErrNoRows {
+        return StatusResult{}, errThreadNotFound
+    }
+    if err == nil {
+        err = tx.Commit()
+    }
+    if err != nil {
+        return StatusResult{}, err
+    }
+
+    // A thread taken off a release no longer waits for it
+    if previous == statusWaitingRelease && change.Status != statusWaitingRelease {
+        db.Exec("DELETE FROM thread_releases WHERE channel_id = $1 AND thread_ts = $2 AND released_at IS NULL", change.ChannelID, change.ThreadTS)
+    }
+    if previous == statusWaitingReporter {
+        endReporterWait(ctx, db, change.ChannelID, change.ThreadTS)
+    }
+    if change.finished() && change.Resolution != "" {
+        labelAnswerSignals(db, change.ChannelID, change.ThreadTS, change.Resolution)
+    }
+
+    result := StatusResult{Previous: previous}
+    if updatedBy.Valid {
+        result.ResolvedBy = &updatedBy.String
+    }
+    if resolvedAt.Valid {
+        result.ResolvedAt = &resolvedAt.Time
+    }
+    if resolution.Valid {
+        result.Resolution = &resolution.String
+    }
+    return result, nil
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestUpdateThreadStatus(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, CreatedAt: time.Now()}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    agent := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+    update := func(target string, body string) *httptest.ResponseRecorder {
+        return serveAs(c, agent, http.MethodPatch, "/api/threads/:channel_id/:thread_ts/status", target, body, c.UpdateThreadStatus)
+    }
+
+    tests := []struct {
+        name   string
+        target string
+        body   string
+        want   int
+    }{
+        {"unknown status", "/api/threads/C1/1700000000.000100/status", `{"status": "gone"}`, http.StatusBadRequest},
+        {"resolved without a resolution", "/api/threads/C1/1700000000.000100/status", `{"status": "resolved"}`, http.StatusBadRequest},
+        {"untracked channel", "/api/threads/C2/1700000000.000100/status", `{"status": "closed", "resolution": "answered"}`, http.StatusNotFound},
+        {"missing thread", "/api/threads/C1/1700000000.000200/status", `{"status": "closed", "resolution": "answered"}`, http.StatusNotFound},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if rec := update(tt.target, tt.body); rec.Code != tt.want {
+                t.Fatalf("got status %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+            }
+        })
+    }
+
+    store.FailNext(errors.New("connection reset"))
+    if rec := update("/api/threads/C1/1700000000.000100/status", `{"status": "resolved", "resolution": "answered"}`); rec.Code != http.StatusInternalServerError {
+        t.Fatalf("failed update got status %d", rec.Code)
+    }
+
+    rec := update("/api/threads/C1/1700000000.000100/status", `{"status": "resolved", "resolution": "answered"}`)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var resolved struct {
+        Status     domain.Status `json:"status"`
+        ResolvedBy *string       `json:"resolved_by"`
+        Resolution *string       `json:"resolution"`
+    }
+    decodeResponse(t, rec, &resolved)
+    if resolved.Status != statusResolved || resolved.ResolvedBy == nil || *resolved.ResolvedBy != "U1" ||
+        resolved.Resolution == nil || *resolved.Resolution != resolutionAnswered {
+        t.Fatalf("got %+v, want the thread resolved by U1 as answered", resolved)
+    }
+
+    // Closing a resolved thread keeps its resolution, opening it again
+    // drops it
+    if rec := update("/api/threads/C1/1700000000.000100/status", `{"status": "closed"}`); rec.Code != http.StatusOK {
+        t.Fatalf("closing got status %d: %s", rec.Code, rec.Body.String())
+    }
+    if rec := update("/api/threads/C1/1700000000.000100/status", `{"status": "open"}`); rec.Code != http.StatusOK {
+        t.Fatalf("opening got status %d: %s", rec.Code, rec.Body.String())
+    }
+    thread, err := store.Thread(context.Background(), "C1", "1700000000.000100")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if thread.Status != statusOpen {
+        t.Fatalf("thread is %s, want open", thread.Status)
+    }
+
+    var changes []string
+    for _, entry := range store.Audited() {
+        changes = append(changes, fmt.Sprintf("%s %v>%v %v", entry.Action, entry.Details["from"], entry.Details["to"], entry.Details["resolution"]))
+    }
+    want := []string{
+        "thread.status open>resolved answered",
+        "thread.status resolved>closed answered",
+        "thread.status closed>open <nil>",
+    }
+    if fmt.Sprint(changes) != fmt.Sprint(want) {
+        t.Fatalf("audited %q, want %q", changes, want)
+    }
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/webhooks"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+// Events only sent to thread webhooks, besides the audited actions on the
+// thread.
+const (
+    // threadEventMessage is a new reply in the thread
+    threadEventMessage = "thread.message"
+    // threadEventAnswered is the thread starting to look answered
+    threadEventAnswered = "thread.answered"
+)
+
+// maxThreadWebhooks caps the webhooks registered on a single thread.
+const maxThreadWebhooks = 10
+
+const threadWebhookColumns = "id, channel_id, thread_ts, url, events, payload_template, headers, created_by, created_at"
+
+// ThreadWebhook is a webhook receiving the events of a single thread. It is
+// removed once the thread is resolved or closed.
+type ThreadWebhook struct {
+    webhooks.Webhook
+    ChannelID string `json:"channel_id"`
+    ThreadTS  string `json:"thread_ts"`
+}
+
+func scanThreadWebhook(row interface{ Scan(...interface{}) error }) (ThreadWebhook, error) {
+    var hook ThreadWebhook
+    var events, headers string
+    err := row.Scan(&hook.ID, &hook.ChannelID, &hook.ThreadTS, &hook.URL, &events, &hook.Template, &headers, &hook.CreatedBy, &hook.CreatedAt)
+    if err != nil {
+        return hook, err
+    }
+    json.Unmarshal([]byte(events), &hook.Events)
+    json.Unmarshal([]byte(headers), &hook.Headers)
+    if hook.Events == nil {
+        hook.Events = []string{}
+    }
+    if hook.Headers == nil {
+        hook.Headers = map[string]string{}
+    }
+    hook.Name = threadWebhookName(hook.ThreadTS)
+    hook.Enabled = true
+    return hook, nil
+}
+
+// threadWebhookName names thread webhooks after their thread.
+func threadWebhookName(threadTS string) string {
+    return "thread " + threadTS
+}
+
+// redacted returns the webhook as shown by the API. Thread webhooks are
+// managed by every triager, header values stay with the bot that set them.
+func (hook ThreadWebhook) redacted() ThreadWebhook {
+    headers := make(map[string]string, len(hook.Headers))
+    for name := range hook.Headers {
+        headers[name] = "redacted"
+    }
+    hook.Headers = headers
+    return hook
+}
+
+// finishesThread reports whether event resolves or closes its thread.
+func finishesThread(event webhooks.Event) bool {
+    if event.Action == "thread.resolve" || event.Action == "thread.resolve_"+ResolutionApproved {
+        return true
+    }
+    if event.Action == "thread.status" {
+        // Statuses are strings once events went through a broker
+        to := domain.Status(fmt.Sprint(event.Details["to"]))
+        return to == statusResolved || to == statusClosed
+    }
+    return false
+}
+
+// publishThreadEvent queues event for the webhooks of its thread, removing
+// them when the event finishes the thread.
+func (c *Container) publishThreadEvent(ctx context.Context, event webhooks.Event) {
+    if event.ThreadTS == "" {
+        return
+    }
+    hooks, err := c.webhookStore.ThreadWebhooks(ctx, event.ChannelID, event.ThreadTS)
+    if err != nil {
+        c.logger.Warnf("failed to load thread webhooks for %s event: %v", event.Action, err)
+        return
+    }
+    for _, hook := range hooks {
+        if hook.Wants(event.Action) {
+            c.webhooks.Enqueue(hook.Webhook, event)
+        }
+    }
+
+    if finishesThread(event) {
+        if err := c.webhookStore.ExpireThreadWebhooks(ctx, event.ChannelID, event.ThreadTS); err != nil {
+            c.logger.Warnf("failed to expire webhooks of thread %s in channel %s: %v", event.ThreadTS, event.ChannelID, err)
+        }
+    }
+}
+
+// PruneThreadWebhooks removes the webhooks of threads finished outside of
+// the dashboard, e.g. by the collector, or no longer tracked.
+func (c *Container) PruneThreadWebhooks(ctx context.Context) {
+    channels, err := c.channels.Channels(ctx)
+    if err != nil {
+        c.logger.Warnf("failed to prune thread webhooks: %v", err)
+        return
+    }
+    tracked := make([]string, 0, len(channels))
+    for _, ch := range channels {
+        tracked = append(tracked, ch.ChannelID)
+    }
+    if err := c.webhookStore.PruneThreadWebhooks(ctx, tracked); err != nil {
+        c.logger.Warnf("failed to prune thread webhooks: %v", err)
+    }
+}
+
+// GetThreadWebhooks - List the webhooks registered on a thread
+func (c *Container) GetThreadWebhooks(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    hooks, err := c.webhookStore.ThreadWebhooks(ctx.Request().Context(), channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread webhooks")
+    }
+    for i := range hooks {
+        hooks[i] = hooks[i].redacted()
+    }
+
+    return ctx.JSON(http.StatusOK, hooks)
+}
+
+// CreateThreadWebhook - Register a webhook receiving the events of an open thread until it is resolved
+func (c *Container) CreateThreadWebhook(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    hook := webhooks.Webhook{Enabled: true}
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&hook); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if hook.Events == nil {
+        hook.Events = []string{}
+    }
+    if hook.Headers == nil {
+        hook.Headers = map[string]string{}
+    }
+    hook.Name = threadWebhookName(threadTS)
+    if err := hook.Validate(); err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+
+    thread, err := c.threads.Thread(ctx.Request().Context(), channelID, threadTS)
+    if err != nil {
+        return threadLookupError(ctx, err)
+    }
+    if thread.Status == statusResolved || thread.Status == statusClosed {
+        return apierror.New(http.StatusConflict, "Thread is already "+string(thread.Status))
+    }
+
+    existing, err := c.webhookStore.ThreadWebhooks(ctx.Request().Context(), channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query thread webhooks")
+    }
+    if len(existing) >= maxThreadWebhooks {
+        return apierror.Newf(http.StatusConflict, "A thread can have at most %d webhooks", maxThreadWebhooks)
+    }
+
+    actor := actorFrom(ctx)
+    hook.CreatedBy = actor
+    created, err := c.webhookStore.CreateThreadWebhook(ctx.Request().Context(), ThreadWebhook{Webhook: hook, ChannelID: channelID, ThreadTS: threadTS})
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to create thread webhook")
+    }
+
+    // Header values often carry credentials and stay out of the audit log
+    c.audit(ctx.Request().Context(), actor, "thread.webhook_create", channelID, threadTS, map[string]interface{}{
+        "webhook_id": created.ID,
+        "url":        created.URL,
+        "events":     created.Events,
+    })
+
+    return ctx.JSON(http.StatusCreated, created.redacted())
+}
+
+// DeleteThreadWebhook - Remove a webhook from a thread before it is resolved
+func (c *Container) DeleteThreadWebhook(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid webhook id")
+    }
+
+    err = c.webhookStore.DeleteThreadWebhook(ctx.Request().Context(), channelID, threadTS, id)
+    if err == errWebhookNotFound {
+        return apierror.New(http.StatusNotFound, "Webhook not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to delete thread webhook")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "thread.webhook_delete", channelID, threadTS, map[string]interface{}{
+        "webhook_id": id,
+    })
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// threadMessageEvent is the event of a new reply in a thread.
+func threadMessageEvent(channelID, threadTS, user, ts string, postedAt time.Time) webhooks.Event {
+    return webhooks.Event{
+        Action:     threadEventMessage,
+        Actor:      user,
+        ChannelID:  channelID,
+        ThreadTS:   threadTS,
+        Details:    map[string]interface{}{"message_ts": ts},
+        OccurredAt: postedAt.UTC(),
+    }
+}
+
+func (s postgresStore) ThreadWebhooks(ctx context.Context, channelID string, threadTS string) ([]ThreadWebhook, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, "SELECT "+threadWebhookColumns+" FROM thread_webhooks WHERE channel_id = $1 AND thread_ts = $2 ORDER BY id",
+        channelID, threadTS)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    hooks := []ThreadWebhook{}
+    for rows.Next() {
+        hook, err := scanThreadWebhook(rows)
+        if err != nil {
+            continue
+        }
+        hooks = append(hooks, hook)
+    }
+    return hooks, rows.Err()
+}
+
+func (s postgresStore) CreateThreadWebhook(ctx context.Context, hook ThreadWebhook) (ThreadWebhook, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return ThreadWebhook{}, err
+    }
+
+    events, _ := json.Marshal(hook.Events)
+    headers, _ := json.Marshal(hook.Headers)
+    return scanThreadWebhook(db.QueryRowContext(ctx, `
+        INSERT INTO thread_webhooks (channel_id, thread_ts, url, events, payload_template, headers, created_by, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+        RETURNING `+threadWebhookColumns,
+        hook.ChannelID, hook.ThreadTS, hook.URL, string(events), hook.Template, string(headers), hook.CreatedBy))
+}
+
+func (s postgresStore) DeleteThreadWebhook(ctx context.Context, channelID string, threadTS string, id int64) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    result, err := db.ExecContext(ctx, "DELETE FROM thread_webhooks WHERE id = $1 AND channel_id = $2 AND thread_ts = $3", id, channelID, threadTS)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return errWebhookNotFound
+    }
+    return nil
+}
+
+func (s postgresStore) ExpireThreadWebhooks(ctx context.Context, channelID string, threadTS string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, "DELETE FROM thread_webhooks WHERE channel_id = $1 AND thread_ts = $2", channelID, threadTS)
+    return err
+}
+
+func (s postgresStore) PruneThreadWebhooks(ctx context.Context, tracked []string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    for _, channelID := range tracked {
+        _, err := db.ExecContext(ctx, `
+            DELETE FROM thread_webhooks w
+            WHERE w.channel_id = $1 AND NOT EXISTS (
+                SELECT 1 FROM threads t
+                WHERE t.channel_id = w.channel_id AND t.thread_ts = w.thread_ts AND t.status NOT IN ($2, $3))
+        `, channelID, statusResolved, statusClosed)
+        if err != nil {
+            s.c.logger.Warnf("failed to prune webhooks of channel %s: %v", channelID, err)
+        }
+    }
+    _, err = db.ExecContext(ctx, "DELETE FROM thread_webhooks WHERE NOT (channel_id = ANY($1))", pq.Array(tracked))
+    return err
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+// maxBatchThreads bounds the threads of a batch get.
+const maxBatchThreads = 200
+
+// ThreadRef identifies a thread.
+type ThreadRef struct {
+    ChannelID string `json:"channel_id"`
+    ThreadTS  string `json:"thread_ts"`
+}
+
+// BatchGetThreadsRequest is the body of BatchGetThreads.
+type BatchGetThreadsRequest struct {
+    Threads []ThreadRef `json:"threads"`
+}
+
+// BatchGetThreadsResponse holds the threads found, in the order they were
+// asked for, and the references matching no thread.
+type BatchGetThreadsResponse struct {
+    Items    []Thread    `json:"items"`
+    NotFound []ThreadRef `json:"not_found"`
+}
+
+// BatchGetThreads - Get threads by their channel ID and thread ts in one request
+func (c *Container) BatchGetThreads(ctx echo.Context) error {
+    var req BatchGetThreadsRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if len(req.Threads) == 0 {
+        return apierror.New(http.StatusBadRequest, "threads is required")
+    }
+    if len(req.Threads) > maxBatchThreads {
+        return apierror.Newf(http.StatusBadRequest, "at most %d threads may be requested at once", maxBatchThreads)
+    }
+
+    for _, ref := range req.Threads {
+        if ref.ChannelID == "" || ref.ThreadTS == "" {
+            return apierror.New(http.StatusBadRequest, "every thread needs a channel_id and a thread_ts")
+        }
+    }
+    found, err := c.threadLists.ThreadsByRef(ctx.Request().Context(), req.Threads)
+    if err != nil {
+        c.logger.Errorf("failed to query threads: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to query threads")
+    }
+
+    resp := BatchGetThreadsResponse{Items: []Thread{}, NotFound: []ThreadRef{}}
+    seen := map[ThreadRef]bool{}
+    for _, ref := range req.Threads {
+        if seen[ref] {
+            continue
+        }
+        seen[ref] = true
+        if thread, ok := found[ref]; ok {
+            resp.Items = append(resp.Items, thread)
+        } else {
+            resp.NotFound = append(resp.NotFound, ref)
+        }
+    }
+    return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s postgresStore) ThreadsByRef(ctx context.Context, refs []ThreadRef) (map[ThreadRef]Thread, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    tsByChannel := map[string][]string{}
+    for _, ref := range refs {
+        tsByChannel[ref.ChannelID] = append(tsByChannel[ref.ChannelID], ref.ThreadTS)
+    }
+    channelIDs := make([]string, 0, len(tsByChannel))
+    for channelID := range tsByChannel {
+        channelIDs = append(channelIDs, channelID)
+    }
+
+    holds, err := loadActiveHolds(db)
+    if err != nil {
+        return nil, err
+    }
+
+    channelRows, err := db.QueryContext(ctx, `
+        SELECT ch.channel_id, ch.channel_name, COALESCE(cc.text_indexing, TRUE),
+               cc.heat_thresholds, cc.priority_calibration, cc.sla_hours
+        FROM channels ch
+        LEFT JOIN channel_config cc ON cc.channel_id = ch.channel_id
+        WHERE ch.channel_id = ANY($1)`, pq.Array(channelIDs))
+    if err != nil {
+        return nil, err
+    }
+    defer channelRows.Close()
+
+    // The threads of every channel are read with one query, like thread
+    // lists read them
+    channels := make(map[string]threadListChannel)
+    refChannels, refThreads, minConfidences := []string{}, []string{}, []float64{}
+    for channelRows.Next() {
+        var channelID, channelName string
+        var textIndexing bool
+        var storedThresholds, storedCalibration, storedSLA sql.NullString
+        if err := channelRows.Scan(&channelID, &channelName, &textIndexing, &storedThresholds, &storedCalibration, &storedSLA); err != nil {
+            continue
+        }
+
+        channels[channelID] = threadListChannel{
+            name:         channelName,
+            textIndexing: textIndexing,
+            thresholds:   parseHeatThresholds(storedThresholds),
+            slaHours:     parseSLAHours(storedSLA),
+        }
+        minConfidence := parsePriorityCalibration(storedCalibration).MinConfidence
+        for _, threadTS := range tsByChannel[channelID] {
+            refChannels = append(refChannels, channelID)
+            refThreads = append(refThreads, threadTS)
+            minConfidences = append(minConfidences, minConfidence)
+        }
+    }
+    channelRows.Close()
+
+    found := map[ThreadRef]Thread{}
+    if len(refChannels) == 0 {
+        return found, nil
+    }
+    threadRows, err := db.QueryContext(ctx, fmt.Sprintf(`
+        SELECT %s,
+               %s
+        FROM threads t
+        JOIN unnest($1::TEXT[], $2::TEXT[], $3::FLOAT8[]) AS ref(channel_id, thread_ts, min_confidence)
+          ON ref.channel_id = t.channel_id AND ref.thread_ts = t.thread_ts`,
+        threadListColumns, priorityColumn("ref.min_confidence")),
+        pq.Array(refChannels), pq.Array(refThreads), pq.Array(minConfidences))
+    if err != nil {
+        return nil, err
+    }
+    defer threadRows.Close()
+
+    now := time.Now()
+    for threadRows.Next() {
+        var thread Thread
+        var dueOverride sql.NullTime
+        if err := threadRows.Scan(threadListDest(&thread, &dueOverride)...); err != nil {
+            continue
+        }
+        channels[thread.ChannelID].present(&thread, holds, dueOverride, now)
+        found[ThreadRef{ChannelID: thread.ChannelID, ThreadTS: thread.ThreadTS}] = thread
+    }
+    return found, threadRows.Err()
+}
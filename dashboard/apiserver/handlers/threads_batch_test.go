@@ -0,0 +1,42 @@
+package handlers
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestBatchGetThreadsKeepsOrder(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    for _, ts := range []string{"1700000000.000100", "1700000000.000200"} {
+        store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: ts, Status: statusOpen, LatestReply: time.Now()}})
+    }
+    c := newTestContainer(t, store, &MemorySlack{})
+    user := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeRead}, Method: auth.MethodSession}
+
+    body := `{"threads": [
+        {"channel_id": "C1", "thread_ts": "1700000000.000200"},
+        {"channel_id": "C2", "thread_ts": "1700000000.000100"},
+        {"channel_id": "C1", "thread_ts": "1700000000.000100"},
+        {"channel_id": "C1", "thread_ts": "1700000000.000200"}
+    ]}`
+    rec := serveAs(c, user, http.MethodPost, "/api/threads/batch", "/api/threads/batch", body, c.BatchGetThreads)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var resp BatchGetThreadsResponse
+    decodeResponse(t, rec, &resp)
+    if len(resp.Items) != 2 || resp.Items[0].ThreadTS != "1700000000.000200" || resp.Items[1].ThreadTS != "1700000000.000100" ||
+        len(resp.NotFound) != 1 || resp.NotFound[0].ChannelID != "C2" {
+        t.Fatalf("got %+v", resp)
+    }
+
+    rec = serveAs(c, user, http.MethodPost, "/api/threads/batch", "/api/threads/batch", `{"threads": [{"channel_id": "C1"}]}`, c.BatchGetThreads)
+    if rec.Code != http.StatusBadRequest {
+        t.Fatalf("a reference without a thread ts got status %d", rec.Code)
+    }
+}
@@ -0,0 +1,430 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+// maxBulkThreads bounds the threads of a bulk operation.
+const maxBulkThreads = 200
+
+// Actions of a bulk operation.
+const (
+    bulkResolve     = "resolve"
+    bulkSnooze      = "snooze"
+    bulkSetPriority = "set_priority"
+    bulkAssign      = "assign"
+)
+
+// BulkThreadsRequest is the body accepted by BulkUpdateThreads. Resolution
+// is required to resolve, with an optional Reason, Until to snooze,
+// Priority to set the priority (high, medium, low or none), and Assignee to
+// assign, empty to unassign.
+type BulkThreadsRequest struct {
+    Threads  []ThreadRef `json:"threads"`
+    Action   string      `json:"action"`
+    Until    *time.Time  `json:"until"`
+    Priority string      `json:"priority"`
+    Assignee string      `json:"assignee"`
+    // Resolution and Reason are used by the resolve action
+    Resolution string `json:"resolution"`
+    Reason     string `json:"reason"`
+}
+
+// BulkThreadsResponse lists the threads changed by a bulk operation, and
+// those it left as they were, e.g. threads already resolved.
+type BulkThreadsResponse struct {
+    Action    string      `json:"action"`
+    Updated   []ThreadRef `json:"updated"`
+    Unchanged []ThreadRef `json:"unchanged"`
+}
+
+// bulkUpdate is a bulk operation of actor on threads, see
+// BulkThreadsRequest.
+type bulkUpdate struct {
+    action     string
+    actor      string
+    refs       []ThreadRef
+    until      time.Time
+    priority   string
+    assignee   string
+    resolution string
+    reason     string
+}
+
+// bulkChange is a thread changed by a bulk operation with the status it
+// had, and its priority or assignee when those were set.
+type bulkChange struct {
+    ThreadRef
+    status   domain.Status
+    previous string
+}
+
+// bulkThread is a thread of a bulk operation, as it was before it.
+type bulkThread struct {
+    ThreadRef
+    status        domain.Status
+    priority      string
+    needsApproval bool
+}
+
+// BulkUpdateThreads - Resolve, snooze, set the priority of or assign many threads at once, all or none of them
+func (c *Container) BulkUpdateThreads(ctx echo.Context) error {
+    var req BulkThreadsRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if len(req.Threads) == 0 {
+        return apierror.New(http.StatusBadRequest, "threads is required")
+    }
+    if len(req.Threads) > maxBulkThreads {
+        return apierror.Newf(http.StatusBadRequest, "at most %d threads may be updated at once", maxBulkThreads)
+    }
+
+    actor := actorFrom(ctx)
+    switch req.Action {
+    case bulkResolve:
+        if !validResolutions[req.Resolution] {
+            return apierror.New(http.StatusBadRequest, errInvalidResolution)
+        }
+    case bulkSnooze:
+        if req.Until == nil || !req.Until.After(time.Now()) {
+            return apierror.New(http.StatusBadRequest, "until must be a time in the future")
+        }
+    case bulkSetPriority:
+        if _, err := domain.ParsePriority(req.Priority); err != nil {
+            return apierror.New(http.StatusBadRequest, "priority must be high, medium, low or none")
+        }
+    case bulkAssign:
+        req.Assignee = strings.TrimSpace(req.Assignee)
+        if req.Assignee == assigneeMe {
+            req.Assignee = actor
+            if req.Assignee == "anonymous" {
+                return apierror.New(http.StatusUnauthorized, "Assigning threads to yourself requires a signed in user")
+            }
+        }
+    default:
+        return apierror.New(http.StatusBadRequest, "action must be resolve, snooze, set_priority or assign")
+    }
+
+    reqCtx := ctx.Request().Context()
+    var err error
+    if req.Action == bulkAssign {
+        if req.Assignee, err = c.resolveAssignee(reqCtx, req.Assignee); err == errUsergroupNotFound {
+            return apierror.New(http.StatusBadRequest, "Usergroup not found")
+        } else if err != nil {
+            return apierror.New(http.StatusInternalServerError, "Failed to look up usergroup")
+        }
+    }
+
+    // Channels are looked up before the operation
+    update := bulkUpdate{action: req.Action, actor: actor, priority: req.Priority, assignee: req.Assignee,
+        resolution: req.Resolution, reason: req.Reason}
+    if req.Until != nil {
+        update.until = req.Until.UTC()
+    }
+    tracked := map[string]bool{}
+    seen := map[ThreadRef]bool{}
+    for _, ref := range req.Threads {
+        if ref.ChannelID == "" || ref.ThreadTS == "" {
+            return apierror.New(http.StatusBadRequest, "every thread needs a channel_id and a thread_ts")
+        }
+        if seen[ref] {
+            continue
+        }
+        seen[ref] = true
+        update.refs = append(update.refs, ref)
+        if tracked[ref.ChannelID] {
+            continue
+        }
+        err = c.channels.Tracked(reqCtx, ref.ChannelID)
+        if err == errChannelNotTracked {
+            return apierror.New(http.StatusNotFound, "Channel "+ref.ChannelID+" not found")
+        }
+        if err != nil {
+            return apierror.New(http.StatusInternalServerError, "Failed to look up channel")
+        }
+        tracked[ref.ChannelID] = true
+    }
+
+    changes, unchanged, err := c.bulk.BulkUpdate(reqCtx, update)
+    if notFound, ok := err.(errThreadsNotFound); ok {
+        return apierror.Newf(http.StatusNotFound, "%d threads not found, none was updated", len(notFound)).
+            WithDetails(map[string]interface{}{"not_found": []ThreadRef(notFound)})
+    }
+    if approvals, ok := err.(errNeedsApproval); ok {
+        return apierror.New(http.StatusConflict, "Resolving some of these threads needs approval, request it with POST /api/threads/:channel_id/:thread_ts/resolve").
+            WithDetails(map[string]interface{}{"needs_approval": []ThreadRef(approvals)})
+    }
+    if err != nil {
+        c.logger.Errorf("failed to bulk %s threads: %v", req.Action, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to update threads")
+    }
+
+    resp := BulkThreadsResponse{Action: req.Action, Updated: []ThreadRef{}, Unchanged: unchanged}
+    for _, change := range changes {
+        resp.Updated = append(resp.Updated, change.ThreadRef)
+        c.auditBulkChange(reqCtx, update, change)
+    }
+    c.logger.Infof("%s bulk %s %d threads", actor, req.Action, len(resp.Updated))
+    return ctx.JSON(http.StatusOK, resp)
+}
+
+// auditBulkChange audits a thread changed by a bulk operation, notifying
+// the assignee of a thread assigned to them.
+func (c *Container) auditBulkChange(ctx context.Context, update bulkUpdate, change bulkChange) {
+    switch update.action {
+    case bulkResolve:
+        c.audit(ctx, update.actor, "thread.status", change.ChannelID, change.ThreadTS, map[string]interface{}{
+            "from": change.status,
+            "to":   statusResolved,
+            "bulk": true,
+            // resolution is one of validResolutions
+            "resolution": update.resolution,
+        })
+    case bulkSnooze:
+        c.audit(ctx, update.actor, "thread.status", change.ChannelID, change.ThreadTS, map[string]interface{}{
+            "from":  change.status,
+            "to":    statusSnoozed,
+            "until": update.until,
+            "bulk":  true,
+        })
+    case bulkSetPriority:
+        c.audit(ctx, update.actor, "thread.priority", change.ChannelID, change.ThreadTS, map[string]interface{}{
+            "from": change.previous,
+            "to":   update.priority,
+            "bulk": true,
+        })
+    case bulkAssign:
+        if update.assignee == "" {
+            c.audit(ctx, update.actor, "thread.unassign", change.ChannelID, change.ThreadTS, map[string]interface{}{
+                "previous_assignee": change.previous,
+                "bulk":              true,
+            })
+            return
+        }
+        c.audit(ctx, update.actor, "thread.assign", change.ChannelID, change.ThreadTS, map[string]interface{}{
+            "assignee":          update.assignee,
+            "previous_assignee": change.previous,
+            "bulk":              true,
+        })
+        c.notifyAssignee(change.ChannelID, change.ThreadTS, update.assignee, update.actor)
+    }
+}
+
+// errThreadsNotFound lists the threads of a bulk operation that do not
+// exist.
+type errThreadsNotFound []ThreadRef
+
+func (e errThreadsNotFound) Error() string {
+    return fmt.Sprintf("%d threads not found", len(e))
+}
+
+// errNeedsApproval lists the threads of a bulk resolve that need approval
+// to be resolved.
+type errNeedsApproval []ThreadRef
+
+func (e errNeedsApproval) Error() string {
+    return fmt.Sprintf("%d threads need approval to be resolved", len(e))
+}
+
+func (s postgresStore) BulkUpdate(ctx context.Context, update bulkUpdate) ([]bulkChange, []ThreadRef, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, nil, err
+    }
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer tx.Rollback()
+
+    // Threads are locked until the operation commits, so none changes
+    // status between being checked and updated
+    threads := make([]bulkThread, 0, len(update.refs))
+    var notFound errThreadsNotFound
+    for _, ref := range update.refs {
+        thread := bulkThread{ThreadRef: ref}
+        var linked bool
+        var storedApproval sql.NullString
+        err := tx.QueryRowContext(ctx, `
+            SELECT t.status, COALESCE(`+manualPriorityColumn+`, t.ai_priority, ''), t.github_issue IS NOT NULL OR t.jira_ticket IS NOT NULL,
+                   (SELECT cc.resolve_approval FROM channel_config cc WHERE cc.channel_id = t.channel_id)
+            FROM threads t
+            WHERE t.thread_ts = $1 AND t.channel_id = $2
+            FOR UPDATE
+        `, ref.ThreadTS, ref.ChannelID).Scan(&thread.status, &thread.priority, &linked, &storedApproval)
+        if err == sql.ErrNoRows {
+            notFound = append(notFound, ref)
+            continue
+        }
+        if err != nil {
+            return nil, nil, err
+        }
+        thread.needsApproval = parseResolveApproval(storedApproval).requires(thread.priority, linked)
+        threads = append(threads, thread)
+    }
+    if len(notFound) > 0 {
+        return nil, nil, notFound
+    }
+
+    var changes []bulkChange
+    var unchanged []ThreadRef
+    switch update.action {
+    case bulkResolve:
+        changes, unchanged, err = bulkResolveThreads(ctx, tx, update, threads)
+    case bulkSnooze:
+        changes, unchanged, err = bulkSnoozeThreads(ctx, tx, update, threads)
+    case bulkSetPriority:
+        changes, unchanged, err = bulkPrioritizeThreads(ctx, tx, update, threads)
+    case bulkAssign:
+        changes, unchanged, err = bulkAssignThreads(ctx, tx, update, threads)
+    }
+    if err == nil {
+        err = tx.Commit()
+    }
+    if err != nil {
+        return nil, nil, err
+    }
+
+    if update.action == bulkResolve {
+        for _, change := range changes {
+            labelAnswerSignals(db, change.ChannelID, change.ThreadTS, update.resolution)
+            // A thread taken off a release no longer waits for it
+            if change.status == statusWaitingRelease {
+                db.ExecContext(ctx, "DELETE FROM thread_releases WHERE channel_id = $1 AND thread_ts = $2 AND released_at IS NULL", change.ChannelID, change.ThreadTS)
+            }
+            if change.status == statusWaitingReporter {
+                endReporterWait(context.Background(), db, change.ChannelID, change.ThreadTS)
+            }
+        }
+    }
+    return changes, unchanged, nil
+}
+
+// bulkResolveThreads resolves the threads not resolved or closed yet for
+// a resolution, failing with errNeedsApproval when any of them needs
+// approval.
+func bulkResolveThreads(ctx context.Context, tx *sql.Tx, update bulkUpdate, threads []bulkThread) ([]bulkChange, []ThreadRef, error) {
+    var approvals errNeedsApproval
+    var changes []bulkChange
+    unchanged := []ThreadRef{}
+    for _, thread := range threads {
+        if thread.status == statusResolved || thread.status == statusClosed {
+            unchanged = append(unchanged, thread.ThreadRef)
+            continue
+        }
+        if thread.needsApproval {
+            approvals = append(approvals, thread.ThreadRef)
+            continue
+        }
+        _, err := tx.ExecContext(ctx, `
+            UPDATE threads
+            SET status = $1, resolved_by = COALESCE(resolved_by, $2), resolved_at = COALESCE(resolved_at, NOW()),
+                resolution = $5, resolution_note = NULLIF($6, ''), updated_at = NOW()
+            WHERE thread_ts = $3 AND channel_id = $4
+        `, statusResolved, update.actor, thread.ThreadTS, thread.ChannelID, update.resolution, update.reason)
+        if err != nil {
+            return nil, nil, err
+        }
+        changes = append(changes, bulkChange{ThreadRef: thread.ThreadRef, status: thread.status})
+    }
+    if len(approvals) > 0 {
+        return nil, nil, approvals
+    }
+    return changes, unchanged, nil
+}
+
+// bulkSnoozeThreads snoozes the threads not resolved or closed until a
+// time.
+func bulkSnoozeThreads(ctx context.Context, tx *sql.Tx, update bulkUpdate, threads []bulkThread) ([]bulkChange, []ThreadRef, error) {
+    var changes []bulkChange
+    unchanged := []ThreadRef{}
+    for _, thread := range threads {
+        if thread.status == statusResolved || thread.status == statusClosed {
+            unchanged = append(unchanged, thread.ThreadRef)
+            continue
+        }
+        _, err := tx.ExecContext(ctx, `
+            UPDATE threads SET status = $1, snoozed_until = $2, updated_at = NOW()
+            WHERE thread_ts = $3 AND channel_id = $4
+        `, statusSnoozed, update.until, thread.ThreadTS, thread.ChannelID)
+        if err != nil {
+            return nil, nil, err
+        }
+        changes = append(changes, bulkChange{ThreadRef: thread.ThreadRef, status: thread.status})
+    }
+    return changes, unchanged, nil
+}
+
+// bulkPrioritizeThreads sets the priority of threads by hand, overriding the one
+// the AI assigned.
+func bulkPrioritizeThreads(ctx context.Context, tx *sql.Tx, update bulkUpdate, threads []bulkThread) ([]bulkChange, []ThreadRef, error) {
+    var changes []bulkChange
+    unchanged := []ThreadRef{}
+    for _, thread := range threads {
+        previous := thread.priority
+        if previous == "" {
+            previous = "none"
+        }
+        if previous == update.priority {
+            unchanged = append(unchanged, thread.ThreadRef)
+            continue
+        }
+        _, err := tx.ExecContext(ctx, upsertManualPriority, thread.ChannelID, thread.ThreadTS, update.priority, update.actor)
+        if err != nil {
+            return nil, nil, err
+        }
+        changes = append(changes, bulkChange{ThreadRef: thread.ThreadRef, status: thread.status, previous: previous})
+    }
+    return changes, unchanged, nil
+}
+
+// bulkAssignThreads assigns threads to the assignee of update, or
+// unassigns them when empty.
+func bulkAssignThreads(ctx context.Context, tx *sql.Tx, update bulkUpdate, threads []bulkThread) ([]bulkChange, []ThreadRef, error) {
+    var changes []bulkChange
+    unchanged := []ThreadRef{}
+    for _, thread := range threads {
+        var previous sql.NullString
+        err := tx.QueryRowContext(ctx, `
+            SELECT assignee FROM thread_assignments WHERE channel_id = $1 AND thread_ts = $2
+        `, thread.ChannelID, thread.ThreadTS).Scan(&previous)
+        if err != nil && err != sql.ErrNoRows {
+            return nil, nil, err
+        }
+        if previous.String == update.assignee {
+            unchanged = append(unchanged, thread.ThreadRef)
+            continue
+        }
+
+        if update.assignee == "" {
+            _, err = tx.ExecContext(ctx, "DELETE FROM thread_assignments WHERE channel_id = $1 AND thread_ts = $2",
+                thread.ChannelID, thread.ThreadTS)
+        } else {
+            _, err = tx.ExecContext(ctx, `
+                INSERT INTO thread_assignments (channel_id, thread_ts, assignee, assigned_by, assigned_at)
+                VALUES ($1, $2, $3, $4, NOW())
+                ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+                    assignee = EXCLUDED.assignee,
+                    assigned_by = EXCLUDED.assigned_by,
+                    assigned_at = EXCLUDED.assigned_at
+            `, thread.ChannelID, thread.ThreadTS, update.assignee, update.actor)
+        }
+        if err != nil {
+            return nil, nil, err
+        }
+        changes = append(changes, bulkChange{ThreadRef: thread.ThreadRef, status: thread.status, previous: previous.String})
+    }
+    return changes, unchanged, nil
+}
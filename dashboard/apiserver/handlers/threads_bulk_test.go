@@ -0,0 +1,60 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestBulkUpdateThreadsAllOrNone(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1"}})
+    now := time.Now()
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, LatestReply: now}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: statusResolved, LatestReply: now}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    user := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    // A missing thread fails the whole operation
+    rec := serveAs(c, user, http.MethodPost, "/api/threads/bulk", "/api/threads/bulk", `{"action": "resolve", "resolution": "answered", "threads": [
+        {"channel_id": "C1", "thread_ts": "1700000000.000100"},
+        {"channel_id": "C1", "thread_ts": "1700000000.000300"}
+    ]}`, c.BulkUpdateThreads)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("a missing thread got status %d", rec.Code)
+    }
+    if thread, _ := store.Thread(context.Background(), "C1", "1700000000.000100"); thread.Status != statusOpen {
+        t.Fatalf("got status %s after a failed operation", thread.Status)
+    }
+
+    rec = serveAs(c, user, http.MethodPost, "/api/threads/bulk", "/api/threads/bulk", `{"action": "resolve", "resolution": "answered", "threads": [
+        {"channel_id": "C1", "thread_ts": "1700000000.000100"},
+        {"channel_id": "C1", "thread_ts": "1700000000.000200"}
+    ]}`, c.BulkUpdateThreads)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var resp BulkThreadsResponse
+    decodeResponse(t, rec, &resp)
+    if len(resp.Updated) != 1 || resp.Updated[0].ThreadTS != "1700000000.000100" || len(resp.Unchanged) != 1 {
+        t.Fatalf("got %+v", resp)
+    }
+    if audited := store.Audited(); len(audited) != 1 || audited[0].Action != "thread.status" || audited[0].Details["bulk"] != true {
+        t.Fatalf("got audit entries %+v", audited)
+    }
+
+    rec = serveAs(c, user, http.MethodPost, "/api/threads/bulk", "/api/threads/bulk", `{"action": "set_priority", "priority": "high", "threads": [
+        {"channel_id": "C1", "thread_ts": "1700000000.000100"}
+    ]}`, c.BulkUpdateThreads)
+    decodeResponse(t, rec, &resp)
+    if len(resp.Updated) != 1 {
+        t.Fatalf("got %+v", resp)
+    }
+    if audited := store.Audited(); audited[1].Details["from"] != "none" || audited[1].Details["to"] != "high" {
+        t.Fatalf("got audit entry %+v", audited[1])
+    }
+}
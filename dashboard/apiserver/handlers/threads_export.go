@@ -0,0 +1,189 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/csv"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/exports"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// threadExportColumns are the columns of thread list exports, in order.
+var threadExportColumns = []string{
+    "channel_id", "channel_name", "thread_ts", "user_id", "status", "priority",
+    "reply_count", "created_at", "latest_reply", "ai_thread_name", "ai_description",
+    "github_issue", "jira_ticket", "claimed_by", "assignee", "acknowledged_by", "due_at",
+    "sla_breached", "heat", "link",
+}
+
+// threadExportContentTypes lists the formats of thread list exports.
+var threadExportContentTypes = map[string]string{
+    "csv":  "text/csv",
+    "xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// threadExportFlushRows is how many rows are written between flushes of
+// the response.
+const threadExportFlushRows = 500
+
+// recordWriter writes the rows of an export, as CSV or Excel.
+type recordWriter interface {
+    Write(record []string) error
+}
+
+// threadExportRecord returns a listed thread as a row of an export.
+func threadExportRecord(t Thread) []string {
+    deref := func(s *string) string {
+        if s == nil {
+            return ""
+        }
+        return *s
+    }
+    formatTime := func(t *time.Time) string {
+        if t == nil {
+            return ""
+        }
+        return t.UTC().Format(time.RFC3339)
+    }
+    return []string{
+        t.ChannelID, t.ChannelName, t.ThreadTS, t.UserID, string(t.Status), string(t.Priority),
+        strconv.Itoa(t.ReplyCount), formatTime(&t.CreatedAt), formatTime(&t.LatestReply),
+        deref(t.AIThreadName), deref(t.AIDescription), deref(t.GithubIssue), deref(t.JiraTicket),
+        deref(t.ClaimedBy), deref(t.Assignee), deref(t.AcknowledgedBy), formatTime(t.DueAt),
+        strconv.FormatBool(t.SLABreached), t.Heat, slack.Permalink(t.ChannelID, t.ThreadTS),
+    }
+}
+
+// ExportThreads - Stream the threads matching the filters of GetThreads as CSV or Excel
+func (c *Container) ExportThreads(ctx echo.Context) error {
+    format := ctx.QueryParam("format")
+    if format == "" {
+        format = "csv"
+    }
+    contentType, ok := threadExportContentTypes[format]
+    if !ok {
+        return apierror.New(http.StatusBadRequest, "format must be csv or xlsx")
+    }
+    sort, order, err := parseThreadSort(ctx)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+    filters, err := parseThreadFilters(ctx)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+
+    resp := ctx.Response()
+    var writer recordWriter
+    flush, finish := func() error { return nil }, func() {}
+    defer func() { finish() }()
+    // The response starts with the first thread, so that failing to query
+    // the threads is still answered with an error
+    started := false
+    start := func() error {
+        started = true
+        resp.Header().Set(echo.HeaderContentType, contentType)
+        resp.Header().Set(echo.HeaderContentDisposition,
+            fmt.Sprintf(`attachment; filename="threads-%s.%s"`, time.Now().UTC().Format("2006-01-02"), format))
+        resp.WriteHeader(http.StatusOK)
+
+        if format == "xlsx" {
+            xlsx, err := exports.NewXLSXWriter(resp)
+            if err != nil {
+                return err
+            }
+            finish = func() {
+                if err := xlsx.Close(); err != nil {
+                    c.logger.Errorf("failed to write thread export: %v", err)
+                }
+            }
+            writer = xlsx
+        } else {
+            csvWriter := csv.NewWriter(resp)
+            finish = csvWriter.Flush
+            writer = csvWriter
+            flush = func() error {
+                csvWriter.Flush()
+                return csvWriter.Error()
+            }
+        }
+        return writer.Write(threadExportColumns)
+    }
+
+    written := 0
+    list := threadList{filters: filters, sort: sort, order: order}
+    err = c.threadLists.StreamThreads(ctx.Request().Context(), list, func(thread Thread) error {
+        if !started {
+            if err := start(); err != nil {
+                return err
+            }
+        }
+        if err := writer.Write(threadExportRecord(thread)); err != nil {
+            return err
+        }
+        if written++; written%threadExportFlushRows == 0 {
+            if err := flush(); err != nil {
+                return err
+            }
+            resp.Flush()
+        }
+        return nil
+    })
+    if !started {
+        if err == errGroupNotFound {
+            return apierror.New(http.StatusNotFound, "Channel group not found")
+        }
+        if err != nil {
+            c.logger.Errorf("failed to query threads to export: %v", err)
+            return apierror.New(http.StatusInternalServerError, "Failed to query threads")
+        }
+        err = start()
+    }
+    // The status is sent, failures past this point can only be logged
+    if err != nil {
+        c.logger.Errorf("failed to export threads: %v", err)
+    }
+    return nil
+}
+
+func (s postgresStore) StreamThreads(ctx context.Context, list threadList, fn func(Thread) error) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    q, err := threadListQuery(db, list.filters)
+    if err != nil || len(q.channels) == 0 {
+        return err
+    }
+
+    rows, err := db.QueryContext(ctx, "SELECT u.* FROM "+q.from+fmt.Sprintf(
+        " ORDER BY %[1]s %[2]s NULLS LAST, u.channel_id %[2]s, u.thread_ts %[2]s", threadSortColumns[list.sort], strings.ToUpper(list.order)),
+        q.args...)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    now := time.Now()
+    for rows.Next() {
+        var thread Thread
+        var dueOverride sql.NullTime
+        if err := rows.Scan(threadListDest(&thread, &dueOverride)...); err != nil {
+            continue
+        }
+        q.channels[thread.ChannelID].present(&thread, nil, dueOverride, now)
+        if err := fn(thread); err != nil {
+            return err
+        }
+    }
+    return rows.Err()
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+    "encoding/csv"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+)
+
+func TestExportThreadsAsCSV(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    now := time.Now()
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: statusOpen, LatestReply: now.Add(-time.Hour)}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: statusOpen, LatestReply: now}})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/threads/export", "/api/threads/export?sort=latest_reply&order=desc", c.ExportThreads)
+    if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv" {
+        t.Fatalf("got status %d, content type %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+    }
+    records, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(records) != 3 || records[0][0] != "channel_id" || records[1][2] != "1700000000.000200" || records[1][1] != "support" {
+        t.Fatalf("got records %v", records)
+    }
+
+    rec = serveRequest(t, c, http.MethodGet, "/api/threads/export", "/api/threads/export?group=missing", c.ExportThreads)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("unknown channel group got status %d", rec.Code)
+    }
+}
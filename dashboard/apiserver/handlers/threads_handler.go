@@ -1,6 +1,8 @@
 package handlers
 
 import (
+    "context"
+    "encoding/base64"
     "net/http"
     "strconv"
     "database/sql"
@@ -8,33 +10,23 @@ import (
     "strings"
     "time"
 
-    _ "github.com/lib/pq"
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/lib/pq"
     "github.com/labstack/echo/v4"
 )
 
 // UserProfile represents a user profile from the database
 type UserProfile struct {
-    UserID           string `json:"user_id"`
-    Name             string `json:"name"`
-    DisplayName      string `json:"display_name"`
-    RealName         string `json:"real_name"`
-    ProfileImageURL  string `json:"profile_image_url"`
-    ProfileImage24   string `json:"profile_image_24"`
-    ProfileImage32   string `json:"profile_image_32"`
-    ProfileImage48   string `json:"profile_image_48"`
-    ProfileImage72   string `json:"profile_image_72"`
+    domain.UserProfile
+    Stats *UserStats `json:"stats,omitempty"`
 }
 
 // Thread represents a thread in the database
 type Thread struct {
-    ThreadTS        string     `json:"thread_ts"`
-    ChannelID       string     `json:"channel_id"`
+    domain.Thread
     ChannelName     string     `json:"channel_name"`
-    UserID          string     `json:"user_id"`
-    ReplyCount      int        `json:"reply_count"`
-    LatestReply     time.Time  `json:"latest_reply"`
-    Status          string     `json:"status"`
-    CreatedAt       time.Time  `json:"created_at"`
     AIThreadName    *string    `json:"ai_thread_name"`
     AIDescription   *string    `json:"ai_description"`
     AIStakeholders  string     `json:"ai_stakeholders"`
@@ -43,7 +35,53 @@ type Thread struct {
     GithubIssue     *string    `json:"github_issue"`
     JiraTicket      *string    `json:"jira_ticket"`
     ThreadIssue     *string    `json:"thread_issue"`
-    Priority        string     `json:"priority"`
+    Priority        domain.Priority `json:"priority"`
+    LegalHold       bool       `json:"legal_hold"`
+    ClaimedBy       *string    `json:"claimed_by"`
+    Assignee        *string    `json:"assignee"`
+    Heat            string     `json:"heat"`
+    DueAt           *time.Time `json:"due_at"`
+    SLAOverride     bool       `json:"sla_override"`
+    SLABreached     bool       `json:"sla_breached"`
+    // PriorityCalibrated is set when Priority was lowered from AIPriority
+    // because the AI was not confident enough for the channel
+    PriorityCalibrated bool `json:"priority_calibrated"`
+    // ManualPriority is the priority set by hand in place of the AI's
+    ManualPriority *string `json:"manual_priority"`
+    // EffectivePriority is ManualPriority when set, else the calibrated AI
+    // priority. Priority is the same, kept for older clients
+    EffectivePriority domain.Priority `json:"effective_priority"`
+    // PrioritySource tells whether EffectivePriority was set by hand
+    PrioritySource PrioritySource `json:"priority_source"`
+    // ResolutionPending is set while a resolution waits for approval
+    ResolutionPending bool `json:"resolution_pending"`
+    // WaitingOnRelease is the "owner/repo@tag" the thread is parked on
+    WaitingOnRelease *string `json:"waiting_on_release"`
+    // AcknowledgedBy is whoever first replied to, claimed or acknowledged
+    // the thread
+    AcknowledgedBy *string    `json:"acknowledged_by"`
+    AcknowledgedAt *time.Time `json:"acknowledged_at"`
+    // EstimateMinutes is the effort estimated at triage
+    EstimateMinutes *int `json:"estimate_minutes"`
+    // LikelyAnswered is set on open threads whose author thanked someone or
+    // accepted an answer
+    LikelyAnswered bool `json:"likely_answered"`
+    // SLAPausedSeconds is how long the thread waited on its author, which
+    // does not count towards its SLA
+    SLAPausedSeconds float64 `json:"sla_paused_seconds"`
+    // QualityScore is how complete the AI found the first message for
+    // triage, from 0 to 1, and MissingInfo what it lacks
+    QualityScore *float64 `json:"quality_score"`
+    MissingInfo  []string `json:"missing_info"`
+    // External is set on threads of Slack Connect channels, ExternalAuthor
+    // when the author belongs to another workspace
+    External       bool `json:"external"`
+    ExternalAuthor bool `json:"external_author"`
+
+    // analysis is the stored AI analysis the quality is read from
+    analysis sql.NullString
+    // externalAI is set when the shared channel allows AI analysis
+    externalAI bool
 }
 
 // DashboardStats represents dashboard statistics
@@ -52,255 +90,729 @@ type DashboardStats struct {
     ActiveThreads int `json:"activeThreads"`
     Channels      int `json:"channels"`
     AIAnalyzed    int `json:"aiAnalyzed"`
+    // The threads of Slack Connect channels, also counted above
+    ExternalThreads       int `json:"externalThreads"`
+    ActiveExternalThreads int `json:"activeExternalThreads"`
+    ExternalChannels      int `json:"externalChannels"`
 }
 
 // GetDashboardStats - Get dashboard statistics
 func (c *Container) GetDashboardStats(ctx echo.Context) error {
-    db, err := c.getDBConnection()
+    if cached, ok := c.loadCachedResponse(ctx); ok {
+        return ctx.JSON(http.StatusOK, cached.(*DashboardStats))
+    }
+    stats, err := c.dashboardStats(ctx)
+    if err != nil {
+        return err
+    }
+    c.storeCachedResponse(ctx, stats)
+    return ctx.JSON(http.StatusOK, stats)
+}
+
+// dashboardStats counts the threads of the tracked channels, of the channel
+// group of the request when given.
+func (c *Container) dashboardStats(ctx echo.Context) (*DashboardStats, error) {
+    stats, err := c.stats.DashboardStats(ctx.Request().Context(), ctx.QueryParam("group"))
+    if err == errGroupNotFound {
+        return nil, apierror.New(http.StatusNotFound, "Channel group not found")
+    }
     if err != nil {
-        return ctx.JSON(http.StatusInternalServerError, map[string]string{
-            "error": "Database connection failed",
-        })
+        c.logger.Errorf("failed to count threads: %v", err)
+        return nil, apierror.New(http.StatusInternalServerError, "Failed to query threads")
     }
-    defer db.Close()
+    return &stats, nil
+}
 
+func (s postgresStore) DashboardStats(ctx context.Context, group string) (DashboardStats, error) {
     stats := DashboardStats{}
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return stats, err
+    }
 
-    // Get total threads across all channels
-    var totalThreads int
-    err = db.QueryRow("SELECT COUNT(*) FROM channels").Scan(&totalThreads)
-    if err == nil {
-        // Get actual thread count from channel tables
-        rows, err := db.Query("SELECT table_name FROM channels")
-        if err == nil {
-            defer rows.Close()
-            totalCount := 0
-            for rows.Next() {
-                var tableName string
-                if err := rows.Scan(&tableName); err == nil {
-                    var count int
-                    countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)
-                    if err := db.QueryRow(countQuery).Scan(&count); err == nil {
-                        totalCount += count
-                    }
-                }
-            }
-            stats.TotalThreads = totalCount
-        }
+    // Stats may be scoped to a channel group
+    groupFilter, groupArgs, err := groupChannelFilter(db, group, "ch.channel_id", 0)
+    if err != nil {
+        return stats, err
     }
+    rows, err := db.QueryContext(ctx, `
+        SELECT ch.channel_id, COALESCE(cc.slack_connect, FALSE)
+        FROM channels ch
+        LEFT JOIN channel_config cc ON cc.channel_id = ch.channel_id
+        WHERE `+groupFilter, groupArgs...)
+    if err != nil {
+        return stats, err
+    }
+    defer rows.Close()
 
-    // Get active threads (status = 'open')
-    rows, err := db.Query("SELECT table_name FROM channels")
-    if err == nil {
-        defer rows.Close()
-        activeCount := 0
-        aiAnalyzedCount := 0
-        for rows.Next() {
-            var tableName string
-            if err := rows.Scan(&tableName); err == nil {
-                var count int
-                activeQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE status = 'open'", tableName)
-                if err := db.QueryRow(activeQuery).Scan(&count); err == nil {
-                    activeCount += count
-                }
-
-                // Count AI analyzed threads
-                var aiCount int
-                aiQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE ai_thread_name IS NOT NULL", tableName)
-                if err := db.QueryRow(aiQuery).Scan(&aiCount); err == nil {
-                    aiAnalyzedCount += aiCount
-                }
-            }
+    var channelIDs, external []string
+    for rows.Next() {
+        var channelID string
+        var isExternal bool
+        if err := rows.Scan(&channelID, &isExternal); err != nil {
+            continue
+        }
+        stats.Channels++
+        channelIDs = append(channelIDs, channelID)
+        if isExternal {
+            stats.ExternalChannels++
+            external = append(external, channelID)
         }
-        stats.ActiveThreads = activeCount
-        stats.AIAnalyzed = aiAnalyzedCount
+    }
+    if len(channelIDs) == 0 {
+        return stats, nil
     }
 
-    // Get total channels
-    err = db.QueryRow("SELECT COUNT(*) FROM channels").Scan(&stats.Channels)
-    if err != nil {
-        stats.Channels = 0
+    err = db.QueryRowContext(ctx, `
+        SELECT COUNT(*), COUNT(*) FILTER (WHERE status = 'open'), COUNT(*) FILTER (WHERE ai_thread_name IS NOT NULL),
+               COUNT(*) FILTER (WHERE channel_id = ANY($2)),
+               COUNT(*) FILTER (WHERE status = 'open' AND channel_id = ANY($2))
+        FROM threads WHERE channel_id = ANY($1)
+    `, pq.Array(channelIDs), pq.Array(external)).Scan(&stats.TotalThreads, &stats.ActiveThreads, &stats.AIAnalyzed,
+        &stats.ExternalThreads, &stats.ActiveExternalThreads)
+    return stats, err
+}
+
+// ThreadPage is a page of threads across channels
+type ThreadPage struct {
+    Items []Thread `json:"items"`
+    // NextCursor fetches the following page, nil on the last one
+    NextCursor *string `json:"next_cursor"`
+    // Total is the number of threads matching the filters
+    Total int `json:"total"`
+    // Groups nests the threads under their channel in place of Items when
+    // grouped by channel, each with up to limit threads
+    Groups []ChannelThreads `json:"groups,omitempty"`
+}
+
+// maxThreadPageSize bounds the limit parameter of GetThreads.
+const maxThreadPageSize = 500
+
+// threadSortColumns are the expressions thread lists are sorted by, over
+// the threads aliased u. Priorities sort by urgency. The
+// same expression is compared with the cursor, so none of them may be NULL:
+// threadListColumns gives threads without a reply the time they were
+// started as their latest reply.
+var threadSortColumns = map[string]string{
+    "latest_reply": "u.latest_reply",
+    "created_at":   "u.created_at",
+    "reply_count":  "u.reply_count",
+    "priority":     "CASE u.priority WHEN 'high' THEN 3 WHEN 'medium' THEN 2 WHEN 'low' THEN 1 ELSE 0 END",
+}
+
+// threadCursor is the position after the last thread of a page, in the sort
+// and order of the page. Value is the sort key of that thread, in
+// nanoseconds for times.
+type threadCursor struct {
+    Sort      string
+    Order     string
+    Value     int64
+    ChannelID string
+    ThreadTS  string
+}
+
+// threadSortValue returns the sort key of a listed thread.
+func threadSortValue(sort string, thread Thread) int64 {
+    switch sort {
+    case "created_at":
+        return thread.CreatedAt.UnixNano()
+    case "reply_count":
+        return int64(thread.ReplyCount)
+    case "priority":
+        return int64(thread.Priority.Rank())
     }
+    return thread.LatestReply.UnixNano()
+}
 
-    return ctx.JSON(http.StatusOK, stats)
+// bindValue returns the value to compare the sort column with.
+func (tc threadCursor) bindValue() interface{} {
+    if tc.Sort == "latest_reply" || tc.Sort == "created_at" {
+        return time.Unix(0, tc.Value).UTC()
+    }
+    return tc.Value
 }
 
-// GetThreads - Get threads with optional filters
-func (c *Container) GetThreads(ctx echo.Context) error {
-    db, err := c.getDBConnection()
+func (tc threadCursor) encode() string {
+    raw := fmt.Sprintf("%s|%s|%d|%s|%s", tc.Sort, tc.Order, tc.Value, tc.ChannelID, tc.ThreadTS)
+    return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeThreadCursor(encoded string) (threadCursor, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(encoded)
     if err != nil {
-        return ctx.JSON(http.StatusInternalServerError, map[string]string{
-            "error": "Database connection failed",
-        })
+        return threadCursor{}, err
     }
-    defer db.Close()
+    parts := strings.SplitN(string(raw), "|", 5)
+    if len(parts) != 5 {
+        return threadCursor{}, fmt.Errorf("malformed cursor")
+    }
+    value, err := strconv.ParseInt(parts[2], 10, 64)
+    if err != nil {
+        return threadCursor{}, err
+    }
+    return threadCursor{Sort: parts[0], Order: parts[1], Value: value, ChannelID: parts[3], ThreadTS: parts[4]}, nil
+}
 
-    // Parse query parameters
-    limitStr := ctx.QueryParam("limit")
-    limit := 10 // default
-    if limitStr != "" {
-        if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-            limit = parsedLimit
+// threadList is a page of a thread list: the threads matching filters in
+// sort and order, up to limit of them after skipping offset or, when
+// cursor is set, after it.
+type threadList struct {
+    filters threadFilters
+    sort    string
+    order   string
+    cursor  *threadCursor
+    limit   int
+    offset  int
+}
+
+// listParam splits a comma separated query parameter, dropping empty values.
+func listParam(value string) []string {
+    var values []string
+    for _, v := range strings.Split(value, ",") {
+        if v = strings.TrimSpace(v); v != "" {
+            values = append(values, v)
         }
     }
+    return values
+}
 
-    channel := ctx.QueryParam("channel")
-    priority := ctx.QueryParam("priority")
+// boolParam parses an optional boolean query parameter, nil when absent.
+func boolParam(ctx echo.Context, name string) (*bool, error) {
+    value := ctx.QueryParam(name)
+    if value == "" {
+        return nil, nil
+    }
+    parsed, err := strconv.ParseBool(value)
+    if err != nil {
+        return nil, fmt.Errorf("%s must be true or false", name)
+    }
+    return &parsed, nil
+}
 
-    // Get all channel tables
-    channelRows, err := db.Query("SELECT channel_id, channel_name, table_name FROM channels")
+// timeParam parses an optional RFC3339 query parameter, nil when absent.
+func timeParam(ctx echo.Context, name string) (*time.Time, error) {
+    value := ctx.QueryParam(name)
+    if value == "" {
+        return nil, nil
+    }
+    parsed, err := time.Parse(time.RFC3339, value)
     if err != nil {
-        return ctx.JSON(http.StatusInternalServerError, map[string]string{
-            "error": "Failed to get channels",
-        })
+        return nil, fmt.Errorf("%s must be an RFC3339 timestamp", name)
     }
-    defer channelRows.Close()
+    parsed = parsed.UTC()
+    return &parsed, nil
+}
+
+// threadListColumns are selected from the threads table aliased t by
+// thread lists, in the order Thread fields are scanned. The
+// latest reply of threads without one is when they were started, like in
+// threadSortColumns.
+const threadListColumns = `t.thread_ts, t.channel_id, t.user_id, t.reply_count,
+    COALESCE(t.latest_reply, t.created_at) AS latest_reply,
+    t.status, t.created_at, t.ai_thread_name, t.ai_description,
+    t.ai_stakeholders, t.ai_priority, t.ai_confidence, t.github_issue,
+    t.jira_ticket, t.thread_issue,
+    (SELECT tc.user_id FROM thread_claims tc
+     WHERE tc.channel_id = t.channel_id AND tc.thread_ts = t.thread_ts) AS claimed_by,
+    (SELECT ta.assignee FROM thread_assignments ta
+     WHERE ta.channel_id = t.channel_id AND ta.thread_ts = t.thread_ts) AS assignee,
+    (SELECT s.due_at FROM thread_sla s
+     WHERE s.channel_id = t.channel_id AND s.thread_ts = t.thread_ts) AS due_override,
+    EXISTS (SELECT 1 FROM resolution_requests r
+     WHERE r.channel_id = t.channel_id AND r.thread_ts = t.thread_ts AND r.status = 'pending') AS resolution_pending,
+    (SELECT tr.repo || '@' || tr.tag FROM thread_releases tr
+     WHERE tr.channel_id = t.channel_id AND tr.thread_ts = t.thread_ts AND tr.released_at IS NULL) AS waiting_on_release,
+    (SELECT a.user_id FROM thread_acks a
+     WHERE a.channel_id = t.channel_id AND a.thread_ts = t.thread_ts) AS acknowledged_by,
+    (SELECT a.acked_at FROM thread_acks a
+     WHERE a.channel_id = t.channel_id AND a.thread_ts = t.thread_ts) AS acknowledged_at,
+    (SELECT e.estimate_minutes FROM thread_effort e
+     WHERE e.channel_id = t.channel_id AND e.thread_ts = t.thread_ts) AS estimate_minutes,
+    t.status = 'open' AND EXISTS (SELECT 1 FROM thread_answer_signals sig
+     WHERE sig.channel_id = t.channel_id AND sig.thread_ts = t.thread_ts AND sig.dismissed_at IS NULL
+       AND ` + answerSignalTrusted + `) AS likely_answered,
+    t.ai_analysis_json, ` + pausedSLAColumn + `,
+    ` + externalColumns + `,
+    ` + manualPriorityColumn + ` AS manual_priority`
+
+// threadListChannel is what thread lists need to know about a channel
+// besides its threads.
+type threadListChannel struct {
+    name         string
+    textIndexing bool
+    thresholds   HeatThresholds
+    slaHours     SLAHours
+}
 
-    allThreads := []Thread{}
+// threadListDest returns the scan destinations of threadListColumns followed
+// by the effective priority.
+func threadListDest(thread *Thread, dueOverride *sql.NullTime) []interface{} {
+    return []interface{}{
+        &thread.ThreadTS, &thread.ChannelID, &thread.UserID,
+        &thread.ReplyCount, &thread.LatestReply, &thread.Status,
+        &thread.CreatedAt, &thread.AIThreadName, &thread.AIDescription,
+        &thread.AIStakeholders, &thread.AIPriority, &thread.AIConfidence,
+        &thread.GithubIssue, &thread.JiraTicket, &thread.ThreadIssue,
+        &thread.ClaimedBy, &thread.Assignee, dueOverride, &thread.ResolutionPending,
+        &thread.WaitingOnRelease, &thread.AcknowledgedBy, &thread.AcknowledgedAt,
+        &thread.EstimateMinutes, &thread.LikelyAnswered, &thread.analysis,
+        &thread.SLAPausedSeconds, &thread.External, &thread.ExternalAuthor,
+        &thread.externalAI, &thread.ManualPriority, &thread.Priority,
+    }
+}
 
-    for channelRows.Next() {
-        var channelID, channelName, tableName string
-        if err := channelRows.Scan(&channelID, &channelName, &tableName); err != nil {
+// present fills in the fields of a listed thread derived from its channel,
+// legal holds and SLA.
+func (ch threadListChannel) present(thread *Thread, holds map[string]activeHolds, dueOverride sql.NullTime, now time.Time) {
+    thread.ChannelName = ch.name
+    // Never serve text the channel opted out of, even if an
+    // older collector still wrote it
+    if !ch.textIndexing {
+        thread.AIThreadName = nil
+        thread.AIDescription = nil
+        thread.ThreadIssue = nil
+    }
+    // Nor the analysis of content shared by other workspaces, e.g. written
+    // before the channel was shared
+    if thread.External && !thread.externalAI {
+        thread.AIThreadName = nil
+        thread.AIDescription = nil
+        thread.analysis = sql.NullString{}
+    }
+
+    thread.LegalHold = holds[thread.ChannelID].covers(thread.ThreadTS)
+    var override *time.Time
+    if dueOverride.Valid {
+        override = &dueOverride.Time
+    }
+    thread.ApplySLA(ch.slaHours.thresholds(ch.thresholds, string(thread.Priority)), override, now)
+    quality := parseThreadQuality(thread.analysis)
+    thread.QualityScore, thread.MissingInfo = quality.Score, quality.Missing
+    thread.EffectivePriority, thread.PrioritySource = thread.Priority, PrioritySourceAI
+    if thread.ManualPriority != nil {
+        thread.PrioritySource = PrioritySourceManual
+    }
+    thread.PriorityCalibrated = thread.PrioritySource == PrioritySourceAI && thread.AIPriority != nil && *thread.AIPriority != string(thread.Priority)
+}
+
+// parseThreadSort returns the sort and order of a thread list, by default
+// the most recently active threads first.
+func parseThreadSort(ctx echo.Context) (string, string, error) {
+    sort := ctx.QueryParam("sort")
+    if sort == "" {
+        sort = "latest_reply"
+    }
+    if _, ok := threadSortColumns[sort]; !ok {
+        return "", "", fmt.Errorf("sort must be latest_reply, created_at, reply_count or priority")
+    }
+    order := strings.ToLower(ctx.QueryParam("order"))
+    if order == "" {
+        order = "desc"
+    }
+    if order != "asc" && order != "desc" {
+        return "", "", fmt.Errorf("order must be asc or desc")
+    }
+    return sort, order, nil
+}
+
+// threadFilters select the threads of a list. Lists match any of their
+// values and nil conditions are not applied.
+type threadFilters struct {
+    group          string
+    channels       []string
+    priorities     []string
+    statuses       []string
+    userIDs        []string
+    stakeholders   []string
+    assignees      []string
+    answered       *bool
+    external       *bool
+    hasGithubIssue *bool
+    hasJiraTicket  *bool
+    createdAfter   *time.Time
+    createdBefore  *time.Time
+    // involves selects the threads of a user, authored by them or them
+    // being a stakeholder. It is not read from the query.
+    involves string
+    // backlogOf selects the threads authored by a user or naming them as
+    // an AI stakeholder. It is not read from the query.
+    backlogOf string
+}
+
+// parseThreadFilters reads the filters of a thread list from the query,
+// where lists are comma separated.
+func parseThreadFilters(ctx echo.Context) (threadFilters, error) {
+    f := threadFilters{
+        group:        ctx.QueryParam("group"),
+        channels:     listParam(ctx.QueryParam("channel")),
+        priorities:   listParam(ctx.QueryParam("priority")),
+        statuses:     listParam(ctx.QueryParam("status")),
+        userIDs:      listParam(ctx.QueryParam("user_id")),
+        stakeholders: listParam(ctx.QueryParam("stakeholder")),
+        assignees:    listParam(ctx.QueryParam("assignee")),
+    }
+    for _, p := range f.priorities {
+        if _, err := domain.ParsePriority(p); err != nil {
+            return f, fmt.Errorf("priority must be high, medium, low or none")
+        }
+    }
+    for i, a := range f.assignees {
+        if a != assigneeMe {
             continue
         }
+        if f.assignees[i] = actorFrom(ctx); f.assignees[i] == "anonymous" {
+            return f, fmt.Errorf("assignee=me requires a signed in user")
+        }
+    }
+    // Unparsable answered and external filters have always been ignored
+    if answered, err := strconv.ParseBool(ctx.QueryParam("answered")); err == nil {
+        f.answered = &answered
+    }
+    if external, err := strconv.ParseBool(ctx.QueryParam("external")); err == nil {
+        f.external = &external
+    }
+    var err error
+    if f.hasGithubIssue, err = boolParam(ctx, "has_github_issue"); err != nil {
+        return f, err
+    }
+    if f.hasJiraTicket, err = boolParam(ctx, "has_jira_ticket"); err != nil {
+        return f, err
+    }
+    if f.createdAfter, err = timeParam(ctx, "created_after"); err != nil {
+        return f, err
+    }
+    if f.createdBefore, err = timeParam(ctx, "created_before"); err != nil {
+        return f, err
+    }
+    return f, nil
+}
+
+// threadQuery selects the threads of every matching channel, merged in
+// from as a subquery aliased u with the columns of threadListColumns and
+// the calibrated priority.
+type threadQuery struct {
+    from     string
+    args     []interface{}
+    channels map[string]threadListChannel
+}
+
+// bind adds an argument to the query and returns its placeholder.
+func (q *threadQuery) bind(value interface{}) string {
+    q.args = append(q.args, value)
+    return fmt.Sprintf("$%d", len(q.args))
+}
+
+// threadListQuery returns the query of the threads matching filters. It
+// selects no channel when none matches.
+func threadListQuery(db *sql.DB, filters threadFilters) (*threadQuery, error) {
+    groupFilter, groupArgs, err := groupChannelFilter(db, filters.group, "ch.channel_id", 0)
+    if err != nil {
+        return nil, err
+    }
+    // Channels are named or given by ID
+    channelFilter, channelArgs := "TRUE", groupArgs
+    if len(filters.channels) > 0 {
+        channelArgs = append(channelArgs, pq.Array(filters.channels))
+        channelFilter = fmt.Sprintf("(ch.channel_name = ANY($%[1]d) OR ch.channel_id = ANY($%[1]d))", len(channelArgs))
+    }
+
+    channelRows, err := db.Query(`
+        SELECT ch.channel_id, ch.channel_name, COALESCE(cc.text_indexing, TRUE),
+               cc.heat_thresholds, cc.priority_calibration, cc.sla_hours
+        FROM channels ch
+        LEFT JOIN channel_config cc ON cc.channel_id = ch.channel_id
+        WHERE `+groupFilter+" AND "+channelFilter, channelArgs...)
+    if err != nil {
+        return nil, err
+    }
+    defer channelRows.Close()
 
-        // Skip if channel filter is specified and doesn't match
-        if channel != "" && channelName != channel {
+    // Calibration lowers AI high priorities inside the query, so priority
+    // filters and limits apply to what is shown
+    q := &threadQuery{channels: make(map[string]threadListChannel)}
+    var channelIDs []string
+    var minConfidences []float64
+    for channelRows.Next() {
+        var channelID, channelName string
+        var textIndexing bool
+        var storedThresholds, storedCalibration, storedSLA sql.NullString
+        if err := channelRows.Scan(&channelID, &channelName, &textIndexing, &storedThresholds, &storedCalibration, &storedSLA); err != nil {
             continue
         }
 
-        // Build query for this channel's table
-        query := fmt.Sprintf(`
-            SELECT thread_ts, channel_id, user_id, reply_count, latest_reply, 
-                   status, created_at, ai_thread_name, ai_description, 
-                   ai_stakeholders, ai_priority, ai_confidence, github_issue, 
-                   jira_ticket, thread_issue
-            FROM %s 
-            WHERE 1=1`, tableName)
-
-        args := []interface{}{}
-        argCount := 0
-
-        if priority != "" {
-            argCount++
-            query += fmt.Sprintf(" AND ai_priority = $%d", argCount)
-            args = append(args, priority)
+        q.channels[channelID] = threadListChannel{
+            name:         channelName,
+            textIndexing: textIndexing,
+            thresholds:   parseHeatThresholds(storedThresholds),
+            slaHours:     parseSLAHours(storedSLA),
         }
+        channelIDs = append(channelIDs, channelID)
+        minConfidences = append(minConfidences, parsePriorityCalibration(storedCalibration).MinConfidence)
+    }
+    if err := channelRows.Err(); err != nil {
+        return nil, err
+    }
+    if len(channelIDs) == 0 {
+        return q, nil
+    }
+
+    q.from = fmt.Sprintf(`(
+            SELECT %s,
+                   %s
+            FROM threads t
+            JOIN unnest(%s::TEXT[], %s::FLOAT8[]) AS cal(channel_id, min_confidence) ON cal.channel_id = t.channel_id
+        ) u WHERE 1=1`, threadListColumns, priorityColumn("cal.min_confidence"), q.bind(pq.Array(channelIDs)), q.bind(pq.Array(minConfidences)))
+    q.filter(filters)
+    return q, nil
+}
+
+// listedThread is a thread read by a thread list, before it is presented.
+type listedThread struct {
+    thread      Thread
+    dueOverride sql.NullTime
+}
+
+// queryThreadPage returns the count of the threads of q, and up to limit of
+// them after skipping offset, in the sort and order of the list and after
+// cursor when given.
+func queryThreadPage(ctx context.Context, db *sql.DB, q *threadQuery, sort string, order string, cursor *threadCursor, limit int, offset int) ([]listedThread, int, error) {
+    var total int
+    if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+q.from, q.args...).Scan(&total); err != nil {
+        return nil, 0, err
+    }
 
-        query += " ORDER BY latest_reply DESC"
-        
-        if limit > 0 {
-            argCount++
-            query += fmt.Sprintf(" LIMIT $%d", argCount)
-            args = append(args, limit)
+    // Ties are broken by channel and thread, so pages never overlap. The
+    // page is bound on a copy, leaving q to count the threads again.
+    page := &threadQuery{from: q.from, args: append([]interface{}{}, q.args...)}
+    sortColumn := threadSortColumns[sort]
+    if cursor != nil {
+        comparison := "<"
+        if order == "asc" {
+            comparison = ">"
         }
+        page.from += fmt.Sprintf(" AND (%s, u.channel_id, u.thread_ts) %s (%s, %s, %s)", sortColumn, comparison,
+            page.bind(cursor.bindValue()), page.bind(cursor.ChannelID), page.bind(cursor.ThreadTS))
+    }
+    query := "SELECT u.* FROM " + page.from + fmt.Sprintf(" ORDER BY %[1]s %[2]s NULLS LAST, u.channel_id %[2]s, u.thread_ts %[2]s LIMIT ",
+        sortColumn, strings.ToUpper(order)) + page.bind(limit)
+    if offset > 0 {
+        query += " OFFSET " + page.bind(offset)
+    }
 
-        threadRows, err := db.Query(query, args...)
-        if err != nil {
-            continue // Skip this channel if query fails
+    rows, err := db.QueryContext(ctx, query, page.args...)
+    if err != nil {
+        return nil, 0, err
+    }
+    defer rows.Close()
+
+    listed := []listedThread{}
+    for rows.Next() {
+        var l listedThread
+        if err := rows.Scan(threadListDest(&l.thread, &l.dueOverride)...); err != nil {
+            return nil, 0, err
         }
+        listed = append(listed, l)
+    }
+    return listed, total, rows.Err()
+}
 
-        for threadRows.Next() {
-            thread := Thread{
-                ChannelName: channelName,
+// filter restricts the threads of the query to those matching filters.
+func (q *threadQuery) filter(filters threadFilters) {
+    if len(filters.priorities) > 0 {
+        q.from += " AND u.priority = ANY(" + q.bind(pq.Array(filters.priorities)) + ")"
+    }
+    if len(filters.statuses) > 0 {
+        q.from += " AND u.status = ANY(" + q.bind(pq.Array(filters.statuses)) + ")"
+    }
+    if len(filters.userIDs) > 0 {
+        q.from += " AND u.user_id = ANY(" + q.bind(pq.Array(filters.userIDs)) + ")"
+    }
+    // Stakeholders are those identified by AI and whoever claimed,
+    // acknowledged or was assigned the thread
+    if len(filters.stakeholders) > 0 {
+        q.from += " AND EXISTS (SELECT 1 FROM " + usergroupRefs(q.bind(pq.Array(filters.stakeholders))) + ` s(id)
+            WHERE strpos(u.ai_stakeholders, '"' || s.id || '"') > 0
+               OR u.claimed_by = s.id OR u.acknowledged_by = s.id OR u.assignee = s.id)`
+    }
+    // A user is also involved in the threads of their usergroups
+    if filters.involves != "" {
+        q.from += fmt.Sprintf(` AND (u.user_id = %[1]s OR strpos(u.ai_stakeholders, '"' || %[1]s || '"') > 0
+            OR u.claimed_by = %[1]s OR u.acknowledged_by = %[1]s OR u.assignee = %[1]s
+            OR EXISTS (SELECT 1 FROM slack_usergroup_members m WHERE m.user_id = %[1]s
+                AND (u.assignee = m.usergroup_id OR strpos(u.ai_stakeholders, '"' || m.usergroup_id || '"') > 0)))`, q.bind(filters.involves))
+    }
+    if filters.backlogOf != "" {
+        q.from += fmt.Sprintf(` AND (u.user_id = %[1]s OR strpos(u.ai_stakeholders, '"' || %[1]s || '"') > 0)`, q.bind(filters.backlogOf))
+    }
+    // "none" selects unassigned threads
+    if len(filters.assignees) > 0 {
+        q.from += " AND (u.assignee IN (SELECT id FROM " + usergroupRefs(q.bind(pq.Array(filters.assignees))) + " s(id))"
+        for _, a := range filters.assignees {
+            if a == "none" {
+                q.from += " OR u.assignee IS NULL"
+                break
             }
+        }
+        q.from += ")"
+    }
+    if filters.answered != nil {
+        q.from += fmt.Sprintf(" AND u.likely_answered = %t", *filters.answered)
+    }
+    if filters.external != nil {
+        q.from += fmt.Sprintf(" AND u.external = %t", *filters.external)
+    }
+    if filters.hasGithubIssue != nil {
+        q.from += fmt.Sprintf(" AND (COALESCE(u.github_issue, '') <> '') = %t", *filters.hasGithubIssue)
+    }
+    if filters.hasJiraTicket != nil {
+        q.from += fmt.Sprintf(" AND (COALESCE(u.jira_ticket, '') <> '') = %t", *filters.hasJiraTicket)
+    }
+    if filters.createdAfter != nil {
+        q.from += " AND u.created_at >= " + q.bind(*filters.createdAfter)
+    }
+    if filters.createdBefore != nil {
+        q.from += " AND u.created_at < " + q.bind(*filters.createdBefore)
+    }
+}
 
-            err := threadRows.Scan(
-                &thread.ThreadTS, &thread.ChannelID, &thread.UserID,
-                &thread.ReplyCount, &thread.LatestReply, &thread.Status,
-                &thread.CreatedAt, &thread.AIThreadName, &thread.AIDescription,
-                &thread.AIStakeholders, &thread.AIPriority, &thread.AIConfidence,
-                &thread.GithubIssue, &thread.JiraTicket, &thread.ThreadIssue,
-            )
-
-            if err == nil {
-                // Set priority for frontend display
-                if thread.AIPriority != nil {
-                    thread.Priority = *thread.AIPriority
-                } else {
-                    thread.Priority = "none"
-                }
-                allThreads = append(allThreads, thread)
-            }
+// GetThreads - Get a page of threads across channels, most recently active first unless sorted otherwise, or the first threads of each channel with group_by=channel
+func (c *Container) GetThreads(ctx echo.Context) error {
+    // Parse query parameters
+    limit := 10 // default
+    if limitStr := ctx.QueryParam("limit"); limitStr != "" {
+        if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+            limit = parsedLimit
+        }
+    }
+    if limit > maxThreadPageSize {
+        limit = maxThreadPageSize
+    }
+    offset := 0
+    if offsetStr := ctx.QueryParam("offset"); offsetStr != "" {
+        parsedOffset, err := strconv.Atoi(offsetStr)
+        if err != nil || parsedOffset < 0 {
+            return apierror.New(http.StatusBadRequest, "offset must be a non-negative integer")
         }
-        threadRows.Close()
+        offset = parsedOffset
+    }
+    sort, order, err := parseThreadSort(ctx)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
     }
 
-    // Sort all threads by latest reply and limit
-    // (In a real implementation, you might want to do this in the database)
-    if len(allThreads) > limit {
-        allThreads = allThreads[:limit]
+    var cursor *threadCursor
+    if cursorStr := ctx.QueryParam("cursor"); cursorStr != "" {
+        if offset > 0 {
+            return apierror.New(http.StatusBadRequest, "use either offset or cursor, not both")
+        }
+        decoded, err := decodeThreadCursor(cursorStr)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, "invalid cursor")
+        }
+        if decoded.Sort != sort || decoded.Order != order {
+            return apierror.New(http.StatusBadRequest, "cursor belongs to another sort or order")
+        }
+        cursor = &decoded
     }
 
-    return ctx.JSON(http.StatusOK, allThreads)
-}
+    // Grouped lists show the first threads of every channel at once
+    groupBy := ctx.QueryParam("group_by")
+    if groupBy != "" && groupBy != groupByChannel {
+        return apierror.New(http.StatusBadRequest, "group_by must be channel")
+    }
+    if groupBy != "" && (offset > 0 || cursor != nil) {
+        return apierror.New(http.StatusBadRequest, "group_by limits the threads of each channel, it cannot be combined with offset or cursor")
+    }
 
-// GetChannels - Get all channels
-func (c *Container) GetChannels(ctx echo.Context) error {
-    db, err := c.getDBConnection()
+    filters, err := parseThreadFilters(ctx)
     if err != nil {
-        return ctx.JSON(http.StatusInternalServerError, map[string]string{
-            "error": "Database connection failed",
-        })
-    }
-    defer db.Close()
-
-    rows, err := db.Query(`
-        SELECT channel_id, channel_name, thread_count, active_thread_count, 
-               last_activity, created_at 
-        FROM channels
-        ORDER BY channel_name
-    `)
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+
+    list := threadList{filters: filters, sort: sort, order: order, cursor: cursor, limit: limit, offset: offset}
+    page := ThreadPage{Items: []Thread{}}
+    if groupBy == groupByChannel {
+        page.Groups, page.Total, err = c.threadLists.GroupThreads(ctx.Request().Context(), list)
+        if err == errGroupNotFound {
+            return apierror.New(http.StatusNotFound, "Channel group not found")
+        }
+        if err != nil {
+            c.logger.Errorf("failed to query threads by channel: %v", err)
+            return apierror.New(http.StatusInternalServerError, "Failed to query threads")
+        }
+        sortChannelThreads(page.Groups, sort, order)
+        return ctx.JSON(http.StatusOK, page)
+    }
+
+    // One more than the limit tells whether another page follows
+    list.limit++
+    page.Items, page.Total, err = c.threadLists.ListThreads(ctx.Request().Context(), list)
+    if err == errGroupNotFound {
+        return apierror.New(http.StatusNotFound, "Channel group not found")
+    }
     if err != nil {
-        return ctx.JSON(http.StatusInternalServerError, map[string]string{
-            "error": "Failed to query channels",
-        })
+        c.logger.Errorf("failed to query threads: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to query threads")
+    }
+    if len(page.Items) > limit {
+        page.Items = page.Items[:limit]
+        last := page.Items[limit-1]
+        next := threadCursor{Sort: sort, Order: order, Value: threadSortValue(sort, last),
+            ChannelID: last.ChannelID, ThreadTS: last.ThreadTS}.encode()
+        page.NextCursor = &next
     }
-    defer rows.Close()
 
-    var channels []map[string]interface{}
+    return ctx.JSON(http.StatusOK, page)
+}
 
-    for rows.Next() {
-        var channelID, channelName string
-        var threadCount, activeThreadCount int
-        var lastActivity, createdAt time.Time
+func (s postgresStore) ListThreads(ctx context.Context, list threadList) ([]Thread, int, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, 0, err
+    }
+    holds, err := loadActiveHolds(db)
+    if err != nil {
+        return nil, 0, err
+    }
+    q, err := threadListQuery(db, list.filters)
+    if err != nil || len(q.channels) == 0 {
+        return []Thread{}, 0, err
+    }
 
-        err := rows.Scan(&channelID, &channelName, &threadCount, 
-                        &activeThreadCount, &lastActivity, &createdAt)
-        if err != nil {
-            continue
-        }
+    listed, total, err := queryThreadPage(ctx, db, q, list.sort, list.order, list.cursor, list.limit, list.offset)
+    if err != nil {
+        return nil, 0, err
+    }
+    now := time.Now()
+    threads := make([]Thread, 0, len(listed))
+    for _, l := range listed {
+        thread := l.thread
+        q.channels[thread.ChannelID].present(&thread, holds, l.dueOverride, now)
+        threads = append(threads, thread)
+    }
+    return threads, total, nil
+}
 
-        channel := map[string]interface{}{
-            "channel_id":           channelID,
-            "channel_name":         channelName,
-            "thread_count":         threadCount,
-            "active_thread_count":  activeThreadCount,
-            "last_activity":        lastActivity,
-            "created_at":           createdAt,
-        }
-        channels = append(channels, channel)
+// GetChannels - Get all channels
+func (c *Container) GetChannels(ctx echo.Context) error {
+    if cached, ok := c.loadCachedResponse(ctx); ok {
+        return ctx.JSON(http.StatusOK, cached.([]ChannelSummary))
+    }
+    channels, err := c.channels.Channels(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query channels")
     }
 
+    c.storeCachedResponse(ctx, channels)
     return ctx.JSON(http.StatusOK, channels)
 }
 
 // GetUserProfiles - Get user profiles for stakeholders
 func (c *Container) GetUserProfiles(ctx echo.Context) error {
-    db, err := c.getDBConnection()
-    if err != nil {
-        return ctx.JSON(http.StatusInternalServerError, map[string]string{
-            "error": "Database connection failed",
-        })
-    }
-    defer db.Close()
-
     // Get user IDs from query parameter (comma-separated)
     userIDs := ctx.QueryParam("user_ids")
     if userIDs == "" {
-        return ctx.JSON(http.StatusBadRequest, map[string]string{
-            "error": "user_ids parameter is required",
-        })
+        return apierror.New(http.StatusBadRequest, "user_ids parameter is required")
     }
 
     // Split user IDs and prepare query
@@ -309,37 +821,69 @@ func (c *Container) GetUserProfiles(ctx echo.Context) error {
         return ctx.JSON(http.StatusOK, []UserProfile{})
     }
 
-    // Build the query with placeholders
-    placeholders := make([]string, len(userIDList))
-    args := make([]interface{}, len(userIDList))
     for i, userID := range userIDList {
+        userIDList[i] = strings.TrimSpace(userID)
+    }
+    profiles, err := c.users.Profiles(ctx.Request().Context(), userIDList)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query user profiles")
+    }
+
+    // Workload context is opt-in since it scans the threads of every channel
+    if includesStats(ctx.QueryParam("include")) {
+        ids := make([]string, len(profiles))
+        for i, profile := range profiles {
+            ids[i] = profile.UserID
+        }
+        stats, err := c.stats.UserStats(ctx.Request().Context(), ids)
+        if err != nil {
+            return apierror.New(http.StatusInternalServerError, "Failed to compute user stats")
+        }
+        for i := range profiles {
+            profiles[i].Stats = stats[profiles[i].UserID]
+        }
+    }
+
+    return ctx.JSON(http.StatusOK, profiles)
+}
+
+// loadUserProfiles returns the stored profiles of users, with the custom
+// directory overlaid and names resolved with the configured precedence.
+// Users without a profile are left out.
+func (c *Container) loadUserProfiles(db *sql.DB, userIDs []string) ([]UserProfile, error) {
+    var profiles []UserProfile
+    if len(userIDs) == 0 {
+        return profiles, nil
+    }
+
+    // Build the query with placeholders
+    placeholders := make([]string, len(userIDs))
+    args := make([]interface{}, len(userIDs))
+    for i, userID := range userIDs {
         placeholders[i] = fmt.Sprintf("$%d", i+1)
-        args[i] = strings.TrimSpace(userID)
+        args[i] = userID
     }
 
     query := fmt.Sprintf(`
         SELECT user_id, name, display_name, real_name, 
                profile_image_url, profile_image_24, profile_image_32, 
-               profile_image_48, profile_image_72
+               profile_image_48, profile_image_72, deactivated_at IS NOT NULL
         FROM user_profiles 
         WHERE user_id IN (%s)
     `, strings.Join(placeholders, ","))
 
     rows, err := db.Query(query, args...)
     if err != nil {
-        return ctx.JSON(http.StatusInternalServerError, map[string]string{
-            "error": "Failed to query user profiles",
-        })
+        return nil, err
     }
     defer rows.Close()
 
-    var profiles []UserProfile
     for rows.Next() {
         var profile UserProfile
         err := rows.Scan(
             &profile.UserID, &profile.Name, &profile.DisplayName, &profile.RealName,
             &profile.ProfileImageURL, &profile.ProfileImage24, &profile.ProfileImage32,
-            &profile.ProfileImage48, &profile.ProfileImage72,
+            &profile.ProfileImage48, &profile.ProfileImage72, &profile.Deactivated,
         )
         if err != nil {
             continue
@@ -347,35 +891,9 @@ func (c *Container) GetUserProfiles(ctx echo.Context) error {
         profiles = append(profiles, profile)
     }
 
-    return ctx.JSON(http.StatusOK, profiles)
-}
-
-// getDBConnection creates a database connection
-func (c *Container) getDBConnection() (*sql.DB, error) {
-    // Database configuration - in production, use environment variables
-    dbConfig := map[string]string{
-        "host":     "10.150.3.246",
-        "port":     "5433",
-        "user":     "yugabyte",
-        "password": "Threads@123",
-        "dbname":   "open_thread_db",
-        "sslmode":  "disable",
-    }
-
-    connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-        dbConfig["host"], dbConfig["port"], dbConfig["user"], 
-        dbConfig["password"], dbConfig["dbname"], dbConfig["sslmode"])
-
-    db, err := sql.Open("postgres", connStr)
-    if err != nil {
-        return nil, err
-    }
-
-    // Test the connection
-    if err := db.Ping(); err != nil {
-        db.Close()
-        return nil, err
+    // Overlay the custom directory and resolve names with the configured precedence
+    if err := applyDirectory(db, profiles); err != nil {
+        c.logger.Warnf("failed to apply user directory: %v", err)
     }
-
-    return db, nil
-} 
\ No newline at end of file
+    return profiles, nil
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+    "fmt"
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+)
+
+func TestGetChannels(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "support"}, ThreadCount: 4})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "billing"}, ThreadCount: 2})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/channels", "/api/channels", c.GetChannels)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var channels []ChannelSummary
+    decodeResponse(t, rec, &channels)
+    if len(channels) != 2 || channels[0].ChannelName != "billing" || channels[1].ThreadCount != 4 {
+        t.Fatalf("got channels %+v, want them sorted by name", channels)
+    }
+}
+
+func TestGetUserProfiles(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddProfile(UserProfile{UserProfile: domain.UserProfile{UserID: "U1", DisplayName: "ada", ResolvedName: "Ada"}})
+    store.AddProfile(UserProfile{UserProfile: domain.UserProfile{UserID: "U2"}})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/user-profiles", "/api/user-profiles", c.GetUserProfiles)
+    if rec.Code != http.StatusBadRequest {
+        t.Fatalf("got status %d without user_ids, want 400", rec.Code)
+    }
+
+    rec = serveRequest(t, c, http.MethodGet, "/api/user-profiles", "/api/user-profiles?user_ids=U1,%20U2,U3", c.GetUserProfiles)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var profiles []UserProfile
+    decodeResponse(t, rec, &profiles)
+    if len(profiles) != 2 {
+        t.Fatalf("got %d profiles, want those of U1 and U2", len(profiles))
+    }
+    if profiles[0].ResolvedName != "Ada" || profiles[1].ResolvedName != "U2" {
+        t.Fatalf("got names %q and %q", profiles[0].ResolvedName, profiles[1].ResolvedName)
+    }
+}
+
+func TestThreadCursor(t *testing.T) {
+    latest := time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)
+    cursor := threadCursor{Sort: "latest_reply", Order: "desc", Value: latest.UnixNano(), ChannelID: "C1", ThreadTS: "1700000000.000100"}
+    decoded, err := decodeThreadCursor(cursor.encode())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if decoded != cursor {
+        t.Fatalf("got cursor %+v, want %+v", decoded, cursor)
+    }
+    if value, ok := decoded.bindValue().(time.Time); !ok || !value.Equal(latest) {
+        t.Fatalf("got bound value %v, want %v", decoded.bindValue(), latest)
+    }
+    if value := (threadCursor{Sort: "reply_count", Value: 3}).bindValue(); value != int64(3) {
+        t.Fatalf("got bound reply count %v, want 3", value)
+    }
+
+    for _, encoded := range []string{"", "not base64!", "bGF0ZXN0X3JlcGx5fGRlc2M"} {
+        if _, err := decodeThreadCursor(encoded); err == nil {
+            t.Errorf("decoded malformed cursor %q", encoded)
+        }
+    }
+}
+
+func TestGetThreads(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C2", ChannelName: "billing"}})
+    start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+    for i, thread := range []domain.Thread{
+        {ChannelID: "C1", ThreadTS: "1700000000.000100", Status: domain.StatusOpen},
+        {ChannelID: "C2", ThreadTS: "1700000000.000200", Status: domain.StatusOpen},
+        {ChannelID: "C1", ThreadTS: "1700000000.000300", Status: domain.StatusResolved},
+        {ChannelID: "C1", ThreadTS: "1700000000.000400", Status: domain.StatusOpen},
+    } {
+        thread.CreatedAt = start
+        thread.LatestReply = start.Add(time.Duration(i) * time.Hour)
+        store.AddThread(StoredThread{Thread: thread})
+    }
+    c := newTestContainer(t, store, &MemorySlack{})
+    get := func(target string) (int, ThreadPage) {
+        t.Helper()
+        rec := serveRequest(t, c, http.MethodGet, "/api/threads", target, c.GetThreads)
+        var page ThreadPage
+        if rec.Code == http.StatusOK {
+            decodeResponse(t, rec, &page)
+        }
+        return rec.Code, page
+    }
+    threadsOf := func(items []Thread) []string {
+        var threads []string
+        for _, thread := range items {
+            threads = append(threads, thread.ThreadTS[len(thread.ThreadTS)-3:])
+        }
+        return threads
+    }
+
+    // The most recently active threads come first, a cursor follows them
+    _, page := get("/api/threads?limit=3")
+    if got := threadsOf(page.Items); page.Total != 4 || page.NextCursor == nil || fmt.Sprint(got) != "[400 300 200]" {
+        t.Fatalf("got threads %v of %d with cursor %v", got, page.Total, page.NextCursor)
+    }
+    if page.Items[0].ChannelName != "support" {
+        t.Fatalf("got channel name %q", page.Items[0].ChannelName)
+    }
+    _, page = get("/api/threads?limit=3&cursor=" + *page.NextCursor)
+    if got := threadsOf(page.Items); page.NextCursor != nil || fmt.Sprint(got) != "[100]" {
+        t.Fatalf("got threads %v after the cursor, with cursor %v", got, page.NextCursor)
+    }
+    if code, _ := get("/api/threads?sort=created_at&cursor=" + threadCursor{Sort: "latest_reply", Order: "desc"}.encode()); code != http.StatusBadRequest {
+        t.Fatalf("got status %d for the cursor of another sort", code)
+    }
+
+    _, page = get("/api/threads?status=open&channel=support&sort=latest_reply&order=asc")
+    if got := threadsOf(page.Items); page.Total != 2 || fmt.Sprint(got) != "[100 400]" {
+        t.Fatalf("got filtered threads %v of %d", got, page.Total)
+    }
+
+    _, page = get("/api/threads?group_by=channel&limit=1")
+    if len(page.Groups) != 2 || page.Total != 4 || page.Groups[0].ChannelID != "C1" || page.Groups[0].Total != 3 ||
+        fmt.Sprint(threadsOf(page.Groups[0].Items)) != "[400]" {
+        t.Fatalf("got groups %+v of %d threads", page.Groups, page.Total)
+    }
+
+    if code, _ := get("/api/threads?group=missing"); code != http.StatusNotFound {
+        t.Fatalf("got status %d for a missing channel group", code)
+    }
+}
+
+func TestGetDashboardStats(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support"}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000100", Status: domain.StatusOpen}})
+    store.AddThread(StoredThread{Thread: domain.Thread{ChannelID: "C1", ThreadTS: "1700000000.000200", Status: domain.StatusClosed}})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/stats", "/api/stats", c.GetDashboardStats)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var stats DashboardStats
+    decodeResponse(t, rec, &stats)
+    if stats.Channels != 1 || stats.TotalThreads != 2 || stats.ActiveThreads != 1 {
+        t.Fatalf("got stats %+v", stats)
+    }
+
+    rec = serveRequest(t, c, http.MethodGet, "/api/stats", "/api/stats?group=missing", c.GetDashboardStats)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("got status %d for a missing channel group", rec.Code)
+    }
+}
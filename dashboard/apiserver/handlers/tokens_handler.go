@@ -0,0 +1,238 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+)
+
+// maxTokenLifetimeDays caps how long a personal token may live.
+const maxTokenLifetimeDays = 365
+
+// errTokenNotFound is returned for tokens that are not active tokens of
+// the caller.
+var errTokenNotFound = errors.New("API token not found")
+
+// APIToken represents a personal API token, without its secret
+type APIToken struct {
+    ID          int64      `json:"id"`
+    Name        string     `json:"name"`
+    TokenPrefix string     `json:"token_prefix"`
+    Scopes      []string   `json:"scopes"`
+    CreatedAt   time.Time  `json:"created_at"`
+    ExpiresAt   *time.Time `json:"expires_at"`
+    LastUsedAt  *time.Time `json:"last_used_at"`
+}
+
+// CreateTokenRequest is the body accepted by CreateMyToken
+type CreateTokenRequest struct {
+    Name          string   `json:"name"`
+    Scopes        []string `json:"scopes"`
+    ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// GetMyTokens - List the caller's active personal API tokens
+func (c *Container) GetMyTokens(ctx echo.Context) error {
+    principal, _ := auth.PrincipalFrom(ctx)
+
+    tokens, err := c.tokens.Tokens(ctx.Request().Context(), principal.UserID)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query API tokens")
+    }
+
+    return ctx.JSON(http.StatusOK, tokens)
+}
+
+// CreateMyToken - Create a personal API token for the caller
+func (c *Container) CreateMyToken(ctx echo.Context) error {
+    principal, _ := auth.PrincipalFrom(ctx)
+
+    var req CreateTokenRequest
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&req); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+
+    req.Name = strings.TrimSpace(req.Name)
+    if req.Name == "" || len(req.Name) > 100 {
+        return apierror.New(http.StatusBadRequest, "name is required and must be at most 100 characters")
+    }
+    if len(req.Scopes) == 0 {
+        return apierror.New(http.StatusBadRequest, "at least one scope is required")
+    }
+
+    scopes := make([]auth.Scope, 0, len(req.Scopes))
+    for _, s := range req.Scopes {
+        scope, err := auth.ParseScope(s)
+        if err != nil {
+            return apierror.New(http.StatusBadRequest, err.Error())
+        }
+        // A token can never carry more privileges than its owner
+        if !principal.Has(scope) {
+            return apierror.New(http.StatusForbidden, "cannot grant scope "+string(scope)+" that you do not hold")
+        }
+        scopes = append(scopes, scope)
+    }
+
+    if req.ExpiresInDays < 0 || req.ExpiresInDays > maxTokenLifetimeDays {
+        return apierror.New(http.StatusBadRequest, "expires_in_days must be between 1 and "+strconv.Itoa(maxTokenLifetimeDays))
+    }
+    if req.ExpiresInDays == 0 {
+        req.ExpiresInDays = 90
+    }
+    expiresAt := time.Now().UTC().AddDate(0, 0, req.ExpiresInDays)
+
+    plaintext, hash, err := auth.GenerateToken()
+    if err != nil {
+        c.logger.Errorf("failed to generate API token: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to generate API token")
+    }
+
+    token, err := c.tokens.CreateToken(ctx.Request().Context(), principal.UserID, APIToken{
+        Name:        req.Name,
+        TokenPrefix: auth.DisplayPrefix(plaintext),
+        Scopes:      strings.Split(auth.JoinScopes(scopes), ","),
+        ExpiresAt:   &expiresAt,
+    }, hash)
+    if err != nil {
+        c.logger.Errorf("failed to store API token: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to create API token")
+    }
+
+    c.logger.Infof("user %s created API token %d with scopes %s", principal.UserID, token.ID, auth.JoinScopes(scopes))
+
+    return ctx.JSON(http.StatusCreated, map[string]interface{}{
+        "token":   plaintext,
+        "details": token,
+    })
+}
+
+// RevokeMyToken - Revoke one of the caller's personal API tokens
+func (c *Container) RevokeMyToken(ctx echo.Context) error {
+    principal, _ := auth.PrincipalFrom(ctx)
+
+    id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "invalid token id")
+    }
+
+    err = c.tokens.RevokeToken(ctx.Request().Context(), principal.UserID, id)
+    if err == errTokenNotFound {
+        return apierror.New(http.StatusNotFound, "API token not found")
+    }
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to revoke API token")
+    }
+
+    return ctx.NoContent(http.StatusNoContent)
+}
+
+// LookupToken resolves a token hash to its owner and scopes. Unknown, expired
+// and revoked tokens resolve to nil.
+func (c *Container) LookupToken(hash string) (*auth.Principal, error) {
+    return c.tokens.LookupToken(context.Background(), hash)
+}
+
+func (s postgresStore) Tokens(ctx context.Context, userID string) ([]APIToken, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT id, name, token_prefix, scopes, created_at, expires_at, last_used_at
+        FROM api_tokens
+        WHERE user_id = $1 AND revoked_at IS NULL
+          AND (expires_at IS NULL OR expires_at > NOW())
+        ORDER BY created_at DESC
+    `, userID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    tokens := []APIToken{}
+    for rows.Next() {
+        var token APIToken
+        var scopes string
+        err := rows.Scan(&token.ID, &token.Name, &token.TokenPrefix, &scopes,
+            &token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt)
+        if err != nil {
+            continue
+        }
+        token.Scopes = strings.Split(scopes, ",")
+        tokens = append(tokens, token)
+    }
+    return tokens, rows.Err()
+}
+
+func (s postgresStore) CreateToken(ctx context.Context, userID string, token APIToken, hash string) (APIToken, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return APIToken{}, err
+    }
+
+    err = db.QueryRowContext(ctx, `
+        INSERT INTO api_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `, userID, token.Name, token.TokenPrefix, hash, strings.Join(token.Scopes, ","), token.ExpiresAt,
+    ).Scan(&token.ID, &token.CreatedAt)
+    return token, err
+}
+
+func (s postgresStore) RevokeToken(ctx context.Context, userID string, id int64) error {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return err
+    }
+
+    result, err := db.ExecContext(ctx, `
+        UPDATE api_tokens SET revoked_at = NOW()
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `, id, userID)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return errTokenNotFound
+    }
+    return nil
+}
+
+func (s postgresStore) LookupToken(ctx context.Context, hash string) (*auth.Principal, error) {
+    db, err := s.c.getDBConnection()
+    if err != nil {
+        return nil, err
+    }
+
+    var id int64
+    var userID, scopes string
+    err = db.QueryRowContext(ctx, `
+        UPDATE api_tokens SET last_used_at = NOW()
+        WHERE token_hash = $1 AND revoked_at IS NULL
+          AND (expires_at IS NULL OR expires_at > NOW())
+        RETURNING id, user_id, scopes
+    `, hash).Scan(&id, &userID, &scopes)
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    parsed, err := auth.ParseScopes(scopes)
+    if err != nil {
+        return nil, err
+    }
+    return &auth.Principal{UserID: userID, Scopes: parsed, TokenID: id, Method: auth.MethodAPIToken}, nil
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+
+    "github.com/labstack/echo/v4"
+)
+
+// serveAs sends body to handler mounted on route as principal.
+func serveAs(c *Container, principal *auth.Principal, method string, route string, target string, body string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+    e := echo.New()
+    e.HTTPErrorHandler = apierror.Handler(c.logger)
+    e.Add(method, route, handler, func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(ctx echo.Context) error {
+            auth.SetPrincipal(ctx, principal)
+            return next(ctx)
+        }
+    })
+    req := httptest.NewRequest(method, target, strings.NewReader(body))
+    req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+    rec := httptest.NewRecorder()
+    e.ServeHTTP(rec, req)
+    return rec
+}
+
+func TestCreateMyTokenLimitsScopes(t *testing.T) {
+    store := NewMemoryStore()
+    c := newTestContainer(t, store, &MemorySlack{})
+    triager := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeTriage}, Method: auth.MethodSession}
+
+    tests := []struct {
+        name string
+        body string
+        want int
+    }{
+        {"scope of the caller", `{"name": "ci", "scopes": ["triage"]}`, http.StatusCreated},
+        {"lower scope", `{"name": "ci", "scopes": ["read-only"]}`, http.StatusCreated},
+        {"scope above the caller", `{"name": "ci", "scopes": ["read-only", "admin"]}`, http.StatusForbidden},
+        {"unknown scope", `{"name": "ci", "scopes": ["owner"]}`, http.StatusBadRequest},
+        {"no scope", `{"name": "ci", "scopes": []}`, http.StatusBadRequest},
+        {"no name", `{"name": " ", "scopes": ["read-only"]}`, http.StatusBadRequest},
+        {"lifetime too long", `{"name": "ci", "scopes": ["read-only"], "expires_in_days": 366}`, http.StatusBadRequest},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            before, _ := store.Tokens(context.Background(), "U1")
+            rec := serveAs(c, triager, http.MethodPost, "/api/me/tokens", "/api/me/tokens", tt.body, c.CreateMyToken)
+            if rec.Code != tt.want {
+                t.Fatalf("got status %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+            }
+            after, _ := store.Tokens(context.Background(), "U1")
+            if created := len(after) - len(before); (created == 1) != (tt.want == http.StatusCreated) {
+                t.Fatalf("stored %d tokens", created)
+            }
+        })
+    }
+}
+
+func TestTokenLifecycle(t *testing.T) {
+    store := NewMemoryStore()
+    c := newTestContainer(t, store, &MemorySlack{})
+    owner := &auth.Principal{UserID: "U1", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+
+    rec := serveAs(c, owner, http.MethodPost, "/api/me/tokens", "/api/me/tokens", `{"name": "ci", "scopes": ["read-only"], "expires_in_days": 7}`, c.CreateMyToken)
+    if rec.Code != http.StatusCreated {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var created struct {
+        Token   string   `json:"token"`
+        Details APIToken `json:"details"`
+    }
+    decodeResponse(t, rec, &created)
+    if !strings.HasPrefix(created.Token, auth.TokenPrefix) || created.Details.TokenPrefix != auth.DisplayPrefix(created.Token) {
+        t.Fatalf("got token %q with prefix %q", created.Token, created.Details.TokenPrefix)
+    }
+    // Tokens are looked up by the hash of their secret only
+    if principal, err := c.LookupToken(created.Token); err != nil || principal != nil {
+        t.Fatalf("the token itself resolved to %+v, %v", principal, err)
+    }
+
+    principal, err := c.LookupToken(auth.HashToken(created.Token))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if principal == nil || principal.UserID != "U1" || principal.Has(auth.ScopeTriage) || !principal.Has(auth.ScopeRead) || principal.TokenID != created.Details.ID {
+        t.Fatalf("got principal %+v, want a read-only token of U1", principal)
+    }
+
+    // Other users cannot revoke the token
+    target := "/api/me/tokens/" + strconv.FormatInt(created.Details.ID, 10)
+    other := &auth.Principal{UserID: "U2", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+    if rec := serveAs(c, other, http.MethodDelete, "/api/me/tokens/:id", target, "", c.RevokeMyToken); rec.Code != http.StatusNotFound {
+        t.Fatalf("revoking the token of another user got status %d", rec.Code)
+    }
+    if rec := serveAs(c, owner, http.MethodDelete, "/api/me/tokens/:id", target, "", c.RevokeMyToken); rec.Code != http.StatusNoContent {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    if principal, err := c.LookupToken(auth.HashToken(created.Token)); err != nil || principal != nil {
+        t.Fatalf("revoked token resolved to %+v, %v", principal, err)
+    }
+
+    expired := time.Now().Add(-time.Second)
+    token, err := store.CreateToken(context.Background(), "U1", APIToken{Name: "old", Scopes: []string{"read-only"}, ExpiresAt: &expired}, "expired-hash")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if principal, err := c.LookupToken("expired-hash"); err != nil || principal != nil {
+        t.Fatalf("expired token resolved to %+v, %v", principal, err)
+    }
+    tokens, _ := store.Tokens(context.Background(), "U1")
+    for _, listed := range tokens {
+        if listed.ID == token.ID || listed.ID == created.Details.ID {
+            t.Fatalf("listed token %d, want only active tokens", listed.ID)
+        }
+    }
+}
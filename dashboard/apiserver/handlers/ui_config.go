@@ -0,0 +1,188 @@
+package handlers
+
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/labstack/echo/v4"
+)
+
+// PriorityStyle is how the SPA renders a thread priority. Symbol differs
+// between priorities so that they are told apart without color.
+type PriorityStyle struct {
+    Label      string `json:"label"`
+    Symbol     string `json:"symbol"`
+    Background string `json:"background"`
+    Foreground string `json:"foreground"`
+    Border     string `json:"border"`
+}
+
+// Palette maps the priorities, high, medium, low and none, to their style.
+type Palette struct {
+    Description    string                   `json:"description"`
+    ColorBlindSafe bool                     `json:"color_blind_safe"`
+    Priorities     map[string]PriorityStyle `json:"priorities"`
+}
+
+// UIConfig is the visual configuration the SPA loads at startup.
+type UIConfig struct {
+    // Theme holds design tokens such as accent or background colors
+    Theme map[string]string `json:"theme"`
+    // Palette names the palette used unless the user picks another one of
+    // Palettes
+    Palette  string             `json:"palette"`
+    Palettes map[string]Palette `json:"palettes"`
+    Features map[string]bool    `json:"features"`
+}
+
+// uiSettings are the UI settings of the environment, applied over the
+// defaults.
+type uiSettings struct {
+    palette  string
+    theme    map[string]string
+    features map[string]bool
+}
+
+const defaultPalette = "standard"
+
+func defaultTheme() map[string]string {
+    return map[string]string{
+        "accent":     "#ea580c",
+        "background": "#f8fafc",
+        "surface":    "#ffffff",
+        "text":       "#0f172a",
+        "muted_text": "#64748b",
+        "border":     "#e2e8f0",
+        "radius":     "0.5rem",
+    }
+}
+
+// uiPalettes are the palettes served to the SPA. The color-blind-safe one
+// uses the Okabe-Ito colors, distinguishable with any color vision
+// deficiency.
+var uiPalettes = map[string]Palette{
+    "standard": {
+        Description: "Red, yellow and green",
+        Priorities: map[string]PriorityStyle{
+            "high":   {Label: "HIGH", Symbol: "🔴", Background: "#fee2e2", Foreground: "#991b1b", Border: "#fca5a5"},
+            "medium": {Label: "MEDIUM", Symbol: "🟡", Background: "#fef9c3", Foreground: "#854d0e", Border: "#fde047"},
+            "low":    {Label: "LOW", Symbol: "🟢", Background: "#dcfce7", Foreground: "#166534", Border: "#86efac"},
+            "none":   {Label: "NONE", Symbol: "⚪", Background: "#f3f4f6", Foreground: "#1f2937", Border: "#d1d5db"},
+        },
+    },
+    "color_blind_safe": {
+        Description:    "Vermillion, orange and blue with distinct symbols",
+        ColorBlindSafe: true,
+        Priorities: map[string]PriorityStyle{
+            "high":   {Label: "HIGH", Symbol: "▲", Background: "#d55e00", Foreground: "#ffffff", Border: "#d55e00"},
+            "medium": {Label: "MEDIUM", Symbol: "◆", Background: "#e69f00", Foreground: "#000000", Border: "#e69f00"},
+            "low":    {Label: "LOW", Symbol: "▼", Background: "#0072b2", Foreground: "#ffffff", Border: "#0072b2"},
+            "none":   {Label: "NONE", Symbol: "○", Background: "#f3f4f6", Foreground: "#1f2937", Border: "#d1d5db"},
+        },
+    },
+    "high_contrast": {
+        Description:    "Black and white with distinct symbols",
+        ColorBlindSafe: true,
+        Priorities: map[string]PriorityStyle{
+            "high":   {Label: "HIGH", Symbol: "▲", Background: "#000000", Foreground: "#ffffff", Border: "#000000"},
+            "medium": {Label: "MEDIUM", Symbol: "◆", Background: "#ffffff", Foreground: "#000000", Border: "#000000"},
+            "low":    {Label: "LOW", Symbol: "▼", Background: "#ffffff", Foreground: "#000000", Border: "#6b7280"},
+            "none":   {Label: "NONE", Symbol: "○", Background: "#ffffff", Foreground: "#4b5563", Border: "#d1d5db"},
+        },
+    },
+}
+
+// parseAssignments parses a comma separated list of name=value pairs.
+func parseAssignments(s string) (map[string]string, error) {
+    values := make(map[string]string)
+    for _, pair := range strings.Split(s, ",") {
+        if pair = strings.TrimSpace(pair); pair == "" {
+            continue
+        }
+        name, value, ok := strings.Cut(pair, "=")
+        name = strings.TrimSpace(name)
+        if !ok || name == "" {
+            return nil, fmt.Errorf("%q is not a name=value pair", pair)
+        }
+        values[name] = strings.TrimSpace(value)
+    }
+    return values, nil
+}
+
+// loadUISettings reads the palette, theme tokens and feature flags set in
+// the environment.
+func loadUISettings() (uiSettings, error) {
+    settings := uiSettings{palette: getEnv(uiPaletteEnv, defaultPalette)}
+    if _, ok := uiPalettes[settings.palette]; !ok {
+        names := make([]string, 0, len(uiPalettes))
+        for name := range uiPalettes {
+            names = append(names, name)
+        }
+        sort.Strings(names)
+        return settings, fmt.Errorf("%s must be one of %s", uiPaletteEnv, strings.Join(names, ", "))
+    }
+    theme, err := parseAssignments(getEnv(uiThemeEnv, ""))
+    if err != nil {
+        return settings, fmt.Errorf("%s: %w", uiThemeEnv, err)
+    }
+    settings.theme = theme
+    flags, err := parseAssignments(getEnv(uiFeaturesEnv, ""))
+    if err != nil {
+        return settings, fmt.Errorf("%s: %w", uiFeaturesEnv, err)
+    }
+    settings.features = make(map[string]bool, len(flags))
+    for name, value := range flags {
+        enabled, err := strconv.ParseBool(value)
+        if err != nil {
+            return settings, fmt.Errorf("%s: %s must be true or false", uiFeaturesEnv, name)
+        }
+        settings.features[name] = enabled
+    }
+    return settings, nil
+}
+
+// features returns which features are available to the SPA, those the
+// environment switches on or off overriding what is configured.
+func (c *Container) features() map[string]bool {
+    features := map[string]bool{
+        "ai_analysis":     c.analyzer != nil,
+        "dashboard_links": c.publicURL != "",
+        "jira":            c.jira.Configured(),
+        "live_updates":    c.liveInterval > 0,
+        "qr_codes":        true,
+        "sign_in":         c.SignInConfigured(),
+        "slack_replies":   c.slackFeatureReady(slackFeatureReplies),
+    }
+    for name, enabled := range c.ui.features {
+        features[name] = enabled
+    }
+    return features
+}
+
+// DefaultUIConfig returns the UI configuration of the default theme and
+// palette with features.
+func DefaultUIConfig(features map[string]bool) UIConfig {
+    return UIConfig{
+        Theme:    defaultTheme(),
+        Palette:  defaultPalette,
+        Palettes: uiPalettes,
+        Features: features,
+    }
+}
+
+// GetUIConfig - Get the theme tokens, priority palettes and feature flags of the SPA
+func (c *Container) GetUIConfig(ctx echo.Context) error {
+    theme := defaultTheme()
+    for token, value := range c.ui.theme {
+        theme[token] = value
+    }
+    return ctx.JSON(http.StatusOK, UIConfig{
+        Theme:    theme,
+        Palette:  c.ui.palette,
+        Palettes: uiPalettes,
+        Features: c.features(),
+    })
+}
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+const displayNamePrecedenceKey = "display_name_precedence"
+
+// Profile fields that may be used as the name of a user.
+const (
+    nameFieldDisplayName    = "display_name"
+    nameFieldRealName       = "real_name"
+    nameFieldName           = "name"
+    nameFieldEmailLocalPart = "email_local_part"
+)
+
+var defaultDisplayNamePrecedence = []string{nameFieldDisplayName, nameFieldRealName, nameFieldName}
+
+var validNameFields = map[string]bool{
+    nameFieldDisplayName:    true,
+    nameFieldRealName:       true,
+    nameFieldName:           true,
+    nameFieldEmailLocalPart: true,
+}
+
+// directoryColumns are the columns accepted in a user directory CSV import.
+var directoryColumns = []string{"user_id", "display_name", "title", "team", "email"}
+
+// DirectoryEntry represents a row of the custom user directory overlaid on
+// Slack profiles
+type DirectoryEntry struct {
+    UserID      string `json:"user_id"`
+    DisplayName string `json:"display_name"`
+    Title       string `json:"title"`
+    Team        string `json:"team"`
+    Email       string `json:"email"`
+    Source      string `json:"source"`
+}
+
+// DisplayNameSettings is the body of the display name settings endpoints
+type DisplayNameSettings struct {
+    Precedence []string `json:"precedence"`
+}
+
+// loadDisplayNamePrecedence returns the configured precedence, or the default.
+func loadDisplayNamePrecedence(db *sql.DB) ([]string, error) {
+    var settings DisplayNameSettings
+    found, err := getSetting(db, displayNamePrecedenceKey, &settings)
+    if err != nil || !found || len(settings.Precedence) == 0 {
+        return defaultDisplayNamePrecedence, err
+    }
+    return settings.Precedence, nil
+}
+
+// loadDirectoryEntries returns the directory entries of the given users.
+func loadDirectoryEntries(db *sql.DB, userIDs []string) (map[string]DirectoryEntry, error) {
+    entries := make(map[string]DirectoryEntry)
+    if len(userIDs) == 0 {
+        return entries, nil
+    }
+
+    placeholders := make([]string, len(userIDs))
+    args := make([]interface{}, len(userIDs))
+    for i, userID := range userIDs {
+        placeholders[i] = fmt.Sprintf("$%d", i+1)
+        args[i] = userID
+    }
+
+    rows, err := db.Query(fmt.Sprintf(`
+        SELECT user_id, display_name, title, team, email, source
+        FROM user_directory
+        WHERE user_id IN (%s)
+    `, strings.Join(placeholders, ",")), args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var entry DirectoryEntry
+        if err := rows.Scan(&entry.UserID, &entry.DisplayName, &entry.Title,
+            &entry.Team, &entry.Email, &entry.Source); err != nil {
+            return nil, err
+        }
+        entries[entry.UserID] = entry
+    }
+    return entries, rows.Err()
+}
+
+// resolveDisplayName picks the first non-empty field of the precedence list.
+// Directory values take over the Slack profile values they overlay.
+func resolveDisplayName(profile UserProfile, entry DirectoryEntry, precedence []string) string {
+    displayName := profile.DisplayName
+    if entry.DisplayName != "" {
+        displayName = entry.DisplayName
+    }
+
+    for _, field := range precedence {
+        var value string
+        switch field {
+        case nameFieldDisplayName:
+            value = displayName
+        case nameFieldRealName:
+            value = profile.RealName
+        case nameFieldName:
+            value = profile.Name
+        case nameFieldEmailLocalPart:
+            value, _, _ = strings.Cut(entry.Email, "@")
+        }
+        if value = strings.TrimSpace(value); value != "" {
+            return value
+        }
+    }
+    return profile.UserID
+}
+
+// applyDirectory fills the directory and resolved name fields of profiles.
+func applyDirectory(db *sql.DB, profiles []UserProfile) error {
+    userIDs := make([]string, len(profiles))
+    for i, profile := range profiles {
+        userIDs[i] = profile.UserID
+    }
+
+    precedence, err := loadDisplayNamePrecedence(db)
+    if err != nil {
+        return err
+    }
+    entries, err := loadDirectoryEntries(db, userIDs)
+    if err != nil {
+        return err
+    }
+
+    for i := range profiles {
+        entry := entries[profiles[i].UserID]
+        profiles[i].ResolvedName = resolveDisplayName(profiles[i], entry, precedence)
+        profiles[i].Title = entry.Title
+        profiles[i].Team = entry.Team
+        profiles[i].Email = entry.Email
+    }
+    return nil
+}
+
+// resolveUserNames returns the display name of each user, for messages sent
+// on behalf of the dashboard. Unknown users resolve to their ID.
+func (c *Container) resolveUserNames(ctx context.Context, userIDs []string) (map[string]string, error) {
+    names := make(map[string]string, len(userIDs))
+    for _, userID := range userIDs {
+        names[userID] = userID
+    }
+    if len(userIDs) == 0 {
+        return names, nil
+    }
+
+    profiles, err := c.users.Profiles(ctx, userIDs)
+    if err != nil {
+        return names, err
+    }
+    for _, profile := range profiles {
+        names[profile.UserID] = profile.ResolvedName
+    }
+    return names, nil
+}
+
+// GetUserDirectory - List the custom user directory entries
+func (c *Container) GetUserDirectory(ctx echo.Context) error {
+    entries, err := c.directory.DirectoryEntries(ctx.Request().Context(), ctx.QueryParam("source"))
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query user directory")
+    }
+
+    return ctx.JSON(http.StatusOK, entries)
+}
+
+// GetDisplayNameSettings - Get the profile field precedence used for user names
+func (c *Container) GetDisplayNameSettings(ctx echo.Context) error {
+    precedence, err := c.directory.DisplayNamePrecedence(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to load display name settings")
+    }
+    return ctx.JSON(http.StatusOK, DisplayNameSettings{Precedence: precedence})
+}
+
+// PutDisplayNameSettings - Set the profile field precedence used for user names
+func (c *Container) PutDisplayNameSettings(ctx echo.Context) error {
+    var settings DisplayNameSettings
+    if err := json.NewDecoder(ctx.Request().Body).Decode(&settings); err != nil {
+        return apierror.New(http.StatusBadRequest, "Invalid request body")
+    }
+    if len(settings.Precedence) == 0 {
+        return apierror.New(http.StatusBadRequest, "precedence must list at least one field")
+    }
+    for _, field := range settings.Precedence {
+        if !validNameFields[field] {
+            return apierror.New(http.StatusBadRequest, "unknown field "+field+", expected display_name, real_name, name or email_local_part")
+        }
+    }
+
+    if err := c.directory.SetDisplayNamePrecedence(ctx.Request().Context(), settings.Precedence, actorFrom(ctx)); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to store display name settings")
+    }
+    return ctx.JSON(http.StatusOK, settings)
+}
+
+// ImportUserDirectory - Import a CSV user directory overlaid on Slack profiles
+//
+// The CSV must have a header row with user_id and any of display_name, title,
+// team and email. With replace=true, entries of the same source missing from
+// the file are removed.
+func (c *Container) ImportUserDirectory(ctx echo.Context) error {
+    source := ctx.QueryParam("source")
+    if source == "" {
+        source = "csv"
+    }
+    replace, _ := strconv.ParseBool(ctx.QueryParam("replace"))
+
+    reader := csv.NewReader(io.LimitReader(ctx.Request().Body, 10<<20))
+    reader.TrimLeadingSpace = true
+    header, err := reader.Read()
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, "expected a CSV body with a header row")
+    }
+
+    index := make(map[string]int)
+    for i, column := range header {
+        index[strings.ToLower(strings.TrimSpace(column))] = i
+    }
+    if _, ok := index["user_id"]; !ok {
+        return apierror.New(http.StatusBadRequest, "CSV header must contain user_id")
+    }
+
+    entries := []DirectoryEntry{}
+    for line := 2; ; line++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return apierror.Newf(http.StatusBadRequest, "invalid CSV at line %d: %v", line, err)
+        }
+        field := func(name string) string {
+            if i, ok := index[name]; ok && i < len(record) {
+                return strings.TrimSpace(record[i])
+            }
+            return ""
+        }
+        entry := DirectoryEntry{
+            UserID:      field("user_id"),
+            DisplayName: field("display_name"),
+            Title:       field("title"),
+            Team:        field("team"),
+            Email:       field("email"),
+            Source:      source,
+        }
+        if entry.UserID == "" {
+            continue
+        }
+        entries = append(entries, entry)
+    }
+
+    if err := c.directory.ImportDirectory(ctx.Request().Context(), source, entries, replace); err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to import user directory")
+    }
+
+    c.audit(ctx.Request().Context(), actorFrom(ctx), "user_directory.import", "", "", map[string]interface{}{
+        "source":  source,
+        "entries": len(entries),
+        "replace": replace,
+    })
+
+    return ctx.JSON(http.StatusOK, map[string]interface{}{
+        "imported": len(entries),
+        "source":   source,
+    })
+}
+
+// userEmail returns the email address of a Slack user in the directory, ""
+// when unknown.
+func (c *Container) userEmail(ctx context.Context, userID string) (string, error) {
+    profiles, err := c.users.Profiles(ctx, []string{userID})
+    if err != nil || len(profiles) == 0 {
+        return "", err
+    }
+    return profiles[0].Email, nil
+}
+
+func (s postgresStore) DirectoryEntries(ctx context.Context, source string) ([]DirectoryEntry, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    query := "SELECT user_id, display_name, title, team, email, source FROM user_directory"
+    args := []interface{}{}
+    if source != "" {
+        args = append(args, source)
+        query += " WHERE source = $1"
+    }
+    query += " ORDER BY user_id"
+
+    rows, err := db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    entries := []DirectoryEntry{}
+    for rows.Next() {
+        var entry DirectoryEntry
+        if err := rows.Scan(&entry.UserID, &entry.DisplayName, &entry.Title,
+            &entry.Team, &entry.Email, &entry.Source); err != nil {
+            continue
+        }
+        entries = append(entries, entry)
+    }
+    return entries, rows.Err()
+}
+
+func (s postgresStore) ImportDirectory(ctx context.Context, source string, entries []DirectoryEntry, replace bool) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    if replace {
+        if _, err := tx.ExecContext(ctx, "DELETE FROM user_directory WHERE source = $1", source); err != nil {
+            return err
+        }
+    }
+    for _, entry := range entries {
+        _, err := tx.ExecContext(ctx, `
+            INSERT INTO user_directory (user_id, display_name, title, team, email, source, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6, NOW())
+            ON CONFLICT (user_id) DO UPDATE SET
+                display_name = EXCLUDED.display_name,
+                title = EXCLUDED.title,
+                team = EXCLUDED.team,
+                email = EXCLUDED.email,
+                source = EXCLUDED.source,
+                updated_at = EXCLUDED.updated_at
+        `, entry.UserID, entry.DisplayName, entry.Title, entry.Team, entry.Email, entry.Source)
+        if err != nil {
+            return fmt.Errorf("import user %s: %w", entry.UserID, err)
+        }
+    }
+    return tx.Commit()
+}
+
+func (s postgresStore) DisplayNamePrecedence(ctx context.Context) ([]string, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return loadDisplayNamePrecedence(db)
+}
+
+func (s postgresStore) SetDisplayNamePrecedence(ctx context.Context, precedence []string, actor string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    return putSetting(db, displayNamePrecedenceKey, DisplayNameSettings{Precedence: precedence}, actor)
+}
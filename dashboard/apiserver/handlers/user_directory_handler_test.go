@@ -0,0 +1,61 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+)
+
+func TestUserDirectoryOverlaysProfiles(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddProfile(UserProfile{UserProfile: domain.UserProfile{UserID: "U1", Name: "ada", DisplayName: "Ada", RealName: "Ada Lovelace"}})
+    store.AddProfile(UserProfile{UserProfile: domain.UserProfile{UserID: "U2", Name: "alan", RealName: "Alan Turing"}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    admin := &auth.Principal{UserID: "U9", Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+
+    csv := "user_id,display_name,team,email\nU1,Countess,Engines,ada@example.com\n,skipped,,\nU2,,Codebreaking,alan@example.com\n"
+    rec := serveAs(c, admin, http.MethodPost, "/api/admin/user-directory", "/api/admin/user-directory?source=hr", csv, c.ImportUserDirectory)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("importing got status %d: %s", rec.Code, rec.Body.String())
+    }
+
+    rec = serveAs(c, admin, http.MethodGet, "/api/admin/user-directory", "/api/admin/user-directory?source=hr", "", c.GetUserDirectory)
+    var entries []DirectoryEntry
+    decodeResponse(t, rec, &entries)
+    if len(entries) != 2 || entries[0].UserID != "U1" || entries[1].Team != "Codebreaking" {
+        t.Fatalf("got entries %+v", entries)
+    }
+    rec = serveAs(c, admin, http.MethodGet, "/api/admin/user-directory", "/api/admin/user-directory?source=csv", "", c.GetUserDirectory)
+    if rec.Body.String() != "[]\n" {
+        t.Fatalf("got %s for another source, want no entries", rec.Body.String())
+    }
+
+    rec = serveAs(c, admin, http.MethodPut, "/api/admin/settings/display-name", "/api/admin/settings/display-name",
+        `{"precedence": ["email_local_part", "real_name"]}`, c.PutDisplayNameSettings)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("setting the precedence got status %d: %s", rec.Code, rec.Body.String())
+    }
+    profiles, err := store.Profiles(context.Background(), []string{"U1", "U2"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(profiles) != 2 || profiles[0].ResolvedName != "ada" || profiles[0].Team != "Engines" || profiles[1].ResolvedName != "alan" {
+        t.Fatalf("got profiles %+v, want names from the directory emails", profiles)
+    }
+
+    // Replacing drops entries of the source missing from the file
+    rec = serveAs(c, admin, http.MethodPost, "/api/admin/user-directory", "/api/admin/user-directory?source=hr&replace=true", "user_id,title\nU2,Fellow\n", c.ImportUserDirectory)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("replacing got status %d: %s", rec.Code, rec.Body.String())
+    }
+    entries, err = store.DirectoryEntries(context.Background(), "hr")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(entries) != 1 || entries[0].UserID != "U2" || entries[0].Title != "Fellow" {
+        t.Fatalf("got entries %+v after replacing", entries)
+    }
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+    "cmp"
+    "context"
+    "errors"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/slack"
+)
+
+// userProfileSyncInterval is how often user profiles are synced from Slack.
+const userProfileSyncInterval = time.Hour
+
+// userProfilePageSize is the number of users fetched per users.list call,
+// the most Slack recommends.
+const userProfilePageSize = 200
+
+// userProfileMaxRetries bounds the retries of a failing page before the
+// sync gives up until the next run. Rate limited calls are retried without
+// counting, the budget pausing them.
+const userProfileMaxRetries = 3
+
+// RunUserProfileSync periodically refreshes the stored user profiles from
+// Slack, so names and avatars of stakeholders follow their changes, until
+// ctx is cancelled. It does nothing without a bot token.
+func (c *Container) RunUserProfileSync(ctx context.Context) {
+    if !c.slack.Configured() {
+        return
+    }
+
+    ticker := time.NewTicker(userProfileSyncInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.syncUserProfiles(ctx); err != nil && ctx.Err() == nil {
+            c.logger.Warnf("failed to sync user profiles: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// syncUserProfiles upserts the profile of every member of the workspace,
+// page by page. Users Slack lists as deleted are marked deactivated and
+// reactivated when they come back; profiles of users it does not list, such
+// as external users of shared channels, are left alone.
+func (c *Container) syncUserProfiles(ctx context.Context) error {
+    if !c.slackFeatureReady(slackFeatureUserProfiles) {
+        return nil
+    }
+    cursor, retries, synced, deactivated := "", 0, 0, 0
+    for {
+        page, err := c.slack.UsersList(ctx, workspaceFrom(ctx), cursor, userProfilePageSize)
+        if err != nil {
+            if ctx.Err() != nil {
+                return ctx.Err()
+            }
+            var rateLimited *slack.RateLimitedError
+            if errors.As(err, &rateLimited) {
+                continue
+            }
+            if retries++; retries > userProfileMaxRetries {
+                return err
+            }
+            select {
+            case <-ctx.Done():
+                return ctx.Err()
+            case <-time.After(time.Duration(retries*retries) * time.Second):
+            }
+            continue
+        }
+        retries = 0
+
+        for _, user := range page.Members {
+            if err := c.users.SaveProfile(ctx, slackUserProfile(user)); err != nil {
+                return err
+            }
+            synced++
+            if user.Deleted {
+                deactivated++
+            }
+        }
+        if cursor = page.ResponseMetadata.NextCursor; cursor == "" {
+            break
+        }
+    }
+    c.logger.Debugf("synced %d user profiles, %d deactivated", synced, deactivated)
+    return nil
+}
+
+// slackUserProfile returns the names and avatars of a user, falling back to
+// their username for missing names like the collector does.
+func slackUserProfile(user slack.User) UserProfile {
+    return UserProfile{UserProfile: domain.UserProfile{
+        UserID:          user.ID,
+        Name:            user.Name,
+        DisplayName:     cmp.Or(user.Profile.DisplayName, user.Name),
+        RealName:        cmp.Or(user.Profile.RealName, user.Name),
+        ProfileImageURL: cmp.Or(user.Profile.ImageOriginal, user.Profile.Image512),
+        ProfileImage24:  user.Profile.Image24,
+        ProfileImage32:  user.Profile.Image32,
+        ProfileImage48:  user.Profile.Image48,
+        ProfileImage72:  user.Profile.Image72,
+        Deactivated:     user.Deleted,
+    }}
+}
+
+func (s postgresStore) SaveProfile(ctx context.Context, profile UserProfile) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    _, err = db.ExecContext(ctx, `
+        INSERT INTO user_profiles (user_id, name, display_name, real_name, profile_image_url,
+            profile_image_24, profile_image_32, profile_image_48, profile_image_72, last_updated, deactivated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), CASE WHEN $10::BOOLEAN THEN NOW() END)
+        ON CONFLICT (user_id) DO UPDATE SET
+            name = EXCLUDED.name,
+            display_name = EXCLUDED.display_name,
+            real_name = EXCLUDED.real_name,
+            profile_image_url = EXCLUDED.profile_image_url,
+            profile_image_24 = EXCLUDED.profile_image_24,
+            profile_image_32 = EXCLUDED.profile_image_32,
+            profile_image_48 = EXCLUDED.profile_image_48,
+            profile_image_72 = EXCLUDED.profile_image_72,
+            last_updated = EXCLUDED.last_updated,
+            deactivated_at = CASE WHEN $10 THEN COALESCE(user_profiles.deactivated_at, NOW()) END
+    `, profile.UserID, profile.Name, profile.DisplayName, profile.RealName, profile.ProfileImageURL,
+        profile.ProfileImage24, profile.ProfileImage32, profile.ProfileImage48, profile.ProfileImage72, profile.Deactivated)
+    return err
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+    "context"
+    "testing"
+
+    "dashboard/apiserver/slack"
+)
+
+func TestSyncUserProfilesFallsBackToUsername(t *testing.T) {
+    store := NewMemoryStore()
+    slackClient := &MemorySlack{Users: []slack.User{
+        {ID: "U1", Name: "ada", Profile: slack.UserProfile{DisplayName: "Ada", Image512: "https://example.com/ada.png"}},
+        {ID: "U2", Name: "bob", Deleted: true},
+    }}
+    c := newTestContainer(t, store, slackClient)
+
+    if err := c.syncUserProfiles(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    profiles, err := store.Profiles(context.Background(), []string{"U1", "U2"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(profiles) != 2 {
+        t.Fatalf("got profiles %+v", profiles)
+    }
+    if ada := profiles[0]; ada.DisplayName != "Ada" || ada.RealName != "ada" || ada.ProfileImageURL != "https://example.com/ada.png" || ada.Deactivated {
+        t.Fatalf("got profile %+v", ada)
+    }
+    if bob := profiles[1]; bob.DisplayName != "bob" || !bob.Deactivated {
+        t.Fatalf("got profile %+v, want the deleted user deactivated", bob)
+    }
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+
+    "github.com/lib/pq"
+)
+
+// UserStats summarizes a user's involvement in tracked threads
+type UserStats struct {
+    OpenThreads     int `json:"open_threads"`
+    AssignedThreads int `json:"assigned_threads"`
+    // AvgResponseSeconds is the average time between a thread the user
+    // started and the first reply by someone else, nil when none of them got
+    // one. Only replies stored in thread_messages are taken into account
+    AvgResponseSeconds *float64 `json:"avg_response_seconds"`
+}
+
+func (s postgresStore) UserStats(ctx context.Context, userIDs []string) (map[string]*UserStats, error) {
+    stats := make(map[string]*UserStats, len(userIDs))
+    for _, userID := range userIDs {
+        stats[userID] = &UserStats{}
+    }
+    if len(userIDs) == 0 {
+        return stats, nil
+    }
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    responseTotals := make(map[string]float64)
+    responseCounts := make(map[string]int)
+    rows, err := db.QueryContext(ctx, `
+        SELECT t.user_id,
+               COUNT(*) FILTER (WHERE t.status = 'open'),
+               COALESCE(SUM(EXTRACT(EPOCH FROM r.first_reply - t.created_at)), 0),
+               COUNT(r.first_reply)
+        FROM threads t
+        JOIN channels ch ON ch.channel_id = t.channel_id
+        LEFT JOIN LATERAL (
+            SELECT MIN(m.posted_at) AS first_reply
+            FROM thread_messages m
+            WHERE m.channel_id = t.channel_id AND m.thread_ts = t.thread_ts
+              AND m.ts <> t.thread_ts AND m.user_id <> t.user_id
+        ) r ON TRUE
+        WHERE t.user_id = ANY($1)
+        GROUP BY t.user_id
+    `, pq.Array(userIDs))
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        var userID string
+        var open, replied int
+        var responseTotal float64
+        if err := rows.Scan(&userID, &open, &responseTotal, &replied); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        if userStats, ok := stats[userID]; ok {
+            userStats.OpenThreads += open
+            responseTotals[userID] += responseTotal
+            responseCounts[userID] += replied
+        }
+    }
+    rows.Close()
+
+    // Stakeholders are stored as a JSON array of user IDs. strpos matches
+    // the quoted ID literally, unlike LIKE which would treat % and _ in it
+    // as wildcards
+    rows, err = db.QueryContext(ctx, `
+        SELECT u.user_id, COUNT(*)
+        FROM threads t
+        JOIN channels ch ON ch.channel_id = t.channel_id,
+        unnest($1::text[]) AS u(user_id)
+        WHERE t.status = 'open' AND strpos(t.ai_stakeholders, '"' || u.user_id || '"') > 0
+        GROUP BY u.user_id
+    `, pq.Array(userIDs))
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        var userID string
+        var assigned int
+        if err := rows.Scan(&userID, &assigned); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        if userStats, ok := stats[userID]; ok {
+            userStats.AssignedThreads += assigned
+        }
+    }
+    rows.Close()
+
+    for userID, count := range responseCounts {
+        if count > 0 {
+            avg := responseTotals[userID] / float64(count)
+            stats[userID].AvgResponseSeconds = &avg
+        }
+    }
+    return stats, nil
+}
+
+// includesStats reports whether a comma-separated include parameter asks for
+// stats.
+func includesStats(include string) bool {
+    for _, part := range strings.Split(include, ",") {
+        if strings.TrimSpace(part) == "stats" {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "net/http"
+    "strconv"
+
+    "dashboard/apiserver/apierror"
+
+    "github.com/labstack/echo/v4"
+)
+
+// UserThreads is a page of the backlog of a user: the open threads they
+// authored or were identified as a stakeholder of, counted by priority.
+type UserThreads struct {
+    UserID string   `json:"user_id"`
+    Items  []Thread `json:"items"`
+    // Total is the number of threads in the backlog
+    Total int `json:"total"`
+    // ByPriority counts the whole backlog by priority: high, medium, low
+    // and none
+    ByPriority map[string]int `json:"by_priority"`
+}
+
+// GetUserThreads - Get the backlog of a user: the open threads they authored or are an AI identified stakeholder of, with counts by priority
+func (c *Container) GetUserThreads(ctx echo.Context) error {
+    userID := ctx.Param("user_id")
+    if userID == assigneeMe {
+        if userID = actorFrom(ctx); userID == "anonymous" {
+            return apierror.New(http.StatusUnauthorized, "user_id=me requires a signed in user")
+        }
+    }
+
+    limit := 10
+    if parsed, err := strconv.Atoi(ctx.QueryParam("limit")); err == nil && parsed > 0 {
+        limit = parsed
+    }
+    if limit > maxThreadPageSize {
+        limit = maxThreadPageSize
+    }
+    offset := 0
+    if offsetStr := ctx.QueryParam("offset"); offsetStr != "" {
+        parsed, err := strconv.Atoi(offsetStr)
+        if err != nil || parsed < 0 {
+            return apierror.New(http.StatusBadRequest, "offset must be a non-negative integer")
+        }
+        offset = parsed
+    }
+    sort, order, err := parseThreadSort(ctx)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+
+    // The backlog narrows down like thread lists, but only has open threads
+    filters, err := parseThreadFilters(ctx)
+    if err != nil {
+        return apierror.New(http.StatusBadRequest, err.Error())
+    }
+    filters.statuses = []string{string(statusOpen)}
+    filters.backlogOf = userID
+
+    backlog := UserThreads{UserID: userID}
+    list := threadList{filters: filters, sort: sort, order: order, limit: limit, offset: offset}
+    backlog.Items, backlog.Total, err = c.threadLists.ListThreads(ctx.Request().Context(), list)
+    if err == nil {
+        backlog.ByPriority, err = c.threadLists.CountByPriority(ctx.Request().Context(), filters)
+    }
+    if err == errGroupNotFound {
+        return apierror.New(http.StatusNotFound, "Channel group not found")
+    }
+    if err != nil {
+        c.logger.Errorf("failed to query threads of %s: %v", userID, err)
+        return apierror.New(http.StatusInternalServerError, "Failed to query threads")
+    }
+    return ctx.JSON(http.StatusOK, backlog)
+}
+
+func (s postgresStore) CountByPriority(ctx context.Context, filters threadFilters) (map[string]int, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+    q, err := threadListQuery(db, filters)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(q.channels) == 0 {
+        return map[string]int{"high": 0, "medium": 0, "low": 0, "none": 0}, nil
+    }
+    return countByPriority(ctx, db, q)
+}
+
+// countByPriority counts the threads selected by q by their calibrated
+// priority.
+func countByPriority(ctx context.Context, db *sql.DB, q *threadQuery) (map[string]int, error) {
+    total := map[string]int{"high": 0, "medium": 0, "low": 0, "none": 0}
+    rows, err := db.QueryContext(ctx, "SELECT u.priority, COUNT(*) FROM "+q.from+" GROUP BY u.priority", q.args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var priority string
+        var count int
+        if err := rows.Scan(&priority, &count); err != nil {
+            return nil, err
+        }
+        total[priority] += count
+    }
+    return total, rows.Err()
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/domain"
+)
+
+func TestUserBacklogCountsByPriority(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddChannel(ChannelSummary{Channel: domain.Channel{ChannelID: "C1", ChannelName: "support", TextIndexing: true}})
+    now := time.Now()
+    for _, thread := range []domain.Thread{
+        {ChannelID: "C1", ThreadTS: "1700000000.000100", UserID: "U1", Status: statusOpen, LatestReply: now},
+        {ChannelID: "C1", ThreadTS: "1700000000.000200", UserID: "U1", Status: statusOpen, LatestReply: now.Add(-time.Hour)},
+        {ChannelID: "C1", ThreadTS: "1700000000.000300", UserID: "U1", Status: statusResolved, LatestReply: now},
+        {ChannelID: "C1", ThreadTS: "1700000000.000400", UserID: "U2", Status: statusOpen, LatestReply: now},
+    } {
+        store.AddThread(StoredThread{Thread: thread})
+    }
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/users/:user_id/threads", "/api/users/U1/threads?limit=1", c.GetUserThreads)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var backlog UserThreads
+    decodeResponse(t, rec, &backlog)
+    // The open threads of U1, a page of one counted in full
+    if backlog.Total != 2 || len(backlog.Items) != 1 || backlog.ByPriority["none"] != 2 {
+        t.Fatalf("got backlog %+v", backlog)
+    }
+
+    rec = serveRequest(t, c, http.MethodGet, "/api/users/:user_id/threads", "/api/users/U1/threads?group=missing", c.GetUserThreads)
+    if rec.Code != http.StatusNotFound {
+        t.Fatalf("unknown channel group got status %d", rec.Code)
+    }
+}
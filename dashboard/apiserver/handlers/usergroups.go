@@ -0,0 +1,326 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+// usergroupSyncInterval is how often usergroups and their members are
+// synced from Slack.
+const usergroupSyncInterval = time.Hour
+
+// errUsergroupNotFound is returned for usergroups not synced from Slack.
+var errUsergroupNotFound = errors.New("usergroup not found")
+
+// Usergroup is a Slack usergroup with its members as of the latest sync
+type Usergroup struct {
+    ID       string    `json:"id"`
+    Handle   string    `json:"handle"`
+    Name     string    `json:"name"`
+    Members  []string  `json:"members"`
+    SyncedAt time.Time `json:"synced_at"`
+}
+
+// UsergroupList is the response of GetUsergroups
+type UsergroupList struct {
+    Usergroups []Usergroup `json:"usergroups"`
+}
+
+// UsergroupStats counts the open threads of a usergroup. OpenThreads counts
+// each thread once, however it involves the group
+type UsergroupStats struct {
+    ID      string `json:"id"`
+    Handle  string `json:"handle"`
+    Name    string `json:"name"`
+    Members int    `json:"members"`
+    // AssignedThreads are assigned to the group itself
+    AssignedThreads int `json:"assigned_threads"`
+    // StakeholderThreads list the group as a stakeholder
+    StakeholderThreads int `json:"stakeholder_threads"`
+    // MemberThreads are assigned to or claimed by a member of the group
+    MemberThreads int `json:"member_threads"`
+    OpenThreads   int `json:"open_threads"`
+}
+
+// UsergroupStatsResponse is the response of GetUsergroupStats
+type UsergroupStatsResponse struct {
+    Usergroups []UsergroupStats `json:"usergroups"`
+}
+
+// isUsergroupRef reports whether an assignee or stakeholder names a
+// usergroup, by @handle or ID.
+func isUsergroupRef(ref string) bool {
+    return strings.HasPrefix(ref, "@") || slack.IsUsergroupID(ref)
+}
+
+// resolveAssignee returns who to record as the assignee of threads: a user
+// as given, or the ID of the usergroup named by @handle or ID.
+func (c *Container) resolveAssignee(ctx context.Context, assignee string) (string, error) {
+    if assignee == "" || !isUsergroupRef(assignee) {
+        return assignee, nil
+    }
+    group, err := c.users.Usergroup(ctx, assignee)
+    return group.ID, err
+}
+
+// usergroupRefs returns SQL selecting the values of the TEXT array bound as
+// param, with the ID of the usergroups named by @handle in place of their
+// handle.
+func usergroupRefs(param string) string {
+    return fmt.Sprintf(`(SELECT COALESCE(g.usergroup_id, r.ref)
+        FROM unnest(%s::TEXT[]) r(ref)
+        LEFT JOIN slack_usergroups g ON '@' || g.handle = r.ref)`, param)
+}
+
+// usergroupMembers returns the members of every usergroup by its ID.
+func usergroupMembers(ctx context.Context, db *sql.DB) (map[string][]string, error) {
+    rows, err := db.QueryContext(ctx, "SELECT usergroup_id, user_id FROM slack_usergroup_members ORDER BY usergroup_id, user_id")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    members := make(map[string][]string)
+    for rows.Next() {
+        var groupID, userID string
+        if err := rows.Scan(&groupID, &userID); err != nil {
+            continue
+        }
+        members[groupID] = append(members[groupID], userID)
+    }
+    return members, rows.Err()
+}
+
+// RunUsergroupSync periodically syncs the usergroups of the workspace and
+// their members from Slack, so that threads can be assigned to them and
+// their members reminded, until ctx is cancelled. It does nothing without
+// a bot token.
+func (c *Container) RunUsergroupSync(ctx context.Context) {
+    if !c.slack.Configured() {
+        return
+    }
+
+    ticker := time.NewTicker(usergroupSyncInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.syncUsergroups(ctx); err != nil && ctx.Err() == nil {
+            c.logger.Warnf("failed to sync usergroups: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// syncUsergroups replaces the stored usergroups with those of Slack.
+// Disabled and deleted usergroups are forgotten, threads assigned to them
+// keep their ID.
+func (c *Container) syncUsergroups(ctx context.Context) error {
+    if !c.slackFeatureReady(slackFeatureUsergroups) {
+        return nil
+    }
+    groups, err := c.slack.UsergroupsList(ctx, workspaceFrom(ctx))
+    if err != nil {
+        return err
+    }
+    return c.users.ReplaceUsergroups(ctx, groups)
+}
+
+// GetUsergroups - List the Slack usergroups threads can be assigned to, with their members
+func (c *Container) GetUsergroups(ctx echo.Context) error {
+    groups, err := c.users.Usergroups(ctx.Request().Context())
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query usergroups")
+    }
+    return ctx.JSON(http.StatusOK, UsergroupList{Usergroups: groups})
+}
+
+// GetUsergroupStats - Count the open threads of each usergroup, assigned to it, naming it as a stakeholder or owned by its members
+func (c *Container) GetUsergroupStats(ctx echo.Context) error {
+    groups, err := c.stats.UsergroupStats(ctx.Request().Context())
+    if err != nil {
+        c.logger.Errorf("failed to count usergroup threads: %v", err)
+        return apierror.New(http.StatusInternalServerError, "Failed to query usergroups")
+    }
+
+    resp := UsergroupStatsResponse{Usergroups: groups}
+    sort.Slice(resp.Usergroups, func(i, j int) bool {
+        if resp.Usergroups[i].OpenThreads != resp.Usergroups[j].OpenThreads {
+            return resp.Usergroups[i].OpenThreads > resp.Usergroups[j].OpenThreads
+        }
+        return resp.Usergroups[i].Handle < resp.Usergroups[j].Handle
+    })
+    return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s postgresStore) UsergroupStats(ctx context.Context) ([]UsergroupStats, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    stats := map[string]*UsergroupStats{}
+    rows, err := db.QueryContext(ctx, `
+        SELECT g.usergroup_id, g.handle, g.name,
+               (SELECT COUNT(*) FROM slack_usergroup_members m WHERE m.usergroup_id = g.usergroup_id)
+        FROM slack_usergroups g
+    `)
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        var group UsergroupStats
+        if err := rows.Scan(&group.ID, &group.Handle, &group.Name, &group.Members); err != nil {
+            continue
+        }
+        stats[group.ID] = &group
+    }
+    rows.Close()
+
+    channels, err := trackedChannels(ctx, db)
+    if err != nil {
+        return nil, err
+    }
+    for _, ch := range channels {
+        rows, err := db.QueryContext(ctx, `
+            SELECT g.usergroup_id,
+                   COUNT(*) FILTER (WHERE assigned),
+                   COUNT(*) FILTER (WHERE stakeholder),
+                   COUNT(*) FILTER (WHERE member),
+                   COUNT(*) FILTER (WHERE assigned OR stakeholder OR member)
+            FROM slack_usergroups g
+            CROSS JOIN LATERAL (
+                SELECT a.assignee = g.usergroup_id AS assigned,
+                       strpos(t.ai_stakeholders, '"' || g.usergroup_id || '"') > 0 AS stakeholder,
+                       EXISTS (SELECT 1 FROM slack_usergroup_members m
+                               WHERE m.usergroup_id = g.usergroup_id AND (m.user_id = a.assignee OR m.user_id = tc.user_id)) AS member
+                FROM threads t
+                LEFT JOIN thread_assignments a ON a.channel_id = t.channel_id AND a.thread_ts = t.thread_ts
+                LEFT JOIN thread_claims tc ON tc.channel_id = t.channel_id AND tc.thread_ts = t.thread_ts
+                WHERE t.channel_id = $1 AND t.status = 'open'
+            ) involved
+            GROUP BY g.usergroup_id
+        `, ch.ID)
+        if err != nil {
+            s.c.logger.Warnf("failed to count usergroup threads of channel %s: %v", ch.ID, err)
+            continue
+        }
+        for rows.Next() {
+            var id string
+            var assigned, stakeholder, member, open int
+            if err := rows.Scan(&id, &assigned, &stakeholder, &member, &open); err != nil {
+                continue
+            }
+            if group, ok := stats[id]; ok {
+                group.AssignedThreads += assigned
+                group.StakeholderThreads += stakeholder
+                group.MemberThreads += member
+                group.OpenThreads += open
+            }
+        }
+        rows.Close()
+    }
+
+    groups := make([]UsergroupStats, 0, len(stats))
+    for _, group := range stats {
+        groups = append(groups, *group)
+    }
+    return groups, nil
+}
+
+func (s postgresStore) Usergroup(ctx context.Context, ref string) (Usergroup, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return Usergroup{}, err
+    }
+
+    var group Usergroup
+    err = db.QueryRowContext(ctx, `
+        SELECT usergroup_id, handle, name, synced_at FROM slack_usergroups WHERE usergroup_id = $1 OR '@' || handle = $1
+    `, ref).Scan(&group.ID, &group.Handle, &group.Name, &group.SyncedAt)
+    if err == sql.ErrNoRows {
+        return Usergroup{}, errUsergroupNotFound
+    }
+    if err != nil {
+        return Usergroup{}, err
+    }
+
+    rows, err := db.QueryContext(ctx, "SELECT user_id FROM slack_usergroup_members WHERE usergroup_id = $1 ORDER BY user_id", group.ID)
+    if err != nil {
+        return Usergroup{}, err
+    }
+    defer rows.Close()
+
+    group.Members = []string{}
+    for rows.Next() {
+        var userID string
+        if err := rows.Scan(&userID); err != nil {
+            continue
+        }
+        group.Members = append(group.Members, userID)
+    }
+    return group, rows.Err()
+}
+
+func (s postgresStore) ReplaceUsergroups(ctx context.Context, groups []slack.Usergroup) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    ids := make([]string, len(groups))
+    for i, group := range groups {
+        ids[i] = group.ID
+        _, err := tx.ExecContext(ctx, `
+            INSERT INTO slack_usergroups (usergroup_id, handle, name, synced_at)
+            VALUES ($1, $2, $3, NOW())
+            ON CONFLICT (usergroup_id) DO UPDATE SET
+                handle = EXCLUDED.handle,
+                name = EXCLUDED.name,
+                synced_at = EXCLUDED.synced_at
+        `, group.ID, group.Handle, group.Name)
+        if err != nil {
+            return err
+        }
+        _, err = tx.ExecContext(ctx, `
+            DELETE FROM slack_usergroup_members WHERE usergroup_id = $1 AND NOT user_id = ANY($2)
+        `, group.ID, pq.Array(group.Users))
+        if err != nil {
+            return err
+        }
+        _, err = tx.ExecContext(ctx, `
+            INSERT INTO slack_usergroup_members (usergroup_id, user_id)
+            SELECT $1, unnest($2::TEXT[])
+            ON CONFLICT (usergroup_id, user_id) DO NOTHING
+        `, group.ID, pq.Array(group.Users))
+        if err != nil {
+            return err
+        }
+    }
+    if _, err := tx.ExecContext(ctx, "DELETE FROM slack_usergroup_members WHERE NOT usergroup_id = ANY($1)", pq.Array(ids)); err != nil {
+        return err
+    }
+    if _, err := tx.ExecContext(ctx, "DELETE FROM slack_usergroups WHERE NOT usergroup_id = ANY($1)", pq.Array(ids)); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "testing"
+
+    "dashboard/apiserver/slack"
+)
+
+func TestGetUsergroups(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddUsergroup(Usergroup{ID: "S2", Handle: "support", Name: "Support", Members: []string{"U1", "U2"}})
+    store.AddUsergroup(Usergroup{ID: "S1", Handle: "oncall", Name: "On call"})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/usergroups", "/api/usergroups", c.GetUsergroups)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var list UsergroupList
+    decodeResponse(t, rec, &list)
+    if len(list.Usergroups) != 2 || list.Usergroups[0].Handle != "oncall" || list.Usergroups[1].Handle != "support" {
+        t.Fatalf("got usergroups %+v, want them sorted by handle", list.Usergroups)
+    }
+    // Groups without members list none rather than null
+    if list.Usergroups[0].Members == nil || len(list.Usergroups[1].Members) != 2 {
+        t.Fatalf("got members %v and %v", list.Usergroups[0].Members, list.Usergroups[1].Members)
+    }
+}
+
+func TestSyncUsergroupsForgetsDisabledGroups(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddUsergroup(Usergroup{ID: "S1", Handle: "oncall", Name: "On call", Members: []string{"U1"}})
+    slackClient := &MemorySlack{Usergroups: []slack.Usergroup{{ID: "S2", Handle: "support", Name: "Support", Users: []string{"U3", "U2"}}}}
+    c := newTestContainer(t, store, slackClient)
+
+    if err := c.syncUsergroups(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    groups, err := store.Usergroups(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(groups) != 1 || groups[0].ID != "S2" || len(groups[0].Members) != 2 || groups[0].Members[0] != "U2" {
+        t.Fatalf("got usergroups %+v, want the synced support group only", groups)
+    }
+    if _, err := store.Usergroup(context.Background(), "@oncall"); err != errUsergroupNotFound {
+        t.Fatalf("disabled usergroup looked up with error %v", err)
+    }
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+    "context"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+    "github.com/lib/pq"
+)
+
+// Sources of thread watchers.
+const (
+    watcherSourceReaction = "reaction"
+)
+
+const (
+    watcherSyncInterval = 15 * time.Minute
+    // watcherSyncWindow limits the sync to recently created threads
+    watcherSyncWindow = 90 * 24 * time.Hour
+)
+
+// Watcher is a user following a thread
+type Watcher struct {
+    UserID    string    `json:"user_id"`
+    Source    string    `json:"source"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// RunWatcherSync periodically records who reacted to the root message of
+// open threads with the watching emoji as watchers of the thread, and
+// threads whose author reacted with a check mark as likely answered. It does
+// nothing without a bot token or when no emoji is configured.
+func (c *Container) RunWatcherSync(ctx context.Context) {
+    if c.watchEmoji == "" || !c.slack.Configured() {
+        return
+    }
+
+    ticker := time.NewTicker(watcherSyncInterval)
+    defer ticker.Stop()
+    for {
+        if err := c.syncWatchers(ctx); err != nil && ctx.Err() == nil {
+            c.logger.Warnf("failed to sync thread watchers: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (c *Container) syncWatchers(ctx context.Context) error {
+    if !c.slackFeatureReady(slackFeatureWatchers) {
+        return nil
+    }
+    threads, err := c.watchers.RecentOpenThreads(ctx, time.Now().UTC().Add(-watcherSyncWindow))
+    if err != nil {
+        return err
+    }
+
+    for _, thread := range threads {
+        ch, ts, author := thread.ChannelID, thread.ThreadTS, thread.UserID
+        reactions, err := c.slack.ReactionsGet(ctx, ch, ts)
+        if err != nil {
+            if ctx.Err() != nil {
+                return ctx.Err()
+            }
+            c.logger.Warnf("failed to get reactions of thread %s in channel %s: %v", ts, ch, err)
+            continue
+        }
+        users := []string{}
+        accepted := false
+        for _, reaction := range reactions {
+            if reaction.BaseName() == c.watchEmoji {
+                users = append(users, reaction.Users...)
+            }
+            if answerReactions[reaction.BaseName()] {
+                for _, user := range reaction.Users {
+                    accepted = accepted || user == author
+                }
+            }
+        }
+        if accepted {
+            if err := c.recordAnswerSignal(ctx, ch, ts, answerSignalCheckmark, author, time.Now()); err != nil {
+                c.logger.Warnf("failed to mark thread %s in channel %s as answered: %v", ts, ch, err)
+            }
+        }
+        if err := c.watchers.SetReactionWatchers(ctx, ch, ts, users); err != nil {
+            c.logger.Warnf("failed to store watchers of thread %s in channel %s: %v", ts, ch, err)
+        }
+    }
+    return nil
+}
+
+// GetThreadWatchers - List the users watching a thread
+func (c *Container) GetThreadWatchers(ctx echo.Context) error {
+    channelID := ctx.Param("channel_id")
+    threadTS := ctx.Param("thread_ts")
+
+    watchers, err := c.threads.Watchers(ctx.Request().Context(), channelID, threadTS)
+    if err != nil {
+        return apierror.New(http.StatusInternalServerError, "Failed to query watchers")
+    }
+
+    return ctx.JSON(http.StatusOK, watchers)
+}
+
+func (s postgresStore) RecentOpenThreads(ctx context.Context, since time.Time) ([]domain.Thread, error) {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := db.QueryContext(ctx, `
+        SELECT t.channel_id, t.thread_ts, t.user_id, t.status, t.created_at
+        FROM threads t
+        JOIN channels ch ON ch.channel_id = t.channel_id
+        WHERE t.status = 'open' AND t.created_at > $1
+        ORDER BY t.channel_id, t.created_at
+    `, since)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    threads := []domain.Thread{}
+    for rows.Next() {
+        var thread domain.Thread
+        if err := rows.Scan(&thread.ChannelID, &thread.ThreadTS, &thread.UserID, &thread.Status, &thread.CreatedAt); err != nil {
+            return nil, err
+        }
+        threads = append(threads, thread)
+    }
+    return threads, rows.Err()
+}
+
+func (s postgresStore) SetReactionWatchers(ctx context.Context, channelID, threadTS string, users []string) error {
+    db, err := s.c.workspaceDB(ctx)
+    if err != nil {
+        return err
+    }
+
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    _, err = tx.ExecContext(ctx, `
+        DELETE FROM thread_watchers
+        WHERE channel_id = $1 AND thread_ts = $2 AND source = $3 AND NOT (user_id = ANY($4))
+    `, channelID, threadTS, watcherSourceReaction, pq.Array(users))
+    if err != nil {
+        return err
+    }
+    for _, userID := range users {
+        _, err := tx.ExecContext(ctx, `
+            INSERT INTO thread_watchers (channel_id, thread_ts, user_id, source)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (channel_id, thread_ts, user_id) DO NOTHING
+        `, channelID, threadTS, userID, watcherSourceReaction)
+        if err != nil {
+            return err
+        }
+    }
+    return tx.Commit()
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestGetThreadWatchers(t *testing.T) {
+    store := NewMemoryStore()
+    since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+    store.AddWatcher("C1", "1700000000.000100", Watcher{UserID: "U1", Source: watcherSourceReaction, CreatedAt: since})
+    store.AddWatcher("C1", "1700000000.000200", Watcher{UserID: "U2", Source: watcherSourceReaction, CreatedAt: since})
+    c := newTestContainer(t, store, &MemorySlack{})
+
+    rec := serveRequest(t, c, http.MethodGet, "/api/threads/:channel_id/:thread_ts/watchers",
+        "/api/threads/C1/1700000000.000100/watchers", c.GetThreadWatchers)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+    }
+    var watchers []Watcher
+    decodeResponse(t, rec, &watchers)
+    if len(watchers) != 1 || watchers[0].UserID != "U1" || !watchers[0].CreatedAt.Equal(since) {
+        t.Fatalf("got watchers %+v, want U1 only", watchers)
+    }
+}
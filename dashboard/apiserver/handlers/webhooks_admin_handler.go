@@ -0,0 +1,25 @@
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+
+    "github.com/labstack/echo/v4"
+)
+
+// GetInboundWebhookStats - Get accepted and rejected counts per webhook source
+func (c *Container) GetInboundWebhookStats(ctx echo.Context) error {
+    return ctx.JSON(http.StatusOK, c.inbound.Stats())
+}
+
+// GetInboundWebhookQuarantine - Get recently rejected webhook payloads
+func (c *Container) GetInboundWebhookQuarantine(ctx echo.Context) error {
+    limit := 50
+    if limitStr := ctx.QueryParam("limit"); limitStr != "" {
+        if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+            limit = parsedLimit
+        }
+    }
+
+    return ctx.JSON(http.StatusOK, c.inbound.Quarantine(ctx.QueryParam("source"), limit))
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/ingest"
+    "dashboard/apiserver/slack"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Callback IDs of the Workflow Builder steps, as declared in the Slack app
+// manifest.
+const (
+    trackThreadStep   = "track_thread"
+    resolveThreadStep = "resolve_thread"
+    snoozeThreadStep  = "snooze_thread"
+)
+
+// workflowStepInput is a field of a step configuration modal. Its name is
+// both the block and the action ID of the field.
+type workflowStepInput struct {
+    name     string
+    label    string
+    hint     string
+    optional bool
+}
+
+// workflowStepDefinition describes the configuration and outputs of a step.
+type workflowStepDefinition struct {
+    inputs  []workflowStepInput
+    outputs []slack.StepOutput
+}
+
+var messageStepInput = workflowStepInput{
+    name:  "message",
+    label: "Message link",
+    hint:  "The message of the thread, e.g. the link to the message that triggered the workflow",
+}
+
+var workflowSteps = map[string]workflowStepDefinition{
+    trackThreadStep: {
+        inputs: []workflowStepInput{
+            messageStepInput,
+            {name: "author", label: "Posted by", hint: "Who asked, e.g. the person who reacted", optional: true},
+        },
+        outputs: []slack.StepOutput{
+            {Name: "thread_link", Type: "text", Label: "Tracked thread link"},
+            {Name: "thread_status", Type: "text", Label: "Tracked thread status"},
+        },
+    },
+    resolveThreadStep: {
+        inputs: []workflowStepInput{
+            messageStepInput,
+            {name: "user", label: "Resolved by", hint: "e.g. the person who submitted the form"},
+            {name: "resolved_as", label: "Resolved as", hint: "answered, duplicate, moved_to_issue, wont_fix or no_response", optional: true},
+            {name: "reason", label: "Reason", hint: "Shown when the channel needs a second person to approve", optional: true},
+        },
+        outputs: []slack.StepOutput{
+            {Name: "resolution", Type: "text", Label: "Resolution outcome"},
+        },
+    },
+    snoozeThreadStep: {
+        inputs: []workflowStepInput{
+            messageStepInput,
+            {name: "user", label: "Snoozed by", optional: true},
+        },
+        outputs: []slack.StepOutput{
+            {Name: "thread_status", Type: "text", Label: "Snoozed thread status"},
+        },
+    },
+}
+
+// errStepFailed wraps the reason a step could not run, shown to the owner of
+// the workflow.
+type errStepFailed string
+
+func (e errStepFailed) Error() string {
+    return string(e)
+}
+
+// stepUser returns the user ID in a workflow variable, which Slack inserts
+// as a mention.
+func stepUser(value string) string {
+    return strings.TrimSuffix(strings.TrimPrefix(value, "<@"), ">")
+}
+
+// editWorkflowStep opens the configuration modal of a step being added to or
+// edited in a workflow.
+func (c *Container) editWorkflowStep(ctx echo.Context, interaction *slack.Interaction) error {
+    definition, ok := workflowSteps[interaction.CallbackID]
+    if !ok {
+        return ctx.NoContent(http.StatusOK)
+    }
+
+    configured := map[string]slack.StepInput{}
+    if interaction.WorkflowStep != nil {
+        configured = interaction.WorkflowStep.Inputs
+    }
+    blocks := make([]slack.Block, 0, len(definition.inputs))
+    for _, input := range definition.inputs {
+        blocks = append(blocks, slack.TextInput(input.name, input.name, input.label, input.hint,
+            configured[input.name].Value, input.optional))
+    }
+
+    view := slack.View{Type: slack.WorkflowStepView, CallbackID: interaction.CallbackID, Blocks: blocks}
+    go func(triggerID string) {
+        openCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+        defer cancel()
+        if err := c.slack.OpenView(openCtx, triggerID, view); err != nil {
+            c.logger.Warnf("failed to open configuration of workflow step %s: %v", view.CallbackID, err)
+        }
+    }(interaction.TriggerID)
+
+    return ctx.NoContent(http.StatusOK)
+}
+
+// saveWorkflowStep stores the inputs submitted in a step configuration modal.
+func (c *Container) saveWorkflowStep(ctx echo.Context, interaction *slack.Interaction) error {
+    view := interaction.View
+    if view == nil || view.Type != slack.WorkflowStepView || interaction.WorkflowStep == nil {
+        return ctx.NoContent(http.StatusOK)
+    }
+    definition, ok := workflowSteps[view.CallbackID]
+    if !ok {
+        return ctx.NoContent(http.StatusOK)
+    }
+    // Steps without a user input act as the workflow, on behalf of whoever
+    // configured it
+    if !c.canTriage(interaction.User.ID) {
+        return ctx.JSON(http.StatusOK, slack.NewViewErrors(map[string]string{messageStepInput.name: triageRequiredMessage}))
+    }
+
+    inputs := make(map[string]slack.StepInput, len(definition.inputs))
+    for _, input := range definition.inputs {
+        if value := strings.TrimSpace(view.Value(input.name, input.name)); value != "" {
+            inputs[input.name] = slack.StepInput{Value: value}
+        }
+    }
+
+    editID := interaction.WorkflowStep.WorkflowStepEditID
+    go func() {
+        err := c.slack.UpdateWorkflowStep(context.Background(), editID, inputs, definition.outputs)
+        if err != nil {
+            c.logger.Warnf("failed to save configuration of workflow step %s: %v", view.CallbackID, err)
+        }
+    }()
+
+    return ctx.NoContent(http.StatusOK)
+}
+
+// executeWorkflowStep runs a step reached by a workflow and reports the
+// outcome to Slack, which only continues the workflow once told.
+func (c *Container) executeWorkflowStep(ctx context.Context, env ingest.Envelope) error {
+    var event slack.WorkflowStepEvent
+    if err := json.Unmarshal(env.Event, &event); err != nil {
+        return err
+    }
+    step := event.WorkflowStep
+    if _, ok := workflowSteps[event.CallbackID]; !ok || step.WorkflowStepExecuteID == "" {
+        return nil
+    }
+
+    outputs, err := c.runWorkflowStep(ctx, event.CallbackID, &step)
+    if err != nil {
+        var failed errStepFailed
+        message := "The dashboard failed to update the thread, please try again."
+        if errors.As(err, &failed) {
+            message = failed.Error()
+        } else {
+            c.logger.Errorf("failed to run workflow step %s of workflow %s: %v", event.CallbackID, step.WorkflowID, err)
+        }
+        return c.slack.FailWorkflowStep(ctx, step.WorkflowStepExecuteID, message)
+    }
+    return c.slack.CompleteWorkflowStep(ctx, step.WorkflowStepExecuteID, outputs)
+}
+
+// runWorkflowStep applies a step to the thread given by its message input
+// and returns the step outputs.
+func (c *Container) runWorkflowStep(ctx context.Context, callbackID string, step *slack.WorkflowStep) (map[string]string, error) {
+    channelID, threadTS, err := slack.ParseMessageRef(step.Input("message"))
+    if err != nil {
+        return nil, errStepFailed("The message link is not a link to a Slack message.")
+    }
+    actor := "workflow:" + step.WorkflowID
+    if user := stepUser(step.Input("user")); user != "" {
+        if !c.canTriage(user) {
+            return nil, errStepFailed(fmt.Sprintf("<@%s> needs the triager role to change threads.", user))
+        }
+        actor = user
+    }
+
+    err = c.channels.Tracked(ctx, channelID)
+    if err == errChannelNotTracked {
+        return nil, errStepFailed(fmt.Sprintf("<#%s> is not tracked by the dashboard.", channelID))
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    switch callbackID {
+    case trackThreadStep:
+        return c.trackThreadStep(ctx, step, actor, channelID, threadTS)
+    case resolveThreadStep:
+        return c.resolveThreadStep(ctx, step, actor, channelID, threadTS)
+    case snoozeThreadStep:
+        return c.snoozeThreadStep(ctx, actor, channelID, threadTS)
+    }
+    return nil, fmt.Errorf("unknown workflow step %q", callbackID)
+}
+
+// trackThreadStep starts tracking a thread the collector has not picked up,
+// e.g. one posted in a channel before it was tracked.
+func (c *Container) trackThreadStep(ctx context.Context, step *slack.WorkflowStep, actor string, channelID string, threadTS string) (map[string]string, error) {
+    postedAt, err := slackTSTime(threadTS)
+    if err != nil {
+        return nil, errStepFailed("The message link is not a link to a Slack message.")
+    }
+
+    added, err := c.messages.TrackThread(ctx, domain.Thread{
+        ChannelID:   channelID,
+        ThreadTS:    threadTS,
+        UserID:      stepUser(step.Input("author")),
+        LatestReply: postedAt,
+        Status:      statusOpen,
+        CreatedAt:   postedAt,
+    })
+    if err != nil {
+        return nil, err
+    }
+    if added {
+        c.audit(ctx, actor, "thread.track", channelID, threadTS, map[string]interface{}{
+            "workflow_id": step.WorkflowID,
+        })
+    }
+
+    thread, err := c.threads.Thread(ctx, channelID, threadTS)
+    if err != nil {
+        return nil, err
+    }
+    return map[string]string{
+        "thread_link":   slack.Permalink(channelID, threadTS),
+        "thread_status": string(thread.Status),
+    }, nil
+}
+
+// resolveThreadStep resolves a thread, or requests approval when its channel
+// requires it.
+func (c *Container) resolveThreadStep(ctx context.Context, step *slack.WorkflowStep, actor string, channelID string, threadTS string) (map[string]string, error) {
+    if stepUser(step.Input("user")) == "" {
+        return nil, errStepFailed("Resolving a thread needs the person resolving it.")
+    }
+
+    resolvedAs := strings.TrimSpace(step.Input("resolved_as"))
+    if resolvedAs != "" && !validResolutions[resolvedAs] {
+        return nil, errStepFailed("Resolved as must be answered, duplicate, moved_to_issue, wont_fix or no_response.")
+    }
+
+    outcome, err := c.resolveOrRequest(ctx, actor, channelID, threadTS, resolvedAs, step.Input("reason"))
+    if err == errThreadNotFound {
+        return nil, errStepFailed("The thread is not tracked by the dashboard.")
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    resolution := "resolved"
+    switch outcome {
+    case resolveRequested:
+        resolution = "approval_requested"
+    case resolveAlreadyRequested:
+        resolution = "approval_pending"
+    case resolveAlreadyClosed:
+        resolution = "already_resolved"
+    }
+    return map[string]string{"resolution": resolution}, nil
+}
+
+// stepSnooze is how long the snooze_thread step snoozes a thread, like the
+// Snooze button of reminders.
+const stepSnooze = buttonSnooze
+
+// snoozeThreadStep snoozes an open thread for stepSnooze. Threads in any
+// other status are left alone.
+func (c *Container) snoozeThreadStep(ctx context.Context, actor string, channelID string, threadTS string) (map[string]string, error) {
+    thread, err := c.threads.Thread(ctx, channelID, threadTS)
+    if err == errThreadNotFound {
+        return nil, errStepFailed("The thread is not tracked by the dashboard.")
+    }
+    if err != nil {
+        return nil, err
+    }
+    if thread.Status != statusOpen {
+        return map[string]string{"thread_status": string(thread.Status)}, nil
+    }
+
+    until := time.Now().Add(stepSnooze).UTC()
+    previous, err := c.threadStatus.SnoozeThread(ctx, channelID, threadTS, until)
+    if err != nil {
+        return nil, err
+    }
+    if previous == statusOpen {
+        c.audit(ctx, actor, "thread.status", channelID, threadTS, map[string]interface{}{
+            "from":  statusOpen,
+            "to":    statusSnoozed,
+            "until": until,
+        })
+    }
+    return map[string]string{"thread_status": string(statusSnoozed)}, nil
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+    "context"
+    "database/sql"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "sync/atomic"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/config"
+
+    "github.com/labstack/echo/v4"
+)
+
+// Requests pick the workspace whose threads they read with this header or
+// query parameter, and use the shared database without either.
+const (
+    workspaceHeader = "X-Workspace-ID"
+    workspaceParam  = "workspace"
+)
+
+// workspaceDatabase keeps the threads of one workspace apart from the
+// others, e.g. in the region the workspace requires.
+type workspaceDatabase struct {
+    config  config.Database
+    db      *sql.DB
+    healthy atomic.Bool
+}
+
+type workspaceKey struct{}
+
+// withWorkspace routes the storage used under ctx to the database of team.
+func withWorkspace(ctx context.Context, team string) context.Context {
+    return context.WithValue(ctx, workspaceKey{}, team)
+}
+
+// workspaceFrom returns the workspace ctx was routed to, empty when none.
+func workspaceFrom(ctx context.Context) string {
+    team, _ := ctx.Value(workspaceKey{}).(string)
+    return team
+}
+
+// openWorkspaceDatabases creates the connection pools of the workspaces
+// stored in their own database.
+func (c *Container) openWorkspaceDatabases(workspaces map[string]config.Database) error {
+    c.workspaces = make(map[string]*workspaceDatabase, len(workspaces))
+    for team, cfg := range workspaces {
+        db, err := openDatabase(cfg, c.recorder, c.chaos)
+        if err != nil {
+            return err
+        }
+        workspace := &workspaceDatabase{config: cfg, db: db}
+        c.workspaces[team] = workspace
+        if err := c.pingDatabase(context.Background(), "database of workspace "+team, db, cfg, &workspace.healthy); err != nil {
+            c.logger.Warnf("database of workspace %s is not reachable yet: %v", team, err)
+        }
+    }
+    return nil
+}
+
+// workspaceDB returns the database of the workspace ctx was routed to, and
+// the shared one for workspaces without their own. Like getDBConnection it
+// pings a database found unreachable before returning it.
+func (c *Container) workspaceDB(ctx context.Context) (*sql.DB, error) {
+    workspace, ok := c.workspaces[workspaceFrom(ctx)]
+    if !ok {
+        return c.getDBConnection()
+    }
+    if !workspace.healthy.Load() {
+        if err := c.pingDatabase(ctx, "database of workspace "+workspaceFrom(ctx), workspace.db, workspace.config, &workspace.healthy); err != nil {
+            return nil, err
+        }
+    }
+    return workspace.db, nil
+}
+
+// channelWorkspaces remembers which workspace database tracks a channel.
+var channelWorkspaces sync.Map
+
+// channelDB returns the database tracking channelID, for callers only
+// knowing the channel. Channels no workspace database tracks are in the
+// shared one.
+func (c *Container) channelDB(ctx context.Context, channelID string) (*sql.DB, error) {
+    if workspaceFrom(ctx) != "" || len(c.workspaces) == 0 {
+        return c.workspaceDB(ctx)
+    }
+    if team, ok := channelWorkspaces.Load(channelID); ok {
+        return c.workspaceDB(withWorkspace(ctx, team.(string)))
+    }
+    for team, workspace := range c.workspaces {
+        var tracked bool
+        err := workspace.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE channel_id = $1)", channelID).Scan(&tracked)
+        if err != nil {
+            c.logger.Warnf("failed to look up channel %s in the database of workspace %s: %v", channelID, team, err)
+            continue
+        }
+        if tracked {
+            channelWorkspaces.Store(channelID, team)
+            return c.workspaceDB(withWorkspace(ctx, team))
+        }
+    }
+    return c.getDBConnection()
+}
+
+// channelWorkspace returns the workspace whose database channelDB found
+// tracking channelID, that of ctx when ctx is routed to one. It is empty for
+// the shared database.
+func (c *Container) channelWorkspace(ctx context.Context, channelID string) string {
+    if team := workspaceFrom(ctx); team != "" || channelID == "" {
+        return team
+    }
+    if team, ok := channelWorkspaces.Load(channelID); ok {
+        return team.(string)
+    }
+    return ""
+}
+
+// sharedContext routes the storage used under ctx to the shared database,
+// for what is kept there whichever workspace a request is routed to.
+func sharedContext(ctx context.Context) context.Context {
+    return withWorkspace(ctx, "")
+}
+
+// WorkspaceContexts returns ctx for the shared database followed by a
+// context routed to each workspace database, for jobs that run once per
+// database.
+func (c *Container) WorkspaceContexts(ctx context.Context) []context.Context {
+    contexts := []context.Context{ctx}
+    for _, team := range c.workspaceIDs() {
+        contexts = append(contexts, withWorkspace(ctx, team))
+    }
+    return contexts
+}
+
+func (c *Container) workspaceIDs() []string {
+    teams := make([]string, 0, len(c.workspaces))
+    for team := range c.workspaces {
+        teams = append(teams, team)
+    }
+    sort.Strings(teams)
+    return teams
+}
+
+// workspaceMember reports whether userID is an active member of team, as
+// known from the directory synced from Slack into its database.
+func (c *Container) workspaceMember(ctx context.Context, team string, userID string) (bool, error) {
+    profiles, err := c.users.Profiles(withWorkspace(ctx, team), []string{userID})
+    if err != nil {
+        return false, err
+    }
+    for _, profile := range profiles {
+        if profile.UserID == userID && !profile.Deactivated {
+            return true, nil
+        }
+    }
+    return false, nil
+}
+
+// RouteWorkspace routes the storage of API requests to the database of the
+// workspace they name, refusing workspaces without their own database and
+// callers who are not members of the workspace. API keys and anonymous
+// callers belong to no workspace.
+func (c *Container) RouteWorkspace(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+        team := strings.TrimSpace(ctx.Request().Header.Get(workspaceHeader))
+        if team == "" {
+            team = strings.TrimSpace(ctx.QueryParam(workspaceParam))
+        }
+        if team == "" {
+            return next(ctx)
+        }
+        if _, ok := c.workspaces[team]; !ok {
+            return apierror.New(http.StatusNotFound, "Workspace not found")
+        }
+        principal, ok := auth.PrincipalFrom(ctx)
+        if !ok || principal.KeyID != 0 || principal.UserID == "" {
+            return apierror.New(http.StatusForbidden, "Only members of the workspace may use it")
+        }
+        member, err := c.workspaceMember(ctx.Request().Context(), team, principal.UserID)
+        if err != nil {
+            c.logger.Errorf("failed to look up %s in workspace %s: %v", principal.UserID, team, err)
+            return apierror.New(http.StatusInternalServerError, "Failed to look up workspace membership")
+        }
+        if !member {
+            return apierror.New(http.StatusForbidden, "Only members of the workspace may use it")
+        }
+        req := ctx.Request()
+        ctx.SetRequest(req.WithContext(withWorkspace(req.Context(), team)))
+        return next(ctx)
+    }
+}
+
+// GetWorkspaces - Get the workspaces stored in their own database
+func (c *Container) GetWorkspaces(ctx echo.Context) error {
+    workspaces := []map[string]interface{}{}
+    for _, team := range c.workspaceIDs() {
+        workspace := c.workspaces[team]
+        workspaces = append(workspaces, map[string]interface{}{
+            "team_id": team,
+            "healthy": workspace.healthy.Load(),
+        })
+    }
+    return ctx.JSON(http.StatusOK, workspaces)
+}
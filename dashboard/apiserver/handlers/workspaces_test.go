@@ -0,0 +1,75 @@
+package handlers
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/auth"
+    "dashboard/apiserver/domain"
+
+    "github.com/labstack/echo/v4"
+)
+
+func TestRouteWorkspaceRefusesOtherTenants(t *testing.T) {
+    store := NewMemoryStore()
+    store.AddWorkspaceProfile("T1", UserProfile{UserProfile: domain.UserProfile{UserID: "U1"}})
+    store.AddWorkspaceProfile("T2", UserProfile{UserProfile: domain.UserProfile{UserID: "U2"}})
+    store.AddWorkspaceProfile("T2", UserProfile{UserProfile: domain.UserProfile{UserID: "U3", Deactivated: true}})
+    c := newTestContainer(t, store, &MemorySlack{})
+    c.workspaces = map[string]*workspaceDatabase{"T1": {}, "T2": {}}
+
+    route := func(principal *auth.Principal, team string) (int, string) {
+        e := echo.New()
+        e.HTTPErrorHandler = apierror.Handler(c.logger)
+        routed := ""
+        e.GET("/api/threads", func(ctx echo.Context) error {
+            routed = workspaceFrom(ctx.Request().Context())
+            return ctx.NoContent(http.StatusNoContent)
+        }, func(next echo.HandlerFunc) echo.HandlerFunc {
+            return func(ctx echo.Context) error {
+                if principal != nil {
+                    auth.SetPrincipal(ctx, principal)
+                }
+                return next(ctx)
+            }
+        }, c.RouteWorkspace)
+        req := httptest.NewRequest(http.MethodGet, "/api/threads", nil)
+        if team != "" {
+            req.Header.Set(workspaceHeader, team)
+        }
+        rec := httptest.NewRecorder()
+        e.ServeHTTP(rec, req)
+        return rec.Code, routed
+    }
+    user := func(userID string) *auth.Principal {
+        return &auth.Principal{UserID: userID, Scopes: []auth.Scope{auth.ScopeAdmin}, Method: auth.MethodSession}
+    }
+
+    tests := []struct {
+        name      string
+        principal *auth.Principal
+        team      string
+        want      int
+    }{
+        {"member of the workspace", user("U1"), "T1", http.StatusNoContent},
+        {"member of another workspace", user("U1"), "T2", http.StatusForbidden},
+        {"deactivated member", user("U3"), "T2", http.StatusForbidden},
+        {"anonymous caller", nil, "T1", http.StatusForbidden},
+        {"API key", &auth.Principal{Scopes: []auth.Scope{auth.ScopeAdmin}, KeyID: 1, Method: auth.MethodAPIKey}, "T1", http.StatusForbidden},
+        {"unknown workspace", user("U1"), "T9", http.StatusNotFound},
+        {"shared database", nil, "", http.StatusNoContent},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            code, routed := route(tt.principal, tt.team)
+            if code != tt.want {
+                t.Fatalf("got status %d, want %d", code, tt.want)
+            }
+            if code == http.StatusNoContent && routed != tt.team {
+                t.Fatalf("routed to workspace %q, want %q", routed, tt.team)
+            }
+        })
+    }
+}
@@ -0,0 +1,238 @@
+package inbound
+
+import (
+    "bytes"
+    "errors"
+    "io"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/logger"
+
+    "github.com/labstack/echo/v4"
+)
+
+const (
+    // DefaultMaxBodyBytes is used for sources that do not set a size limit.
+    DefaultMaxBodyBytes int64 = 1 << 20
+    quarantineCapacity        = 200
+    quarantinePreviewBytes    = 4096
+)
+
+// Rejection reasons reported in metrics and the quarantine log.
+const (
+    ReasonNotConfigured = "not_configured"
+    ReasonTooLarge      = "too_large"
+    ReasonBadSignature  = "bad_signature"
+    ReasonInvalidSchema = "invalid_schema"
+)
+
+// Source describes an inbound webhook producer.
+type Source struct {
+    Name         string
+    MaxBodyBytes int64
+    // Verifier is nil when no secret is configured, in which case every
+    // request from the source is rejected.
+    Verifier Verifier
+    // Schema is optional.
+    Schema Schema
+}
+
+// QuarantinedPayload is a rejected request kept around for debugging.
+type QuarantinedPayload struct {
+    Source      string            `json:"source"`
+    Reason      string            `json:"reason"`
+    Detail      string            `json:"detail"`
+    RemoteIP    string            `json:"remote_ip"`
+    ReceivedAt  time.Time         `json:"received_at"`
+    Headers     map[string]string `json:"headers"`
+    BodySize    int               `json:"body_size"`
+    BodyPreview string            `json:"body_preview"`
+}
+
+// SourceStats holds the accept and reject counters of a source.
+type SourceStats struct {
+    Source   string           `json:"source"`
+    Enabled  bool             `json:"enabled"`
+    Accepted int64            `json:"accepted"`
+    Rejected map[string]int64 `json:"rejected"`
+}
+
+// Guard enforces size limits, signatures and schemas on inbound webhooks.
+type Guard struct {
+    logger  logger.Logger
+    sources map[string]Source
+
+    mu         sync.Mutex
+    accepted   map[string]int64
+    rejected   map[string]map[string]int64
+    quarantine []QuarantinedPayload
+    next       int
+}
+
+// NewGuard returns a guard for the given sources.
+func NewGuard(log logger.Logger, sources ...Source) *Guard {
+    g := &Guard{
+        logger:   log,
+        sources:  make(map[string]Source),
+        accepted: make(map[string]int64),
+        rejected: make(map[string]map[string]int64),
+    }
+    for _, source := range sources {
+        if source.MaxBodyBytes <= 0 {
+            source.MaxBodyBytes = DefaultMaxBodyBytes
+        }
+        g.sources[source.Name] = source
+        g.rejected[source.Name] = make(map[string]int64)
+    }
+    return g
+}
+
+// Middleware protects a webhook route receiving requests from source. The
+// verified body is put back on the request for the handler to read.
+func (g *Guard) Middleware(source string) echo.MiddlewareFunc {
+    return func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(ctx echo.Context) error {
+            src, ok := g.sources[source]
+            if !ok || src.Verifier == nil {
+                g.reject(ctx, source, ReasonNotConfigured, "no signing secret configured", nil)
+                return apierror.New(http.StatusServiceUnavailable, "Webhook source "+source+" is not configured")
+            }
+
+            req := ctx.Request()
+            if req.ContentLength > src.MaxBodyBytes {
+                g.reject(ctx, source, ReasonTooLarge, "declared content length exceeds limit", nil)
+                return apierror.New(http.StatusRequestEntityTooLarge, "Payload too large")
+            }
+
+            body, err := io.ReadAll(http.MaxBytesReader(ctx.Response(), req.Body, src.MaxBodyBytes))
+            if err != nil {
+                var maxBytesErr *http.MaxBytesError
+                if errors.As(err, &maxBytesErr) {
+                    g.reject(ctx, source, ReasonTooLarge, err.Error(), body)
+                    return apierror.New(http.StatusRequestEntityTooLarge, "Payload too large")
+                }
+                return apierror.New(http.StatusBadRequest, "Failed to read request body")
+            }
+
+            if err := src.Verifier.Verify(req.Header, body); err != nil {
+                g.reject(ctx, source, ReasonBadSignature, err.Error(), body)
+                return apierror.New(http.StatusUnauthorized, "Invalid webhook signature")
+            }
+
+            if src.Schema != nil {
+                if err := src.Schema.Validate(body); err != nil {
+                    g.reject(ctx, source, ReasonInvalidSchema, err.Error(), body)
+                    return apierror.New(http.StatusBadRequest, "Invalid webhook payload: "+err.Error())
+                }
+            }
+
+            g.mu.Lock()
+            g.accepted[source]++
+            g.mu.Unlock()
+
+            req.Body = io.NopCloser(bytes.NewReader(body))
+            return next(ctx)
+        }
+    }
+}
+
+func (g *Guard) reject(ctx echo.Context, source string, reason string, detail string, body []byte) {
+    g.logger.Warnf("rejected %s webhook from %s: %s (%s)", source, ctx.RealIP(), reason, detail)
+
+    preview := body
+    if len(preview) > quarantinePreviewBytes {
+        preview = preview[:quarantinePreviewBytes]
+    }
+    entry := QuarantinedPayload{
+        Source:      source,
+        Reason:      reason,
+        Detail:      detail,
+        RemoteIP:    ctx.RealIP(),
+        ReceivedAt:  time.Now().UTC(),
+        Headers:     sanitizeHeaders(ctx.Request().Header),
+        BodySize:    len(body),
+        BodyPreview: string(preview),
+    }
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    if _, ok := g.rejected[source]; !ok {
+        g.rejected[source] = make(map[string]int64)
+    }
+    g.rejected[source][reason]++
+
+    if len(g.quarantine) < quarantineCapacity {
+        g.quarantine = append(g.quarantine, entry)
+    } else {
+        g.quarantine[g.next] = entry
+    }
+    g.next = (g.next + 1) % quarantineCapacity
+}
+
+// Stats returns the counters of every known source.
+func (g *Guard) Stats() []SourceStats {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    stats := []SourceStats{}
+    for name, rejected := range g.rejected {
+        copied := make(map[string]int64, len(rejected))
+        for reason, count := range rejected {
+            copied[reason] = count
+        }
+        source, ok := g.sources[name]
+        stats = append(stats, SourceStats{
+            Source:   name,
+            Enabled:  ok && source.Verifier != nil,
+            Accepted: g.accepted[name],
+            Rejected: copied,
+        })
+    }
+    sort.Slice(stats, func(i, j int) bool { return stats[i].Source < stats[j].Source })
+    return stats
+}
+
+// Quarantine returns the most recent rejected payloads, newest first,
+// optionally restricted to a single source.
+func (g *Guard) Quarantine(source string, limit int) []QuarantinedPayload {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    entries := []QuarantinedPayload{}
+    for i := 0; i < len(g.quarantine) && len(entries) < limit; i++ {
+        idx := (g.next - 1 - i + 2*quarantineCapacity) % quarantineCapacity
+        if idx >= len(g.quarantine) {
+            continue
+        }
+        entry := g.quarantine[idx]
+        if source != "" && entry.Source != source {
+            continue
+        }
+        entries = append(entries, entry)
+    }
+    return entries
+}
+
+var sensitiveHeaders = []string{"authorization", "cookie", "signature", "token", "secret"}
+
+func sanitizeHeaders(header http.Header) map[string]string {
+    sanitized := make(map[string]string, len(header))
+    for name, values := range header {
+        value := strings.Join(values, ", ")
+        lower := strings.ToLower(name)
+        for _, sensitive := range sensitiveHeaders {
+            if strings.Contains(lower, sensitive) {
+                value = "[redacted]"
+                break
+            }
+        }
+        sanitized[name] = value
+    }
+    return sanitized
+}
@@ -0,0 +1,113 @@
+package inbound
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "dashboard/apiserver/apierror"
+    "dashboard/apiserver/logger"
+
+    "github.com/labstack/echo/v4"
+)
+
+func newTestGuard(t *testing.T, sources ...Source) (*Guard, *echo.Echo) {
+    t.Helper()
+    log, err := logger.NewLogger(logger.Error)
+    if err != nil {
+        t.Fatal(err)
+    }
+    guard := NewGuard(log, sources...)
+    e := echo.New()
+    e.HTTPErrorHandler = apierror.Handler(log)
+    for _, source := range []string{SourceEmail, SourceGitHub} {
+        e.POST("/"+source+"/webhook", func(ctx echo.Context) error {
+            // The handler reads the verified body again
+            body, err := io.ReadAll(ctx.Request().Body)
+            if err != nil {
+                return err
+            }
+            return ctx.String(http.StatusOK, string(body))
+        }, guard.Middleware(source))
+    }
+    return guard, e
+}
+
+// post sends body to the webhook route of source, signed for the email
+// gateway with secret unless it is empty.
+func post(e *echo.Echo, source string, body io.Reader, secret string, signed []byte) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(http.MethodPost, "/"+source+"/webhook", body)
+    if secret != "" {
+        req.Header.Set(EmailSignatureHeader, hmacHex(secret, signed))
+    }
+    req.Header.Set(echo.HeaderAuthorization, "Bearer token")
+    rec := httptest.NewRecorder()
+    e.ServeHTTP(rec, req)
+    return rec
+}
+
+func TestGuardMiddleware(t *testing.T) {
+    source := EmailSource("secret")
+    source.MaxBodyBytes = 64
+    guard, e := newTestGuard(t, source, GitHubSource(""))
+
+    valid := `{"from":"alice@example.com","subject":"unsubscribe"}`
+    oversized := `{"from":"alice@example.com","subject":"` + strings.Repeat("x", 64) + `"}`
+    tests := []struct {
+        name   string
+        source string
+        body   io.Reader
+        secret string
+        signed string
+        want   int
+        reason string
+    }{
+        {"signed payload", SourceEmail, strings.NewReader(valid), "secret", valid, http.StatusOK, ""},
+        {"signature mismatch", SourceEmail, strings.NewReader(valid), "other", valid, http.StatusUnauthorized, ReasonBadSignature},
+        {"unsigned payload", SourceEmail, strings.NewReader(valid), "", valid, http.StatusUnauthorized, ReasonBadSignature},
+        {"declared oversize body", SourceEmail, strings.NewReader(oversized), "secret", oversized, http.StatusRequestEntityTooLarge, ReasonTooLarge},
+        // Without a content length the body is cut off while it is read
+        {"streamed oversize body", SourceEmail, io.MultiReader(strings.NewReader(oversized)), "secret", oversized, http.StatusRequestEntityTooLarge, ReasonTooLarge},
+        {"invalid schema", SourceEmail, strings.NewReader(`{"from":"alice@example.com"}`), "secret", `{"from":"alice@example.com"}`, http.StatusBadRequest, ReasonInvalidSchema},
+        {"source without secret", SourceGitHub, strings.NewReader(`{"sender":{}}`), "", "", http.StatusServiceUnavailable, ReasonNotConfigured},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rec := post(e, tt.source, tt.body, tt.secret, []byte(tt.signed))
+            if rec.Code != tt.want {
+                t.Fatalf("got status %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+            }
+            if tt.want == http.StatusOK && rec.Body.String() != tt.signed {
+                t.Fatalf("handler read %q, want the verified body", rec.Body.String())
+            }
+            if tt.reason == "" {
+                return
+            }
+            quarantined := guard.Quarantine(tt.source, 1)
+            if len(quarantined) != 1 || quarantined[0].Reason != tt.reason {
+                t.Fatalf("got quarantine %+v, want a %s entry", quarantined, tt.reason)
+            }
+            if quarantined[0].Headers[echo.HeaderAuthorization] != "[redacted]" {
+                t.Fatalf("quarantine kept header %q", quarantined[0].Headers[echo.HeaderAuthorization])
+            }
+            if signature, ok := quarantined[0].Headers[EmailSignatureHeader]; ok && signature != "[redacted]" {
+                t.Fatalf("quarantine kept signature %q", signature)
+            }
+        })
+    }
+
+    for _, stats := range guard.Stats() {
+        switch stats.Source {
+        case SourceEmail:
+            if !stats.Enabled || stats.Accepted != 1 || stats.Rejected[ReasonBadSignature] != 2 || stats.Rejected[ReasonTooLarge] != 2 || stats.Rejected[ReasonInvalidSchema] != 1 {
+                t.Errorf("got email stats %+v", stats)
+            }
+        case SourceGitHub:
+            if stats.Enabled || stats.Accepted != 0 || stats.Rejected[ReasonNotConfigured] != 1 {
+                t.Errorf("got github stats %+v", stats)
+            }
+        }
+    }
+}
@@ -0,0 +1,74 @@
+package inbound
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+)
+
+// FieldType is the JSON type expected for a payload field.
+type FieldType string
+
+const (
+    String FieldType = "string"
+    Number FieldType = "number"
+    Bool   FieldType = "boolean"
+    Object FieldType = "object"
+    Array  FieldType = "array"
+)
+
+// Schema lists the fields a payload must contain, keyed by dotted path
+// (e.g. "event.type").
+type Schema map[string]FieldType
+
+// Validate checks that body is a JSON object holding every field of the
+// schema with the expected type.
+func (s Schema) Validate(body []byte) error {
+    var payload map[string]interface{}
+    if err := json.Unmarshal(body, &payload); err != nil {
+        return fmt.Errorf("payload is not a JSON object: %w", err)
+    }
+
+    for path, expected := range s {
+        value, ok := lookup(payload, path)
+        if !ok {
+            return fmt.Errorf("missing required field %q", path)
+        }
+        if actual := typeOf(value); actual != expected {
+            return fmt.Errorf("field %q is %s, expected %s", path, actual, expected)
+        }
+    }
+    return nil
+}
+
+func lookup(payload map[string]interface{}, path string) (interface{}, bool) {
+    var current interface{} = payload
+    for _, key := range strings.Split(path, ".") {
+        object, ok := current.(map[string]interface{})
+        if !ok {
+            return nil, false
+        }
+        current, ok = object[key]
+        if !ok {
+            return nil, false
+        }
+    }
+    return current, true
+}
+
+func typeOf(value interface{}) FieldType {
+    switch value.(type) {
+    case string:
+        return String
+    case float64:
+        return Number
+    case bool:
+        return Bool
+    case map[string]interface{}:
+        return Object
+    case []interface{}:
+        return Array
+    default:
+        return "null"
+    }
+}
@@ -0,0 +1,78 @@
+package inbound
+
+// Names of the inbound webhook sources.
+const (
+    SourceSlack             = "slack"
+    SourceSlackInteractions = "slack_interactions"
+    SourceGitHub            = "github"
+    SourceJira              = "jira"
+    SourceEmail             = "email"
+)
+
+// EmailSignatureHeader carries the body HMAC added by the email gateway.
+const EmailSignatureHeader = "X-Gateway-Signature"
+
+// SlackSource returns the source for the Slack Events API.
+func SlackSource(signingSecret string) Source {
+    source := Source{
+        Name:         SourceSlack,
+        MaxBodyBytes: 512 << 10,
+        Schema:       Schema{"type": String},
+    }
+    if signingSecret != "" {
+        source.Verifier = SlackVerifier{SigningSecret: signingSecret}
+    }
+    return source
+}
+
+// SlackInteractionsSource returns the source for Slack interactivity
+// requests, which carry a form encoded payload instead of JSON.
+func SlackInteractionsSource(signingSecret string) Source {
+    source := Source{
+        Name:         SourceSlackInteractions,
+        MaxBodyBytes: 512 << 10,
+    }
+    if signingSecret != "" {
+        source.Verifier = SlackVerifier{SigningSecret: signingSecret}
+    }
+    return source
+}
+
+// GitHubSource returns the source for GitHub repository webhooks.
+func GitHubSource(secret string) Source {
+    source := Source{
+        Name:         SourceGitHub,
+        MaxBodyBytes: 1 << 20,
+        Schema:       Schema{"sender": Object},
+    }
+    if secret != "" {
+        source.Verifier = HubSignatureVerifier{Header: "X-Hub-Signature-256", Secret: secret}
+    }
+    return source
+}
+
+// JiraSource returns the source for Jira Cloud webhooks.
+func JiraSource(secret string) Source {
+    source := Source{
+        Name:         SourceJira,
+        MaxBodyBytes: 1 << 20,
+        Schema:       Schema{"webhookEvent": String},
+    }
+    if secret != "" {
+        source.Verifier = HubSignatureVerifier{Header: "X-Hub-Signature", Secret: secret}
+    }
+    return source
+}
+
+// EmailSource returns the source for the inbound email gateway.
+func EmailSource(secret string) Source {
+    source := Source{
+        Name:         SourceEmail,
+        MaxBodyBytes: 5 << 20,
+        Schema:       Schema{"from": String, "subject": String},
+    }
+    if secret != "" {
+        source.Verifier = HexSignatureVerifier{Header: EmailSignatureHeader, Secret: secret}
+    }
+    return source
+}
@@ -0,0 +1,112 @@
+package inbound
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// slackMaxClockSkew is the maximum age of a Slack request timestamp, as
+// recommended by Slack to mitigate replay attacks.
+const slackMaxClockSkew = 5 * time.Minute
+
+var (
+    errMissingSignature = errors.New("missing signature header")
+    errBadSignature     = errors.New("signature mismatch")
+    errStaleTimestamp   = errors.New("request timestamp outside the allowed window")
+)
+
+// Verifier checks the authenticity of an inbound request body.
+type Verifier interface {
+    Verify(header http.Header, body []byte) error
+}
+
+// SlackVerifier verifies Slack's v0 request signatures.
+type SlackVerifier struct {
+    SigningSecret string
+    now           func() time.Time
+}
+
+func (v SlackVerifier) Verify(header http.Header, body []byte) error {
+    signature := header.Get("X-Slack-Signature")
+    timestamp := header.Get("X-Slack-Request-Timestamp")
+    if signature == "" || timestamp == "" {
+        return errMissingSignature
+    }
+
+    ts, err := strconv.ParseInt(timestamp, 10, 64)
+    if err != nil {
+        return errStaleTimestamp
+    }
+    now := time.Now
+    if v.now != nil {
+        now = v.now
+    }
+    if age := now().Sub(time.Unix(ts, 0)); age > slackMaxClockSkew || age < -slackMaxClockSkew {
+        return errStaleTimestamp
+    }
+
+    expected := "v0=" + hmacHex(v.SigningSecret, []byte("v0:"+timestamp+":"), body)
+    if !hmac.Equal([]byte(signature), []byte(expected)) {
+        return errBadSignature
+    }
+    return nil
+}
+
+// HubSignatureVerifier verifies "sha256=<hex hmac>" signatures as sent by
+// GitHub (X-Hub-Signature-256) and Jira Cloud (X-Hub-Signature).
+type HubSignatureVerifier struct {
+    Header string
+    Secret string
+}
+
+func (v HubSignatureVerifier) Verify(header http.Header, body []byte) error {
+    signature := header.Get(v.Header)
+    if signature == "" {
+        return errMissingSignature
+    }
+    expected := "sha256=" + hmacHex(v.Secret, body)
+    if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+        return errBadSignature
+    }
+    return nil
+}
+
+// HexSignatureVerifier verifies a bare hex HMAC-SHA256 of the body, the
+// format used by the email gateway.
+type HexSignatureVerifier struct {
+    Header string
+    Secret string
+}
+
+func (v HexSignatureVerifier) Verify(header http.Header, body []byte) error {
+    signature := header.Get(v.Header)
+    if signature == "" {
+        return errMissingSignature
+    }
+    expected := hmacHex(v.Secret, body)
+    if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+        return errBadSignature
+    }
+    return nil
+}
+
+func hmacHex(secret string, parts ...[]byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    for _, part := range parts {
+        mac.Write(part)
+    }
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Ensure that Verifier interface is implemented
+var (
+    _ Verifier = SlackVerifier{}
+    _ Verifier = HubSignatureVerifier{}
+    _ Verifier = HexSignatureVerifier{}
+)
@@ -0,0 +1,89 @@
+package inbound
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+    "testing"
+    "time"
+)
+
+func TestSlackVerifier(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    verifier := SlackVerifier{SigningSecret: "secret", now: func() time.Time { return now }}
+    body := []byte(`{"type":"event_callback"}`)
+    signed := func(at time.Time, secret string, body []byte) http.Header {
+        timestamp := strconv.FormatInt(at.Unix(), 10)
+        header := http.Header{}
+        header.Set("X-Slack-Request-Timestamp", timestamp)
+        header.Set("X-Slack-Signature", "v0="+hmacHex(secret, []byte("v0:"+timestamp+":"), body))
+        return header
+    }
+
+    tests := []struct {
+        name   string
+        header http.Header
+        body   []byte
+        want   error
+    }{
+        {"valid", signed(now, "secret", body), body, nil},
+        {"within the window", signed(now.Add(-4*time.Minute), "secret", body), body, nil},
+        {"other secret", signed(now, "other", body), body, errBadSignature},
+        {"altered body", signed(now, "secret", body), []byte(`{"type":"url_verification"}`), errBadSignature},
+        {"replayed", signed(now.Add(-6*time.Minute), "secret", body), body, errStaleTimestamp},
+        {"from the future", signed(now.Add(6*time.Minute), "secret", body), body, errStaleTimestamp},
+        {"missing headers", http.Header{}, body, errMissingSignature},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := verifier.Verify(tt.header, tt.body); !errors.Is(err, tt.want) {
+                t.Fatalf("got %v, want %v", err, tt.want)
+            }
+        })
+    }
+
+    // A replayed request cannot be refreshed without the secret
+    header := signed(now.Add(-time.Hour), "secret", body)
+    header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(now.Unix(), 10))
+    if err := verifier.Verify(header, body); !errors.Is(err, errBadSignature) {
+        t.Fatalf("replay with a fresh timestamp got %v, want %v", err, errBadSignature)
+    }
+}
+
+func TestHMACVerifiers(t *testing.T) {
+    body := []byte(`{"from":"alice@example.com","subject":"unsubscribe"}`)
+    withHeader := func(name, value string) http.Header {
+        header := http.Header{}
+        header.Set(name, value)
+        return header
+    }
+
+    tests := []struct {
+        name     string
+        verifier Verifier
+        header   http.Header
+        want     error
+    }{
+        {"hub signature", HubSignatureVerifier{Header: "X-Hub-Signature-256", Secret: "secret"},
+            withHeader("X-Hub-Signature-256", "sha256="+hmacHex("secret", body)), nil},
+        {"hub signature without prefix", HubSignatureVerifier{Header: "X-Hub-Signature-256", Secret: "secret"},
+            withHeader("X-Hub-Signature-256", hmacHex("secret", body)), errBadSignature},
+        {"hub signature of other secret", HubSignatureVerifier{Header: "X-Hub-Signature-256", Secret: "secret"},
+            withHeader("X-Hub-Signature-256", "sha256="+hmacHex("other", body)), errBadSignature},
+        {"hub signature missing", HubSignatureVerifier{Header: "X-Hub-Signature-256", Secret: "secret"},
+            http.Header{}, errMissingSignature},
+        {"gateway signature", HexSignatureVerifier{Header: EmailSignatureHeader, Secret: "secret"},
+            withHeader(EmailSignatureHeader, hmacHex("secret", body)), nil},
+        {"gateway signature of other secret", HexSignatureVerifier{Header: EmailSignatureHeader, Secret: "secret"},
+            withHeader(EmailSignatureHeader, hmacHex("other", body)), errBadSignature},
+        {"gateway signature missing", HexSignatureVerifier{Header: EmailSignatureHeader, Secret: "secret"},
+            http.Header{}, errMissingSignature},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := tt.verifier.Verify(tt.header, body); !errors.Is(err, tt.want) {
+                t.Fatalf("got %v, want %v", err, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,234 @@
+package ingest
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/logger"
+    "dashboard/apiserver/slack"
+)
+
+// Backfill states.
+const (
+    BackfillPending   = "pending"
+    BackfillRunning   = "running"
+    BackfillCompleted = "completed"
+    BackfillFailed    = "failed"
+)
+
+const (
+    backfillPageSize   = 200
+    backfillMaxRetries = 5
+)
+
+// Checkpoint is the persisted progress of a channel backfill. Cursor is the
+// conversations.history cursor of the next page to fetch, so a restarted
+// backfill resumes where it stopped.
+type Checkpoint struct {
+    ChannelID        string     `json:"channel_id"`
+    Status           string     `json:"status"`
+    Oldest           string     `json:"oldest"`
+    Cursor           string     `json:"-"`
+    Pages            int        `json:"pages"`
+    MessagesImported int        `json:"messages_imported"`
+    LastError        string     `json:"last_error"`
+    StartedAt        time.Time  `json:"started_at"`
+    UpdatedAt        time.Time  `json:"updated_at"`
+    FinishedAt       *time.Time `json:"finished_at"`
+}
+
+// CheckpointStore persists backfill checkpoints.
+type CheckpointStore interface {
+    SaveCheckpoint(cp *Checkpoint) error
+    ListCheckpoints() ([]Checkpoint, error)
+}
+
+// MessageWriter stores a page of history messages of a channel. It must be
+// idempotent since pages are refetched after a crash.
+type MessageWriter func(ctx context.Context, channelID string, messages []slack.Message) error
+
+// Backfiller imports channel history. All channels share the Slack client's
+// rate limit budget, and at most concurrency channels are fetched at once.
+type Backfiller struct {
+    logger logger.Logger
+    client *slack.Client
+    store  CheckpointStore
+    write  MessageWriter
+    slots  chan struct{}
+
+    mu      sync.Mutex
+    running map[string]bool
+}
+
+// NewBackfiller returns a backfiller fetching at most concurrency channels
+// at the same time.
+func NewBackfiller(log logger.Logger, client *slack.Client, store CheckpointStore, write MessageWriter, concurrency int) *Backfiller {
+    if concurrency <= 0 {
+        concurrency = 2
+    }
+    return &Backfiller{
+        logger:  log,
+        client:  client,
+        store:   store,
+        write:   write,
+        slots:   make(chan struct{}, concurrency),
+        running: make(map[string]bool),
+    }
+}
+
+// Schedule starts backfilling channelIDs from oldest (a Slack ts, empty for
+// the whole history). Channels already being backfilled are skipped.
+func (b *Backfiller) Schedule(ctx context.Context, channelIDs []string, oldest string) ([]Checkpoint, error) {
+    if !b.client.Configured() {
+        return nil, slack.ErrNotConfigured
+    }
+
+    scheduled := []Checkpoint{}
+    for _, channelID := range channelIDs {
+        cp := &Checkpoint{
+            ChannelID: channelID,
+            Status:    BackfillPending,
+            Oldest:    oldest,
+            StartedAt: time.Now().UTC(),
+            UpdatedAt: time.Now().UTC(),
+        }
+        if !b.claim(channelID) {
+            continue
+        }
+        if err := b.store.SaveCheckpoint(cp); err != nil {
+            b.release(channelID)
+            return scheduled, err
+        }
+        scheduled = append(scheduled, *cp)
+        go b.run(ctx, cp)
+    }
+    return scheduled, nil
+}
+
+// Resume restarts every backfill that was pending or running when the
+// process stopped.
+func (b *Backfiller) Resume(ctx context.Context) error {
+    if !b.client.Configured() {
+        return nil
+    }
+
+    checkpoints, err := b.store.ListCheckpoints()
+    if err != nil {
+        return err
+    }
+    for i := range checkpoints {
+        cp := checkpoints[i]
+        if cp.Status != BackfillPending && cp.Status != BackfillRunning {
+            continue
+        }
+        if !b.claim(cp.ChannelID) {
+            continue
+        }
+        b.logger.Infof("resuming backfill of channel %s after %d pages", cp.ChannelID, cp.Pages)
+        go b.run(ctx, &cp)
+    }
+    return nil
+}
+
+func (b *Backfiller) claim(channelID string) bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if b.running[channelID] {
+        return false
+    }
+    b.running[channelID] = true
+    return true
+}
+
+func (b *Backfiller) release(channelID string) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    delete(b.running, channelID)
+}
+
+func (b *Backfiller) run(ctx context.Context, cp *Checkpoint) {
+    defer b.release(cp.ChannelID)
+
+    select {
+    case b.slots <- struct{}{}:
+    case <-ctx.Done():
+        return
+    }
+    defer func() { <-b.slots }()
+
+    cp.Status = BackfillRunning
+    b.save(cp)
+
+    retries := 0
+    for {
+        page, err := b.client.ConversationsHistory(ctx, cp.ChannelID, cp.Oldest, cp.Cursor, backfillPageSize)
+        if err == nil {
+            err = b.write(ctx, cp.ChannelID, page.Messages)
+        }
+        if err != nil {
+            if ctx.Err() != nil {
+                // Shutting down, the checkpoint stays running and is resumed
+                return
+            }
+            var rateLimited *slack.RateLimitedError
+            if errors.As(err, &rateLimited) {
+                // The budget already paused the tier, just try again
+                continue
+            }
+            retries++
+            cp.LastError = err.Error()
+            if retries > backfillMaxRetries {
+                b.logger.Errorf("giving up backfill of channel %s: %v", cp.ChannelID, err)
+                b.finish(cp, BackfillFailed)
+                return
+            }
+            b.save(cp)
+            if !sleep(ctx, time.Duration(retries*retries)*time.Second) {
+                return
+            }
+            continue
+        }
+
+        retries = 0
+        cp.Pages++
+        cp.MessagesImported += len(page.Messages)
+        cp.Cursor = page.ResponseMetadata.NextCursor
+        cp.LastError = ""
+
+        if !page.HasMore || cp.Cursor == "" {
+            b.logger.Infof("backfill of channel %s completed with %d messages", cp.ChannelID, cp.MessagesImported)
+            b.finish(cp, BackfillCompleted)
+            return
+        }
+        b.save(cp)
+    }
+}
+
+func (b *Backfiller) finish(cp *Checkpoint, status string) {
+    now := time.Now().UTC()
+    cp.Status = status
+    cp.FinishedAt = &now
+    b.save(cp)
+}
+
+func (b *Backfiller) save(cp *Checkpoint) {
+    cp.UpdatedAt = time.Now().UTC()
+    if err := b.store.SaveCheckpoint(cp); err != nil {
+        b.logger.Warnf("failed to save backfill checkpoint of channel %s: %v", cp.ChannelID, err)
+    }
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return false
+    case <-timer.C:
+        return true
+    }
+}
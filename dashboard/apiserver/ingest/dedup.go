@@ -0,0 +1,73 @@
+package ingest
+
+import (
+    "sync"
+    "time"
+)
+
+// DefaultDedupTTL covers Slack's retry schedule (immediately, after 1 minute
+// and after 5 minutes) with a generous margin.
+const DefaultDedupTTL = time.Hour
+
+// DedupStore remembers which keys have already been processed.
+type DedupStore interface {
+    // MarkSeen records key and reports whether it had been seen before
+    // within the TTL.
+    MarkSeen(key string) (duplicate bool, err error)
+    // Forget removes key so a later delivery is processed again.
+    Forget(key string) error
+}
+
+// MemoryDedupStore is a DedupStore for a single apiserver instance.
+type MemoryDedupStore struct {
+    ttl time.Duration
+    now func() time.Time
+
+    mu   sync.Mutex
+    seen map[string]time.Time
+}
+
+// NewMemoryDedupStore returns a store forgetting keys after ttl.
+func NewMemoryDedupStore(ttl time.Duration) *MemoryDedupStore {
+    return &MemoryDedupStore{
+        ttl:  ttl,
+        now:  time.Now,
+        seen: make(map[string]time.Time),
+    }
+}
+
+func (s *MemoryDedupStore) MarkSeen(key string) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := s.now()
+    if seenAt, ok := s.seen[key]; ok && now.Sub(seenAt) < s.ttl {
+        return true, nil
+    }
+    s.seen[key] = now
+    return false, nil
+}
+
+func (s *MemoryDedupStore) Forget(key string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    delete(s.seen, key)
+    return nil
+}
+
+// Prune drops keys older than the TTL.
+func (s *MemoryDedupStore) Prune() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := s.now()
+    for key, seenAt := range s.seen {
+        if now.Sub(seenAt) >= s.ttl {
+            delete(s.seen, key)
+        }
+    }
+}
+
+// Ensure that DedupStore interface is implemented
+var _ DedupStore = (*MemoryDedupStore)(nil)
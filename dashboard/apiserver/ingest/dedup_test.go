@@ -0,0 +1,42 @@
+package ingest
+
+import (
+    "testing"
+    "time"
+)
+
+func TestMemoryDedupStore(t *testing.T) {
+    now := time.Now()
+    store := NewMemoryDedupStore(time.Hour)
+    store.now = func() time.Time { return now }
+
+    if duplicate, _ := store.MarkSeen("event:Ev1"); duplicate {
+        t.Fatal("first delivery reported as a duplicate")
+    }
+    now = now.Add(5 * time.Minute)
+    if duplicate, _ := store.MarkSeen("event:Ev1"); !duplicate {
+        t.Fatal("retry within the TTL not reported as a duplicate")
+    }
+
+    // Keys seen again once the TTL is over count as new
+    now = now.Add(time.Hour)
+    if duplicate, _ := store.MarkSeen("event:Ev1"); duplicate {
+        t.Fatal("delivery after the TTL reported as a duplicate")
+    }
+
+    store.Forget("event:Ev1")
+    if duplicate, _ := store.MarkSeen("event:Ev1"); duplicate {
+        t.Fatal("forgotten key reported as a duplicate")
+    }
+
+    store.MarkSeen("event:Ev2")
+    now = now.Add(2 * time.Hour)
+    store.MarkSeen("event:Ev3")
+    store.Prune()
+    if _, ok := store.seen["event:Ev2"]; ok {
+        t.Fatal("prune kept an expired key")
+    }
+    if _, ok := store.seen["event:Ev3"]; !ok {
+        t.Fatal("prune dropped a live key")
+    }
+}
@@ -0,0 +1,58 @@
+package ingest
+
+import (
+    "encoding/json"
+)
+
+// Envelope is the outer payload of a Slack Events API delivery.
+type Envelope struct {
+    Type      string          `json:"type"`
+    Token     string          `json:"token"`
+    Challenge string          `json:"challenge"`
+    TeamID    string          `json:"team_id"`
+    APIAppID  string          `json:"api_app_id"`
+    EventID   string          `json:"event_id"`
+    EventTime int64           `json:"event_time"`
+    Event     json.RawMessage `json:"event"`
+    // IsExtSharedChannel is set on events of Slack Connect channels
+    IsExtSharedChannel bool `json:"is_ext_shared_channel"`
+    // EnterpriseID is the Enterprise Grid of TeamID, IsEnterpriseInstall is
+    // set when the app is installed across the whole grid
+    EnterpriseID        string `json:"enterprise_id"`
+    IsEnterpriseInstall bool   `json:"is_enterprise_install"`
+}
+
+// MessageEvent is the subset of a Slack message event used for tracking
+// threads.
+type MessageEvent struct {
+    Type    string `json:"type"`
+    Subtype string `json:"subtype"`
+    Channel string `json:"channel"`
+    User    string `json:"user"`
+    Text    string `json:"text"`
+    // UserTeam is the workspace of the user, another one than the team of
+    // the envelope in Slack Connect channels
+    UserTeam string `json:"user_team"`
+    TS       string `json:"ts"`
+    ThreadTS string `json:"thread_ts"`
+    EventTS  string `json:"event_ts"`
+}
+
+// IsReply reports whether the message was posted inside a thread rather than
+// being a thread root.
+func (m MessageEvent) IsReply() bool {
+    return m.ThreadTS != "" && m.ThreadTS != m.TS
+}
+
+// RootTS returns the ts of the thread the message belongs to.
+func (m MessageEvent) RootTS() string {
+    if m.ThreadTS != "" {
+        return m.ThreadTS
+    }
+    return m.TS
+}
+
+// MessageKey identifies a message independently of the delivery carrying it.
+func (m MessageEvent) MessageKey() string {
+    return m.Channel + ":" + m.TS
+}
@@ -0,0 +1,123 @@
+package ingest
+
+import (
+    "context"
+    "encoding/json"
+    "sync/atomic"
+
+    "dashboard/apiserver/logger"
+)
+
+// MessageHandler applies a unique message to storage.
+type MessageHandler func(ctx context.Context, env Envelope, msg MessageEvent) error
+
+// TextPolicy reports whether message text of a channel may be kept. Channels
+// that opted out of text indexing only get metadata stored.
+type TextPolicy func(ctx context.Context, channelID string) (bool, error)
+
+// PipelineStats counts what happened to deliveries.
+type PipelineStats struct {
+    Processed  int64 `json:"processed"`
+    Duplicates int64 `json:"duplicates"`
+    Ignored    int64 `json:"ignored"`
+    Failed     int64 `json:"failed"`
+}
+
+// Pipeline processes Slack event deliveries exactly once. A delivery is
+// dropped when its event_id was already processed (Slack retries reuse it)
+// or when the message it carries was already applied through another event
+// subscription, e.g. both message.channels and app_mention.
+type Pipeline struct {
+    logger   logger.Logger
+    dedup    DedupStore
+    handle   MessageHandler
+    keepText TextPolicy
+
+    processed  atomic.Int64
+    duplicates atomic.Int64
+    ignored    atomic.Int64
+    failed     atomic.Int64
+}
+
+// NewPipeline returns a pipeline deduplicating through store and stripping
+// text of channels rejected by keepText.
+func NewPipeline(log logger.Logger, store DedupStore, keepText TextPolicy, handle MessageHandler) *Pipeline {
+    return &Pipeline{
+        logger:   log,
+        dedup:    store,
+        handle:   handle,
+        keepText: keepText,
+    }
+}
+
+// Process handles one event_callback delivery.
+func (p *Pipeline) Process(ctx context.Context, env Envelope) error {
+    var msg MessageEvent
+    if err := json.Unmarshal(env.Event, &msg); err != nil {
+        p.ignored.Add(1)
+        return err
+    }
+    // Edits, deletions and bot chatter do not change thread tracking yet
+    if msg.Type != "message" || msg.Subtype != "" || msg.Channel == "" || msg.TS == "" {
+        p.ignored.Add(1)
+        return nil
+    }
+
+    keep, err := p.keepText(ctx, msg.Channel)
+    if err != nil {
+        p.failed.Add(1)
+        return err
+    }
+    if !keep {
+        msg.Text = ""
+    }
+
+    keys := []string{"message:" + msg.MessageKey()}
+    if env.EventID != "" {
+        keys = append([]string{"event:" + env.EventID}, keys...)
+    }
+
+    marked := []string{}
+    for _, key := range keys {
+        duplicate, err := p.dedup.MarkSeen(key)
+        if err != nil {
+            p.forget(marked)
+            p.failed.Add(1)
+            return err
+        }
+        if duplicate {
+            p.forget(marked)
+            p.duplicates.Add(1)
+            p.logger.Debugf("dropping duplicate Slack delivery %s (%s)", env.EventID, key)
+            return nil
+        }
+        marked = append(marked, key)
+    }
+
+    if err := p.handle(ctx, env, msg); err != nil {
+        // Let Slack's retry of this delivery through
+        p.forget(marked)
+        p.failed.Add(1)
+        return err
+    }
+    p.processed.Add(1)
+    return nil
+}
+
+func (p *Pipeline) forget(keys []string) {
+    for _, key := range keys {
+        if err := p.dedup.Forget(key); err != nil {
+            p.logger.Warnf("failed to forget dedup key %s: %v", key, err)
+        }
+    }
+}
+
+// Stats returns the pipeline counters.
+func (p *Pipeline) Stats() PipelineStats {
+    return PipelineStats{
+        Processed:  p.processed.Load(),
+        Duplicates: p.duplicates.Load(),
+        Ignored:    p.ignored.Load(),
+        Failed:     p.failed.Load(),
+    }
+}
@@ -0,0 +1,69 @@
+package ingest
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/logger"
+)
+
+func message(eventID string, channel string, ts string, text string) Envelope {
+    event, _ := json.Marshal(MessageEvent{Type: "message", Channel: channel, User: "U1", Text: text, TS: ts})
+    return Envelope{Type: "event_callback", EventID: eventID, Event: event}
+}
+
+func TestPipelineProcessesOnce(t *testing.T) {
+    log, err := logger.NewLogger(logger.Error)
+    if err != nil {
+        t.Fatal(err)
+    }
+    handled := []MessageEvent{}
+    fail := false
+    pipeline := NewPipeline(log, NewMemoryDedupStore(time.Hour),
+        func(ctx context.Context, channelID string) (bool, error) { return channelID != "C2", nil },
+        func(ctx context.Context, env Envelope, msg MessageEvent) error {
+            if fail {
+                return errors.New("storage unavailable")
+            }
+            handled = append(handled, msg)
+            return nil
+        })
+    process := func(env Envelope) {
+        t.Helper()
+        if err := pipeline.Process(context.Background(), env); err != nil && !fail {
+            t.Fatal(err)
+        }
+    }
+
+    process(message("Ev1", "C1", "1700000000.000100", "help"))
+    // Slack retry of the same delivery
+    process(message("Ev1", "C1", "1700000000.000100", "help"))
+    // The same message through another subscription
+    process(message("Ev2", "C1", "1700000000.000100", "help"))
+    if len(handled) != 1 {
+        t.Fatalf("handled %d messages, want 1", len(handled))
+    }
+
+    // A failed delivery is processed again on retry
+    fail = true
+    process(message("Ev3", "C1", "1700000000.000200", "again"))
+    fail = false
+    process(message("Ev3", "C1", "1700000000.000200", "again"))
+    if len(handled) != 2 {
+        t.Fatalf("handled %d messages after the retry, want 2", len(handled))
+    }
+
+    // Text of channels that opted out of indexing is not kept
+    process(message("Ev4", "C2", "1700000000.000300", "secret"))
+    if len(handled) != 3 || handled[2].Text != "" {
+        t.Fatalf("got messages %+v, want the last one without text", handled)
+    }
+
+    want := PipelineStats{Processed: 3, Duplicates: 2, Failed: 1}
+    if stats := pipeline.Stats(); stats != want {
+        t.Fatalf("got stats %+v, want %+v", stats, want)
+    }
+}
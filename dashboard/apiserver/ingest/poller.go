@@ -0,0 +1,307 @@
+package ingest
+
+import (
+    "context"
+    "errors"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/logger"
+    "dashboard/apiserver/slack"
+)
+
+const (
+    pollPageSize = 200
+    // pollLookback is how long before the previous poll a poll starts, so
+    // that replies to threads posted shortly before it are picked up.
+    pollLookback = time.Hour
+)
+
+// PollerConfig configures a Poller. Channels with activity are polled every
+// MinInterval, quiet channels back off exponentially up to MaxInterval.
+type PollerConfig struct {
+    MinInterval time.Duration
+    MaxInterval time.Duration
+}
+
+// ChannelLister returns the channels to poll.
+type ChannelLister func(ctx context.Context) ([]string, error)
+
+// ChannelPollStats are the poll metrics of a channel. Active polls found new
+// messages or replies since the previous poll.
+type ChannelPollStats struct {
+    ChannelID       string     `json:"channel_id"`
+    IntervalSeconds float64    `json:"interval_seconds"`
+    NextPollAt      time.Time  `json:"next_poll_at"`
+    LastPolledAt    *time.Time `json:"last_polled_at"`
+    LastActivityAt  *time.Time `json:"last_activity_at"`
+    Polls           int64      `json:"polls"`
+    ActivePolls     int64      `json:"active_polls"`
+    Calls           int64      `json:"calls"`
+    Messages        int64      `json:"messages"`
+    Failures        int64      `json:"failures"`
+    LastError       string     `json:"last_error"`
+}
+
+// PollerStats are the poll metrics of every polled channel, the soonest
+// polled first.
+type PollerStats struct {
+    MinIntervalSeconds float64            `json:"min_interval_seconds"`
+    MaxIntervalSeconds float64            `json:"max_interval_seconds"`
+    Polls              int64              `json:"polls"`
+    Calls              int64              `json:"calls"`
+    Channels           []ChannelPollStats `json:"channels"`
+}
+
+// channelPoll is the polling state of a channel. latest is the newest
+// message or reply ts seen so far.
+type channelPoll struct {
+    stats    ChannelPollStats
+    interval time.Duration
+    latest   float64
+}
+
+// Poller polls the history of channels to pick up messages whose events
+// were missed. Channels are polled one at a time within the Slack client's
+// rate limit budget, and how often depends on their activity: every
+// MinInterval while they have new messages, twice as long after every poll
+// finding none, up to MaxInterval. Slack events about a channel bring it
+// back to MinInterval, see Touch.
+type Poller struct {
+    logger logger.Logger
+    client *slack.Client
+    write  MessageWriter
+    config PollerConfig
+
+    mu       sync.Mutex
+    channels map[string]*channelPoll
+}
+
+// NewPoller returns a poller writing polled messages with write.
+func NewPoller(log logger.Logger, client *slack.Client, write MessageWriter, config PollerConfig) *Poller {
+    if config.MaxInterval < config.MinInterval {
+        config.MaxInterval = config.MinInterval
+    }
+    return &Poller{
+        logger:   log,
+        client:   client,
+        write:    write,
+        config:   config,
+        channels: make(map[string]*channelPoll),
+    }
+}
+
+// Run polls the channels returned by list as they fall due until ctx is
+// cancelled, listing them again every MinInterval. Pollers may run for
+// several lists at once, each forgetting only the channels it stopped
+// listing.
+func (p *Poller) Run(ctx context.Context, list ChannelLister) {
+    if !p.client.Configured() || p.config.MinInterval <= 0 {
+        return
+    }
+
+    listed := map[string]bool{}
+    for {
+        if channels, err := list(ctx); err != nil {
+            if ctx.Err() == nil {
+                p.logger.Warnf("failed to list the channels to poll: %v", err)
+            }
+        } else {
+            listed = p.track(listed, channels)
+        }
+
+        relisted := time.Now().Add(p.config.MinInterval)
+        for {
+            channelID, due := p.next(listed)
+            if channelID == "" || due.After(relisted) {
+                break
+            }
+            if !sleep(ctx, time.Until(due)) {
+                return
+            }
+            p.poll(ctx, channelID)
+            if ctx.Err() != nil {
+                return
+            }
+        }
+        if !sleep(ctx, time.Until(relisted)) {
+            return
+        }
+    }
+}
+
+// track starts polling the channels newly listed, right away, and stops
+// polling those previously listed but no longer. It returns the channels
+// now listed.
+func (p *Poller) track(previous map[string]bool, channels []string) map[string]bool {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    listed := make(map[string]bool, len(channels))
+    for _, channelID := range channels {
+        listed[channelID] = true
+        if _, ok := p.channels[channelID]; !ok {
+            p.channels[channelID] = &channelPoll{
+                stats:    ChannelPollStats{ChannelID: channelID, NextPollAt: time.Now().UTC()},
+                interval: p.config.MinInterval,
+            }
+        }
+    }
+    for channelID := range previous {
+        if !listed[channelID] {
+            delete(p.channels, channelID)
+        }
+    }
+    return listed
+}
+
+// next returns the listed channel due soonest, and when.
+func (p *Poller) next(listed map[string]bool) (string, time.Time) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    var next string
+    var due time.Time
+    for channelID := range listed {
+        ch, ok := p.channels[channelID]
+        if ok && (next == "" || ch.stats.NextPollAt.Before(due)) {
+            next, due = channelID, ch.stats.NextPollAt
+        }
+    }
+    return next, due
+}
+
+// Touch notes activity in a channel, polling it again within MinInterval.
+// Channels not polled are ignored.
+func (p *Poller) Touch(channelID string) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    ch, ok := p.channels[channelID]
+    if !ok {
+        return
+    }
+    now := time.Now().UTC()
+    ch.interval = p.config.MinInterval
+    ch.stats.LastActivityAt = &now
+    if due := now.Add(ch.interval); ch.stats.NextPollAt.After(due) {
+        ch.stats.NextPollAt = due
+    }
+}
+
+// poll fetches the history of a channel since shortly before its previous
+// poll and writes it, then schedules the next poll of the channel.
+func (p *Poller) poll(ctx context.Context, channelID string) {
+    p.mu.Lock()
+    ch, ok := p.channels[channelID]
+    if !ok {
+        p.mu.Unlock()
+        return
+    }
+    since := time.Now()
+    if ch.stats.LastPolledAt != nil {
+        since = *ch.stats.LastPolledAt
+    }
+    previous := ch.latest
+    p.mu.Unlock()
+
+    startedAt := time.Now().UTC()
+    oldest := strconv.FormatInt(since.Add(-pollLookback).Unix(), 10)
+    latest := previous
+    var calls, messages int64
+    var cursor string
+    var err error
+    for {
+        var page *slack.HistoryPage
+        page, err = p.client.ConversationsHistory(ctx, channelID, oldest, cursor, pollPageSize)
+        var rateLimited *slack.RateLimitedError
+        if errors.As(err, &rateLimited) && ctx.Err() == nil {
+            // The budget already paused the tier, just try again
+            continue
+        }
+        calls++
+        if err == nil {
+            err = p.write(ctx, channelID, page.Messages)
+        }
+        if err != nil {
+            break
+        }
+        messages += int64(len(page.Messages))
+        for _, msg := range page.Messages {
+            for _, ts := range []string{msg.TS, msg.LatestReply} {
+                if seen, err := strconv.ParseFloat(ts, 64); err == nil && seen > latest {
+                    latest = seen
+                }
+            }
+        }
+        cursor = page.ResponseMetadata.NextCursor
+        if !page.HasMore || cursor == "" {
+            break
+        }
+    }
+    if ctx.Err() != nil {
+        return
+    }
+
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    ch, ok = p.channels[channelID]
+    if !ok {
+        return
+    }
+    ch.stats.Polls++
+    ch.stats.Calls += calls
+    ch.stats.Messages += messages
+    if err != nil {
+        ch.stats.Failures++
+        ch.stats.LastError = err.Error()
+        p.logger.Warnf("failed to poll channel %s: %v", channelID, err)
+    } else {
+        ch.stats.LastPolledAt = &startedAt
+        ch.stats.LastError = ""
+    }
+
+    // Activity noted by Touch while polling counts too
+    touched := ch.stats.LastActivityAt != nil && ch.stats.LastActivityAt.After(startedAt)
+    if latest > previous {
+        ch.latest = latest
+        ch.stats.ActivePolls++
+        if !touched {
+            ch.stats.LastActivityAt = &startedAt
+        }
+    }
+    if latest > previous || touched {
+        ch.interval = p.config.MinInterval
+    } else {
+        ch.interval *= 2
+        if ch.interval > p.config.MaxInterval {
+            ch.interval = p.config.MaxInterval
+        }
+    }
+    ch.stats.NextPollAt = time.Now().UTC().Add(ch.interval)
+}
+
+// Stats returns the poll metrics of every polled channel.
+func (p *Poller) Stats() PollerStats {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    stats := PollerStats{
+        MinIntervalSeconds: p.config.MinInterval.Seconds(),
+        MaxIntervalSeconds: p.config.MaxInterval.Seconds(),
+        Channels:           make([]ChannelPollStats, 0, len(p.channels)),
+    }
+    for _, ch := range p.channels {
+        channel := ch.stats
+        channel.IntervalSeconds = ch.interval.Seconds()
+        stats.Polls += channel.Polls
+        stats.Calls += channel.Calls
+        stats.Channels = append(stats.Channels, channel)
+    }
+    sort.Slice(stats.Channels, func(i, j int) bool {
+        return stats.Channels[i].NextPollAt.Before(stats.Channels[j].NextPollAt)
+    })
+    return stats
+}
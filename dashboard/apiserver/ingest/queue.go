@@ -0,0 +1,615 @@
+package ingest
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "dashboard/apiserver/logger"
+)
+
+const (
+    spillFileName  = "slack-events.jsonl"
+    refillInterval = 200 * time.Millisecond
+)
+
+// Deliveries are spilled to numbered segments. The single spill file of
+// earlier versions is picked up as the oldest segment.
+const (
+    spillSegmentPrefix = "slack-events-"
+    spillSegmentSuffix = ".jsonl"
+    deadLetterFileName = "slack-events-failed.jsonl"
+    // spillSegmentSize is the number of deliveries appended to a spill
+    // segment before a new one is started, bounding what a refill reads and
+    // rewrites.
+    spillSegmentSize = 1000
+    // maxDeliveryAttempts is how often a delivery is processed before it is
+    // given up on and written to the dead letter file.
+    maxDeliveryAttempts = 5
+    retryBaseDelay      = time.Second
+    retryMaxDelay       = time.Minute
+)
+
+// ErrSpillFull is returned by Enqueue once MaxSpilled deliveries wait on
+// disk, so the sender retries later instead of filling the disk.
+var ErrSpillFull = errors.New("ingest spill is full")
+
+// QueueConfig sizes an ingestion queue.
+type QueueConfig struct {
+    // Capacity is the number of deliveries buffered in memory before new
+    // ones overflow to disk.
+    Capacity int
+    // Workers is the number of deliveries processed concurrently, which
+    // bounds the load put on the database.
+    Workers int
+    // SpillDir holds deliveries that did not fit in memory.
+    SpillDir string
+    // MaxSpilled is the number of deliveries kept on disk before new ones
+    // are refused. Defaults to 100000.
+    MaxSpilled int
+}
+
+// QueueStats reports the state of the queue.
+type QueueStats struct {
+    Depth         int     `json:"depth"`
+    Capacity      int     `json:"capacity"`
+    Spilled       int     `json:"spilled"`
+    Retrying      int     `json:"retrying"`
+    Enqueued      int64   `json:"enqueued"`
+    SpilledTotal  int64   `json:"spilled_total"`
+    Processed     int64   `json:"processed"`
+    Failed        int64   `json:"failed"`
+    DeadLettered  int64   `json:"dead_lettered"`
+    Dropped       int64   `json:"dropped"`
+    LagSeconds    float64 `json:"lag_seconds"`
+    RatePerSecond float64 `json:"rate_per_second"`
+}
+
+type queuedEnvelope struct {
+    Envelope   Envelope  `json:"envelope"`
+    EnqueuedAt time.Time `json:"enqueued_at"`
+    Attempts   int       `json:"attempts,omitempty"`
+
+    retryAt time.Time
+}
+
+// spillSegment is one file of spilled deliveries.
+type spillSegment struct {
+    seq   int
+    count int
+}
+
+// Queue decouples receiving Slack deliveries from applying them. Deliveries
+// beyond the in-memory capacity are appended to segmented spill files and
+// fed back in order once the workers catch up, so a burst never blocks
+// Slack's requests nor floods the database. Deliveries that fail are retried
+// with backoff and kept in a dead letter file once they keep failing.
+type Queue struct {
+    logger   logger.Logger
+    config   QueueConfig
+    process  func(ctx context.Context, env Envelope) error
+    items    chan queuedEnvelope
+    spillDir string
+
+    // mu guards segments and spilled. Only the newest segment is appended
+    // to, older ones belong to refill, which reads and rewrites them
+    // without holding mu.
+    mu       sync.Mutex
+    segments []spillSegment
+    nextSeq  int
+    spilled  int
+
+    // retryMu guards retries, the failed deliveries waiting out their
+    // backoff.
+    retryMu sync.Mutex
+    retries []queuedEnvelope
+
+    deadLetterMu sync.Mutex
+
+    enqueued     atomic.Int64
+    spilledTotal atomic.Int64
+    processed    atomic.Int64
+    failed       atomic.Int64
+    deadLettered atomic.Int64
+    dropped      atomic.Int64
+    lagNanos     atomic.Int64
+    rate         *rateCounter
+}
+
+// NewQueue returns a queue feeding process. Deliveries left on disk by a
+// previous run are picked up again.
+func NewQueue(log logger.Logger, config QueueConfig, process func(ctx context.Context, env Envelope) error) (*Queue, error) {
+    if config.Capacity <= 0 {
+        config.Capacity = 1000
+    }
+    if config.Workers <= 0 {
+        config.Workers = 4
+    }
+    if config.MaxSpilled <= 0 {
+        config.MaxSpilled = 100000
+    }
+    if err := os.MkdirAll(config.SpillDir, 0o700); err != nil {
+        return nil, err
+    }
+
+    q := &Queue{
+        logger:   log,
+        config:   config,
+        process:  process,
+        items:    make(chan queuedEnvelope, config.Capacity),
+        spillDir: config.SpillDir,
+        nextSeq:  1,
+        rate:     newRateCounter(),
+    }
+
+    if err := q.loadSegments(); err != nil {
+        return nil, err
+    }
+    if q.spilled > 0 {
+        log.Infof("resuming %d Slack deliveries spilled to disk", q.spilled)
+    }
+    return q, nil
+}
+
+// loadSegments finds the spill segments left by a previous run.
+func (q *Queue) loadSegments() error {
+    legacy := filepath.Join(q.spillDir, spillFileName)
+    if _, err := os.Stat(legacy); err == nil {
+        if err := os.Rename(legacy, q.segmentPath(0)); err != nil {
+            return err
+        }
+    }
+
+    entries, err := os.ReadDir(q.spillDir)
+    if err != nil {
+        return err
+    }
+    for _, entry := range entries {
+        name := entry.Name()
+        if !strings.HasPrefix(name, spillSegmentPrefix) || !strings.HasSuffix(name, spillSegmentSuffix) {
+            continue
+        }
+        seq, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, spillSegmentPrefix), spillSegmentSuffix))
+        if err != nil {
+            // The dead letter file and unrelated files
+            continue
+        }
+        items, err := q.readItems(q.segmentPath(seq))
+        if err != nil {
+            return err
+        }
+        q.segments = append(q.segments, spillSegment{seq: seq, count: len(items)})
+        q.spilled += len(items)
+    }
+    sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].seq < q.segments[j].seq })
+    if len(q.segments) > 0 {
+        q.nextSeq = q.segments[len(q.segments)-1].seq + 1
+    }
+    return nil
+}
+
+// Enqueue accepts a delivery without blocking.
+func (q *Queue) Enqueue(env Envelope) error {
+    item := queuedEnvelope{Envelope: env, EnqueuedAt: time.Now()}
+
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    // Keep FIFO order while older deliveries are still on disk
+    if q.spilled == 0 {
+        select {
+        case q.items <- item:
+            q.enqueued.Add(1)
+            return nil
+        default:
+        }
+    }
+
+    if q.spilled >= q.config.MaxSpilled {
+        q.dropped.Add(1)
+        return ErrSpillFull
+    }
+    if err := q.spill(item); err != nil {
+        q.dropped.Add(1)
+        return err
+    }
+    q.enqueued.Add(1)
+    q.spilledTotal.Add(1)
+    return nil
+}
+
+// requeue puts a delivery whose backoff ran out back in line. It is not
+// refused when the spill is full, it was accepted already.
+func (q *Queue) requeue(item queuedEnvelope) error {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    if q.spilled == 0 {
+        select {
+        case q.items <- item:
+            return nil
+        default:
+        }
+    }
+    return q.spill(item)
+}
+
+// spill appends item to the newest segment, starting a new one when it is
+// full or already handed to refill. Callers hold mu.
+func (q *Queue) spill(item queuedEnvelope) error {
+    if len(q.segments) == 0 || q.segments[len(q.segments)-1].count >= spillSegmentSize {
+        q.segments = append(q.segments, spillSegment{seq: q.nextSeq})
+        q.nextSeq++
+    }
+    tail := &q.segments[len(q.segments)-1]
+    if err := appendItems(q.segmentPath(tail.seq), []queuedEnvelope{item}); err != nil {
+        return err
+    }
+    tail.count++
+    q.spilled++
+    return nil
+}
+
+// Run processes deliveries until ctx is cancelled. Deliveries still in
+// memory or waiting to be retried at that point are spilled to disk for the
+// next run.
+func (q *Queue) Run(ctx context.Context) {
+    var wg sync.WaitGroup
+    for i := 0; i < q.config.Workers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            q.work(ctx)
+        }()
+    }
+
+    ticker := time.NewTicker(refillInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            wg.Wait()
+            q.flush()
+            return
+        case now := <-ticker.C:
+            q.retryDue(now)
+            q.refill()
+        }
+    }
+}
+
+func (q *Queue) work(ctx context.Context) {
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case item := <-q.items:
+            q.lagNanos.Store(int64(time.Since(item.EnqueuedAt)))
+            if err := q.process(ctx, item.Envelope); err != nil {
+                q.failed.Add(1)
+                q.retry(ctx, item, err)
+                continue
+            }
+            q.processed.Add(1)
+            q.rate.Add(time.Now())
+        }
+    }
+}
+
+// retryDelay is the backoff before the given attempt, doubling from
+// retryBaseDelay up to retryMaxDelay.
+func retryDelay(attempts int) time.Duration {
+    if attempts < 1 {
+        return 0
+    }
+    delay := retryBaseDelay << (attempts - 1)
+    if delay > retryMaxDelay || delay <= 0 {
+        delay = retryMaxDelay
+    }
+    return delay
+}
+
+// retry schedules a failed delivery to be processed again after a backoff,
+// or moves it to the dead letter file once it failed maxDeliveryAttempts
+// times. Deliveries interrupted by shutdown are kept without counting the
+// attempt.
+func (q *Queue) retry(ctx context.Context, item queuedEnvelope, err error) {
+    if ctx.Err() == nil {
+        item.Attempts++
+    }
+    if item.Attempts >= maxDeliveryAttempts {
+        q.logger.Errorf("giving up on Slack delivery %s after %d attempts: %v", item.Envelope.EventID, item.Attempts, err)
+        q.deadLetter(item)
+        return
+    }
+    delay := retryDelay(item.Attempts)
+    item.retryAt = time.Now().Add(delay)
+    q.logger.Warnf("failed to process Slack delivery %s, retrying in %s: %v", item.Envelope.EventID, delay, err)
+
+    q.retryMu.Lock()
+    defer q.retryMu.Unlock()
+    q.retries = append(q.retries, item)
+}
+
+// retryDue puts deliveries whose backoff ran out back in line.
+func (q *Queue) retryDue(now time.Time) {
+    q.retryMu.Lock()
+    due := []queuedEnvelope{}
+    waiting := q.retries[:0]
+    for _, item := range q.retries {
+        if now.Before(item.retryAt) {
+            waiting = append(waiting, item)
+        } else {
+            due = append(due, item)
+        }
+    }
+    q.retries = waiting
+    q.retryMu.Unlock()
+
+    for _, item := range due {
+        if err := q.requeue(item); err != nil {
+            q.logger.Errorf("failed to requeue Slack delivery %s: %v", item.Envelope.EventID, err)
+            q.deadLetter(item)
+        }
+    }
+}
+
+// deadLetter keeps a delivery that could not be processed, for an operator
+// to inspect or replay.
+func (q *Queue) deadLetter(item queuedEnvelope) {
+    q.deadLetterMu.Lock()
+    defer q.deadLetterMu.Unlock()
+
+    if err := appendItems(filepath.Join(q.spillDir, deadLetterFileName), []queuedEnvelope{item}); err != nil {
+        q.logger.Errorf("failed to keep Slack delivery %s in the dead letter file: %v", item.Envelope.EventID, err)
+        q.dropped.Add(1)
+        return
+    }
+    q.deadLettered.Add(1)
+}
+
+// refill moves spilled deliveries back into memory as room frees up.
+func (q *Queue) refill() {
+    for q.refillSegment() {
+    }
+}
+
+// refillSegment moves deliveries of the oldest segment back into memory and
+// reports whether all of them fit. The segment is sealed first so Enqueue no
+// longer appends to it, then read and rewritten outside of mu.
+func (q *Queue) refillSegment() bool {
+    q.mu.Lock()
+    if q.spilled == 0 || len(q.items) == cap(q.items) {
+        q.mu.Unlock()
+        return false
+    }
+    if len(q.segments) == 1 {
+        q.segments = append(q.segments, spillSegment{seq: q.nextSeq})
+        q.nextSeq++
+    }
+    head := q.segments[0]
+    q.mu.Unlock()
+
+    pending, err := q.readItems(q.segmentPath(head.seq))
+    if err != nil {
+        q.logger.Errorf("failed to read ingest spill segment: %v", err)
+        return false
+    }
+
+    moved := 0
+    for _, item := range pending {
+        select {
+        case q.items <- item:
+            moved++
+            continue
+        default:
+        }
+        break
+    }
+
+    // Left as is on failure, the moved deliveries are read again and
+    // skipped as duplicates
+    if err := writeItems(q.segmentPath(head.seq), pending[moved:]); err != nil {
+        q.logger.Errorf("failed to rewrite ingest spill segment: %v", err)
+        return false
+    }
+
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    remaining := len(pending) - moved
+    q.spilled -= head.count - remaining
+    if remaining > 0 {
+        q.segments[0].count = remaining
+        return false
+    }
+    q.segments = q.segments[1:]
+    return true
+}
+
+// flush spills everything still buffered in memory or waiting to be
+// retried, ahead of what is already on disk.
+func (q *Queue) flush() {
+    q.retryMu.Lock()
+    buffered := q.retries
+    q.retries = nil
+    q.retryMu.Unlock()
+    for {
+        select {
+        case item := <-q.items:
+            buffered = append(buffered, item)
+            continue
+        default:
+        }
+        break
+    }
+    if len(buffered) == 0 {
+        return
+    }
+
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    seq := q.nextSeq
+    if len(q.segments) > 0 {
+        seq = q.segments[0].seq - 1
+    }
+    if err := writeItems(q.segmentPath(seq), buffered); err != nil {
+        q.logger.Errorf("failed to spill %d buffered Slack deliveries: %v", len(buffered), err)
+        q.dropped.Add(int64(len(buffered)))
+        return
+    }
+    q.segments = append([]spillSegment{{seq: seq, count: len(buffered)}}, q.segments...)
+    q.spilled += len(buffered)
+    q.logger.Infof("spilled %d buffered Slack deliveries to disk", len(buffered))
+}
+
+func (q *Queue) segmentPath(seq int) string {
+    return filepath.Join(q.spillDir, fmt.Sprintf("%s%010d%s", spillSegmentPrefix, seq, spillSegmentSuffix))
+}
+
+func appendItems(path string, items []queuedEnvelope) error {
+    file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    encoder := json.NewEncoder(file)
+    for _, item := range items {
+        if err := encoder.Encode(item); err != nil {
+            return err
+        }
+    }
+    return file.Sync()
+}
+
+func (q *Queue) readItems(path string) ([]queuedEnvelope, error) {
+    file, err := os.Open(path)
+    if os.IsNotExist(err) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    items := []queuedEnvelope{}
+    scanner := bufio.NewScanner(file)
+    scanner.Buffer(make([]byte, 64*1024), 4<<20)
+    for scanner.Scan() {
+        var item queuedEnvelope
+        if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+            q.logger.Warnf("skipping corrupt line in ingest spill segment: %v", err)
+            continue
+        }
+        items = append(items, item)
+    }
+    return items, scanner.Err()
+}
+
+func writeItems(path string, items []queuedEnvelope) error {
+    if len(items) == 0 {
+        err := os.Remove(path)
+        if os.IsNotExist(err) {
+            return nil
+        }
+        return err
+    }
+
+    tmp := path + ".tmp"
+    if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+        return err
+    }
+    file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY, 0o600)
+    if err != nil {
+        return err
+    }
+    encoder := json.NewEncoder(file)
+    for _, item := range items {
+        if err := encoder.Encode(item); err != nil {
+            file.Close()
+            return err
+        }
+    }
+    if err := file.Sync(); err != nil {
+        file.Close()
+        return err
+    }
+    if err := file.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp, path)
+}
+
+// Stats returns the current depth, counters, lag and processing rate.
+func (q *Queue) Stats() QueueStats {
+    q.mu.Lock()
+    spilled := q.spilled
+    q.mu.Unlock()
+    q.retryMu.Lock()
+    retrying := len(q.retries)
+    q.retryMu.Unlock()
+
+    return QueueStats{
+        Depth:         len(q.items),
+        Capacity:      cap(q.items),
+        Spilled:       spilled,
+        Retrying:      retrying,
+        Enqueued:      q.enqueued.Load(),
+        SpilledTotal:  q.spilledTotal.Load(),
+        Processed:     q.processed.Load(),
+        Failed:        q.failed.Load(),
+        DeadLettered:  q.deadLettered.Load(),
+        Dropped:       q.dropped.Load(),
+        LagSeconds:    time.Duration(q.lagNanos.Load()).Seconds(),
+        RatePerSecond: q.rate.PerSecond(time.Now()),
+    }
+}
+
+// rateCounter counts events over a sliding one minute window.
+type rateCounter struct {
+    mu      sync.Mutex
+    buckets [60]int64
+    seconds [60]int64
+}
+
+func newRateCounter() *rateCounter {
+    return &rateCounter{}
+}
+
+func (r *rateCounter) Add(now time.Time) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    sec := now.Unix()
+    idx := sec % int64(len(r.buckets))
+    if r.seconds[idx] != sec {
+        r.seconds[idx] = sec
+        r.buckets[idx] = 0
+    }
+    r.buckets[idx]++
+}
+
+func (r *rateCounter) PerSecond(now time.Time) float64 {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    var total int64
+    cutoff := now.Unix() - int64(len(r.buckets))
+    for i, sec := range r.seconds {
+        if sec > cutoff {
+            total += r.buckets[i]
+        }
+    }
+    return float64(total) / float64(len(r.buckets))
+}
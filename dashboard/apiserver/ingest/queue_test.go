@@ -0,0 +1,266 @@
+package ingest
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "dashboard/apiserver/logger"
+)
+
+func newTestQueue(t *testing.T, dir string, config QueueConfig, process func(ctx context.Context, env Envelope) error) *Queue {
+    t.Helper()
+    log, err := logger.NewLogger(logger.Error)
+    if err != nil {
+        t.Fatal(err)
+    }
+    config.SpillDir = dir
+    q, err := NewQueue(log, config, process)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return q
+}
+
+func envelope(i int) Envelope {
+    return Envelope{EventID: fmt.Sprintf("Ev%04d", i)}
+}
+
+func drain(q *Queue) []string {
+    ids := []string{}
+    for {
+        select {
+        case item := <-q.items:
+            ids = append(ids, item.Envelope.EventID)
+            continue
+        default:
+        }
+        return ids
+    }
+}
+
+func TestQueueSpillsInOrder(t *testing.T) {
+    dir := t.TempDir()
+    q := newTestQueue(t, dir, QueueConfig{Capacity: 10}, nil)
+
+    total := 10 + 2*spillSegmentSize + 5
+    for i := 0; i < total; i++ {
+        if err := q.Enqueue(envelope(i)); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if got := q.Stats().Spilled; got != total-10 {
+        t.Fatalf("got %d spilled, want %d", got, total-10)
+    }
+    if len(q.segments) != 3 {
+        t.Fatalf("got %d spill segments, want 3", len(q.segments))
+    }
+
+    seen := []string{}
+    for q.Stats().Spilled > 0 || len(q.items) > 0 {
+        seen = append(seen, drain(q)...)
+        q.refill()
+    }
+    if len(seen) != total {
+        t.Fatalf("got %d deliveries back, want %d", len(seen), total)
+    }
+    for i, id := range seen {
+        if want := envelope(i).EventID; id != want {
+            t.Fatalf("delivery %d is %s, want %s", i, id, want)
+        }
+    }
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(entries) != 0 {
+        t.Fatalf("%d files left in the spill directory", len(entries))
+    }
+}
+
+func TestQueueKeepsOrderWhileRefilling(t *testing.T) {
+    q := newTestQueue(t, t.TempDir(), QueueConfig{Capacity: 2}, nil)
+    for i := 0; i < 5; i++ {
+        if err := q.Enqueue(envelope(i)); err != nil {
+            t.Fatal(err)
+        }
+    }
+    seen := drain(q)
+    q.refill()
+    // Arrives while older deliveries are still on disk
+    if err := q.Enqueue(envelope(5)); err != nil {
+        t.Fatal(err)
+    }
+    for q.Stats().Spilled > 0 || len(q.items) > 0 {
+        seen = append(seen, drain(q)...)
+        q.refill()
+    }
+    for i, id := range seen {
+        if want := envelope(i).EventID; id != want {
+            t.Fatalf("delivery %d is %s, want %s", i, id, want)
+        }
+    }
+}
+
+func TestQueueResumesSpill(t *testing.T) {
+    dir := t.TempDir()
+    q := newTestQueue(t, dir, QueueConfig{Capacity: 1}, nil)
+    for i := 0; i < 4; i++ {
+        if err := q.Enqueue(envelope(i)); err != nil {
+            t.Fatal(err)
+        }
+    }
+    q.flush()
+
+    resumed := newTestQueue(t, dir, QueueConfig{Capacity: 10}, nil)
+    if got := resumed.Stats().Spilled; got != 4 {
+        t.Fatalf("got %d spilled after restart, want 4", got)
+    }
+    resumed.refill()
+    seen := drain(resumed)
+    for i, id := range seen {
+        if want := envelope(i).EventID; id != want {
+            t.Fatalf("delivery %d is %s, want %s", i, id, want)
+        }
+    }
+    if len(seen) != 4 {
+        t.Fatalf("got %d deliveries back, want 4", len(seen))
+    }
+}
+
+func TestQueueResumesLegacySpillFile(t *testing.T) {
+    dir := t.TempDir()
+    if err := appendItems(filepath.Join(dir, spillFileName), []queuedEnvelope{{Envelope: envelope(0)}, {Envelope: envelope(1)}}); err != nil {
+        t.Fatal(err)
+    }
+    q := newTestQueue(t, dir, QueueConfig{Capacity: 10}, nil)
+    if got := q.Stats().Spilled; got != 2 {
+        t.Fatalf("got %d spilled, want 2", got)
+    }
+    if err := q.Enqueue(envelope(2)); err != nil {
+        t.Fatal(err)
+    }
+    q.refill()
+    q.refill()
+    if seen := drain(q); len(seen) != 3 || seen[0] != envelope(0).EventID || seen[2] != envelope(2).EventID {
+        t.Fatalf("got deliveries %v", seen)
+    }
+}
+
+func TestQueueRefusesPastMaxSpilled(t *testing.T) {
+    q := newTestQueue(t, t.TempDir(), QueueConfig{Capacity: 1, MaxSpilled: 2}, nil)
+    for i := 0; i < 3; i++ {
+        if err := q.Enqueue(envelope(i)); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := q.Enqueue(envelope(3)); !errors.Is(err, ErrSpillFull) {
+        t.Fatalf("got error %v, want %v", err, ErrSpillFull)
+    }
+    if got := q.Stats().Dropped; got != 1 {
+        t.Fatalf("got %d dropped, want 1", got)
+    }
+}
+
+func TestQueueRetriesFailedDeliveries(t *testing.T) {
+    attempts := 0
+    q := newTestQueue(t, t.TempDir(), QueueConfig{Capacity: 10}, func(ctx context.Context, env Envelope) error {
+        attempts++
+        if attempts < 3 {
+            return errors.New("database unavailable")
+        }
+        return nil
+    })
+    if err := q.Enqueue(envelope(0)); err != nil {
+        t.Fatal(err)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    go func() {
+        q.work(ctx)
+        close(done)
+    }()
+    deadline := time.Now().Add(5 * time.Second)
+    now := time.Now()
+    for q.Stats().Processed == 0 {
+        if time.Now().After(deadline) {
+            t.Fatal("delivery was not retried")
+        }
+        // Skip the backoff
+        now = now.Add(retryMaxDelay)
+        q.retryDue(now)
+        time.Sleep(10 * time.Millisecond)
+    }
+    cancel()
+    <-done
+
+    stats := q.Stats()
+    if stats.Failed != 2 || stats.Retrying != 0 || stats.DeadLettered != 0 {
+        t.Fatalf("got %+v", stats)
+    }
+}
+
+func TestQueueDeadLettersAfterMaxAttempts(t *testing.T) {
+    dir := t.TempDir()
+    q := newTestQueue(t, dir, QueueConfig{Capacity: 10}, nil)
+    item := queuedEnvelope{Envelope: envelope(0)}
+    for i := 0; i < maxDeliveryAttempts; i++ {
+        q.retry(context.Background(), item, errors.New("bad payload"))
+        q.retryMu.Lock()
+        if len(q.retries) > 0 {
+            item = q.retries[0]
+            q.retries = nil
+        }
+        q.retryMu.Unlock()
+    }
+
+    if got := q.Stats().DeadLettered; got != 1 {
+        t.Fatalf("got %d dead lettered, want 1", got)
+    }
+    items, err := q.readItems(filepath.Join(dir, deadLetterFileName))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(items) != 1 || items[0].Attempts != maxDeliveryAttempts {
+        t.Fatalf("got dead letters %+v", items)
+    }
+}
+
+func TestQueueKeepsRetriesOnShutdown(t *testing.T) {
+    dir := t.TempDir()
+    q := newTestQueue(t, dir, QueueConfig{Capacity: 10}, nil)
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    q.retry(ctx, queuedEnvelope{Envelope: envelope(0)}, context.Canceled)
+    q.flush()
+
+    resumed := newTestQueue(t, dir, QueueConfig{Capacity: 10}, nil)
+    resumed.refill()
+    seen := drain(resumed)
+    if len(seen) != 1 || seen[0] != envelope(0).EventID {
+        t.Fatalf("got deliveries %v", seen)
+    }
+}
+
+func TestRetryDelay(t *testing.T) {
+    tests := []struct {
+        attempts int
+        want     time.Duration
+    }{
+        {1, retryBaseDelay},
+        {2, 2 * retryBaseDelay},
+        {3, 4 * retryBaseDelay},
+        {30, retryMaxDelay},
+        {100, retryMaxDelay},
+    }
+    for _, tt := range tests {
+        if got := retryDelay(tt.attempts); got != tt.want {
+            t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+        }
+    }
+}
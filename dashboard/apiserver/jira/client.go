@@ -0,0 +1,224 @@
+// Package jira is a minimal Jira REST API client.
+package jira
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "regexp"
+    "strings"
+    "time"
+)
+
+// ErrNotConfigured is returned by every call of a client without a site or
+// token.
+var ErrNotConfigured = errors.New("jira site or token is not configured")
+
+// keyPattern matches issue keys such as "DB-123".
+var keyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-[0-9]+$`)
+
+// projectPattern matches project keys such as "DB".
+var projectPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+$`)
+
+// ValidKey reports whether key is an issue key.
+func ValidKey(key string) bool {
+    return keyPattern.MatchString(key)
+}
+
+// ValidProject reports whether project is a project key.
+func ValidProject(project string) bool {
+    return projectPattern.MatchString(project)
+}
+
+// APIError is a response with an unexpected status.
+type APIError struct {
+    Path     string
+    Status   int
+    Messages []string
+}
+
+func (e *APIError) Error() string {
+    return fmt.Sprintf("jira %s: status %d: %s", e.Path, e.Status, strings.Join(e.Messages, "; "))
+}
+
+// Client calls the REST API of a Jira site. Jira Cloud authenticates with
+// the email of an account and an API token, Jira Data Center with a personal
+// access token alone.
+type Client struct {
+    baseURL    string
+    email      string
+    token      string
+    httpClient *http.Client
+}
+
+// NewClient returns a client for the Jira site at baseURL.
+func NewClient(baseURL string, email string, token string) *Client {
+    return &Client{
+        baseURL:    strings.TrimSuffix(baseURL, "/"),
+        email:      email,
+        token:      token,
+        httpClient: &http.Client{Timeout: 30 * time.Second},
+    }
+}
+
+// Configured reports whether the client has a site and a token.
+func (c *Client) Configured() bool {
+    return c.baseURL != "" && c.token != ""
+}
+
+// BrowseURL returns the link of an issue on the site.
+func (c *Client) BrowseURL(key string) string {
+    return c.baseURL + "/browse/" + key
+}
+
+func (c *Client) post(ctx context.Context, path string, in interface{}, out interface{}) error {
+    if !c.Configured() {
+        return ErrNotConfigured
+    }
+    encoded, err := json.Marshal(in)
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(encoded))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Accept", "application/json")
+    if c.email != "" {
+        req.SetBasicAuth(c.email, c.token)
+    } else {
+        req.Header.Set("Authorization", "Bearer "+c.token)
+    }
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+        return &APIError{Path: path, Status: resp.StatusCode, Messages: errorMessages(body)}
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// errorMessages extracts the messages of a Jira error response.
+func errorMessages(body []byte) []string {
+    var payload struct {
+        ErrorMessages []string          `json:"errorMessages"`
+        Errors        map[string]string `json:"errors"`
+    }
+    if err := json.Unmarshal(body, &payload); err != nil {
+        return []string{strings.TrimSpace(string(body))}
+    }
+    messages := payload.ErrorMessages
+    for field, message := range payload.Errors {
+        messages = append(messages, field+": "+message)
+    }
+    return messages
+}
+
+// NewIssue is the body of CreateIssue.
+type NewIssue struct {
+    Project     string
+    IssueType   string
+    Summary     string
+    Description string
+    Labels      []string
+}
+
+// CreatedIssue identifies an issue created by CreateIssue.
+type CreatedIssue struct {
+    ID   string `json:"id"`
+    Key  string `json:"key"`
+    Self string `json:"self"`
+}
+
+// CreateIssue creates an issue. Version 2 of the API is used since it takes
+// the description as plain text on both Jira Cloud and Data Center.
+func (c *Client) CreateIssue(ctx context.Context, issue NewIssue) (*CreatedIssue, error) {
+    if !ValidProject(issue.Project) {
+        return nil, fmt.Errorf("jira: invalid project %q", issue.Project)
+    }
+    fields := map[string]interface{}{
+        "project":     map[string]string{"key": issue.Project},
+        "issuetype":   map[string]string{"name": issue.IssueType},
+        "summary":     issue.Summary,
+        "description": issue.Description,
+    }
+    if len(issue.Labels) > 0 {
+        fields["labels"] = issue.Labels
+    }
+    var created CreatedIssue
+    if err := c.post(ctx, "/rest/api/2/issue", map[string]interface{}{"fields": fields}, &created); err != nil {
+        return nil, err
+    }
+    return &created, nil
+}
+
+// Comment identifies a comment created by AddComment.
+type Comment struct {
+    ID   string `json:"id"`
+    Self string `json:"self"`
+}
+
+// AddComment comments body, in plain text, on the issue key.
+func (c *Client) AddComment(ctx context.Context, key string, body string) (*Comment, error) {
+    if !ValidKey(key) {
+        return nil, fmt.Errorf("jira: invalid issue key %q", key)
+    }
+    var created Comment
+    if err := c.post(ctx, "/rest/api/2/issue/"+key+"/comment", map[string]string{"body": body}, &created); err != nil {
+        return nil, err
+    }
+    return &created, nil
+}
+
+// IssueEvent is the part of an issue webhook the dashboard reads.
+type IssueEvent struct {
+    WebhookEvent string `json:"webhookEvent"`
+    Issue        struct {
+        Key    string `json:"key"`
+        Fields struct {
+            Status struct {
+                Name           string `json:"name"`
+                StatusCategory struct {
+                    Key string `json:"key"`
+                } `json:"statusCategory"`
+            } `json:"status"`
+        } `json:"fields"`
+    } `json:"issue"`
+    User struct {
+        DisplayName string `json:"displayName"`
+    } `json:"user"`
+    Changelog struct {
+        Items []struct {
+            Field      string `json:"field"`
+            FromString string `json:"fromString"`
+            ToString   string `json:"toString"`
+        } `json:"items"`
+    } `json:"changelog"`
+}
+
+// StatusCategoryDone is the status category of finished issues.
+const StatusCategoryDone = "done"
+
+// StatusChange returns the previous and new status of an issue updated
+// event. ok is false for events that did not transition the issue.
+func (e *IssueEvent) StatusChange() (from string, to string, ok bool) {
+    if e.WebhookEvent != "jira:issue_updated" {
+        return "", "", false
+    }
+    for _, item := range e.Changelog.Items {
+        if item.Field == "status" {
+            return item.FromString, item.ToString, true
+        }
+    }
+    return "", "", false
+}
@@ -0,0 +1,55 @@
+package apiserver
+
+import (
+    "context"
+    "sync"
+)
+
+// jobs runs the background jobs of the server until shutdown, when they are
+// cancelled and waited for.
+type jobs struct {
+    ctx    context.Context
+    cancel context.CancelFunc
+    wg     sync.WaitGroup
+}
+
+func newJobs() *jobs {
+    ctx, cancel := context.WithCancel(context.Background())
+    return &jobs{ctx: ctx, cancel: cancel}
+}
+
+// context returns the context the jobs run with until they are stopped.
+func (j *jobs) context() context.Context {
+    return j.ctx
+}
+
+// run starts job with the context of the jobs.
+func (j *jobs) run(job func(ctx context.Context)) {
+    j.runWith(j.ctx, job)
+}
+
+// runWith starts job with ctx, which must be derived from the context of the
+// jobs.
+func (j *jobs) runWith(ctx context.Context, job func(ctx context.Context)) {
+    j.wg.Add(1)
+    go func() {
+        defer j.wg.Done()
+        job(ctx)
+    }()
+}
+
+// stop cancels the jobs and waits for them to return, or for ctx to be done.
+func (j *jobs) stop(ctx context.Context) error {
+    j.cancel()
+    done := make(chan struct{})
+    go func() {
+        j.wg.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
@@ -0,0 +1,28 @@
+package apiserver
+
+import (
+    "dashboard/apiserver/handlers"
+    "dashboard/apiserver/logger"
+)
+
+// Migrate applies the pending schema migrations to the shared and workspace
+// databases without starting the server. It returns the process exit code,
+// non-zero when a migration failed.
+func Migrate() int {
+    log, _ := logger.NewLogger(logger.Info)
+    defer log.Cleanup()
+
+    c, err := handlers.NewContainer(log)
+    if err != nil {
+        log.Errorf("invalid configuration: %v", err)
+        return 1
+    }
+    defer c.Close()
+
+    if err := c.EnsureSchema(); err != nil {
+        log.Errorf("failed to migrate the database schema: %v", err)
+        return 1
+    }
+    log.Infof("database schema is up to date")
+    return 0
+}
@@ -0,0 +1,180 @@
+// Package migrations versions the schema of the dashboard databases. Each
+// migration is an embedded SQL file named VERSION_NAME.up.sql, applied once
+// per database in the order of versions and recorded in schema_migrations.
+// Released migrations must never be edited, only followed by new ones.
+package migrations
+
+import (
+    "context"
+    "database/sql"
+    "embed"
+    "fmt"
+    "io/fs"
+    "regexp"
+    "sort"
+    "strconv"
+)
+
+//go:embed sql/*.up.sql
+var files embed.FS
+
+// filePattern matches the names of migration files.
+var filePattern = regexp.MustCompile(`^(\d+)_(\w+)\.up\.sql$`)
+
+// createTablePattern finds the tables created by migrations.
+var createTablePattern = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+)`)
+
+// dropTablePattern finds the tables dropped by migrations.
+var dropTablePattern = regexp.MustCompile(`DROP TABLE IF EXISTS (\w+)`)
+
+// Migration is a versioned change of the schema.
+type Migration struct {
+    Version int
+    Name    string
+    SQL     string
+}
+
+// All returns the embedded migrations by version.
+func All() ([]Migration, error) {
+    entries, err := fs.ReadDir(files, "sql")
+    if err != nil {
+        return nil, err
+    }
+
+    all := []Migration{}
+    seen := map[int]string{}
+    for _, entry := range entries {
+        m := filePattern.FindStringSubmatch(entry.Name())
+        if m == nil {
+            return nil, fmt.Errorf("migrations: unexpected file %s", entry.Name())
+        }
+        version, _ := strconv.Atoi(m[1])
+        if other, ok := seen[version]; ok {
+            return nil, fmt.Errorf("migrations: %s and %s have the same version", other, entry.Name())
+        }
+        seen[version] = entry.Name()
+
+        content, err := files.ReadFile("sql/" + entry.Name())
+        if err != nil {
+            return nil, err
+        }
+        all = append(all, Migration{Version: version, Name: m[2], SQL: string(content)})
+    }
+    sort.Slice(all, func(i, j int) bool {
+        return all[i].Version < all[j].Version
+    })
+    return all, nil
+}
+
+// Tables returns the tables created by the migrations and not dropped by
+// a later one.
+func Tables() ([]string, error) {
+    all, err := All()
+    if err != nil {
+        return nil, err
+    }
+    tables := []string{}
+    for _, m := range all {
+        for _, match := range createTablePattern.FindAllStringSubmatch(m.SQL, -1) {
+            tables = append(tables, match[1])
+        }
+        for _, match := range dropTablePattern.FindAllStringSubmatch(m.SQL, -1) {
+            for i, table := range tables {
+                if table == match[1] {
+                    tables = append(tables[:i], tables[i+1:]...)
+                    break
+                }
+            }
+        }
+    }
+    return tables, nil
+}
+
+// Schema is a database migrations are applied to.
+type Schema interface {
+    // Version returns the latest migration applied, 0 before the first.
+    Version(ctx context.Context) (int, error)
+    // Migrate runs m and records it, or does neither when it fails.
+    Migrate(ctx context.Context, m Migration) error
+}
+
+// Postgres returns the schema of db, recorded in schema_migrations.
+func Postgres(db *sql.DB) Schema {
+    return postgresSchema{db}
+}
+
+type postgresSchema struct {
+    db *sql.DB
+}
+
+// Pending returns the migrations schema has not seen yet.
+func Pending(ctx context.Context, schema Schema) ([]Migration, error) {
+    all, err := All()
+    if err != nil {
+        return nil, err
+    }
+    current, err := schema.Version(ctx)
+    if err != nil {
+        return nil, err
+    }
+    pending := []Migration{}
+    for _, m := range all {
+        if m.Version > current {
+            pending = append(pending, m)
+        }
+    }
+    return pending, nil
+}
+
+// Apply applies the pending migrations of schema in order and returns those
+// applied. It stops at the first failing one.
+func Apply(ctx context.Context, schema Schema) ([]Migration, error) {
+    pending, err := Pending(ctx, schema)
+    if err != nil {
+        return nil, err
+    }
+
+    applied := []Migration{}
+    for _, m := range pending {
+        if err := schema.Migrate(ctx, m); err != nil {
+            return applied, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+        }
+        applied = append(applied, m)
+    }
+    return applied, nil
+}
+
+func (s postgresSchema) Version(ctx context.Context) (int, error) {
+    // The table recording the applied migrations is created first
+    _, err := s.db.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INT PRIMARY KEY,
+            name TEXT NOT NULL,
+            applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`)
+    if err != nil {
+        return 0, err
+    }
+    var current int
+    err = s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+    return current, err
+}
+
+// Migrate runs m in a transaction. Another server applying it at the same
+// time makes the insert conflict and one of the transactions roll back.
+func (s postgresSchema) Migrate(ctx context.Context, m Migration) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    // Without arguments the statements of the file are sent at once
+    if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+        return err
+    }
+    if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
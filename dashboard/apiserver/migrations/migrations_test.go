@@ -0,0 +1,173 @@
+package migrations
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "regexp"
+    "strings"
+    "testing"
+)
+
+// functionPattern finds the functions created by migrations with their body.
+var functionPattern = regexp.MustCompile(`(?s)CREATE OR REPLACE FUNCTION (\w+)\(.*?\$\$(.*?)\$\$`)
+
+// dropFunctionPattern finds the functions dropped by migrations.
+var dropFunctionPattern = regexp.MustCompile(`DROP FUNCTION IF EXISTS (\w+)\(`)
+
+// threadDeletePattern finds statements deleting rows of threads.
+var threadDeletePattern = regexp.MustCompile(`(?i)DELETE FROM threads\b|TRUNCATE (TABLE )?threads\b|DROP TABLE (IF EXISTS )?threads\b`)
+
+// memorySchema records the migrations applied to it. Migrating failVersion
+// fails.
+type memorySchema struct {
+    applied     []int
+    failVersion int
+}
+
+func (s *memorySchema) Version(context.Context) (int, error) {
+    current := 0
+    for _, version := range s.applied {
+        current = max(current, version)
+    }
+    return current, nil
+}
+
+func (s *memorySchema) Migrate(_ context.Context, m Migration) error {
+    if m.Version == s.failVersion {
+        return errors.New("syntax error")
+    }
+    s.applied = append(s.applied, m.Version)
+    return nil
+}
+
+func versions(migrations []Migration) []int {
+    list := []int{}
+    for _, m := range migrations {
+        list = append(list, m.Version)
+    }
+    return list
+}
+
+func TestAll(t *testing.T) {
+    all, err := All()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(all) == 0 {
+        t.Fatal("got no migrations")
+    }
+    // Versions follow each other, a gap is a missing or misnamed file
+    for i, m := range all {
+        if m.Version != i+1 {
+            t.Fatalf("got versions %v, want 1 to %d", versions(all), len(all))
+        }
+        if m.Name == "" || strings.TrimSpace(m.SQL) == "" {
+            t.Errorf("migration %d has no name or no statements", m.Version)
+        }
+    }
+}
+
+func TestTables(t *testing.T) {
+    tables, err := Tables()
+    if err != nil {
+        t.Fatal(err)
+    }
+    seen := map[string]bool{}
+    for _, table := range tables {
+        if seen[table] {
+            t.Errorf("table %s is created by several migrations", table)
+        }
+        seen[table] = true
+    }
+    for _, table := range []string{"channels", "user_profiles", "threads"} {
+        if !seen[table] {
+            t.Errorf("got tables %v, want %s among them", tables, table)
+        }
+    }
+    // Tables dropped by a later migration are not expected
+    if seen["thread_table_mirrors"] {
+        t.Errorf("got tables %v, want the dropped thread_table_mirrors left out", tables)
+    }
+}
+
+func TestThreadsAreNeverDeleted(t *testing.T) {
+    all, err := All()
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Threads may be under legal hold, so no migration deletes them and no
+    // function deleting them, like the mirror of the channel tables, is
+    // left behind
+    deleting := map[string]int{}
+    for _, m := range all {
+        statements := m.SQL
+        for _, match := range functionPattern.FindAllStringSubmatch(m.SQL, -1) {
+            delete(deleting, match[1])
+            if threadDeletePattern.MatchString(match[2]) {
+                deleting[match[1]] = m.Version
+            }
+            statements = strings.Replace(statements, match[0], "", 1)
+        }
+        if threadDeletePattern.MatchString(statements) {
+            t.Errorf("migration %d (%s) deletes threads", m.Version, m.Name)
+        }
+        for _, match := range dropFunctionPattern.FindAllStringSubmatch(m.SQL, -1) {
+            delete(deleting, match[1])
+        }
+    }
+    for name, version := range deleting {
+        t.Errorf("function %s of migration %d deletes threads and is never dropped", name, version)
+    }
+}
+
+func TestApply(t *testing.T) {
+    all, err := All()
+    if err != nil {
+        t.Fatal(err)
+    }
+    schema := &memorySchema{applied: []int{1, 2}}
+    applied, err := Apply(context.Background(), schema)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got, want := fmt.Sprint(versions(applied)), fmt.Sprint(versions(all[2:])); got != want {
+        t.Fatalf("applied %s, want the migrations after 2 %s", got, want)
+    }
+    if current, _ := schema.Version(context.Background()); current != len(all) {
+        t.Fatalf("recorded version %d, want %d", current, len(all))
+    }
+
+    // Applying again finds nothing to do
+    applied, err = Apply(context.Background(), schema)
+    if err != nil || len(applied) != 0 {
+        t.Fatalf("applied %v (%v) to an up to date database", versions(applied), err)
+    }
+}
+
+func TestApplyFailure(t *testing.T) {
+    all, err := All()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(all) < 3 {
+        t.Skip("needs three migrations")
+    }
+    schema := &memorySchema{failVersion: all[2].Version}
+
+    // Migrations before the failing one stay applied, the following ones
+    // are not attempted
+    applied, err := Apply(context.Background(), schema)
+    if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("migration %d (%s)", all[2].Version, all[2].Name)) {
+        t.Fatalf("got error %v, want the failing migration named", err)
+    }
+    if fmt.Sprint(versions(applied)) != "[1 2]" || fmt.Sprint(schema.applied) != "[1 2]" {
+        t.Fatalf("applied %v and recorded %v, want [1 2]", versions(applied), schema.applied)
+    }
+
+    schema.failVersion = 0
+    applied, err = Apply(context.Background(), schema)
+    if err != nil || len(applied) != len(all)-2 {
+        t.Fatalf("applied %v (%v) once fixed, want the remaining migrations", versions(applied), err)
+    }
+}
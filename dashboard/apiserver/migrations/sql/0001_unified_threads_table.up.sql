@@ -0,0 +1,123 @@
+-- A single table holding the threads of every channel, kept in sync with the
+-- per-channel tables of the collector by the mirror_channel_thread trigger
+-- function. Failing to mirror a row only warns, the write to the channel
+-- table goes through.
+
+CREATE TABLE IF NOT EXISTS threads (
+    thread_ts TEXT NOT NULL,
+    channel_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    reply_count INTEGER DEFAULT 0,
+    latest_reply TIMESTAMP,
+    status TEXT DEFAULT 'open',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    ai_thread_name TEXT,
+    ai_description TEXT,
+    ai_stakeholders TEXT DEFAULT '[]',
+    ai_priority VARCHAR(10),
+    ai_confidence DECIMAL(3,2),
+    github_issue TEXT,
+    jira_ticket TEXT,
+    thread_issue TEXT,
+    ai_analysis_json TEXT,
+    last_bot_message_ts TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_by VARCHAR(50),
+    resolved_at TIMESTAMP,
+    PRIMARY KEY (channel_id, thread_ts)
+);
+
+CREATE INDEX IF NOT EXISTS threads_latest_reply_idx ON threads (latest_reply DESC);
+
+CREATE INDEX IF NOT EXISTS threads_channel_latest_reply_idx ON threads (channel_id, latest_reply DESC);
+
+CREATE INDEX IF NOT EXISTS threads_created_at_idx ON threads (created_at);
+
+CREATE INDEX IF NOT EXISTS threads_status_idx ON threads (status);
+
+CREATE TABLE IF NOT EXISTS thread_table_mirrors (
+    channel_id VARCHAR(50) PRIMARY KEY,
+    table_name VARCHAR(100) NOT NULL,
+    mirrored_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Rows are read through JSON so columns a channel table lacks, e.g.
+-- resolved_by before anyone resolved a thread, are NULL
+CREATE OR REPLACE FUNCTION mirror_channel_thread() RETURNS TRIGGER AS $$
+DECLARE
+    r JSONB;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        DELETE FROM threads WHERE channel_id = OLD.channel_id AND thread_ts = OLD.thread_ts;
+        RETURN OLD;
+    END IF;
+    r := to_jsonb(NEW);
+    INSERT INTO threads (
+        thread_ts,
+        channel_id,
+        user_id,
+        reply_count,
+        latest_reply,
+        status,
+        created_at,
+        ai_thread_name,
+        ai_description,
+        ai_stakeholders,
+        ai_priority,
+        ai_confidence,
+        github_issue,
+        jira_ticket,
+        thread_issue,
+        ai_analysis_json,
+        last_bot_message_ts,
+        updated_at,
+        resolved_by,
+        resolved_at
+    )
+    VALUES (
+        r->>'thread_ts',
+        r->>'channel_id',
+        r->>'user_id',
+        (r->>'reply_count')::INTEGER,
+        (r->>'latest_reply')::TIMESTAMP,
+        r->>'status',
+        (r->>'created_at')::TIMESTAMP,
+        r->>'ai_thread_name',
+        r->>'ai_description',
+        r->>'ai_stakeholders',
+        (r->>'ai_priority')::VARCHAR(10),
+        (r->>'ai_confidence')::DECIMAL(3,2),
+        r->>'github_issue',
+        r->>'jira_ticket',
+        r->>'thread_issue',
+        r->>'ai_analysis_json',
+        (r->>'last_bot_message_ts')::TIMESTAMP,
+        (r->>'updated_at')::TIMESTAMP,
+        (r->>'resolved_by')::VARCHAR(50),
+        (r->>'resolved_at')::TIMESTAMP
+    )
+    ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+        user_id = EXCLUDED.user_id,
+        reply_count = EXCLUDED.reply_count,
+        latest_reply = EXCLUDED.latest_reply,
+        status = EXCLUDED.status,
+        created_at = EXCLUDED.created_at,
+        ai_thread_name = EXCLUDED.ai_thread_name,
+        ai_description = EXCLUDED.ai_description,
+        ai_stakeholders = EXCLUDED.ai_stakeholders,
+        ai_priority = EXCLUDED.ai_priority,
+        ai_confidence = EXCLUDED.ai_confidence,
+        github_issue = EXCLUDED.github_issue,
+        jira_ticket = EXCLUDED.jira_ticket,
+        thread_issue = EXCLUDED.thread_issue,
+        ai_analysis_json = EXCLUDED.ai_analysis_json,
+        last_bot_message_ts = EXCLUDED.last_bot_message_ts,
+        updated_at = EXCLUDED.updated_at,
+        resolved_by = EXCLUDED.resolved_by,
+        resolved_at = EXCLUDED.resolved_at;
+    RETURN NEW;
+EXCEPTION WHEN OTHERS THEN
+    RAISE WARNING 'failed to mirror thread of %: %', TG_TABLE_NAME, SQLERRM;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
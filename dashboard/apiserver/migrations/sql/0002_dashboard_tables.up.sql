@@ -0,0 +1,412 @@
+-- Tables owned by the dashboard, created by schema statements run at every
+-- start before migrations were versioned.
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(20) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_id_idx ON api_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS login_audit (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(50),
+    remote_ip VARCHAR(64) NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    method VARCHAR(30) NOT NULL,
+    success BOOLEAN NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS login_audit_created_at_idx ON login_audit (created_at);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id VARCHAR(50) PRIMARY KEY,
+    role VARCHAR(20) NOT NULL,
+    granted_by VARCHAR(50) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    session_hash CHAR(64) PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL DEFAULT '',
+    remote_ip VARCHAR(64) NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS slack_event_dedup (
+    dedup_key VARCHAR(200) PRIMARY KEY,
+    seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS backfill_checkpoints (
+    channel_id VARCHAR(50) PRIMARY KEY,
+    status VARCHAR(20) NOT NULL,
+    oldest VARCHAR(30) NOT NULL DEFAULT '',
+    cursor TEXT NOT NULL DEFAULT '',
+    pages INTEGER NOT NULL DEFAULT 0,
+    messages_imported INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS channel_config (
+    channel_id VARCHAR(50) PRIMARY KEY,
+    text_indexing BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor VARCHAR(50) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    channel_id VARCHAR(50) NOT NULL DEFAULT '',
+    thread_ts TEXT,
+    details TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_thread_idx ON audit_log (channel_id, thread_ts);
+
+CREATE TABLE IF NOT EXISTS legal_holds (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT,
+    reason TEXT NOT NULL,
+    placed_by VARCHAR(50) NOT NULL,
+    placed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    released_by VARCHAR(50),
+    released_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS legal_holds_channel_idx ON legal_holds (channel_id);
+
+CREATE TABLE IF NOT EXISTS settings (
+    key VARCHAR(100) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_by VARCHAR(50) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_directory (
+    user_id VARCHAR(50) PRIMARY KEY,
+    display_name VARCHAR(255) NOT NULL DEFAULT '',
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    team VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    source VARCHAR(50) NOT NULL DEFAULT 'csv',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS thread_suggestions (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    suggested_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, thread_ts)
+);
+
+CREATE TABLE IF NOT EXISTS thread_claims (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    claimed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, thread_ts)
+);
+
+CREATE TABLE IF NOT EXISTS thread_sla (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    due_at TIMESTAMP NOT NULL,
+    set_by VARCHAR(50) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, thread_ts)
+);
+
+CREATE TABLE IF NOT EXISTS resolution_requests (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    requested_by VARCHAR(50) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    decided_by VARCHAR(50),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    decided_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS resolution_requests_pending_idx ON resolution_requests (channel_id, thread_ts) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS stats_snapshots (
+    day DATE NOT NULL,
+    channel_id VARCHAR(50) NOT NULL,
+    total_threads INT NOT NULL,
+    open_threads INT NOT NULL,
+    open_high_threads INT NOT NULL,
+    captured_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (day, channel_id)
+);
+
+CREATE TABLE IF NOT EXISTS goals (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    channel_id VARCHAR(50),
+    metric VARCHAR(50) NOT NULL,
+    target INT NOT NULL,
+    start_date DATE NOT NULL,
+    due_date DATE NOT NULL,
+    created_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS thread_releases (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    repo TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    set_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    released_at TIMESTAMP,
+    release_url TEXT,
+    PRIMARY KEY (channel_id, thread_ts)
+);
+
+CREATE TABLE IF NOT EXISTS thread_reporter_waits (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    set_by VARCHAR(50) NOT NULL,
+    waiting_since TIMESTAMP,
+    nudge_at TIMESTAMP,
+    nudged_at TIMESTAMP,
+    paused_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (channel_id, thread_ts)
+);
+
+CREATE TABLE IF NOT EXISTS channel_groups (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    remind_after VARCHAR(20) NOT NULL DEFAULT '',
+    created_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS channel_group_members (
+    group_id BIGINT NOT NULL,
+    channel_id VARCHAR(50) NOT NULL,
+    PRIMARY KEY (group_id, channel_id)
+);
+
+CREATE TABLE IF NOT EXISTS thread_acks (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    thread_created_at TIMESTAMP NOT NULL,
+    acked_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (channel_id, thread_ts)
+);
+
+CREATE INDEX IF NOT EXISTS thread_acks_acked_at_idx ON thread_acks (acked_at);
+
+CREATE TABLE IF NOT EXISTS thread_effort (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    estimate_minutes INT NOT NULL,
+    set_by VARCHAR(50) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, thread_ts)
+);
+
+CREATE TABLE IF NOT EXISTS thread_time_entries (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    stopped_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS thread_time_entries_thread_idx ON thread_time_entries (channel_id, thread_ts);
+
+CREATE TABLE IF NOT EXISTS outgoing_webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    events TEXT NOT NULL DEFAULT '[]',
+    payload_template TEXT NOT NULL DEFAULT '',
+    headers TEXT NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS thread_webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    url TEXT NOT NULL,
+    events TEXT NOT NULL DEFAULT '[]',
+    payload_template TEXT NOT NULL DEFAULT '',
+    headers TEXT NOT NULL DEFAULT '{}',
+    created_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS thread_webhooks_thread_idx ON thread_webhooks (channel_id, thread_ts);
+
+CREATE TABLE IF NOT EXISTS email_preferences (
+    email VARCHAR(255) PRIMARY KEY,
+    digest BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS thread_answer_signals (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    signal VARCHAR(30) NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    detected_at TIMESTAMP NOT NULL,
+    dismissed_by VARCHAR(50),
+    dismissed_at TIMESTAMP,
+    PRIMARY KEY (channel_id, thread_ts, signal)
+);
+
+CREATE TABLE IF NOT EXISTS reply_templates (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    body TEXT NOT NULL,
+    created_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS thread_quality_prompts (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    missing_info TEXT NOT NULL DEFAULT '[]',
+    asked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, thread_ts)
+);
+
+CREATE TABLE IF NOT EXISTS external_users (
+    user_id VARCHAR(50) PRIMARY KEY,
+    team_id VARCHAR(50) NOT NULL,
+    first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS thread_messages (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    ts TEXT NOT NULL,
+    user_id VARCHAR(50),
+    text TEXT,
+    posted_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (channel_id, ts)
+);
+
+CREATE INDEX IF NOT EXISTS thread_messages_thread_idx ON thread_messages (channel_id, thread_ts);
+
+CREATE TABLE IF NOT EXISTS thread_jira_tickets (
+    ticket_key VARCHAR(50) PRIMARY KEY,
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    status TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS api_usage (
+    day DATE NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    client VARCHAR(50) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    route TEXT NOT NULL,
+    calls BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, user_id, client, method, route)
+);
+
+CREATE TABLE IF NOT EXISTS storage_snapshots (
+    day DATE NOT NULL,
+    table_name VARCHAR(100) NOT NULL,
+    row_count BIGINT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    captured_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (day, table_name)
+);
+
+CREATE TABLE IF NOT EXISTS reminder_nudges (
+    user_id VARCHAR(50) NOT NULL,
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    nudged_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, channel_id, thread_ts)
+);
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS view_config TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS heat_thresholds TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS priority_calibration TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS resolve_approval TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS responder_rotation TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS reminder_schedule TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS quality_prompts TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS slack_connect BOOLEAN;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS slack_connect_ai BOOLEAN;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS github_routing TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS jira_mapping TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS sla_hours TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS default_assignees TEXT;
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS muted BOOLEAN;
+
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    requested_by VARCHAR(50) NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    filters TEXT NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    size_bytes BIGINT,
+    object_key TEXT,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS thread_watchers (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, thread_ts, user_id)
+);
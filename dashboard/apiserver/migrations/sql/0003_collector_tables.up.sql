@@ -0,0 +1,26 @@
+-- Tables otherwise created by the Python collector, so their later changes
+-- are versioned too and the dashboard can run on its own. Channel thread
+-- tables are created per channel, by the collector or in quickstart mode.
+
+CREATE TABLE IF NOT EXISTS channels (
+    channel_id VARCHAR(50) PRIMARY KEY,
+    channel_name VARCHAR(100) NOT NULL,
+    table_name VARCHAR(100) NOT NULL,
+    thread_count INTEGER DEFAULT 0,
+    active_thread_count INTEGER DEFAULT 0,
+    last_activity TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(100),
+    display_name VARCHAR(100),
+    real_name VARCHAR(100),
+    profile_image_url TEXT,
+    profile_image_24 TEXT,
+    profile_image_32 TEXT,
+    profile_image_48 TEXT,
+    profile_image_72 TEXT,
+    last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,12 @@
+-- Who is assigned to triage a thread, set from the dashboard.
+
+CREATE TABLE IF NOT EXISTS thread_assignments (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    assignee VARCHAR(50) NOT NULL,
+    assigned_by VARCHAR(50) NOT NULL,
+    assigned_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, thread_ts)
+);
+
+CREATE INDEX IF NOT EXISTS thread_assignments_assignee_idx ON thread_assignments (assignee);
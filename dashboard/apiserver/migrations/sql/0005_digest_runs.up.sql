@@ -0,0 +1,6 @@
+-- The scheduled digests already sent, so that only one replica sends each.
+
+CREATE TABLE IF NOT EXISTS digest_runs (
+    scheduled_at TIMESTAMP PRIMARY KEY,
+    sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,18 @@
+-- Triage notes written on threads from the dashboard, and whether each was
+-- mirrored as a comment on the GitHub issue and Jira ticket of its thread.
+
+CREATE TABLE IF NOT EXISTS thread_notes (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    author VARCHAR(50) NOT NULL,
+    body TEXT NOT NULL,
+    github_comment TEXT,
+    jira_comment TEXT,
+    sync_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS thread_notes_thread_idx ON thread_notes (channel_id, thread_ts);
+
+ALTER TABLE channel_config ADD COLUMN IF NOT EXISTS note_sync BOOLEAN;
@@ -0,0 +1,4 @@
+-- Open threads past the red heat threshold of their channel, compared by the
+-- stats diff. Snapshots taken before count none.
+
+ALTER TABLE stats_snapshots ADD COLUMN IF NOT EXISTS stale_threads INT;
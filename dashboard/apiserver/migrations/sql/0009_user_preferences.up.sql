@@ -0,0 +1,8 @@
+-- Preferences of dashboard users, e.g. how their priority inbox is ranked,
+-- as JSON. Users without a row get the defaults.
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id VARCHAR(50) PRIMARY KEY,
+    inbox_ranking TEXT,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,110 @@
+-- Structured resolutions: why a thread was resolved is asked when it is,
+-- and kept on pending resolution requests until they are approved.
+-- Channel tables get their resolution columns when first resolved, and
+-- the threads table mirrors them.
+
+ALTER TABLE resolution_requests ADD COLUMN IF NOT EXISTS resolution VARCHAR(20) NOT NULL DEFAULT '';
+
+ALTER TABLE threads
+    ADD COLUMN IF NOT EXISTS resolution VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS resolution_note TEXT;
+
+-- mirror_channel_thread of 0001, copying the resolution columns too
+CREATE OR REPLACE FUNCTION mirror_channel_thread() RETURNS TRIGGER AS $$
+DECLARE
+    r JSONB;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        DELETE FROM threads WHERE channel_id = OLD.channel_id AND thread_ts = OLD.thread_ts;
+        RETURN OLD;
+    END IF;
+    r := to_jsonb(NEW);
+    INSERT INTO threads (
+        thread_ts,
+        channel_id,
+        user_id,
+        reply_count,
+        latest_reply,
+        status,
+        created_at,
+        ai_thread_name,
+        ai_description,
+        ai_stakeholders,
+        ai_priority,
+        ai_confidence,
+        github_issue,
+        jira_ticket,
+        thread_issue,
+        ai_analysis_json,
+        last_bot_message_ts,
+        updated_at,
+        resolved_by,
+        resolved_at,
+        resolution,
+        resolution_note
+    )
+    VALUES (
+        r->>'thread_ts',
+        r->>'channel_id',
+        r->>'user_id',
+        (r->>'reply_count')::INTEGER,
+        (r->>'latest_reply')::TIMESTAMP,
+        r->>'status',
+        (r->>'created_at')::TIMESTAMP,
+        r->>'ai_thread_name',
+        r->>'ai_description',
+        r->>'ai_stakeholders',
+        (r->>'ai_priority')::VARCHAR(10),
+        (r->>'ai_confidence')::DECIMAL(3,2),
+        r->>'github_issue',
+        r->>'jira_ticket',
+        r->>'thread_issue',
+        r->>'ai_analysis_json',
+        (r->>'last_bot_message_ts')::TIMESTAMP,
+        (r->>'updated_at')::TIMESTAMP,
+        (r->>'resolved_by')::VARCHAR(50),
+        (r->>'resolved_at')::TIMESTAMP,
+        (r->>'resolution')::VARCHAR(20),
+        r->>'resolution_note'
+    )
+    ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+        user_id = EXCLUDED.user_id,
+        reply_count = EXCLUDED.reply_count,
+        latest_reply = EXCLUDED.latest_reply,
+        status = EXCLUDED.status,
+        created_at = EXCLUDED.created_at,
+        ai_thread_name = EXCLUDED.ai_thread_name,
+        ai_description = EXCLUDED.ai_description,
+        ai_stakeholders = EXCLUDED.ai_stakeholders,
+        ai_priority = EXCLUDED.ai_priority,
+        ai_confidence = EXCLUDED.ai_confidence,
+        github_issue = EXCLUDED.github_issue,
+        jira_ticket = EXCLUDED.jira_ticket,
+        thread_issue = EXCLUDED.thread_issue,
+        ai_analysis_json = EXCLUDED.ai_analysis_json,
+        last_bot_message_ts = EXCLUDED.last_bot_message_ts,
+        updated_at = EXCLUDED.updated_at,
+        resolved_by = EXCLUDED.resolved_by,
+        resolved_at = EXCLUDED.resolved_at,
+        resolution = EXCLUDED.resolution,
+        resolution_note = EXCLUDED.resolution_note;
+    RETURN NEW;
+EXCEPTION WHEN OTHERS THEN
+    RAISE WARNING 'failed to mirror thread of %: %', TG_TABLE_NAME, SQLERRM;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Answer signals are labelled with the resolution of their thread, and
+-- answer_signal_accuracy counts per channel how often each signal turned
+-- out to be right, to stop surfacing signals that are mostly wrong.
+ALTER TABLE thread_answer_signals ADD COLUMN IF NOT EXISTS resolution VARCHAR(20);
+
+CREATE TABLE IF NOT EXISTS answer_signal_accuracy (
+    channel_id VARCHAR(50) NOT NULL,
+    signal VARCHAR(30) NOT NULL,
+    labelled INTEGER NOT NULL DEFAULT 0,
+    answered INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, signal)
+);
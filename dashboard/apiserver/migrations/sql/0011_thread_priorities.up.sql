@@ -0,0 +1,12 @@
+-- Priorities set by hand on threads from the dashboard, overriding the one
+-- the AI assigned. A manual_priority of 'none' marks a thread as not urgent
+-- at all.
+
+CREATE TABLE IF NOT EXISTS thread_priorities (
+    channel_id VARCHAR(50) NOT NULL,
+    thread_ts TEXT NOT NULL,
+    manual_priority VARCHAR(10) NOT NULL,
+    set_by VARCHAR(50) NOT NULL,
+    set_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, thread_ts)
+);
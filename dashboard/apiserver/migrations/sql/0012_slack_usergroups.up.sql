@@ -0,0 +1,20 @@
+-- Slack usergroups, mentioned by handles such as @backend-team, and their
+-- members as of the latest sync. Threads are assigned to a usergroup and
+-- list it as a stakeholder by its ID.
+
+CREATE TABLE IF NOT EXISTS slack_usergroups (
+    usergroup_id VARCHAR(50) PRIMARY KEY,
+    handle VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    synced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS slack_usergroups_handle_idx ON slack_usergroups (handle);
+
+CREATE TABLE IF NOT EXISTS slack_usergroup_members (
+    usergroup_id VARCHAR(50) NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    PRIMARY KEY (usergroup_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS slack_usergroup_members_user_idx ON slack_usergroup_members (user_id);
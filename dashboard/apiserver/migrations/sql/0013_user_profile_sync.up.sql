@@ -0,0 +1,4 @@
+-- Users who left the workspace keep their profile, for the threads they
+-- took part in, with when the profile sync found them deactivated.
+
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP;
@@ -0,0 +1,15 @@
+-- Summaries of the open threads of channels kept up to date in Slack, as
+-- the canvas of the channel or as a pinned message, with what was written
+-- last so unchanged summaries are not written again.
+
+CREATE TABLE IF NOT EXISTS channel_summaries (
+    channel_id VARCHAR(50) PRIMARY KEY,
+    mode VARCHAR(10) NOT NULL,
+    canvas_id VARCHAR(50),
+    message_ts VARCHAR(50),
+    content_hash VARCHAR(64),
+    synced_at TIMESTAMP,
+    last_error TEXT,
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,46 @@
+-- Channel tables record until when a thread is snoozed. The collector
+-- creates them without the column, so it is added to the tables tracked
+-- now and, by the channels_prepare_table trigger, to those tracked later.
+-- The trigger runs as whoever registers the channel, the owner of its
+-- table, so serving requests needs no right to alter tables.
+
+-- Tables having the column already are not altered, which would lock them
+CREATE OR REPLACE FUNCTION prepare_channel_table(table_name TEXT) RETURNS VOID AS $$
+DECLARE
+    rel REGCLASS := to_regclass(table_name);
+BEGIN
+    IF rel IS NULL THEN
+        RETURN;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_attribute WHERE attrelid = rel AND attname = 'snoozed_until' AND NOT attisdropped) THEN
+        EXECUTE format('ALTER TABLE %I ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMP', table_name);
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+    ch RECORD;
+BEGIN
+    FOR ch IN SELECT table_name FROM channels LOOP
+        PERFORM prepare_channel_table(ch.table_name);
+    END LOOP;
+END;
+$$;
+
+-- A table that cannot be prepared only warns, registering the channel goes
+-- through
+CREATE OR REPLACE FUNCTION channels_prepare_table() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM prepare_channel_table(NEW.table_name);
+    RETURN NEW;
+EXCEPTION WHEN OTHERS THEN
+    RAISE WARNING 'failed to prepare table % of channel %: %', NEW.table_name, NEW.channel_id, SQLERRM;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS channels_prepare_table ON channels;
+
+CREATE TRIGGER channels_prepare_table AFTER INSERT OR UPDATE OF table_name ON channels
+    FOR EACH ROW EXECUTE PROCEDURE channels_prepare_table();
@@ -0,0 +1,35 @@
+-- prepare_channel_table of 0016, also adding the columns recording who
+-- resolved a thread, when and why, which the dashboard used to add to
+-- channel tables when first resolving one of their threads.
+
+CREATE OR REPLACE FUNCTION prepare_channel_table(table_name TEXT) RETURNS VOID AS $$
+DECLARE
+    rel REGCLASS := to_regclass(table_name);
+    col TEXT[];
+BEGIN
+    IF rel IS NULL THEN
+        RETURN;
+    END IF;
+    FOREACH col SLICE 1 IN ARRAY ARRAY[
+        ['snoozed_until', 'TIMESTAMP'],
+        ['resolved_by', 'VARCHAR(50)'],
+        ['resolved_at', 'TIMESTAMP'],
+        ['resolution', 'VARCHAR(20)'],
+        ['resolution_note', 'TEXT']
+    ] LOOP
+        IF NOT EXISTS (SELECT 1 FROM pg_attribute WHERE attrelid = rel AND attname = col[1] AND NOT attisdropped) THEN
+            EXECUTE format('ALTER TABLE %I ADD COLUMN IF NOT EXISTS %I %s', table_name, col[1], col[2]);
+        END IF;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+    ch RECORD;
+BEGIN
+    FOR ch IN SELECT table_name FROM channels LOOP
+        PERFORM prepare_channel_table(ch.table_name);
+    END LOOP;
+END;
+$$;
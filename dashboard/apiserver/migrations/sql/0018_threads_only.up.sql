@@ -0,0 +1,62 @@
+-- The threads table is the only table of threads. The rows of the channel
+-- tables are copied into it a last time, then the channel tables, their
+-- mirroring triggers and the functions preparing them are dropped. No row
+-- of the threads table is deleted, so threads under legal hold are kept.
+
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMP;
+
+DROP TRIGGER IF EXISTS channels_prepare_table ON channels;
+
+DROP FUNCTION IF EXISTS channels_prepare_table();
+
+DROP FUNCTION IF EXISTS prepare_channel_table(TEXT);
+
+-- Rows are read through JSON so columns a channel table lacks are NULL
+DO $$
+DECLARE
+    ch RECORD;
+BEGIN
+    FOR ch IN SELECT DISTINCT table_name FROM channels WHERE table_name <> 'threads' LOOP
+        IF to_regclass(ch.table_name) IS NULL THEN
+            CONTINUE;
+        END IF;
+        EXECUTE format($sql$
+            INSERT INTO threads
+            SELECT (jsonb_populate_record(NULL::threads, to_jsonb(c))).* FROM %I c
+            ON CONFLICT (channel_id, thread_ts) DO UPDATE SET
+                user_id = EXCLUDED.user_id,
+                reply_count = EXCLUDED.reply_count,
+                latest_reply = EXCLUDED.latest_reply,
+                status = EXCLUDED.status,
+                created_at = EXCLUDED.created_at,
+                ai_thread_name = EXCLUDED.ai_thread_name,
+                ai_description = EXCLUDED.ai_description,
+                ai_stakeholders = EXCLUDED.ai_stakeholders,
+                ai_priority = EXCLUDED.ai_priority,
+                ai_confidence = EXCLUDED.ai_confidence,
+                github_issue = EXCLUDED.github_issue,
+                jira_ticket = EXCLUDED.jira_ticket,
+                thread_issue = EXCLUDED.thread_issue,
+                ai_analysis_json = EXCLUDED.ai_analysis_json,
+                last_bot_message_ts = EXCLUDED.last_bot_message_ts,
+                updated_at = EXCLUDED.updated_at,
+                resolved_by = EXCLUDED.resolved_by,
+                resolved_at = EXCLUDED.resolved_at,
+                resolution = EXCLUDED.resolution,
+                resolution_note = EXCLUDED.resolution_note,
+                snoozed_until = EXCLUDED.snoozed_until
+        $sql$, ch.table_name);
+        EXECUTE format('DROP TABLE %I', ch.table_name);
+    END LOOP;
+END;
+$$;
+
+DROP FUNCTION IF EXISTS mirror_channel_thread();
+
+DROP TABLE IF EXISTS thread_table_mirrors;
+
+UPDATE channels SET table_name = 'threads';
+
+ALTER TABLE channels ALTER COLUMN table_name SET DEFAULT 'threads';
+
+CREATE INDEX IF NOT EXISTS threads_snoozed_until_idx ON threads (snoozed_until) WHERE snoozed_until IS NOT NULL;
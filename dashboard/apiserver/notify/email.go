@@ -0,0 +1,80 @@
+package notify
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "mime"
+    "net"
+    "net/smtp"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Email sends notifications to the address of their recipient through an
+// SMTP server.
+type Email struct {
+    // Addr is the host:port of the SMTP server, From the sender address
+    Addr     string
+    Username string
+    Password string
+    From     string
+    // Address returns the email address of a Slack user, "" when unknown
+    Address func(ctx context.Context, userID string) (string, error)
+}
+
+func (e Email) Name() string {
+    return SinkEmail
+}
+
+func (e Email) Configured() bool {
+    return e.Addr != "" && e.From != "" && e.Address != nil
+}
+
+func (e Email) Send(ctx context.Context, n Notification) error {
+    if n.Recipient == "" {
+        return errors.New("notification has no recipient")
+    }
+    to, err := e.Address(ctx, n.Recipient)
+    if err != nil {
+        return err
+    }
+    if to == "" {
+        return fmt.Errorf("no email address known for %s", n.Recipient)
+    }
+    return e.SendTo(to, n)
+}
+
+// SendTo emails n to an address instead of its recipient, e.g. a mailing
+// list.
+func (e Email) SendTo(to string, n Notification) error {
+    headers := map[string]string{
+        "From":                      e.From,
+        "To":                        to,
+        "Subject":                   mime.QEncoding.Encode("utf-8", strings.SplitN(PlainText(n.Text), "\n", 2)[0]),
+        "Date":                      time.Now().Format(time.RFC1123Z),
+        "MIME-Version":              "1.0",
+        "Content-Type":              "text/plain; charset=utf-8",
+        "Content-Transfer-Encoding": "8bit",
+    }
+    names := make([]string, 0, len(headers))
+    for name := range headers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    var msg strings.Builder
+    for _, name := range names {
+        msg.WriteString(name + ": " + headers[name] + "\r\n")
+    }
+    msg.WriteString("\r\n")
+    msg.WriteString(strings.ReplaceAll(PlainText(n.FullText()), "\n", "\r\n"))
+    msg.WriteString("\r\n")
+
+    var auth smtp.Auth
+    if e.Username != "" {
+        host, _, _ := net.SplitHostPort(e.Addr)
+        auth = smtp.PlainAuth("", e.Username, e.Password, host)
+    }
+    return smtp.SendMail(e.Addr, auth, e.From, []string{to}, []byte(msg.String()))
+}
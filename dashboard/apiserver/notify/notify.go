@@ -0,0 +1,217 @@
+// Package notify delivers notifications, such as thread reminders, through
+// pluggable sinks: Slack direct messages and channels, email, webhooks and
+// Microsoft Teams. Rules select the sinks of each kind of notification and
+// a notification is fanned out to all of them, so that adding a delivery
+// channel only takes a new Sink.
+package notify
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+
+    "dashboard/apiserver/logger"
+    "dashboard/apiserver/slack"
+)
+
+// Kinds of notifications, which rules are keyed by.
+const (
+    EventReminder     = "reminder"
+    EventRelease      = "release"
+    EventResolution   = "resolution"
+    EventStorageAlert = "storage_alert"
+    EventAssignment   = "assignment"
+)
+
+// Names of the built-in sinks.
+const (
+    SinkSlackDM      = "slack_dm"
+    SinkSlackChannel = "slack_channel"
+    SinkEmail        = "email"
+    SinkWebhook      = "webhook"
+    SinkTeams        = "teams"
+)
+
+// Notification is a message about the dashboard, usually for one person.
+type Notification struct {
+    Event string
+    // Recipient is the Slack user ID the notification is for, empty when it
+    // is for a channel
+    Recipient string
+    // Channel is the Slack channel the slack_channel sink posts in, its
+    // default channel when empty
+    Channel string
+    // Text is the message in Slack mrkdwn, short enough for a subject or a
+    // push notification. Blocks lay it out in Slack, and Body is the whole
+    // message for sinks without blocks, Text when empty
+    Text   string
+    Blocks []slack.Block
+    Body   string
+    // ChannelID and ThreadTS are the thread the notification is about, if
+    // any
+    ChannelID string
+    ThreadTS  string
+}
+
+// Permalink links to the thread of the notification, empty without one.
+func (n Notification) Permalink() string {
+    if n.ThreadTS == "" {
+        return ""
+    }
+    return slack.Permalink(n.ChannelID, n.ThreadTS)
+}
+
+// FullText returns Body, or Text without one.
+func (n Notification) FullText() string {
+    if n.Body != "" {
+        return n.Body
+    }
+    return n.Text
+}
+
+var (
+    linkPattern    = regexp.MustCompile(`<(https?://[^|>]+)\|([^>]+)>`)
+    urlPattern     = regexp.MustCompile(`<(https?://[^|>]+)>`)
+    mentionPattern = regexp.MustCompile(`<@([A-Z0-9]+)>`)
+    channelPattern = regexp.MustCompile(`<#([A-Z0-9]+)(\|[^>]*)?>`)
+)
+
+// PlainText returns mrkdwn without Slack markup, links written as
+// "label (url)".
+func PlainText(mrkdwn string) string {
+    text := linkPattern.ReplaceAllString(mrkdwn, "$2 ($1)")
+    text = urlPattern.ReplaceAllString(text, "$1")
+    text = mentionPattern.ReplaceAllString(text, "@$1")
+    return channelPattern.ReplaceAllString(text, "#$1")
+}
+
+// Sink delivers notifications through one medium.
+type Sink interface {
+    // Name is how rules refer to the sink
+    Name() string
+    // Configured reports whether the sink has what it needs to deliver
+    Configured() bool
+    Send(ctx context.Context, n Notification) error
+}
+
+// DefaultRules returns the sinks of each kind of notification when not
+// configured otherwise.
+func DefaultRules() map[string][]string {
+    return map[string][]string{
+        EventReminder:     {SinkSlackDM},
+        EventRelease:      {SinkSlackDM},
+        EventResolution:   {SinkSlackDM},
+        EventStorageAlert: {SinkSlackChannel},
+        EventAssignment:   {SinkSlackDM},
+    }
+}
+
+// ParseRules parses rules such as "reminder=slack_dm+email,storage_alert=teams"
+// over DefaultRules. An empty sink list turns a kind of notification off.
+func ParseRules(s string) (map[string][]string, error) {
+    rules := DefaultRules()
+    for _, rule := range strings.Split(s, ",") {
+        if rule = strings.TrimSpace(rule); rule == "" {
+            continue
+        }
+        event, sinks, ok := strings.Cut(rule, "=")
+        event = strings.TrimSpace(event)
+        if _, known := rules[event]; !ok || !known {
+            return nil, fmt.Errorf("invalid notification rule %q, expected one of %s followed by =", rule, strings.Join(events(), ", "))
+        }
+        rules[event] = nil
+        for _, sink := range strings.Split(sinks, "+") {
+            if sink = strings.TrimSpace(sink); sink != "" {
+                rules[event] = append(rules[event], sink)
+            }
+        }
+    }
+    return rules, nil
+}
+
+// events returns the kinds of notifications, sorted.
+func events() []string {
+    names := []string{}
+    for event := range DefaultRules() {
+        names = append(names, event)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Router fans notifications out to the sinks of their rule.
+type Router struct {
+    log   logger.Logger
+    sinks map[string]Sink
+    rules map[string][]string
+}
+
+// NewRouter returns a router applying rules to sinks. Rules may only name
+// the given sinks.
+func NewRouter(log logger.Logger, rules map[string][]string, sinks ...Sink) (*Router, error) {
+    r := &Router{log: log, sinks: make(map[string]Sink, len(sinks)), rules: rules}
+    for _, sink := range sinks {
+        r.sinks[sink.Name()] = sink
+    }
+    for event, names := range rules {
+        for _, name := range names {
+            if _, ok := r.sinks[name]; !ok {
+                return nil, fmt.Errorf("notification rule of %s names unknown sink %q", event, name)
+            }
+        }
+    }
+    return r, nil
+}
+
+// configured returns the sinks of the rule of event able to deliver.
+func (r *Router) configured(event string) []Sink {
+    sinks := []Sink{}
+    for _, name := range r.rules[event] {
+        if sink := r.sinks[name]; sink.Configured() {
+            sinks = append(sinks, sink)
+        }
+    }
+    return sinks
+}
+
+// Enabled reports whether notifications of event are delivered anywhere.
+func (r *Router) Enabled(event string) bool {
+    return len(r.configured(event)) > 0
+}
+
+// Notify sends n through every configured sink of its rule at once. Failing
+// sinks do not keep the others from delivering, and are only logged unless
+// all of them failed.
+func (r *Router) Notify(ctx context.Context, n Notification) error {
+    sinks := r.configured(n.Event)
+    errs := make([]error, len(sinks))
+    var wg sync.WaitGroup
+    for i, sink := range sinks {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if err := sink.Send(ctx, n); err != nil {
+                errs[i] = fmt.Errorf("%s: %w", sink.Name(), err)
+            }
+        }()
+    }
+    wg.Wait()
+
+    failed := []error{}
+    for _, err := range errs {
+        if err != nil {
+            failed = append(failed, err)
+        }
+    }
+    if len(failed) > 0 && len(failed) == len(sinks) {
+        return errors.Join(failed...)
+    }
+    for _, err := range failed {
+        r.log.Warnf("failed to deliver %s notification: %v", n.Event, err)
+    }
+    return nil
+}
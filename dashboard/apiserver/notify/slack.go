@@ -0,0 +1,61 @@
+package notify
+
+import (
+    "context"
+    "errors"
+
+    "dashboard/apiserver/slack"
+)
+
+// SlackDM sends notifications to their recipient as a Slack direct message.
+type SlackDM struct {
+    Client *slack.Client
+}
+
+func (s SlackDM) Name() string {
+    return SinkSlackDM
+}
+
+func (s SlackDM) Configured() bool {
+    return s.Client.Configured()
+}
+
+func (s SlackDM) Send(ctx context.Context, n Notification) error {
+    if n.Recipient == "" {
+        return errors.New("notification has no recipient")
+    }
+    _, err := s.Client.PostMessage(ctx, n.Recipient, n.Text, n.Blocks)
+    return err
+}
+
+// SlackChannel posts notifications in a Slack channel, mentioning their
+// recipient. Notifications are dropped when no channel is set.
+type SlackChannel struct {
+    Client *slack.Client
+    // Channel is posted in when the notification names no channel
+    Channel string
+}
+
+func (s SlackChannel) Name() string {
+    return SinkSlackChannel
+}
+
+func (s SlackChannel) Configured() bool {
+    return s.Client.Configured()
+}
+
+func (s SlackChannel) Send(ctx context.Context, n Notification) error {
+    channel := n.Channel
+    if channel == "" {
+        channel = s.Channel
+    }
+    if channel == "" {
+        return nil
+    }
+    text := n.Text
+    if n.Recipient != "" {
+        text = "<@" + n.Recipient + "> " + text
+    }
+    _, err := s.Client.PostMessage(ctx, channel, text, n.Blocks)
+    return err
+}
@@ -0,0 +1,101 @@
+package notify
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON posts payload to url and fails on any status but 2xx.
+func postJSON(ctx context.Context, url string, payload interface{}) error {
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    if resp.StatusCode/100 != 2 {
+        return fmt.Errorf("%s answered %s", url, resp.Status)
+    }
+    return nil
+}
+
+// Webhook posts notifications as JSON to a URL.
+type Webhook struct {
+    URL string
+}
+
+// webhookPayload is the body posted by Webhook.
+type webhookPayload struct {
+    Event     string `json:"event"`
+    Recipient string `json:"recipient,omitempty"`
+    Text      string `json:"text"`
+    Body      string `json:"body"`
+    ChannelID string `json:"channel_id,omitempty"`
+    ThreadTS  string `json:"thread_ts,omitempty"`
+    Permalink string `json:"permalink,omitempty"`
+}
+
+func (w Webhook) Name() string {
+    return SinkWebhook
+}
+
+func (w Webhook) Configured() bool {
+    return w.URL != ""
+}
+
+func (w Webhook) Send(ctx context.Context, n Notification) error {
+    return postJSON(ctx, w.URL, webhookPayload{
+        Event:     n.Event,
+        Recipient: n.Recipient,
+        Text:      PlainText(n.Text),
+        Body:      PlainText(n.FullText()),
+        ChannelID: n.ChannelID,
+        ThreadTS:  n.ThreadTS,
+        Permalink: n.Permalink(),
+    })
+}
+
+// Teams posts notifications to a Microsoft Teams channel through an
+// incoming webhook.
+type Teams struct {
+    URL string
+}
+
+func (t Teams) Name() string {
+    return SinkTeams
+}
+
+func (t Teams) Configured() bool {
+    return t.URL != ""
+}
+
+func (t Teams) Send(ctx context.Context, n Notification) error {
+    text := n.FullText()
+    if n.Recipient != "" {
+        text = "<@" + n.Recipient + "> " + text
+    }
+    // Teams renders Markdown, where single line breaks are ignored
+    return postJSON(ctx, t.URL, map[string]string{"text": markdownLinks(text)})
+}
+
+// markdownLinks returns mrkdwn with links and line breaks in Markdown.
+func markdownLinks(mrkdwn string) string {
+    text := linkPattern.ReplaceAllString(mrkdwn, "[$2]($1)")
+    text = PlainText(text)
+    return strings.ReplaceAll(text, "\n", "  \n")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+    "go/ast"
+    "go/parser"
+    "go/token"
+    "log"
+    "path/filepath"
+    "strings"
+)
+
+// docIndex finds the doc comments of declarations from their position,
+// parsing the files declaring them once.
+type docIndex struct {
+    fset  *token.FileSet
+    files map[string]*ast.File
+}
+
+func newDocIndex() *docIndex {
+    return &docIndex{fset: token.NewFileSet(), files: map[string]*ast.File{}}
+}
+
+func (d *docIndex) file(name string) *ast.File {
+    if f, ok := d.files[name]; ok {
+        return f
+    }
+    f, err := parser.ParseFile(d.fset, name, nil, parser.ParseComments)
+    if err != nil {
+        log.Printf("failed to parse %s: %v", name, err)
+    }
+    d.files[name] = f
+    return f
+}
+
+// text joins the lines of a comment.
+func text(groups ...*ast.CommentGroup) string {
+    for _, group := range groups {
+        if group != nil {
+            return strings.Join(strings.Fields(group.Text()), " ")
+        }
+    }
+    return ""
+}
+
+// typeDoc returns the doc comment of the type declared at pos.
+func (d *docIndex) typeDoc(pos token.Position) string {
+    f := d.file(pos.Filename)
+    if f == nil {
+        return ""
+    }
+    for _, decl := range f.Decls {
+        gen, ok := decl.(*ast.GenDecl)
+        if !ok || gen.Tok != token.TYPE {
+            continue
+        }
+        for _, spec := range gen.Specs {
+            ts := spec.(*ast.TypeSpec)
+            if d.fset.Position(ts.Name.Pos()).Line != pos.Line {
+                continue
+            }
+            if len(gen.Specs) == 1 {
+                return text(ts.Doc, gen.Doc)
+            }
+            return text(ts.Doc)
+        }
+    }
+    return ""
+}
+
+// fieldDoc returns the doc comment, or line comment, of the struct field
+// declared at pos.
+func (d *docIndex) fieldDoc(pos token.Position) string {
+    f := d.file(pos.Filename)
+    if f == nil {
+        return ""
+    }
+    doc := ""
+    ast.Inspect(f, func(n ast.Node) bool {
+        field, ok := n.(*ast.Field)
+        if !ok || doc != "" {
+            return doc == ""
+        }
+        if d.fset.Position(field.Pos()).Line == pos.Line {
+            doc = text(field.Doc, field.Comment)
+        }
+        return true
+    })
+    return doc
+}
+
+// funcDecl returns the declaration of the function name of the package in
+// dir.
+func (d *docIndex) funcDecl(dir string, name string) *ast.FuncDecl {
+    names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+    if err != nil {
+        return nil
+    }
+    for _, file := range names {
+        f := d.file(file)
+        if f == nil {
+            continue
+        }
+        for _, decl := range f.Decls {
+            if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name == name {
+                return fd
+            }
+        }
+    }
+    return nil
+}
@@ -0,0 +1,854 @@
+// Command gen writes the OpenAPI specification of the /api routes and the
+// JSDoc models of its schemas for the UI. Routes are read from where the
+// server registers them, and each operation from the handler serving it:
+// its doc comment, the query parameters it reads, the body it decodes, the
+// responses it writes and the errors it returns.
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "go/ast"
+    "go/constant"
+    "go/importer"
+    "go/parser"
+    "go/token"
+    "go/types"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "reflect"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+const (
+    module       = "dashboard"
+    handlersPath = module + "/apiserver/handlers"
+    apierrorPath = module + "/apiserver/apierror"
+    authPath     = module + "/apiserver/auth"
+    echoContext  = "github.com/labstack/echo/v4.Context"
+)
+
+func main() {
+    routesFile := flag.String("routes", "../apiserver.go", "file registering the routes")
+    handlersDir := flag.String("handlers", "../handlers", "directory of the handlers package")
+    out := flag.String("out", "openapi.json", "file to write the specification to")
+    models := flag.String("models", "", "file to write the JSDoc models to")
+    flag.Parse()
+
+    fset := token.NewFileSet()
+    g := &generator{
+        fset:       fset,
+        imports:    importer.ForCompiler(fset, "source", nil).(types.ImporterFrom),
+        docs:       newDocIndex(),
+        components: map[string]interface{}{},
+        names:      map[*types.TypeName]string{},
+        taken:      map[string]*types.TypeName{},
+    }
+    if err := g.loadHandlers(*handlersDir); err != nil {
+        log.Fatalf("failed to load handlers: %v", err)
+    }
+    routes, err := g.readRoutes(*routesFile)
+    if err != nil {
+        log.Fatalf("failed to read routes: %v", err)
+    }
+
+    spec := g.spec(routes)
+    if err := writeJSON(*out, spec); err != nil {
+        log.Fatalf("failed to write specification: %v", err)
+    }
+    if *models != "" {
+        if err := os.WriteFile(*models, []byte(jsdocModels(g.components)), 0o644); err != nil {
+            log.Fatalf("failed to write models: %v", err)
+        }
+    }
+    fmt.Printf("%d operations, %d schemas\n", len(routes), len(g.components))
+}
+
+func writeJSON(name string, v interface{}) error {
+    var buf bytes.Buffer
+    enc := json.NewEncoder(&buf)
+    enc.SetEscapeHTML(false)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(v); err != nil {
+        return err
+    }
+    return os.WriteFile(name, buf.Bytes(), 0o644)
+}
+
+type generator struct {
+    fset    *token.FileSet
+    imports types.ImporterFrom
+    docs    *docIndex
+
+    handlersDir string
+    handlers    *types.Package
+    info        *types.Info
+    funcs       map[*types.Func]*ast.FuncDecl
+
+    // components are the schemas of named types, names the component of
+    // each type and taken the type of each component name
+    components map[string]interface{}
+    names      map[*types.TypeName]string
+    taken      map[string]*types.TypeName
+    // request is set while the schema of a request body is made, whose
+    // fields are all optional unless checked by the handler
+    request bool
+}
+
+// loadHandlers type-checks the handlers package.
+func (g *generator) loadHandlers(dir string) error {
+    g.handlersDir = dir
+    pkgs, err := parser.ParseDir(g.fset, dir, nil, parser.ParseComments)
+    if err != nil {
+        return err
+    }
+    var files []*ast.File
+    for name, pkg := range pkgs {
+        if strings.HasSuffix(name, "_test") {
+            continue
+        }
+        for _, file := range pkg.Files {
+            files = append(files, file)
+        }
+    }
+    g.info = &types.Info{
+        Types: map[ast.Expr]types.TypeAndValue{},
+        Defs:  map[*ast.Ident]types.Object{},
+        Uses:  map[*ast.Ident]types.Object{},
+    }
+    conf := types.Config{Importer: g.imports}
+    g.handlers, err = conf.Check(handlersPath, g.fset, files, g.info)
+    if err != nil {
+        return err
+    }
+    g.funcs = map[*types.Func]*ast.FuncDecl{}
+    for _, file := range files {
+        for _, decl := range file.Decls {
+            if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+                if fn, ok := g.info.Defs[fd.Name].(*types.Func); ok {
+                    g.funcs[fn] = fd
+                }
+            }
+        }
+    }
+    return nil
+}
+
+// importPackage returns a package imported by the handlers, or any other
+// package of the module.
+func (g *generator) importPackage(path string) *types.Package {
+    pkg, err := g.imports.ImportFrom(path, ".", 0)
+    if err != nil {
+        log.Fatalf("failed to import %s: %v", path, err)
+    }
+    return pkg
+}
+
+// constValue returns the value of the string constant name of pkg.
+func constValue(pkg *types.Package, name string) string {
+    if c, ok := pkg.Scope().Lookup(name).(*types.Const); ok && c.Val().Kind() == constant.String {
+        return constant.StringVal(c.Val())
+    }
+    return ""
+}
+
+// route is an API route and the handler serving it.
+type route struct {
+    method string
+    path   string
+    // pkg is the package declaring handler, the handlers package when
+    // handler is a method of the container
+    pkg     string
+    handler string
+    scopes  []string
+}
+
+// group is a route group, with the scopes its middleware requires.
+type group struct {
+    prefix string
+    scopes []string
+}
+
+var methods = map[string]bool{
+    http.MethodGet: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+}
+
+// readRoutes finds the /api routes registered in file, following groups.
+func (g *generator) readRoutes(file string) ([]route, error) {
+    f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+    if err != nil {
+        return nil, err
+    }
+    packages := map[string]string{}
+    for _, spec := range f.Imports {
+        path, _ := strconv.Unquote(spec.Path.Value)
+        name := filepath.Base(path)
+        if spec.Name != nil {
+            name = spec.Name.Name
+        }
+        packages[name] = path
+    }
+    auth := g.importPackage(authPath)
+
+    groups := map[string]group{"e": {}}
+    scopesOf := func(args []ast.Expr) []string {
+        var scopes []string
+        for _, arg := range args {
+            call, ok := arg.(*ast.CallExpr)
+            if !ok || len(call.Args) != 1 {
+                continue
+            }
+            if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "RequireScope" {
+                continue
+            }
+            if scope, ok := call.Args[0].(*ast.SelectorExpr); ok {
+                scopes = append(scopes, constValue(auth, scope.Sel.Name))
+            }
+        }
+        return scopes
+    }
+
+    var routes []route
+    ast.Inspect(f, func(n ast.Node) bool {
+        switch n := n.(type) {
+        case *ast.AssignStmt:
+            if len(n.Lhs) != 1 || len(n.Rhs) != 1 {
+                return true
+            }
+            call, ok := n.Rhs[0].(*ast.CallExpr)
+            if !ok {
+                return true
+            }
+            sel, ok := call.Fun.(*ast.SelectorExpr)
+            if !ok || sel.Sel.Name != "Group" || len(call.Args) == 0 {
+                return true
+            }
+            parent, ok := groups[identName(sel.X)]
+            prefix, isLit := stringLit(call.Args[0])
+            if !ok || !isLit {
+                return true
+            }
+            groups[identName(n.Lhs[0])] = group{
+                prefix: parent.prefix + prefix,
+                scopes: append(append([]string{}, parent.scopes...), scopesOf(call.Args[1:])...),
+            }
+        case *ast.CallExpr:
+            sel, ok := n.Fun.(*ast.SelectorExpr)
+            if !ok || !methods[sel.Sel.Name] || len(n.Args) < 2 {
+                return true
+            }
+            parent, ok := groups[identName(sel.X)]
+            path, isLit := stringLit(n.Args[0])
+            handler, isSel := n.Args[1].(*ast.SelectorExpr)
+            if !ok || !isLit || !isSel || !strings.HasPrefix(parent.prefix+path, "/api") {
+                return true
+            }
+            r := route{
+                method:  sel.Sel.Name,
+                path:    parent.prefix + path,
+                pkg:     handlersPath,
+                handler: handler.Sel.Name,
+                scopes:  append(append([]string{}, parent.scopes...), scopesOf(n.Args[2:])...),
+            }
+            if pkg, ok := packages[identName(handler.X)]; ok {
+                r.pkg = pkg
+            }
+            routes = append(routes, r)
+        }
+        return true
+    })
+    return routes, nil
+}
+
+func identName(expr ast.Expr) string {
+    if ident, ok := expr.(*ast.Ident); ok {
+        return ident.Name
+    }
+    return ""
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+    lit, ok := expr.(*ast.BasicLit)
+    if !ok || lit.Kind != token.STRING {
+        return "", false
+    }
+    s, err := strconv.Unquote(lit.Value)
+    return s, err == nil
+}
+
+// operation is what a handler tells about the operation it serves.
+type operation struct {
+    summary string
+    query   []string
+    request types.Type
+    // responses are the successful responses by status, errors the statuses
+    // of the errors returned
+    responses map[int]map[string]interface{}
+    errors    map[int]bool
+}
+
+var handlerDoc = regexp.MustCompile(`^\w+ - `)
+
+// operation reads the handler of r.
+func (g *generator) operation(r route) operation {
+    op := operation{responses: map[int]map[string]interface{}{}, errors: map[int]bool{}}
+    var fd *ast.FuncDecl
+    if r.pkg == handlersPath {
+        container := g.handlers.Scope().Lookup("Container").Type()
+        method, _, _ := types.LookupFieldOrMethod(types.NewPointer(container), false, g.handlers, r.handler)
+        if fn, ok := method.(*types.Func); ok {
+            fd = g.funcs[fn]
+        }
+    } else {
+        fd = g.docs.funcDecl(g.moduleDir(r.pkg), r.handler)
+    }
+    if fd == nil {
+        log.Fatalf("no handler %s for %s %s", r.handler, r.method, r.path)
+    }
+    if fd.Doc != nil {
+        op.summary = handlerDoc.ReplaceAllString(strings.TrimSpace(fd.Doc.Text()), "")
+    }
+    if r.pkg == handlersPath {
+        g.scan(fd, &op, map[*ast.FuncDecl]bool{})
+    }
+    if len(op.responses) == 0 {
+        op.responses[http.StatusOK] = map[string]interface{}{"description": http.StatusText(http.StatusOK)}
+    }
+    return op
+}
+
+// moduleDir returns the directory of a package of the module, from the
+// directory of the handlers.
+func (g *generator) moduleDir(path string) string {
+    root := filepath.Join(g.handlersDir, "..", "..")
+    return filepath.Join(root, strings.TrimPrefix(path, module+"/"))
+}
+
+// scan reads a handler, and the functions of the package it passes its
+// context to.
+func (g *generator) scan(fd *ast.FuncDecl, op *operation, seen map[*ast.FuncDecl]bool) {
+    if seen[fd] {
+        return
+    }
+    seen[fd] = true
+    ast.Inspect(fd.Body, func(n ast.Node) bool {
+        switch n := n.(type) {
+        case *ast.SelectorExpr:
+            if obj := g.info.Uses[n.Sel]; obj != nil && obj.Pkg() != nil && obj.Pkg().Path() == apierrorPath && obj.Name() == "ErrDatabaseUnavailable" {
+                op.errors[http.StatusServiceUnavailable] = true
+            }
+        case *ast.CallExpr:
+            g.scanCall(n, op, seen)
+        }
+        return true
+    })
+}
+
+func (g *generator) scanCall(call *ast.CallExpr, op *operation, seen map[*ast.FuncDecl]bool) {
+    var callee types.Object
+    switch fun := call.Fun.(type) {
+    case *ast.SelectorExpr:
+        callee = g.info.Uses[fun.Sel]
+        if g.isContext(fun.X) {
+            g.scanContextCall(fun.Sel.Name, call.Args, op)
+            return
+        }
+        if fun.Sel.Name == "Decode" && len(call.Args) == 1 {
+            if inner, ok := fun.X.(*ast.CallExpr); ok {
+                if sel, ok := inner.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "NewDecoder" {
+                    if ref, ok := call.Args[0].(*ast.UnaryExpr); ok && ref.Op == token.AND && op.request == nil {
+                        op.request = g.info.TypeOf(ref.X)
+                    }
+                }
+            }
+        }
+    case *ast.Ident:
+        callee = g.info.Uses[fun]
+    }
+    fn, ok := callee.(*types.Func)
+    if !ok || fn.Pkg() == nil {
+        return
+    }
+    if fn.Pkg().Path() == apierrorPath && (fn.Name() == "New" || fn.Name() == "Newf") && len(call.Args) > 0 {
+        if status, ok := g.intValue(call.Args[0]); ok {
+            op.errors[status] = true
+        }
+        return
+    }
+    if fd, ok := g.funcs[fn]; ok {
+        for _, arg := range call.Args {
+            if g.isContext(arg) {
+                g.scan(fd, op, seen)
+                return
+            }
+        }
+    }
+}
+
+// scanContextCall reads a call to a method of the echo context.
+func (g *generator) scanContextCall(method string, args []ast.Expr, op *operation) {
+    status := http.StatusOK
+    if len(args) > 0 {
+        if value, ok := g.intValue(args[0]); ok {
+            status = value
+        }
+    }
+    if status >= http.StatusBadRequest {
+        return
+    }
+    if _, ok := op.responses[status]; ok && method != "QueryParam" {
+        return
+    }
+    response := map[string]interface{}{"description": http.StatusText(status)}
+    content := func(contentType string, schema interface{}) {
+        response["content"] = map[string]interface{}{contentType: map[string]interface{}{"schema": schema}}
+    }
+    binary := map[string]interface{}{"type": "string", "format": "binary"}
+    switch method {
+    case "QueryParam":
+        if name, ok := stringLit(args[0]); ok {
+            for _, q := range op.query {
+                if q == name {
+                    return
+                }
+            }
+            op.query = append(op.query, name)
+        }
+        return
+    case "JSON", "JSONPretty", "JSONBlob":
+        if method == "JSONBlob" {
+            content(echoMIMEJSON, map[string]interface{}{})
+        } else {
+            content(echoMIMEJSON, g.exprSchema(args[1]))
+        }
+    case "Blob", "Stream":
+        contentType, ok := g.stringValue(args[1])
+        if !ok {
+            contentType = "application/octet-stream"
+        }
+        content(contentType, binary)
+    case "String":
+        content("text/plain", map[string]interface{}{"type": "string"})
+    case "HTML", "HTMLBlob":
+        content("text/html", map[string]interface{}{"type": "string"})
+    case "Attachment", "File":
+        content("application/octet-stream", binary)
+    case "NoContent", "Redirect":
+    default:
+        return
+    }
+    op.responses[status] = response
+}
+
+const echoMIMEJSON = "application/json"
+
+func (g *generator) isContext(expr ast.Expr) bool {
+    t := g.info.TypeOf(expr)
+    return t != nil && t.String() == echoContext
+}
+
+func (g *generator) intValue(expr ast.Expr) (int, bool) {
+    tv, ok := g.info.Types[expr]
+    if !ok || tv.Value == nil || tv.Value.Kind() != constant.Int {
+        return 0, false
+    }
+    value, exact := constant.Int64Val(tv.Value)
+    return int(value), exact
+}
+
+func (g *generator) stringValue(expr ast.Expr) (string, bool) {
+    tv, ok := g.info.Types[expr]
+    if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+        return "", false
+    }
+    return constant.StringVal(tv.Value), true
+}
+
+// exprSchema returns the schema of a response, telling the keys of map
+// literals apart.
+func (g *generator) exprSchema(expr ast.Expr) interface{} {
+    lit, ok := expr.(*ast.CompositeLit)
+    if !ok {
+        return g.schema(g.info.TypeOf(expr))
+    }
+    if _, isMap := g.info.TypeOf(lit).Underlying().(*types.Map); !isMap || len(lit.Elts) == 0 {
+        return g.schema(g.info.TypeOf(expr))
+    }
+    properties := map[string]interface{}{}
+    var required []string
+    for _, elt := range lit.Elts {
+        kv, ok := elt.(*ast.KeyValueExpr)
+        if !ok {
+            return g.schema(g.info.TypeOf(expr))
+        }
+        key, ok := g.stringValue(kv.Key)
+        if !ok {
+            return g.schema(g.info.TypeOf(expr))
+        }
+        properties[key] = g.exprSchema(kv.Value)
+        required = append(required, key)
+    }
+    schema := map[string]interface{}{"type": "object", "properties": properties}
+    if len(required) > 0 && !g.request {
+        sort.Strings(required)
+        schema["required"] = required
+    }
+    return schema
+}
+
+// schema returns the schema of values of t once encoded to JSON.
+func (g *generator) schema(t types.Type) interface{} {
+    if t == nil {
+        return map[string]interface{}{}
+    }
+    switch t := t.(type) {
+    case *types.Named:
+        return g.namedSchema(t)
+    case *types.Alias:
+        return g.schema(types.Unalias(t))
+    case *types.Basic:
+        return basicSchema(t)
+    case *types.Pointer:
+        return nullable(g.schema(t.Elem()))
+    case *types.Slice:
+        if basic, ok := t.Elem().(*types.Basic); ok && basic.Kind() == types.Byte {
+            return map[string]interface{}{"type": "string", "format": "byte"}
+        }
+        return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+    case *types.Array:
+        return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+    case *types.Map:
+        return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+    case *types.Struct:
+        return g.structSchema(t)
+    }
+    return map[string]interface{}{}
+}
+
+// nullTypes are the database types encoded as the value they may hold.
+var nullTypes = map[string]string{
+    "database/sql.NullString":  "string",
+    "database/sql.NullBool":    "boolean",
+    "database/sql.NullInt64":   "integer",
+    "database/sql.NullInt32":   "integer",
+    "database/sql.NullFloat64": "number",
+}
+
+func (g *generator) namedSchema(t *types.Named) interface{} {
+    tn := t.Obj()
+    name := tn.Name()
+    if tn.Pkg() != nil {
+        name = tn.Pkg().Path() + "." + tn.Name()
+    }
+    switch name {
+    case "time.Time", "database/sql.NullTime":
+        return map[string]interface{}{"type": "string", "format": "date-time"}
+    case "time.Duration":
+        return map[string]interface{}{"type": "integer", "format": "int64", "description": "Nanoseconds"}
+    case "encoding/json.RawMessage":
+        return map[string]interface{}{}
+    case "github.com/lib/pq.StringArray":
+        return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+    }
+    if basic, ok := nullTypes[name]; ok {
+        return map[string]interface{}{"type": basic, "nullable": true}
+    }
+    if marshals(t) {
+        return map[string]interface{}{"description": g.docs.typeDoc(g.fset.Position(tn.Pos()))}
+    }
+
+    switch underlying := t.Underlying().(type) {
+    case *types.Basic:
+        schema := basicSchema(underlying)
+        if underlying.Info()&types.IsString != 0 && tn.Pkg() != nil {
+            if enum := enumValues(t); len(enum) > 0 {
+                schema["enum"] = enum
+            }
+        }
+        return schema
+    case *types.Struct:
+    default:
+        return g.schema(underlying)
+    }
+
+    component, ok := g.names[tn]
+    if !ok {
+        component = tn.Name()
+        if other, ok := g.taken[component]; ok && other != tn {
+            pkg := tn.Pkg().Name()
+            component = strings.ToUpper(pkg[:1]) + pkg[1:] + tn.Name()
+        }
+        g.names[tn] = component
+        g.taken[component] = tn
+        // Set first for types referring to themselves
+        g.components[component] = map[string]interface{}{}
+        schema := g.structSchema(t.Underlying().(*types.Struct))
+        if doc := g.docs.typeDoc(g.fset.Position(tn.Pos())); doc != "" {
+            schema["description"] = doc
+        }
+        g.components[component] = schema
+    }
+    return map[string]interface{}{"$ref": "#/components/schemas/" + component}
+}
+
+// marshals tells whether t encodes itself to JSON.
+func marshals(t types.Type) bool {
+    for _, recv := range []types.Type{t, types.NewPointer(t)} {
+        methods := types.NewMethodSet(recv)
+        for i := 0; i < methods.Len(); i++ {
+            if methods.At(i).Obj().Name() == "MarshalJSON" {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// enumValues returns the constants declared of the string type t.
+func enumValues(t *types.Named) []string {
+    scope := t.Obj().Pkg().Scope()
+    var values []string
+    for _, name := range scope.Names() {
+        if c, ok := scope.Lookup(name).(*types.Const); ok && types.Identical(c.Type(), t) && c.Val().Kind() == constant.String {
+            values = append(values, constant.StringVal(c.Val()))
+        }
+    }
+    sort.Strings(values)
+    return values
+}
+
+func basicSchema(t *types.Basic) map[string]interface{} {
+    info := t.Info()
+    switch {
+    case info&types.IsBoolean != 0:
+        return map[string]interface{}{"type": "boolean"}
+    case info&types.IsInteger != 0:
+        if t.Kind() == types.Int64 || t.Kind() == types.Uint64 {
+            return map[string]interface{}{"type": "integer", "format": "int64"}
+        }
+        return map[string]interface{}{"type": "integer"}
+    case info&types.IsFloat != 0:
+        return map[string]interface{}{"type": "number"}
+    case info&types.IsString != 0:
+        return map[string]interface{}{"type": "string"}
+    }
+    return map[string]interface{}{}
+}
+
+// nullable marks a schema as allowing null, wrapping references which
+// cannot be annotated.
+func nullable(schema interface{}) interface{} {
+    s, ok := schema.(map[string]interface{})
+    if !ok {
+        return schema
+    }
+    if _, isRef := s["$ref"]; isRef {
+        return map[string]interface{}{"allOf": []interface{}{s}, "nullable": true}
+    }
+    if len(s) == 0 {
+        return s
+    }
+    copied := map[string]interface{}{"nullable": true}
+    for k, v := range s {
+        copied[k] = v
+    }
+    return copied
+}
+
+// structSchema returns the schema of a struct as encoding/json encodes it:
+// embedded structs are flattened, fields without omitempty always set.
+func (g *generator) structSchema(st *types.Struct) map[string]interface{} {
+    properties := map[string]interface{}{}
+    var required []string
+    g.addFields(st, properties, &required)
+    schema := map[string]interface{}{"type": "object", "properties": properties}
+    if len(required) > 0 && !g.request {
+        sort.Strings(required)
+        schema["required"] = required
+    }
+    return schema
+}
+
+func (g *generator) addFields(st *types.Struct, properties map[string]interface{}, required *[]string) {
+    for i := 0; i < st.NumFields(); i++ {
+        field := st.Field(i)
+        tag := reflect.StructTag(st.Tag(i)).Get("json")
+        if tag == "-" {
+            continue
+        }
+        name, options, _ := strings.Cut(tag, ",")
+        if field.Embedded() && name == "" {
+            embedded := field.Type()
+            if ptr, ok := embedded.(*types.Pointer); ok {
+                embedded = ptr.Elem()
+            }
+            if inner, ok := embedded.Underlying().(*types.Struct); ok {
+                g.addFields(inner, properties, required)
+                continue
+            }
+        }
+        if !field.Exported() {
+            continue
+        }
+        if name == "" {
+            name = field.Name()
+        }
+        schema := g.schema(field.Type())
+        if doc := g.docs.fieldDoc(g.fset.Position(field.Pos())); doc != "" {
+            if s, ok := schema.(map[string]interface{}); ok {
+                if _, isRef := s["$ref"]; isRef {
+                    schema = map[string]interface{}{"allOf": []interface{}{s}}
+                    s = schema.(map[string]interface{})
+                } else {
+                    copied := map[string]interface{}{}
+                    for k, v := range s {
+                        copied[k] = v
+                    }
+                    s = copied
+                    schema = s
+                }
+                s["description"] = doc
+            }
+        }
+        properties[name] = schema
+        if !strings.Contains(options, "omitempty") {
+            *required = append(*required, name)
+        }
+    }
+}
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// spec returns the OpenAPI document of the routes.
+func (g *generator) spec(routes []route) map[string]interface{} {
+    paths := map[string]map[string]interface{}{}
+    operationIDs := map[string]int{}
+    for _, r := range routes {
+        op := g.operation(r)
+        path := pathParam.ReplaceAllString(r.path, "{$1}")
+        segments := strings.Split(strings.TrimPrefix(r.path, "/api/"), "/")
+
+        var parameters []interface{}
+        for _, match := range pathParam.FindAllStringSubmatch(r.path, -1) {
+            parameters = append(parameters, map[string]interface{}{
+                "name": match[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+            })
+        }
+        for _, name := range op.query {
+            parameters = append(parameters, map[string]interface{}{
+                "name": name, "in": "query", "schema": map[string]interface{}{"type": "string"},
+            })
+        }
+
+        responses := map[string]interface{}{}
+        for status, response := range op.responses {
+            responses[strconv.Itoa(status)] = response
+        }
+        errorResponse := map[string]interface{}{"$ref": "#/components/responses/Error"}
+        for status := range op.errors {
+            responses[strconv.Itoa(status)] = errorResponse
+        }
+        responses["default"] = errorResponse
+
+        operationID := r.handler
+        if operationIDs[operationID]++; operationIDs[operationID] > 1 {
+            operationID += strconv.Itoa(operationIDs[operationID])
+        }
+        operation := map[string]interface{}{
+            "operationId": operationID,
+            "summary":     op.summary,
+            "tags":        []string{segments[0]},
+            "responses":   responses,
+        }
+        if len(parameters) > 0 {
+            operation["parameters"] = parameters
+        }
+        if op.request != nil {
+            g.request = true
+            operation["requestBody"] = map[string]interface{}{
+                "required": true,
+                "content": map[string]interface{}{
+                    echoMIMEJSON: map[string]interface{}{"schema": g.schema(op.request)},
+                },
+            }
+            g.request = false
+        }
+        if len(r.scopes) > 0 {
+            operation["x-required-scopes"] = r.scopes
+        }
+        if paths[path] == nil {
+            paths[path] = map[string]interface{}{}
+        }
+        paths[path][strings.ToLower(r.method)] = operation
+    }
+
+    var codes []string
+    apierror := g.importPackage(apierrorPath)
+    for _, name := range apierror.Scope().Names() {
+        if strings.HasPrefix(name, "Code") {
+            codes = append(codes, constValue(apierror, name))
+        }
+    }
+    sort.Strings(codes)
+    g.components["Error"] = map[string]interface{}{
+        "description": "The envelope of failed requests.",
+        "type":        "object",
+        "required":    []string{"error"},
+        "properties": map[string]interface{}{
+            "error": map[string]interface{}{
+                "type":     "object",
+                "required": []string{"code", "message"},
+                "properties": map[string]interface{}{
+                    "code":       map[string]interface{}{"type": "string", "enum": codes, "description": "What went wrong, for clients to branch on"},
+                    "message":    map[string]interface{}{"type": "string", "description": "What went wrong, for people"},
+                    "request_id": map[string]interface{}{"type": "string", "description": "ID of the request, also in the X-Request-ID header"},
+                    "details":    map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{}, "description": "Data helping to act on the error"},
+                },
+            },
+        },
+    }
+
+    auth := g.importPackage(authPath)
+    return map[string]interface{}{
+        "openapi": "3.0.3",
+        "info": map[string]interface{}{
+            "title":   "open-threads-reminder dashboard API",
+            "version": "1",
+        },
+        "paths": paths,
+        "components": map[string]interface{}{
+            "schemas": g.components,
+            "responses": map[string]interface{}{
+                "Error": map[string]interface{}{
+                    "description": "Error",
+                    "content": map[string]interface{}{
+                        echoMIMEJSON: map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}},
+                    },
+                },
+            },
+            "securitySchemes": map[string]interface{}{
+                "token": map[string]interface{}{
+                    "type":        "http",
+                    "scheme":      "bearer",
+                    "description": "Personal API token (" + constValue(auth, "TokenPrefix") + "...) or API key (" + constValue(auth, "KeyPrefix") + "...)",
+                },
+                "session": map[string]interface{}{
+                    "type": "apiKey",
+                    "in":   "cookie",
+                    "name": constValue(auth, "SessionCookie"),
+                },
+            },
+        },
+        "security": []interface{}{
+            map[string]interface{}{"token": []string{}},
+            map[string]interface{}{"session": []string{}},
+        },
+    }
+}
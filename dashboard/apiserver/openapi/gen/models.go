@@ -0,0 +1,114 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// jsdocModels returns the schemas as JSDoc typedefs, importable by the UI
+// with import('./apiModels').Name.
+func jsdocModels(components map[string]interface{}) string {
+    var b strings.Builder
+    b.WriteString("// Generated by go generate ./apiserver/openapi from the API handlers, do not edit.\n")
+    names := make([]string, 0, len(components))
+    for name := range components {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        schema, _ := components[name].(map[string]interface{})
+        b.WriteString("\n/**\n")
+        if doc, ok := schema["description"].(string); ok && doc != "" {
+            fmt.Fprintf(&b, " * %s\n", doc)
+        }
+        properties, _ := schema["properties"].(map[string]interface{})
+        if properties == nil {
+            fmt.Fprintf(&b, " * @typedef {%s} %s\n */\n", jsdocType(schema), name)
+            continue
+        }
+        fmt.Fprintf(&b, " * @typedef {Object} %s\n", name)
+        required := map[string]bool{}
+        if list, ok := schema["required"].([]string); ok {
+            for _, property := range list {
+                required[property] = true
+            }
+        }
+        props := make([]string, 0, len(properties))
+        for property := range properties {
+            props = append(props, property)
+        }
+        sort.Strings(props)
+        for _, property := range props {
+            propSchema, _ := properties[property].(map[string]interface{})
+            field := property
+            if !required[property] {
+                field = "[" + property + "]"
+            }
+            line := fmt.Sprintf(" * @property {%s} %s", jsdocType(propSchema), field)
+            if doc, ok := propSchema["description"].(string); ok && doc != "" {
+                line += " - " + doc
+            }
+            b.WriteString(line + "\n")
+        }
+        b.WriteString(" */\n")
+    }
+    b.WriteString("\nexport {}\n")
+    return b.String()
+}
+
+// jsdocType returns the JSDoc type of values of a schema.
+func jsdocType(schema map[string]interface{}) string {
+    t := schemaType(schema)
+    if nullable, _ := schema["nullable"].(bool); nullable {
+        return "(" + t + "|null)"
+    }
+    return t
+}
+
+func schemaType(schema map[string]interface{}) string {
+    if ref, ok := schema["$ref"].(string); ok {
+        return ref[strings.LastIndex(ref, "/")+1:]
+    }
+    if allOf, ok := schema["allOf"].([]interface{}); ok && len(allOf) == 1 {
+        inner, _ := allOf[0].(map[string]interface{})
+        return schemaType(inner)
+    }
+    switch schema["type"] {
+    case "string":
+        if enum, ok := schema["enum"].([]string); ok && len(enum) > 0 {
+            quoted := make([]string, len(enum))
+            for i, value := range enum {
+                quoted[i] = "'" + value + "'"
+            }
+            return strings.Join(quoted, "|")
+        }
+        return "string"
+    case "integer", "number":
+        return "number"
+    case "boolean":
+        return "boolean"
+    case "array":
+        items, _ := schema["items"].(map[string]interface{})
+        return "Array<" + jsdocType(items) + ">"
+    case "object":
+        if properties, ok := schema["properties"].(map[string]interface{}); ok {
+            names := make([]string, 0, len(properties))
+            for name := range properties {
+                names = append(names, name)
+            }
+            sort.Strings(names)
+            fields := make([]string, len(names))
+            for i, name := range names {
+                property, _ := properties[name].(map[string]interface{})
+                fields[i] = name + ": " + jsdocType(property)
+            }
+            return "{" + strings.Join(fields, ", ") + "}"
+        }
+        if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+            return "Object<string, " + jsdocType(additional) + ">"
+        }
+        return "Object"
+    }
+    return "*"
+}
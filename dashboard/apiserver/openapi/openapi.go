@@ -0,0 +1,37 @@
+// Package openapi serves the OpenAPI specification of the /api routes, and
+// Swagger UI to browse and try them. The specification, and the models the
+// UI uses, are generated from the routes and their handlers: run
+// go generate ./apiserver/openapi after changing them.
+package openapi
+
+//go:generate go run ./gen -routes ../apiserver.go -handlers ../handlers -out openapi.json -models ../../ui/src/lib/apiModels.js
+
+import (
+    _ "embed"
+    "net/http"
+
+    "github.com/labstack/echo/v4"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// docs loads Swagger UI, pinned to a version, from a CDN.
+//
+//go:embed docs.html
+var docs []byte
+
+// Spec returns the OpenAPI specification of the API as JSON.
+func Spec() []byte {
+    return spec
+}
+
+// GetSpec - Get the OpenAPI specification of the API
+func GetSpec(ctx echo.Context) error {
+    return ctx.JSONBlob(http.StatusOK, spec)
+}
+
+// GetDocs - Browse the API specification with Swagger UI
+func GetDocs(ctx echo.Context) error {
+    return ctx.HTMLBlob(http.StatusOK, docs)
+}
@@ -0,0 +1,73 @@
+package apiserver
+
+import (
+    "context"
+    "encoding/json"
+    "os"
+    "time"
+
+    "dashboard/apiserver/handlers"
+    "dashboard/apiserver/logger"
+)
+
+const preflightTimeout = 30 * time.Second
+
+// PreflightReport is printed by Check
+type PreflightReport struct {
+    OK     bool                   `json:"ok"`
+    Checks []handlers.CheckResult `json:"checks"`
+}
+
+// Check validates the configuration, database, schema, Slack token and
+// templates without starting the server. It prints a JSON report and
+// returns the process exit code, non-zero when any check failed.
+func Check() int {
+    log, _ := logger.NewLogger(logger.Error)
+    defer log.Cleanup()
+
+    report := PreflightReport{OK: true, Checks: []handlers.CheckResult{}}
+    add := func(result handlers.CheckResult) {
+        if result.Status == handlers.CheckFailed {
+            report.OK = false
+        }
+        report.Checks = append(report.Checks, result)
+    }
+
+    switch level := getEnv(logLevelEnv, "info"); level {
+    case "debug", "info", "warn", "error":
+        add(handlers.CheckResult{Name: "log_level", Status: handlers.CheckOK})
+    default:
+        add(handlers.CheckResult{Name: "log_level", Status: handlers.CheckFailed, Detail: "unknown log level " + level})
+    }
+
+    if err := LoadTemplates(); err != nil {
+        add(handlers.CheckResult{Name: "templates", Status: handlers.CheckFailed, Detail: err.Error()})
+    } else if _, ok := templatesMap["index.html"]; !ok {
+        add(handlers.CheckResult{Name: "templates", Status: handlers.CheckFailed, Detail: "index.html is missing from the UI build"})
+    } else {
+        add(handlers.CheckResult{Name: "templates", Status: handlers.CheckOK})
+    }
+
+    c, err := handlers.NewContainer(log)
+    if err != nil {
+        add(handlers.CheckResult{Name: "config", Status: handlers.CheckFailed, Detail: err.Error()})
+    } else {
+        defer c.Close()
+        add(handlers.CheckResult{Name: "config", Status: handlers.CheckOK})
+
+        ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+        defer cancel()
+        for _, result := range c.Preflight(ctx) {
+            add(result)
+        }
+    }
+
+    encoder := json.NewEncoder(os.Stdout)
+    encoder.SetIndent("", "  ")
+    encoder.Encode(report)
+
+    if !report.OK {
+        return 1
+    }
+    return 0
+}
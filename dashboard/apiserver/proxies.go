@@ -0,0 +1,40 @@
+package apiserver
+
+import (
+    "fmt"
+    "net"
+    "strings"
+
+    "github.com/labstack/echo/v4"
+)
+
+// trustedProxiesEnv lists the CIDR ranges of the load balancers in front of
+// the dashboard, comma separated. X-Forwarded-For is only believed for hops
+// from these ranges; when unset the peer address of the connection is used.
+const trustedProxiesEnv = "YB_OPEN_THREADS_REMINDER_TRUSTED_PROXIES"
+
+// ipExtractor returns how a request's client address is found. Lockouts,
+// CAPTCHA challenges and rate limits are keyed on it, so a header a client
+// can set is never believed unless it was appended by a trusted proxy.
+func ipExtractor(trustedProxies string) (echo.IPExtractor, error) {
+    options := []echo.TrustOption{
+        echo.TrustLoopback(false),
+        echo.TrustLinkLocal(false),
+        echo.TrustPrivateNet(false),
+    }
+    for _, cidr := range strings.Split(trustedProxies, ",") {
+        cidr = strings.TrimSpace(cidr)
+        if cidr == "" {
+            continue
+        }
+        _, ipRange, err := net.ParseCIDR(cidr)
+        if err != nil {
+            return nil, fmt.Errorf("invalid trusted proxy range %q: %w", cidr, err)
+        }
+        options = append(options, echo.TrustIPRange(ipRange))
+    }
+    if len(options) == 3 {
+        return echo.ExtractIPDirect(), nil
+    }
+    return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -0,0 +1,45 @@
+package apiserver
+
+import (
+    "net/http/httptest"
+    "testing"
+
+    "github.com/labstack/echo/v4"
+)
+
+func TestIPExtractor(t *testing.T) {
+    tests := []struct {
+        name           string
+        trustedProxies string
+        remoteAddr     string
+        forwardedFor   string
+        want           string
+    }{
+        {"no proxies ignores header", "", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+        {"private peer not trusted by default", "", "10.0.0.5:5000", "198.51.100.1", "10.0.0.5"},
+        {"trusted proxy", "10.0.0.0/8", "10.0.0.5:5000", "198.51.100.1", "198.51.100.1"},
+        {"untrusted peer", "10.0.0.0/8", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+        {"spoofed hop before proxy", "10.0.0.0/8", "10.0.0.5:5000", "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+        {"several ranges", "192.168.0.0/16, 10.0.0.0/8", "10.0.0.5:5000", "198.51.100.1", "198.51.100.1"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            extractor, err := ipExtractor(tt.trustedProxies)
+            if err != nil {
+                t.Fatal(err)
+            }
+            req := httptest.NewRequest("GET", "/", nil)
+            req.RemoteAddr = tt.remoteAddr
+            req.Header.Set(echo.HeaderXForwardedFor, tt.forwardedFor)
+            if got := extractor(req); got != tt.want {
+                t.Fatalf("got %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestIPExtractorInvalidRange(t *testing.T) {
+    if _, err := ipExtractor("10.0.0.0/8,not-a-range"); err == nil {
+        t.Fatal("expected an error for an invalid range")
+    }
+}
@@ -0,0 +1,150 @@
+// Package reminders coalesces thread reminders so that each recipient gets
+// one message listing everything due rather than a DM per thread, and holds
+// the per-channel schedules deciding when and where reminders go.
+package reminders
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/logger"
+)
+
+// Nudge is a reminder about one thread for one recipient.
+type Nudge struct {
+    Recipient string
+    ChannelID string
+    ThreadTS  string
+    Title     string
+    Priority  domain.Priority
+    // Idle is how long the thread has been without activity.
+    Idle time.Duration
+    // Overdue is set for threads past a due date set on the thread itself.
+    Overdue bool
+    // Unacknowledged is set for threads nobody replied to, claimed or
+    // acknowledged yet.
+    Unacknowledged bool
+    // Usergroup is set for nudges of the members of a usergroup owning the
+    // thread, to its ID.
+    Usergroup string
+}
+
+// Key identifies the thread of a nudge.
+func (n Nudge) Key() string {
+    return n.ChannelID + ":" + n.ThreadTS
+}
+
+// Sender delivers the batched nudges of a recipient.
+type Sender func(ctx context.Context, recipient string, nudges []Nudge) error
+
+type pending struct {
+    nudges []Nudge
+    keys   map[string]bool
+    timer  *time.Timer
+}
+
+// Batcher holds nudges for window after the first one of a recipient, then
+// sends them all at once. The same thread is only listed once per batch.
+type Batcher struct {
+    logger logger.Logger
+    window time.Duration
+    send   Sender
+
+    mu      sync.Mutex
+    ctx     context.Context
+    pending map[string]*pending
+}
+
+// NewBatcher returns a batcher delivering through send. Batches are sent
+// with ctx, which should outlive the batcher.
+func NewBatcher(ctx context.Context, log logger.Logger, window time.Duration, send Sender) *Batcher {
+    return &Batcher{
+        logger:  log,
+        window:  window,
+        send:    send,
+        ctx:     ctx,
+        pending: make(map[string]*pending),
+    }
+}
+
+// Add queues a nudge. It reports false when the thread was already queued
+// for the recipient.
+func (b *Batcher) Add(nudge Nudge) bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    batch, ok := b.pending[nudge.Recipient]
+    if !ok {
+        batch = &pending{keys: make(map[string]bool)}
+        b.pending[nudge.Recipient] = batch
+        recipient := nudge.Recipient
+        batch.timer = time.AfterFunc(b.window, func() { b.flush(recipient) })
+    }
+    if batch.keys[nudge.Key()] {
+        return false
+    }
+    batch.keys[nudge.Key()] = true
+    batch.nudges = append(batch.nudges, nudge)
+    return true
+}
+
+// Pending reports whether a thread is queued for a recipient.
+func (b *Batcher) Pending(recipient string, key string) bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    batch, ok := b.pending[recipient]
+    return ok && batch.keys[key]
+}
+
+// Drop removes a thread from every pending batch, e.g. once it got a reply
+// and is no longer idle.
+func (b *Batcher) Drop(key string) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    for _, batch := range b.pending {
+        if !batch.keys[key] {
+            continue
+        }
+        delete(batch.keys, key)
+        nudges := batch.nudges[:0]
+        for _, nudge := range batch.nudges {
+            if nudge.Key() != key {
+                nudges = append(nudges, nudge)
+            }
+        }
+        batch.nudges = nudges
+    }
+}
+
+// Flush sends every pending batch right away, e.g. on shutdown.
+func (b *Batcher) Flush() {
+    b.mu.Lock()
+    recipients := make([]string, 0, len(b.pending))
+    for recipient, batch := range b.pending {
+        batch.timer.Stop()
+        recipients = append(recipients, recipient)
+    }
+    b.mu.Unlock()
+
+    for _, recipient := range recipients {
+        b.flush(recipient)
+    }
+}
+
+func (b *Batcher) flush(recipient string) {
+    b.mu.Lock()
+    batch, ok := b.pending[recipient]
+    delete(b.pending, recipient)
+    b.mu.Unlock()
+
+    if !ok || len(batch.nudges) == 0 {
+        return
+    }
+    if err := b.send(b.ctx, recipient, batch.nudges); err != nil {
+        b.logger.Warnf("failed to send %d reminders to %s: %v", len(batch.nudges), recipient, err)
+    }
+}
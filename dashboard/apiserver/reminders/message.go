@@ -0,0 +1,157 @@
+package reminders
+
+import (
+    "fmt"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/slack"
+)
+
+// Action IDs of the per-thread buttons of a reminder message.
+const (
+    ClaimAction   = "claim_thread"
+    ResolveAction = "resolve_thread"
+    AckAction     = "ack_thread"
+    SnoozeAction  = "snooze_thread"
+    AssignAction  = "assign_thread"
+)
+
+// BlockPrefix starts the block IDs of the buttons of each thread of a
+// reminder message, followed by the key of its nudge.
+const BlockPrefix = "reminder:"
+
+// outcomePrefix starts the block IDs of the outcomes of clicked buttons.
+const outcomePrefix = "reminder-outcome:"
+
+// maxListed keeps messages below Slack's limit of 50 blocks.
+const maxListed = 15
+
+// Message returns the fallback text and blocks listing nudges, with
+// buttons to claim, assign, snooze or resolve each thread. Threads nobody
+// acknowledged yet get an acknowledge button too.
+func Message(nudges []Nudge) (string, []slack.Block) {
+    text := fmt.Sprintf("%d threads are waiting on you", len(nudges))
+    if len(nudges) == 1 {
+        text = "A thread is waiting on you"
+    }
+
+    blocks := []slack.Block{slack.Section("*" + text + "*"), slack.Divider()}
+    for i, nudge := range nudges {
+        if i == maxListed {
+            blocks = append(blocks, slack.Section(fmt.Sprintf("_…and %d more on the dashboard._", len(nudges)-maxListed)))
+            break
+        }
+
+        blocks = append(blocks, slack.Section(describe(nudge)))
+        buttons := []slack.Element{}
+        if nudge.Unacknowledged {
+            buttons = append(buttons, slack.Button("Acknowledge", AckAction, nudge.Key()))
+        }
+        buttons = append(buttons,
+            slack.Button("Claim", ClaimAction, nudge.Key()),
+            slack.Button("Assign to me", AssignAction, nudge.Key()),
+            slack.Button("Snooze 1d", SnoozeAction, nudge.Key()),
+            slack.Button("Mark resolved", ResolveAction, nudge.Key()),
+        )
+        blocks = append(blocks, slack.Actions(BlockPrefix+nudge.Key(), buttons...))
+    }
+    return text, blocks
+}
+
+// Acted returns the blocks of a reminder message once the button actionID
+// of the thread whose buttons are blockID was clicked. The outcome shows in
+// place of the previous one of the thread, above the buttons left: none
+// when the thread was dealt with, the others otherwise.
+func Acted(blocks []slack.Block, blockID string, actionID string, outcome string, dealt bool) []slack.Block {
+    key := strings.TrimPrefix(blockID, BlockPrefix)
+    updated := make([]slack.Block, 0, len(blocks)+1)
+    for _, block := range blocks {
+        if block.BlockID == outcomePrefix+key {
+            continue
+        }
+        if block.BlockID != blockID {
+            updated = append(updated, block)
+            continue
+        }
+
+        result := slack.Section("_" + outcome + "_")
+        result.BlockID = outcomePrefix + key
+        updated = append(updated, result)
+        if dealt {
+            continue
+        }
+        buttons := []slack.Element{}
+        for _, button := range block.Elements {
+            if button.ActionID != actionID {
+                buttons = append(buttons, button)
+            }
+        }
+        if len(buttons) > 0 {
+            block.Elements = buttons
+            updated = append(updated, block)
+        }
+    }
+    return updated
+}
+
+// Summary returns the mrkdwn text listing every nudge, for notifications
+// that cannot show blocks such as email.
+func Summary(nudges []Nudge) string {
+    text, _ := Message(nudges)
+    for _, nudge := range nudges {
+        text += "\n\n" + describe(nudge)
+    }
+    return text
+}
+
+// describe returns the linked title of the thread of a nudge, then on a
+// second line where it is and why it is due.
+func describe(nudge Nudge) string {
+    emoji := nudge.Priority.Emoji()
+    title := nudge.Title
+    if title == "" {
+        title = "Untitled thread"
+    }
+    state := "idle for " + humanize(nudge.Idle)
+    if nudge.Overdue {
+        state = "past its due date"
+    }
+    if nudge.Unacknowledged {
+        state += ", not acknowledged yet"
+    }
+    if nudge.Usergroup != "" {
+        state = "for " + slack.Mention(nudge.Usergroup) + ", " + state
+    }
+    return fmt.Sprintf("%s <%s|%s>\nIn <#%s>, %s",
+        emoji, slack.Permalink(nudge.ChannelID, nudge.ThreadTS), title, nudge.ChannelID, state)
+}
+
+// ThreadMessage returns the reply posted in an idle thread, mentioning the
+// recipients of its nudges, users or usergroups by their handle.
+func ThreadMessage(nudges []Nudge) string {
+    if len(nudges) == 0 {
+        return ""
+    }
+    nudge := nudges[0]
+    state := "has been idle for " + humanize(nudge.Idle)
+    if nudge.Overdue {
+        state = "is past its due date"
+    }
+    mentions := ""
+    for _, n := range nudges {
+        mentions += " " + slack.Mention(n.Recipient)
+    }
+    return fmt.Sprintf(":alarm_clock: This thread %s.%s", state, mentions)
+}
+
+func humanize(d time.Duration) string {
+    switch {
+    case d >= 48*time.Hour:
+        return fmt.Sprintf("%d days", int(d.Hours()/24))
+    case d >= 2*time.Hour:
+        return fmt.Sprintf("%d hours", int(d.Hours()))
+    default:
+        return fmt.Sprintf("%d minutes", int(d.Minutes()))
+    }
+}
@@ -0,0 +1,121 @@
+package reminders
+
+import (
+    "errors"
+    "fmt"
+    "time"
+)
+
+// Where the reminders of a channel are delivered.
+const (
+    // DeliverDirect sends each owner a direct message listing their threads
+    DeliverDirect = "dm"
+    // DeliverThread replies in the idle thread, mentioning its owners
+    DeliverThread = "thread"
+    // DeliverBoth does both
+    DeliverBoth = "both"
+)
+
+// Schedule is a channel's reminder policy, overriding the dashboard-wide
+// and channel group thresholds.
+type Schedule struct {
+    // RemindAfter is how long a thread may stay idle, e.g. "24h"
+    RemindAfter string      `json:"remind_after"`
+    Delivery    string      `json:"delivery"`
+    QuietHours  *QuietHours `json:"quiet_hours,omitempty"`
+}
+
+// QuietHours is a daily window, and optionally weekends, without
+// reminders. Reminders held back are sent once the window ends.
+type QuietHours struct {
+    // Start and End are "15:04" clock times, the window wraps past
+    // midnight when End is before Start
+    Start    string `json:"start"`
+    End      string `json:"end"`
+    TimeZone string `json:"time_zone"`
+    Weekends bool   `json:"weekends"`
+}
+
+// Validate checks a schedule and fills in the default delivery.
+func (s *Schedule) Validate() error {
+    if d, err := time.ParseDuration(s.RemindAfter); err != nil || d < time.Minute {
+        return errors.New("remind_after must be a duration of at least 1m, e.g. 24h")
+    }
+    switch s.Delivery {
+    case "":
+        s.Delivery = DeliverDirect
+    case DeliverDirect, DeliverThread, DeliverBoth:
+    default:
+        return fmt.Errorf("delivery must be %s, %s or %s", DeliverDirect, DeliverThread, DeliverBoth)
+    }
+    if s.QuietHours != nil {
+        return s.QuietHours.validate()
+    }
+    return nil
+}
+
+// Threshold returns how long a thread may stay idle.
+func (s *Schedule) Threshold() time.Duration {
+    d, _ := time.ParseDuration(s.RemindAfter)
+    return d
+}
+
+// Direct reports whether owners get direct messages.
+func (s *Schedule) Direct() bool {
+    return s.Delivery != DeliverThread
+}
+
+// InThread reports whether reminders are posted in the thread.
+func (s *Schedule) InThread() bool {
+    return s.Delivery == DeliverThread || s.Delivery == DeliverBoth
+}
+
+// Quiet reports whether reminders are held back at now.
+func (s *Schedule) Quiet(now time.Time) bool {
+    return s.QuietHours != nil && s.QuietHours.contains(now)
+}
+
+func (q *QuietHours) validate() error {
+    if _, err := time.LoadLocation(q.TimeZone); err != nil {
+        return fmt.Errorf("unknown time_zone %q", q.TimeZone)
+    }
+    start, err := time.Parse("15:04", q.Start)
+    if err != nil {
+        return errors.New("quiet_hours start must be a time like 19:00")
+    }
+    end, err := time.Parse("15:04", q.End)
+    if err != nil {
+        return errors.New("quiet_hours end must be a time like 08:00")
+    }
+    if start.Equal(end) && !q.Weekends {
+        return errors.New("quiet_hours start and end must differ")
+    }
+    return nil
+}
+
+func (q *QuietHours) contains(now time.Time) bool {
+    location, err := time.LoadLocation(q.TimeZone)
+    if err != nil {
+        return false
+    }
+    local := now.In(location)
+    if q.Weekends && (local.Weekday() == time.Saturday || local.Weekday() == time.Sunday) {
+        return true
+    }
+
+    start, err := time.Parse("15:04", q.Start)
+    if err != nil {
+        return false
+    }
+    end, err := time.Parse("15:04", q.End)
+    if err != nil {
+        return false
+    }
+    minute := local.Hour()*60 + local.Minute()
+    from := start.Hour()*60 + start.Minute()
+    to := end.Hour()*60 + end.Minute()
+    if from <= to {
+        return minute >= from && minute < to
+    }
+    return minute >= from || minute < to
+}
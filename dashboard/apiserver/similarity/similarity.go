@@ -0,0 +1,77 @@
+// Package similarity finds past threads resembling a new one by comparing
+// the words of their titles and descriptions.
+package similarity
+
+import (
+    "sort"
+    "strings"
+    "unicode"
+)
+
+// Document is a thread reduced to an identifier and its text.
+type Document struct {
+    ID   string
+    Text string
+}
+
+// Match is a document scored against a query.
+type Match struct {
+    Document
+    Score float64
+}
+
+var stopWords = map[string]bool{
+    "the": true, "and": true, "for": true, "with": true, "this": true, "that": true,
+    "from": true, "are": true, "was": true, "not": true, "but": true, "have": true,
+    "has": true, "how": true, "what": true, "when": true, "why": true, "can": true,
+    "does": true, "into": true, "any": true, "our": true, "you": true, "your": true,
+    "issue": true, "thread": true, "question": true,
+}
+
+// Terms returns the set of significant lowercase words of text.
+func Terms(text string) map[string]bool {
+    terms := make(map[string]bool)
+    words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+    for _, word := range words {
+        if len(word) < 3 || stopWords[word] {
+            continue
+        }
+        terms[word] = true
+    }
+    return terms
+}
+
+// Jaccard returns the size of the intersection of a and b over the size of
+// their union.
+func Jaccard(a, b map[string]bool) float64 {
+    if len(a) == 0 || len(b) == 0 {
+        return 0
+    }
+    shared := 0
+    for term := range a {
+        if b[term] {
+            shared++
+        }
+    }
+    return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// Rank returns at most limit candidates scoring at least minScore against
+// query, best first.
+func Rank(query string, candidates []Document, limit int, minScore float64) []Match {
+    queryTerms := Terms(query)
+    matches := []Match{}
+    for _, candidate := range candidates {
+        score := Jaccard(queryTerms, Terms(candidate.Text))
+        if score > 0 && score >= minScore {
+            matches = append(matches, Match{Document: candidate, Score: score})
+        }
+    }
+    sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+    if limit > 0 && len(matches) > limit {
+        matches = matches[:limit]
+    }
+    return matches
+}
@@ -0,0 +1,96 @@
+package slack
+
+import "strings"
+
+// Block Kit object and block types used by the dashboard.
+const (
+    TextPlain    = "plain_text"
+    TextMarkdown = "mrkdwn"
+)
+
+// Text is a Block Kit text object.
+type Text struct {
+    Type string `json:"type"`
+    Text string `json:"text"`
+}
+
+// Element is a Block Kit interactive or context element.
+type Element struct {
+    Type         string `json:"type"`
+    Text         *Text  `json:"text,omitempty"`
+    ActionID     string `json:"action_id,omitempty"`
+    Value        string `json:"value,omitempty"`
+    URL          string `json:"url,omitempty"`
+    Style        string `json:"style,omitempty"`
+    Placeholder  *Text  `json:"placeholder,omitempty"`
+    InitialValue string `json:"initial_value,omitempty"`
+}
+
+// Block is a Block Kit layout block.
+type Block struct {
+    Type      string    `json:"type"`
+    BlockID   string    `json:"block_id,omitempty"`
+    Text      *Text     `json:"text,omitempty"`
+    Accessory *Element  `json:"accessory,omitempty"`
+    Elements  []Element `json:"elements,omitempty"`
+    Label     *Text     `json:"label,omitempty"`
+    Element   *Element  `json:"element,omitempty"`
+    Hint      *Text     `json:"hint,omitempty"`
+    Optional  bool      `json:"optional,omitempty"`
+}
+
+// Markdown returns a mrkdwn text object.
+func Markdown(text string) *Text {
+    return &Text{Type: TextMarkdown, Text: text}
+}
+
+// Plain returns a plain text object.
+func Plain(text string) *Text {
+    return &Text{Type: TextPlain, Text: text}
+}
+
+// Section returns a section block showing text.
+func Section(text string) Block {
+    return Block{Type: "section", Text: Markdown(text)}
+}
+
+// Divider returns a divider block.
+func Divider() Block {
+    return Block{Type: "divider"}
+}
+
+// Actions returns an actions block holding elements.
+func Actions(blockID string, elements ...Element) Block {
+    return Block{Type: "actions", BlockID: blockID, Elements: elements}
+}
+
+// Button returns a button element sending value with actionID when clicked.
+func Button(text string, actionID string, value string) Element {
+    return Element{Type: "button", Text: Plain(text), ActionID: actionID, Value: value}
+}
+
+// TextInput returns an input block with a single line plain text field
+// whose value is reported under blockID and actionID.
+func TextInput(blockID string, actionID string, label string, hint string, initial string, optional bool) Block {
+    block := Block{
+        Type:    "input",
+        BlockID: blockID,
+        Label:   Plain(label),
+        Element: &Element{
+            Type:         "plain_text_input",
+            ActionID:     actionID,
+            InitialValue: initial,
+        },
+        Optional: optional,
+    }
+    if hint != "" {
+        block.Hint = Plain(hint)
+    }
+    return block
+}
+
+// Permalink returns a link to a message that works without knowing the
+// workspace domain.
+func Permalink(channelID string, ts string) string {
+    return "https://slack.com/archives/" + channelID + "/p" + strings.ReplaceAll(ts, ".", "")
+}
@@ -0,0 +1,143 @@
+package slack
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "golang.org/x/time/rate"
+)
+
+// Tier is a Slack Web API rate limit tier.
+type Tier int
+
+const (
+    Tier1 Tier = iota + 1
+    Tier2
+    Tier3
+    Tier4
+    // TierPostMessage covers chat.postMessage style methods, limited to
+    // roughly one message per second.
+    TierPostMessage
+)
+
+// perMinute is the documented budget of each tier.
+var perMinute = map[Tier]int{
+    Tier1:           1,
+    Tier2:           20,
+    Tier3:           50,
+    Tier4:           100,
+    TierPostMessage: 60,
+}
+
+// methodTiers lists the tier of the methods used by the dashboard. Unknown
+// methods are conservatively treated as Tier 2.
+var methodTiers = map[string]Tier{
+    "auth.test":             Tier4,
+    "conversations.history": Tier3,
+    "conversations.replies": Tier3,
+    "conversations.info":    Tier3,
+    "conversations.list":    Tier2,
+    "conversations.members": Tier4,
+    "users.conversations":   Tier3,
+    "users.info":            Tier4,
+    "users.list":            Tier2,
+    "usergroups.list":       Tier2,
+    "reactions.get":         Tier3,
+    "chat.getPermalink":     Tier4,
+    "chat.postMessage":      TierPostMessage,
+    "chat.postEphemeral":    TierPostMessage,
+    "chat.update":           Tier3,
+    "views.open":            Tier4,
+
+    // Channel summaries
+    "pins.add":                      Tier2,
+    "canvases.edit":                 Tier3,
+    "conversations.canvases.create": Tier2,
+}
+
+// TierOf returns the rate limit tier of method.
+func TierOf(method string) Tier {
+    if tier, ok := methodTiers[method]; ok {
+        return tier
+    }
+    return Tier2
+}
+
+// Budget paces Web API calls so that every caller sharing it, e.g. several
+// channel backfills running at once, stays within Slack's per-tier limits.
+type Budget struct {
+    mu       sync.Mutex
+    limiters map[Tier]*rate.Limiter
+    paused   map[Tier]time.Time
+    // share is the fraction of each tier's budget this process may use,
+    // leaving room for the collector using the same token.
+    share float64
+}
+
+// NewBudget returns a budget using share (0, 1] of each tier's limit.
+func NewBudget(share float64) *Budget {
+    if share <= 0 || share > 1 {
+        share = 1
+    }
+    return &Budget{
+        limiters: make(map[Tier]*rate.Limiter),
+        paused:   make(map[Tier]time.Time),
+        share:    share,
+    }
+}
+
+func (b *Budget) limiter(tier Tier) *rate.Limiter {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    limiter, ok := b.limiters[tier]
+    if !ok {
+        perSecond := float64(perMinute[tier]) * b.share / 60
+        burst := int(float64(perMinute[tier]) * b.share / 10)
+        if burst < 1 {
+            burst = 1
+        }
+        limiter = rate.NewLimiter(rate.Limit(perSecond), burst)
+        b.limiters[tier] = limiter
+    }
+    return limiter
+}
+
+// Wait blocks until a call to method fits in the budget.
+func (b *Budget) Wait(ctx context.Context, method string) error {
+    tier := TierOf(method)
+
+    b.mu.Lock()
+    resumeAt := b.paused[tier]
+    b.mu.Unlock()
+    if wait := time.Until(resumeAt); wait > 0 {
+        timer := time.NewTimer(wait)
+        defer timer.Stop()
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-timer.C:
+        }
+    }
+
+    return b.limiter(tier).Wait(ctx)
+}
+
+// PerMinute returns how many calls of method's tier the budget allows per
+// minute.
+func (b *Budget) PerMinute(method string) float64 {
+    return float64(perMinute[TierOf(method)]) * b.share
+}
+
+// Backoff pauses every call of method's tier after Slack answered 429.
+func (b *Budget) Backoff(method string, retryAfter time.Duration) {
+    tier := TierOf(method)
+
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if resumeAt := time.Now().Add(retryAfter); resumeAt.After(b.paused[tier]) {
+        b.paused[tier] = resumeAt
+    }
+}
@@ -0,0 +1,49 @@
+package slack
+
+import (
+    "context"
+    "encoding/json"
+    "net/url"
+)
+
+// canvasDocument is the content of a canvas, written in markdown.
+type canvasDocument struct {
+    Type     string `json:"type"`
+    Markdown string `json:"markdown"`
+}
+
+// CreateChannelCanvas creates the canvas of a channel, shown in its header,
+// from markdown. It returns the ID of the canvas. A channel has one canvas,
+// Slack answers channel_canvas_already_exists when it was created already.
+func (c *Client) CreateChannelCanvas(ctx context.Context, channel string, markdown string) (string, error) {
+    content, err := json.Marshal(canvasDocument{Type: "markdown", Markdown: markdown})
+    if err != nil {
+        return "", err
+    }
+    params := url.Values{}
+    params.Set("channel_id", channel)
+    params.Set("document_content", string(content))
+
+    var resp struct {
+        CanvasID string `json:"canvas_id"`
+    }
+    if err := c.Call(ctx, "conversations.canvases.create", params, &resp); err != nil {
+        return "", err
+    }
+    return resp.CanvasID, nil
+}
+
+// ReplaceCanvas replaces the whole content of a canvas with markdown.
+func (c *Client) ReplaceCanvas(ctx context.Context, canvasID string, markdown string) error {
+    changes, err := json.Marshal([]map[string]interface{}{{
+        "operation":        "replace",
+        "document_content": canvasDocument{Type: "markdown", Markdown: markdown},
+    }})
+    if err != nil {
+        return err
+    }
+    params := url.Values{}
+    params.Set("canvas_id", canvasID)
+    params.Set("changes", string(changes))
+    return c.Call(ctx, "canvases.edit", params, nil)
+}
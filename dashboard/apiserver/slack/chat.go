@@ -0,0 +1,114 @@
+package slack
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+)
+
+// PostEphemeral shows a message to a single member of a channel.
+func (c *Client) PostEphemeral(ctx context.Context, channel string, user string, text string, blocks []Block) error {
+    params := url.Values{}
+    params.Set("channel", channel)
+    params.Set("user", user)
+    params.Set("text", text)
+    if len(blocks) > 0 {
+        encoded, err := json.Marshal(blocks)
+        if err != nil {
+            return err
+        }
+        params.Set("blocks", string(encoded))
+    }
+    return c.Call(ctx, "chat.postEphemeral", params, nil)
+}
+
+// PostMessage posts a message to a channel, or to a direct message when
+// channel is a user ID. It returns the ts of the posted message.
+func (c *Client) PostMessage(ctx context.Context, channel string, text string, blocks []Block) (string, error) {
+    params := url.Values{}
+    params.Set("channel", channel)
+    params.Set("text", text)
+    if len(blocks) > 0 {
+        encoded, err := json.Marshal(blocks)
+        if err != nil {
+            return "", err
+        }
+        params.Set("blocks", string(encoded))
+    }
+
+    var resp struct {
+        TS string `json:"ts"`
+    }
+    if err := c.Call(ctx, "chat.postMessage", params, &resp); err != nil {
+        return "", err
+    }
+    return resp.TS, nil
+}
+
+// UpdateMessage replaces the text and blocks of a message posted by the bot.
+func (c *Client) UpdateMessage(ctx context.Context, channel string, ts string, text string, blocks []Block) error {
+    params := url.Values{}
+    params.Set("channel", channel)
+    params.Set("ts", ts)
+    params.Set("text", text)
+    encoded, err := json.Marshal(blocks)
+    if err != nil {
+        return err
+    }
+    params.Set("blocks", string(encoded))
+    return c.Call(ctx, "chat.update", params, nil)
+}
+
+// PinMessage pins a message to its channel.
+func (c *Client) PinMessage(ctx context.Context, channel string, ts string) error {
+    params := url.Values{}
+    params.Set("channel", channel)
+    params.Set("timestamp", ts)
+    return c.Call(ctx, "pins.add", params, nil)
+}
+
+// PostReply posts a plain text reply in the thread of a channel message.
+func (c *Client) PostReply(ctx context.Context, channel string, threadTS string, text string) error {
+    params := url.Values{}
+    params.Set("channel", channel)
+    params.Set("thread_ts", threadTS)
+    params.Set("text", text)
+    return c.Call(ctx, "chat.postMessage", params, nil)
+}
+
+// ResponseMessage is sent to the response_url of an interaction.
+type ResponseMessage struct {
+    Text            string  `json:"text"`
+    Blocks          []Block `json:"blocks,omitempty"`
+    ResponseType    string  `json:"response_type,omitempty"`
+    ReplaceOriginal bool    `json:"replace_original,omitempty"`
+    DeleteOriginal  bool    `json:"delete_original,omitempty"`
+}
+
+// Respond answers an interaction through its response_url, which needs no
+// token and is not subject to the Web API rate limits.
+func (c *Client) Respond(ctx context.Context, responseURL string, msg ResponseMessage) error {
+    body, err := json.Marshal(msg)
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("slack response_url: unexpected status %d", resp.StatusCode)
+    }
+    return nil
+}
@@ -0,0 +1,165 @@
+package slack
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const defaultBaseURL = "https://slack.com/api/"
+
+// ErrNotConfigured is returned by every call of a client without a token.
+var ErrNotConfigured = errors.New("slack bot token is not configured")
+
+// APIError is a response with "ok": false.
+type APIError struct {
+    Method string
+    Code   string
+}
+
+func (e *APIError) Error() string {
+    return fmt.Sprintf("slack %s: %s", e.Method, e.Code)
+}
+
+// RateLimitedError is returned when Slack answers with HTTP 429.
+type RateLimitedError struct {
+    Method     string
+    RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+    return fmt.Sprintf("slack %s: rate limited, retry after %s", e.Method, e.RetryAfter)
+}
+
+// Client is a minimal Slack Web API client.
+type Client struct {
+    token      string
+    baseURL    string
+    httpClient *http.Client
+    budget     *Budget
+}
+
+// NewClient returns a client authenticating with a bot token. Calls are
+// paced by budget, which may be shared with other clients of the same app.
+func NewClient(token string, budget *Budget) *Client {
+    return &Client{
+        token:      token,
+        baseURL:    defaultBaseURL,
+        httpClient: &http.Client{Timeout: 30 * time.Second},
+        budget:     budget,
+    }
+}
+
+// WrapTransport wraps the transport Web API calls are sent through, e.g. to
+// inject faults.
+func (c *Client) WrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+    transport := c.httpClient.Transport
+    if transport == nil {
+        transport = http.DefaultTransport
+    }
+    c.httpClient.Transport = wrap(transport)
+}
+
+// Configured reports whether the client has a token.
+func (c *Client) Configured() bool {
+    return c != nil && c.token != ""
+}
+
+// CallsPerMinute returns how many calls of method's tier the client makes
+// at most per minute.
+func (c *Client) CallsPerMinute(method string) float64 {
+    if c.budget == nil {
+        return float64(perMinute[TierOf(method)])
+    }
+    return c.budget.PerMinute(method)
+}
+
+// Call invokes a Web API method with form parameters and decodes the
+// response into out.
+func (c *Client) Call(ctx context.Context, method string, params url.Values, out interface{}) error {
+    _, err := c.call(ctx, method, params, out)
+    return err
+}
+
+func (c *Client) call(ctx context.Context, method string, params url.Values, out interface{}) (http.Header, error) {
+    if !c.Configured() {
+        return nil, ErrNotConfigured
+    }
+    if c.budget != nil {
+        if err := c.budget.Wait(ctx, method); err != nil {
+            return nil, err
+        }
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+method, strings.NewReader(params.Encode()))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Authorization", "Bearer "+c.token)
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode == http.StatusTooManyRequests {
+        retryAfter := time.Minute
+        if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+            retryAfter = time.Duration(seconds) * time.Second
+        }
+        if c.budget != nil {
+            c.budget.Backoff(method, retryAfter)
+        }
+        return resp.Header, &RateLimitedError{Method: method, RetryAfter: retryAfter}
+    }
+    if resp.StatusCode != http.StatusOK {
+        return resp.Header, fmt.Errorf("slack %s: unexpected status %d", method, resp.StatusCode)
+    }
+
+    var raw json.RawMessage
+    if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+        return resp.Header, err
+    }
+    var status struct {
+        OK    bool   `json:"ok"`
+        Error string `json:"error"`
+    }
+    if err := json.Unmarshal(raw, &status); err != nil {
+        return resp.Header, err
+    }
+    if !status.OK {
+        return resp.Header, &APIError{Method: method, Code: status.Error}
+    }
+    if out == nil {
+        return resp.Header, nil
+    }
+    return resp.Header, json.Unmarshal(raw, out)
+}
+
+// AuthTest returns the OAuth scopes granted to the token.
+func (c *Client) AuthTest(ctx context.Context) ([]string, error) {
+    header, err := c.call(ctx, "auth.test", url.Values{}, nil)
+    if err != nil {
+        return nil, err
+    }
+    scopes := []string{}
+    for _, scope := range strings.Split(header.Get("X-OAuth-Scopes"), ",") {
+        if scope = strings.TrimSpace(scope); scope != "" {
+            scopes = append(scopes, scope)
+        }
+    }
+    return scopes, nil
+}
+
+// ResponseMetadata carries the cursor of paginated methods.
+type ResponseMetadata struct {
+    NextCursor string `json:"next_cursor"`
+}
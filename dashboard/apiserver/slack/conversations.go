@@ -0,0 +1,124 @@
+package slack
+
+import (
+    "context"
+    "net/url"
+    "strconv"
+)
+
+// Message is a message returned by the conversations methods.
+type Message struct {
+    Type        string `json:"type"`
+    Subtype     string `json:"subtype"`
+    User        string `json:"user"`
+    Team        string `json:"team"`
+    BotID       string `json:"bot_id"`
+    Text        string `json:"text"`
+    TS          string `json:"ts"`
+    ThreadTS    string `json:"thread_ts"`
+    ReplyCount  int    `json:"reply_count"`
+    LatestReply string `json:"latest_reply"`
+}
+
+// IsThreadRoot reports whether the message starts a thread or is a plain
+// top level message.
+func (m Message) IsThreadRoot() bool {
+    return m.ThreadTS == "" || m.ThreadTS == m.TS
+}
+
+// HistoryPage is one page of conversations.history.
+type HistoryPage struct {
+    Messages         []Message        `json:"messages"`
+    HasMore          bool             `json:"has_more"`
+    ResponseMetadata ResponseMetadata `json:"response_metadata"`
+}
+
+// ConversationsHistory fetches a page of top level messages of a channel
+// posted after oldest (a Slack ts, may be empty).
+func (c *Client) ConversationsHistory(ctx context.Context, channel string, oldest string, cursor string, limit int) (*HistoryPage, error) {
+    params := url.Values{}
+    params.Set("channel", channel)
+    params.Set("limit", strconv.Itoa(limit))
+    if oldest != "" {
+        params.Set("oldest", oldest)
+    }
+    if cursor != "" {
+        params.Set("cursor", cursor)
+    }
+
+    var page HistoryPage
+    if err := c.Call(ctx, "conversations.history", params, &page); err != nil {
+        return nil, err
+    }
+    return &page, nil
+}
+
+// Conversation is the subset of conversations.info telling shared channels
+// apart.
+type Conversation struct {
+    ID          string `json:"id"`
+    Name        string `json:"name"`
+    IsExtShared bool   `json:"is_ext_shared"`
+    // IsPendingExtShared is set while an invitation to share is pending
+    IsPendingExtShared bool `json:"is_pending_ext_shared"`
+}
+
+// ConversationInfo fetches a channel.
+func (c *Client) ConversationInfo(ctx context.Context, channel string) (*Conversation, error) {
+    params := url.Values{}
+    params.Set("channel", channel)
+
+    var resp struct {
+        Channel Conversation `json:"channel"`
+    }
+    if err := c.Call(ctx, "conversations.info", params, &resp); err != nil {
+        return nil, err
+    }
+    return &resp.Channel, nil
+}
+
+// ConversationsReplies fetches a page of the messages of a thread, its root
+// first.
+func (c *Client) ConversationsReplies(ctx context.Context, channel string, ts string, cursor string, limit int) (*HistoryPage, error) {
+    params := url.Values{}
+    params.Set("channel", channel)
+    params.Set("ts", ts)
+    params.Set("limit", strconv.Itoa(limit))
+    if cursor != "" {
+        params.Set("cursor", cursor)
+    }
+
+    var page HistoryPage
+    if err := c.Call(ctx, "conversations.replies", params, &page); err != nil {
+        return nil, err
+    }
+    return &page, nil
+}
+
+// ConversationsPage is one page of users.conversations.
+type ConversationsPage struct {
+    Channels         []Conversation   `json:"channels"`
+    ResponseMetadata ResponseMetadata `json:"response_metadata"`
+}
+
+// UsersConversations fetches a page of the unarchived public and private
+// channels the bot is a member of. Org-wide installs of an Enterprise Grid
+// list the channels of one workspace, teamID, at a time.
+func (c *Client) UsersConversations(ctx context.Context, teamID string, cursor string, limit int) (*ConversationsPage, error) {
+    params := url.Values{}
+    params.Set("types", "public_channel,private_channel")
+    if teamID != "" {
+        params.Set("team_id", teamID)
+    }
+    params.Set("exclude_archived", "true")
+    params.Set("limit", strconv.Itoa(limit))
+    if cursor != "" {
+        params.Set("cursor", cursor)
+    }
+
+    var page ConversationsPage
+    if err := c.Call(ctx, "users.conversations", params, &page); err != nil {
+        return nil, err
+    }
+    return &page, nil
+}
@@ -0,0 +1,77 @@
+package slack
+
+import (
+    "context"
+    "net/url"
+    "strconv"
+    "strings"
+)
+
+// Identity is who a token belongs to. Org-wide installs of an Enterprise
+// Grid have an enterprise but no workspace of their own.
+type Identity struct {
+    TeamID              string `json:"team_id"`
+    Team                string `json:"team"`
+    UserID              string `json:"user_id"`
+    EnterpriseID        string `json:"enterprise_id"`
+    IsEnterpriseInstall bool   `json:"is_enterprise_install"`
+}
+
+// Identity returns who the token belongs to.
+func (c *Client) Identity(ctx context.Context) (*Identity, error) {
+    var identity Identity
+    if err := c.Call(ctx, "auth.test", url.Values{}, &identity); err != nil {
+        return nil, err
+    }
+    return &identity, nil
+}
+
+// Team is a workspace of an Enterprise Grid.
+type Team struct {
+    ID   string `json:"id"`
+    Name string `json:"name"`
+}
+
+// TeamsPage is one page of auth.teams.list.
+type TeamsPage struct {
+    Teams            []Team           `json:"teams"`
+    ResponseMetadata ResponseMetadata `json:"response_metadata"`
+}
+
+// AuthTeamsList fetches a page of the workspaces an org-wide install was
+// granted access to.
+func (c *Client) AuthTeamsList(ctx context.Context, cursor string, limit int) (*TeamsPage, error) {
+    params := url.Values{}
+    params.Set("limit", strconv.Itoa(limit))
+    if cursor != "" {
+        params.Set("cursor", cursor)
+    }
+
+    var page TeamsPage
+    if err := c.Call(ctx, "auth.teams.list", params, &page); err != nil {
+        return nil, err
+    }
+    return &page, nil
+}
+
+// MigrationExchange maps local user IDs of a workspace to their IDs across
+// the grid. IDs Slack does not know are left out of the map.
+func (c *Client) MigrationExchange(ctx context.Context, teamID string, userIDs []string) (map[string]string, error) {
+    params := url.Values{}
+    params.Set("users", strings.Join(userIDs, ","))
+    params.Set("to_old", "false")
+    if teamID != "" {
+        params.Set("team_id", teamID)
+    }
+
+    var resp struct {
+        UserIDMap map[string]string `json:"user_id_map"`
+    }
+    if err := c.Call(ctx, "migration.exchange", params, &resp); err != nil {
+        return nil, err
+    }
+    if resp.UserIDMap == nil {
+        resp.UserIDMap = map[string]string{}
+    }
+    return resp.UserIDMap, nil
+}
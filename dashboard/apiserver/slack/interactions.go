@@ -0,0 +1,94 @@
+package slack
+
+import (
+    "encoding/json"
+    "errors"
+    "net/url"
+)
+
+// Action is a clicked interactive element of a block_actions interaction.
+type Action struct {
+    ActionID string `json:"action_id"`
+    BlockID  string `json:"block_id"`
+    Value    string `json:"value"`
+}
+
+// Interaction is the payload Slack posts when a user interacts with a
+// message, e.g. clicks a button.
+type Interaction struct {
+    Type string `json:"type"`
+    User struct {
+        ID string `json:"id"`
+    } `json:"user"`
+    Team struct {
+        ID string `json:"id"`
+    } `json:"team"`
+    Channel struct {
+        ID string `json:"id"`
+    } `json:"channel"`
+    TriggerID   string   `json:"trigger_id"`
+    ResponseURL string   `json:"response_url"`
+    Actions     []Action `json:"actions"`
+    // Message is the message holding the clicked element of a
+    // block_actions interaction
+    Message *InteractionMessage `json:"message"`
+    // CallbackID names the step of a workflow_step_edit interaction
+    CallbackID   string        `json:"callback_id"`
+    WorkflowStep *WorkflowStep `json:"workflow_step"`
+    View         *View         `json:"view"`
+}
+
+// InteractionMessage is the message an interaction happened in.
+type InteractionMessage struct {
+    TS     string  `json:"ts"`
+    Text   string  `json:"text"`
+    Blocks []Block `json:"blocks"`
+}
+
+// View is a modal or workflow step configuration view. State holds the
+// submitted values by block and action ID.
+type View struct {
+    Type       string  `json:"type"`
+    CallbackID string  `json:"callback_id"`
+    Blocks     []Block `json:"blocks"`
+    State      struct {
+        Values map[string]map[string]struct {
+            Value string `json:"value"`
+        } `json:"values"`
+    } `json:"state"`
+}
+
+// Value returns the submitted value of the input with blockID and actionID.
+func (v *View) Value(blockID string, actionID string) string {
+    return v.State.Values[blockID][actionID].Value
+}
+
+// ViewErrors answers a view submission with errors shown under the given
+// blocks, keeping the modal open.
+type ViewErrors struct {
+    ResponseAction string            `json:"response_action"`
+    Errors         map[string]string `json:"errors"`
+}
+
+// NewViewErrors returns the answer showing errors, keyed by block ID.
+func NewViewErrors(errors map[string]string) ViewErrors {
+    return ViewErrors{ResponseAction: "errors", Errors: errors}
+}
+
+// ParseInteraction decodes the form encoded body of an interaction request.
+func ParseInteraction(body []byte) (*Interaction, error) {
+    form, err := url.ParseQuery(string(body))
+    if err != nil {
+        return nil, err
+    }
+    payload := form.Get("payload")
+    if payload == "" {
+        return nil, errors.New("missing payload")
+    }
+
+    var interaction Interaction
+    if err := json.Unmarshal([]byte(payload), &interaction); err != nil {
+        return nil, err
+    }
+    return &interaction, nil
+}
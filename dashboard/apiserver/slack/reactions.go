@@ -0,0 +1,38 @@
+package slack
+
+import (
+    "context"
+    "net/url"
+    "strings"
+)
+
+// Reaction is an emoji reaction on a message with the users who added it.
+type Reaction struct {
+    Name  string   `json:"name"`
+    Count int      `json:"count"`
+    Users []string `json:"users"`
+}
+
+// BaseName returns the emoji name without its skin tone modifier.
+func (r Reaction) BaseName() string {
+    name, _, _ := strings.Cut(r.Name, "::")
+    return name
+}
+
+// ReactionsGet returns the reactions on a message.
+func (c *Client) ReactionsGet(ctx context.Context, channel string, ts string) ([]Reaction, error) {
+    params := url.Values{}
+    params.Set("channel", channel)
+    params.Set("timestamp", ts)
+    params.Set("full", "true")
+
+    var resp struct {
+        Message struct {
+            Reactions []Reaction `json:"reactions"`
+        } `json:"message"`
+    }
+    if err := c.Call(ctx, "reactions.get", params, &resp); err != nil {
+        return nil, err
+    }
+    return resp.Message.Reactions, nil
+}
@@ -0,0 +1,66 @@
+package slack
+
+import (
+    "context"
+    "net/url"
+    "regexp"
+)
+
+// usergroupIDPattern matches the IDs of usergroups.
+var usergroupIDPattern = regexp.MustCompile(`^S[A-Z0-9]+$`)
+
+// subteamMentionPattern matches mentions of usergroups in message text,
+// e.g. <!subteam^S0123ABCD|@backend-team>.
+var subteamMentionPattern = regexp.MustCompile(`<!subteam\^(S[A-Z0-9]+)`)
+
+// Usergroup is a group of users mentioned at once by its handle, such as
+// @backend-team.
+type Usergroup struct {
+    ID     string   `json:"id"`
+    TeamID string   `json:"team_id"`
+    Handle string   `json:"handle"`
+    Name   string   `json:"name"`
+    Users  []string `json:"users"`
+}
+
+// UsergroupsList fetches the enabled usergroups with their members. Org-wide
+// installs of an Enterprise Grid list those of one workspace, teamID.
+func (c *Client) UsergroupsList(ctx context.Context, teamID string) ([]Usergroup, error) {
+    params := url.Values{}
+    params.Set("include_users", "true")
+    if teamID != "" {
+        params.Set("team_id", teamID)
+    }
+
+    var resp struct {
+        Usergroups []Usergroup `json:"usergroups"`
+    }
+    if err := c.Call(ctx, "usergroups.list", params, &resp); err != nil {
+        return nil, err
+    }
+    return resp.Usergroups, nil
+}
+
+// IsUsergroupID reports whether id is the ID of a usergroup rather than of a
+// user.
+func IsUsergroupID(id string) bool {
+    return usergroupIDPattern.MatchString(id)
+}
+
+// Mention returns the mrkdwn mentioning a user or a usergroup, which
+// notifies every member of the group.
+func Mention(id string) string {
+    if IsUsergroupID(id) {
+        return "<!subteam^" + id + ">"
+    }
+    return "<@" + id + ">"
+}
+
+// MentionedUsergroups returns the IDs of the usergroups mentioned in text.
+func MentionedUsergroups(text string) []string {
+    var ids []string
+    for _, match := range subteamMentionPattern.FindAllStringSubmatch(text, -1) {
+        ids = append(ids, match[1])
+    }
+    return ids
+}
@@ -0,0 +1,55 @@
+package slack
+
+import (
+    "context"
+    "net/url"
+    "strconv"
+)
+
+// User is a member of the workspace as listed by users.list.
+type User struct {
+    ID      string      `json:"id"`
+    TeamID  string      `json:"team_id"`
+    Name    string      `json:"name"`
+    Deleted bool        `json:"deleted"`
+    IsBot   bool        `json:"is_bot"`
+    Profile UserProfile `json:"profile"`
+}
+
+// UserProfile holds the names and avatars of a user.
+type UserProfile struct {
+    DisplayName   string `json:"display_name"`
+    RealName      string `json:"real_name"`
+    Image24       string `json:"image_24"`
+    Image32       string `json:"image_32"`
+    Image48       string `json:"image_48"`
+    Image72       string `json:"image_72"`
+    Image512      string `json:"image_512"`
+    ImageOriginal string `json:"image_original"`
+}
+
+// UsersPage is one page of users.list.
+type UsersPage struct {
+    Members          []User           `json:"members"`
+    ResponseMetadata ResponseMetadata `json:"response_metadata"`
+}
+
+// UsersList fetches a page of the members of the workspace, deactivated
+// ones included with Deleted set. Org-wide installs of an Enterprise Grid
+// list the members of one workspace, teamID, at a time.
+func (c *Client) UsersList(ctx context.Context, teamID string, cursor string, limit int) (*UsersPage, error) {
+    params := url.Values{}
+    if teamID != "" {
+        params.Set("team_id", teamID)
+    }
+    params.Set("limit", strconv.Itoa(limit))
+    if cursor != "" {
+        params.Set("cursor", cursor)
+    }
+
+    var page UsersPage
+    if err := c.Call(ctx, "users.list", params, &page); err != nil {
+        return nil, err
+    }
+    return &page, nil
+}
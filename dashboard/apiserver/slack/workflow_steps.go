@@ -0,0 +1,146 @@
+package slack
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/url"
+    "strings"
+)
+
+// Event and interaction types of Workflow Builder steps.
+const (
+    WorkflowStepEdit    = "workflow_step_edit"
+    WorkflowStepExecute = "workflow_step_execute"
+    ViewSubmission      = "view_submission"
+    // WorkflowStepView is the view type of a step configuration modal
+    WorkflowStepView = "workflow_step"
+)
+
+// StepInput is a configured input of a workflow step. Values may hold
+// workflow variables, which Slack replaces before the step executes.
+type StepInput struct {
+    Value string `json:"value"`
+}
+
+// StepOutput declares a value a workflow step hands to the steps after it.
+type StepOutput struct {
+    Name  string `json:"name"`
+    Type  string `json:"type"`
+    Label string `json:"label"`
+}
+
+// WorkflowStep identifies a step being configured or executed.
+type WorkflowStep struct {
+    WorkflowStepEditID    string               `json:"workflow_step_edit_id"`
+    WorkflowStepExecuteID string               `json:"workflow_step_execute_id"`
+    WorkflowID            string               `json:"workflow_id"`
+    StepID                string               `json:"step_id"`
+    Inputs                map[string]StepInput `json:"inputs"`
+    Outputs               []StepOutput         `json:"outputs"`
+}
+
+// Input returns the value of the input called name.
+func (s *WorkflowStep) Input(name string) string {
+    return strings.TrimSpace(s.Inputs[name].Value)
+}
+
+// WorkflowStepEvent is the workflow_step_execute event sent when a
+// workflow reaches a step of the app.
+type WorkflowStepEvent struct {
+    Type         string       `json:"type"`
+    CallbackID   string       `json:"callback_id"`
+    WorkflowStep WorkflowStep `json:"workflow_step"`
+}
+
+// OpenView shows view to the user of the interaction carrying triggerID.
+// Trigger IDs expire three seconds after the interaction.
+func (c *Client) OpenView(ctx context.Context, triggerID string, view View) error {
+    encoded, err := json.Marshal(map[string]interface{}{
+        "type":        view.Type,
+        "callback_id": view.CallbackID,
+        "blocks":      view.Blocks,
+    })
+    if err != nil {
+        return err
+    }
+    params := url.Values{}
+    params.Set("trigger_id", triggerID)
+    params.Set("view", string(encoded))
+    return c.Call(ctx, "views.open", params, nil)
+}
+
+// UpdateWorkflowStep saves the configuration of a step being edited.
+func (c *Client) UpdateWorkflowStep(ctx context.Context, editID string, inputs map[string]StepInput, outputs []StepOutput) error {
+    encodedInputs, err := json.Marshal(inputs)
+    if err != nil {
+        return err
+    }
+    if outputs == nil {
+        outputs = []StepOutput{}
+    }
+    encodedOutputs, err := json.Marshal(outputs)
+    if err != nil {
+        return err
+    }
+    params := url.Values{}
+    params.Set("workflow_step_edit_id", editID)
+    params.Set("inputs", string(encodedInputs))
+    params.Set("outputs", string(encodedOutputs))
+    return c.Call(ctx, "workflows.updateStep", params, nil)
+}
+
+// CompleteWorkflowStep reports a step as done and hands its outputs to the
+// rest of the workflow.
+func (c *Client) CompleteWorkflowStep(ctx context.Context, executeID string, outputs map[string]string) error {
+    encoded, err := json.Marshal(outputs)
+    if err != nil {
+        return err
+    }
+    params := url.Values{}
+    params.Set("workflow_step_execute_id", executeID)
+    params.Set("outputs", string(encoded))
+    return c.Call(ctx, "workflows.stepCompleted", params, nil)
+}
+
+// FailWorkflowStep reports a step as failed, stopping the workflow with
+// message shown to its owner.
+func (c *Client) FailWorkflowStep(ctx context.Context, executeID string, message string) error {
+    encoded, err := json.Marshal(map[string]string{"message": message})
+    if err != nil {
+        return err
+    }
+    params := url.Values{}
+    params.Set("workflow_step_execute_id", executeID)
+    params.Set("error", string(encoded))
+    return c.Call(ctx, "workflows.stepFailed", params, nil)
+}
+
+// ParseMessageRef returns the channel and ts of the message a permalink
+// points to, e.g. https://acme.slack.com/archives/C123/p1712345678123456,
+// or of a "channel_id:ts" reference. For replies the ts of the thread root
+// is returned, taken from the thread_ts parameter of the link.
+func ParseMessageRef(ref string) (string, string, error) {
+    ref = strings.Trim(strings.TrimSpace(ref), "<>")
+    if channelID, ts, ok := strings.Cut(ref, ":"); ok && !strings.Contains(ref, "/") {
+        if channelID == "" || ts == "" {
+            return "", "", fmt.Errorf("invalid message reference %q", ref)
+        }
+        return channelID, ts, nil
+    }
+
+    link, err := url.Parse(ref)
+    if err != nil {
+        return "", "", fmt.Errorf("invalid message link %q", ref)
+    }
+    parts := strings.Split(strings.Trim(link.Path, "/"), "/")
+    if len(parts) != 3 || parts[0] != "archives" || !strings.HasPrefix(parts[2], "p") || len(parts[2]) <= 7 {
+        return "", "", fmt.Errorf("invalid message link %q", ref)
+    }
+    digits := parts[2][1:]
+    ts := digits[:len(digits)-6] + "." + digits[len(digits)-6:]
+    if threadTS := link.Query().Get("thread_ts"); threadTS != "" {
+        ts = threadTS
+    }
+    return parts[1], ts, nil
+}
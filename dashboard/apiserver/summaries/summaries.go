@@ -0,0 +1,168 @@
+// Package summaries renders the list of open threads kept up to date in the
+// channels opting in, as their canvas or as a pinned message, so people
+// living in Slack see the backlog without opening the dashboard.
+package summaries
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "net/url"
+    "strings"
+    "time"
+
+    "dashboard/apiserver/domain"
+    "dashboard/apiserver/slack"
+)
+
+// MaxListed is how many open threads a summary lists, the most urgent
+// first.
+const MaxListed = 20
+
+// dateLayout is how dates are written in canvases, which cannot format
+// them for the reader.
+const dateLayout = "Jan 2 15:04 UTC"
+
+// Thread is an open thread listed by a summary.
+type Thread struct {
+    ThreadTS string `json:"thread_ts"`
+    Title    string `json:"title"`
+    Priority domain.Priority `json:"priority"`
+    Assignee string `json:"assignee,omitempty"`
+    // AssigneeName is the handle of usergroup assignees, which canvases
+    // cannot mention
+    AssigneeName string    `json:"assignee_name,omitempty"`
+    LatestReply  time.Time `json:"latest_reply"`
+    Overdue      bool      `json:"overdue"`
+}
+
+// Summary is the backlog of a channel: its open threads counted by
+// priority and the most urgent ones.
+type Summary struct {
+    ChannelID    string         `json:"channel_id"`
+    ChannelName  string         `json:"channel_name"`
+    OpenThreads  int            `json:"open_threads"`
+    ByPriority   map[string]int `json:"by_priority"`
+    Threads      []Thread       `json:"threads"`
+    DashboardURL string         `json:"dashboard_url,omitempty"`
+}
+
+// Hash identifies the content of a summary, so unchanged summaries are not
+// written to Slack again.
+func (s *Summary) Hash() string {
+    encoded, _ := json.Marshal(s)
+    sum := sha256.Sum256(encoded)
+    return hex.EncodeToString(sum[:])
+}
+
+// headline counts the open threads of a summary.
+func (s *Summary) headline() string {
+    if s.OpenThreads == 0 {
+        return "No open threads"
+    }
+    counts := []string{}
+    for _, priority := range domain.Priorities {
+        if n := s.ByPriority[string(priority)]; n > 0 {
+            counts = append(counts, fmt.Sprintf("%d %s", n, priority))
+        }
+    }
+    text := fmt.Sprintf("%d open threads", s.OpenThreads)
+    if s.OpenThreads == 1 {
+        text = "1 open thread"
+    }
+    if len(counts) > 0 {
+        text += ": " + strings.Join(counts, ", ")
+    }
+    return text
+}
+
+// channelLink returns the address of the channel on the dashboard, empty
+// without one.
+func (s *Summary) channelLink() string {
+    if s.DashboardURL == "" {
+        return ""
+    }
+    return s.DashboardURL + "/channels/" + url.PathEscape(s.ChannelID) + "/threads"
+}
+
+func title(t Thread) string {
+    if t.Title == "" {
+        return "Untitled thread"
+    }
+    return t.Title
+}
+
+// Markdown returns the summary as the markdown of a canvas.
+func Markdown(s *Summary) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "# Open threads in #%s\n\n", s.ChannelName)
+    fmt.Fprintf(&b, "**%s**\n\n", s.headline())
+    for _, t := range s.Threads {
+        state := "last reply " + t.LatestReply.UTC().Format(dateLayout)
+        if t.Overdue {
+            state = "**overdue**, " + state
+        }
+        if t.Assignee != "" {
+            state += ", assigned to " + mention(t)
+        }
+        fmt.Fprintf(&b, "- %s [%s](%s) %s\n", t.Priority.Emoji(), markdownText(title(t)), slack.Permalink(s.ChannelID, t.ThreadTS), state)
+    }
+    if more := s.OpenThreads - len(s.Threads); more > 0 {
+        fmt.Fprintf(&b, "\n…and %d more.\n", more)
+    }
+    if link := s.channelLink(); link != "" {
+        fmt.Fprintf(&b, "\n[Open the dashboard](%s)\n", link)
+    }
+    b.WriteString("\n_Kept up to date by the open threads dashboard, edits are overwritten._\n")
+    return b.String()
+}
+
+// Message returns the fallback text and blocks of the summary as a pinned
+// message.
+func Message(s *Summary) (string, []slack.Block) {
+    text := fmt.Sprintf("Open threads in <#%s>: %s", s.ChannelID, s.headline())
+    blocks := []slack.Block{slack.Section("*" + text + "*"), slack.Divider()}
+    for _, t := range s.Threads {
+        state := fmt.Sprintf("last reply <!date^%d^{date_short_pretty} {time}|%s>", t.LatestReply.Unix(), t.LatestReply.UTC().Format(dateLayout))
+        if t.Overdue {
+            state = "*overdue*, " + state
+        }
+        if t.Assignee != "" {
+            state += ", assigned to " + slack.Mention(t.Assignee)
+        }
+        blocks = append(blocks, slack.Section(fmt.Sprintf("%s <%s|%s>\n%s",
+            t.Priority.Emoji(), slack.Permalink(s.ChannelID, t.ThreadTS), mrkdwnText(title(t)), state)))
+    }
+    footer := "_Kept up to date by the open threads dashboard._"
+    if more := s.OpenThreads - len(s.Threads); more > 0 {
+        footer = fmt.Sprintf("_…and %d more._ ", more) + footer
+    }
+    if link := s.channelLink(); link != "" {
+        footer += fmt.Sprintf(" <%s|Open the dashboard>", link)
+    }
+    blocks = append(blocks, slack.Divider(), slack.Section(footer))
+    return text, blocks
+}
+
+// mention returns the canvas markdown mentioning the assignee of a thread,
+// the name of usergroups.
+func mention(t Thread) string {
+    if !slack.IsUsergroupID(t.Assignee) {
+        return "![](@" + t.Assignee + ")"
+    }
+    if t.AssigneeName != "" {
+        return t.AssigneeName
+    }
+    return "a usergroup"
+}
+
+// markdownText escapes the characters breaking a markdown link text.
+func markdownText(text string) string {
+    return strings.NewReplacer("[", "\\[", "]", "\\]").Replace(text)
+}
+
+// mrkdwnText escapes the characters Slack mrkdwn gives a meaning.
+func mrkdwnText(text string) string {
+    return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
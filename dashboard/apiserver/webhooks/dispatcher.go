@@ -0,0 +1,120 @@
+package webhooks
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "time"
+
+    "dashboard/apiserver/logger"
+)
+
+const (
+    maxAttempts = 3
+    workers     = 2
+)
+
+// retryDelays are waited before the second and third attempt.
+var retryDelays = []time.Duration{5 * time.Second, 30 * time.Second}
+
+type delivery struct {
+    hook  Webhook
+    event Event
+}
+
+// Dispatcher posts events to webhooks in the background, retrying failed
+// deliveries. Events are dropped when the queue is full rather than
+// slowing down the actions producing them.
+type Dispatcher struct {
+    log        logger.Logger
+    httpClient *http.Client
+    queue      chan delivery
+}
+
+// NewDispatcher returns a dispatcher buffering up to size deliveries.
+func NewDispatcher(log logger.Logger, size int) *Dispatcher {
+    return &Dispatcher{
+        log:        log,
+        httpClient: &http.Client{Timeout: 10 * time.Second},
+        queue:      make(chan delivery, size),
+    }
+}
+
+// Enqueue schedules the delivery of event to hook and reports whether it
+// was queued.
+func (d *Dispatcher) Enqueue(hook Webhook, event Event) bool {
+    select {
+    case d.queue <- delivery{hook: hook, event: event}:
+        return true
+    default:
+        d.log.Warnf("dropping %s event for webhook %d, the delivery queue is full", event.Action, hook.ID)
+        return false
+    }
+}
+
+// Run delivers queued events until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+    for i := 0; i < workers; i++ {
+        go func() {
+            for {
+                select {
+                case <-ctx.Done():
+                    return
+                case next := <-d.queue:
+                    d.deliverWithRetries(ctx, next)
+                }
+            }
+        }()
+    }
+    <-ctx.Done()
+}
+
+func (d *Dispatcher) deliverWithRetries(ctx context.Context, next delivery) {
+    for attempt := 1; ; attempt++ {
+        status, err := d.Deliver(ctx, next.hook, next.event)
+        if err == nil {
+            return
+        }
+        // Client errors will not go away by retrying
+        if attempt == maxAttempts || (status >= 400 && status < 500 && status != http.StatusTooManyRequests) {
+            d.log.Warnf("failed to deliver %s event to webhook %d: %v", next.event.Action, next.hook.ID, err)
+            return
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-time.After(retryDelays[attempt-1]):
+        }
+    }
+}
+
+// Deliver renders and posts event to hook once, returning the response
+// status.
+func (d *Dispatcher) Deliver(ctx context.Context, hook Webhook, event Event) (int, error) {
+    payload, err := hook.Render(event)
+    if err != nil {
+        return 0, err
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+    if err != nil {
+        return 0, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", "open-threads-reminder-webhooks")
+    for name, value := range hook.Headers {
+        req.Header.Set(name, value)
+    }
+
+    resp, err := d.httpClient.Do(req)
+    if err != nil {
+        return 0, err
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+    if resp.StatusCode >= 300 {
+        return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+    }
+    return resp.StatusCode, nil
+}
@@ -0,0 +1,158 @@
+// Package webhooks delivers dashboard events to HTTP endpoints registered by
+// admins, with payloads shaped by per-webhook templates.
+package webhooks
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "text/template"
+    "time"
+
+    "dashboard/apiserver/slack"
+)
+
+const (
+    maxTemplateSize = 16 << 10
+    maxHeaders      = 20
+)
+
+// reservedHeaders are set by the transport and may not be overridden.
+var reservedHeaders = map[string]bool{
+    "Content-Length":    true,
+    "Host":              true,
+    "Transfer-Encoding": true,
+    "Connection":        true,
+}
+
+// Event is something that happened on the dashboard, the object payload
+// templates are executed over. Action uses the audit log names, e.g.
+// "thread.claim" or "channel.view".
+type Event struct {
+    Action     string                 `json:"action"`
+    Actor      string                 `json:"actor"`
+    ChannelID  string                 `json:"channel_id"`
+    ThreadTS   string                 `json:"thread_ts,omitempty"`
+    Details    map[string]interface{} `json:"details"`
+    OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// Permalink links to the thread of the event, empty for channel events.
+func (e Event) Permalink() string {
+    if e.ThreadTS == "" {
+        return ""
+    }
+    return slack.Permalink(e.ChannelID, e.ThreadTS)
+}
+
+// SampleEvent is used to validate templates and to test webhooks.
+func SampleEvent() Event {
+    return Event{
+        Action:     "thread.claim",
+        Actor:      "U0000000000",
+        ChannelID:  "C0000000000",
+        ThreadTS:   "1700000000.000100",
+        Details:    map[string]interface{}{"sample": true},
+        OccurredAt: time.Now().UTC(),
+    }
+}
+
+// Webhook is an endpoint events are posted to. Events lists the actions it
+// receives, all of them when empty. Template is a text/template executed
+// over the Event, the event as JSON is sent without one.
+type Webhook struct {
+    ID        int64             `json:"id"`
+    Name      string            `json:"name"`
+    URL       string            `json:"url"`
+    Events    []string          `json:"events"`
+    Template  string            `json:"template"`
+    Headers   map[string]string `json:"headers"`
+    Enabled   bool              `json:"enabled"`
+    CreatedBy string            `json:"created_by"`
+    CreatedAt time.Time         `json:"created_at"`
+}
+
+// Wants reports whether the webhook subscribes to action.
+func (w Webhook) Wants(action string) bool {
+    if len(w.Events) == 0 {
+        return true
+    }
+    for _, event := range w.Events {
+        if event == action || (strings.HasSuffix(event, ".*") && strings.HasPrefix(action, strings.TrimSuffix(event, "*"))) {
+            return true
+        }
+    }
+    return false
+}
+
+// Validate checks the URL, headers and template of a webhook, rendering the
+// template against a sample event.
+func (w Webhook) Validate() error {
+    if strings.TrimSpace(w.Name) == "" {
+        return fmt.Errorf("name is required")
+    }
+    parsed, err := url.Parse(w.URL)
+    if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+        return fmt.Errorf("url must be an absolute http or https URL")
+    }
+    if len(w.Headers) > maxHeaders {
+        return fmt.Errorf("at most %d headers are allowed", maxHeaders)
+    }
+    for name, value := range w.Headers {
+        if !validHeaderName(name) || strings.ContainsAny(value, "\r\n") {
+            return fmt.Errorf("invalid header %q", name)
+        }
+        if reservedHeaders[http.CanonicalHeaderKey(name)] {
+            return fmt.Errorf("header %s cannot be set", name)
+        }
+    }
+    if len(w.Template) > maxTemplateSize {
+        return fmt.Errorf("template must be at most %d bytes", maxTemplateSize)
+    }
+    if _, err := w.Render(SampleEvent()); err != nil {
+        return err
+    }
+    return nil
+}
+
+func validHeaderName(name string) bool {
+    if name == "" {
+        return false
+    }
+    for _, r := range name {
+        if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+            return false
+        }
+    }
+    return true
+}
+
+// templateFuncs are available to payload templates besides the builtins.
+var templateFuncs = template.FuncMap{
+    // json encodes a value, e.g. {{json .ChannelID}} for a quoted string
+    "json": func(v interface{}) (string, error) {
+        encoded, err := json.Marshal(v)
+        return string(encoded), err
+    },
+    "upper": strings.ToUpper,
+    "lower": strings.ToLower,
+}
+
+// Render returns the payload of event for the webhook.
+func (w Webhook) Render(event Event) ([]byte, error) {
+    if w.Template == "" {
+        return json.Marshal(event)
+    }
+    tmpl, err := template.New("payload").Funcs(templateFuncs).Option("missingkey=zero").Parse(w.Template)
+    if err != nil {
+        return nil, fmt.Errorf("invalid template: %v", err)
+    }
+    var buf bytes.Buffer
+    if err := tmpl.Execute(&buf, event); err != nil {
+        return nil, fmt.Errorf("template failed: %v", err)
+    }
+    return buf.Bytes(), nil
+}
@@ -3,6 +3,7 @@ package main
 import (
     "dashboard/apiserver"
 
+    "flag"
     "os"
 )
 
@@ -13,6 +14,16 @@ var (
 
 var help bool
 
+var check bool
+
+var migrate bool
+
+var demo bool
+
+var quickstart bool
+
+var chaos bool
+
 func getEnv(key, fallback string) string {
     if value, ok := os.LookupEnv(key); ok {
         return value
@@ -22,8 +33,21 @@ func getEnv(key, fallback string) string {
 
 // TODO: change main function
 func main() {
+    flag.BoolVar(&check, "check", false, "validate the configuration, database, Slack token and UI build, then exit")
+    flag.BoolVar(&migrate, "migrate", false, "apply pending database schema migrations, then exit")
+    flag.BoolVar(&demo, "demo", false, "serve generated data from memory instead of the database and Slack")
+    flag.BoolVar(&quickstart, "quickstart", false, "track every channel the bot is in with default settings, needing only the Slack bot token")
+    flag.BoolVar(&chaos, "chaos", false, "let admins inject database latency, Slack call failures and dropped events")
+    flag.Parse()
+    if check {
+        os.Exit(apiserver.Check())
+    }
+    if migrate {
+        os.Exit(apiserver.Migrate())
+    }
+
     Addr = getEnv("YB_OPEN_THREADS_REMINDER_ADDR", "127.0.0.1")
     Port = getEnv("YB_OPEN_THREADS_REMINDER_PORT", "18080")
 
-    apiserver.Start(Addr, Port)
+    apiserver.Start(Addr, Port, demo, quickstart, chaos)
 }
\ No newline at end of file
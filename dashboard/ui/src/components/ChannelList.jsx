@@ -4,10 +4,14 @@ import { Card, CardContent, CardDescription, CardHeader, CardTitle } from './ui/
 import { Badge } from './ui/badge'
 import { Button } from './ui/button'
 import Stakeholders from './Stakeholders'
+import StaleNotice from './StaleNotice'
+import { priorityStyle, useUIConfig } from '../lib/uiConfig'
+import { staleSince } from '../lib/utils'
 
 
 const ChannelList = () => {
   const navigate = useNavigate()
+  const uiConfig = useUIConfig()
   const [stats, setStats] = useState({
     totalThreads: 0,
     activeThreads: 0,
@@ -18,6 +22,7 @@ const ChannelList = () => {
   const [recentThreads, setRecentThreads] = useState([])
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState(null)
+  const [stale, setStale] = useState(null)
 
   useEffect(() => {
     fetchChannelData()
@@ -28,28 +33,43 @@ const ChannelList = () => {
       setLoading(true)
       setError(null)
       
+      // Responses cached while the database is unreachable are shown
+      // since the oldest of them
+      let oldest = null
+      const checkStale = (response) => {
+        const since = staleSince(response)
+        if (since && (!oldest || since < oldest)) oldest = since
+      }
+
       // Fetch stats
       const statsResponse = await fetch('/api/stats')
       if (!statsResponse.ok) {
         throw new Error('Failed to fetch stats')
       }
+      /** @type {import('../lib/apiModels').DashboardStats} */
       const statsData = await statsResponse.json()
       setStats(statsData)
+      checkStale(statsResponse)
 
       // Fetch channels
       const channelsResponse = await fetch('/api/channels')
       if (!channelsResponse.ok) {
         throw new Error('Failed to fetch channels')
       }
+      /** @type {Array<import('../lib/apiModels').ChannelSummary>} */
       const channelsData = await channelsResponse.json()
       setChannels(channelsData || [])
+      checkStale(channelsResponse)
 
       // Fetch recent threads with stakeholders
       const threadsResponse = await fetch('/api/threads?limit=5')
       if (threadsResponse.ok) {
-        const threadsData = await threadsResponse.json()
-        setRecentThreads(threadsData || [])
+        /** @type {import('../lib/apiModels').ThreadPage} */
+        const threadsPage = await threadsResponse.json()
+        setRecentThreads(threadsPage.items || [])
+        checkStale(threadsResponse)
       }
+      setStale(oldest)
     } catch (error) {
       console.error('Error fetching channel data:', error)
       setError(error.message)
@@ -106,6 +126,14 @@ const ChannelList = () => {
   }
 
   const getPriorityBadge = (priority) => {
+    const style = priorityStyle(uiConfig, priority)
+    if (style) {
+      return (
+        <Badge style={{ backgroundColor: style.background, color: style.foreground, borderColor: style.border }}>
+          {style.symbol} {style.label}
+        </Badge>
+      )
+    }
     switch (priority) {
       case 'high':
         return <Badge className="bg-red-100 text-red-800 border border-red-300">🔴 HIGH</Badge>
@@ -206,6 +234,8 @@ const ChannelList = () => {
           </p>
         </div>
 
+        <StaleNotice since={stale} />
+
         {/* Stats Cards */}
         <div className="grid gap-6 md:grid-cols-2 lg:grid-cols-4">
           <Card className="yb-stats-card border-l-4 border-l-orange-500">
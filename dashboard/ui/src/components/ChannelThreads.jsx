@@ -4,16 +4,21 @@ import { Card, CardContent, CardDescription, CardHeader, CardTitle } from './ui/
 import { Badge } from './ui/badge'
 import { Button } from './ui/button'
 import Stakeholders from './Stakeholders'
+import StaleNotice from './StaleNotice'
+import { priorityStyle, useUIConfig } from '../lib/uiConfig'
+import { staleSince } from '../lib/utils'
 
 
 const ChannelThreads = () => {
   const navigate = useNavigate()
   const { channelId } = useParams()
   const location = useLocation()
+  const uiConfig = useUIConfig()
   const [channel, setChannel] = useState(location.state?.channel || null)
   const [threads, setThreads] = useState([])
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState(null)
+  const [stale, setStale] = useState(null)
   const [filter, setFilter] = useState('all') // all, active, high, medium, low
 
   useEffect(() => {
@@ -36,10 +41,11 @@ const ChannelThreads = () => {
       if (!response.ok) {
         throw new Error('Failed to fetch threads')
       }
-      const threadsData = await response.json()
+      const threadsPage = await response.json()
+      setStale(staleSince(response))
       
       // Filter active threads if needed
-      let filteredThreads = threadsData || []
+      let filteredThreads = threadsPage.items || []
       if (filter === 'active') {
         filteredThreads = filteredThreads.filter(thread => thread.status === 'open')
       }
@@ -80,6 +86,14 @@ const ChannelThreads = () => {
   }
 
   const getPriorityBadge = (priority) => {
+    const style = priorityStyle(uiConfig, priority)
+    if (style) {
+      return (
+        <Badge style={{ backgroundColor: style.background, color: style.foreground, borderColor: style.border }}>
+          {style.symbol} {style.label}
+        </Badge>
+      )
+    }
     switch (priority) {
       case 'high':
         return <Badge variant="destructive">🔴 HIGH</Badge>
@@ -252,6 +266,8 @@ const ChannelThreads = () => {
           </nav>
         </div>
 
+        <StaleNotice since={stale} />
+
         {/* Header */}
         <div className="bg-white rounded-lg p-6 border border-slate-200 shadow-lg">
           <div className="flex items-center justify-between">
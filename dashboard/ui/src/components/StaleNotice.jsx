@@ -0,0 +1,14 @@
+import React from 'react'
+
+// StaleNotice tells what is shown was cached while the database is
+// unreachable, since the given time.
+const StaleNotice = ({ since }) => {
+  if (!since) return null
+  return (
+    <div className="bg-yellow-50 border border-yellow-300 text-yellow-800 rounded-lg px-4 py-3 text-sm">
+      ⚠️ The database is unreachable, showing what was known at {since.toLocaleString()}.
+    </div>
+  )
+}
+
+export default StaleNotice
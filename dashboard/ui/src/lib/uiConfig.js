@@ -0,0 +1,38 @@
+import { useEffect, useState } from 'react'
+
+let configPromise = null
+
+// loadUIConfig fetches the theme, priority palettes and feature flags once
+export function loadUIConfig() {
+  if (!configPromise) {
+    configPromise = fetch('/api/ui-config')
+      .then(response => (response.ok ? response.json() : null))
+      .catch(() => null)
+  }
+  return configPromise
+}
+
+export function useUIConfig() {
+  const [config, setConfig] = useState(null)
+
+  useEffect(() => {
+    let active = true
+    loadUIConfig().then(loaded => {
+      if (active) setConfig(loaded)
+    })
+    return () => {
+      active = false
+    }
+  }, [])
+
+  return config
+}
+
+// priorityStyle returns the style of a priority in the palette the user
+// picked, or else the one of the server, null until the config is loaded
+export function priorityStyle(config, priority) {
+  if (!config) return null
+  const palette = config.palettes[localStorage.getItem('palette')] || config.palettes[config.palette]
+  if (!palette) return null
+  return palette.priorities[priority] || palette.priorities.none
+}
@@ -3,4 +3,13 @@ import { twMerge } from "tailwind-merge"
 
 export function cn(...inputs) {
   return twMerge(clsx(inputs))
-} 
\ No newline at end of file
+} 
+
+// staleSince returns when a response served while the database was
+// unreachable was cached, or null for a fresh response. Lists are only
+// flagged by the X-Stale headers, objects also by their stale fields.
+export function staleSince(response) {
+  if (response.headers.get('X-Stale') !== 'true') return null
+  const since = response.headers.get('X-Stale-Since')
+  return since ? new Date(since) : new Date()
+}
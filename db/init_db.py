@@ -1,17 +1,21 @@
 import psycopg2
 import psycopg2.extras
+import psycopg2.errors
 from psycopg2 import sql
 from datetime import datetime, timedelta
 from typing import Dict, List, Optional
 import json
 
+# Threads of all channels are kept in one table shared with the dashboard
+THREADS_TABLE = "threads"
+
 class DBClient:
     """
     PostgreSQL database client for managing Slack thread data.
     
     This class provides a high-level interface for storing and retrieving
-    Slack thread information across different channels. Each channel gets
-    its own table for better data organization and performance.
+    Slack thread information across different channels. The threads of all
+    channels are kept in the threads table, keyed by channel and timestamp.
     
     Features:
     - Automatic database and table creation
@@ -66,21 +70,14 @@ class DBClient:
             # Create master tables first
             self._create_master_tables()
 
-            # Create/update channel tables
+            # Create the threads table shared by all channels
+            self._create_threads_table()
+
             for channel in channels:
-                table_name = channel["channel_name"].replace("-", "_")
-                
-                # Validate table name
-                if not table_name.replace('_', '').isalnum():
-                    raise ValueError(f"Table name contains invalid characters: {table_name}")
-                
-                self._create_or_update_channel_table(table_name)
-                
                 # Insert/update channel in master table
                 self.upsert_channel_info(
                     channel_id=channel["channel_id"],
-                    channel_name=channel["channel_name"], 
-                    table_name=table_name
+                    channel_name=channel["channel_name"]
                 )
 
         except psycopg2.Error as e:
@@ -126,8 +123,8 @@ class DBClient:
         self.cursor.execute(create_profiles_query)
         print("User profiles cache table created/verified")
 
-    def _create_or_update_channel_table(self, table_name: str):
-        """Create channel table with all enhanced columns from the beginning."""
+    def _create_threads_table(self):
+        """Create the threads table with all enhanced columns from the beginning."""
         
         # Create complete table with all enhanced columns
         create_table_query = sql.SQL("""
@@ -150,19 +147,18 @@ class DBClient:
                 ai_analysis_json TEXT,  -- Full AI response
                 last_bot_message_ts TIMESTAMP,  -- When bot last sent message
                 updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-                PRIMARY KEY(thread_ts, channel_id)
+                PRIMARY KEY(channel_id, thread_ts)
             )
-        """).format(sql.Identifier(table_name))
+        """).format(sql.Identifier(THREADS_TABLE))
         
         self.cursor.execute(create_table_query)
-        print(f"Enhanced channel table created: {table_name}")
+        print(f"Enhanced threads table created: {THREADS_TABLE}")
 
-    def store_thread_in_table(self, table: str, thread_data: Dict):
+    def store_thread(self, thread_data: Dict):
         """
-        Insert or update a thread's data in the specified table with AI analysis.
+        Insert or update a thread's data in the threads table with AI analysis.
 
         Args:
-            table: Target table name
             thread_data: Dictionary with thread information including AI analysis
         """
         ts_latest_reply = thread_data['latest_reply']
@@ -190,7 +186,7 @@ class DBClient:
                 %(ai_stakeholders)s, %(ai_priority)s, %(ai_confidence)s, %(github_issue)s, 
                 %(jira_ticket)s, %(thread_issue)s, %(ai_analysis_json)s, %(last_bot_message_ts)s, %(updated_at)s
             )
-            ON CONFLICT (thread_ts, channel_id)
+            ON CONFLICT (channel_id, thread_ts)
             DO UPDATE SET
                 reply_count = EXCLUDED.reply_count,
                 latest_reply = EXCLUDED.latest_reply,
@@ -205,10 +201,7 @@ class DBClient:
                 ai_analysis_json = COALESCE(EXCLUDED.ai_analysis_json, {}.ai_analysis_json),
                 last_bot_message_ts = COALESCE(EXCLUDED.last_bot_message_ts, {}.last_bot_message_ts),
                 updated_at = EXCLUDED.updated_at
-        """).format(sql.Identifier(table), sql.Identifier(table), sql.Identifier(table), 
-                    sql.Identifier(table), sql.Identifier(table), sql.Identifier(table), 
-                    sql.Identifier(table), sql.Identifier(table), sql.Identifier(table), 
-                    sql.Identifier(table), sql.Identifier(table))
+        """).format(*[sql.Identifier(THREADS_TABLE)] * 11)
 
         # Prepare the dict for SQL
         thread_data_sql = {
@@ -227,7 +220,7 @@ class DBClient:
             print(f"Error inserting data: {e}")
             raise
 
-    def upsert_channel_info(self, channel_id: str, channel_name: str, table_name: str):
+    def upsert_channel_info(self, channel_id: str, channel_name: str):
         """Insert or update channel information in the master channels table."""
         query = """
             INSERT INTO channels (channel_id, channel_name, table_name, last_activity)
@@ -243,7 +236,7 @@ class DBClient:
             self.cursor.execute(query, {
                 'channel_id': channel_id,
                 'channel_name': channel_name, 
-                'table_name': table_name,
+                'table_name': THREADS_TABLE,
                 'last_activity': datetime.now()
             })
         except psycopg2.Error as e:
@@ -316,46 +309,39 @@ class DBClient:
 
     def update_channel_stats(self, channel_id: str):
         """Update thread counts for a channel."""
-        # Get table name for this channel
-        self.cursor.execute("SELECT table_name FROM channels WHERE channel_id = %s", (channel_id,))
-        result = self.cursor.fetchone()
-        if not result:
-            return
-            
-        table_name = result['table_name']
-        
         # Count total and active threads
         query = sql.SQL("""
             UPDATE channels SET 
-                thread_count = (SELECT COUNT(*) FROM {}),
-                active_thread_count = (SELECT COUNT(*) FROM {} WHERE status = 'open'),
-                last_activity = (SELECT MAX(latest_reply) FROM {})
-            WHERE channel_id = %s
-        """).format(sql.Identifier(table_name), sql.Identifier(table_name), sql.Identifier(table_name))
+                thread_count = (SELECT COUNT(*) FROM {0} WHERE channel_id = %(channel_id)s),
+                active_thread_count = (SELECT COUNT(*) FROM {0} WHERE channel_id = %(channel_id)s AND status = 'open'),
+                last_activity = (SELECT MAX(latest_reply) FROM {0} WHERE channel_id = %(channel_id)s)
+            WHERE channel_id = %(channel_id)s
+        """).format(sql.Identifier(THREADS_TABLE))
         
         try:
-            self.cursor.execute(query, (channel_id,))
+            self.cursor.execute(query, {'channel_id': channel_id})
         except psycopg2.Error as e:
             print(f"Error updating channel stats: {e}")
             raise
 
-    def get_open_threads_within_range(self, table: str, days: int) -> List[Dict]:
+    def get_open_threads_within_range(self, channel_id: str, days: int) -> List[Dict]:
         # Use psycopg2.sql for safe table name formatting
         query = sql.SQL("""
             SELECT * FROM {}
-            WHERE status = 'open'
+            WHERE channel_id = %s
+              AND status = 'open'
               AND created_at >= NOW() - INTERVAL %s
-        """).format(sql.Identifier(table))
+        """).format(sql.Identifier(THREADS_TABLE))
         
-        self.cursor.execute(query, (f'{days} days',))
+        self.cursor.execute(query, (channel_id, f'{days} days'))
         return self.cursor.fetchall()
 
-    def get_thread_by_id(self, table: str, thread_ts: str, channel_id: str) -> Optional[Dict]:
+    def get_thread_by_id(self, thread_ts: str, channel_id: str) -> Optional[Dict]:
         """Get a specific thread by its timestamp and channel ID."""
         query = sql.SQL("""
             SELECT * FROM {}
             WHERE thread_ts = %s AND channel_id = %s
-        """).format(sql.Identifier(table))
+        """).format(sql.Identifier(THREADS_TABLE))
         
         try:
             self.cursor.execute(query, (thread_ts, channel_id))
@@ -364,28 +350,28 @@ class DBClient:
             print(f"Error fetching thread: {e}")
             raise
 
-    def get_threads_by_status(self, table: str, status: str) -> List[Dict]:
-        """Get all threads with a specific status."""
+    def get_threads_by_status(self, channel_id: str, status: str) -> List[Dict]:
+        """Get all threads of a channel with a specific status."""
         query = sql.SQL("""
             SELECT * FROM {}
-            WHERE status = %s
+            WHERE channel_id = %s AND status = %s
             ORDER BY created_at DESC
-        """).format(sql.Identifier(table))
+        """).format(sql.Identifier(THREADS_TABLE))
         
         try:
-            self.cursor.execute(query, (status,))
+            self.cursor.execute(query, (channel_id, status))
             return self.cursor.fetchall()
         except psycopg2.Error as e:
             print(f"Error fetching threads by status: {e}")
             raise
 
-    def update_thread_reply_count(self, table: str, thread_id: str, channel_id: str, reply_count: int, last_reply) -> bool:
+    def update_thread_reply_count(self, thread_id: str, channel_id: str, reply_count: int, last_reply) -> bool:
         """Update reply count and latest reply timestamp for a thread."""
         query = sql.SQL("""
             UPDATE {} 
             SET reply_count = %s, latest_reply = %s 
             WHERE thread_ts = %s AND channel_id = %s
-        """).format(sql.Identifier(table))
+        """).format(sql.Identifier(THREADS_TABLE))
         
         try:
             self.cursor.execute(query, (
@@ -396,13 +382,13 @@ class DBClient:
             print(f"Error updating thread reply count: {e}")
             raise
 
-    def update_thread_as_closed(self, table: str, thread_id: str, channel_id: str) -> None:
+    def update_thread_as_closed(self, thread_id: str, channel_id: str) -> None:
         """Mark a thread as closed."""
         query = sql.SQL("""
             UPDATE {}
             SET status = 'closed'
             WHERE thread_ts = %s AND channel_id = %s
-        """).format(sql.Identifier(table))
+        """).format(sql.Identifier(THREADS_TABLE))
         
         try:
             self.cursor.execute(query, (thread_id, channel_id))
@@ -410,13 +396,42 @@ class DBClient:
             print(f"Error closing thread: {e}")
             raise
 
-    def delete_thread(self, table: str, thread_ts: str, channel_id: str) -> bool:
-        """Delete a specific thread."""
+    def delete_thread(self, thread_ts: str, channel_id: str) -> bool:
+        """Delete a specific thread.
+
+        Threads under an active legal hold of the dashboard, on the thread
+        or its channel, are kept: the blocked deletion is audited and False
+        is returned.
+        """
+        query = sql.SQL("""
+            DELETE FROM {} t
+            WHERE t.thread_ts = %s AND t.channel_id = %s
+            AND NOT EXISTS (
+                SELECT 1 FROM legal_holds h
+                WHERE h.released_at IS NULL AND h.channel_id = t.channel_id
+                AND (h.thread_ts IS NULL OR h.thread_ts = t.thread_ts)
+            )
+        """).format(sql.Identifier(THREADS_TABLE))
+
+        try:
+            self.cursor.execute(query, (thread_ts, channel_id))
+            if self.cursor.rowcount > 0:
+                return True
+            if self.legal_hold_active(thread_ts, channel_id):
+                self._audit_blocked("thread.delete", thread_ts, channel_id)
+            return False
+        except psycopg2.errors.UndefinedTable:
+            # legal_holds is created by the dashboard, which may not run
+            pass
+        except psycopg2.Error as e:
+            print(f"Error deleting thread: {e}")
+            raise
+
         query = sql.SQL("""
             DELETE FROM {}
             WHERE thread_ts = %s AND channel_id = %s
-        """).format(sql.Identifier(table))
-        
+        """).format(sql.Identifier(THREADS_TABLE))
+
         try:
             self.cursor.execute(query, (thread_ts, channel_id))
             return self.cursor.rowcount > 0
@@ -424,6 +439,25 @@ class DBClient:
             print(f"Error deleting thread: {e}")
             raise
 
+    def legal_hold_active(self, thread_ts: str, channel_id: str) -> bool:
+        """Check whether a thread or its channel is under an active legal hold."""
+        self.cursor.execute("""
+            SELECT EXISTS (
+                SELECT 1 FROM legal_holds
+                WHERE released_at IS NULL AND channel_id = %s
+                AND (thread_ts IS NULL OR thread_ts = %s)
+            ) AS held
+        """, (channel_id, thread_ts))
+        return self.cursor.fetchone()['held']
+
+    def _audit_blocked(self, action: str, thread_ts: str, channel_id: str) -> None:
+        """Record in the audit log of the dashboard an action refused by a legal hold."""
+        print(f"Blocked {action} on {channel_id}/{thread_ts}: legal hold")
+        self.cursor.execute("""
+            INSERT INTO audit_log (actor, action, channel_id, thread_ts, details)
+            VALUES ('collector', 'legal_hold.blocked', %s, %s, %s)
+        """, (channel_id, thread_ts, json.dumps({'attempted_action': action})))
+
     def table_exists(self, table_name: str) -> bool:
         """Check if a table exists in the current database."""
         try:
@@ -461,12 +495,43 @@ class DBClient:
         """Automatically close connection when exiting context."""
         self.close()
 
-    def can_bot_send_message(self, table: str, thread_ts: str, channel_id: str, cooldown_minutes: int) -> bool:
+    def ai_analysis_allowed(self, channel_id: str) -> bool:
+        """Check whether threads of a channel may be sent for AI analysis.
+
+        Channels that opted out of text indexing in the dashboard are never
+        analyzed, their text must not leave Slack. Content of Slack Connect
+        channels, shared with other workspaces, is only analyzed once the
+        dashboard enables it for the channel.
+        """
+        query = """
+            SELECT text_indexing, slack_connect, slack_connect_ai
+            FROM channel_config
+            WHERE channel_id = %s
+        """
+
+        try:
+            self.cursor.execute(query, (channel_id,))
+            config = self.cursor.fetchone()
+        except psycopg2.errors.UndefinedTable:
+            # channel_config is created by the dashboard, which may not run
+            return True
+        except psycopg2.Error as e:
+            # Without the policy the text is kept from the AI
+            print(f"Could not check the text indexing policy: {e}")
+            return False
+
+        if not config:
+            return True
+        if config['text_indexing'] is False:
+            return False
+        return not (config['slack_connect'] and not config['slack_connect_ai'])
+
+    def can_bot_send_message(self, thread_ts: str, channel_id: str, cooldown_minutes: int) -> bool:
         """Check if bot can send a message based on cooldown period."""
         query = sql.SQL("""
             SELECT last_bot_message_ts FROM {}
             WHERE thread_ts = %s AND channel_id = %s
-        """).format(sql.Identifier(table))
+        """).format(sql.Identifier(THREADS_TABLE))
         
         try:
             self.cursor.execute(query, (thread_ts, channel_id))
@@ -487,13 +552,13 @@ class DBClient:
             # Default to allowing message on error
             return True
 
-    def update_bot_message_timestamp(self, table: str, thread_ts: str, channel_id: str) -> bool:
+    def update_bot_message_timestamp(self, thread_ts: str, channel_id: str) -> bool:
         """Update the timestamp when bot sends a message to a thread."""
         query = sql.SQL("""
             UPDATE {} 
             SET last_bot_message_ts = %s, updated_at = %s
             WHERE thread_ts = %s AND channel_id = %s
-        """).format(sql.Identifier(table))
+        """).format(sql.Identifier(THREADS_TABLE))
         
         try:
             current_time = datetime.now()
@@ -21,7 +21,7 @@ def initialize_enhanced_database():
         print("\nCreated/Updated:")
         print("  📊 Master channels table")
         print("  👥 User profiles cache table")
-        print("  🧵 Enhanced channel tables with AI analysis columns")
+        print("  🧵 Enhanced threads table with AI analysis columns")
         
         # Test the setup by checking channels
         print("\n🔍 Verifying setup...")
@@ -1,6 +1,6 @@
 from slack_services.init_slack import SlackService
 from datetime import datetime, timedelta, timezone
-from db.init_db import DBClient
+from db.init_db import DBClient, THREADS_TABLE
 from config import (DB_CONFIG, DB_NAME, channels, RESPONSE_LIMIT, THREAD_CYCLE, 
                     TESTING_MODE, ACTIVE_RESPONSE_LIMIT, ACTIVE_THREAD_CYCLE, ACTIVE_TIME_UNIT,
                     ACTIVE_BOT_COOLDOWN)
@@ -57,21 +57,17 @@ def ensure_database_setup():
         except:
             master_exists = False
         
-        # Check if enhanced columns exist in channel tables
+        # Check if enhanced columns exist in the threads table
         enhanced_exists = True
-        for channel in channels:
-            table_name = channel["channel_name"].replace("-", "_")
-            try:
-                db.cursor.execute(f"""
-                    SELECT column_name FROM information_schema.columns 
-                    WHERE table_name = '{table_name}' AND column_name = 'ai_thread_name'
-                """)
-                if not db.cursor.fetchone():
-                    enhanced_exists = False
-                    break
-            except:
+        try:
+            db.cursor.execute("""
+                SELECT column_name FROM information_schema.columns 
+                WHERE table_name = %s AND column_name = 'ai_thread_name'
+            """, (THREADS_TABLE,))
+            if not db.cursor.fetchone():
                 enhanced_exists = False
-                break
+        except:
+            enhanced_exists = False
         
         db.close()
         
@@ -125,13 +121,12 @@ def verify_enhanced_setup():
             print(f"  📢 {channel['channel_name']} -> {channel['table_name']}")
         
         # Check enhanced features
-        sample_table = channels[0]["channel_name"].replace("-", "_")
-        db.cursor.execute(f"""
+        db.cursor.execute("""
             SELECT column_name FROM information_schema.columns 
-            WHERE table_name = '{sample_table}' 
+            WHERE table_name = %s 
             AND column_name IN ('ai_thread_name', 'github_issue', 'jira_ticket')
             ORDER BY column_name
-        """)
+        """, (THREADS_TABLE,))
         enhanced_columns = [row['column_name'] for row in db.cursor.fetchall()]
         
         print(f"\n🎯 Enhanced features available:")
@@ -354,14 +349,18 @@ def main():
     # Get last THREAD_CYCLE (90) days threads, which are open from database.
     for channel in channels:
         channel_id = channel['channel_id']
-        table_name = channel['channel_name'].replace("-", "_")
-        
         print(f"\n=== Processing Channel: {channel['channel_name']} ===")
         
         threads = db.get_open_threads_within_range(
-            table=table_name, days=ACTIVE_THREAD_CYCLE
+            channel_id=channel_id, days=ACTIVE_THREAD_CYCLE
         )
         print(f"Found {len(threads)} open threads in channel {channel['channel_name']}.")
+
+        # Channels opted out of text indexing, and Slack Connect channels
+        # holding content of other workspaces, are not analyzed
+        ai_allowed = db.ai_analysis_allowed(channel_id)
+        if not ai_allowed:
+            print(f"🔒 Text indexing disabled or shared channel without AI analysis enabled - skipping AI analysis in {channel['channel_name']}")
         
         for stored_thread_info in threads:
             print(f"\nProcessing thread: {stored_thread_info['thread_ts']}")
@@ -403,13 +402,14 @@ def main():
                     latest_reply_dt = latest_reply_dt.replace(tzinfo=timezone.utc)
                 
                 db.update_thread_reply_count(
-                    table=table_name,
                     thread_id=stored_thread_info['thread_ts'],
                     channel_id=stored_thread_info["channel_id"],
                     reply_count=current_thread_info["reply_count"],
                     last_reply=latest_reply_dt
                 )
             elif last_reply < (datetime.now(timezone.utc) - get_timedelta_for_config(ACTIVE_RESPONSE_LIMIT, ACTIVE_TIME_UNIT)):
+                if not ai_allowed:
+                    continue
                 print(f"Thread {stored_thread_info['thread_ts']} is inactive (>{ACTIVE_RESPONSE_LIMIT} {ACTIVE_TIME_UNIT}), processing AI analysis...")
                 
                 # Fetch the actual thread conversation
@@ -446,7 +446,7 @@ def main():
                     'status': stored_thread_info['status'],
                     **ai_data
                 }
-                db.store_thread_in_table(table_name, enhanced_thread_data)
+                db.store_thread(enhanced_thread_data)
                 
                 # Show all found references for debugging
                 issue_refs = ai_data.get('ai_response', {}).get('issue_references', {})
@@ -465,7 +465,6 @@ def main():
                     # Check bot message cooldown before sending
                     cooldown_minutes = ACTIVE_BOT_COOLDOWN if ACTIVE_TIME_UNIT == "minutes" else ACTIVE_BOT_COOLDOWN * 60
                     can_send = db.can_bot_send_message(
-                        table=table_name,
                         thread_ts=stored_thread_info['thread_ts'],
                         channel_id=stored_thread_info['channel_id'],
                         cooldown_minutes=cooldown_minutes
@@ -499,7 +498,7 @@ def main():
                     previous_bot_message_info = None
                     try:
                         # Get the timestamp of the last bot message
-                        query = sql.SQL("SELECT last_bot_message_ts FROM {} WHERE thread_ts = %s AND channel_id = %s").format(sql.Identifier(table_name))
+                        query = sql.SQL("SELECT last_bot_message_ts FROM {} WHERE thread_ts = %s AND channel_id = %s").format(sql.Identifier(THREADS_TABLE))
                         db.cursor.execute(query, (stored_thread_info['thread_ts'], stored_thread_info['channel_id']))
                         result = db.cursor.fetchone()
                         if result and result['last_bot_message_ts']:
@@ -544,7 +543,7 @@ def main():
                         try:
                             import json
                             stakeholders_json = json.dumps(fresh_human_stakeholders)
-                            update_query = sql.SQL("UPDATE {} SET ai_stakeholders = %s WHERE thread_ts = %s AND channel_id = %s").format(sql.Identifier(table_name))
+                            update_query = sql.SQL("UPDATE {} SET ai_stakeholders = %s WHERE thread_ts = %s AND channel_id = %s").format(sql.Identifier(THREADS_TABLE))
                             db.cursor.execute(update_query, (stakeholders_json, stored_thread_info['thread_ts'], stored_thread_info['channel_id']))
                             db.connection.commit()
                             print(f"💾 Updated database with fresh stakeholders: {fresh_human_stakeholders}")
@@ -680,15 +679,13 @@ def main():
                     # Update bot message timestamp if message was sent successfully
                     if message_ts:
                         db.update_bot_message_timestamp(
-                            table=table_name,
-                            thread_ts=stored_thread_info['thread_ts'],
+                                thread_ts=stored_thread_info['thread_ts'],
                             channel_id=stored_thread_info['channel_id']
                         )
                         print(f"✅ Bot message timestamp updated for cooldown tracking")
                     
                     # Update thread reply count
                     db.update_thread_reply_count(
-                        table=table_name,
                         thread_id=stored_thread_info['thread_ts'],
                         channel_id=stored_thread_info['channel_id'],
                         reply_count=current_thread_info['reply_count'] + 1,
@@ -697,7 +694,6 @@ def main():
                 else:
                     # Update on database that thread is closed.
                     db.update_thread_as_closed(
-                        table=table_name,
                         thread_id=stored_thread_info['thread_ts'],
                         channel_id=stored_thread_info['channel_id']
                     )
@@ -735,10 +731,7 @@ def main():
                 'last_bot_message_ts': None
             }
             
-            db.store_thread_in_table(
-                table=table_name,
-                thread_data=thread_data
-            )
+            db.store_thread(thread_data=thread_data)
         
         # Update channel statistics
         print(f"Updating channel statistics for {channel['channel_name']}...")
@@ -755,15 +748,14 @@ def main():
     
     # Count actual data for each channel
     for channel in channels:
-        table_name = channel['channel_name'].replace("-", "_")
         try:
             # Count total threads
-            db.cursor.execute(f"SELECT COUNT(*) as count FROM {table_name}")
+            db.cursor.execute("SELECT COUNT(*) as count FROM threads WHERE channel_id = %s", (channel['channel_id'],))
             thread_count = db.cursor.fetchone()['count']
             total_threads_processed += thread_count
             
             # Count AI analyzed threads
-            db.cursor.execute(f"SELECT COUNT(*) as count FROM {table_name} WHERE ai_thread_name IS NOT NULL")
+            db.cursor.execute("SELECT COUNT(*) as count FROM threads WHERE channel_id = %s AND ai_thread_name IS NOT NULL", (channel['channel_id'],))
             ai_count = db.cursor.fetchone()['count']
             total_ai_analyzed += ai_count
             
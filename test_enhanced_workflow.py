@@ -28,20 +28,18 @@ def test_enhanced_workflow():
         print("\n2️⃣ Testing Thread Data with AI Analysis...")
         
         for channel in channels:
-            table_name = channel["channel_name"].replace("-", "_")
-            
             # Get a sample thread with AI analysis
             query = f"""
                 SELECT thread_ts, ai_thread_name, ai_description, ai_stakeholders, 
                        ai_priority, ai_confidence, github_issue, jira_ticket, thread_issue,
                        status, reply_count, latest_reply
-                FROM {table_name} 
-                WHERE ai_thread_name IS NOT NULL 
+                FROM threads 
+                WHERE channel_id = %s AND ai_thread_name IS NOT NULL 
                 LIMIT 3
             """
             
             try:
-                db.cursor.execute(query)
+                db.cursor.execute(query, (channel["channel_id"],))
                 threads = db.cursor.fetchall()
                 
                 print(f"\n   📊 Sample threads from {channel['channel_name']}:")
@@ -78,7 +76,7 @@ def test_enhanced_workflow():
                     print()
                     
             except Exception as e:
-                print(f"   ❌ Error querying {table_name}: {e}")
+                print(f"   ❌ Error querying threads of {channel['channel_name']}: {e}")
         
         # Test 3: Check user profiles cache
         print("\n3️⃣ Testing User Profiles Cache...")
@@ -154,19 +152,17 @@ def test_enhanced_workflow():
         total_threads = 0
         
         for channel in channels:
-            table_name = channel["channel_name"].replace("-", "_")
-            
             try:
-                db.cursor.execute(f"SELECT COUNT(*) as total FROM {table_name}")
+                db.cursor.execute("SELECT COUNT(*) as total FROM threads WHERE channel_id = %s", (channel["channel_id"],))
                 total = db.cursor.fetchone()['total']
                 total_threads += total
                 
-                db.cursor.execute(f"SELECT COUNT(*) as analyzed FROM {table_name} WHERE ai_thread_name IS NOT NULL")
+                db.cursor.execute("SELECT COUNT(*) as analyzed FROM threads WHERE channel_id = %s AND ai_thread_name IS NOT NULL", (channel["channel_id"],))
                 analyzed = db.cursor.fetchone()['analyzed']
                 total_analyzed += analyzed
                 
             except Exception as e:
-                print(f"Warning: Could not count threads in {table_name}: {e}")
+                print(f"Warning: Could not count threads of {channel['channel_name']}: {e}")
         
         print(f"📊 Total threads: {total_threads}")
         print(f"🤖 AI analyzed: {total_analyzed}")
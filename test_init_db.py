@@ -0,0 +1,90 @@
+#!/usr/bin/env python3
+"""
+Test the channel policies and legal holds the collector reads from the dashboard, without a database
+"""
+import unittest
+from unittest import mock
+
+import psycopg2.errors
+
+from db.init_db import DBClient
+
+
+def client_returning(config=None, error=None):
+    """A DBClient whose cursor returns config for channel_config, or raises error."""
+    db = DBClient.__new__(DBClient)
+    db.conn = None
+    db.cursor = mock.Mock()
+    if error:
+        db.cursor.execute.side_effect = error
+    db.cursor.fetchone.return_value = config
+    return db
+
+
+class AIAnalysisAllowedTest(unittest.TestCase):
+
+    def test_text_indexing_opt_out(self):
+        db = client_returning({'text_indexing': False, 'slack_connect': None, 'slack_connect_ai': None})
+        self.assertFalse(db.ai_analysis_allowed("C1"))
+
+    def test_text_indexing_opt_out_of_shared_channel_with_ai(self):
+        db = client_returning({'text_indexing': False, 'slack_connect': True, 'slack_connect_ai': True})
+        self.assertFalse(db.ai_analysis_allowed("C1"))
+
+    def test_shared_channel_without_ai(self):
+        db = client_returning({'text_indexing': True, 'slack_connect': True, 'slack_connect_ai': None})
+        self.assertFalse(db.ai_analysis_allowed("C1"))
+
+    def test_shared_channel_with_ai(self):
+        db = client_returning({'text_indexing': True, 'slack_connect': True, 'slack_connect_ai': True})
+        self.assertTrue(db.ai_analysis_allowed("C1"))
+
+    def test_channel_without_configuration(self):
+        self.assertTrue(client_returning(None).ai_analysis_allowed("C1"))
+
+    def test_dashboard_never_ran(self):
+        db = client_returning(error=psycopg2.errors.UndefinedTable())
+        self.assertTrue(db.ai_analysis_allowed("C1"))
+
+    def test_policy_unreadable(self):
+        db = client_returning(error=psycopg2.OperationalError())
+        self.assertFalse(db.ai_analysis_allowed("C1"))
+
+
+class DeleteThreadTest(unittest.TestCase):
+
+    def statements(self, db):
+        return [" ".join(str(c.args[0]).split()) for c in db.cursor.execute.call_args_list]
+
+    def test_thread_without_hold(self):
+        db = client_returning()
+        db.cursor.rowcount = 1
+        self.assertTrue(db.delete_thread("1700000000.000100", "C1"))
+        self.assertEqual(len(self.statements(db)), 1)
+        self.assertIn("legal_holds", self.statements(db)[0])
+
+    def test_held_thread(self):
+        db = client_returning({'held': True})
+        db.cursor.rowcount = 0
+        self.assertFalse(db.delete_thread("1700000000.000100", "C1"))
+        audit = db.cursor.execute.call_args_list[-1].args
+        self.assertTrue(" ".join(audit[0].split()).startswith("INSERT INTO audit_log"))
+        self.assertEqual(audit[1][:2], ("C1", "1700000000.000100"))
+        self.assertIn("thread.delete", audit[1][2])
+
+    def test_missing_thread(self):
+        db = client_returning({'held': False})
+        db.cursor.rowcount = 0
+        self.assertFalse(db.delete_thread("1700000000.000100", "C1"))
+        self.assertFalse(any("audit_log" in s for s in self.statements(db)))
+
+    def test_dashboard_never_ran(self):
+        db = client_returning()
+        db.cursor.execute.side_effect = [psycopg2.errors.UndefinedTable(), None]
+        db.cursor.rowcount = 1
+        self.assertTrue(db.delete_thread("1700000000.000100", "C1"))
+        self.assertNotIn("legal_holds", self.statements(db)[1])
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -1,7 +1,7 @@
 import os
 from typing import Dict, Any
 from dotenv import load_dotenv
-from .enums import ThreadState, ReminderAction, ThreadPriority
+from .enums import ThreadState, ReminderAction, ThreadPriority, MissingInfo
 
 load_dotenv()
 
@@ -50,6 +50,7 @@ class VertexAIClient:
               Classification Categories:
               - thread_state: one of ["open", "closed", "resolved", "deferred", "chit_chat", "unknown"]
               - priority: one of ["high", "medium", "low", "none"]
+              - missing_info: any of ["logs", "version", "clear_question", "steps_to_reproduce"]
 
               Conversation Data:
               {conversation_data}
@@ -60,7 +61,10 @@ class VertexAIClient:
               3. Extract ALL user IDs in the format U123ABC456 (e.g., [User: U123ABC456] or just U123ABC456).
               4. Identify clear action items (as a list of strings).
               5. Identify unresolved questions with the user being asked.
-              6. Return ONLY a JSON object matching the exact structure below — no extra text, markdown, or code blocks.
+              6. Score how complete the first message is for triage, from 0.0 (nothing to go on) to 1.0 (ready to act on),
+                 and list what it lacks: logs or error output, the version in use, a clear question or ask, steps to reproduce.
+                 Only list details that matter for this kind of thread; chit chat and announcements lack nothing.
+              7. Return ONLY a JSON object matching the exact structure below — no extra text, markdown, or code blocks.
 
               Required JSON format:
               {{
@@ -73,7 +77,9 @@ class VertexAIClient:
                 "open_questions_left": [
                   {{ "question": "Why is the API not working?", "asked_person": "U123ABC456" }},
                   {{ "question": "When will the database migration be completed?", "asked_person": "U789DEF012" }}
-                ]
+                ],
+                "quality_score": 0.4,
+                "missing_info": ["logs", "version"]
               }}
 
               IMPORTANT:
@@ -111,6 +117,8 @@ class VertexAIClient:
         # Remove duplicates while preserving order
         stakeholders = list(dict.fromkeys(user_ids))
         
+        quality = self._fallback_quality(text_lower)
+
         urgent_keywords = ['urgent', 'critical', 'production', 'down', 'error', 'bug', 'broken']
         resolved_keywords = ['completed', 'done', 'finished', 'deployed', 'fixed', 'resolved', 'closed']
         deferred_keywords = ['defer', 'postpone', 'later', 'next sprint', 'backlog', 'future']
@@ -123,7 +131,8 @@ class VertexAIClient:
                 "confidence": 0.8,
                 "reasoning": "Contains urgent/critical keywords",
                 "action_items": ["Address urgent issue"],
-                "stakeholders": stakeholders
+                "stakeholders": stakeholders,
+                **quality
             }
         elif any(word in text_lower for word in resolved_keywords):
             return {
@@ -132,7 +141,9 @@ class VertexAIClient:
                 "confidence": 0.8,
                 "reasoning": "Contains completion keywords",
                 "action_items": [],
-                "stakeholders": stakeholders
+                "stakeholders": stakeholders,
+                "quality_score": 1.0,
+                "missing_info": []
             }
         elif any(word in text_lower for word in deferred_keywords):
             return {
@@ -141,7 +152,8 @@ class VertexAIClient:
                 "confidence": 0.8,
                 "reasoning": "Contains deferral keywords",
                 "action_items": ["Schedule for later"],
-                "stakeholders": stakeholders
+                "stakeholders": stakeholders,
+                **quality
             }
         elif any(word in text_lower for word in casual_keywords):
             return {
@@ -150,7 +162,9 @@ class VertexAIClient:
                 "confidence": 0.9,
                 "reasoning": "Contains casual conversation keywords",
                 "action_items": [],
-                "stakeholders": stakeholders
+                "stakeholders": stakeholders,
+                "quality_score": 1.0,
+                "missing_info": []
             }
         else:
             return {
@@ -159,9 +173,27 @@ class VertexAIClient:
                 "confidence": 0.6,
                 "reasoning": "Default classification for active discussion",
                 "action_items": ["Review and respond"],
-                "stakeholders": stakeholders
+                "stakeholders": stakeholders,
+                **quality
             }
 
+    def _fallback_quality(self, text_lower: str) -> Dict[str, Any]:
+        """Rule-based completeness score when AI fails"""
+        import re
+        missing = []
+        if not re.search(r'traceback|exception|stack trace|\berror\b|\blogs?\b|```', text_lower):
+            missing.append(MissingInfo.LOGS.value)
+        if not re.search(r'\bv?\d+\.\d+(\.\d+)?\b|\bversion\b', text_lower):
+            missing.append(MissingInfo.VERSION.value)
+        if '?' not in text_lower and not re.search(r'\b(how|why|what|can|could|please|help)\b', text_lower):
+            missing.append(MissingInfo.CLEAR_QUESTION.value)
+        if not re.search(r'\bsteps?\b|\breproduc|\b1\.', text_lower):
+            missing.append(MissingInfo.STEPS_TO_REPRODUCE.value)
+        return {
+            "quality_score": round(1 - len(missing) / len(MissingInfo), 2),
+            "missing_info": missing
+        }
+
     def should_send_reminder(self, classification_json: str, days_since_activity: int) -> Dict[str, Any]:
         """
         Determine reminder eligibility based on classification and time
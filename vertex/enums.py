@@ -15,8 +15,16 @@ class ReminderAction(Enum):
     NO_REMINDER = "no_reminder"
 
 class ThreadPriority(Enum):
-    """Enum for thread priority levels"""
+    """Enum for thread priority levels, kept in step with Priority in the
+    dashboard's Go domain package by hand"""
     HIGH = "high"
     MEDIUM = "medium"
     LOW = "low"
-    NONE = "none" 
\ No newline at end of file
+    NONE = "none" 
+
+class MissingInfo(Enum):
+    """Enum for details a thread may lack before it can be triaged"""
+    LOGS = "logs"
+    VERSION = "version"
+    CLEAR_QUESTION = "clear_question"
+    STEPS_TO_REPRODUCE = "steps_to_reproduce"